@@ -0,0 +1,116 @@
+package apperror
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+type Code string
+
+const (
+	CodeInvalidRequest  Code = "INVALID_REQUEST"
+	CodeValidation      Code = "VALIDATION"
+	CodeUnauthenticated Code = "UNAUTHENTICATED"
+	CodeForbidden       Code = "FORBIDDEN"
+	CodeNotFound        Code = "NOT_FOUND"
+	CodeConflict        Code = "CONFLICT"
+	CodeTooManyRequests Code = "TOO_MANY_REQUESTS"
+	CodeInternal        Code = "INTERNAL"
+)
+
+var httpStatus = map[Code]int{
+	CodeInvalidRequest:  http.StatusBadRequest,
+	CodeValidation:      http.StatusUnprocessableEntity,
+	CodeUnauthenticated: http.StatusUnauthorized,
+	CodeForbidden:       http.StatusForbidden,
+	CodeNotFound:        http.StatusNotFound,
+	CodeConflict:        http.StatusConflict,
+	CodeTooManyRequests: http.StatusTooManyRequests,
+	CodeInternal:        http.StatusInternalServerError,
+}
+
+// Error is a domain error with a stable code, Message and Fields are safe to show to clients.
+type Error struct {
+	Code    Code
+	Message string
+	Fields  []FieldError
+	// RetryAfter tells the client when to try again, sent as the Retry-After header when set
+	RetryAfter time.Duration
+	Err        error
+}
+
+// FieldError describes why a single request field is invalid.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func New(code Code, message string) *Error {
+	return &Error{
+		Code:    code,
+		Message: message,
+	}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches any *Error with the same code and message, so sentinels still match after WithCause.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code && t.Message == e.Message
+}
+
+// WithCause returns a copy of e wrapping cause, the cause is logged but never shown to clients.
+func (e *Error) WithCause(cause error) *Error {
+	return &Error{
+		Code:       e.Code,
+		Message:    e.Message,
+		Fields:     e.Fields,
+		RetryAfter: e.RetryAfter,
+		Err:        cause,
+	}
+}
+
+// WithRetryAfter returns a copy of e telling the client to wait d before retrying.
+func (e *Error) WithRetryAfter(d time.Duration) *Error {
+	return &Error{
+		Code:       e.Code,
+		Message:    e.Message,
+		Fields:     e.Fields,
+		RetryAfter: d,
+		Err:        e.Err,
+	}
+}
+
+// Validation builds a CodeValidation error listing every invalid field.
+func Validation(fields ...FieldError) *Error {
+	return &Error{
+		Code:    CodeValidation,
+		Message: "request validation failed",
+		Fields:  fields,
+	}
+}
+
+// CodeOf returns the code of the first *Error in err's chain, CodeInternal when there is none.
+func CodeOf(err error) Code {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return CodeInternal
+}
+
+// HttpStatus maps err to the http status code of its Code.
+func HttpStatus(err error) int {
+	return httpStatus[CodeOf(err)]
+}
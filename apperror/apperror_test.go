@@ -0,0 +1,84 @@
+package apperror
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_HttpStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{
+			name: "not found wrapping sql.ErrNoRows",
+			err:  ErrLoanNotFound.WithCause(sql.ErrNoRows),
+			want: http.StatusNotFound,
+		},
+		{
+			name: "conflict wrapped with fmt",
+			err:  fmt.Errorf("pay loan: %w", ErrTermAlreadyPaid),
+			want: http.StatusConflict,
+		},
+		{
+			name: "validation",
+			err:  ErrMinimumPaymentNotReached,
+			want: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "forbidden",
+			err:  ErrForbidden,
+			want: http.StatusForbidden,
+		},
+		{
+			name: "unauthenticated",
+			err:  ErrInvalidToken.WithCause(errors.New("token is expired")),
+			want: http.StatusUnauthorized,
+		},
+		{
+			name: "invalid request",
+			err:  New(CodeInvalidRequest, "EOF"),
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "too many requests keeps its status with a retry delay",
+			err:  ErrTooManyLoginAttempts.WithRetryAfter(time.Minute),
+			want: http.StatusTooManyRequests,
+		},
+		{
+			name: "unknown error is internal",
+			err:  errors.New("pq: connection refused"),
+			want: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HttpStatus(tt.err); got != tt.want {
+				t.Errorf("HttpStatus test failed. want: %d, got: %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_Is(t *testing.T) {
+	err := ErrLoanNotFound.WithCause(sql.ErrNoRows)
+
+	if !errors.Is(err, ErrLoanNotFound) {
+		t.Errorf("wrapped sentinel should match ErrLoanNotFound")
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("wrapped sentinel should match its cause")
+	}
+	if errors.Is(err, ErrUserNotFound) {
+		t.Errorf("ErrLoanNotFound should not match ErrUserNotFound")
+	}
+	if err.Error() != "loan not found: sql: no rows in result set" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+}
@@ -0,0 +1,61 @@
+package apperror
+
+var (
+	ErrUnauthenticated = New(CodeUnauthenticated, "unauthorized")
+	ErrCookieNotFound  = New(CodeUnauthenticated, "cookie not found")
+	ErrInvalidToken    = New(CodeUnauthenticated, "invalid token")
+	ErrForbidden       = New(CodeForbidden, "forbidden")
+	ErrRateLimited     = New(CodeTooManyRequests, "rate limit exceeded, retry later")
+
+	// ErrInvalidCredentials is the only login failure, unknown emails and wrong passwords look the same
+	ErrInvalidCredentials   = New(CodeUnauthenticated, "invalid email or password")
+	ErrTooManyLoginAttempts = New(CodeTooManyRequests, "too many failed login attempts, retry later")
+	ErrEmailRegistered      = New(CodeConflict, "email already registered")
+	ErrUserNotFound         = New(CodeNotFound, "user not found")
+
+	ErrInvalidVerificationToken = New(CodeValidation, "invalid or expired verification token")
+	ErrEmailAlreadyVerified     = New(CodeConflict, "email already verified")
+	ErrEmailNotVerified         = New(CodeForbidden, "email not verified")
+
+	ErrSessionRevoked = New(CodeUnauthenticated, "session revoked, log in again")
+	// ErrPasswordChangeRequired refuses every endpoint but the password change to a user who must set a new password
+	ErrPasswordChangeRequired = New(CodeForbidden, "password change required, change your password first")
+	ErrInvalidResetToken      = New(CodeValidation, "invalid or expired password reset token")
+	ErrOldPasswordInvalid     = &Error{
+		Code:    CodeValidation,
+		Message: "old password is incorrect",
+		Fields:  []FieldError{{Field: "old_password", Message: "is incorrect"}},
+	}
+
+	ErrTwoFactorRequired     = New(CodeUnauthenticated, "two factor authentication required, log in again")
+	ErrInvalidChallengeToken = New(CodeUnauthenticated, "invalid or expired two factor challenge")
+	ErrInvalidTotpCode       = New(CodeUnauthenticated, "invalid two factor code")
+	ErrTotpAlreadyEnabled    = New(CodeConflict, "two factor authentication already enabled")
+	ErrTotpNotEnrolled       = New(CodeConflict, "two factor enrollment not started")
+
+	ErrApprovalLimitExceeded = New(CodeForbidden, "loan amount exceeds your approval limit")
+	ErrCannotChangeOwnRoles  = New(CodeForbidden, "you can't change your own roles")
+	ErrUserNotStaff          = New(CodeConflict, "roles can only be granted to admin accounts")
+
+	ErrLoanNotFound             = New(CodeNotFound, "loan not found")
+	ErrLoanNotApproved          = New(CodeConflict, "loan not approved")
+	ErrLoanNotPending           = New(CodeConflict, "loan is not pending approval")
+	ErrSelfApproval             = New(CodeForbidden, "you can't approve your own loan")
+	ErrAlreadyApproved          = New(CodeConflict, "you already approved this loan")
+	ErrLoanNotDisbursed         = New(CodeConflict, "loan not disbursed")
+	ErrLoanAlreadyDisbursed     = New(CodeConflict, "loan already disbursed")
+	ErrDisbursementNotFound     = New(CodeNotFound, "disbursement not found")
+	ErrTermNotFound             = New(CodeNotFound, "term not found")
+	ErrPreviousTermUnpaid       = New(CodeConflict, "there is a term before that has not been paid")
+	ErrTermAlreadyPaid          = New(CodeConflict, "already paid for this term")
+	ErrMinimumPaymentNotReached = New(CodeValidation, "minimum payment not reached")
+	ErrPaidMoreThanLoan         = New(CodeValidation, "paid more than loan")
+
+	ErrPaymentIntentNotFound   = New(CodeNotFound, "payment intent not found")
+	ErrPaymentIntentPending    = New(CodeConflict, "a payment of this term is already pending")
+	ErrInvalidWebhookSignature = New(CodeUnauthenticated, "invalid webhook signature")
+	ErrWebhookReplayed         = New(CodeConflict, "webhook already received")
+
+	ErrWebhookEndpointNotFound = New(CodeNotFound, "webhook endpoint not found")
+	ErrWebhookDeliveryNotFound = New(CodeNotFound, "webhook delivery not found")
+)
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"example.com/m/v2/config"
+)
+
+const Name = "mini-aspire"
+
+// exit codes returned by Run
+const (
+	ExitOK    = 0
+	ExitError = 1
+	ExitUsage = 2
+)
+
+type command struct {
+	name        string
+	summary     string
+	subcommands []*command
+	// setup registers command specific flags and returns the function executed once flags are parsed
+	setup func(fs *flag.FlagSet) func(ctx context.Context, a *app) error
+}
+
+func (c *command) find(name string) *command {
+	for _, sub := range c.subcommands {
+		if sub.name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+type usageError struct {
+	message string
+}
+
+func (e usageError) Error() string {
+	return e.message
+}
+
+type app struct {
+	stdout     io.Writer
+	stderr     io.Writer
+	configPath string
+	env        string
+}
+
+// Run parses args (without the program name), executes the matching command and returns the process exit code.
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	a := &app{
+		stdout: stdout,
+		stderr: stderr,
+		env:    "development",
+	}
+
+	return a.dispatch(ctx, root(), Name, args)
+}
+
+func (a *app) dispatch(ctx context.Context, cmd *command, path string, args []string) int {
+	fs := flag.NewFlagSet(path, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.StringVar(&a.configPath, "config", a.configPath, "path to the config file, takes precedence over --env")
+	fs.StringVar(&a.env, "env", a.env, "environment name, reads files/<env>.yaml")
+
+	var run func(ctx context.Context, a *app) error
+	if cmd.setup != nil {
+		run = cmd.setup(fs)
+	}
+	fs.Usage = func() {
+		a.usage(cmd, path, fs)
+	}
+
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitOK
+	}
+	if err != nil {
+		return ExitUsage
+	}
+
+	rest := fs.Args()
+	if len(cmd.subcommands) > 0 {
+		if len(rest) == 0 {
+			fs.Usage()
+			return ExitUsage
+		}
+		if rest[0] == "help" {
+			fs.SetOutput(a.stdout)
+			fs.Usage()
+			return ExitOK
+		}
+
+		sub := cmd.find(rest[0])
+		if sub == nil {
+			fmt.Fprintf(a.stderr, "unknown command %q\n\n", rest[0])
+			fs.Usage()
+			return ExitUsage
+		}
+
+		return a.dispatch(ctx, sub, path+" "+sub.name, rest[1:])
+	}
+
+	if len(rest) > 0 {
+		fmt.Fprintf(a.stderr, "unexpected arguments: %s\n\n", strings.Join(rest, " "))
+		fs.Usage()
+		return ExitUsage
+	}
+
+	err = run(ctx, a)
+	var uErr usageError
+	if errors.As(err, &uErr) {
+		fmt.Fprintf(a.stderr, "%s\n\n", uErr.message)
+		fs.Usage()
+		return ExitUsage
+	}
+	if err != nil {
+		fmt.Fprintf(a.stderr, "error: %v\n", err)
+		return ExitError
+	}
+
+	return ExitOK
+}
+
+func (a *app) usage(cmd *command, path string, fs *flag.FlagSet) {
+	out := fs.Output()
+	if len(cmd.subcommands) > 0 {
+		fmt.Fprintf(out, "Usage: %s [flags] <command>\n", path)
+	} else {
+		fmt.Fprintf(out, "Usage: %s [flags]\n", path)
+	}
+	if cmd.summary != "" {
+		fmt.Fprintf(out, "\n%s\n", cmd.summary)
+	}
+	if len(cmd.subcommands) > 0 {
+		fmt.Fprintf(out, "\nCommands:\n")
+		for _, sub := range cmd.subcommands {
+			fmt.Fprintf(out, "  %-14s %s\n", sub.name, sub.summary)
+		}
+	}
+	fmt.Fprintf(out, "\nFlags:\n")
+	fs.PrintDefaults()
+}
+
+func (a *app) loadConfig() (cfg config.Config, err error) {
+	return config.Load(config.Options{
+		Path: a.configPath,
+		Env:  a.env,
+	})
+}
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_Run(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "test.yaml")
+	os.WriteFile(cfgPath, []byte("server_address: :8000\njwt_secret: tes\ndb:\n  user: tes\n  dbname: tes\npayment:\n  webhook_secret: tes-webhook-secret-of-32-characters\n"), 0600)
+	invalidCfgPath := filepath.Join(dir, "invalid.yaml")
+	os.WriteFile(invalidCfgPath, []byte("server_address: :99999\n"), 0600)
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "no command",
+			args:       []string{},
+			wantCode:   ExitUsage,
+			wantStderr: "Usage: mini-aspire [flags] <command>",
+		},
+		{
+			name:       "unknown command",
+			args:       []string{"tes"},
+			wantCode:   ExitUsage,
+			wantStderr: `unknown command "tes"`,
+		},
+		{
+			name:     "unknown flag",
+			args:     []string{"--tes", "server"},
+			wantCode: ExitUsage,
+		},
+		{
+			name:       "help",
+			args:       []string{"help"},
+			wantCode:   ExitOK,
+			wantStdout: "run the http server",
+		},
+		{
+			name:       "missing subcommand",
+			args:       []string{"user"},
+			wantCode:   ExitUsage,
+			wantStderr: "Usage: mini-aspire user [flags] <command>",
+		},
+		{
+			name:       "unexpected arguments",
+			args:       []string{"migrate", "tes"},
+			wantCode:   ExitUsage,
+			wantStderr: "unexpected arguments: tes",
+		},
+		{
+			name:       "create admin without email",
+			args:       []string{"user", "create-admin", "--password", "tes"},
+			wantCode:   ExitUsage,
+			wantStderr: "--email and --password are required",
+		},
+		{
+			name:       "approve loan without id",
+			args:       []string{"loan", "approve"},
+			wantCode:   ExitUsage,
+			wantStderr: "--id must be a positive loan id",
+		},
+		{
+			name:       "disburse loan without id",
+			args:       []string{"loan", "disburse"},
+			wantCode:   ExitUsage,
+			wantStderr: "--id must be a positive loan id",
+		},
+		{
+			name:       "mark overdue with a malformed time",
+			args:       []string{"loan", "mark-overdue", "--at", "yesterday"},
+			wantCode:   ExitUsage,
+			wantStderr: "--at must be an RFC 3339 time",
+		},
+		{
+			name:       "requeue dead outbox event with a negative id",
+			args:       []string{"outbox", "requeue-dead", "--id", "-1"},
+			wantCode:   ExitUsage,
+			wantStderr: "--id must be a positive event id",
+		},
+		{
+			name:       "retry failed notification with a negative id",
+			args:       []string{"notification", "retry-failed", "--id", "-1"},
+			wantCode:   ExitUsage,
+			wantStderr: "--id must be a positive notification id",
+		},
+		{
+			name:       "approve loan without approver",
+			args:       []string{"loan", "approve", "--id", "1"},
+			wantCode:   ExitUsage,
+			wantStderr: "--approver must be a positive user id",
+		},
+		{
+			name:       "config validate missing file",
+			args:       []string{"--config", filepath.Join(dir, "missing.yaml"), "config", "validate"},
+			wantCode:   ExitError,
+			wantStderr: "error:",
+		},
+		{
+			name:       "config validate invalid config",
+			args:       []string{"config", "validate", "--config", invalidCfgPath},
+			wantCode:   ExitError,
+			wantStderr: "server_address: port must be between 1 and 65535",
+		},
+		{
+			name:       "config validate",
+			args:       []string{"config", "validate", "--config", cfgPath},
+			wantCode:   ExitOK,
+			wantStdout: "config ok",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			got := Run(context.Background(), tt.args, &stdout, &stderr)
+			if got != tt.wantCode {
+				t.Errorf("Run test failed. want code: %d, got code: %d, stderr: %s", tt.wantCode, got, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.wantStdout) {
+				t.Errorf("Run test failed. want stdout containing: %q, got: %q", tt.wantStdout, stdout.String())
+			}
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Errorf("Run test failed. want stderr containing: %q, got: %q", tt.wantStderr, stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,575 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/config"
+	"example.com/m/v2/constant"
+	db "example.com/m/v2/database"
+	"example.com/m/v2/dependency"
+	"example.com/m/v2/logger"
+	"example.com/m/v2/notify"
+	"example.com/m/v2/outbox"
+	"example.com/m/v2/resource"
+	"example.com/m/v2/route"
+	"example.com/m/v2/tracing"
+	"example.com/m/v2/webhook"
+)
+
+func root() *command {
+	return &command{
+		name:    Name,
+		summary: "Mini aspire loan service.",
+		subcommands: []*command{
+			{
+				name:    "server",
+				summary: "run the http server",
+				setup:   serverCommand,
+			},
+			{
+				name:    "worker",
+				summary: "run the background jobs",
+				setup:   workerCommand,
+			},
+			{
+				name:    "migrate",
+				summary: "run database migrations",
+				setup:   migrateCommand,
+			},
+			{
+				name:    "seed",
+				summary: "seed the database with the default admin",
+				setup:   seedCommand,
+			},
+			{
+				name:    "user",
+				summary: "manage users",
+				subcommands: []*command{
+					{
+						name:    "create-admin",
+						summary: "create an admin user",
+						setup:   createAdminCommand,
+					},
+				},
+			},
+			{
+				name:    "loan",
+				summary: "manage loans",
+				subcommands: []*command{
+					{
+						name:    "approve",
+						summary: "approve a pending loan",
+						setup:   approveLoanCommand,
+					},
+					{
+						name:    "disburse",
+						summary: "pay an approved loan out, or retry a pending payout",
+						setup:   disburseLoanCommand,
+					},
+					{
+						name:    "mark-overdue",
+						summary: "mark unpaid terms overdue, charge late fees and default loans",
+						setup:   markOverdueCommand,
+					},
+				},
+			},
+			{
+				name:    "outbox",
+				summary: "relay domain events",
+				subcommands: []*command{
+					{
+						name:    "relay",
+						summary: "publish outbox events to the configured sink",
+						setup:   outboxRelayCommand,
+					},
+					{
+						name:    "requeue-dead",
+						summary: "put dead-lettered events back in the outbox",
+						setup:   outboxRequeueDeadCommand,
+					},
+				},
+			},
+			{
+				name:    "webhook",
+				summary: "deliver partner webhooks",
+				subcommands: []*command{
+					{
+						name:    "dispatch",
+						summary: "send pending webhook deliveries",
+						setup:   webhookDispatchCommand,
+					},
+				},
+			},
+			{
+				name:    "notification",
+				summary: "send customer notifications",
+				subcommands: []*command{
+					{
+						name:    "dispatch",
+						summary: "send pending notifications",
+						setup:   notificationDispatchCommand,
+					},
+					{
+						name:    "retry-failed",
+						summary: "put failed notifications back to pending",
+						setup:   notificationRetryFailedCommand,
+					},
+				},
+			},
+			{
+				name:    "config",
+				summary: "inspect configuration",
+				subcommands: []*command{
+					{
+						name:    "validate",
+						summary: "load the configuration and report errors",
+						setup:   validateConfigCommand,
+					},
+				},
+			},
+		},
+	}
+}
+
+func withResource(ctx context.Context, a *app, fn func(cfg *config.Config, res *resource.Resource) error) (err error) {
+	cfg, err := a.loadConfig()
+	if err != nil {
+		return
+	}
+
+	log, err := logger.New(cfg.Log, a.stdout)
+	if err != nil {
+		return
+	}
+	slog.SetDefault(log)
+
+	shutdownTracing, err := tracing.Init(ctx, cfg.Tracing, a.stdout)
+	if err != nil {
+		return
+	}
+	defer shutdownTracing(context.Background())
+
+	res, err := resource.Init(ctx, &cfg)
+	if err != nil {
+		return
+	}
+	defer res.Close()
+
+	return fn(&cfg, res)
+}
+
+func serverCommand(fs *flag.FlagSet) func(ctx context.Context, a *app) error {
+	addr := fs.String("addr", "", "address to listen on, overrides server_address from config")
+
+	return func(ctx context.Context, a *app) error {
+		return withResource(ctx, a, func(cfg *config.Config, res *resource.Resource) error {
+			if *addr != "" {
+				cfg.ServerAddress = *addr
+			}
+
+			dep, err := dependency.Init(cfg, res)
+			if err != nil {
+				return err
+			}
+
+			route.Init(dep)
+
+			return serve(ctx, a, cfg, dep)
+		})
+	}
+}
+
+// serve runs the http server until SIGINT / SIGTERM, then fails readiness and drains in-flight requests. The job
+// runner runs alongside when jobs.in_server is set, it stops with the server.
+func serve(ctx context.Context, a *app, cfg *config.Config, dep dependency.Dependency) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.Jobs.InServer {
+		jobsDone := make(chan struct{})
+		go func() {
+			defer close(jobsDone)
+			if err := dep.Jobs.Run(ctx); err != nil {
+				slog.Error("job runner", "error", err)
+			}
+		}()
+		defer func() {
+			stop()
+			<-jobsDone
+		}()
+	}
+
+	srv := &http.Server{
+		Addr: cfg.ServerAddress,
+	}
+
+	errCh := make(chan error, 2)
+	go func() {
+		slog.Info("running server", "addr", cfg.ServerAddress)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	if cfg.MetricsAddress != "" {
+		metricsSrv := &http.Server{
+			Addr:    cfg.MetricsAddress,
+			Handler: route.Metrics(),
+		}
+		go func() {
+			slog.Info("running metrics server", "addr", cfg.MetricsAddress)
+			errCh <- metricsSrv.ListenAndServe()
+		}()
+		defer metricsSrv.Close()
+	}
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down server")
+	dep.Health.SetShuttingDown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	return srv.Shutdown(shutdownCtx)
+}
+
+// workerCommand runs the job runner until SIGINT / SIGTERM, the running job is cancelled and retried later. The
+// outbox relay, the webhook dispatcher and the notification dispatcher run alongside when outbox.in_worker,
+// webhooks.in_worker and notifications.in_worker are set.
+func workerCommand(fs *flag.FlagSet) func(ctx context.Context, a *app) error {
+	return func(ctx context.Context, a *app) error {
+		return withResource(ctx, a, func(cfg *config.Config, res *resource.Resource) error {
+			dep, err := dependency.Init(cfg, res)
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			var wg sync.WaitGroup
+			defer func() {
+				stop()
+				wg.Wait()
+			}()
+			background := func(name string, run func(ctx context.Context) error) {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := run(ctx); err != nil {
+						slog.Error(name, "error", err)
+					}
+				}()
+			}
+
+			if cfg.Outbox.InWorker {
+				background("outbox relay", func(ctx context.Context) error {
+					return runRelay(ctx, a, cfg, res)
+				})
+			}
+			if cfg.Webhooks.InWorker {
+				background("webhook dispatcher", webhook.NewDispatcher(webhook.NewPostgresStore(res.PostgresDb), cfg.Webhooks).Run)
+			}
+			if cfg.Notifications.InWorker {
+				background("notification dispatcher", func(ctx context.Context) error {
+					return runNotifications(ctx, a, cfg, res)
+				})
+			}
+
+			return dep.Jobs.Run(ctx)
+		})
+	}
+}
+
+// runRelay publishes outbox events to the configured sink until ctx is done.
+func runRelay(ctx context.Context, a *app, cfg *config.Config, res *resource.Resource) error {
+	sink, err := outbox.NewSink(cfg.Outbox, a.stdout)
+	if err != nil {
+		return err
+	}
+	defer sink.Close()
+
+	return outbox.NewRelay(outbox.NewPostgresStore(res.PostgresDb), sink, cfg.Outbox).Run(ctx)
+}
+
+// outboxRelayCommand runs the outbox relay until SIGINT / SIGTERM, an event being published when it stops is
+// published again by the next relay.
+func outboxRelayCommand(fs *flag.FlagSet) func(ctx context.Context, a *app) error {
+	return func(ctx context.Context, a *app) error {
+		return withResource(ctx, a, func(cfg *config.Config, res *resource.Resource) error {
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			return runRelay(ctx, a, cfg, res)
+		})
+	}
+}
+
+func outboxRequeueDeadCommand(fs *flag.FlagSet) func(ctx context.Context, a *app) error {
+	id := fs.Int64("id", 0, "id of the event to requeue, all dead events when omitted")
+
+	return func(ctx context.Context, a *app) error {
+		if *id < 0 {
+			return usageError{"--id must be a positive event id"}
+		}
+
+		return withResource(ctx, a, func(cfg *config.Config, res *resource.Resource) error {
+			requeued, err := outbox.NewPostgresStore(res.PostgresDb).RequeueDead(ctx, *id, time.Now())
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(a.stdout, "%d events requeued\n", requeued)
+			return nil
+		})
+	}
+}
+
+// webhookDispatchCommand sends webhook deliveries until SIGINT / SIGTERM, a delivery being sent when it stops is
+// sent again by the next dispatcher.
+func webhookDispatchCommand(fs *flag.FlagSet) func(ctx context.Context, a *app) error {
+	return func(ctx context.Context, a *app) error {
+		return withResource(ctx, a, func(cfg *config.Config, res *resource.Resource) error {
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			return webhook.NewDispatcher(webhook.NewPostgresStore(res.PostgresDb), cfg.Webhooks).Run(ctx)
+		})
+	}
+}
+
+// runNotifications sends notifications on the configured channels until ctx is done.
+func runNotifications(ctx context.Context, a *app, cfg *config.Config, res *resource.Resource) error {
+	catalogue, err := notify.DefaultCatalogue(cfg.Notifications.DefaultLocale)
+	if err != nil {
+		return err
+	}
+	channels, err := notify.NewChannels(cfg.Notifications, a.stdout)
+	if err != nil {
+		return err
+	}
+	defer notify.CloseChannels(channels)
+
+	return notify.NewDispatcher(notify.NewPostgresStore(res.PostgresDb), catalogue, channels, cfg.Notifications).Run(ctx)
+}
+
+// notificationDispatchCommand sends notifications until SIGINT / SIGTERM, a notification being sent when it stops
+// is sent again by the next dispatcher.
+func notificationDispatchCommand(fs *flag.FlagSet) func(ctx context.Context, a *app) error {
+	return func(ctx context.Context, a *app) error {
+		return withResource(ctx, a, func(cfg *config.Config, res *resource.Resource) error {
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			return runNotifications(ctx, a, cfg, res)
+		})
+	}
+}
+
+func notificationRetryFailedCommand(fs *flag.FlagSet) func(ctx context.Context, a *app) error {
+	id := fs.Int64("id", 0, "id of the notification to retry, all failed notifications when omitted")
+
+	return func(ctx context.Context, a *app) error {
+		if *id < 0 {
+			return usageError{"--id must be a positive notification id"}
+		}
+
+		return withResource(ctx, a, func(cfg *config.Config, res *resource.Resource) error {
+			retried, err := notify.NewPostgresStore(res.PostgresDb).RetryFailed(ctx, *id, time.Now())
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(a.stdout, "%d notifications retried\n", retried)
+			return nil
+		})
+	}
+}
+
+func migrateCommand(fs *flag.FlagSet) func(ctx context.Context, a *app) error {
+	return func(ctx context.Context, a *app) error {
+		return withResource(ctx, a, func(cfg *config.Config, res *resource.Resource) error {
+			return db.Migrate(ctx, res)
+		})
+	}
+}
+
+// seedCommand creates the default admin with seed_admin_password, or a generated password printed once. The password
+// must be changed on first login.
+func seedCommand(fs *flag.FlagSet) func(ctx context.Context, a *app) error {
+	return func(ctx context.Context, a *app) error {
+		return withResource(ctx, a, func(cfg *config.Config, res *resource.Resource) error {
+			dep, err := dependency.Init(cfg, res)
+			if err != nil {
+				return err
+			}
+
+			generated, err := dep.Handler.Usecase.SeedAdmin(ctx, cfg.SeedAdminPassword)
+			if errors.Is(err, apperror.ErrEmailRegistered) {
+				fmt.Fprintf(a.stdout, "admin %s already seeded\n", constant.SeedAdminEmail)
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			if generated != "" {
+				fmt.Fprintf(a.stdout, "admin %s created with password %s, it must be changed on first login\n", constant.SeedAdminEmail, generated)
+			} else {
+				fmt.Fprintf(a.stdout, "admin %s created, the password must be changed on first login\n", constant.SeedAdminEmail)
+			}
+			return nil
+		})
+	}
+}
+
+func createAdminCommand(fs *flag.FlagSet) func(ctx context.Context, a *app) error {
+	email := fs.String("email", "", "admin email (required)")
+	password := fs.String("password", "", "admin password (required)")
+
+	return func(ctx context.Context, a *app) error {
+		if *email == "" || *password == "" {
+			return usageError{"--email and --password are required"}
+		}
+
+		return withResource(ctx, a, func(cfg *config.Config, res *resource.Resource) error {
+			dep, err := dependency.Init(cfg, res)
+			if err != nil {
+				return err
+			}
+
+			err = dep.Handler.Usecase.UserCreateAdmin(ctx, *email, *password)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(a.stdout, "admin %s created\n", *email)
+			return nil
+		})
+	}
+}
+
+func approveLoanCommand(fs *flag.FlagSet) func(ctx context.Context, a *app) error {
+	loanId := fs.Int64("id", 0, "id of the loan to approve (required)")
+	approverId := fs.Int64("approver", 0, "id of the admin approving, their roles and approval limit apply (required)")
+
+	return func(ctx context.Context, a *app) error {
+		if *loanId <= 0 {
+			return usageError{"--id must be a positive loan id"}
+		}
+		if *approverId <= 0 {
+			return usageError{"--approver must be a positive user id"}
+		}
+
+		return withResource(ctx, a, func(cfg *config.Config, res *resource.Resource) error {
+			dep, err := dependency.Init(cfg, res)
+			if err != nil {
+				return err
+			}
+
+			approver, err := dep.Handler.Usecase.Authorize(ctx, *approverId, constant.PermissionLoanApprove)
+			if err != nil {
+				return err
+			}
+
+			result, err := dep.Handler.Usecase.ApproveLoan(ctx, *loanId, approver)
+			if err != nil {
+				return err
+			}
+
+			if result.Status == constant.LoanStatusApproved {
+				fmt.Fprintf(a.stdout, "loan %d approved\n", *loanId)
+			} else {
+				fmt.Fprintf(a.stdout, "loan %d approval recorded, %d of %d approvals\n", *loanId, result.Approvals, result.Required)
+			}
+			return nil
+		})
+	}
+}
+
+func disburseLoanCommand(fs *flag.FlagSet) func(ctx context.Context, a *app) error {
+	loanId := fs.Int64("id", 0, "id of the loan to disburse (required)")
+
+	return func(ctx context.Context, a *app) error {
+		if *loanId <= 0 {
+			return usageError{"--id must be a positive loan id"}
+		}
+
+		return withResource(ctx, a, func(cfg *config.Config, res *resource.Resource) error {
+			dep, err := dependency.Init(cfg, res)
+			if err != nil {
+				return err
+			}
+
+			disbursement, err := dep.Handler.Usecase.DisburseLoan(ctx, *loanId)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(a.stdout, "loan %d disbursement %s, reference %q\n", *loanId, disbursement.Status, disbursement.ProviderReference)
+			return nil
+		})
+	}
+}
+
+func markOverdueCommand(fs *flag.FlagSet) func(ctx context.Context, a *app) error {
+	at := fs.String("at", "", "run as of this RFC 3339 time instead of now")
+
+	return func(ctx context.Context, a *app) error {
+		now := time.Now()
+		if *at != "" {
+			parsed, err := time.Parse(time.RFC3339, *at)
+			if err != nil {
+				return usageError{"--at must be an RFC 3339 time"}
+			}
+			now = parsed
+		}
+
+		return withResource(ctx, a, func(cfg *config.Config, res *resource.Resource) error {
+			dep, err := dependency.Init(cfg, res)
+			if err != nil {
+				return err
+			}
+
+			run, err := dep.Handler.Usecase.MarkOverdue(ctx, now)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(a.stdout, "%d terms overdue, %d late fees charged (%.2f), %d loans defaulted\n", run.Overdue, run.Fees, run.FeeAmount, run.Defaulted)
+			return nil
+		})
+	}
+}
+
+func validateConfigCommand(fs *flag.FlagSet) func(ctx context.Context, a *app) error {
+	return func(ctx context.Context, a *app) error {
+		_, err := a.loadConfig()
+		var vErr config.ValidationError
+		if errors.As(err, &vErr) {
+			for _, f := range vErr {
+				fmt.Fprintf(a.stderr, "  %s: %s\n", f.Field, f.Message)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(a.stdout, "config ok")
+		return nil
+	}
+}
@@ -1,29 +1,456 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/go-yaml/yaml"
 )
 
 type Config struct {
 	PostgresDb    PostgresDb `yaml:"db"`
-	ServerAddress string     `yaml:"server_address"`
-	JwtSecret     string     `yaml:"jwt_secret"`
+	ServerAddress string     `yaml:"server_address" env:"SERVER_ADDRESS"`
+	// MetricsAddress serves /metrics apart from the api, keep it off the public network. empty disables it
+	MetricsAddress string `yaml:"metrics_address" env:"METRICS_ADDRESS"`
+	JwtSecret      string `yaml:"jwt_secret" env:"JWT_SECRET"`
+	// ShutdownTimeout is how long in-flight requests may take to drain after SIGINT / SIGTERM
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	Log             Log           `yaml:"log"`
+	Tracing         Tracing       `yaml:"tracing"`
+	Password        Password      `yaml:"password"`
+	Mailer          Mailer        `yaml:"mailer"`
+	Verification    Verification  `yaml:"verification"`
+	PasswordReset   PasswordReset `yaml:"password_reset"`
+	Lockout         Lockout       `yaml:"lockout"`
+	RateLimit       RateLimit     `yaml:"rate_limit"`
+	Totp            Totp          `yaml:"totp"`
+	Approval        Approval      `yaml:"approval"`
+	Payout          Payout        `yaml:"payout"`
+	Payment         Payment       `yaml:"payment"`
+	Overdue         Overdue       `yaml:"overdue"`
+	Jobs            Jobs          `yaml:"jobs"`
+	Outbox          Outbox        `yaml:"outbox"`
+	Webhooks        Webhooks      `yaml:"webhooks"`
+	Notifications   Notifications `yaml:"notifications"`
+	// SeedAdminPassword is the password of the admin created by the seed command, generated when empty
+	SeedAdminPassword string `yaml:"seed_admin_password" env:"SEED_ADMIN_PASSWORD"`
+	// TrustProxyHeaders takes the client ip from the last X-Forwarded-For entry, enable it only behind a reverse proxy
+	TrustProxyHeaders bool `yaml:"trust_proxy_headers" env:"TRUST_PROXY_HEADERS"`
+}
+
+type Password struct {
+	MinLength int `yaml:"min_length" env:"PASSWORD_MIN_LENGTH"`
+	// MaxLength can't exceed 72, bcrypt ignores anything after the 72nd byte
+	MaxLength int `yaml:"max_length" env:"PASSWORD_MAX_LENGTH"`
+	// DenylistFile lists breached / common passwords, one per line, compared case-insensitively
+	DenylistFile string `yaml:"denylist_file" env:"PASSWORD_DENYLIST_FILE"`
+}
+
+type Mailer struct {
+	// Driver is log (mails are written to the application log) or file (mails are appended to File)
+	Driver string `yaml:"driver" env:"MAILER_DRIVER"`
+	File   string `yaml:"file" env:"MAILER_FILE"`
+	From   string `yaml:"from" env:"MAILER_FROM"`
+}
+
+type Verification struct {
+	// TokenTtl is how long an email verification link stays valid
+	TokenTtl time.Duration `yaml:"token_ttl" env:"VERIFICATION_TOKEN_TTL"`
+	// Url is the page the verification link points to, the token is appended as the token query parameter
+	Url string `yaml:"url" env:"VERIFICATION_URL"`
+}
+
+type Log struct {
+	// Level is one of debug, info, warn, error
+	Level string `yaml:"level" env:"LOG_LEVEL"`
+	// Format is json or text
+	Format string `yaml:"format" env:"LOG_FORMAT"`
 }
 
 type PostgresDb struct {
-	Host     string `yaml:"host"`
-	Port     int    `yaml:"port"`
-	User     string `yaml:"user"`
-	Password string `yaml:"password"`
-	Dbname   string `yaml:"dbname"`
+	Host        string `yaml:"host" env:"DB_HOST"`
+	Port        int    `yaml:"port" env:"DB_PORT"`
+	User        string `yaml:"user" env:"DB_USER"`
+	Password    string `yaml:"password" env:"DB_PASSWORD"`
+	Dbname      string `yaml:"dbname" env:"DB_NAME"`
+	SslMode     string `yaml:"sslmode" env:"DB_SSLMODE"`
+	SslCert     string `yaml:"sslcert" env:"DB_SSLCERT"`
+	SslKey      string `yaml:"sslkey" env:"DB_SSLKEY"`
+	SslRootCert string `yaml:"sslrootcert" env:"DB_SSLROOTCERT"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	// ConnectTimeout bounds every connection attempt, including each startup ping
+	ConnectTimeout time.Duration `yaml:"connect_timeout" env:"DB_CONNECT_TIMEOUT"`
+	// ConnectRetries is the number of extra startup ping attempts, with exponential backoff in between
+	ConnectRetries int `yaml:"connect_retries" env:"DB_CONNECT_RETRIES"`
+}
+
+type Tracing struct {
+	// Exporter is none, stdout or otlp
+	Exporter string `yaml:"exporter" env:"TRACING_EXPORTER"`
+	// OtlpEndpoint is the host:port of the OTLP/HTTP collector
+	OtlpEndpoint string  `yaml:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT"`
+	OtlpInsecure bool    `yaml:"otlp_insecure" env:"TRACING_OTLP_INSECURE"`
+	SampleRatio  float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+	ServiceName  string  `yaml:"service_name" env:"TRACING_SERVICE_NAME"`
+}
+
+type PasswordReset struct {
+	// TokenTtl is how long a password reset link stays valid, tokens are single-use
+	TokenTtl time.Duration `yaml:"token_ttl" env:"PASSWORD_RESET_TOKEN_TTL"`
+	// Url is the page the reset link points to, the token is appended as the token query parameter
+	Url string `yaml:"url" env:"PASSWORD_RESET_URL"`
+}
+
+type Lockout struct {
+	// Store is postgres (shared by every instance) or memory (single instance only)
+	Store string `yaml:"store" env:"LOCKOUT_STORE"`
+	// AccountThreshold is the number of failed logins on one email before it is locked
+	AccountThreshold int `yaml:"account_threshold" env:"LOCKOUT_ACCOUNT_THRESHOLD"`
+	// IpThreshold is the number of failed logins from one ip, across emails, before it is locked
+	IpThreshold int `yaml:"ip_threshold" env:"LOCKOUT_IP_THRESHOLD"`
+	// BaseDelay is the first lock, it doubles with every further failure up to MaxDelay
+	BaseDelay time.Duration `yaml:"base_delay" env:"LOCKOUT_BASE_DELAY"`
+	MaxDelay  time.Duration `yaml:"max_delay" env:"LOCKOUT_MAX_DELAY"`
+	// ResetAfter forgets failures when no new one happened for this long
+	ResetAfter time.Duration `yaml:"reset_after" env:"LOCKOUT_RESET_AFTER"`
+}
+
+type RateLimit struct {
+	// Enabled applies the per route limits declared in route.Init
+	Enabled bool `yaml:"enabled" env:"RATE_LIMIT_ENABLED"`
+	// Store is postgres (shared by every instance) or memory (limits are per instance)
+	Store string `yaml:"store" env:"RATE_LIMIT_STORE"`
+}
+
+type Totp struct {
+	// Issuer is the account label authenticator apps show next to the email
+	Issuer string `yaml:"issuer" env:"TOTP_ISSUER"`
+	// ChallengeTtl is how long the token returned by a password login stays valid for the second step
+	ChallengeTtl time.Duration `yaml:"challenge_ttl" env:"TOTP_CHALLENGE_TTL"`
+}
+
+type Approval struct {
+	// DualThreshold is the loan amount above which Quorum distinct approvers are needed, smaller loans need one
+	DualThreshold float64 `yaml:"dual_threshold" env:"APPROVAL_DUAL_THRESHOLD"`
+	Quorum        int     `yaml:"quorum" env:"APPROVAL_QUORUM"`
+	// Ttl is how long an approval counts towards the quorum, a loan still pending by then needs it again
+	Ttl time.Duration `yaml:"ttl" env:"APPROVAL_TTL"`
+}
+
+type Payout struct {
+	// Driver is the payout provider disbursing approved loans, only fake exists for now
+	Driver string `yaml:"driver" env:"PAYOUT_DRIVER"`
+	// FakeOutcome is success, failure or delay (payouts succeed FakeDelay after they were submitted)
+	FakeOutcome string        `yaml:"fake_outcome" env:"PAYOUT_FAKE_OUTCOME"`
+	FakeDelay   time.Duration `yaml:"fake_delay" env:"PAYOUT_FAKE_DELAY"`
+}
+
+type Payment struct {
+	// Driver is the payment gateway customers pay their terms through, only mock exists for now
+	Driver string `yaml:"driver" env:"PAYMENT_DRIVER"`
+	// WebhookSecret is shared with the gateway, settlement callbacks are signed with it (HMAC-SHA256)
+	WebhookSecret string `yaml:"webhook_secret" env:"PAYMENT_WEBHOOK_SECRET"`
+	// WebhookTolerance is how far a callback timestamp may be from now, older callbacks are rejected as replays
+	WebhookTolerance time.Duration `yaml:"webhook_tolerance" env:"PAYMENT_WEBHOOK_TOLERANCE"`
+	// IntentTtl is how long the virtual account of a payment intent can be paid
+	IntentTtl time.Duration `yaml:"intent_ttl" env:"PAYMENT_INTENT_TTL"`
+}
+
+type Overdue struct {
+	// GracePeriod is how long after its due date an unpaid term turns OVERDUE and starts accruing late fees
+	GracePeriod time.Duration `yaml:"grace_period" env:"OVERDUE_GRACE_PERIOD"`
+	// FeeType is flat (FeeAmount per FeeInterval) or percent (FeeAmount percent of the term's minimum payment)
+	FeeType     string        `yaml:"fee_type" env:"OVERDUE_FEE_TYPE"`
+	FeeAmount   float64       `yaml:"fee_amount" env:"OVERDUE_FEE_AMOUNT"`
+	FeeInterval time.Duration `yaml:"fee_interval" env:"OVERDUE_FEE_INTERVAL"`
+	// FeeCap bounds the late fees of one term, 0 leaves them uncapped
+	FeeCap float64 `yaml:"fee_cap" env:"OVERDUE_FEE_CAP"`
+	// DefaultAfter is how long past its due date an unpaid term puts the loan in DEFAULTED
+	DefaultAfter time.Duration `yaml:"default_after" env:"OVERDUE_DEFAULT_AFTER"`
+}
+
+type Jobs struct {
+	// InServer runs the job runner inside server too, worker always runs it
+	InServer bool `yaml:"in_server" env:"JOBS_IN_SERVER"`
+	// Store is postgres (instances share the schedule and a run is claimed by one of them) or memory (single instance only)
+	Store string `yaml:"store" env:"JOBS_STORE"`
+	// PollInterval is how often the runner looks for due jobs
+	PollInterval time.Duration `yaml:"poll_interval" env:"JOBS_POLL_INTERVAL"`
+	// Lease is how long a claimed run may take, it is cancelled after that and another instance may claim it again
+	Lease time.Duration `yaml:"lease" env:"JOBS_LEASE"`
+	// MaxAttempts is how often a failing run is tried, BaseBackoff apart and doubling up to MaxBackoff, before the
+	// job waits for its next schedule
+	MaxAttempts int           `yaml:"max_attempts" env:"JOBS_MAX_ATTEMPTS"`
+	BaseBackoff time.Duration `yaml:"base_backoff" env:"JOBS_BASE_BACKOFF"`
+	MaxBackoff  time.Duration `yaml:"max_backoff" env:"JOBS_MAX_BACKOFF"`
+	// MarkOverdue, ExpireLoans, DueReminders, PruneLoginAttempts, PruneRateLimits and ReconcileDisbursements are cron
+	// expressions evaluated in UTC, empty disables the job
+	MarkOverdue            string `yaml:"mark_overdue" env:"JOBS_MARK_OVERDUE"`
+	ExpireLoans            string `yaml:"expire_loans" env:"JOBS_EXPIRE_LOANS"`
+	DueReminders           string `yaml:"due_reminders" env:"JOBS_DUE_REMINDERS"`
+	PruneLoginAttempts     string `yaml:"prune_login_attempts" env:"JOBS_PRUNE_LOGIN_ATTEMPTS"`
+	PruneRateLimits        string `yaml:"prune_rate_limits" env:"JOBS_PRUNE_RATE_LIMITS"`
+	ReconcileDisbursements string `yaml:"reconcile_disbursements" env:"JOBS_RECONCILE_DISBURSEMENTS"`
+	// PendingLoanTtl is how long a loan may wait for approval before it is EXPIRED
+	PendingLoanTtl time.Duration `yaml:"pending_loan_ttl" env:"JOBS_PENDING_LOAN_TTL"`
+	// RemindBefore is how long before its due date the borrower is reminded of a term
+	RemindBefore time.Duration `yaml:"remind_before" env:"JOBS_REMIND_BEFORE"`
+	// ReconcileAfter is how long a payout stays pending before ReconcileDisbursements asks the provider about it
+	ReconcileAfter time.Duration `yaml:"reconcile_after" env:"JOBS_RECONCILE_AFTER"`
 }
 
-func ReadConfig(env string) (cfg Config, err error) {
-	absPath, err := filepath.Abs(fmt.Sprintf("files/%s.yaml", env))
+type Outbox struct {
+	// Sink is where the relay publishes domain events: webhook, file or stdout
+	Sink           string        `yaml:"sink" env:"OUTBOX_SINK"`
+	WebhookUrl     string        `yaml:"webhook_url" env:"OUTBOX_WEBHOOK_URL"`
+	WebhookTimeout time.Duration `yaml:"webhook_timeout" env:"OUTBOX_WEBHOOK_TIMEOUT"`
+	File           string        `yaml:"file" env:"OUTBOX_FILE"`
+	// InWorker runs the relay inside worker too, outbox relay always runs it
+	InWorker bool `yaml:"in_worker" env:"OUTBOX_IN_WORKER"`
+	// PollInterval is how often the relay looks for events once the outbox is drained
+	PollInterval time.Duration `yaml:"poll_interval" env:"OUTBOX_POLL_INTERVAL"`
+	BatchSize    int           `yaml:"batch_size" env:"OUTBOX_BATCH_SIZE"`
+	// MaxAttempts is how often an event is published, BaseBackoff apart and doubling up to MaxBackoff, before it is
+	// dead-lettered
+	MaxAttempts int           `yaml:"max_attempts" env:"OUTBOX_MAX_ATTEMPTS"`
+	BaseBackoff time.Duration `yaml:"base_backoff" env:"OUTBOX_BASE_BACKOFF"`
+	MaxBackoff  time.Duration `yaml:"max_backoff" env:"OUTBOX_MAX_BACKOFF"`
+}
+
+type Webhooks struct {
+	// InWorker runs the dispatcher inside worker too, webhook dispatch always runs it
+	InWorker bool `yaml:"in_worker" env:"WEBHOOKS_IN_WORKER"`
+	// AllowHttp accepts plain http endpoint urls, for receivers on a local network only
+	AllowHttp bool `yaml:"allow_http" env:"WEBHOOKS_ALLOW_HTTP"`
+	// Timeout bounds a delivery, from connecting to reading the response status
+	Timeout      time.Duration `yaml:"timeout" env:"WEBHOOKS_TIMEOUT"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"WEBHOOKS_POLL_INTERVAL"`
+	BatchSize    int           `yaml:"batch_size" env:"WEBHOOKS_BATCH_SIZE"`
+	// MaxAttempts is how often a delivery is tried, BaseBackoff apart and doubling up to MaxBackoff, before it fails
+	MaxAttempts int           `yaml:"max_attempts" env:"WEBHOOKS_MAX_ATTEMPTS"`
+	BaseBackoff time.Duration `yaml:"base_backoff" env:"WEBHOOKS_BASE_BACKOFF"`
+	MaxBackoff  time.Duration `yaml:"max_backoff" env:"WEBHOOKS_MAX_BACKOFF"`
+}
+
+type Notifications struct {
+	// DefaultLocale renders the messages of locales missing from the catalogue
+	DefaultLocale string `yaml:"default_locale" env:"NOTIFICATIONS_DEFAULT_LOCALE"`
+	// Email is smtp, console or file, Sms and Push are http, console or file. none disables a channel, console and
+	// file write the messages to stdout or File for local development
+	Email string `yaml:"email" env:"NOTIFICATIONS_EMAIL"`
+	Sms   string `yaml:"sms" env:"NOTIFICATIONS_SMS"`
+	Push  string `yaml:"push" env:"NOTIFICATIONS_PUSH"`
+	File  string `yaml:"file" env:"NOTIFICATIONS_FILE"`
+	// SmtpHost is dialed on SmtpPort, STARTTLS is used when offered and SmtpUsername authenticates when set
+	SmtpHost     string `yaml:"smtp_host" env:"NOTIFICATIONS_SMTP_HOST"`
+	SmtpPort     int    `yaml:"smtp_port" env:"NOTIFICATIONS_SMTP_PORT"`
+	SmtpUsername string `yaml:"smtp_username" env:"NOTIFICATIONS_SMTP_USERNAME"`
+	SmtpPassword string `yaml:"smtp_password" env:"NOTIFICATIONS_SMTP_PASSWORD"`
+	SmtpFrom     string `yaml:"smtp_from" env:"NOTIFICATIONS_SMTP_FROM"`
+	// SmsUrl and PushUrl are the provider endpoints of the http drivers, their token is sent as a bearer token
+	SmsUrl    string `yaml:"sms_url" env:"NOTIFICATIONS_SMS_URL"`
+	SmsToken  string `yaml:"sms_token" env:"NOTIFICATIONS_SMS_TOKEN"`
+	PushUrl   string `yaml:"push_url" env:"NOTIFICATIONS_PUSH_URL"`
+	PushToken string `yaml:"push_token" env:"NOTIFICATIONS_PUSH_TOKEN"`
+	// Timeout bounds sending one message
+	Timeout time.Duration `yaml:"timeout" env:"NOTIFICATIONS_TIMEOUT"`
+	// InWorker runs the dispatcher inside worker too, notification dispatch always runs it
+	InWorker     bool          `yaml:"in_worker" env:"NOTIFICATIONS_IN_WORKER"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"NOTIFICATIONS_POLL_INTERVAL"`
+	BatchSize    int           `yaml:"batch_size" env:"NOTIFICATIONS_BATCH_SIZE"`
+	// MaxAttempts is how often a notification is sent, BaseBackoff apart and doubling up to MaxBackoff, before it
+	// fails
+	MaxAttempts int           `yaml:"max_attempts" env:"NOTIFICATIONS_MAX_ATTEMPTS"`
+	BaseBackoff time.Duration `yaml:"base_backoff" env:"NOTIFICATIONS_BASE_BACKOFF"`
+	MaxBackoff  time.Duration `yaml:"max_backoff" env:"NOTIFICATIONS_MAX_BACKOFF"`
+}
+
+// Options controls where Load reads configuration from.
+type Options struct {
+	// Path is an explicit config file, it must exist when set
+	Path string
+	// Env reads files/<env>.yaml when Path is empty, a missing file is not an error
+	Env string
+	// LookupEnv resolves environment variables, defaults to os.LookupEnv
+	LookupEnv func(key string) (string, bool)
+}
+
+// Default returns the configuration used before any file or environment variable is applied.
+func Default() Config {
+	return Config{
+		ServerAddress:   ":8000",
+		MetricsAddress:  "localhost:9090",
+		ShutdownTimeout: 15 * time.Second,
+		Log: Log{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: Tracing{
+			Exporter:     "none",
+			OtlpEndpoint: "localhost:4318",
+			SampleRatio:  1,
+			ServiceName:  "mini-aspire",
+		},
+		Password: Password{
+			MinLength: 10,
+			MaxLength: 72,
+		},
+		Mailer: Mailer{
+			Driver: "log",
+			From:   "no-reply@mini-aspire.local",
+		},
+		Verification: Verification{
+			TokenTtl: 24 * time.Hour,
+			Url:      "http://localhost:3000/verify-email",
+		},
+		PasswordReset: PasswordReset{
+			TokenTtl: time.Hour,
+			Url:      "http://localhost:3000/reset-password",
+		},
+		Lockout: Lockout{
+			Store:            "postgres",
+			AccountThreshold: 5,
+			IpThreshold:      20,
+			BaseDelay:        time.Second,
+			MaxDelay:         15 * time.Minute,
+			ResetAfter:       time.Hour,
+		},
+		RateLimit: RateLimit{
+			Enabled: true,
+			Store:   "postgres",
+		},
+		Totp: Totp{
+			Issuer:       "mini-aspire",
+			ChallengeTtl: 5 * time.Minute,
+		},
+		Approval: Approval{
+			DualThreshold: 5_000_000,
+			Quorum:        2,
+			Ttl:           72 * time.Hour,
+		},
+		Payout: Payout{
+			Driver:      "fake",
+			FakeOutcome: "success",
+			FakeDelay:   30 * time.Second,
+		},
+		Payment: Payment{
+			Driver:           "mock",
+			WebhookTolerance: 5 * time.Minute,
+			IntentTtl:        24 * time.Hour,
+		},
+		Overdue: Overdue{
+			GracePeriod:  72 * time.Hour,
+			FeeType:      "percent",
+			FeeAmount:    2,
+			FeeInterval:  7 * 24 * time.Hour,
+			FeeCap:       500_000,
+			DefaultAfter: 90 * 24 * time.Hour,
+		},
+		Jobs: Jobs{
+			Store:                  "postgres",
+			PollInterval:           10 * time.Second,
+			Lease:                  10 * time.Minute,
+			MaxAttempts:            5,
+			BaseBackoff:            30 * time.Second,
+			MaxBackoff:             30 * time.Minute,
+			MarkOverdue:            "@hourly",
+			ExpireLoans:            "*/15 * * * *",
+			DueReminders:           "0 8 * * *",
+			PruneLoginAttempts:     "30 * * * *",
+			PruneRateLimits:        "45 * * * *",
+			ReconcileDisbursements: "*/10 * * * *",
+			PendingLoanTtl:         30 * 24 * time.Hour,
+			RemindBefore:           72 * time.Hour,
+			ReconcileAfter:         10 * time.Minute,
+		},
+		Outbox: Outbox{
+			Sink:           "stdout",
+			WebhookTimeout: 10 * time.Second,
+			InWorker:       true,
+			PollInterval:   time.Second,
+			BatchSize:      100,
+			MaxAttempts:    10,
+			BaseBackoff:    5 * time.Second,
+			MaxBackoff:     10 * time.Minute,
+		},
+		Webhooks: Webhooks{
+			InWorker:     true,
+			Timeout:      10 * time.Second,
+			PollInterval: time.Second,
+			BatchSize:    50,
+			MaxAttempts:  8,
+			BaseBackoff:  30 * time.Second,
+			MaxBackoff:   6 * time.Hour,
+		},
+		Notifications: Notifications{
+			DefaultLocale: "en",
+			Email:         "console",
+			Sms:           "none",
+			Push:          "none",
+			SmtpPort:      587,
+			SmtpFrom:      "no-reply@mini-aspire.local",
+			Timeout:       10 * time.Second,
+			InWorker:      true,
+			PollInterval:  time.Second,
+			BatchSize:     50,
+			MaxAttempts:   5,
+			BaseBackoff:   30 * time.Second,
+			MaxBackoff:    time.Hour,
+		},
+		PostgresDb: PostgresDb{
+			Host:            "localhost",
+			Port:            5432,
+			SslMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnectTimeout:  5 * time.Second,
+			ConnectRetries:  5,
+		},
+	}
+}
+
+// Load builds the configuration from defaults, the YAML file and APP_* environment variables (in that order)
+// and validates the result.
+func Load(opts Options) (cfg Config, err error) {
+	cfg = Default()
+
+	switch {
+	case opts.Path != "":
+		err = readFile(opts.Path, &cfg)
+	case opts.Env != "":
+		err = readFile(fmt.Sprintf("files/%s.yaml", opts.Env), &cfg)
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	lookup := opts.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	err = applyEnv(&cfg, lookup)
+	if err != nil {
+		return
+	}
+
+	err = cfg.Validate()
+
+	return
+}
+
+func readFile(path string, cfg *Config) (err error) {
+	absPath, err := filepath.Abs(path)
 	if err != nil {
 		return
 	}
@@ -35,7 +462,10 @@ func ReadConfig(env string) (cfg Config, err error) {
 
 	d := yaml.NewDecoder(file)
 
-	err = d.Decode(&cfg)
+	err = d.Decode(cfg)
+	if err != nil {
+		err = fmt.Errorf("decode %s: %w", path, err)
+	}
 
 	return
 }
@@ -0,0 +1,463 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_Load(t *testing.T) {
+	dir := t.TempDir()
+
+	cfgPath := filepath.Join(dir, "tes.yaml")
+	os.WriteFile(cfgPath, []byte("server_address: :9000\njwt_secret: yaml\ndb:\n  host: db\n  user: postgres\n  password: yaml\n  dbname: tes\npayment:\n  webhook_secret: yaml-webhook-secret-of-32-characters\n"), 0600)
+
+	secretPath := filepath.Join(dir, "secret")
+	os.WriteFile(secretPath, []byte("from-file\n"), 0600)
+
+	valid := Config{
+		ServerAddress:   ":9000",
+		MetricsAddress:  "localhost:9090",
+		JwtSecret:       "yaml",
+		ShutdownTimeout: 15 * time.Second,
+		Log: Log{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: Tracing{
+			Exporter:     "none",
+			OtlpEndpoint: "localhost:4318",
+			SampleRatio:  1,
+			ServiceName:  "mini-aspire",
+		},
+		Password: Password{
+			MinLength: 10,
+			MaxLength: 72,
+		},
+		Mailer: Mailer{
+			Driver: "log",
+			From:   "no-reply@mini-aspire.local",
+		},
+		Verification: Verification{
+			TokenTtl: 24 * time.Hour,
+			Url:      "http://localhost:3000/verify-email",
+		},
+		PasswordReset: PasswordReset{
+			TokenTtl: time.Hour,
+			Url:      "http://localhost:3000/reset-password",
+		},
+		Lockout: Lockout{
+			Store:            "postgres",
+			AccountThreshold: 5,
+			IpThreshold:      20,
+			BaseDelay:        time.Second,
+			MaxDelay:         15 * time.Minute,
+			ResetAfter:       time.Hour,
+		},
+		RateLimit: RateLimit{
+			Enabled: true,
+			Store:   "postgres",
+		},
+		Totp: Totp{
+			Issuer:       "mini-aspire",
+			ChallengeTtl: 5 * time.Minute,
+		},
+		Approval: Approval{
+			DualThreshold: 5_000_000,
+			Quorum:        2,
+			Ttl:           72 * time.Hour,
+		},
+		Payout: Payout{
+			Driver:      "fake",
+			FakeOutcome: "success",
+			FakeDelay:   30 * time.Second,
+		},
+		Payment: Payment{
+			Driver:           "mock",
+			WebhookSecret:    "yaml-webhook-secret-of-32-characters",
+			WebhookTolerance: 5 * time.Minute,
+			IntentTtl:        24 * time.Hour,
+		},
+		Overdue: Overdue{
+			GracePeriod:  72 * time.Hour,
+			FeeType:      "percent",
+			FeeAmount:    2,
+			FeeInterval:  7 * 24 * time.Hour,
+			FeeCap:       500_000,
+			DefaultAfter: 90 * 24 * time.Hour,
+		},
+		Jobs: Jobs{
+			Store:                  "postgres",
+			PollInterval:           10 * time.Second,
+			Lease:                  10 * time.Minute,
+			MaxAttempts:            5,
+			BaseBackoff:            30 * time.Second,
+			MaxBackoff:             30 * time.Minute,
+			MarkOverdue:            "@hourly",
+			ExpireLoans:            "*/15 * * * *",
+			DueReminders:           "0 8 * * *",
+			PruneLoginAttempts:     "30 * * * *",
+			PruneRateLimits:        "45 * * * *",
+			ReconcileDisbursements: "*/10 * * * *",
+			PendingLoanTtl:         30 * 24 * time.Hour,
+			RemindBefore:           72 * time.Hour,
+			ReconcileAfter:         10 * time.Minute,
+		},
+		Outbox: Outbox{
+			Sink:           "stdout",
+			WebhookTimeout: 10 * time.Second,
+			InWorker:       true,
+			PollInterval:   time.Second,
+			BatchSize:      100,
+			MaxAttempts:    10,
+			BaseBackoff:    5 * time.Second,
+			MaxBackoff:     10 * time.Minute,
+		},
+		Webhooks: Webhooks{
+			InWorker:     true,
+			Timeout:      10 * time.Second,
+			PollInterval: time.Second,
+			BatchSize:    50,
+			MaxAttempts:  8,
+			BaseBackoff:  30 * time.Second,
+			MaxBackoff:   6 * time.Hour,
+		},
+		Notifications: Notifications{
+			DefaultLocale: "en",
+			Email:         "console",
+			Sms:           "none",
+			Push:          "none",
+			SmtpPort:      587,
+			SmtpFrom:      "no-reply@mini-aspire.local",
+			Timeout:       10 * time.Second,
+			InWorker:      true,
+			PollInterval:  time.Second,
+			BatchSize:     50,
+			MaxAttempts:   5,
+			BaseBackoff:   30 * time.Second,
+			MaxBackoff:    time.Hour,
+		},
+		PostgresDb: PostgresDb{
+			Host:     "db",
+			Port:     5432,
+			User:     "postgres",
+			Password: "yaml",
+			Dbname:   "tes",
+
+			SslMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnectTimeout:  5 * time.Second,
+			ConnectRetries:  5,
+		},
+	}
+
+	tests := []struct {
+		name    string
+		opts    Options
+		env     map[string]string
+		want    Config
+		wantErr bool
+		check   func(t *testing.T, err error)
+	}{
+		{
+			name: "yaml with defaults",
+			opts: Options{Path: cfgPath},
+			want: valid,
+		},
+		{
+			name:    "explicit path not found",
+			opts:    Options{Path: filepath.Join(dir, "missing.yaml")},
+			wantErr: true,
+			check: func(t *testing.T, err error) {
+				if !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("want not exist error, got %v", err)
+				}
+			},
+		},
+		{
+			name: "env override",
+			opts: Options{Path: cfgPath},
+			env: map[string]string{
+				"APP_DB_PASSWORD":          "env",
+				"APP_DB_PORT":              "6432",
+				"APP_SERVER_ADDRESS":       ":7000",
+				"APP_DB_SSLMODE":           "verify-full",
+				"APP_DB_CONN_MAX_LIFETIME": "1h",
+			},
+			want: func() Config {
+				c := valid
+				c.PostgresDb.Password = "env"
+				c.PostgresDb.Port = 6432
+				c.PostgresDb.SslMode = "verify-full"
+				c.PostgresDb.ConnMaxLifetime = time.Hour
+				c.ServerAddress = ":7000"
+				return c
+			}(),
+		},
+		{
+			name: "env file override",
+			opts: Options{Path: cfgPath},
+			env: map[string]string{
+				"APP_JWT_SECRET_FILE": secretPath,
+			},
+			want: func() Config {
+				c := valid
+				c.JwtSecret = "from-file"
+				return c
+			}(),
+		},
+		{
+			name: "env and env file both set",
+			opts: Options{Path: cfgPath},
+			env: map[string]string{
+				"APP_JWT_SECRET":      "env",
+				"APP_JWT_SECRET_FILE": secretPath,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid env value",
+			opts: Options{Path: cfgPath},
+			env: map[string]string{
+				"APP_DB_PORT": "tes",
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing env file falls back to environment variables",
+			opts: Options{Env: filepath.Join(dir, "missing")},
+			env: map[string]string{
+				"APP_JWT_SECRET":             "env",
+				"APP_PAYMENT_WEBHOOK_SECRET": "env-webhook-secret-of-32-characters",
+				"APP_DB_USER":                "postgres",
+				"APP_DB_NAME":                "tes",
+			},
+			want: func() Config {
+				c := Default()
+				c.JwtSecret = "env"
+				c.Payment.WebhookSecret = "env-webhook-secret-of-32-characters"
+				c.PostgresDb.User = "postgres"
+				c.PostgresDb.Dbname = "tes"
+				return c
+			}(),
+		},
+		{
+			name: "validation errors",
+			opts: Options{Path: cfgPath},
+			env: map[string]string{
+				"APP_JWT_SECRET":        "",
+				"APP_SERVER_ADDRESS":    ":0",
+				"APP_METRICS_ADDRESS":   "9090",
+				"APP_DB_MAX_OPEN_CONNS": "5",
+				"APP_DB_SSLMODE":        "tes",
+			},
+			wantErr: true,
+			check: func(t *testing.T, err error) {
+				var vErr ValidationError
+				if !errors.As(err, &vErr) {
+					t.Fatalf("want ValidationError, got %v", err)
+				}
+				want := ValidationError{
+					{Field: "server_address", Message: "port must be between 1 and 65535"},
+					{Field: "metrics_address", Message: "must be empty or in host:port form"},
+					{Field: "jwt_secret", Message: "is required (APP_JWT_SECRET)"},
+					{Field: "db.sslmode", Message: "must be one of disable, allow, prefer, require, verify-ca, verify-full"},
+					{Field: "db.max_idle_conns", Message: "must not exceed db.max_open_conns"},
+				}
+				if !reflect.DeepEqual(vErr, want) {
+					t.Errorf("want %+v, got %+v", want, vErr)
+				}
+			},
+		},
+		{
+			name: "password, mailer and verification validation errors",
+			opts: Options{Path: cfgPath},
+			env: map[string]string{
+				"APP_PASSWORD_MIN_LENGTH":    "6",
+				"APP_PASSWORD_MAX_LENGTH":    "100",
+				"APP_MAILER_DRIVER":          "file",
+				"APP_VERIFICATION_TOKEN_TTL": "1s",
+				"APP_VERIFICATION_URL":       "/user/verify",
+			},
+			wantErr: true,
+			check: func(t *testing.T, err error) {
+				var vErr ValidationError
+				if !errors.As(err, &vErr) {
+					t.Fatalf("want ValidationError, got %v", err)
+				}
+				want := ValidationError{
+					{Field: "password.min_length", Message: "must be at least 8"},
+					{Field: "password.max_length", Message: "must not exceed 72, bcrypt truncates longer passwords"},
+					{Field: "mailer.file", Message: "is required with the file driver (APP_MAILER_FILE)"},
+					{Field: "verification.token_ttl", Message: "must be at least 1m"},
+					{Field: "verification.url", Message: "must be an absolute url"},
+				}
+				if !reflect.DeepEqual(vErr, want) {
+					t.Errorf("want %+v, got %+v", want, vErr)
+				}
+			},
+		},
+		{
+			name: "lockout, rate limit, totp, approval, payout, payment, overdue, jobs, outbox, webhooks and notifications validation errors",
+			opts: Options{Path: cfgPath},
+			env: map[string]string{
+				"APP_LOCKOUT_STORE":                "redis",
+				"APP_LOCKOUT_ACCOUNT_THRESHOLD":    "0",
+				"APP_LOCKOUT_MAX_DELAY":            "500ms",
+				"APP_RATE_LIMIT_STORE":             "redis",
+				"APP_TOTP_ISSUER":                  "mini:aspire",
+				"APP_TOTP_CHALLENGE_TTL":           "1h",
+				"APP_APPROVAL_DUAL_THRESHOLD":      "-1",
+				"APP_APPROVAL_QUORUM":              "1",
+				"APP_APPROVAL_TTL":                 "30m",
+				"APP_PAYOUT_DRIVER":                "bank",
+				"APP_PAYOUT_FAKE_OUTCOME":          "maybe",
+				"APP_PAYOUT_FAKE_DELAY":            "-1s",
+				"APP_PAYMENT_DRIVER":               "stripe",
+				"APP_PAYMENT_WEBHOOK_SECRET":       "short",
+				"APP_PAYMENT_WEBHOOK_TOLERANCE":    "1h",
+				"APP_PAYMENT_INTENT_TTL":           "1m",
+				"APP_OVERDUE_GRACE_PERIOD":         "-1h",
+				"APP_OVERDUE_FEE_TYPE":             "daily",
+				"APP_OVERDUE_FEE_AMOUNT":           "-5",
+				"APP_OVERDUE_FEE_INTERVAL":         "30m",
+				"APP_OVERDUE_FEE_CAP":              "-1",
+				"APP_OVERDUE_DEFAULT_AFTER":        "-2h",
+				"APP_JOBS_STORE":                   "redis",
+				"APP_JOBS_POLL_INTERVAL":           "10ms",
+				"APP_JOBS_LEASE":                   "1s",
+				"APP_JOBS_MAX_ATTEMPTS":            "0",
+				"APP_JOBS_BASE_BACKOFF":            "1m",
+				"APP_JOBS_MAX_BACKOFF":             "30s",
+				"APP_JOBS_MARK_OVERDUE":            "every hour",
+				"APP_JOBS_EXPIRE_LOANS":            "0 0 30 2 *",
+				"APP_JOBS_DUE_REMINDERS":           "0 25 * * *",
+				"APP_JOBS_PENDING_LOAN_TTL":        "1m",
+				"APP_JOBS_REMIND_BEFORE":           "0s",
+				"APP_JOBS_RECONCILE_AFTER":         "1s",
+				"APP_OUTBOX_SINK":                  "webhook",
+				"APP_OUTBOX_WEBHOOK_URL":           "/events",
+				"APP_OUTBOX_WEBHOOK_TIMEOUT":       "100ms",
+				"APP_OUTBOX_POLL_INTERVAL":         "1ms",
+				"APP_OUTBOX_BATCH_SIZE":            "0",
+				"APP_OUTBOX_MAX_ATTEMPTS":          "0",
+				"APP_OUTBOX_BASE_BACKOFF":          "1ms",
+				"APP_OUTBOX_MAX_BACKOFF":           "0s",
+				"APP_WEBHOOKS_TIMEOUT":             "10ms",
+				"APP_WEBHOOKS_POLL_INTERVAL":       "0s",
+				"APP_WEBHOOKS_BATCH_SIZE":          "5000",
+				"APP_WEBHOOKS_MAX_ATTEMPTS":        "0",
+				"APP_WEBHOOKS_BASE_BACKOFF":        "10ms",
+				"APP_WEBHOOKS_MAX_BACKOFF":         "1ms",
+				"APP_NOTIFICATIONS_DEFAULT_LOCALE": "fr",
+				"APP_NOTIFICATIONS_EMAIL":          "smtp",
+				"APP_NOTIFICATIONS_SMTP_PORT":      "0",
+				"APP_NOTIFICATIONS_SMTP_FROM":      "nobody",
+				"APP_NOTIFICATIONS_SMS":            "http",
+				"APP_NOTIFICATIONS_SMS_URL":        "sms.tes/send",
+				"APP_NOTIFICATIONS_PUSH":           "file",
+				"APP_NOTIFICATIONS_TIMEOUT":        "1ms",
+				"APP_NOTIFICATIONS_POLL_INTERVAL":  "1ms",
+				"APP_NOTIFICATIONS_BATCH_SIZE":     "0",
+				"APP_NOTIFICATIONS_MAX_ATTEMPTS":   "0",
+				"APP_NOTIFICATIONS_BASE_BACKOFF":   "1ms",
+				"APP_NOTIFICATIONS_MAX_BACKOFF":    "0s",
+			},
+			wantErr: true,
+			check: func(t *testing.T, err error) {
+				var vErr ValidationError
+				if !errors.As(err, &vErr) {
+					t.Fatalf("want ValidationError, got %v", err)
+				}
+				want := ValidationError{
+					{Field: "lockout.store", Message: "must be postgres or memory"},
+					{Field: "lockout.account_threshold", Message: "must be at least 1"},
+					{Field: "lockout.max_delay", Message: "must not be lower than lockout.base_delay"},
+					{Field: "rate_limit.store", Message: "must be postgres or memory"},
+					{Field: "totp.issuer", Message: "is required and must not contain a colon"},
+					{Field: "totp.challenge_ttl", Message: "must be between 1m and 15m"},
+					{Field: "approval.dual_threshold", Message: "must not be negative"},
+					{Field: "approval.quorum", Message: "must be between 2 and 5"},
+					{Field: "approval.ttl", Message: "must be at least 1h"},
+					{Field: "payout.driver", Message: "must be fake"},
+					{Field: "payout.fake_outcome", Message: "must be success, failure or delay"},
+					{Field: "payout.fake_delay", Message: "must not be negative"},
+					{Field: "payment.driver", Message: "must be mock"},
+					{Field: "payment.webhook_secret", Message: "is required and must be at least 32 characters (APP_PAYMENT_WEBHOOK_SECRET)"},
+					{Field: "payment.webhook_tolerance", Message: "must be between 1m and 15m"},
+					{Field: "payment.intent_ttl", Message: "must be at least 15m"},
+					{Field: "overdue.grace_period", Message: "must not be negative"},
+					{Field: "overdue.fee_type", Message: "must be flat or percent"},
+					{Field: "overdue.fee_amount", Message: "must not be negative, nor above 100 for percent fees"},
+					{Field: "overdue.fee_interval", Message: "must be at least 1h"},
+					{Field: "overdue.fee_cap", Message: "must not be negative"},
+					{Field: "overdue.default_after", Message: "must be longer than grace_period"},
+					{Field: "jobs.store", Message: "must be postgres or memory"},
+					{Field: "jobs.poll_interval", Message: "must be at least 1s"},
+					{Field: "jobs.lease", Message: "must be at least 1m"},
+					{Field: "jobs.max_attempts", Message: "must be at least 1"},
+					{Field: "jobs.max_backoff", Message: "must not be lower than jobs.base_backoff"},
+					{Field: "jobs.mark_overdue", Message: `must be empty or a cron expression, cron expression "every hour" must have 5 fields`},
+					{Field: "jobs.expire_loans", Message: "must match a time within five years"},
+					{Field: "jobs.due_reminders", Message: `must be empty or a cron expression, hour must be between 0 and 23, got "25"`},
+					{Field: "jobs.pending_loan_ttl", Message: "must be at least 1h"},
+					{Field: "jobs.remind_before", Message: "must be at least 1h"},
+					{Field: "jobs.reconcile_after", Message: "must be at least 1m"},
+					{Field: "outbox.webhook_url", Message: "must be an absolute url when sink is webhook"},
+					{Field: "outbox.webhook_timeout", Message: "must be at least 1s"},
+					{Field: "outbox.poll_interval", Message: "must be at least 100ms"},
+					{Field: "outbox.batch_size", Message: "must be between 1 and 1000"},
+					{Field: "outbox.max_attempts", Message: "must be at least 1"},
+					{Field: "outbox.base_backoff", Message: "must be at least 1s"},
+					{Field: "outbox.max_backoff", Message: "must not be lower than outbox.base_backoff"},
+					{Field: "webhooks.timeout", Message: "must be at least 1s"},
+					{Field: "webhooks.poll_interval", Message: "must be at least 100ms"},
+					{Field: "webhooks.batch_size", Message: "must be between 1 and 1000"},
+					{Field: "webhooks.max_attempts", Message: "must be at least 1"},
+					{Field: "webhooks.base_backoff", Message: "must be at least 1s"},
+					{Field: "webhooks.max_backoff", Message: "must not be lower than webhooks.base_backoff"},
+					{Field: "notifications.default_locale", Message: "must be one of en, id"},
+					{Field: "notifications.smtp_host", Message: "is required with the smtp driver (APP_NOTIFICATIONS_SMTP_HOST)"},
+					{Field: "notifications.smtp_port", Message: "must be between 1 and 65535"},
+					{Field: "notifications.smtp_from", Message: "must be a valid email address"},
+					{Field: "notifications.sms_url", Message: "must be an absolute url with the http driver"},
+					{Field: "notifications.file", Message: "is required when a channel uses the file driver"},
+					{Field: "notifications.timeout", Message: "must be at least 1s"},
+					{Field: "notifications.poll_interval", Message: "must be at least 100ms"},
+					{Field: "notifications.batch_size", Message: "must be between 1 and 1000"},
+					{Field: "notifications.max_attempts", Message: "must be at least 1"},
+					{Field: "notifications.base_backoff", Message: "must be at least 1s"},
+					{Field: "notifications.max_backoff", Message: "must not be lower than notifications.base_backoff"},
+				}
+				if !reflect.DeepEqual(vErr, want) {
+					t.Errorf("want %+v, got %+v", want, vErr)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.LookupEnv = func(key string) (string, bool) {
+				v, ok := tt.env[key]
+				return v, ok
+			}
+
+			got, err := Load(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load test failed. wantErr: %v, gotErr: %v", tt.wantErr, err)
+			}
+			if tt.check != nil {
+				tt.check(t, err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Load test failed. want: %+v, got: %+v", tt.want, got)
+			}
+		})
+	}
+}
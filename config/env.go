@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix is prepended to every `env` struct tag.
+const EnvPrefix = "APP_"
+
+// applyEnv overrides every field tagged with `env:"NAME"` from APP_NAME, or from the content of the file
+// referenced by APP_NAME_FILE (docker / kubernetes secrets).
+func applyEnv(cfg *Config, lookup func(string) (string, bool)) error {
+	return applyEnvStruct(reflect.ValueOf(cfg).Elem(), lookup)
+}
+
+func applyEnvStruct(v reflect.Value, lookup func(string) (string, bool)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		tag := t.Field(i).Tag.Get("env")
+
+		if tag == "" {
+			if field.Kind() == reflect.Struct {
+				err := applyEnvStruct(field, lookup)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		key := EnvPrefix + tag
+		value, ok, err := lookupEnv(key, lookup)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		err = setField(field, value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+func lookupEnv(key string, lookup func(string) (string, bool)) (value string, ok bool, err error) {
+	value, ok = lookup(key)
+	path, fileOk := lookup(key + "_FILE")
+	if ok && fileOk {
+		err = fmt.Errorf("both %s and %s_FILE are set", key, key)
+		return
+	}
+	if !fileOk {
+		return
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		err = fmt.Errorf("%s_FILE: %w", key, err)
+		return
+	}
+
+	return strings.TrimSpace(string(content)), true, nil
+}
+
+func setField(field reflect.Value, value string) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported config type %s", field.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,468 @@
+package config
+
+import (
+	"net"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/m/v2/constant"
+	"example.com/m/v2/cron"
+)
+
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// ValidationError lists every invalid field found by Config.Validate.
+type ValidationError []FieldError
+
+func (v ValidationError) Error() string {
+	msgs := make([]string, 0, len(v))
+	for _, f := range v {
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+	return "invalid config: " + strings.Join(msgs, "; ")
+}
+
+func (v *ValidationError) add(field, message string) {
+	*v = append(*v, FieldError{Field: field, Message: message})
+}
+
+func (c Config) Validate() error {
+	var errs ValidationError
+
+	if _, port, err := net.SplitHostPort(c.ServerAddress); err != nil {
+		errs.add("server_address", "must be in host:port form")
+	} else if !validPort(port) {
+		errs.add("server_address", "port must be between 1 and 65535")
+	}
+	if c.MetricsAddress != "" {
+		if _, port, err := net.SplitHostPort(c.MetricsAddress); err != nil {
+			errs.add("metrics_address", "must be empty or in host:port form")
+		} else if !validPort(port) {
+			errs.add("metrics_address", "port must be between 1 and 65535")
+		} else if c.MetricsAddress == c.ServerAddress {
+			errs.add("metrics_address", "must differ from server_address")
+		}
+	}
+
+	if c.ShutdownTimeout < 0 {
+		errs.add("shutdown_timeout", "must not be negative")
+	}
+
+	if !logLevels[strings.ToLower(c.Log.Level)] {
+		errs.add("log.level", "must be one of debug, info, warn, error")
+	}
+	if !logFormats[strings.ToLower(c.Log.Format)] {
+		errs.add("log.format", "must be json or text")
+	}
+
+	if !tracingExporters[c.Tracing.Exporter] {
+		errs.add("tracing.exporter", "must be one of none, stdout, otlp")
+	}
+	if c.Tracing.Exporter == "otlp" && c.Tracing.OtlpEndpoint == "" {
+		errs.add("tracing.otlp_endpoint", "is required with the otlp exporter (APP_TRACING_OTLP_ENDPOINT)")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs.add("tracing.sample_ratio", "must be between 0 and 1")
+	}
+
+	if c.Password.MinLength < 8 {
+		errs.add("password.min_length", "must be at least 8")
+	}
+	if c.Password.MaxLength > 72 {
+		errs.add("password.max_length", "must not exceed 72, bcrypt truncates longer passwords")
+	} else if c.Password.MaxLength < c.Password.MinLength {
+		errs.add("password.max_length", "must not be lower than password.min_length")
+	}
+
+	if !mailerDrivers[c.Mailer.Driver] {
+		errs.add("mailer.driver", "must be log or file")
+	}
+	if c.Mailer.Driver == "file" && c.Mailer.File == "" {
+		errs.add("mailer.file", "is required with the file driver (APP_MAILER_FILE)")
+	}
+	if _, err := mail.ParseAddress(c.Mailer.From); err != nil {
+		errs.add("mailer.from", "must be a valid email address")
+	}
+
+	if c.Verification.TokenTtl < time.Minute {
+		errs.add("verification.token_ttl", "must be at least 1m")
+	}
+	if u, err := url.Parse(c.Verification.Url); err != nil || u.Scheme == "" || u.Host == "" {
+		errs.add("verification.url", "must be an absolute url")
+	}
+
+	if c.PasswordReset.TokenTtl < time.Minute {
+		errs.add("password_reset.token_ttl", "must be at least 1m")
+	}
+	if u, err := url.Parse(c.PasswordReset.Url); err != nil || u.Scheme == "" || u.Host == "" {
+		errs.add("password_reset.url", "must be an absolute url")
+	}
+
+	if !lockoutStores[c.Lockout.Store] {
+		errs.add("lockout.store", "must be postgres or memory")
+	}
+	if c.Lockout.AccountThreshold < 1 {
+		errs.add("lockout.account_threshold", "must be at least 1")
+	}
+	if c.Lockout.IpThreshold < 1 {
+		errs.add("lockout.ip_threshold", "must be at least 1")
+	}
+	if c.Lockout.BaseDelay < time.Second {
+		errs.add("lockout.base_delay", "must be at least 1s")
+	}
+	if c.Lockout.MaxDelay < c.Lockout.BaseDelay {
+		errs.add("lockout.max_delay", "must not be lower than lockout.base_delay")
+	}
+	if c.Lockout.ResetAfter < c.Lockout.MaxDelay {
+		errs.add("lockout.reset_after", "must not be lower than lockout.max_delay")
+	}
+
+	if !rateLimitStores[c.RateLimit.Store] {
+		errs.add("rate_limit.store", "must be postgres or memory")
+	}
+
+	if c.Totp.Issuer == "" || strings.Contains(c.Totp.Issuer, ":") {
+		errs.add("totp.issuer", "is required and must not contain a colon")
+	}
+	if c.Totp.ChallengeTtl < time.Minute || c.Totp.ChallengeTtl > 15*time.Minute {
+		errs.add("totp.challenge_ttl", "must be between 1m and 15m")
+	}
+
+	if c.Approval.DualThreshold < 0 {
+		errs.add("approval.dual_threshold", "must not be negative")
+	}
+	if c.Approval.Quorum < 2 || c.Approval.Quorum > 5 {
+		errs.add("approval.quorum", "must be between 2 and 5")
+	}
+	if c.Approval.Ttl < time.Hour {
+		errs.add("approval.ttl", "must be at least 1h")
+	}
+
+	if !payoutDrivers[c.Payout.Driver] {
+		errs.add("payout.driver", "must be fake")
+	}
+	if !fakePayoutOutcomes[c.Payout.FakeOutcome] {
+		errs.add("payout.fake_outcome", "must be success, failure or delay")
+	}
+	if c.Payout.FakeDelay < 0 {
+		errs.add("payout.fake_delay", "must not be negative")
+	}
+
+	if !paymentDrivers[c.Payment.Driver] {
+		errs.add("payment.driver", "must be mock")
+	}
+	if len(c.Payment.WebhookSecret) < 32 {
+		errs.add("payment.webhook_secret", "is required and must be at least 32 characters (APP_PAYMENT_WEBHOOK_SECRET)")
+	}
+	if c.Payment.WebhookTolerance < time.Minute || c.Payment.WebhookTolerance > 15*time.Minute {
+		errs.add("payment.webhook_tolerance", "must be between 1m and 15m")
+	}
+	if c.Payment.IntentTtl < 15*time.Minute {
+		errs.add("payment.intent_ttl", "must be at least 15m")
+	}
+
+	if c.Overdue.GracePeriod < 0 {
+		errs.add("overdue.grace_period", "must not be negative")
+	}
+	if !lateFeeTypes[c.Overdue.FeeType] {
+		errs.add("overdue.fee_type", "must be flat or percent")
+	}
+	if c.Overdue.FeeAmount < 0 || (c.Overdue.FeeType == "percent" && c.Overdue.FeeAmount > 100) {
+		errs.add("overdue.fee_amount", "must not be negative, nor above 100 for percent fees")
+	}
+	if c.Overdue.FeeInterval < time.Hour {
+		errs.add("overdue.fee_interval", "must be at least 1h")
+	}
+	if c.Overdue.FeeCap < 0 {
+		errs.add("overdue.fee_cap", "must not be negative")
+	}
+	if c.Overdue.DefaultAfter <= c.Overdue.GracePeriod {
+		errs.add("overdue.default_after", "must be longer than grace_period")
+	}
+
+	if !jobStores[c.Jobs.Store] {
+		errs.add("jobs.store", "must be postgres or memory")
+	}
+	if c.Jobs.PollInterval < time.Second {
+		errs.add("jobs.poll_interval", "must be at least 1s")
+	}
+	if c.Jobs.Lease < time.Minute {
+		errs.add("jobs.lease", "must be at least 1m")
+	}
+	if c.Jobs.MaxAttempts < 1 {
+		errs.add("jobs.max_attempts", "must be at least 1")
+	}
+	if c.Jobs.BaseBackoff < time.Second {
+		errs.add("jobs.base_backoff", "must be at least 1s")
+	}
+	if c.Jobs.MaxBackoff < c.Jobs.BaseBackoff {
+		errs.add("jobs.max_backoff", "must not be lower than jobs.base_backoff")
+	}
+	for _, schedule := range []struct{ field, expr string }{
+		{"jobs.mark_overdue", c.Jobs.MarkOverdue},
+		{"jobs.expire_loans", c.Jobs.ExpireLoans},
+		{"jobs.due_reminders", c.Jobs.DueReminders},
+		{"jobs.prune_login_attempts", c.Jobs.PruneLoginAttempts},
+		{"jobs.prune_rate_limits", c.Jobs.PruneRateLimits},
+		{"jobs.reconcile_disbursements", c.Jobs.ReconcileDisbursements},
+	} {
+		if schedule.expr == "" {
+			continue
+		}
+		s, err := cron.Parse(schedule.expr)
+		if err != nil {
+			errs.add(schedule.field, "must be empty or a cron expression, "+err.Error())
+		} else if s.Next(time.Now()).IsZero() {
+			errs.add(schedule.field, "must match a time within five years")
+		}
+	}
+	if c.Jobs.PendingLoanTtl < time.Hour {
+		errs.add("jobs.pending_loan_ttl", "must be at least 1h")
+	}
+	if c.Jobs.RemindBefore < time.Hour {
+		errs.add("jobs.remind_before", "must be at least 1h")
+	}
+	if c.Jobs.ReconcileAfter < time.Minute {
+		errs.add("jobs.reconcile_after", "must be at least 1m")
+	}
+
+	if !outboxSinks[c.Outbox.Sink] {
+		errs.add("outbox.sink", "must be webhook, file or stdout")
+	}
+	if c.Outbox.Sink == "webhook" {
+		if u, err := url.Parse(c.Outbox.WebhookUrl); err != nil || u.Scheme == "" || u.Host == "" {
+			errs.add("outbox.webhook_url", "must be an absolute url when sink is webhook")
+		}
+		if c.Outbox.WebhookTimeout < time.Second {
+			errs.add("outbox.webhook_timeout", "must be at least 1s")
+		}
+	}
+	if c.Outbox.Sink == "file" && c.Outbox.File == "" {
+		errs.add("outbox.file", "is required when sink is file")
+	}
+	if c.Outbox.PollInterval < 100*time.Millisecond {
+		errs.add("outbox.poll_interval", "must be at least 100ms")
+	}
+	if c.Outbox.BatchSize < 1 || c.Outbox.BatchSize > 1000 {
+		errs.add("outbox.batch_size", "must be between 1 and 1000")
+	}
+	if c.Outbox.MaxAttempts < 1 {
+		errs.add("outbox.max_attempts", "must be at least 1")
+	}
+	if c.Outbox.BaseBackoff < time.Second {
+		errs.add("outbox.base_backoff", "must be at least 1s")
+	}
+	if c.Outbox.MaxBackoff < c.Outbox.BaseBackoff {
+		errs.add("outbox.max_backoff", "must not be lower than outbox.base_backoff")
+	}
+
+	if c.Webhooks.Timeout < time.Second {
+		errs.add("webhooks.timeout", "must be at least 1s")
+	}
+	if c.Webhooks.PollInterval < 100*time.Millisecond {
+		errs.add("webhooks.poll_interval", "must be at least 100ms")
+	}
+	if c.Webhooks.BatchSize < 1 || c.Webhooks.BatchSize > 1000 {
+		errs.add("webhooks.batch_size", "must be between 1 and 1000")
+	}
+	if c.Webhooks.MaxAttempts < 1 {
+		errs.add("webhooks.max_attempts", "must be at least 1")
+	}
+	if c.Webhooks.BaseBackoff < time.Second {
+		errs.add("webhooks.base_backoff", "must be at least 1s")
+	}
+	if c.Webhooks.MaxBackoff < c.Webhooks.BaseBackoff {
+		errs.add("webhooks.max_backoff", "must not be lower than webhooks.base_backoff")
+	}
+
+	if !slices.Contains(constant.NotificationLocales, c.Notifications.DefaultLocale) {
+		errs.add("notifications.default_locale", "must be one of "+strings.Join(constant.NotificationLocales, ", "))
+	}
+	if !emailChannelDrivers[c.Notifications.Email] {
+		errs.add("notifications.email", "must be smtp, console, file or none")
+	}
+	if c.Notifications.Email == "smtp" {
+		if c.Notifications.SmtpHost == "" {
+			errs.add("notifications.smtp_host", "is required with the smtp driver (APP_NOTIFICATIONS_SMTP_HOST)")
+		}
+		if c.Notifications.SmtpPort < 1 || c.Notifications.SmtpPort > 65535 {
+			errs.add("notifications.smtp_port", "must be between 1 and 65535")
+		}
+		if _, err := mail.ParseAddress(c.Notifications.SmtpFrom); err != nil {
+			errs.add("notifications.smtp_from", "must be a valid email address")
+		}
+	}
+	for _, channel := range []struct{ field, driver, url string }{
+		{"notifications.sms", c.Notifications.Sms, c.Notifications.SmsUrl},
+		{"notifications.push", c.Notifications.Push, c.Notifications.PushUrl},
+	} {
+		if !providerChannelDrivers[channel.driver] {
+			errs.add(channel.field, "must be http, console, file or none")
+		}
+		if u, err := url.Parse(channel.url); channel.driver == "http" && (err != nil || u.Scheme == "" || u.Host == "") {
+			errs.add(channel.field+"_url", "must be an absolute url with the http driver")
+		}
+	}
+	usesFile := c.Notifications.Email == "file" || c.Notifications.Sms == "file" || c.Notifications.Push == "file"
+	if usesFile && c.Notifications.File == "" {
+		errs.add("notifications.file", "is required when a channel uses the file driver")
+	}
+	if c.Notifications.Timeout < time.Second {
+		errs.add("notifications.timeout", "must be at least 1s")
+	}
+	if c.Notifications.PollInterval < 100*time.Millisecond {
+		errs.add("notifications.poll_interval", "must be at least 100ms")
+	}
+	if c.Notifications.BatchSize < 1 || c.Notifications.BatchSize > 1000 {
+		errs.add("notifications.batch_size", "must be between 1 and 1000")
+	}
+	if c.Notifications.MaxAttempts < 1 {
+		errs.add("notifications.max_attempts", "must be at least 1")
+	}
+	if c.Notifications.BaseBackoff < time.Second {
+		errs.add("notifications.base_backoff", "must be at least 1s")
+	}
+	if c.Notifications.MaxBackoff < c.Notifications.BaseBackoff {
+		errs.add("notifications.max_backoff", "must not be lower than notifications.base_backoff")
+	}
+
+	if c.JwtSecret == "" {
+		errs.add("jwt_secret", "is required (APP_JWT_SECRET)")
+	}
+
+	if c.PostgresDb.Host == "" {
+		errs.add("db.host", "is required (APP_DB_HOST)")
+	}
+	if c.PostgresDb.Port < 1 || c.PostgresDb.Port > 65535 {
+		errs.add("db.port", "must be between 1 and 65535")
+	}
+	if c.PostgresDb.User == "" {
+		errs.add("db.user", "is required (APP_DB_USER)")
+	}
+	if c.PostgresDb.Dbname == "" {
+		errs.add("db.dbname", "is required (APP_DB_NAME)")
+	}
+	if !sslModes[c.PostgresDb.SslMode] {
+		errs.add("db.sslmode", "must be one of disable, allow, prefer, require, verify-ca, verify-full")
+	}
+	if c.PostgresDb.MaxOpenConns < 0 {
+		errs.add("db.max_open_conns", "must not be negative")
+	}
+	if c.PostgresDb.MaxIdleConns < 0 {
+		errs.add("db.max_idle_conns", "must not be negative")
+	} else if c.PostgresDb.MaxOpenConns > 0 && c.PostgresDb.MaxIdleConns > c.PostgresDb.MaxOpenConns {
+		errs.add("db.max_idle_conns", "must not exceed db.max_open_conns")
+	}
+	if c.PostgresDb.ConnMaxLifetime < 0 {
+		errs.add("db.conn_max_lifetime", "must not be negative")
+	}
+	if c.PostgresDb.ConnectTimeout < time.Second {
+		errs.add("db.connect_timeout", "must be at least 1s")
+	}
+	if c.PostgresDb.ConnectRetries < 0 {
+		errs.add("db.connect_retries", "must not be negative")
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+var logLevels = map[string]bool{
+	"debug": true,
+	"info":  true,
+	"warn":  true,
+	"error": true,
+}
+
+var logFormats = map[string]bool{
+	"json": true,
+	"text": true,
+}
+
+var tracingExporters = map[string]bool{
+	"none":   true,
+	"stdout": true,
+	"otlp":   true,
+}
+
+var mailerDrivers = map[string]bool{
+	"log":  true,
+	"file": true,
+}
+
+var lockoutStores = map[string]bool{
+	"postgres": true,
+	"memory":   true,
+}
+
+var rateLimitStores = map[string]bool{
+	"postgres": true,
+	"memory":   true,
+}
+
+var jobStores = map[string]bool{
+	"postgres": true,
+	"memory":   true,
+}
+
+var sslModes = map[string]bool{
+	"disable":     true,
+	"allow":       true,
+	"prefer":      true,
+	"require":     true,
+	"verify-ca":   true,
+	"verify-full": true,
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n >= 1 && n <= 65535
+}
+
+var payoutDrivers = map[string]bool{
+	"fake": true,
+}
+
+var paymentDrivers = map[string]bool{
+	"mock": true,
+}
+
+var outboxSinks = map[string]bool{
+	"webhook": true,
+	"file":    true,
+	"stdout":  true,
+}
+
+var emailChannelDrivers = map[string]bool{
+	"smtp":    true,
+	"console": true,
+	"file":    true,
+	"none":    true,
+}
+
+var providerChannelDrivers = map[string]bool{
+	"http":    true,
+	"console": true,
+	"file":    true,
+	"none":    true,
+}
+
+var lateFeeTypes = map[string]bool{
+	"flat":    true,
+	"percent": true,
+}
+
+var fakePayoutOutcomes = map[string]bool{
+	"success": true,
+	"failure": true,
+	"delay":   true,
+}
@@ -0,0 +1,44 @@
+package constant
+
+const (
+	AggregateLoan = "loan"
+)
+
+const (
+	EventLoanCreated          = "loan.created"
+	EventLoanApproved         = "loan.approved"
+	EventLoanPaymentReceived  = "loan.payment_received"
+	EventLoanPaid             = "loan.paid"
+	EventLoanPaymentUnapplied = "loan.payment_unapplied"
+	EventLoanDefaulted        = "loan.defaulted"
+)
+
+// EventTypes lists every event type, webhook endpoints filter on them.
+var EventTypes = []string{
+	EventLoanCreated,
+	EventLoanApproved,
+	EventLoanPaymentReceived,
+	EventLoanPaid,
+	EventLoanPaymentUnapplied,
+	EventLoanDefaulted,
+}
+
+const (
+	OutboxStatusPending   = "PENDING"
+	OutboxStatusPublished = "PUBLISHED"
+	OutboxStatusDead      = "DEAD"
+)
+
+const (
+	WebhookDeliveryPending   = "PENDING"
+	WebhookDeliverySucceeded = "SUCCEEDED"
+	WebhookDeliveryFailed    = "FAILED"
+)
+
+const (
+	// WebhookSignatureHeader holds v1=<hex HMAC-SHA256 of "<timestamp>.<body>"> keyed with the endpoint secret
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookEventHeader     = "X-Webhook-Event"
+)
@@ -1,7 +1,38 @@
 package constant
 
 const (
-	HttpHeaderAppJson    = "application/json"
-	HttpHeaderSetContent = "Set-Content"
-	HttpHeaderSetCookie  = "Set-Cookie"
+	HttpHeaderAppJson        = "application/json"
+	HttpHeaderAppProblemJson = "application/problem+json"
+	HttpHeaderContentType    = "Content-Type"
+	HttpHeaderSetContent     = "Set-Content"
+	HttpHeaderSetCookie      = "Set-Cookie"
+	HttpHeaderRequestId      = "X-Request-ID"
+	HttpHeaderRetryAfter     = "Retry-After"
+	HttpHeaderForwardedFor   = "X-Forwarded-For"
+
+	HttpHeaderRateLimitLimit     = "RateLimit-Limit"
+	HttpHeaderRateLimitRemaining = "RateLimit-Remaining"
+	HttpHeaderRateLimitReset     = "RateLimit-Reset"
+
+	// payment gateway callbacks are signed over the timestamp, the nonce and the raw body
+	HttpHeaderWebhookTimestamp = "X-Webhook-Timestamp"
+	HttpHeaderWebhookNonce     = "X-Webhook-Nonce"
+	HttpHeaderWebhookSignature = "X-Webhook-Signature"
+)
+
+const (
+	// ProblemTypeBase prefixes the problem+json type, followed by the kebab-cased error code
+	ProblemTypeBase = "/problems/"
+	// ProblemInternalDetail replaces the detail of internal errors, the real error is only logged
+	ProblemInternalDetail = "an unexpected error occurred"
+)
+
+const (
+	// MaxRequestBodyBytes bounds json request bodies, larger bodies are rejected before decoding completes
+	MaxRequestBodyBytes = 1 << 20
+)
+
+const (
+	// CookieClearSession expires the SID cookie set on login
+	CookieClearSession = "SID=; HttpOnly; Path=/; Max-Age=0; Domain=localhost;"
 )
@@ -1,7 +1,17 @@
 package constant
 
 const (
-	LoanStatusPending  = "PENDING"
-	LoanStatusApproved = "APPROVED"
-	LoanStatusPaid     = "PAID"
+	LoanStatusPending    = "PENDING"
+	LoanStatusExpired    = "EXPIRED"
+	LoanStatusApproved   = "APPROVED"
+	LoanStatusDisbursing = "DISBURSING"
+	LoanStatusDisbursed  = "DISBURSED"
+	LoanStatusDefaulted  = "DEFAULTED"
+	LoanStatusPaid       = "PAID"
+)
+
+const (
+	DisbursementStatusPending   = "PENDING"
+	DisbursementStatusSucceeded = "SUCCEEDED"
+	DisbursementStatusFailed    = "FAILED"
 )
@@ -0,0 +1,41 @@
+package constant
+
+// notification kinds, loan notifications are named after the event they tell the borrower about
+const (
+	NotificationUserRegistered      = "user.registered"
+	NotificationLoanCreated         = EventLoanCreated
+	NotificationLoanApproved        = EventLoanApproved
+	NotificationLoanPaymentReceived = EventLoanPaymentReceived
+	NotificationLoanRepaymentDue    = "loan.repayment_due"
+)
+
+// NotificationKinds lists every kind, the message catalogue has a message for each of them in every locale.
+var NotificationKinds = []string{
+	NotificationUserRegistered,
+	NotificationLoanCreated,
+	NotificationLoanApproved,
+	NotificationLoanPaymentReceived,
+	NotificationLoanRepaymentDue,
+}
+
+const (
+	NotificationChannelEmail = "email"
+	NotificationChannelSms   = "sms"
+	NotificationChannelPush  = "push"
+)
+
+// NotificationChannels lists every channel, email is the only one enabled for users without preferences.
+var NotificationChannels = []string{
+	NotificationChannelEmail,
+	NotificationChannelSms,
+	NotificationChannelPush,
+}
+
+// NotificationLocales lists the locales of the message catalogue.
+var NotificationLocales = []string{"en", "id"}
+
+const (
+	NotificationStatusPending = "PENDING"
+	NotificationStatusSent    = "SENT"
+	NotificationStatusFailed  = "FAILED"
+)
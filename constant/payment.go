@@ -0,0 +1,10 @@
+package constant
+
+const (
+	PaymentIntentStatusPending   = "PENDING"
+	PaymentIntentStatusSucceeded = "SUCCEEDED"
+	PaymentIntentStatusFailed    = "FAILED"
+	// PaymentIntentStatusUnapplied is a payment the gateway received that could not be applied to its term, the
+	// received amount has to be refunded or applied by hand
+	PaymentIntentStatusUnapplied = "UNAPPLIED"
+)
@@ -2,5 +2,15 @@ package constant
 
 const (
 	RepaymentStatusPending = "PENDING"
+	RepaymentStatusOverdue = "OVERDUE"
 	RepaymentStatusPaid    = "PAID"
 )
+
+const (
+	RepaymentFeeKindLate = "LATE_FEE"
+)
+
+const (
+	LateFeeTypeFlat    = "flat"
+	LateFeeTypePercent = "percent"
+)
@@ -1,5 +1,11 @@
 package constant
 
+import "time"
+
 const (
 	TimeFormatCookieExpiry = "2 Jan 2006 15:04:05 -0700"
 )
+
+const (
+	HealthCheckTimeout = 3 * time.Second
+)
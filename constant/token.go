@@ -0,0 +1,18 @@
+package constant
+
+const (
+	// TokenPurposeVerifyEmail marks email verification tokens, session tokens carry no purpose claim
+	TokenPurposeVerifyEmail = "verify_email"
+	// TokenPurposeTotpChallenge marks the token returned by a password login that still needs a TOTP code
+	TokenPurposeTotpChallenge = "totp_challenge"
+	// TokenPurposeTotpEnroll marks the token returned by a password login that must enroll TOTP first
+	TokenPurposeTotpEnroll = "totp_enroll"
+)
+
+const (
+	// TwoFactorVerify and TwoFactorEnroll tell the client which second step a challenge token is for
+	TwoFactorVerify = "verify"
+	TwoFactorEnroll = "enroll"
+	// TotpRecoveryCodes is the number of single-use recovery codes issued when TOTP is enabled
+	TotpRecoveryCodes = 10
+)
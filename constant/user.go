@@ -1,7 +1,33 @@
 package constant
 
 const (
-	JwtSecret    = "hmacSampleSecret"
 	CustomerRole = "CUSTOMER"
 	AdminRole    = "ADMIN"
 )
+
+const (
+	// DummyPasswordHash is compared against when a login email is unknown, so the response takes as long as a
+	// wrong password and does not reveal which emails are registered. It is the bcrypt hash of a random string.
+	DummyPasswordHash = "$2a$10$LJogT1.DQQ8X1V5p8ohwmuMoQr74tSmXPAwdN7flnNBhktjK4B9EK"
+
+	// SeedAdminEmail is the SUPER_ADMIN created by the seed command
+	SeedAdminEmail = "admin@admin.com"
+)
+
+const (
+	// staff roles are granted to ADMIN accounts through user_roles, their permissions live in role_permissions
+	RoleSuperAdmin  = "SUPER_ADMIN"
+	RoleLoanOfficer = "LOAN_OFFICER"
+	RoleFinance     = "FINANCE"
+	RoleSupport     = "SUPPORT"
+)
+
+const (
+	PermissionLoanRead      = "loan:read"
+	PermissionLoanApprove   = "loan:approve"
+	PermissionLoanDisburse  = "loan:disburse"
+	PermissionPaymentRecord = "payment:record"
+	PermissionLoginUnlock   = "login:unlock"
+	PermissionRoleAssign    = "role:assign"
+	PermissionWebhookManage = "webhook:manage"
+)
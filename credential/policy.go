@@ -0,0 +1,95 @@
+package credential
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/config"
+)
+
+// Policy decides whether a password may be stored.
+type Policy struct {
+	minLength int
+	maxLength int
+	denylist  map[string]struct{}
+}
+
+// NewPolicy builds the policy from cfg, loading the denylist file when configured.
+func NewPolicy(cfg config.Password) (*Policy, error) {
+	p := &Policy{
+		minLength: cfg.MinLength,
+		maxLength: cfg.MaxLength,
+		denylist:  map[string]struct{}{},
+	}
+	if cfg.DenylistFile == "" {
+		return p, nil
+	}
+
+	file, err := os.Open(cfg.DenylistFile)
+	if err != nil {
+		return nil, fmt.Errorf("open password denylist: %w", err)
+	}
+	defer file.Close()
+
+	err = p.loadDenylist(file)
+	if err != nil {
+		return nil, fmt.Errorf("read password denylist: %w", err)
+	}
+
+	return p, nil
+}
+
+// NewPolicyFromList builds a policy with an in-memory denylist.
+func NewPolicyFromList(minLength, maxLength int, denylist ...string) *Policy {
+	p := &Policy{
+		minLength: minLength,
+		maxLength: maxLength,
+		denylist:  map[string]struct{}{},
+	}
+	p.loadDenylist(strings.NewReader(strings.Join(denylist, "\n")))
+	return p
+}
+
+// loadDenylist reads one password per line, blank lines and lines starting with # are skipped.
+func (p *Policy) loadDenylist(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.denylist[strings.ToLower(line)] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+// Check validates password for the account identified by email, returning a validation error on the password field.
+func (p *Policy) Check(password, email string) error {
+	var fields []apperror.FieldError
+	reject := func(message string) {
+		fields = append(fields, apperror.FieldError{Field: "password", Message: message})
+	}
+
+	if utf8.RuneCountInString(password) < p.minLength {
+		reject(fmt.Sprintf("must be at least %d characters", p.minLength))
+	}
+	if len(password) > p.maxLength {
+		reject(fmt.Sprintf("must be at most %d bytes", p.maxLength))
+	}
+	if _, ok := p.denylist[strings.ToLower(password)]; ok {
+		reject("is too common, choose another one")
+	}
+	if email != "" && strings.EqualFold(password, email) {
+		reject("must not be the same as the email")
+	}
+
+	if len(fields) > 0 {
+		return apperror.Validation(fields...)
+	}
+	return nil
+}
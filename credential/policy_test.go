@@ -0,0 +1,103 @@
+package credential
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/config"
+)
+
+func Test_PolicyCheck(t *testing.T) {
+	p := NewPolicyFromList(10, 72, "# common", "", "Password123", "qwertyuiop")
+
+	tests := []struct {
+		name       string
+		password   string
+		email      string
+		wantFields []apperror.FieldError
+	}{
+		{
+			name:     "valid",
+			password: "correct horse battery",
+			email:    "tes@tes.com",
+		},
+		{
+			name:     "too short",
+			password: "short",
+			wantFields: []apperror.FieldError{
+				{Field: "password", Message: "must be at least 10 characters"},
+			},
+		},
+		{
+			name:     "multibyte characters count once",
+			password: "ééééééééé",
+			wantFields: []apperror.FieldError{
+				{Field: "password", Message: "must be at least 10 characters"},
+			},
+		},
+		{
+			name:     "longer than bcrypt accepts",
+			password: string(make([]byte, 73)),
+			wantFields: []apperror.FieldError{
+				{Field: "password", Message: "must be at most 72 bytes"},
+			},
+		},
+		{
+			name:     "denylisted regardless of case",
+			password: "PASSWORD123",
+			wantFields: []apperror.FieldError{
+				{Field: "password", Message: "is too common, choose another one"},
+			},
+		},
+		{
+			name:     "same as email",
+			password: "Tes@Tes.com",
+			email:    "tes@tes.com",
+			wantFields: []apperror.FieldError{
+				{Field: "password", Message: "must not be the same as the email"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.Check(tt.password, tt.email)
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Errorf("Check test failed. want nil, got %v", err)
+				}
+				return
+			}
+
+			appErr, ok := err.(*apperror.Error)
+			if !ok || appErr.Code != apperror.CodeValidation {
+				t.Fatalf("Check test failed. want validation error, got %v", err)
+			}
+			if !reflect.DeepEqual(appErr.Fields, tt.wantFields) {
+				t.Errorf("Check test failed. want %+v, got %+v", tt.wantFields, appErr.Fields)
+			}
+		})
+	}
+}
+
+func Test_NewPolicy(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "denylist.txt")
+	os.WriteFile(path, []byte("letmein12345\n"), 0600)
+
+	p, err := NewPolicy(config.Password{MinLength: 10, MaxLength: 72, DenylistFile: path})
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+	if p.Check("LetMeIn12345", "") == nil {
+		t.Errorf("denylisted password from file accepted")
+	}
+
+	_, err = NewPolicy(config.Password{MinLength: 10, MaxLength: 72, DenylistFile: filepath.Join(dir, "missing.txt")})
+	if err == nil {
+		t.Errorf("missing denylist file accepted")
+	}
+}
@@ -0,0 +1,154 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed five field cron expression: minute, hour, day of month, month and day of week.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny are set when the field is *, a day then only has to match the other day field
+	domAny, dowAny bool
+}
+
+var descriptors = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+type bounds struct {
+	name     string
+	min, max int
+}
+
+var fields = []bounds{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// Parse reads a cron expression. Every field accepts *, a value, a range a-b, a step */n or a-b/n and comma
+// separated lists of them. @hourly, @daily, @weekly and @monthly are accepted too.
+func Parse(expr string) (s Schedule, err error) {
+	if d, ok := descriptors[expr]; ok {
+		expr = d
+	}
+
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return s, fmt.Errorf("cron expression %q must have %d fields", expr, len(fields))
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, part := range parts {
+		bits[i], err = parseField(part, fields[i])
+		if err != nil {
+			return
+		}
+	}
+
+	return Schedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: parts[2] == "*",
+		dowAny: parts[4] == "*",
+	}, nil
+}
+
+func parseField(field string, b bounds) (bits uint64, err error) {
+	for _, item := range strings.Split(field, ",") {
+		lo, hi, step := b.min, b.max, 1
+
+		rng := item
+		if i := strings.IndexByte(item, '/'); i >= 0 {
+			rng = item[:i]
+			step, err = strconv.Atoi(item[i+1:])
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %s field %q", b.name, field)
+			}
+		}
+
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			from, to, _ := strings.Cut(rng, "-")
+			lo, err = value(from, b)
+			if err != nil {
+				return
+			}
+			hi, err = value(to, b)
+			if err != nil {
+				return
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range in %s field %q", b.name, field)
+			}
+		default:
+			lo, err = value(rng, b)
+			if err != nil {
+				return
+			}
+			if rng == item {
+				hi = lo
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return
+}
+
+func value(s string, b bounds) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < b.min || v > b.max {
+		return 0, fmt.Errorf("%s must be between %d and %d, got %q", b.name, b.min, b.max, s)
+	}
+	return v, nil
+}
+
+// Next returns the first time after t matching the schedule, in t's location. It returns the zero time when
+// nothing matches within five years, like 0 0 30 2 *.
+func (s Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// dayMatches follows cron: when both day fields are restricted a day matching either one is enough.
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,65 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr bool
+	}{
+		{name: "every minute", expr: "* * * * *"},
+		{name: "lists, ranges and steps", expr: "0,30 8-18/2 1-7 */3 1-5"},
+		{name: "descriptor", expr: "@daily"},
+		{name: "missing field", expr: "* * * *", wantErr: true},
+		{name: "value out of bounds", expr: "60 * * * *", wantErr: true},
+		{name: "zero step", expr: "*/0 * * * *", wantErr: true},
+		{name: "reversed range", expr: "* 18-8 * * *", wantErr: true},
+		{name: "not a number", expr: "* * * jan *", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_Schedule_Next(t *testing.T) {
+	// a monday
+	from := time.Date(2026, 10, 19, 9, 17, 42, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{name: "every minute", expr: "* * * * *", want: time.Date(2026, 10, 19, 9, 18, 0, 0, time.UTC)},
+		{name: "every 15 minutes", expr: "*/15 * * * *", want: time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC)},
+		{name: "hourly", expr: "@hourly", want: time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)},
+		{name: "daily at 8 is tomorrow", expr: "0 8 * * *", want: time.Date(2026, 10, 20, 8, 0, 0, 0, time.UTC)},
+		{name: "next saturday", expr: "30 6 * * 6", want: time.Date(2026, 10, 24, 6, 30, 0, 0, time.UTC)},
+		{name: "next month", expr: "@monthly", want: time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "next year", expr: "0 0 1 1 *", want: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "day of month or day of week", expr: "0 0 1 * 3", want: time.Date(2026, 10, 21, 0, 0, 0, 0, time.UTC)},
+		{name: "never", expr: "0 0 30 2 *", want: time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.expr, err)
+			}
+			if got := s.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", from, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,83 +1,616 @@
 package migration
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"log/slog"
 
 	"example.com/m/v2/resource"
 )
 
-func Migrate(res *resource.Resource) {
-	_, err := res.PostgresDb.Query(`
-		CREATE TYPE UserRole AS ENUM ('ADMIN','CUSTOMER');
-	`)
+type migration struct {
+	version int
+	name    string
+	query   string
+}
+
+// migrations are applied in order and recorded in schema_migrations, append new entries with the next version.
+// queries must stay idempotent because databases created before versioning already contain the first tables.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create users",
+		query: `
+			DO $$ BEGIN
+				CREATE TYPE UserRole AS ENUM ('ADMIN','CUSTOMER');
+			EXCEPTION
+				WHEN duplicate_object THEN NULL;
+			END $$;
+
+			CREATE TABLE IF NOT EXISTS users(
+				id BIGSERIAL PRIMARY KEY,
+				email TEXT UNIQUE,
+				password TEXT,
+				role UserRole,
+				created_at TIMESTAMPTZ,
+				updated_at TIMESTAMPTZ
+			);
+		`,
+	},
+	{
+		version: 2,
+		name:    "create loans",
+		query: `
+			DO $$ BEGIN
+				CREATE TYPE LoanStatus AS ENUM ('PENDING','APPROVED','PAID');
+			EXCEPTION
+				WHEN duplicate_object THEN NULL;
+			END $$;
+
+			CREATE TABLE IF NOT EXISTS loans(
+				id BIGSERIAL PRIMARY KEY,
+				user_id BIGINT,
+				amount NUMERIC,
+				status LoanStatus,
+				created_at TIMESTAMPTZ,
+				updated_at TIMESTAMPTZ
+			);
+		`,
+	},
+	{
+		version: 3,
+		name:    "create repayments",
+		query: `
+			DO $$ BEGIN
+				CREATE TYPE RepaymentStatus AS ENUM ('PENDING','PAID');
+			EXCEPTION
+				WHEN duplicate_object THEN NULL;
+			END $$;
+
+			CREATE TABLE IF NOT EXISTS repayments(
+				id BIGSERIAL PRIMARY KEY,
+				loan_id BIGINT,
+				minimum_payment NUMERIC,
+				actual_payment NUMERIC,
+				status RepaymentStatus,
+				due_date TIMESTAMPTZ,
+				created_at TIMESTAMPTZ,
+				updated_at TIMESTAMPTZ
+			);
+		`,
+	},
+	{
+		version: 4,
+		name:    "normalize emails and add email verification",
+		query: `
+			ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+
+			-- accounts created before verification existed are trusted
+			UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
+
+			-- emails are stored lower cased from now on, clashing legacy accounts are left for manual review
+			UPDATE users SET email = lower(email)
+			WHERE email <> lower(email)
+				AND NOT EXISTS (SELECT 1 FROM users u WHERE u.email = lower(users.email));
+		`,
+	},
+	{
+		version: 5,
+		name:    "add session versions and password reset tokens",
+		query: `
+			-- bumped on every password change, sessions signed with an older version are rejected
+			ALTER TABLE users ADD COLUMN IF NOT EXISTS session_version BIGINT NOT NULL DEFAULT 0;
+
+			CREATE TABLE IF NOT EXISTS password_reset_tokens(
+				id BIGSERIAL PRIMARY KEY,
+				user_id BIGINT NOT NULL REFERENCES users(id),
+				token_hash TEXT NOT NULL UNIQUE,
+				expires_at TIMESTAMPTZ NOT NULL,
+				used_at TIMESTAMPTZ,
+				created_at TIMESTAMPTZ NOT NULL
+			);
+
+			CREATE INDEX IF NOT EXISTS password_reset_tokens_user_id_idx ON password_reset_tokens(user_id);
+		`,
+	},
+	{
+		version: 6,
+		name:    "add login attempts",
+		query: `
+			-- failed logins per account (account:<email>) and per client ip (ip:<addr>)
+			CREATE TABLE IF NOT EXISTS login_attempts(
+				key TEXT PRIMARY KEY,
+				failures INT NOT NULL,
+				last_failure_at TIMESTAMPTZ NOT NULL
+			);
+		`,
+	},
+	{
+		version: 7,
+		name:    "add rate limit buckets",
+		query: `
+			-- one token bucket per route policy and client, key is <policy>:<user or ip>
+			CREATE TABLE IF NOT EXISTS rate_limit_buckets(
+				key TEXT PRIMARY KEY,
+				tokens DOUBLE PRECISION NOT NULL,
+				updated_at TIMESTAMPTZ NOT NULL
+			);
+		`,
+	},
+	{
+		version: 8,
+		name:    "add totp two factor authentication",
+		query: `
+			-- totp_secret is set on enrollment, totp_enabled_at once a first code was verified.
+			-- totp_last_step is the last accepted time step, a code is never accepted twice
+			ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret TEXT;
+			ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMPTZ;
+			ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+			CREATE TABLE IF NOT EXISTS recovery_codes(
+				id BIGSERIAL PRIMARY KEY,
+				user_id BIGINT NOT NULL REFERENCES users(id),
+				code_hash TEXT NOT NULL,
+				used_at TIMESTAMPTZ,
+				created_at TIMESTAMPTZ NOT NULL
+			);
+
+			CREATE INDEX IF NOT EXISTS recovery_codes_user_id_idx ON recovery_codes(user_id);
+		`,
+	},
+	{
+		version: 9,
+		name:    "add roles and permissions",
+		query: `
+			-- staff roles granted to ADMIN accounts, approval_limit caps loan:approve (NULL is unlimited)
+			CREATE TABLE IF NOT EXISTS roles(
+				name TEXT PRIMARY KEY,
+				description TEXT NOT NULL,
+				approval_limit NUMERIC
+			);
+
+			CREATE TABLE IF NOT EXISTS role_permissions(
+				role TEXT NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+				permission TEXT NOT NULL,
+				PRIMARY KEY (role, permission)
+			);
+
+			CREATE TABLE IF NOT EXISTS user_roles(
+				user_id BIGINT NOT NULL REFERENCES users(id),
+				role TEXT NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+				assigned_by BIGINT REFERENCES users(id),
+				assigned_at TIMESTAMPTZ NOT NULL,
+				PRIMARY KEY (user_id, role)
+			);
+
+			INSERT INTO roles(name, description, approval_limit) VALUES
+				('SUPER_ADMIN', 'every permission, assigns roles', NULL),
+				('LOAN_OFFICER', 'reads and approves loans up to the approval limit', 10000000),
+				('FINANCE', 'records payments', NULL),
+				('SUPPORT', 'reads loans and unlocks logins', NULL)
+			ON CONFLICT (name) DO NOTHING;
+
+			INSERT INTO role_permissions(role, permission) VALUES
+				('SUPER_ADMIN', 'loan:read'),
+				('SUPER_ADMIN', 'loan:approve'),
+				('SUPER_ADMIN', 'payment:record'),
+				('SUPER_ADMIN', 'login:unlock'),
+				('SUPER_ADMIN', 'role:assign'),
+				('LOAN_OFFICER', 'loan:read'),
+				('LOAN_OFFICER', 'loan:approve'),
+				('FINANCE', 'loan:read'),
+				('FINANCE', 'payment:record'),
+				('SUPPORT', 'loan:read'),
+				('SUPPORT', 'login:unlock')
+			ON CONFLICT DO NOTHING;
+
+			-- admins could do everything before roles existed
+			INSERT INTO user_roles(user_id, role, assigned_at)
+			SELECT id, 'SUPER_ADMIN', NOW() FROM users WHERE role = 'ADMIN'
+			ON CONFLICT DO NOTHING;
+		`,
+	},
+	{
+		version: 10,
+		name:    "add loan approvals",
+		query: `
+			-- one row per approver and loan, only approvals not expired yet count towards the quorum
+			CREATE TABLE IF NOT EXISTS loan_approvals(
+				id BIGSERIAL PRIMARY KEY,
+				loan_id BIGINT NOT NULL REFERENCES loans(id),
+				approver_id BIGINT NOT NULL REFERENCES users(id),
+				approved_at TIMESTAMPTZ NOT NULL,
+				expires_at TIMESTAMPTZ NOT NULL,
+				UNIQUE (loan_id, approver_id)
+			);
+		`,
+	},
+	{
+		version: 11,
+		name:    "add disbursing loan statuses",
+		query: `
+			-- new enum values can't be used in the transaction adding them, version 12 does
+			ALTER TYPE LoanStatus ADD VALUE IF NOT EXISTS 'DISBURSING' AFTER 'APPROVED';
+			ALTER TYPE LoanStatus ADD VALUE IF NOT EXISTS 'DISBURSED' AFTER 'DISBURSING';
+		`,
+	},
+	{
+		version: 12,
+		name:    "add disbursements",
+		query: `
+			ALTER TABLE loans ADD COLUMN IF NOT EXISTS disbursed_at TIMESTAMPTZ;
+
+			-- one row per payout attempt, a failed attempt leaves the loan APPROVED for a new one
+			CREATE TABLE IF NOT EXISTS disbursements(
+				id BIGSERIAL PRIMARY KEY,
+				loan_id BIGINT NOT NULL REFERENCES loans(id),
+				amount NUMERIC NOT NULL,
+				idempotency_key TEXT NOT NULL UNIQUE,
+				provider TEXT NOT NULL,
+				provider_reference TEXT,
+				status TEXT NOT NULL,
+				failure_reason TEXT,
+				attempts INT NOT NULL DEFAULT 0,
+				last_error TEXT,
+				created_at TIMESTAMPTZ NOT NULL,
+				updated_at TIMESTAMPTZ NOT NULL,
+				completed_at TIMESTAMPTZ
+			);
+
+			CREATE UNIQUE INDEX IF NOT EXISTS disbursements_active_loan_idx ON disbursements(loan_id) WHERE status <> 'FAILED';
+
+			INSERT INTO role_permissions(role, permission) VALUES
+				('SUPER_ADMIN', 'loan:disburse'),
+				('FINANCE', 'loan:disburse')
+			ON CONFLICT DO NOTHING;
+
+			-- loans approved before disbursements existed were treated as paid out, their due dates stay as they are
+			UPDATE loans SET status = 'DISBURSED', disbursed_at = created_at WHERE status = 'APPROVED';
+		`,
+	},
+	{
+		version: 13,
+		name:    "add payment intents",
+		query: `
+			CREATE TABLE IF NOT EXISTS payment_intents(
+				id BIGSERIAL PRIMARY KEY,
+				loan_id BIGINT NOT NULL REFERENCES loans(id),
+				user_id BIGINT NOT NULL REFERENCES users(id),
+				term BIGINT NOT NULL,
+				amount NUMERIC NOT NULL,
+				gateway TEXT NOT NULL,
+				gateway_reference TEXT NOT NULL UNIQUE,
+				virtual_account TEXT,
+				status TEXT NOT NULL,
+				failure_reason TEXT,
+				expires_at TIMESTAMPTZ NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL,
+				updated_at TIMESTAMPTZ NOT NULL,
+				settled_at TIMESTAMPTZ
+			);
+
+			CREATE INDEX IF NOT EXISTS payment_intents_loan_id_idx ON payment_intents(loan_id);
+
+			-- every webhook nonce is accepted once, nonces older than the timestamp tolerance are forgotten
+			CREATE TABLE IF NOT EXISTS webhook_nonces(
+				nonce TEXT PRIMARY KEY,
+				received_at TIMESTAMPTZ NOT NULL
+			);
+
+			CREATE INDEX IF NOT EXISTS webhook_nonces_received_at_idx ON webhook_nonces(received_at);
+		`,
+	},
+	{
+		version: 14,
+		name:    "add overdue statuses",
+		query: `
+			-- new enum values can't be used in the transaction adding them, version 15 does
+			ALTER TYPE RepaymentStatus ADD VALUE IF NOT EXISTS 'OVERDUE' AFTER 'PENDING';
+			ALTER TYPE LoanStatus ADD VALUE IF NOT EXISTS 'DEFAULTED' AFTER 'DISBURSED';
+		`,
+	},
+	{
+		version: 15,
+		name:    "add repayment fees",
+		query: `
+			-- fee lines charged on a term, a term is paid with its minimum payment plus its fees
+			CREATE TABLE IF NOT EXISTS repayment_fees(
+				id BIGSERIAL PRIMARY KEY,
+				repayment_id BIGINT NOT NULL REFERENCES repayments(id),
+				kind TEXT NOT NULL,
+				amount NUMERIC NOT NULL,
+				assessed_at TIMESTAMPTZ NOT NULL
+			);
+
+			CREATE INDEX IF NOT EXISTS repayment_fees_repayment_id_idx ON repayment_fees(repayment_id);
+			CREATE INDEX IF NOT EXISTS repayments_unpaid_due_date_idx ON repayments(due_date) WHERE status IN ('PENDING','OVERDUE');
+		`,
+	},
+	{
+		version: 16,
+		name:    "add expired loan status",
+		query: `
+			ALTER TYPE LoanStatus ADD VALUE IF NOT EXISTS 'EXPIRED' AFTER 'PENDING';
+		`,
+	},
+	{
+		version: 17,
+		name:    "add jobs",
+		query: `
+			-- one row per scheduled job, a run is claimed by setting locked_until with the row locked
+			CREATE TABLE IF NOT EXISTS jobs(
+				name TEXT PRIMARY KEY,
+				schedule TEXT NOT NULL,
+				next_run_at TIMESTAMPTZ NOT NULL,
+				locked_until TIMESTAMPTZ,
+				attempts INT NOT NULL DEFAULT 0,
+				last_error TEXT,
+				last_run_at TIMESTAMPTZ,
+				last_success_at TIMESTAMPTZ
+			);
+
+			-- set once the borrower was reminded of the due date of the term
+			ALTER TABLE repayments ADD COLUMN IF NOT EXISTS reminded_at TIMESTAMPTZ;
+		`,
+	},
+	{
+		version: 18,
+		name:    "add outbox",
+		query: `
+			-- domain events written in the transaction of the change they describe, the relay publishes them
+			CREATE TABLE IF NOT EXISTS outbox(
+				id BIGSERIAL PRIMARY KEY,
+				aggregate_type TEXT NOT NULL,
+				aggregate_id BIGINT NOT NULL,
+				event_type TEXT NOT NULL,
+				payload JSONB NOT NULL,
+				status TEXT NOT NULL DEFAULT 'PENDING',
+				attempts INT NOT NULL DEFAULT 0,
+				next_attempt_at TIMESTAMPTZ NOT NULL,
+				last_error TEXT,
+				created_at TIMESTAMPTZ NOT NULL,
+				published_at TIMESTAMPTZ
+			);
+
+			CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox(next_attempt_at) WHERE status = 'PENDING';
+			CREATE INDEX IF NOT EXISTS outbox_aggregate_pending_idx ON outbox(aggregate_type, aggregate_id, id) WHERE status = 'PENDING';
+		`,
+	},
+	{
+		version: 19,
+		name:    "add webhook endpoints",
+		query: `
+			-- partner endpoints, an empty event_types receives every event
+			CREATE TABLE IF NOT EXISTS webhook_endpoints(
+				id BIGSERIAL PRIMARY KEY,
+				url TEXT NOT NULL,
+				description TEXT NOT NULL DEFAULT '',
+				secret TEXT NOT NULL,
+				event_types TEXT[] NOT NULL DEFAULT '{}',
+				active BOOLEAN NOT NULL DEFAULT TRUE,
+				created_by BIGINT REFERENCES users(id),
+				created_at TIMESTAMPTZ NOT NULL,
+				updated_at TIMESTAMPTZ NOT NULL
+			);
+
+			-- one row per outbox event and endpoint, written in the transaction of the event
+			CREATE TABLE IF NOT EXISTS webhook_deliveries(
+				id BIGSERIAL PRIMARY KEY,
+				endpoint_id BIGINT NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+				event_id BIGINT NOT NULL REFERENCES outbox(id),
+				status TEXT NOT NULL DEFAULT 'PENDING',
+				attempts INT NOT NULL DEFAULT 0,
+				next_attempt_at TIMESTAMPTZ NOT NULL,
+				response_code INT,
+				last_error TEXT,
+				created_at TIMESTAMPTZ NOT NULL,
+				delivered_at TIMESTAMPTZ,
+				UNIQUE (endpoint_id, event_id)
+			);
+
+			CREATE INDEX IF NOT EXISTS webhook_deliveries_pending_idx ON webhook_deliveries(next_attempt_at) WHERE status = 'PENDING';
+			CREATE INDEX IF NOT EXISTS webhook_deliveries_endpoint_idx ON webhook_deliveries(endpoint_id, id);
+
+			-- the delivery log, one row per attempt
+			CREATE TABLE IF NOT EXISTS webhook_delivery_attempts(
+				id BIGSERIAL PRIMARY KEY,
+				delivery_id BIGINT NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+				attempted_at TIMESTAMPTZ NOT NULL,
+				response_code INT,
+				error TEXT,
+				duration_ms BIGINT NOT NULL
+			);
+
+			CREATE INDEX IF NOT EXISTS webhook_delivery_attempts_delivery_idx ON webhook_delivery_attempts(delivery_id, id);
+
+			INSERT INTO role_permissions(role, permission) VALUES
+				('SUPER_ADMIN', 'webhook:manage')
+			ON CONFLICT DO NOTHING;
+		`,
+	},
+	{
+		version: 20,
+		name:    "add notifications",
+		query: `
+			ALTER TABLE users ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT 'en';
+
+			-- a missing row leaves email enabled and sms / push disabled, address is the phone number or device token
+			CREATE TABLE IF NOT EXISTS notification_preferences(
+				user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				channel TEXT NOT NULL,
+				enabled BOOLEAN NOT NULL,
+				address TEXT NOT NULL DEFAULT '',
+				updated_at TIMESTAMPTZ NOT NULL,
+				PRIMARY KEY (user_id, channel)
+			);
+
+			-- one row per message and channel, rendered from data when sent. subject is kept for the history
+			CREATE TABLE IF NOT EXISTS notifications(
+				id BIGSERIAL PRIMARY KEY,
+				user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				kind TEXT NOT NULL,
+				channel TEXT NOT NULL,
+				locale TEXT NOT NULL,
+				recipient TEXT NOT NULL,
+				data JSONB NOT NULL,
+				status TEXT NOT NULL DEFAULT 'PENDING',
+				attempts INT NOT NULL DEFAULT 0,
+				next_attempt_at TIMESTAMPTZ NOT NULL,
+				subject TEXT,
+				last_error TEXT,
+				created_at TIMESTAMPTZ NOT NULL,
+				sent_at TIMESTAMPTZ
+			);
+
+			CREATE INDEX IF NOT EXISTS notifications_pending_idx ON notifications(next_attempt_at) WHERE status = 'PENDING';
+			CREATE INDEX IF NOT EXISTS notifications_user_idx ON notifications(user_id, id);
+		`,
+	},
+	{
+		version: 21,
+		name:    "add unique pending payment intent",
+		query: `
+			-- a term has one pending intent at most, the older duplicates are failed
+			UPDATE payment_intents SET status = 'FAILED', failure_reason = 'superseded by a newer payment intent', updated_at = NOW()
+			WHERE status = 'PENDING' AND id NOT IN (
+				SELECT MAX(id) FROM payment_intents WHERE status = 'PENDING' GROUP BY loan_id, term
+			);
+
+			CREATE UNIQUE INDEX IF NOT EXISTS payment_intents_pending_term_idx ON payment_intents(loan_id, term) WHERE status = 'PENDING';
+		`,
+	},
+	{
+		version: 22,
+		name:    "add unapplied payment intents",
+		query: `
+			-- the amount the gateway received for an UNAPPLIED intent, to be refunded or applied by hand
+			ALTER TABLE payment_intents ADD COLUMN IF NOT EXISTS received_amount NUMERIC;
+		`,
+	},
+	{
+		version: 23,
+		name:    "drop unchecked permissions",
+		query: `
+			-- no endpoint ever checked report:read and user:read
+			DELETE FROM role_permissions WHERE permission IN ('report:read', 'user:read');
+
+			UPDATE roles SET description = 'records payments' WHERE name = 'FINANCE';
+			UPDATE roles SET description = 'reads loans and unlocks logins' WHERE name = 'SUPPORT';
+		`,
+	},
+	{
+		version: 24,
+		name:    "index login attempts by last failure",
+		query: `
+			-- the prune-login-attempts job deletes the attempts older than lockout.reset_after
+			CREATE INDEX IF NOT EXISTS login_attempts_last_failure_at_idx ON login_attempts(last_failure_at);
+		`,
+	},
+	{
+		version: 25,
+		name:    "add rate limit bucket refill time",
+		query: `
+			-- full_at is when a bucket is full again, the prune-rate-limits job deletes it from then on.
+			-- existing buckets get the slowest refill of the route policies, an hour
+			ALTER TABLE rate_limit_buckets ADD COLUMN IF NOT EXISTS full_at TIMESTAMPTZ;
+			UPDATE rate_limit_buckets SET full_at = updated_at + INTERVAL '1 hour' WHERE full_at IS NULL;
+			ALTER TABLE rate_limit_buckets ALTER COLUMN full_at SET NOT NULL;
+
+			CREATE INDEX IF NOT EXISTS rate_limit_buckets_full_at_idx ON rate_limit_buckets(full_at);
+		`,
+	},
+	{
+		version: 26,
+		name:    "add users password change required",
+		query: `
+			ALTER TABLE users ADD COLUMN IF NOT EXISTS password_change_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+			-- the admin seeded before this migration still has the published password "admin"
+			UPDATE users SET password_change_required = TRUE
+			WHERE email = 'admin@admin.com' AND password = '$2a$10$DnOPfZCTGIsFTmue/g.wJuaDfr.CCcpYW6y8MqJxnq3AJATTNmRwm';
+		`,
+	},
+}
+
+// LatestVersion is the schema version this build expects.
+func LatestVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// Version returns the highest applied migration, 0 when the database has never been migrated.
+func Version(ctx context.Context, db *sql.DB) (version int, err error) {
+	var exists bool
+	err = db.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists)
+	if err != nil || !exists {
+		return
+	}
+
+	err = db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+
+	return
+}
+
+// CheckVersion fails when the database schema is not at LatestVersion.
+func CheckVersion(ctx context.Context, db *sql.DB) error {
+	version, err := Version(ctx, db)
 	if err != nil {
-		fmt.Println(err)
+		return err
 	}
+	if version != LatestVersion() {
+		return fmt.Errorf("schema at version %d, expected %d", version, LatestVersion())
+	}
+	return nil
+}
 
-	_, err = res.PostgresDb.Query(`
-		CREATE TABLE IF NOT EXISTS users(
-			id BIGSERIAL PRIMARY KEY,
-			email TEXT UNIQUE,
-			password TEXT,
-			role UserRole,
-			created_at TIMESTAMPTZ,
-			updated_at TIMESTAMPTZ
+func Migrate(ctx context.Context, res *resource.Resource) (err error) {
+	_, err = res.PostgresDb.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations(
+			version INT PRIMARY KEY,
+			name TEXT,
+			applied_at TIMESTAMPTZ
 		);
 	`)
 	if err != nil {
-		fmt.Println(err)
+		return
 	}
 
-	_, err = res.PostgresDb.Query(`
-		CREATE TYPE LoanStatus AS ENUM ('PENDING','APPROVED','PAID');
-	`)
+	current, err := Version(ctx, res.PostgresDb)
 	if err != nil {
-		fmt.Println(err)
+		return
 	}
 
-	_, err = res.PostgresDb.Query(`
-		CREATE TABLE IF NOT EXISTS loans(
-			id BIGSERIAL PRIMARY KEY,
-			user_id BIGINT,
-			amount NUMERIC,
-			status LoanStatus,
-			created_at TIMESTAMPTZ,
-			updated_at TIMESTAMPTZ
-		);
-	`)
-	if err != nil {
-		fmt.Println(err)
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		err = apply(ctx, res.PostgresDb, m)
+		if err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+		slog.InfoContext(ctx, "applied migration", "version", m.version, "name", m.name)
 	}
 
-	_, err = res.PostgresDb.Query(`
-		CREATE TYPE RepaymentStatus AS ENUM ('PENDING','PAID');
-	`)
+	return
+}
+
+func apply(ctx context.Context, db *sql.DB, m migration) (err error) {
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		fmt.Println(err)
+		return
 	}
+	defer tx.Rollback()
 
-	_, err = res.PostgresDb.Query(`
-		CREATE TABLE IF NOT EXISTS repayments(
-			id BIGSERIAL PRIMARY KEY,
-			loan_id BIGINT,
-			minimum_payment NUMERIC,
-			actual_payment NUMERIC,
-			status RepaymentStatus,
-			due_date TIMESTAMPTZ,
-			created_at TIMESTAMPTZ,
-			updated_at TIMESTAMPTZ
-		);
-	`)
+	_, err = tx.ExecContext(ctx, m.query)
 	if err != nil {
-		fmt.Println(err)
+		return
 	}
-}
 
-func Seed(res *resource.Resource) {
-	_, err := res.PostgresDb.Query(`
-		INSERT INTO users(email,password,role,created_at,updated_at) VALUES ('admin@admin.com','$2a$10$DnOPfZCTGIsFTmue/g.wJuaDfr.CCcpYW6y8MqJxnq3AJATTNmRwm','ADMIN',NOW(),NOW())
-	`)
+	_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations(version, name, applied_at) VALUES ($1, $2, NOW())`, m.version, m.name)
 	if err != nil {
-		fmt.Println(err)
+		return
 	}
+
+	return tx.Commit()
 }
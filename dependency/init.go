@@ -1,25 +1,84 @@
 package dependency
 
 import (
+	"context"
+	"log/slog"
+
 	"example.com/m/v2/config"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/credential"
+	migration "example.com/m/v2/database"
+	"example.com/m/v2/health"
+	"example.com/m/v2/jobs"
+	"example.com/m/v2/lockout"
 	"example.com/m/v2/logic/handler"
 	rImpl "example.com/m/v2/logic/repository/impl"
+	"example.com/m/v2/metrics"
+	"example.com/m/v2/ratelimit"
 	"example.com/m/v2/resource"
 
 	ucImpl "example.com/m/v2/logic/usecase/impl"
 )
 
-func Init(cfg *config.Config, res *resource.Resource) Dependency {
-	repository := rImpl.New(res)
-	usecase := ucImpl.New(repository, cfg)
+func Init(cfg *config.Config, res *resource.Resource) (dep Dependency, err error) {
+	err = metrics.RegisterDbStats(res.PostgresDb, cfg.PostgresDb.Dbname)
+	if err != nil {
+		return
+	}
+
+	passwordPolicy, err := credential.NewPolicy(cfg.Password)
+	if err != nil {
+		return
+	}
+
+	lockoutStore, err := lockout.NewStore(cfg.Lockout, res.PostgresDb)
+	if err != nil {
+		return
+	}
+
+	var rateLimitStore ratelimit.Store
+	if cfg.RateLimit.Enabled {
+		rateLimitStore, err = ratelimit.NewStore(cfg.RateLimit, res.PostgresDb)
+		if err != nil {
+			return
+		}
+	}
+
+	loginGuard := lockout.New(lockoutStore, cfg.Lockout)
+	repository := rImpl.New(res, cfg)
+	usecase := ucImpl.New(repository, cfg, passwordPolicy, loginGuard)
 
-	return Dependency{
+	jobRunner, err := newJobRunner(cfg, res, usecase, loginGuard, rateLimitStore)
+	if err != nil {
+		return
+	}
+
+	dep = Dependency{
+		Config: cfg,
 		Handler: handler.Handler{
 			Usecase: usecase,
 		},
+		Logger:         slog.Default(),
+		RateLimitStore: rateLimitStore,
+		Jobs:           jobRunner,
+		Health: health.New(
+			constant.HealthCheckTimeout,
+			health.Ping("postgres", res.PostgresDb),
+			health.CheckerFunc("migrations", func(ctx context.Context) error {
+				return migration.CheckVersion(ctx, res.PostgresDb)
+			}),
+		),
 	}
+
+	return
 }
 
 type Dependency struct {
+	Config  *config.Config
 	Handler handler.Handler
+	Health  *health.Health
+	Logger  *slog.Logger
+	// RateLimitStore is nil when rate limiting is disabled
+	RateLimitStore ratelimit.Store
+	Jobs           *jobs.Runner
 }
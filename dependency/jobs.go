@@ -0,0 +1,120 @@
+package dependency
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"example.com/m/v2/config"
+	"example.com/m/v2/cron"
+	"example.com/m/v2/jobs"
+	"example.com/m/v2/lockout"
+	"example.com/m/v2/logger"
+	"example.com/m/v2/ratelimit"
+	"example.com/m/v2/resource"
+
+	uc "example.com/m/v2/logic/usecase"
+)
+
+// newJobRunner schedules the background jobs, a job with an empty schedule is left out. rateLimitStore is nil when
+// rate limiting is disabled, prune-rate-limits is then left out too.
+func newJobRunner(cfg *config.Config, res *resource.Resource, usecase uc.Usecase, loginGuard *lockout.Guard, rateLimitStore ratelimit.Store) (*jobs.Runner, error) {
+	store, err := jobs.NewStore(cfg.Jobs, res.PostgresDb)
+	if err != nil {
+		return nil, err
+	}
+
+	pruneRateLimits := cfg.Jobs.PruneRateLimits
+	if rateLimitStore == nil {
+		pruneRateLimits = ""
+	}
+
+	var scheduled []jobs.Job
+	for _, job := range []struct {
+		name string
+		spec string
+		run  func(ctx context.Context, now time.Time) error
+	}{
+		{
+			name: "mark-overdue",
+			spec: cfg.Jobs.MarkOverdue,
+			run: func(ctx context.Context, now time.Time) error {
+				run, err := usecase.MarkOverdue(ctx, now)
+				if err != nil {
+					return err
+				}
+				logger.FromContext(ctx).InfoContext(ctx, "overdue terms marked", "overdue", run.Overdue, "fees", run.Fees, "fee_amount", run.FeeAmount, "defaulted", run.Defaulted)
+				return nil
+			},
+		},
+		{
+			name: "expire-loans",
+			spec: cfg.Jobs.ExpireLoans,
+			run: func(ctx context.Context, now time.Time) error {
+				_, err := usecase.ExpirePendingLoans(ctx, now)
+				return err
+			},
+		},
+		{
+			name: "due-reminders",
+			spec: cfg.Jobs.DueReminders,
+			run: func(ctx context.Context, now time.Time) error {
+				_, err := usecase.SendDueReminders(ctx, now)
+				return err
+			},
+		},
+		{
+			name: "reconcile-disbursements",
+			spec: cfg.Jobs.ReconcileDisbursements,
+			run: func(ctx context.Context, now time.Time) error {
+				settled, err := usecase.ReconcileDisbursements(ctx, now)
+				if err != nil {
+					return err
+				}
+				logger.FromContext(ctx).InfoContext(ctx, "pending disbursements reconciled", "settled", settled)
+				return nil
+			},
+		},
+		{
+			name: "prune-login-attempts",
+			spec: cfg.Jobs.PruneLoginAttempts,
+			run: func(ctx context.Context, now time.Time) error {
+				pruned, err := loginGuard.Prune(ctx, now)
+				if err != nil {
+					return err
+				}
+				logger.FromContext(ctx).InfoContext(ctx, "login attempts pruned", "pruned", pruned)
+				return nil
+			},
+		},
+		{
+			name: "prune-rate-limits",
+			spec: pruneRateLimits,
+			run: func(ctx context.Context, now time.Time) error {
+				pruned, err := rateLimitStore.Prune(ctx, now)
+				if err != nil {
+					return err
+				}
+				logger.FromContext(ctx).InfoContext(ctx, "rate limit buckets pruned", "pruned", pruned)
+				return nil
+			},
+		},
+	} {
+		if job.spec == "" {
+			continue
+		}
+
+		schedule, err := cron.Parse(job.spec)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %w", job.name, err)
+		}
+		scheduled = append(scheduled, jobs.Job{
+			Name:     job.name,
+			Schedule: schedule,
+			Spec:     job.spec,
+			Run:      job.run,
+		})
+	}
+
+	return jobs.New(store, cfg.Jobs, scheduled...), nil
+}
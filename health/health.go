@@ -0,0 +1,153 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"example.com/m/v2/constant"
+	"example.com/m/v2/logger"
+	"example.com/m/v2/model"
+)
+
+const (
+	StatusOk          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Checker is a readiness component check, Check returns nil when the component is healthy.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type checkerFunc struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func (c checkerFunc) Name() string {
+	return c.name
+}
+
+func (c checkerFunc) Check(ctx context.Context) error {
+	return c.fn(ctx)
+}
+
+// CheckerFunc adapts fn to a Checker named name.
+func CheckerFunc(name string, fn func(ctx context.Context) error) Checker {
+	return checkerFunc{name: name, fn: fn}
+}
+
+// Ping checks db reachability.
+func Ping(name string, db *sql.DB) Checker {
+	return CheckerFunc(name, db.PingContext)
+}
+
+type Health struct {
+	mu           sync.RWMutex
+	checkers     []Checker
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+func New(timeout time.Duration, checkers ...Checker) *Health {
+	return &Health{
+		checkers: checkers,
+		timeout:  timeout,
+	}
+}
+
+func (h *Health) Register(c Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checkers = append(h.checkers, c)
+}
+
+// SetShuttingDown makes readiness fail so the orchestrator stops routing traffic while the server drains.
+func (h *Health) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Liveness reports that the process is up and serving http.
+func (h *Health) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, model.HealthRes{
+		Status: StatusOk,
+	})
+}
+
+// Readiness runs every registered check concurrently and reports 503 when any of them fails.
+func (h *Health) Readiness(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	checkers := h.checkers
+	h.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	res := model.HealthRes{
+		Status: StatusOk,
+		Checks: make(map[string]model.HealthCheckRes, len(checkers)+1),
+	}
+
+	if h.shuttingDown.Load() {
+		res.Checks["shutdown"] = model.HealthCheckRes{Status: StatusUnavailable, Error: "server is shutting down"}
+	} else {
+		res.Checks["shutdown"] = model.HealthCheckRes{Status: StatusOk}
+	}
+
+	results := make([]model.HealthCheckRes, len(checkers))
+	var wg sync.WaitGroup
+	for idx, c := range checkers {
+		wg.Add(1)
+		go func(idx int, c Checker) {
+			defer wg.Done()
+			results[idx] = run(ctx, c)
+		}(idx, c)
+	}
+	wg.Wait()
+
+	for idx, c := range checkers {
+		res.Checks[c.Name()] = results[idx]
+	}
+
+	status := http.StatusOK
+	for _, c := range res.Checks {
+		if c.Status != StatusOk {
+			res.Status = StatusUnavailable
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	writeJson(w, status, res)
+}
+
+// errCheckFailed replaces the error of a failed check in the response, readiness is served unauthenticated and
+// dependency errors can name hosts and databases. The error itself is logged.
+const errCheckFailed = "dependency unavailable"
+
+func run(ctx context.Context, c Checker) model.HealthCheckRes {
+	start := time.Now()
+	err := c.Check(ctx)
+	res := model.HealthCheckRes{
+		Status:   StatusOk,
+		Duration: time.Since(start).String(),
+	}
+	if err != nil {
+		res.Status = StatusUnavailable
+		res.Error = errCheckFailed
+		logger.FromContext(ctx).WarnContext(ctx, "readiness check failed", "check", c.Name(), "error", err)
+	}
+	return res
+}
+
+func writeJson(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set(constant.HttpHeaderContentType, constant.HttpHeaderAppJson)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,106 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/m/v2/model"
+)
+
+func Test_Liveness(t *testing.T) {
+	h := New(time.Second)
+	w := httptest.NewRecorder()
+
+	h.Liveness(w, httptest.NewRequest("GET", "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Status code returned, %d, did not match expected code %d", w.Code, http.StatusOK)
+	}
+
+	var got model.HealthRes
+	json.NewDecoder(w.Body).Decode(&got)
+	if got.Status != StatusOk {
+		t.Errorf("handler returned unexpected status: got %s want %s", got.Status, StatusOk)
+	}
+}
+
+func Test_Readiness(t *testing.T) {
+	ok := CheckerFunc("postgres", func(ctx context.Context) error {
+		return nil
+	})
+	fail := CheckerFunc("migrations", func(ctx context.Context) error {
+		return errors.New("schema at version 1, expected 3")
+	})
+
+	tests := []struct {
+		name           string
+		checkers       []Checker
+		shuttingDown   bool
+		wantStatusCode int
+		wantChecks     map[string]model.HealthCheckRes
+	}{
+		{
+			name:           "ready",
+			checkers:       []Checker{ok},
+			wantStatusCode: http.StatusOK,
+			wantChecks: map[string]model.HealthCheckRes{
+				"postgres": {Status: StatusOk},
+				"shutdown": {Status: StatusOk},
+			},
+		},
+		{
+			name:           "failing check",
+			checkers:       []Checker{ok, fail},
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantChecks: map[string]model.HealthCheckRes{
+				"postgres":   {Status: StatusOk},
+				"migrations": {Status: StatusUnavailable, Error: "dependency unavailable"},
+				"shutdown":   {Status: StatusOk},
+			},
+		},
+		{
+			name:           "shutting down",
+			checkers:       []Checker{ok},
+			shuttingDown:   true,
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantChecks: map[string]model.HealthCheckRes{
+				"postgres": {Status: StatusOk},
+				"shutdown": {Status: StatusUnavailable, Error: "server is shutting down"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(time.Second)
+			for _, c := range tt.checkers {
+				h.Register(c)
+			}
+			if tt.shuttingDown {
+				h.SetShuttingDown()
+			}
+
+			w := httptest.NewRecorder()
+			h.Readiness(w, httptest.NewRequest("GET", "/readyz", nil))
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("Status code returned, %d, did not match expected code %d", w.Code, tt.wantStatusCode)
+			}
+
+			var got model.HealthRes
+			json.NewDecoder(w.Body).Decode(&got)
+			if len(got.Checks) != len(tt.wantChecks) {
+				t.Fatalf("handler returned unexpected checks: got %+v want %+v", got.Checks, tt.wantChecks)
+			}
+			for name, want := range tt.wantChecks {
+				check := got.Checks[name]
+				if check.Status != want.Status || check.Error != want.Error {
+					t.Errorf("handler returned unexpected check %s: got %+v want %+v", name, check, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"example.com/m/v2/config"
+	"example.com/m/v2/cron"
+	"example.com/m/v2/metrics"
+	"example.com/m/v2/poll"
+	"example.com/m/v2/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Job is a task run on a cron schedule, now is the time the run was claimed at.
+type Job struct {
+	Name     string
+	Schedule cron.Schedule
+	// Spec is the cron expression Schedule was parsed from, a changed spec reschedules the job
+	Spec string
+	Run  func(ctx context.Context, now time.Time) error
+}
+
+// Claim is a due job leased to one runner, Attempts counts the failed runs since its last success.
+type Claim struct {
+	Name     string
+	Attempts int
+}
+
+// Store keeps when every job runs next, implementations must hand a due job to a single caller until its lease ends.
+type Store interface {
+	// Register adds a job first running at next. A known job keeps its state unless its spec changed, it then
+	// runs at next.
+	Register(ctx context.Context, name, spec string, next time.Time) error
+	// Claim leases a job among names that is due at now until now+lease, ok is false when none is due.
+	Claim(ctx context.Context, names []string, now time.Time, lease time.Duration) (claim Claim, ok bool, err error)
+	// Finish ends the lease of a run at, the job runs again at next. runErr is empty after a success.
+	Finish(ctx context.Context, name string, next time.Time, attempts int, runErr string, at time.Time) error
+}
+
+// NewStore builds the Store selected by cfg.Store, db is only used by the postgres store.
+func NewStore(cfg config.Jobs, db *sql.DB) (Store, error) {
+	switch cfg.Store {
+	case "postgres":
+		return NewPostgresStore(db), nil
+	case "memory":
+		return NewMemoryStore(), nil
+	}
+	return nil, fmt.Errorf("unknown jobs store %q", cfg.Store)
+}
+
+// Runner runs the jobs that are due every poll interval. A failing run is retried with an exponential backoff, after
+// MaxAttempts failures the job waits for its next schedule.
+type Runner struct {
+	store Store
+	cfg   config.Jobs
+	jobs  map[string]Job
+	names []string
+	now   func() time.Time
+}
+
+func New(store Store, cfg config.Jobs, jobs ...Job) *Runner {
+	r := &Runner{
+		store: store,
+		cfg:   cfg,
+		jobs:  map[string]Job{},
+		now:   time.Now,
+	}
+	for _, job := range jobs {
+		r.jobs[job.Name] = job
+		r.names = append(r.names, job.Name)
+	}
+	return r
+}
+
+// Run registers the jobs, then runs the due ones until ctx is done. Store errors are logged and retried at the next
+// poll, only a failed registration stops it.
+func (r *Runner) Run(ctx context.Context) error {
+	err := r.Register(ctx)
+	if err != nil {
+		return err
+	}
+
+	poll.Run(ctx, "job runner", r.cfg.PollInterval, func(ctx context.Context) (int, error) {
+		return 0, r.RunDue(ctx)
+	}, "jobs", r.names)
+	return nil
+}
+
+// Register schedules the jobs from now, jobs already known to the store keep their next run. It fails for a schedule
+// that never matches, the job would be due all the time.
+func (r *Runner) Register(ctx context.Context) error {
+	now := r.now().UTC()
+	for _, name := range r.names {
+		job := r.jobs[name]
+		next := job.Schedule.Next(now)
+		if next.IsZero() {
+			return fmt.Errorf("register job %s: schedule %q never matches", name, job.Spec)
+		}
+		err := r.store.Register(ctx, name, job.Spec, next)
+		if err != nil {
+			return fmt.Errorf("register job %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// RunDue runs the due jobs one after the other until none is left.
+func (r *Runner) RunDue(ctx context.Context) error {
+	for ctx.Err() == nil {
+		now := r.now().UTC()
+		claim, ok, err := r.store.Claim(ctx, r.names, now, r.cfg.Lease)
+		if err != nil || !ok {
+			return err
+		}
+
+		err = r.run(ctx, claim, now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Runner) run(ctx context.Context, claim Claim, now time.Time) (err error) {
+	job := r.jobs[claim.Name]
+	log := slog.Default().With("job", job.Name)
+
+	next, attempts, lastError := job.Schedule.Next(now), 0, ""
+	if next.IsZero() {
+		return fmt.Errorf("job %s: schedule %q never matches", job.Name, job.Spec)
+	}
+
+	runErr := r.runJob(ctx, job, now)
+
+	if runErr != nil {
+		attempts, lastError = claim.Attempts+1, runErr.Error()
+		if attempts < r.cfg.MaxAttempts {
+			next = now.Add(r.backoff(attempts))
+			log.WarnContext(ctx, "job failed, retrying", "attempt", attempts, "retry_at", next, "error", runErr)
+		} else {
+			attempts = 0
+			log.ErrorContext(ctx, "job failed, waiting for its next schedule", "attempts", r.cfg.MaxAttempts, "next_run_at", next, "error", runErr)
+		}
+		metrics.JobRun(job.Name, false)
+	} else {
+		log.InfoContext(ctx, "job done", "duration", r.now().Sub(now), "next_run_at", next)
+		metrics.JobRun(job.Name, true)
+	}
+
+	// the outcome is recorded even when ctx is cancelled during the run, the run would be repeated otherwise
+	return r.store.Finish(context.WithoutCancel(ctx), job.Name, next, attempts, lastError, r.now().UTC())
+}
+
+// runJob runs job within its lease, a panic fails the run instead of the runner.
+func (r *Runner) runJob(ctx context.Context, job Job, now time.Time) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Lease)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "jobs."+job.Name, attribute.String("job", job.Name))
+	defer tracing.End(span, &err)
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panicked: %v", p)
+		}
+	}()
+
+	return job.Run(ctx, now)
+}
+
+// backoff is the delay before retrying a job that failed attempts times.
+func (r *Runner) backoff(attempts int) time.Duration {
+	return poll.Backoff(r.cfg.BaseBackoff, r.cfg.MaxBackoff, attempts)
+}
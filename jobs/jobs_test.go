@@ -0,0 +1,185 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"example.com/m/v2/config"
+	"example.com/m/v2/cron"
+)
+
+var testCfg = config.Jobs{
+	PollInterval: time.Second,
+	Lease:        time.Minute,
+	MaxAttempts:  3,
+	BaseBackoff:  10 * time.Second,
+	MaxBackoff:   30 * time.Second,
+}
+
+func hourly(t *testing.T, name string, run func(ctx context.Context, now time.Time) error) Job {
+	s, err := cron.Parse("@hourly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Job{Name: name, Schedule: s, Spec: "@hourly", Run: run}
+}
+
+// clock is the time the runner sees, tests move it by hand
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func Test_Runner(t *testing.T) {
+	ctx := context.Background()
+	c := &clock{now: time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC)}
+
+	var runs []time.Time
+	fail := 0
+	job := hourly(t, "tes", func(ctx context.Context, now time.Time) error {
+		runs = append(runs, now)
+		if fail > 0 {
+			fail--
+			return errors.New("err tes")
+		}
+		return nil
+	})
+
+	store := NewMemoryStore()
+	r := New(store, testCfg, job)
+	r.now = c.Now
+
+	if err := r.Register(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	runDue := func(wantRuns int) {
+		t.Helper()
+		runs = nil
+		if err := r.RunDue(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if len(runs) != wantRuns {
+			t.Fatalf("at %v want %d runs, got %d", c.now, wantRuns, len(runs))
+		}
+	}
+
+	// nothing is due before the first schedule
+	runDue(0)
+
+	c.now = time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	runDue(1)
+	// it is rescheduled to the next hour
+	runDue(0)
+	c.now = c.now.Add(59 * time.Minute)
+	runDue(0)
+
+	// failures are retried 10s then 20s later, the third one waits for the next schedule
+	fail = 3
+	c.now = time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC)
+	runDue(1)
+	c.now = c.now.Add(9 * time.Second)
+	runDue(0)
+	c.now = c.now.Add(time.Second)
+	runDue(1)
+	c.now = c.now.Add(20 * time.Second)
+	runDue(1)
+	c.now = c.now.Add(time.Minute)
+	runDue(0)
+	c.now = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	runDue(1)
+
+	// registering again, on the next boot, keeps the schedule
+	c.now = time.Date(2026, 10, 19, 12, 30, 0, 0, time.UTC)
+	if err := r.Register(ctx); err != nil {
+		t.Fatal(err)
+	}
+	runDue(0)
+}
+
+func Test_Runner_recovers_panics(t *testing.T) {
+	ctx := context.Background()
+	c := &clock{now: time.Date(2026, 10, 19, 9, 59, 0, 0, time.UTC)}
+
+	store := NewMemoryStore()
+	r := New(store, testCfg, hourly(t, "tes", func(ctx context.Context, now time.Time) error {
+		panic("boom")
+	}))
+	r.now = c.Now
+
+	if err := r.Register(ctx); err != nil {
+		t.Fatal(err)
+	}
+	c.now = c.now.Add(time.Minute)
+	if err := r.RunDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	job := store.(*memoryStore).jobs["tes"]
+	if job.attempts != 1 || job.lastError != "job panicked: boom" {
+		t.Errorf("want a failed attempt, got %+v", job)
+	}
+}
+
+func Test_Runner_Register_never_matching(t *testing.T) {
+	s, err := cron.Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewMemoryStore()
+	r := New(store, testCfg, Job{Name: "tes", Schedule: s, Spec: "0 0 30 2 *", Run: func(ctx context.Context, now time.Time) error {
+		return nil
+	}})
+
+	err = r.Register(context.Background())
+	if err == nil || err.Error() != `register job tes: schedule "0 0 30 2 *" never matches` {
+		t.Errorf("want the schedule refused, got %v", err)
+	}
+	if len(store.(*memoryStore).jobs) != 0 {
+		t.Error("want nothing registered")
+	}
+}
+
+func Test_memoryStore_Claim(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+
+	store := NewMemoryStore()
+	store.Register(ctx, "late", "@hourly", now.Add(-time.Hour))
+	store.Register(ctx, "later", "@hourly", now.Add(-2*time.Hour))
+	store.Register(ctx, "future", "@hourly", now.Add(time.Hour))
+	names := []string{"late", "later", "future"}
+
+	// the most overdue job comes first and a leased job is not handed out twice
+	for _, want := range []string{"later", "late"} {
+		claim, ok, _ := store.Claim(ctx, names, now, time.Minute)
+		if !ok || claim.Name != want {
+			t.Fatalf("want claim of %s, got %+v (ok %v)", want, claim, ok)
+		}
+	}
+	if claim, ok, _ := store.Claim(ctx, names, now, time.Minute); ok {
+		t.Fatalf("want no claim, got %+v", claim)
+	}
+
+	// an expired lease can be claimed again, the run is assumed lost
+	claim, ok, _ := store.Claim(ctx, names, now.Add(time.Minute), time.Minute)
+	if !ok || claim.Name != "later" {
+		t.Fatalf("want the expired lease claimed again, got %+v (ok %v)", claim, ok)
+	}
+}
+
+func Test_Runner_backoff(t *testing.T) {
+	r := New(NewMemoryStore(), testCfg)
+	for attempts, want := range map[int]time.Duration{
+		1: 10 * time.Second,
+		2: 20 * time.Second,
+		3: 30 * time.Second,
+		9: 30 * time.Second,
+	} {
+		if got := r.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryJob struct {
+	spec        string
+	nextRunAt   time.Time
+	lockedUntil time.Time
+	attempts    int
+	lastError   string
+}
+
+type memoryStore struct {
+	mu   sync.Mutex
+	jobs map[string]*memoryJob
+}
+
+// NewMemoryStore keeps the schedule in process memory, it restarts on every boot and is not shared between
+// instances, each of them would run every job.
+func NewMemoryStore() Store {
+	return &memoryStore{jobs: map[string]*memoryJob{}}
+}
+
+func (s *memoryStore) Register(ctx context.Context, name, spec string, next time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[name]; ok && job.spec == spec {
+		return nil
+	}
+	s.jobs[name] = &memoryJob{spec: spec, nextRunAt: next}
+	return nil
+}
+
+func (s *memoryStore) Claim(ctx context.Context, names []string, now time.Time, lease time.Duration) (Claim, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due string
+	for _, name := range names {
+		job, ok := s.jobs[name]
+		if !ok || job.nextRunAt.After(now) || job.lockedUntil.After(now) {
+			continue
+		}
+		if due == "" || job.nextRunAt.Before(s.jobs[due].nextRunAt) {
+			due = name
+		}
+	}
+	if due == "" {
+		return Claim{}, false, nil
+	}
+
+	job := s.jobs[due]
+	job.lockedUntil = now.Add(lease)
+	return Claim{Name: due, Attempts: job.attempts}, true, nil
+}
+
+func (s *memoryStore) Finish(ctx context.Context, name string, next time.Time, attempts int, runErr string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[name]; ok {
+		job.nextRunAt = next
+		job.attempts = attempts
+		job.lastError = runErr
+		job.lockedUntil = time.Time{}
+	}
+	return nil
+}
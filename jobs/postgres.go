@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"example.com/m/v2/tracing"
+	"github.com/lib/pq"
+)
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore keeps the schedule in the jobs table, shared by every instance.
+func NewPostgresStore(db *sql.DB) Store {
+	return &postgresStore{db: db}
+}
+
+func (s *postgresStore) Register(ctx context.Context, name, spec string, next time.Time) (err error) {
+	ctx, span := tracing.StartDb(ctx, "jobs.Register", "INSERT", "jobs")
+	defer tracing.End(span, &err)
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO jobs(name, schedule, next_run_at) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET
+			schedule = EXCLUDED.schedule,
+			next_run_at = EXCLUDED.next_run_at,
+			attempts = 0
+		WHERE jobs.schedule <> EXCLUDED.schedule
+	`, name, spec, next)
+
+	return
+}
+
+// Claim leases the most overdue job. SKIP LOCKED lets concurrent runners pass over a row another one is claiming
+// instead of waiting for it, the lease then keeps it from being claimed again while it runs.
+func (s *postgresStore) Claim(ctx context.Context, names []string, now time.Time, lease time.Duration) (claim Claim, ok bool, err error) {
+	ctx, span := tracing.StartDb(ctx, "jobs.Claim", "UPDATE", "jobs")
+	defer tracing.End(span, &err)
+
+	err = s.db.QueryRowContext(ctx, `
+		UPDATE jobs SET locked_until = $3
+		WHERE name = (
+			SELECT name FROM jobs
+			WHERE name = ANY($1) AND next_run_at <= $2 AND (locked_until IS NULL OR locked_until <= $2)
+			ORDER BY next_run_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING name, attempts
+	`, pq.Array(names), now, now.Add(lease)).Scan(&claim.Name, &claim.Attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return claim, false, nil
+	}
+
+	return claim, err == nil, err
+}
+
+func (s *postgresStore) Finish(ctx context.Context, name string, next time.Time, attempts int, runErr string, at time.Time) (err error) {
+	ctx, span := tracing.StartDb(ctx, "jobs.Finish", "UPDATE", "jobs")
+	defer tracing.End(span, &err)
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE jobs SET
+			next_run_at = $2,
+			attempts = $3,
+			last_error = NULLIF($4, ''),
+			last_run_at = $5,
+			last_success_at = CASE WHEN $4 = '' THEN $5 ELSE last_success_at END,
+			locked_until = NULL
+		WHERE name = $1
+	`, name, next, attempts, runErr, at)
+
+	return
+}
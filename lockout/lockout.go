@@ -0,0 +1,152 @@
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/config"
+)
+
+// State is the failed login history of one key.
+type State struct {
+	Failures      int
+	LastFailureAt time.Time
+}
+
+// Store keeps failed login counts, implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the zero State for unknown keys.
+	Get(ctx context.Context, key string) (State, error)
+	// Fail records a failure at now, counting from 1 again when the previous one happened before resetBefore.
+	Fail(ctx context.Context, key string, now, resetBefore time.Time) (State, error)
+	Reset(ctx context.Context, key string) error
+	// Prune deletes the keys whose last failure is before resetBefore, they are unknown keys again.
+	Prune(ctx context.Context, resetBefore time.Time) (pruned int64, err error)
+}
+
+// NewStore builds the Store selected by cfg.Store, db is only used by the postgres store.
+func NewStore(cfg config.Lockout, db *sql.DB) (Store, error) {
+	switch cfg.Store {
+	case "postgres":
+		return NewPostgresStore(db), nil
+	case "memory":
+		return NewMemoryStore(), nil
+	}
+	return nil, fmt.Errorf("unknown lockout store %q", cfg.Store)
+}
+
+func AccountKey(email string) string {
+	return "account:" + email
+}
+
+func IpKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Guard locks accounts and client ips after repeated failed logins. Once a key reaches its threshold every
+// further failure doubles the lock, starting at BaseDelay and capped at MaxDelay.
+type Guard struct {
+	store Store
+	cfg   config.Lockout
+	now   func() time.Time
+}
+
+func New(store Store, cfg config.Lockout) *Guard {
+	return &Guard{
+		store: store,
+		cfg:   cfg,
+		now:   time.Now,
+	}
+}
+
+// Check fails with apperror.ErrTooManyLoginAttempts while the email or the ip is locked, an empty ip is ignored.
+func (g *Guard) Check(ctx context.Context, email, ip string) (err error) {
+	now := g.now()
+	var wait time.Duration
+	for _, k := range g.keys(email, ip) {
+		state, err := g.store.Get(ctx, k.key)
+		if err != nil {
+			return err
+		}
+		if d := g.lockedUntil(state, k.threshold).Sub(now); d > wait {
+			wait = d
+		}
+	}
+	if wait > 0 {
+		err = apperror.ErrTooManyLoginAttempts.WithRetryAfter(wait)
+	}
+
+	return
+}
+
+// Fail records a failed login for the email and the ip.
+func (g *Guard) Fail(ctx context.Context, email, ip string) (err error) {
+	now := g.now()
+	for _, k := range g.keys(email, ip) {
+		_, err = g.store.Fail(ctx, k.key, now, now.Add(-g.cfg.ResetAfter))
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// Succeed forgets the failures of the email. The ip keeps its count, otherwise an attacker owning one account
+// could reset it between guesses on others.
+func (g *Guard) Succeed(ctx context.Context, email string) error {
+	return g.store.Reset(ctx, AccountKey(email))
+}
+
+// Unlock forgets the failures of the email and the ip, empty values are skipped.
+func (g *Guard) Unlock(ctx context.Context, email, ip string) (err error) {
+	for _, k := range g.keys(email, ip) {
+		err = g.store.Reset(ctx, k.key)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// Prune deletes the failures older than ResetAfter at now. ResetAfter is at least MaxDelay, so their locks are over
+// and their next failure would count from 1 again: a pruned key behaves as it did.
+func (g *Guard) Prune(ctx context.Context, now time.Time) (pruned int64, err error) {
+	return g.store.Prune(ctx, now.Add(-g.cfg.ResetAfter))
+}
+
+type guardKey struct {
+	key       string
+	threshold int
+}
+
+func (g *Guard) keys(email, ip string) (keys []guardKey) {
+	if email != "" {
+		keys = append(keys, guardKey{key: AccountKey(email), threshold: g.cfg.AccountThreshold})
+	}
+	if ip != "" {
+		keys = append(keys, guardKey{key: IpKey(ip), threshold: g.cfg.IpThreshold})
+	}
+	return
+}
+
+// lockedUntil is the zero time when state is below threshold.
+func (g *Guard) lockedUntil(state State, threshold int) time.Time {
+	if state.Failures < threshold {
+		return time.Time{}
+	}
+
+	delay := g.cfg.MaxDelay
+	// past 30 doublings any sane BaseDelay exceeds MaxDelay, the bound also keeps the shift from overflowing
+	if exp := state.Failures - threshold; exp < 30 {
+		if d := g.cfg.BaseDelay << exp; d < delay {
+			delay = d
+		}
+	}
+
+	return state.LastFailureAt.Add(delay)
+}
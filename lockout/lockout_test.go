@@ -0,0 +1,199 @@
+package lockout
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/config"
+)
+
+var testCfg = config.Lockout{
+	Store:            "memory",
+	AccountThreshold: 3,
+	IpThreshold:      5,
+	BaseDelay:        time.Second,
+	MaxDelay:         time.Minute,
+	ResetAfter:       time.Hour,
+}
+
+func Test_Guard(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		// fails are the failed logins recorded as email/ip pairs, each one a second apart
+		fails     [][2]string
+		succeed   string
+		unlock    [2]string
+		elapsed   time.Duration
+		email     string
+		ip        string
+		wantRetry time.Duration
+	}{
+		{
+			name:  "below threshold",
+			fails: [][2]string{{"a@tes.com", "192.0.2.1"}, {"a@tes.com", "192.0.2.1"}},
+			email: "a@tes.com",
+			ip:    "192.0.2.1",
+		},
+		{
+			name:      "account locked at threshold",
+			fails:     [][2]string{{"a@tes.com", "192.0.2.1"}, {"a@tes.com", "192.0.2.2"}, {"a@tes.com", "192.0.2.3"}},
+			email:     "a@tes.com",
+			ip:        "192.0.2.4",
+			wantRetry: time.Second,
+		},
+		{
+			name: "lock doubles with every failure past threshold",
+			fails: [][2]string{
+				{"a@tes.com", ""}, {"a@tes.com", ""}, {"a@tes.com", ""}, {"a@tes.com", ""}, {"a@tes.com", ""},
+			},
+			email:     "a@tes.com",
+			wantRetry: 4 * time.Second,
+		},
+		{
+			name: "lock is capped",
+			fails: func() (fails [][2]string) {
+				for i := 0; i < 40; i++ {
+					fails = append(fails, [2]string{"a@tes.com", ""})
+				}
+				return
+			}(),
+			email:     "a@tes.com",
+			wantRetry: time.Minute,
+		},
+		{
+			name:    "lock expires",
+			fails:   [][2]string{{"a@tes.com", ""}, {"a@tes.com", ""}, {"a@tes.com", ""}},
+			elapsed: 2 * time.Second,
+			email:   "a@tes.com",
+		},
+		{
+			name: "ip locked across emails",
+			fails: [][2]string{
+				{"a@tes.com", "192.0.2.1"}, {"b@tes.com", "192.0.2.1"}, {"c@tes.com", "192.0.2.1"},
+				{"d@tes.com", "192.0.2.1"}, {"e@tes.com", "192.0.2.1"},
+			},
+			email:     "f@tes.com",
+			ip:        "192.0.2.1",
+			wantRetry: time.Second,
+		},
+		{
+			name:    "success resets the account",
+			fails:   [][2]string{{"a@tes.com", "192.0.2.1"}, {"a@tes.com", "192.0.2.1"}, {"a@tes.com", "192.0.2.1"}},
+			succeed: "a@tes.com",
+			email:   "a@tes.com",
+			ip:      "192.0.2.1",
+		},
+		{
+			name: "success keeps the ip count",
+			fails: [][2]string{
+				{"a@tes.com", "192.0.2.1"}, {"a@tes.com", "192.0.2.1"}, {"a@tes.com", "192.0.2.1"},
+				{"b@tes.com", "192.0.2.1"}, {"c@tes.com", "192.0.2.1"},
+			},
+			succeed:   "a@tes.com",
+			email:     "a@tes.com",
+			ip:        "192.0.2.1",
+			wantRetry: time.Second,
+		},
+		{
+			name:   "unlock",
+			fails:  [][2]string{{"a@tes.com", "192.0.2.1"}, {"a@tes.com", "192.0.2.1"}, {"a@tes.com", "192.0.2.1"}},
+			unlock: [2]string{"a@tes.com", ""},
+			email:  "a@tes.com",
+			ip:     "192.0.2.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			now := start
+			g := New(NewMemoryStore(), testCfg)
+			g.now = func() time.Time { return now }
+
+			for i, f := range tt.fails {
+				if i > 0 {
+					now = now.Add(time.Second)
+				}
+				if err := g.Fail(ctx, f[0], f[1]); err != nil {
+					t.Fatalf("Fail: %v", err)
+				}
+			}
+			if tt.succeed != "" {
+				g.Succeed(ctx, tt.succeed)
+			}
+			if tt.unlock != [2]string{} {
+				g.Unlock(ctx, tt.unlock[0], tt.unlock[1])
+			}
+			now = now.Add(tt.elapsed)
+
+			err := g.Check(ctx, tt.email, tt.ip)
+			if tt.wantRetry == 0 {
+				if err != nil {
+					t.Errorf("Check test failed. want nil, got %v", err)
+				}
+				return
+			}
+			appErr, ok := err.(*apperror.Error)
+			if !ok || appErr.Code != apperror.CodeTooManyRequests || appErr.RetryAfter != tt.wantRetry {
+				t.Errorf("Check test failed. want retry after %v, got %#v", tt.wantRetry, err)
+			}
+		})
+	}
+}
+
+func Test_memoryStore_evict(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewMemoryStore().(*memoryStore)
+
+	for i := 0; i < minSweep; i++ {
+		s.Fail(ctx, IpKey(fmt.Sprint(i)), start, start.Add(-time.Hour))
+	}
+	later := start.Add(2 * time.Hour)
+	state, _ := s.Fail(ctx, AccountKey("a@tes.com"), later, later.Add(-time.Hour))
+
+	if len(s.entries) != 1 {
+		t.Errorf("evict test failed. want 1 entry, got %d", len(s.entries))
+	}
+	if state.Failures != 1 {
+		t.Errorf("evict test failed. want 1 failure, got %d", state.Failures)
+	}
+}
+
+func Test_memoryStore_Fail_resets_stale(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+
+	s.Fail(ctx, "k", start, start.Add(-time.Hour))
+	s.Fail(ctx, "k", start, start.Add(-time.Hour))
+	later := start.Add(2 * time.Hour)
+	state, _ := s.Fail(ctx, "k", later, later.Add(-time.Hour))
+
+	if state.Failures != 1 || !state.LastFailureAt.Equal(later) {
+		t.Errorf("Fail test failed. want 1 failure at %v, got %+v", later, state)
+	}
+}
+
+func Test_Guard_Prune(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewMemoryStore().(*memoryStore)
+	g := New(s, testCfg)
+
+	s.Fail(ctx, IpKey("192.0.2.1"), start, start.Add(-time.Hour))
+	s.Fail(ctx, AccountKey("a@tes.com"), start.Add(30*time.Minute), start)
+
+	pruned, err := g.Prune(ctx, start.Add(time.Hour+time.Minute))
+	if err != nil || pruned != 1 {
+		t.Fatalf("Prune test failed. want 1 pruned, got %d, err: %v", pruned, err)
+	}
+	if _, ok := s.entries[AccountKey("a@tes.com")]; !ok || len(s.entries) != 1 {
+		t.Errorf("Prune test failed. want only the recent failure kept, got %+v", s.entries)
+	}
+}
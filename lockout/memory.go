@@ -0,0 +1,79 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// minSweep is the number of entries a MemoryStore holds before it starts evicting stale ones.
+const minSweep = 1024
+
+type memoryStore struct {
+	mu      sync.Mutex
+	entries map[string]State
+	sweepAt int
+}
+
+// NewMemoryStore keeps failures in process memory, they are lost on restart and not shared between instances.
+// Entries older than the reset window are evicted once the map grows, so spraying ips can't exhaust memory.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		entries: map[string]State{},
+		sweepAt: minSweep,
+	}
+}
+
+func (s *memoryStore) Get(ctx context.Context, key string) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.entries[key], nil
+}
+
+func (s *memoryStore) Fail(ctx context.Context, key string, now, resetBefore time.Time) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.entries) >= s.sweepAt {
+		s.evict(resetBefore)
+	}
+
+	state := s.entries[key]
+	if state.LastFailureAt.Before(resetBefore) {
+		state.Failures = 0
+	}
+	state.Failures++
+	state.LastFailureAt = now
+	s.entries[key] = state
+
+	return state, nil
+}
+
+func (s *memoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *memoryStore) Prune(ctx context.Context, resetBefore time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.entries)
+	s.evict(resetBefore)
+	return int64(n - len(s.entries)), nil
+}
+
+// evict drops entries whose last failure is before resetBefore, then waits for the map to double before the next
+// sweep so the cost stays amortized constant per failure.
+func (s *memoryStore) evict(resetBefore time.Time) {
+	for k, state := range s.entries {
+		if state.LastFailureAt.Before(resetBefore) {
+			delete(s.entries, k)
+		}
+	}
+	s.sweepAt = max(minSweep, 2*len(s.entries))
+}
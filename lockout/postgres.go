@@ -0,0 +1,70 @@
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"example.com/m/v2/tracing"
+)
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore keeps failures in the login_attempts table, shared by every instance.
+func NewPostgresStore(db *sql.DB) Store {
+	return &postgresStore{db: db}
+}
+
+func (s *postgresStore) Get(ctx context.Context, key string) (state State, err error) {
+	ctx, span := tracing.StartDb(ctx, "lockout.Get", "SELECT", "login_attempts")
+	defer tracing.End(span, &err)
+
+	err = s.db.QueryRowContext(ctx, `SELECT failures, last_failure_at FROM login_attempts WHERE key = $1`, key).
+		Scan(&state.Failures, &state.LastFailureAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+
+	return
+}
+
+func (s *postgresStore) Fail(ctx context.Context, key string, now, resetBefore time.Time) (state State, err error) {
+	ctx, span := tracing.StartDb(ctx, "lockout.Fail", "INSERT", "login_attempts")
+	defer tracing.End(span, &err)
+
+	// stale rows are restarted in place until Prune deletes them
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO login_attempts(key, failures, last_failure_at) VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < $3 THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure_at = $2
+		RETURNING failures, last_failure_at
+	`, key, now, resetBefore).Scan(&state.Failures, &state.LastFailureAt)
+
+	return
+}
+
+func (s *postgresStore) Reset(ctx context.Context, key string) (err error) {
+	ctx, span := tracing.StartDb(ctx, "lockout.Reset", "DELETE", "login_attempts")
+	defer tracing.End(span, &err)
+
+	_, err = s.db.ExecContext(ctx, `DELETE FROM login_attempts WHERE key = $1`, key)
+
+	return
+}
+
+func (s *postgresStore) Prune(ctx context.Context, resetBefore time.Time) (pruned int64, err error) {
+	ctx, span := tracing.StartDb(ctx, "lockout.Prune", "DELETE", "login_attempts")
+	defer tracing.End(span, &err)
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM login_attempts WHERE last_failure_at < $1`, resetBefore)
+	if err != nil {
+		return
+	}
+	pruned, err = res.RowsAffected()
+
+	return
+}
@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"example.com/m/v2/config"
+)
+
+type ctxKey struct{}
+
+// New builds the application logger from cfg, writing to w.
+func New(cfg config.Log, w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(cfg.Level))
+	if err != nil {
+		return nil, fmt.Errorf("log level: %w", err)
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(cfg.Format) {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("log format %q must be json or text", cfg.Format)
+	}
+}
+
+// WithContext returns a copy of ctx carrying l.
+func WithContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the request scoped logger, or slog.Default when ctx carries none.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
@@ -1,9 +1,142 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/logger"
 	uc "example.com/m/v2/logic/usecase"
+	"example.com/m/v2/middleware"
+	"example.com/m/v2/model"
 )
 
 type Handler struct {
 	Usecase uc.Usecase
 }
+
+// logError records usecase / repository failures with the request scoped logger so they carry the request id.
+func logError(ctx context.Context, msg string, err error) {
+	logger.FromContext(ctx).ErrorContext(ctx, msg, "error", err)
+}
+
+// invalidRequest wraps a body decoding error as a 400.
+func invalidRequest(err error) error {
+	return apperror.New(apperror.CodeInvalidRequest, err.Error())
+}
+
+// decode strictly reads a single json object without unknown fields into req and validates it.
+// Malformed bodies are a 400, bodies failing req.Validate a 422.
+func decode(w http.ResponseWriter, r *http.Request, req model.Validator) error {
+	body := http.MaxBytesReader(w, r.Body, constant.MaxRequestBodyBytes)
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(req)
+	if err != nil {
+		return decodeError(err)
+	}
+	if _, err = dec.Token(); err != io.EOF {
+		return apperror.New(apperror.CodeInvalidRequest, "request body must contain a single json object")
+	}
+
+	return req.Validate()
+}
+
+// decodeError turns json decoding failures into client facing invalid request errors.
+func decodeError(err error) error {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		maxErr    *http.MaxBytesError
+	)
+	switch {
+	case errors.Is(err, io.EOF):
+		return apperror.New(apperror.CodeInvalidRequest, "request body is empty")
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return apperror.New(apperror.CodeInvalidRequest, "request body is not valid json").WithCause(err)
+	case errors.As(err, &typeErr):
+		appErr := apperror.New(apperror.CodeInvalidRequest, "request body has a field of the wrong type")
+		appErr.Fields = []apperror.FieldError{{Field: typeErr.Field, Message: "must be a json " + jsonType(typeErr.Type.Kind())}}
+		return appErr.WithCause(err)
+	case errors.As(err, &maxErr):
+		return apperror.New(apperror.CodeInvalidRequest, fmt.Sprintf("request body must not exceed %d bytes", maxErr.Limit))
+	}
+	// unknown fields only surface as a plain error: json: unknown field "x"
+	return invalidRequest(err)
+}
+
+func jsonType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return "number"
+}
+
+// authenticate resolves the caller from the session cookie.
+func (h *Handler) authenticate(r *http.Request) (userId int64, role string, err error) {
+	return h.session(r, false)
+}
+
+// authenticatePasswordChange resolves the caller of the password change, the one endpoint open to a user who must
+// change their password.
+func (h *Handler) authenticatePasswordChange(r *http.Request) (userId int64, err error) {
+	userId, _, err = h.session(r, true)
+	return
+}
+
+func (h *Handler) session(r *http.Request, passwordChange bool) (userId int64, role string, err error) {
+	claims, err := h.Usecase.DecodeJwt(r.Context(), r.Cookies())
+	if passwordChange && errors.Is(err, apperror.ErrPasswordChangeRequired) {
+		err = nil
+	}
+	if err != nil {
+		if code := apperror.CodeOf(err); code != apperror.CodeUnauthenticated && code != apperror.CodeForbidden {
+			err = apperror.ErrUnauthenticated.WithCause(err)
+		}
+		return
+	}
+
+	id, ok := claims["id"].(float64)
+	if !ok {
+		err = apperror.ErrUnauthenticated
+		return
+	}
+	role, _ = claims["role"].(string)
+
+	return int64(id), role, nil
+}
+
+// authorize authenticates the caller and checks that their roles grant permission.
+func (h *Handler) authorize(r *http.Request, permission string) (principal model.Principal, err error) {
+	userId, _, err := h.authenticate(r)
+	if err != nil {
+		return
+	}
+
+	return h.Usecase.Authorize(r.Context(), userId, permission)
+}
+
+// UserRateKey counts requests per authenticated user and falls back to the client ip for anonymous ones, which the
+// handler rejects anyway. It resolves the session once more than the handler does.
+func (h *Handler) UserRateKey(r *http.Request) string {
+	userId, _, err := h.authenticate(r)
+	if err != nil {
+		return middleware.ByIp(r)
+	}
+	return "user:" + strconv.FormatInt(userId, 10)
+}
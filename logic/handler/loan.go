@@ -1,51 +1,36 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"time"
 
+	"example.com/m/v2/apperror"
 	"example.com/m/v2/constant"
 	"example.com/m/v2/model"
 )
 
 func (h *Handler) NewLoan(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
 
 	var req model.NewLoanReq
-	err := json.NewDecoder(r.Body).Decode(&req)
+	err := decode(w, r, &req)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(model.HttpRes{
-			Message: err.Error(),
-		})
+		writeError(w, r, err)
 		return
 	}
 
-	user, err := h.Usecase.DecodeJwt(r.Cookies())
+	userId, _, err := h.authenticate(r)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(model.HttpRes{
-			Message: err.Error(),
-		})
+		writeError(w, r, err)
 		return
 	}
 
-	ctx := context.Background()
-	userId, ok := user["id"].(float64)
-	if !ok {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(model.HttpRes{
-			Message: "invalid token",
-		})
-		return
-	}
-	err = h.Usecase.NewLoan(ctx, req.Amount, req.Terms, int64(userId))
+	err = h.Usecase.NewLoan(ctx, req.Amount, req.Terms, userId)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(model.HttpRes{
-			Message: err.Error(),
-		})
+		writeError(w, r, err)
 		return
 	}
 
@@ -56,122 +41,192 @@ func (h *Handler) NewLoan(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) ApproveLoan(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
 
 	var req model.ApproveLoanReq
-	err := json.NewDecoder(r.Body).Decode(&req)
+	err := decode(w, r, &req)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(model.HttpRes{
-			Message: err.Error(),
-		})
+		writeError(w, r, err)
 		return
 	}
 
-	user, err := h.Usecase.DecodeJwt(r.Cookies())
+	principal, err := h.authorize(r, constant.PermissionLoanApprove)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(model.HttpRes{
-			Message: err.Error(),
-		})
+		writeError(w, r, err)
+		return
+	}
+
+	result, err := h.Usecase.ApproveLoan(ctx, req.LoanId, principal)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(model.HttpResApproval{
+		Message: "success",
+		Data:    model.NewApprovalRes(result),
+	})
+}
+
+// GetLoanApprovals lists the approvals of the loan given by the loan_id query parameter.
+func (h *Handler) GetLoanApprovals(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	loanId, err := strconv.ParseInt(r.URL.Query().Get("loan_id"), 10, 64)
+	if err != nil || loanId < 1 {
+		writeError(w, r, apperror.Validation(apperror.FieldError{Field: "loan_id", Message: "is required"}))
 		return
 	}
 
-	if userRole, ok := user["role"].(string); !ok || userRole != "ADMIN" {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(model.HttpRes{
-			Message: "unauthorized",
-		})
+	_, err = h.authorize(r, constant.PermissionLoanRead)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
-	ctx := context.Background()
-	err = h.Usecase.ApproveLoan(ctx, req.LoanId)
+
+	got, err := h.Usecase.GetLoanApprovals(ctx, loanId)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(model.HttpRes{
-			Message: err.Error(),
-		})
+		writeError(w, r, err)
 		return
 	}
-	json.NewEncoder(w).Encode(model.HttpRes{
+	json.NewEncoder(w).Encode(model.HttpResLoanApprovals{
 		Message: "success",
+		Data:    model.NewLoanApprovalResList(got, time.Now()),
 	})
 }
 
+// DisburseLoan pays an approved loan out, calling it again for the same loan retries the same payout.
+func (h *Handler) DisburseLoan(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	var req model.DisburseLoanReq
+	err := decode(w, r, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	_, err = h.authorize(r, constant.PermissionLoanDisburse)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	disbursement, err := h.Usecase.DisburseLoan(ctx, req.LoanId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(model.HttpResDisbursement{
+		Message: "success",
+		Data:    model.NewDisbursementRes(disbursement),
+	})
+}
+
+// PayLoan starts the payment of a term at the payment gateway and returns the intent to pay, the term is paid once
+// the gateway confirms the settlement on the payment webhook.
 func (h *Handler) PayLoan(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
 
 	var req model.PayLoanReq
-	err := json.NewDecoder(r.Body).Decode(&req)
+	err := decode(w, r, &req)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(model.HttpRes{
-			Message: err.Error(),
-		})
+		writeError(w, r, err)
 		return
 	}
 
-	user, err := h.Usecase.DecodeJwt(r.Cookies())
+	userId, _, err := h.authenticate(r)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(model.HttpRes{
-			Message: err.Error(),
-		})
+		writeError(w, r, err)
 		return
 	}
 
-	userId, ok := user["id"].(float64)
-	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(model.HttpRes{
-			Message: "unauthorized",
-		})
+	intent, err := h.Usecase.CreatePaymentIntent(ctx, req.Amount, req.LoanId, req.Term, userId)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
-	ctx := context.Background()
-	err = h.Usecase.PayLoan(ctx, req.Amount, req.LoanId, req.Term, int64(userId))
+	json.NewEncoder(w).Encode(model.HttpResPaymentIntent{
+		Message: "success",
+		Data:    model.NewPaymentIntentRes(intent),
+	})
+}
+
+func (h *Handler) GetLoan(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	userId, _, err := h.authenticate(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(model.HttpRes{
-			Message: err.Error(),
-		})
+		writeError(w, r, err)
 		return
 	}
-	json.NewEncoder(w).Encode(model.HttpRes{
+
+	got, err := h.Usecase.GetLoan(ctx, userId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(model.HttpResLoan{
 		Message: "success",
+		Data:    model.NewLoanResList(got),
 	})
 }
 
-func (h *Handler) GetLoan(w http.ResponseWriter, r *http.Request) {
+// RecordPayment pays a term of any loan, for payments received outside the app.
+func (h *Handler) RecordPayment(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	var req model.PayLoanReq
+	err := decode(w, r, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
-	user, err := h.Usecase.DecodeJwt(r.Cookies())
+	_, err = h.authorize(r, constant.PermissionPaymentRecord)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(model.HttpRes{
-			Message: err.Error(),
-		})
+		writeError(w, r, err)
 		return
 	}
 
-	userId, ok := user["id"].(float64)
-	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(model.HttpRes{
-			Message: "unauthorized",
-		})
+	err = h.Usecase.RecordPayment(ctx, req.Amount, req.LoanId, req.Term)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
-	ctx := context.Background()
-	got, err := h.Usecase.GetLoan(ctx, int64(userId))
+	json.NewEncoder(w).Encode(model.HttpRes{
+		Message: "success",
+	})
+}
+
+// GetUserLoans lists the loans of the user given by the user_id query parameter.
+func (h *Handler) GetUserLoans(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	userId, err := strconv.ParseInt(r.URL.Query().Get("user_id"), 10, 64)
+	if err != nil || userId < 1 {
+		writeError(w, r, apperror.Validation(apperror.FieldError{Field: "user_id", Message: "is required"}))
+		return
+	}
+
+	_, err = h.authorize(r, constant.PermissionLoanRead)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	got, err := h.Usecase.GetLoan(ctx, userId)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(model.HttpRes{
-			Message: err.Error(),
-		})
+		writeError(w, r, err)
 		return
 	}
 	json.NewEncoder(w).Encode(model.HttpResLoan{
 		Message: "success",
-		Data:    got,
+		Data:    model.NewLoanResList(got),
 	})
 }
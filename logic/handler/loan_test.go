@@ -3,13 +3,18 @@ package handler
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"example.com/m/v2/apperror"
 	"example.com/m/v2/constant"
 	u "example.com/m/v2/logic/usecase"
 	"example.com/m/v2/model"
@@ -36,6 +41,7 @@ func Test_NewLoan(t *testing.T) {
 		args           args
 		wantStatusCode int
 		wantBody       model.HttpRes
+		wantProblem    model.Problem
 		wantHeader     map[string]string
 	}{
 		{
@@ -45,18 +51,144 @@ func Test_NewLoan(t *testing.T) {
 				r: httptest.NewRequest("POST", "/loan", &bytes.Buffer{}),
 			},
 			wantStatusCode: http.StatusBadRequest,
-			wantBody: model.HttpRes{
-				Message: "EOF",
+			wantProblem: model.Problem{
+				Type:     "/problems/invalid-request",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "request body is empty",
+				Instance: "/loan",
+				Code:     "INVALID_REQUEST",
 			},
 			wantHeader: map[string]string{
-				constant.HttpHeaderSetContent: constant.HttpHeaderAppJson,
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
+			},
+		},
+		{
+			name: "err unknown field",
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/loan", strings.NewReader(`{"amount": 10000, "terms": 3, "user_id": 2}`)),
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantProblem: model.Problem{
+				Type:     "/problems/invalid-request",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   `json: unknown field "user_id"`,
+				Instance: "/loan",
+				Code:     "INVALID_REQUEST",
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
+			},
+		},
+		{
+			name: "err more than one json object",
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/loan", strings.NewReader(`{"amount": 10000, "terms": 3} {}`)),
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantProblem: model.Problem{
+				Type:     "/problems/invalid-request",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "request body must contain a single json object",
+				Instance: "/loan",
+				Code:     "INVALID_REQUEST",
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
+			},
+		},
+		{
+			name: "err malformed json",
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/loan", strings.NewReader(`{"amount": 10000,`)),
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantProblem: model.Problem{
+				Type:     "/problems/invalid-request",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "request body is not valid json",
+				Instance: "/loan",
+				Code:     "INVALID_REQUEST",
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
+			},
+		},
+		{
+			name: "err wrong field type",
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/loan", strings.NewReader(`{"amount": "10000", "terms": 3}`)),
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantProblem: model.Problem{
+				Type:     "/problems/invalid-request",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "request body has a field of the wrong type",
+				Instance: "/loan",
+				Code:     "INVALID_REQUEST",
+				Errors: []model.ProblemFieldError{
+					{Field: "amount", Message: "must be a json number"},
+				},
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
+			},
+		},
+		{
+			name: "err body too large",
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/loan", strings.NewReader(`{"amount": 10000, "terms": 3, "pad": "`+strings.Repeat("a", constant.MaxRequestBodyBytes)+`"}`)),
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantProblem: model.Problem{
+				Type:     "/problems/invalid-request",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   fmt.Sprintf("request body must not exceed %d bytes", constant.MaxRequestBodyBytes),
+				Instance: "/loan",
+				Code:     "INVALID_REQUEST",
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
+			},
+		},
+		{
+			name: "err validation",
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/loan", strings.NewReader(`{"amount": -1, "terms": 0}`)),
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantProblem: model.Problem{
+				Type:     "/problems/validation",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "request validation failed",
+				Instance: "/loan",
+				Code:     "VALIDATION",
+				Errors: []model.ProblemFieldError{
+					{Field: "amount", Message: "must be greater than 0 and at most 1000000000"},
+					{Field: "terms", Message: "must be between 1 and 520"},
+				},
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
 			},
 		},
 		{
 			name: "success",
 			mock: func() {
 				ucMock.
-					On("DecodeJwt", []*http.Cookie{}).
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
 					Return(jwt.MapClaims{
 						"id": float64(1),
 					}, nil).
@@ -95,10 +227,18 @@ func Test_NewLoan(t *testing.T) {
 				t.Errorf("Status code returned, %d, did not match expected code %d", tt.args.w.Result().StatusCode, tt.wantStatusCode)
 			}
 
-			var got model.HttpRes
-			json.NewDecoder(tt.args.w.Body).Decode(&got)
-			if got != tt.wantBody {
-				t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+			if tt.wantStatusCode >= http.StatusBadRequest {
+				var got model.Problem
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantProblem) {
+					t.Errorf("handler returned unexpected problem: got %+v want %+v", got, tt.wantProblem)
+				}
+			} else {
+				var got model.HttpRes
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if got != tt.wantBody {
+					t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+				}
 			}
 
 			for key, val := range tt.wantHeader {
@@ -112,6 +252,7 @@ func Test_NewLoan(t *testing.T) {
 
 func Test_ApproveLoan(t *testing.T) {
 	ucMock := new(u.MockUsecase)
+	principal := model.Principal{UserId: 1, Roles: []model.Role{{Name: constant.RoleSuperAdmin}}}
 	rBody := model.ApproveLoanReq{
 		LoanId: 1,
 	}
@@ -128,7 +269,8 @@ func Test_ApproveLoan(t *testing.T) {
 		mock           func()
 		args           args
 		wantStatusCode int
-		wantBody       model.HttpRes
+		wantBody       model.HttpResApproval
+		wantProblem    model.Problem
 		wantHeader     map[string]string
 	}{
 		{
@@ -138,26 +280,67 @@ func Test_ApproveLoan(t *testing.T) {
 				r: httptest.NewRequest("PUT", "/loan/approve", &bytes.Buffer{}),
 			},
 			wantStatusCode: http.StatusBadRequest,
-			wantBody: model.HttpRes{
-				Message: "EOF",
+			wantProblem: model.Problem{
+				Type:     "/problems/invalid-request",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "request body is empty",
+				Instance: "/loan/approve",
+				Code:     "INVALID_REQUEST",
 			},
 			wantHeader: map[string]string{
-				constant.HttpHeaderSetContent: constant.HttpHeaderAppJson,
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
+			},
+		},
+		{
+			name: "forbidden",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id":   float64(2),
+						"role": constant.CustomerRole,
+					}, nil).
+					Once()
+				ucMock.
+					On("Authorize", context.Background(), int64(2), constant.PermissionLoanApprove).
+					Return(model.Principal{UserId: 2}, apperror.ErrForbidden).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("PUT", "/loan/approve", bytes.NewBufferString(`{"loan_id":1}`)),
+			},
+			wantStatusCode: http.StatusForbidden,
+			wantProblem: model.Problem{
+				Type:     "/problems/forbidden",
+				Title:    "Forbidden",
+				Status:   http.StatusForbidden,
+				Detail:   "forbidden",
+				Instance: "/loan/approve",
+				Code:     "FORBIDDEN",
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
 			},
 		},
 		{
 			name: "success",
 			mock: func() {
 				ucMock.
-					On("DecodeJwt", []*http.Cookie{}).
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
 					Return(jwt.MapClaims{
 						"id":   float64(1),
 						"role": constant.AdminRole,
 					}, nil).
 					Once()
 				ucMock.
-					On("ApproveLoan", context.Background(), int64(1)).
-					Return(nil).
+					On("Authorize", context.Background(), int64(1), constant.PermissionLoanApprove).
+					Return(principal, nil).
+					Once()
+				ucMock.
+					On("ApproveLoan", context.Background(), int64(1), principal).
+					Return(model.ApprovalResult{LoanId: 1, Status: constant.LoanStatusPending, Approvals: 1, Required: 2}, nil).
 					Once()
 			},
 			args: args{
@@ -165,8 +348,14 @@ func Test_ApproveLoan(t *testing.T) {
 				r: httptest.NewRequest("PUT", "/loan/approve", &buf),
 			},
 			wantStatusCode: 200,
-			wantBody: model.HttpRes{
+			wantBody: model.HttpResApproval{
 				Message: "success",
+				Data: model.ApprovalRes{
+					LoanId:            1,
+					Status:            constant.LoanStatusPending,
+					Approvals:         1,
+					ApprovalsRequired: 2,
+				},
 			},
 			wantHeader: map[string]string{
 				constant.HttpHeaderSetContent: constant.HttpHeaderAppJson,
@@ -189,10 +378,211 @@ func Test_ApproveLoan(t *testing.T) {
 				t.Errorf("Status code returned, %d, did not match expected code %d", tt.args.w.Result().StatusCode, tt.wantStatusCode)
 			}
 
-			var got model.HttpRes
-			json.NewDecoder(tt.args.w.Body).Decode(&got)
-			if got != tt.wantBody {
-				t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+			if tt.wantStatusCode >= http.StatusBadRequest {
+				var got model.Problem
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantProblem) {
+					t.Errorf("handler returned unexpected problem: got %+v want %+v", got, tt.wantProblem)
+				}
+			} else {
+				var got model.HttpResApproval
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if got != tt.wantBody {
+					t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+				}
+			}
+
+			for key, val := range tt.wantHeader {
+				if tt.args.w.Header().Get(key) != val {
+					t.Errorf("handler returned unexpected header: got %+v want %+v", tt.args.w.Header().Get(key), val)
+				}
+			}
+		})
+	}
+}
+
+func Test_DisburseLoan(t *testing.T) {
+	ucMock := new(u.MockUsecase)
+
+	type args struct {
+		w *httptest.ResponseRecorder
+		r *http.Request
+	}
+
+	tests := []struct {
+		name           string
+		mock           func()
+		args           args
+		wantStatusCode int
+		wantBody       model.HttpResDisbursement
+		wantProblem    model.Problem
+		wantHeader     map[string]string
+	}{
+		{
+			name: "invalid request",
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/admin/loan/disburse", bytes.NewBufferString(`{"loan_id":0}`)),
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantProblem: model.Problem{
+				Type:     "/problems/validation",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "request validation failed",
+				Instance: "/admin/loan/disburse",
+				Code:     "VALIDATION",
+				Errors: []model.ProblemFieldError{
+					{Field: "loan_id", Message: "is required"},
+				},
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
+			},
+		},
+		{
+			name: "forbidden",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(2),
+					}, nil).
+					Once()
+				ucMock.
+					On("Authorize", context.Background(), int64(2), constant.PermissionLoanDisburse).
+					Return(model.Principal{UserId: 2}, apperror.ErrForbidden).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/admin/loan/disburse", bytes.NewBufferString(`{"loan_id":1}`)),
+			},
+			wantStatusCode: http.StatusForbidden,
+			wantProblem: model.Problem{
+				Type:     "/problems/forbidden",
+				Title:    "Forbidden",
+				Status:   http.StatusForbidden,
+				Detail:   "forbidden",
+				Instance: "/admin/loan/disburse",
+				Code:     "FORBIDDEN",
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
+			},
+		},
+		{
+			name: "loan not approved",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(1),
+					}, nil).
+					Once()
+				ucMock.
+					On("Authorize", context.Background(), int64(1), constant.PermissionLoanDisburse).
+					Return(model.Principal{UserId: 1}, nil).
+					Once()
+				ucMock.
+					On("DisburseLoan", context.Background(), int64(1)).
+					Return(model.Disbursement{}, apperror.ErrLoanNotApproved).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/admin/loan/disburse", bytes.NewBufferString(`{"loan_id":1}`)),
+			},
+			wantStatusCode: http.StatusConflict,
+			wantProblem: model.Problem{
+				Type:     "/problems/conflict",
+				Title:    "Conflict",
+				Status:   http.StatusConflict,
+				Detail:   "loan not approved",
+				Instance: "/admin/loan/disburse",
+				Code:     "CONFLICT",
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
+			},
+		},
+		{
+			name: "success",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(1),
+					}, nil).
+					Once()
+				ucMock.
+					On("Authorize", context.Background(), int64(1), constant.PermissionLoanDisburse).
+					Return(model.Principal{UserId: 1}, nil).
+					Once()
+				ucMock.
+					On("DisburseLoan", context.Background(), int64(1)).
+					Return(model.Disbursement{
+						Id:                5,
+						LoanId:            1,
+						Amount:            1000,
+						IdempotencyKey:    "key",
+						Provider:          "fake",
+						ProviderReference: "fake_ref",
+						Status:            constant.DisbursementStatusPending,
+						Attempts:          1,
+					}, nil).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/admin/loan/disburse", bytes.NewBufferString(`{"loan_id":1}`)),
+			},
+			wantStatusCode: http.StatusOK,
+			wantBody: model.HttpResDisbursement{
+				Message: "success",
+				Data: model.DisbursementRes{
+					Id:                5,
+					LoanId:            1,
+					Amount:            1000,
+					Provider:          "fake",
+					ProviderReference: "fake_ref",
+					Status:            constant.DisbursementStatusPending,
+					Attempts:          1,
+				},
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderSetContent: constant.HttpHeaderAppJson,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		h := Handler{
+			Usecase: ucMock,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			h.DisburseLoan(tt.args.w, tt.args.r)
+			if tt.args.w.Result().StatusCode != tt.wantStatusCode {
+				t.Errorf("Status code returned, %d, did not match expected code %d", tt.args.w.Result().StatusCode, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode >= http.StatusBadRequest {
+				var got model.Problem
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantProblem) {
+					t.Errorf("handler returned unexpected problem: got %+v want %+v", got, tt.wantProblem)
+				}
+			} else {
+				var got model.HttpResDisbursement
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantBody) {
+					t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+				}
 			}
 
 			for key, val := range tt.wantHeader {
@@ -206,6 +596,19 @@ func Test_ApproveLoan(t *testing.T) {
 
 func Test_PayLoan(t *testing.T) {
 	ucMock := new(u.MockUsecase)
+	expiresAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	intent := model.PaymentIntent{
+		Id:               7,
+		LoanId:           1,
+		UserId:           1,
+		Term:             1,
+		Amount:           10000,
+		Gateway:          "mock",
+		GatewayReference: "mock_ref",
+		VirtualAccount:   "88080000000001",
+		Status:           constant.PaymentIntentStatusPending,
+		ExpiresAt:        expiresAt,
+	}
 	rBody := model.PayLoanReq{
 		LoanId: 1,
 		Term:   1,
@@ -224,7 +627,8 @@ func Test_PayLoan(t *testing.T) {
 		mock           func()
 		args           args
 		wantStatusCode int
-		wantBody       model.HttpRes
+		wantBody       model.HttpResPaymentIntent
+		wantProblem    model.Problem
 		wantHeader     map[string]string
 	}{
 		{
@@ -234,26 +638,146 @@ func Test_PayLoan(t *testing.T) {
 				r: httptest.NewRequest("POST", "/loan/pay", &bytes.Buffer{}),
 			},
 			wantStatusCode: http.StatusBadRequest,
-			wantBody: model.HttpRes{
-				Message: "EOF",
+			wantProblem: model.Problem{
+				Type:     "/problems/invalid-request",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "request body is empty",
+				Instance: "/loan/pay",
+				Code:     "INVALID_REQUEST",
 			},
 			wantHeader: map[string]string{
-				constant.HttpHeaderSetContent: constant.HttpHeaderAppJson,
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
+			},
+		},
+		{
+			name: "err term 0",
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/loan/pay", strings.NewReader(`{"loan_id": 1, "term": 0, "amount": 100}`)),
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantProblem: model.Problem{
+				Type:     "/problems/validation",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "request validation failed",
+				Instance: "/loan/pay",
+				Code:     "VALIDATION",
+				Errors: []model.ProblemFieldError{
+					{Field: "term", Message: "must be between 1 and 520"},
+				},
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
+			},
+		},
+		{
+			name: "loan not disbursed",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(1),
+					}, nil).
+					Once()
+				ucMock.
+					On("CreatePaymentIntent", context.Background(), float64(10000), int64(1), int64(1), int64(1)).
+					Return(model.PaymentIntent{}, apperror.ErrLoanNotDisbursed).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/loan/pay", bytes.NewBufferString(`{"loan_id":1,"term":1,"amount":10000}`)),
+			},
+			wantStatusCode: http.StatusConflict,
+			wantProblem: model.Problem{
+				Type:     "/problems/conflict",
+				Title:    "Conflict",
+				Status:   http.StatusConflict,
+				Detail:   "loan not disbursed",
+				Instance: "/loan/pay",
+				Code:     "CONFLICT",
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
+			},
+		},
+		{
+			name: "loan not found",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(1),
+					}, nil).
+					Once()
+				ucMock.
+					On("CreatePaymentIntent", context.Background(), float64(10000), int64(1), int64(1), int64(1)).
+					Return(model.PaymentIntent{}, apperror.ErrLoanNotFound.WithCause(sql.ErrNoRows)).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/loan/pay", bytes.NewBufferString(`{"loan_id":1,"term":1,"amount":10000}`)),
+			},
+			wantStatusCode: http.StatusNotFound,
+			wantProblem: model.Problem{
+				Type:     "/problems/not-found",
+				Title:    "Not Found",
+				Status:   http.StatusNotFound,
+				Detail:   "loan not found",
+				Instance: "/loan/pay",
+				Code:     "NOT_FOUND",
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
+			},
+		},
+		{
+			name: "internal error",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(1),
+					}, nil).
+					Once()
+				ucMock.
+					On("CreatePaymentIntent", context.Background(), float64(10000), int64(1), int64(1), int64(1)).
+					Return(model.PaymentIntent{}, errors.New("err CreatePaymentIntent")).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/loan/pay", bytes.NewBufferString(`{"loan_id":1,"term":1,"amount":10000}`)),
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantProblem: model.Problem{
+				Type:     "/problems/internal",
+				Title:    "Internal Server Error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "an unexpected error occurred",
+				Instance: "/loan/pay",
+				Code:     "INTERNAL",
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
 			},
 		},
 		{
 			name: "success",
 			mock: func() {
 				ucMock.
-					On("DecodeJwt", []*http.Cookie{}).
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
 					Return(jwt.MapClaims{
 						"id":   float64(1),
 						"role": constant.AdminRole,
 					}, nil).
 					Once()
 				ucMock.
-					On("PayLoan", context.Background(), float64(10000), int64(1), int64(1), int64(1)).
-					Return(nil).
+					On("CreatePaymentIntent", context.Background(), float64(10000), int64(1), int64(1), int64(1)).
+					Return(intent, nil).
 					Once()
 			},
 			args: args{
@@ -261,8 +785,19 @@ func Test_PayLoan(t *testing.T) {
 				r: httptest.NewRequest("POST", "/loan/pay", &buf),
 			},
 			wantStatusCode: 200,
-			wantBody: model.HttpRes{
+			wantBody: model.HttpResPaymentIntent{
 				Message: "success",
+				Data: model.PaymentIntentRes{
+					Id:             7,
+					LoanId:         1,
+					Term:           1,
+					Amount:         10000,
+					Gateway:        "mock",
+					Reference:      "mock_ref",
+					VirtualAccount: "88080000000001",
+					Status:         constant.PaymentIntentStatusPending,
+					ExpiresAt:      expiresAt,
+				},
 			},
 			wantHeader: map[string]string{
 				constant.HttpHeaderSetContent: constant.HttpHeaderAppJson,
@@ -285,10 +820,18 @@ func Test_PayLoan(t *testing.T) {
 				t.Errorf("Status code returned, %d, did not match expected code %d", tt.args.w.Result().StatusCode, tt.wantStatusCode)
 			}
 
-			var got model.HttpRes
-			json.NewDecoder(tt.args.w.Body).Decode(&got)
-			if got != tt.wantBody {
-				t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+			if tt.wantStatusCode >= http.StatusBadRequest {
+				var got model.Problem
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantProblem) {
+					t.Errorf("handler returned unexpected problem: got %+v want %+v", got, tt.wantProblem)
+				}
+			} else {
+				var got model.HttpResPaymentIntent
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantBody) {
+					t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+				}
 			}
 
 			for key, val := range tt.wantHeader {
@@ -314,13 +857,14 @@ func Test_GetLoan(t *testing.T) {
 		args           args
 		wantStatusCode int
 		wantBody       model.HttpResLoan
+		wantProblem    model.Problem
 		wantHeader     map[string]string
 	}{
 		{
 			name: "err DecodeJwt",
 			mock: func() {
 				ucMock.
-					On("DecodeJwt", []*http.Cookie{}).
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
 					Return(jwt.MapClaims{}, errors.New("err DecodeJwt")).
 					Once()
 			},
@@ -328,19 +872,24 @@ func Test_GetLoan(t *testing.T) {
 				w: httptest.NewRecorder(),
 				r: httptest.NewRequest("GET", "/loan", nil),
 			},
-			wantStatusCode: http.StatusBadRequest,
-			wantBody: model.HttpResLoan{
-				Message: "err DecodeJwt",
+			wantStatusCode: http.StatusUnauthorized,
+			wantProblem: model.Problem{
+				Type:     "/problems/unauthenticated",
+				Title:    "Unauthorized",
+				Status:   http.StatusUnauthorized,
+				Detail:   "unauthorized",
+				Instance: "/loan",
+				Code:     "UNAUTHENTICATED",
 			},
 			wantHeader: map[string]string{
-				constant.HttpHeaderSetContent: constant.HttpHeaderAppJson,
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
 			},
 		},
 		{
 			name: "unauthorized",
 			mock: func() {
 				ucMock.
-					On("DecodeJwt", []*http.Cookie{}).
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
 					Return(jwt.MapClaims{
 						"id": "asd",
 					}, nil).
@@ -351,18 +900,23 @@ func Test_GetLoan(t *testing.T) {
 				r: httptest.NewRequest("GET", "/loan", nil),
 			},
 			wantStatusCode: http.StatusUnauthorized,
-			wantBody: model.HttpResLoan{
-				Message: "unauthorized",
+			wantProblem: model.Problem{
+				Type:     "/problems/unauthenticated",
+				Title:    "Unauthorized",
+				Status:   http.StatusUnauthorized,
+				Detail:   "unauthorized",
+				Instance: "/loan",
+				Code:     "UNAUTHENTICATED",
 			},
 			wantHeader: map[string]string{
-				constant.HttpHeaderSetContent: constant.HttpHeaderAppJson,
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
 			},
 		},
 		{
 			name: "success",
 			mock: func() {
 				ucMock.
-					On("DecodeJwt", []*http.Cookie{}).
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
 					Return(jwt.MapClaims{
 						"id":   float64(1),
 						"role": constant.AdminRole,
@@ -384,7 +938,7 @@ func Test_GetLoan(t *testing.T) {
 			wantStatusCode: 200,
 			wantBody: model.HttpResLoan{
 				Message: "success",
-				Data: []model.Loan{
+				Data: []model.LoanRes{
 					{
 						Id: 1,
 					},
@@ -411,10 +965,18 @@ func Test_GetLoan(t *testing.T) {
 				t.Errorf("Status code returned, %d, did not match expected code %d", tt.args.w.Result().StatusCode, tt.wantStatusCode)
 			}
 
-			var got model.HttpResLoan
-			json.NewDecoder(tt.args.w.Body).Decode(&got)
-			if !reflect.DeepEqual(got, tt.wantBody) {
-				t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+			if tt.wantStatusCode >= http.StatusBadRequest {
+				var got model.Problem
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantProblem) {
+					t.Errorf("handler returned unexpected problem: got %+v want %+v", got, tt.wantProblem)
+				}
+			} else {
+				var got model.HttpResLoan
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantBody) {
+					t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+				}
 			}
 
 			for key, val := range tt.wantHeader {
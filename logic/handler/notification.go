@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"example.com/m/v2/constant"
+	"example.com/m/v2/model"
+)
+
+// GetNotifications lists the latest notifications of the caller, one per channel they were sent on.
+func (h *Handler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	userId, _, err := h.authenticate(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	notifications, err := h.Usecase.GetNotifications(ctx, userId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(model.HttpResNotifications{
+		Message: "success",
+		Data:    model.NewNotificationResList(notifications),
+	})
+}
+
+func (h *Handler) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	userId, _, err := h.authenticate(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	prefs, err := h.Usecase.GetNotificationPreferences(ctx, userId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(model.HttpResNotificationPreferences{
+		Message: "success",
+		Data:    model.NewNotificationPreferencesRes(prefs),
+	})
+}
+
+// UpdateNotificationPreferences answers with every channel, the ones left out of the request included.
+func (h *Handler) UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	var req model.UpdateNotificationPreferencesReq
+	err := decode(w, r, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	userId, _, err := h.authenticate(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	prefs, err := h.Usecase.UpdateNotificationPreferences(ctx, userId, model.NotificationPreferences{
+		Locale:   req.Locale,
+		Channels: req.Channels,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(model.HttpResNotificationPreferences{
+		Message: "success",
+		Data:    model.NewNotificationPreferencesRes(prefs),
+	})
+}
@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	u "example.com/m/v2/logic/usecase"
+	"example.com/m/v2/model"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func Test_UpdateNotificationPreferences(t *testing.T) {
+	ucMock := new(u.MockUsecase)
+
+	type args struct {
+		w *httptest.ResponseRecorder
+		r *http.Request
+	}
+
+	body := `{"locale": "id", "channels": [{"channel": "sms", "enabled": true, "address": "+6281234567890"}]}`
+	sms := model.ChannelPreference{Channel: constant.NotificationChannelSms, Enabled: true, Address: "+6281234567890"}
+	prefs := model.NotificationPreferences{
+		Locale: "id",
+		Channels: []model.ChannelPreference{
+			{Channel: constant.NotificationChannelEmail, Enabled: true},
+			sms,
+			{Channel: constant.NotificationChannelPush},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		mock           func()
+		args           args
+		wantStatusCode int
+		wantBody       model.HttpResNotificationPreferences
+		wantProblem    model.Problem
+	}{
+		{
+			name: "err duplicate channels",
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("PUT", "/user/notifications/preferences", strings.NewReader(`{"locale": "en", "channels": [{"channel": "sms"}, {"channel": "sms"}]}`)),
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantProblem: model.Problem{
+				Type:     "/problems/validation",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "request validation failed",
+				Instance: "/user/notifications/preferences",
+				Code:     "VALIDATION",
+				Errors: []model.ProblemFieldError{
+					{Field: "channels", Message: "must not contain duplicates"},
+				},
+			},
+		},
+		{
+			name: "err DecodeJwt",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{}, apperror.ErrSessionRevoked).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("PUT", "/user/notifications/preferences", strings.NewReader(body)),
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			wantProblem: model.Problem{
+				Type:     "/problems/unauthenticated",
+				Title:    "Unauthorized",
+				Status:   http.StatusUnauthorized,
+				Detail:   "session revoked, log in again",
+				Instance: "/user/notifications/preferences",
+				Code:     "UNAUTHENTICATED",
+			},
+		},
+		{
+			name: "err password change required",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(1),
+					}, apperror.ErrPasswordChangeRequired).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("PUT", "/user/notifications/preferences", strings.NewReader(body)),
+			},
+			wantStatusCode: http.StatusForbidden,
+			wantProblem: model.Problem{
+				Type:     "/problems/forbidden",
+				Title:    "Forbidden",
+				Status:   http.StatusForbidden,
+				Detail:   "password change required, change your password first",
+				Instance: "/user/notifications/preferences",
+				Code:     "FORBIDDEN",
+			},
+		},
+		{
+			name: "err invalid phone number",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(1),
+					}, nil).
+					Once()
+				ucMock.
+					On("UpdateNotificationPreferences", context.Background(), int64(1), model.NotificationPreferences{
+						Locale:   "id",
+						Channels: []model.ChannelPreference{sms},
+					}).
+					Return(model.NotificationPreferences{}, apperror.Validation(apperror.FieldError{Field: "channels", Message: "sms address must be an E.164 phone number"})).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("PUT", "/user/notifications/preferences", strings.NewReader(body)),
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantProblem: model.Problem{
+				Type:     "/problems/validation",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "request validation failed",
+				Instance: "/user/notifications/preferences",
+				Code:     "VALIDATION",
+				Errors: []model.ProblemFieldError{
+					{Field: "channels", Message: "sms address must be an E.164 phone number"},
+				},
+			},
+		},
+		{
+			name: "success",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(1),
+					}, nil).
+					Once()
+				ucMock.
+					On("UpdateNotificationPreferences", context.Background(), int64(1), model.NotificationPreferences{
+						Locale:   "id",
+						Channels: []model.ChannelPreference{sms},
+					}).
+					Return(prefs, nil).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("PUT", "/user/notifications/preferences", strings.NewReader(body)),
+			},
+			wantStatusCode: 200,
+			wantBody: model.HttpResNotificationPreferences{
+				Message: "success",
+				Data:    model.NewNotificationPreferencesRes(prefs),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		h := Handler{
+			Usecase: ucMock,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			h.UpdateNotificationPreferences(tt.args.w, tt.args.r)
+			if tt.args.w.Result().StatusCode != tt.wantStatusCode {
+				t.Errorf("Status code returned, %d, did not match expected code %d", tt.args.w.Result().StatusCode, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode >= http.StatusBadRequest {
+				var got model.Problem
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantProblem) {
+					t.Errorf("handler returned unexpected problem: got %+v want %+v", got, tt.wantProblem)
+				}
+			} else {
+				var got model.HttpResNotificationPreferences
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantBody) {
+					t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+				}
+			}
+		})
+	}
+
+	ucMock.AssertExpectations(t)
+}
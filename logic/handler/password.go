@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"example.com/m/v2/constant"
+	"example.com/m/v2/middleware"
+	"example.com/m/v2/model"
+)
+
+// ForgotPassword always answers success, whether the email is registered or not.
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	var req model.ForgotPasswordReq
+	err := decode(w, r, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = h.Usecase.ForgotPassword(ctx, req.Email)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(model.HttpRes{
+		Message: "success",
+	})
+}
+
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	var req model.ResetPasswordReq
+	err := decode(w, r, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = h.Usecase.ResetPassword(ctx, req.Token, req.Password)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(model.HttpRes{
+		Message: "success",
+	})
+}
+
+// ChangePassword ends every session including the caller's, the cookie is cleared so the client logs in again. It is
+// the one endpoint open to a user who must change their password.
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	var req model.ChangePasswordReq
+	err := decode(w, r, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	userId, err := h.authenticatePasswordChange(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = h.Usecase.ChangePassword(ctx, userId, req.OldPassword, req.NewPassword, middleware.ClientIpFromRequest(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set(constant.HttpHeaderSetCookie, constant.CookieClearSession)
+
+	json.NewEncoder(w).Encode(model.HttpRes{
+		Message: "success",
+	})
+}
@@ -0,0 +1,398 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	u "example.com/m/v2/logic/usecase"
+	"example.com/m/v2/model"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func Test_ForgotPassword(t *testing.T) {
+	ucMock := new(u.MockUsecase)
+
+	type args struct {
+		w *httptest.ResponseRecorder
+		r *http.Request
+	}
+
+	tests := []struct {
+		name           string
+		mock           func()
+		args           args
+		wantStatusCode int
+		wantBody       model.HttpRes
+		wantProblem    model.Problem
+	}{
+		{
+			name: "err invalid email",
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/password/forgot", strings.NewReader(`{"email": "tes"}`)),
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantProblem: model.Problem{
+				Type:     "/problems/validation",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "request validation failed",
+				Instance: "/user/password/forgot",
+				Code:     "VALIDATION",
+				Errors: []model.ProblemFieldError{
+					{Field: "email", Message: "must be a valid email address"},
+				},
+			},
+		},
+		{
+			name: "err ForgotPassword",
+			mock: func() {
+				ucMock.
+					On("ForgotPassword", context.Background(), "tes@tes.com").
+					Return(errors.New("err ForgotPassword")).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/password/forgot", strings.NewReader(`{"email": "tes@tes.com"}`)),
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantProblem: model.Problem{
+				Type:     "/problems/internal",
+				Title:    "Internal Server Error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "an unexpected error occurred",
+				Instance: "/user/password/forgot",
+				Code:     "INTERNAL",
+			},
+		},
+		{
+			name: "success",
+			mock: func() {
+				ucMock.
+					On("ForgotPassword", context.Background(), "tes@tes.com").
+					Return(nil).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/password/forgot", strings.NewReader(`{"email": "tes@tes.com"}`)),
+			},
+			wantStatusCode: 200,
+			wantBody: model.HttpRes{
+				Message: "success",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		h := Handler{
+			Usecase: ucMock,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			h.ForgotPassword(tt.args.w, tt.args.r)
+			if tt.args.w.Result().StatusCode != tt.wantStatusCode {
+				t.Errorf("Status code returned, %d, did not match expected code %d", tt.args.w.Result().StatusCode, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode >= http.StatusBadRequest {
+				var got model.Problem
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantProblem) {
+					t.Errorf("handler returned unexpected problem: got %+v want %+v", got, tt.wantProblem)
+				}
+			} else {
+				var got model.HttpRes
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if got != tt.wantBody {
+					t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+				}
+			}
+		})
+	}
+}
+
+func Test_ResetPassword(t *testing.T) {
+	ucMock := new(u.MockUsecase)
+
+	type args struct {
+		w *httptest.ResponseRecorder
+		r *http.Request
+	}
+
+	tests := []struct {
+		name           string
+		mock           func()
+		args           args
+		wantStatusCode int
+		wantBody       model.HttpRes
+		wantProblem    model.Problem
+	}{
+		{
+			name: "err missing fields",
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/password/reset", strings.NewReader(`{}`)),
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantProblem: model.Problem{
+				Type:     "/problems/validation",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "request validation failed",
+				Instance: "/user/password/reset",
+				Code:     "VALIDATION",
+				Errors: []model.ProblemFieldError{
+					{Field: "token", Message: "is required"},
+					{Field: "password", Message: "is required"},
+				},
+			},
+		},
+		{
+			name: "err used or expired token",
+			mock: func() {
+				ucMock.
+					On("ResetPassword", context.Background(), "tes", "correct-horse-battery").
+					Return(apperror.ErrInvalidResetToken).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/password/reset", strings.NewReader(`{"token": "tes", "password": "correct-horse-battery"}`)),
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantProblem: model.Problem{
+				Type:     "/problems/validation",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "invalid or expired password reset token",
+				Instance: "/user/password/reset",
+				Code:     "VALIDATION",
+			},
+		},
+		{
+			name: "success",
+			mock: func() {
+				ucMock.
+					On("ResetPassword", context.Background(), "tes", "correct-horse-battery").
+					Return(nil).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/password/reset", strings.NewReader(`{"token": "tes", "password": "correct-horse-battery"}`)),
+			},
+			wantStatusCode: 200,
+			wantBody: model.HttpRes{
+				Message: "success",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		h := Handler{
+			Usecase: ucMock,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			h.ResetPassword(tt.args.w, tt.args.r)
+			if tt.args.w.Result().StatusCode != tt.wantStatusCode {
+				t.Errorf("Status code returned, %d, did not match expected code %d", tt.args.w.Result().StatusCode, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode >= http.StatusBadRequest {
+				var got model.Problem
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantProblem) {
+					t.Errorf("handler returned unexpected problem: got %+v want %+v", got, tt.wantProblem)
+				}
+			} else {
+				var got model.HttpRes
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if got != tt.wantBody {
+					t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+				}
+			}
+		})
+	}
+}
+
+func Test_ChangePassword(t *testing.T) {
+	ucMock := new(u.MockUsecase)
+	body := `{"old_password": "old-password-1", "new_password": "correct-horse-battery"}`
+
+	type args struct {
+		w *httptest.ResponseRecorder
+		r *http.Request
+	}
+
+	tests := []struct {
+		name           string
+		mock           func()
+		args           args
+		wantStatusCode int
+		wantBody       model.HttpRes
+		wantProblem    model.Problem
+		wantHeader     map[string]string
+	}{
+		{
+			name: "err DecodeJwt",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{}, apperror.ErrSessionRevoked).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("PUT", "/user/password", strings.NewReader(body)),
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			wantProblem: model.Problem{
+				Type:     "/problems/unauthenticated",
+				Title:    "Unauthorized",
+				Status:   http.StatusUnauthorized,
+				Detail:   "session revoked, log in again",
+				Instance: "/user/password",
+				Code:     "UNAUTHENTICATED",
+			},
+		},
+		{
+			name: "err wrong old password",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(1),
+					}, nil).
+					Once()
+				ucMock.
+					On("ChangePassword", context.Background(), int64(1), "old-password-1", "correct-horse-battery", "192.0.2.1").
+					Return(apperror.ErrOldPasswordInvalid).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("PUT", "/user/password", strings.NewReader(body)),
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantProblem: model.Problem{
+				Type:     "/problems/validation",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "old password is incorrect",
+				Instance: "/user/password",
+				Code:     "VALIDATION",
+				Errors: []model.ProblemFieldError{
+					{Field: "old_password", Message: "is incorrect"},
+				},
+			},
+		},
+		{
+			name: "success with a password change required",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(1),
+					}, apperror.ErrPasswordChangeRequired).
+					Once()
+				ucMock.
+					On("ChangePassword", context.Background(), int64(1), "old-password-1", "correct-horse-battery", "192.0.2.1").
+					Return(nil).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("PUT", "/user/password", strings.NewReader(body)),
+			},
+			wantStatusCode: 200,
+			wantBody: model.HttpRes{
+				Message: "success",
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderSetCookie: constant.CookieClearSession,
+			},
+		},
+		{
+			name: "success",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(1),
+					}, nil).
+					Once()
+				ucMock.
+					On("ChangePassword", context.Background(), int64(1), "old-password-1", "correct-horse-battery", "192.0.2.1").
+					Return(nil).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("PUT", "/user/password", strings.NewReader(body)),
+			},
+			wantStatusCode: 200,
+			wantBody: model.HttpRes{
+				Message: "success",
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderSetCookie: constant.CookieClearSession,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		h := Handler{
+			Usecase: ucMock,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			h.ChangePassword(tt.args.w, tt.args.r)
+			if tt.args.w.Result().StatusCode != tt.wantStatusCode {
+				t.Errorf("Status code returned, %d, did not match expected code %d", tt.args.w.Result().StatusCode, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode >= http.StatusBadRequest {
+				var got model.Problem
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantProblem) {
+					t.Errorf("handler returned unexpected problem: got %+v want %+v", got, tt.wantProblem)
+				}
+			} else {
+				var got model.HttpRes
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if got != tt.wantBody {
+					t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+				}
+			}
+
+			for key, val := range tt.wantHeader {
+				if tt.args.w.Header().Get(key) != val {
+					t.Errorf("handler returned unexpected header: got %+v want %+v", tt.args.w.Header().Get(key), val)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"example.com/m/v2/constant"
+	"example.com/m/v2/model"
+)
+
+// PaymentWebhook receives settlement callbacks of the payment gateway. The signature covers the raw body, so it is
+// verified before the body is decoded.
+func (h *Handler) PaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, constant.MaxRequestBodyBytes))
+	if err != nil {
+		writeError(w, r, decodeError(err))
+		return
+	}
+
+	sig := model.WebhookSignature{
+		Timestamp: r.Header.Get(constant.HttpHeaderWebhookTimestamp),
+		Nonce:     r.Header.Get(constant.HttpHeaderWebhookNonce),
+		Signature: r.Header.Get(constant.HttpHeaderWebhookSignature),
+	}
+	err = h.Usecase.VerifyPaymentWebhook(ctx, sig, body)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	var req model.PaymentWebhookReq
+	err = decode(w, r, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = h.Usecase.SettlePayment(ctx, sig.Nonce, req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(model.HttpRes{
+		Message: "success",
+	})
+}
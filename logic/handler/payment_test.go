@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	u "example.com/m/v2/logic/usecase"
+	"example.com/m/v2/model"
+	"example.com/m/v2/payment"
+)
+
+func Test_PaymentWebhook(t *testing.T) {
+	ucMock := new(u.MockUsecase)
+
+	gateway := payment.NewMock([]byte("tes"))
+	intent, _ := gateway.CreateIntent(context.Background(), model.PaymentIntentRequest{LoanId: 1, UserId: 1, Term: 1, Amount: 500})
+
+	// callback builds the request the gateway sends to settle the intent
+	callback := func(status string) (*http.Request, model.WebhookSignature, []byte) {
+		body, header, _ := gateway.Settle(intent.Reference, status)
+		r := httptest.NewRequest("POST", "/payment/webhook", bytes.NewReader(body))
+		r.Header = header
+		return r, model.WebhookSignature{
+			Timestamp: header.Get(constant.HttpHeaderWebhookTimestamp),
+			Nonce:     header.Get(constant.HttpHeaderWebhookNonce),
+			Signature: header.Get(constant.HttpHeaderWebhookSignature),
+		}, body
+	}
+
+	type args struct {
+		w *httptest.ResponseRecorder
+		r *http.Request
+	}
+
+	tests := []struct {
+		name           string
+		args           func() (args, func())
+		wantStatusCode int
+		wantProblem    model.Problem
+	}{
+		{
+			name: "invalid signature",
+			args: func() (args, func()) {
+				r, sig, body := callback(constant.PaymentIntentStatusSucceeded)
+				return args{w: httptest.NewRecorder(), r: r}, func() {
+					ucMock.
+						On("VerifyPaymentWebhook", context.Background(), sig, body).
+						Return(apperror.ErrInvalidWebhookSignature).
+						Once()
+				}
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			wantProblem: model.Problem{
+				Type:     "/problems/unauthenticated",
+				Title:    "Unauthorized",
+				Status:   http.StatusUnauthorized,
+				Detail:   "invalid webhook signature",
+				Instance: "/payment/webhook",
+				Code:     "UNAUTHENTICATED",
+			},
+		},
+		{
+			name: "invalid event",
+			args: func() (args, func()) {
+				r, sig, body := callback("PAID")
+				return args{w: httptest.NewRecorder(), r: r}, func() {
+					ucMock.
+						On("VerifyPaymentWebhook", context.Background(), sig, body).
+						Return(nil).
+						Once()
+				}
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantProblem: model.Problem{
+				Type:     "/problems/validation",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "request validation failed",
+				Instance: "/payment/webhook",
+				Code:     "VALIDATION",
+				Errors: []model.ProblemFieldError{
+					{Field: "status", Message: "must be SUCCEEDED or FAILED"},
+				},
+			},
+		},
+		{
+			name: "replayed callback",
+			args: func() (args, func()) {
+				r, sig, body := callback(constant.PaymentIntentStatusSucceeded)
+				return args{w: httptest.NewRecorder(), r: r}, func() {
+					ucMock.
+						On("VerifyPaymentWebhook", context.Background(), sig, body).
+						Return(nil).
+						Once()
+					ucMock.
+						On("SettlePayment", context.Background(), sig.Nonce, model.PaymentWebhookReq{Reference: intent.Reference, Status: constant.PaymentIntentStatusSucceeded, Amount: 500}).
+						Return(apperror.ErrWebhookReplayed).
+						Once()
+				}
+			},
+			wantStatusCode: http.StatusConflict,
+			wantProblem: model.Problem{
+				Type:     "/problems/conflict",
+				Title:    "Conflict",
+				Status:   http.StatusConflict,
+				Detail:   "webhook already received",
+				Instance: "/payment/webhook",
+				Code:     "CONFLICT",
+			},
+		},
+		{
+			name: "success",
+			args: func() (args, func()) {
+				r, sig, body := callback(constant.PaymentIntentStatusSucceeded)
+				return args{w: httptest.NewRecorder(), r: r}, func() {
+					ucMock.
+						On("VerifyPaymentWebhook", context.Background(), sig, body).
+						Return(nil).
+						Once()
+					ucMock.
+						On("SettlePayment", context.Background(), sig.Nonce, model.PaymentWebhookReq{Reference: intent.Reference, Status: constant.PaymentIntentStatusSucceeded, Amount: 500}).
+						Return(nil).
+						Once()
+				}
+			},
+			wantStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		h := Handler{
+			Usecase: ucMock,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			args, mock := tt.args()
+			mock()
+
+			h.PaymentWebhook(args.w, args.r)
+			if args.w.Result().StatusCode != tt.wantStatusCode {
+				t.Errorf("Status code returned, %d, did not match expected code %d", args.w.Result().StatusCode, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode >= http.StatusBadRequest {
+				var got model.Problem
+				json.NewDecoder(args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantProblem) {
+					t.Errorf("handler returned unexpected problem: got %+v want %+v", got, tt.wantProblem)
+				}
+			}
+			ucMock.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/logger"
+	"example.com/m/v2/middleware"
+	"example.com/m/v2/model"
+)
+
+// WriteError answers err the way handlers do, for middlewares rejecting a request before it reaches one.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	writeError(w, r, err)
+}
+
+// writeError renders err as application/problem+json with the http status of its apperror code.
+// Errors without a code are internal: they are logged and masked behind a generic detail.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	ctx := r.Context()
+	code := apperror.CodeOf(err)
+	status := apperror.HttpStatus(err)
+
+	problem := model.Problem{
+		Type:      constant.ProblemTypeBase + strings.ReplaceAll(strings.ToLower(string(code)), "_", "-"),
+		Title:     http.StatusText(status),
+		Status:    status,
+		Instance:  r.URL.Path,
+		Code:      string(code),
+		RequestId: middleware.RequestIdFromContext(ctx),
+	}
+
+	var appErr *apperror.Error
+	if code != apperror.CodeInternal && errors.As(err, &appErr) {
+		problem.Detail = appErr.Message
+		for _, f := range appErr.Fields {
+			problem.Errors = append(problem.Errors, model.ProblemFieldError{
+				Field:   f.Field,
+				Message: f.Message,
+			})
+		}
+		if appErr.RetryAfter > 0 {
+			w.Header().Set(constant.HttpHeaderRetryAfter, retryAfterSeconds(appErr.RetryAfter))
+		}
+		logger.FromContext(ctx).WarnContext(ctx, "request rejected", "error", err, "status", status)
+	} else {
+		problem.Detail = constant.ProblemInternalDetail
+		logError(ctx, "request failed", err)
+	}
+
+	w.Header().Set(constant.HttpHeaderContentType, constant.HttpHeaderAppProblemJson)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// retryAfterSeconds formats d as Retry-After delay-seconds, rounded up so clients never retry too early.
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
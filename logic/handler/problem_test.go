@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/middleware"
+	"example.com/m/v2/model"
+)
+
+func Test_writeError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantProblem    model.Problem
+		wantRetryAfter string
+	}{
+		{
+			name: "validation error with fields",
+			err: apperror.Validation(
+				apperror.FieldError{Field: "amount", Message: "must be greater than 0"},
+				apperror.FieldError{Field: "term", Message: "is required"},
+			),
+			wantProblem: model.Problem{
+				Type:     "/problems/validation",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "request validation failed",
+				Instance: "/loan",
+				Code:     "VALIDATION",
+				Errors: []model.ProblemFieldError{
+					{Field: "amount", Message: "must be greater than 0"},
+					{Field: "term", Message: "is required"},
+				},
+			},
+		},
+		{
+			name: "wrapped domain error keeps its detail",
+			err:  apperror.ErrLoanNotFound.WithCause(errors.New("sql: no rows in result set")),
+			wantProblem: model.Problem{
+				Type:     "/problems/not-found",
+				Title:    "Not Found",
+				Status:   http.StatusNotFound,
+				Detail:   apperror.ErrLoanNotFound.Message,
+				Instance: "/loan",
+				Code:     "NOT_FOUND",
+			},
+		},
+		{
+			name: "too many requests sets retry after",
+			err:  apperror.ErrTooManyLoginAttempts.WithRetryAfter(1500 * time.Millisecond),
+			wantProblem: model.Problem{
+				Type:     "/problems/too-many-requests",
+				Title:    "Too Many Requests",
+				Status:   http.StatusTooManyRequests,
+				Detail:   apperror.ErrTooManyLoginAttempts.Message,
+				Instance: "/loan",
+				Code:     "TOO_MANY_REQUESTS",
+			},
+			wantRetryAfter: "2",
+		},
+		{
+			name: "internal error is masked",
+			err:  errors.New("pq: password authentication failed"),
+			wantProblem: model.Problem{
+				Type:     "/problems/internal",
+				Title:    "Internal Server Error",
+				Status:   http.StatusInternalServerError,
+				Detail:   constant.ProblemInternalDetail,
+				Instance: "/loan",
+				Code:     "INTERNAL",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requestId string
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/loan", nil)
+			middleware.RequestId(slog.New(slog.NewTextHandler(io.Discard, nil)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requestId = middleware.RequestIdFromContext(r.Context())
+				writeError(w, r, tt.err)
+			})).ServeHTTP(w, r)
+
+			if w.Code != tt.wantProblem.Status {
+				t.Errorf("handler returned wrong status code: got %v want %v", w.Code, tt.wantProblem.Status)
+			}
+			if got := w.Header().Get(constant.HttpHeaderContentType); got != constant.HttpHeaderAppProblemJson {
+				t.Errorf("handler returned wrong content type: got %v want %v", got, constant.HttpHeaderAppProblemJson)
+			}
+			if got := w.Header().Get(constant.HttpHeaderRetryAfter); got != tt.wantRetryAfter {
+				t.Errorf("handler returned wrong retry after: got %v want %v", got, tt.wantRetryAfter)
+			}
+
+			var got model.Problem
+			json.NewDecoder(w.Body).Decode(&got)
+			if requestId == "" || got.RequestId != requestId {
+				t.Errorf("handler returned wrong request id: got %v want %v", got.RequestId, requestId)
+			}
+			got.RequestId = ""
+			if !reflect.DeepEqual(got, tt.wantProblem) {
+				t.Errorf("handler returned unexpected problem: got %+v want %+v", got, tt.wantProblem)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"example.com/m/v2/constant"
+	"example.com/m/v2/model"
+)
+
+func (h *Handler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	_, err := h.authorize(r, constant.PermissionRoleAssign)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	roles, err := h.Usecase.ListRoles(ctx)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(model.HttpResRoles{
+		Message: "success",
+		Data:    model.NewRoleResList(roles),
+	})
+}
+
+func (h *Handler) AssignRoles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	var req model.AssignRolesReq
+	err := decode(w, r, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	principal, err := h.authorize(r, constant.PermissionRoleAssign)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = h.Usecase.AssignRoles(ctx, principal, req.UserId, req.Roles)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(model.HttpRes{
+		Message: "success",
+	})
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	u "example.com/m/v2/logic/usecase"
+	"example.com/m/v2/model"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func Test_AssignRoles(t *testing.T) {
+	ucMock := new(u.MockUsecase)
+	principal := model.Principal{UserId: 1, Roles: []model.Role{{Name: constant.RoleSuperAdmin}}}
+	body := `{"user_id": 2, "roles": ["LOAN_OFFICER"]}`
+
+	type args struct {
+		w *httptest.ResponseRecorder
+		r *http.Request
+	}
+
+	tests := []struct {
+		name           string
+		mock           func()
+		args           args
+		wantStatusCode int
+		wantBody       model.HttpRes
+		wantProblem    model.Problem
+	}{
+		{
+			name: "err missing fields",
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("PUT", "/admin/user/roles", strings.NewReader(`{}`)),
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantProblem: model.Problem{
+				Type:     "/problems/validation",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "request validation failed",
+				Instance: "/admin/user/roles",
+				Code:     "VALIDATION",
+				Errors: []model.ProblemFieldError{
+					{Field: "user_id", Message: "is required"},
+					{Field: "roles", Message: "is required"},
+				},
+			},
+		},
+		{
+			name: "forbidden",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(3),
+					}, nil).
+					Once()
+				ucMock.
+					On("Authorize", context.Background(), int64(3), constant.PermissionRoleAssign).
+					Return(model.Principal{UserId: 3}, apperror.ErrForbidden).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("PUT", "/admin/user/roles", strings.NewReader(body)),
+			},
+			wantStatusCode: http.StatusForbidden,
+			wantProblem: model.Problem{
+				Type:     "/problems/forbidden",
+				Title:    "Forbidden",
+				Status:   http.StatusForbidden,
+				Detail:   "forbidden",
+				Instance: "/admin/user/roles",
+				Code:     "FORBIDDEN",
+			},
+		},
+		{
+			name: "err customer account",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(1),
+					}, nil).
+					Once()
+				ucMock.
+					On("Authorize", context.Background(), int64(1), constant.PermissionRoleAssign).
+					Return(principal, nil).
+					Once()
+				ucMock.
+					On("AssignRoles", context.Background(), principal, int64(2), []string{constant.RoleLoanOfficer}).
+					Return(apperror.ErrUserNotStaff).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("PUT", "/admin/user/roles", strings.NewReader(body)),
+			},
+			wantStatusCode: http.StatusConflict,
+			wantProblem: model.Problem{
+				Type:     "/problems/conflict",
+				Title:    "Conflict",
+				Status:   http.StatusConflict,
+				Detail:   "roles can only be granted to admin accounts",
+				Instance: "/admin/user/roles",
+				Code:     "CONFLICT",
+			},
+		},
+		{
+			name: "success",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(1),
+					}, nil).
+					Once()
+				ucMock.
+					On("Authorize", context.Background(), int64(1), constant.PermissionRoleAssign).
+					Return(principal, nil).
+					Once()
+				ucMock.
+					On("AssignRoles", context.Background(), principal, int64(2), []string{constant.RoleLoanOfficer}).
+					Return(nil).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("PUT", "/admin/user/roles", strings.NewReader(body)),
+			},
+			wantStatusCode: 200,
+			wantBody: model.HttpRes{
+				Message: "success",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		h := Handler{
+			Usecase: ucMock,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			h.AssignRoles(tt.args.w, tt.args.r)
+			if tt.args.w.Result().StatusCode != tt.wantStatusCode {
+				t.Errorf("Status code returned, %d, did not match expected code %d", tt.args.w.Result().StatusCode, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode >= http.StatusBadRequest {
+				var got model.Problem
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantProblem) {
+					t.Errorf("handler returned unexpected problem: got %+v want %+v", got, tt.wantProblem)
+				}
+			} else {
+				var got model.HttpRes
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if got != tt.wantBody {
+					t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"example.com/m/v2/constant"
+	"example.com/m/v2/middleware"
+	"example.com/m/v2/model"
+)
+
+func (h *Handler) LoginTotp(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	var req model.LoginTotpReq
+	err := decode(w, r, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	sid, err := h.Usecase.UserLoginTotp(ctx, req.ChallengeToken, req.Code, middleware.ClientIpFromRequest(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	setSessionCookie(w, sid)
+
+	json.NewEncoder(w).Encode(model.LoginRes{
+		Message: "success",
+	})
+}
+
+func (h *Handler) EnrollTotp(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	var req model.TotpEnrollReq
+	err := decode(w, r, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	userId, err := h.authenticateTotp(r, req.ChallengeToken)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	enrollment, err := h.Usecase.EnrollTotp(ctx, userId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(model.HttpResTotpEnroll{
+		Message: "success",
+		Data:    model.NewTotpEnrollRes(enrollment),
+	})
+}
+
+// EnableTotp logs the caller out like every other session, the next login asks for a code.
+func (h *Handler) EnableTotp(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	var req model.TotpEnableReq
+	err := decode(w, r, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	userId, err := h.authenticateTotp(r, req.ChallengeToken)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	codes, err := h.Usecase.EnableTotp(ctx, userId, req.Code)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set(constant.HttpHeaderSetCookie, constant.CookieClearSession)
+
+	json.NewEncoder(w).Encode(model.HttpResTotpEnable{
+		Message: "success",
+		Data: model.TotpEnableRes{
+			RecoveryCodes: codes,
+		},
+	})
+}
+
+// authenticateTotp resolves the enroll challenge of a login when given, the session cookie otherwise.
+func (h *Handler) authenticateTotp(r *http.Request, challengeToken string) (userId int64, err error) {
+	if challengeToken != "" {
+		return h.Usecase.DecodeTotpEnrollChallenge(r.Context(), challengeToken)
+	}
+
+	userId, _, err = h.authenticate(r)
+	return
+}
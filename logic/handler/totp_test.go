@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	u "example.com/m/v2/logic/usecase"
+	"example.com/m/v2/model"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func Test_LoginTotp(t *testing.T) {
+	ucMock := new(u.MockUsecase)
+	body := `{"challenge_token": "challenge", "code": "123456"}`
+
+	type args struct {
+		w *httptest.ResponseRecorder
+		r *http.Request
+	}
+
+	tests := []struct {
+		name           string
+		mock           func()
+		args           args
+		wantStatusCode int
+		wantBody       model.LoginRes
+		wantProblem    model.Problem
+		wantCookie     bool
+	}{
+		{
+			name: "err missing fields",
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/login/2fa", strings.NewReader(`{}`)),
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantProblem: model.Problem{
+				Type:     "/problems/validation",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "request validation failed",
+				Instance: "/user/login/2fa",
+				Code:     "VALIDATION",
+				Errors: []model.ProblemFieldError{
+					{Field: "challenge_token", Message: "is required"},
+					{Field: "code", Message: "is required"},
+				},
+			},
+		},
+		{
+			name: "err invalid code",
+			mock: func() {
+				ucMock.
+					On("UserLoginTotp", context.Background(), "challenge", "123456", "192.0.2.1").
+					Return("", apperror.ErrInvalidTotpCode).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/login/2fa", strings.NewReader(body)),
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			wantProblem: model.Problem{
+				Type:     "/problems/unauthenticated",
+				Title:    "Unauthorized",
+				Status:   http.StatusUnauthorized,
+				Detail:   "invalid two factor code",
+				Instance: "/user/login/2fa",
+				Code:     "UNAUTHENTICATED",
+			},
+		},
+		{
+			name: "success",
+			mock: func() {
+				ucMock.
+					On("UserLoginTotp", context.Background(), "challenge", "123456", "192.0.2.1").
+					Return("sid", nil).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/login/2fa", strings.NewReader(body)),
+			},
+			wantStatusCode: 200,
+			wantBody: model.LoginRes{
+				Message: "success",
+			},
+			wantCookie: true,
+		},
+	}
+
+	for _, tt := range tests {
+		h := Handler{
+			Usecase: ucMock,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			h.LoginTotp(tt.args.w, tt.args.r)
+			if tt.args.w.Result().StatusCode != tt.wantStatusCode {
+				t.Errorf("Status code returned, %d, did not match expected code %d", tt.args.w.Result().StatusCode, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode >= http.StatusBadRequest {
+				var got model.Problem
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantProblem) {
+					t.Errorf("handler returned unexpected problem: got %+v want %+v", got, tt.wantProblem)
+				}
+			} else {
+				var got model.LoginRes
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if got != tt.wantBody {
+					t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+				}
+			}
+
+			gotCookie := strings.HasPrefix(tt.args.w.Header().Get(constant.HttpHeaderSetCookie), "SID=sid;")
+			if gotCookie != tt.wantCookie {
+				t.Errorf("handler returned unexpected cookie: got %q", tt.args.w.Header().Get(constant.HttpHeaderSetCookie))
+			}
+		})
+	}
+}
+
+func Test_EnableTotp(t *testing.T) {
+	ucMock := new(u.MockUsecase)
+
+	type args struct {
+		w *httptest.ResponseRecorder
+		r *http.Request
+	}
+
+	tests := []struct {
+		name           string
+		mock           func()
+		args           args
+		wantStatusCode int
+		wantBody       model.HttpResTotpEnable
+		wantProblem    model.Problem
+		wantHeader     map[string]string
+	}{
+		{
+			name: "err invalid challenge",
+			mock: func() {
+				ucMock.
+					On("DecodeTotpEnrollChallenge", context.Background(), "challenge").
+					Return(int64(0), apperror.ErrInvalidChallengeToken).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/2fa/enable", strings.NewReader(`{"challenge_token": "challenge", "code": "123456"}`)),
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			wantProblem: model.Problem{
+				Type:     "/problems/unauthenticated",
+				Title:    "Unauthorized",
+				Status:   http.StatusUnauthorized,
+				Detail:   "invalid or expired two factor challenge",
+				Instance: "/user/2fa/enable",
+				Code:     "UNAUTHENTICATED",
+			},
+		},
+		{
+			name: "err EnableTotp",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(1),
+					}, nil).
+					Once()
+				ucMock.
+					On("EnableTotp", context.Background(), int64(1), "123456").
+					Return([]string(nil), errors.New("err EnableTotp")).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/2fa/enable", strings.NewReader(`{"code": "123456"}`)),
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantProblem: model.Problem{
+				Type:     "/problems/internal",
+				Title:    "Internal Server Error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "an unexpected error occurred",
+				Instance: "/user/2fa/enable",
+				Code:     "INTERNAL",
+			},
+		},
+		{
+			name: "success with challenge",
+			mock: func() {
+				ucMock.
+					On("DecodeTotpEnrollChallenge", context.Background(), "challenge").
+					Return(int64(1), nil).
+					Once()
+				ucMock.
+					On("EnableTotp", context.Background(), int64(1), "123456").
+					Return([]string{"aaaaa-bbbbb"}, nil).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/2fa/enable", strings.NewReader(`{"challenge_token": "challenge", "code": "123456"}`)),
+			},
+			wantStatusCode: 200,
+			wantBody: model.HttpResTotpEnable{
+				Message: "success",
+				Data: model.TotpEnableRes{
+					RecoveryCodes: []string{"aaaaa-bbbbb"},
+				},
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderSetCookie: constant.CookieClearSession,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		h := Handler{
+			Usecase: ucMock,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			h.EnableTotp(tt.args.w, tt.args.r)
+			if tt.args.w.Result().StatusCode != tt.wantStatusCode {
+				t.Errorf("Status code returned, %d, did not match expected code %d", tt.args.w.Result().StatusCode, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode >= http.StatusBadRequest {
+				var got model.Problem
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantProblem) {
+					t.Errorf("handler returned unexpected problem: got %+v want %+v", got, tt.wantProblem)
+				}
+			} else {
+				var got model.HttpResTotpEnable
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantBody) {
+					t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+				}
+			}
+
+			for k, v := range tt.wantHeader {
+				if got := tt.args.w.Header().Get(k); got != v {
+					t.Errorf("handler returned unexpected header %s: got %q want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
@@ -1,72 +1,165 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
 
 	"example.com/m/v2/constant"
+	"example.com/m/v2/middleware"
 	"example.com/m/v2/model"
 )
 
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
 
-	var req model.User
-	err := json.NewDecoder(r.Body).Decode(&req)
+	var req model.LoginReq
+	err := decode(w, r, &req)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(model.HttpRes{
-			Message: err.Error(),
-		})
+		writeError(w, r, err)
 		return
 	}
 
-	ctx := context.Background()
-
-	sid, err := h.Usecase.UserLogin(ctx, req.Email, req.Password)
+	res, err := h.Usecase.UserLogin(ctx, req.Email, req.Password, middleware.ClientIpFromRequest(r))
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(model.HttpRes{
-			Message: err.Error(),
+		writeError(w, r, err)
+		return
+	}
+
+	if res.ChallengeToken != "" {
+		json.NewEncoder(w).Encode(model.LoginRes{
+			Message:        "two factor authentication required",
+			TwoFactor:      res.TwoFactor,
+			ChallengeToken: res.ChallengeToken,
 		})
 		return
 	}
 
+	setSessionCookie(w, res.SessionToken)
+
+	json.NewEncoder(w).Encode(model.LoginRes{
+		Message: "success",
+	})
+	return
+}
+
+func setSessionCookie(w http.ResponseWriter, sid string) {
 	w.Header().Set(
 		constant.HttpHeaderSetCookie,
 		fmt.Sprintf("SID=%s; HttpOnly; Path=/; Expires=%s; Domain=localhost;", sid, time.Now().Add(24*time.Hour).Format(constant.TimeFormatCookieExpiry)),
 	)
+}
+
+func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	var req model.RegisterReq
+	err := decode(w, r, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = h.Usecase.UserRegister(ctx, req.ToUser())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
 	json.NewEncoder(w).Encode(model.HttpRes{
 		Message: "success",
 	})
-	return
 }
 
-func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
 
-	var req model.User
-	err := json.NewDecoder(r.Body).Decode(&req)
+	userId, _, err := h.authenticate(r)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(model.HttpRes{
-			Message: err.Error(),
-		})
+		writeError(w, r, err)
+		return
+	}
+
+	user, err := h.Usecase.GetUser(ctx, userId)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	ctx := context.Background()
+	json.NewEncoder(w).Encode(model.HttpResUser{
+		Message: "success",
+		Data:    model.NewUserRes(user),
+	})
+}
 
-	err = h.Usecase.UserRegister(ctx, req)
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	var req model.VerifyEmailReq
+	err := decode(w, r, &req)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(model.HttpRes{
-			Message: err.Error(),
-		})
+		writeError(w, r, err)
+		return
+	}
+
+	err = h.Usecase.VerifyEmail(ctx, req.Token)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(model.HttpRes{
+		Message: "success",
+	})
+}
+
+func (h *Handler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	userId, _, err := h.authenticate(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = h.Usecase.ResendVerification(ctx, userId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(model.HttpRes{
+		Message: "success",
+	})
+}
+
+// UnlockLogin lifts a lockout before it expires.
+func (h *Handler) UnlockLogin(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	var req model.UnlockLoginReq
+	err := decode(w, r, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	_, err = h.authorize(r, constant.PermissionLoginUnlock)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = h.Usecase.UnlockLogin(ctx, req.Email, req.Ip)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
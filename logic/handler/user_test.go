@@ -7,17 +7,21 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"example.com/m/v2/apperror"
 	"example.com/m/v2/constant"
 	u "example.com/m/v2/logic/usecase"
 	"example.com/m/v2/model"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 func Test_UserLogin(t *testing.T) {
 	ucMock := new(u.MockUsecase)
-	rBody := model.User{
+	rBody := model.LoginReq{
 		Email:    "tes@tes.com",
 		Password: "tes",
 	}
@@ -34,7 +38,8 @@ func Test_UserLogin(t *testing.T) {
 		mock           func()
 		args           args
 		wantStatusCode int
-		wantBody       model.HttpRes
+		wantBody       model.LoginRes
+		wantProblem    model.Problem
 		wantHeader     map[string]string
 	}{
 		{
@@ -44,10 +49,64 @@ func Test_UserLogin(t *testing.T) {
 				r: httptest.NewRequest("POST", "/user/login", &bytes.Buffer{}),
 			},
 			wantStatusCode: http.StatusBadRequest,
-			wantBody: model.HttpRes{
-				Message: "EOF",
+			wantProblem: model.Problem{
+				Type:     "/problems/invalid-request",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "request body is empty",
+				Instance: "/user/login",
+				Code:     "INVALID_REQUEST",
 			},
 			wantHeader: map[string]string{
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
+			},
+		},
+		{
+			name: "locked",
+			mock: func() {
+				ucMock.
+					On("UserLogin", context.Background(), "tes@tes.com", "wrong", "192.0.2.1").
+					Return(model.LoginResult{}, apperror.ErrTooManyLoginAttempts.WithRetryAfter(90*time.Second)).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/login", bytes.NewBufferString(`{"email":"tes@tes.com","password":"wrong"}`)),
+			},
+			wantStatusCode: http.StatusTooManyRequests,
+			wantProblem: model.Problem{
+				Type:     "/problems/too-many-requests",
+				Title:    "Too Many Requests",
+				Status:   http.StatusTooManyRequests,
+				Detail:   "too many failed login attempts, retry later",
+				Instance: "/user/login",
+				Code:     "TOO_MANY_REQUESTS",
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
+				constant.HttpHeaderRetryAfter:  "90",
+			},
+		},
+		{
+			name: "two factor challenge",
+			mock: func() {
+				ucMock.
+					On("UserLogin", context.Background(), "admin@tes.com", "tes", "192.0.2.1").
+					Return(model.LoginResult{ChallengeToken: "c", TwoFactor: constant.TwoFactorVerify}, nil).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/login", bytes.NewBufferString(`{"email":"admin@tes.com","password":"tes"}`)),
+			},
+			wantStatusCode: 200,
+			wantBody: model.LoginRes{
+				Message:        "two factor authentication required",
+				TwoFactor:      constant.TwoFactorVerify,
+				ChallengeToken: "c",
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderSetCookie:  "",
 				constant.HttpHeaderSetContent: constant.HttpHeaderAppJson,
 			},
 		},
@@ -55,8 +114,8 @@ func Test_UserLogin(t *testing.T) {
 			name: "success",
 			mock: func() {
 				ucMock.
-					On("UserLogin", context.Background(), "tes@tes.com", "tes").
-					Return("a", nil).
+					On("UserLogin", context.Background(), "tes@tes.com", "tes", "192.0.2.1").
+					Return(model.LoginResult{SessionToken: "a"}, nil).
 					Once()
 			},
 			args: args{
@@ -64,7 +123,7 @@ func Test_UserLogin(t *testing.T) {
 				r: httptest.NewRequest("POST", "/user/login", &buf),
 			},
 			wantStatusCode: 200,
-			wantBody: model.HttpRes{
+			wantBody: model.LoginRes{
 				Message: "success",
 			},
 			wantHeader: map[string]string{
@@ -89,10 +148,161 @@ func Test_UserLogin(t *testing.T) {
 				t.Errorf("Status code returned, %d, did not match expected code %d", tt.args.w.Result().StatusCode, tt.wantStatusCode)
 			}
 
-			var got model.HttpRes
-			json.NewDecoder(tt.args.w.Body).Decode(&got)
-			if got != tt.wantBody {
-				t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+			if tt.wantStatusCode >= http.StatusBadRequest {
+				var got model.Problem
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantProblem) {
+					t.Errorf("handler returned unexpected problem: got %+v want %+v", got, tt.wantProblem)
+				}
+			} else {
+				var got model.LoginRes
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if got != tt.wantBody {
+					t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+				}
+			}
+
+			for key, val := range tt.wantHeader {
+				if tt.args.w.Header().Get(key) != val {
+					t.Errorf("handler returned unexpected header: got %+v want %+v", tt.args.w.Header().Get(key), val)
+				}
+			}
+		})
+	}
+}
+
+func Test_UnlockLogin(t *testing.T) {
+	ucMock := new(u.MockUsecase)
+	principal := model.Principal{UserId: 1, Roles: []model.Role{{Name: constant.RoleSuperAdmin}}}
+
+	type args struct {
+		w *httptest.ResponseRecorder
+		r *http.Request
+	}
+
+	tests := []struct {
+		name           string
+		mock           func()
+		args           args
+		wantStatusCode int
+		wantBody       model.HttpRes
+		wantProblem    model.Problem
+		wantHeader     map[string]string
+	}{
+		{
+			name: "err validation",
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/admin/user/unlock", bytes.NewBufferString(`{"ip":"tes"}`)),
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantProblem: model.Problem{
+				Type:     "/problems/validation",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "request validation failed",
+				Instance: "/admin/user/unlock",
+				Code:     "VALIDATION",
+				Errors: []model.ProblemFieldError{
+					{Field: "ip", Message: "must be a valid ip address"},
+				},
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
+			},
+		},
+		{
+			name: "forbidden",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id":   float64(2),
+						"role": constant.CustomerRole,
+					}, nil).
+					Once()
+				ucMock.
+					On("Authorize", context.Background(), int64(2), constant.PermissionLoginUnlock).
+					Return(model.Principal{UserId: 2}, apperror.ErrForbidden).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/admin/user/unlock", bytes.NewBufferString(`{"email":"tes@tes.com"}`)),
+			},
+			wantStatusCode: http.StatusForbidden,
+			wantProblem: model.Problem{
+				Type:     "/problems/forbidden",
+				Title:    "Forbidden",
+				Status:   http.StatusForbidden,
+				Detail:   "forbidden",
+				Instance: "/admin/user/unlock",
+				Code:     "FORBIDDEN",
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
+			},
+		},
+		{
+			name: "success",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id":   float64(1),
+						"role": constant.AdminRole,
+					}, nil).
+					Once()
+				ucMock.
+					On("Authorize", context.Background(), int64(1), constant.PermissionLoginUnlock).
+					Return(principal, nil).
+					Once()
+				ucMock.
+					On("UnlockLogin", context.Background(), "tes@tes.com", "192.0.2.1").
+					Return(nil).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/admin/user/unlock", bytes.NewBufferString(`{"email":"tes@tes.com","ip":"192.0.2.1"}`)),
+			},
+			wantStatusCode: 200,
+			wantBody: model.HttpRes{
+				Message: "success",
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderSetContent: constant.HttpHeaderAppJson,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		h := Handler{
+			Usecase: ucMock,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			h.UnlockLogin(tt.args.w, tt.args.r)
+			if tt.args.w.Result().StatusCode != tt.wantStatusCode {
+				t.Errorf("Status code returned, %d, did not match expected code %d", tt.args.w.Result().StatusCode, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode >= http.StatusBadRequest {
+				var got model.Problem
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantProblem) {
+					t.Errorf("handler returned unexpected problem: got %+v want %+v", got, tt.wantProblem)
+				}
+			} else {
+				var got model.HttpRes
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if got != tt.wantBody {
+					t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+				}
 			}
 
 			for key, val := range tt.wantHeader {
@@ -106,7 +316,7 @@ func Test_UserLogin(t *testing.T) {
 
 func Test_UserRegister(t *testing.T) {
 	ucMock := new(u.MockUsecase)
-	rBody := model.User{
+	rBody := model.RegisterReq{
 		Email:    "tes@tes.com",
 		Password: "tes",
 	}
@@ -124,6 +334,7 @@ func Test_UserRegister(t *testing.T) {
 		args           args
 		wantStatusCode int
 		wantBody       model.HttpRes
+		wantProblem    model.Problem
 		wantHeader     map[string]string
 	}{
 		{
@@ -133,11 +344,58 @@ func Test_UserRegister(t *testing.T) {
 				r: httptest.NewRequest("POST", "/user/register", &bytes.Buffer{}),
 			},
 			wantStatusCode: http.StatusBadRequest,
-			wantBody: model.HttpRes{
-				Message: "EOF",
+			wantProblem: model.Problem{
+				Type:     "/problems/invalid-request",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "request body is empty",
+				Instance: "/user/register",
+				Code:     "INVALID_REQUEST",
 			},
 			wantHeader: map[string]string{
-				constant.HttpHeaderSetContent: constant.HttpHeaderAppJson,
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
+			},
+		},
+		{
+			name: "err role is not accepted",
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/register", strings.NewReader(`{"email": "tes@tes.com", "password": "tes", "role": "ADMIN"}`)),
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantProblem: model.Problem{
+				Type:     "/problems/invalid-request",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   `json: unknown field "role"`,
+				Instance: "/user/register",
+				Code:     "INVALID_REQUEST",
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
+			},
+		},
+		{
+			name: "err invalid email and missing password",
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/register", strings.NewReader(`{"email": "tes"}`)),
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantProblem: model.Problem{
+				Type:     "/problems/validation",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "request validation failed",
+				Instance: "/user/register",
+				Code:     "VALIDATION",
+				Errors: []model.ProblemFieldError{
+					{Field: "email", Message: "must be a valid email address"},
+					{Field: "password", Message: "is required"},
+				},
+			},
+			wantHeader: map[string]string{
+				constant.HttpHeaderContentType: constant.HttpHeaderAppProblemJson,
 			},
 		},
 		{
@@ -180,10 +438,18 @@ func Test_UserRegister(t *testing.T) {
 				t.Errorf("Status code returned, %d, did not match expected code %d", tt.args.w.Result().StatusCode, tt.wantStatusCode)
 			}
 
-			var got model.HttpRes
-			json.NewDecoder(tt.args.w.Body).Decode(&got)
-			if got != tt.wantBody {
-				t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+			if tt.wantStatusCode >= http.StatusBadRequest {
+				var got model.Problem
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantProblem) {
+					t.Errorf("handler returned unexpected problem: got %+v want %+v", got, tt.wantProblem)
+				}
+			} else {
+				var got model.HttpRes
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if got != tt.wantBody {
+					t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+				}
 			}
 
 			for key, val := range tt.wantHeader {
@@ -194,3 +460,373 @@ func Test_UserRegister(t *testing.T) {
 		})
 	}
 }
+
+func Test_Me(t *testing.T) {
+	ucMock := new(u.MockUsecase)
+	hash := "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy"
+
+	type args struct {
+		w *httptest.ResponseRecorder
+		r *http.Request
+	}
+
+	tests := []struct {
+		name           string
+		mock           func()
+		args           args
+		wantStatusCode int
+		wantBody       model.HttpResUser
+		wantProblem    model.Problem
+	}{
+		{
+			name: "err DecodeJwt",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{}, apperror.ErrCookieNotFound).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("GET", "/user/me", nil),
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			wantProblem: model.Problem{
+				Type:     "/problems/unauthenticated",
+				Title:    "Unauthorized",
+				Status:   http.StatusUnauthorized,
+				Detail:   "cookie not found",
+				Instance: "/user/me",
+				Code:     "UNAUTHENTICATED",
+			},
+		},
+		{
+			name: "user not found",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(1),
+					}, nil).
+					Once()
+				ucMock.
+					On("GetUser", context.Background(), int64(1)).
+					Return(model.User{}, apperror.ErrUserNotFound).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("GET", "/user/me", nil),
+			},
+			wantStatusCode: http.StatusNotFound,
+			wantProblem: model.Problem{
+				Type:     "/problems/not-found",
+				Title:    "Not Found",
+				Status:   http.StatusNotFound,
+				Detail:   "user not found",
+				Instance: "/user/me",
+				Code:     "NOT_FOUND",
+			},
+		},
+		{
+			name: "success",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(1),
+					}, nil).
+					Once()
+				ucMock.
+					On("GetUser", context.Background(), int64(1)).
+					Return(model.User{
+						Id:       1,
+						Email:    "tes@tes.com",
+						Password: hash,
+						Role:     constant.CustomerRole,
+					}, nil).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("GET", "/user/me", nil),
+			},
+			wantStatusCode: 200,
+			wantBody: model.HttpResUser{
+				Message: "success",
+				Data: model.UserRes{
+					Id:    1,
+					Email: "tes@tes.com",
+					Role:  constant.CustomerRole,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		h := Handler{
+			Usecase: ucMock,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			h.Me(tt.args.w, tt.args.r)
+			if tt.args.w.Result().StatusCode != tt.wantStatusCode {
+				t.Errorf("Status code returned, %d, did not match expected code %d", tt.args.w.Result().StatusCode, tt.wantStatusCode)
+			}
+
+			body := tt.args.w.Body.String()
+			if strings.Contains(body, hash) || strings.Contains(body, "password") {
+				t.Errorf("handler leaked the password hash: %s", body)
+			}
+
+			if tt.wantStatusCode >= http.StatusBadRequest {
+				var got model.Problem
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantProblem) {
+					t.Errorf("handler returned unexpected problem: got %+v want %+v", got, tt.wantProblem)
+				}
+			} else {
+				var got model.HttpResUser
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if got != tt.wantBody {
+					t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+				}
+			}
+		})
+	}
+}
+
+func Test_VerifyEmail(t *testing.T) {
+	ucMock := new(u.MockUsecase)
+
+	type args struct {
+		w *httptest.ResponseRecorder
+		r *http.Request
+	}
+
+	tests := []struct {
+		name           string
+		mock           func()
+		args           args
+		wantStatusCode int
+		wantBody       model.HttpRes
+		wantProblem    model.Problem
+	}{
+		{
+			name: "err missing token",
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/verify", strings.NewReader(`{}`)),
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantProblem: model.Problem{
+				Type:     "/problems/validation",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "request validation failed",
+				Instance: "/user/verify",
+				Code:     "VALIDATION",
+				Errors: []model.ProblemFieldError{
+					{Field: "token", Message: "is required"},
+				},
+			},
+		},
+		{
+			name: "err invalid token",
+			mock: func() {
+				ucMock.
+					On("VerifyEmail", context.Background(), "tes").
+					Return(apperror.ErrInvalidVerificationToken).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/verify", strings.NewReader(`{"token": "tes"}`)),
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantProblem: model.Problem{
+				Type:     "/problems/validation",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "invalid or expired verification token",
+				Instance: "/user/verify",
+				Code:     "VALIDATION",
+			},
+		},
+		{
+			name: "success",
+			mock: func() {
+				ucMock.
+					On("VerifyEmail", context.Background(), "tes").
+					Return(nil).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/verify", strings.NewReader(`{"token": "tes"}`)),
+			},
+			wantStatusCode: 200,
+			wantBody: model.HttpRes{
+				Message: "success",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		h := Handler{
+			Usecase: ucMock,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			h.VerifyEmail(tt.args.w, tt.args.r)
+			if tt.args.w.Result().StatusCode != tt.wantStatusCode {
+				t.Errorf("Status code returned, %d, did not match expected code %d", tt.args.w.Result().StatusCode, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode >= http.StatusBadRequest {
+				var got model.Problem
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantProblem) {
+					t.Errorf("handler returned unexpected problem: got %+v want %+v", got, tt.wantProblem)
+				}
+			} else {
+				var got model.HttpRes
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if got != tt.wantBody {
+					t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+				}
+			}
+		})
+	}
+}
+
+func Test_ResendVerification(t *testing.T) {
+	ucMock := new(u.MockUsecase)
+
+	type args struct {
+		w *httptest.ResponseRecorder
+		r *http.Request
+	}
+
+	tests := []struct {
+		name           string
+		mock           func()
+		args           args
+		wantStatusCode int
+		wantBody       model.HttpRes
+		wantProblem    model.Problem
+	}{
+		{
+			name: "err DecodeJwt",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{}, apperror.ErrCookieNotFound).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/verify/resend", nil),
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			wantProblem: model.Problem{
+				Type:     "/problems/unauthenticated",
+				Title:    "Unauthorized",
+				Status:   http.StatusUnauthorized,
+				Detail:   "cookie not found",
+				Instance: "/user/verify/resend",
+				Code:     "UNAUTHENTICATED",
+			},
+		},
+		{
+			name: "err already verified",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(1),
+					}, nil).
+					Once()
+				ucMock.
+					On("ResendVerification", context.Background(), int64(1)).
+					Return(apperror.ErrEmailAlreadyVerified).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/verify/resend", nil),
+			},
+			wantStatusCode: http.StatusConflict,
+			wantProblem: model.Problem{
+				Type:     "/problems/conflict",
+				Title:    "Conflict",
+				Status:   http.StatusConflict,
+				Detail:   "email already verified",
+				Instance: "/user/verify/resend",
+				Code:     "CONFLICT",
+			},
+		},
+		{
+			name: "success",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(1),
+					}, nil).
+					Once()
+				ucMock.
+					On("ResendVerification", context.Background(), int64(1)).
+					Return(nil).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/user/verify/resend", nil),
+			},
+			wantStatusCode: 200,
+			wantBody: model.HttpRes{
+				Message: "success",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		h := Handler{
+			Usecase: ucMock,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			h.ResendVerification(tt.args.w, tt.args.r)
+			if tt.args.w.Result().StatusCode != tt.wantStatusCode {
+				t.Errorf("Status code returned, %d, did not match expected code %d", tt.args.w.Result().StatusCode, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode >= http.StatusBadRequest {
+				var got model.Problem
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantProblem) {
+					t.Errorf("handler returned unexpected problem: got %+v want %+v", got, tt.wantProblem)
+				}
+			} else {
+				var got model.HttpRes
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if got != tt.wantBody {
+					t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/model"
+)
+
+func (h *Handler) ListWebhookEndpoints(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	_, err := h.authorize(r, constant.PermissionWebhookManage)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	endpoints, err := h.Usecase.ListWebhookEndpoints(ctx)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(model.HttpResWebhookEndpoints{
+		Message: "success",
+		Data:    model.NewWebhookEndpointResList(endpoints),
+	})
+}
+
+// CreateWebhookEndpoint registers a partner endpoint, the response holds its signing secret which is not shown again.
+func (h *Handler) CreateWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	var req model.CreateWebhookEndpointReq
+	err := decode(w, r, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	principal, err := h.authorize(r, constant.PermissionWebhookManage)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	endpoint, err := h.Usecase.CreateWebhookEndpoint(ctx, principal, model.WebhookEndpoint{
+		Url:         req.Url,
+		Description: req.Description,
+		EventTypes:  req.EventTypes,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(model.HttpResWebhookEndpoint{
+		Message: "success",
+		Data:    model.NewWebhookEndpointRes(endpoint, true),
+	})
+}
+
+func (h *Handler) UpdateWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	var req model.UpdateWebhookEndpointReq
+	err := decode(w, r, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	_, err = h.authorize(r, constant.PermissionWebhookManage)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	endpoint, err := h.Usecase.UpdateWebhookEndpoint(ctx, model.WebhookEndpoint{
+		Id:          req.Id,
+		Url:         req.Url,
+		Description: req.Description,
+		EventTypes:  req.EventTypes,
+		Active:      *req.Active,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(model.HttpResWebhookEndpoint{
+		Message: "success",
+		Data:    model.NewWebhookEndpointRes(endpoint, false),
+	})
+}
+
+// DeleteWebhookEndpoint removes the endpoint given by the id query parameter.
+func (h *Handler) DeleteWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil || id < 1 {
+		writeError(w, r, apperror.Validation(apperror.FieldError{Field: "id", Message: "is required"}))
+		return
+	}
+
+	_, err = h.authorize(r, constant.PermissionWebhookManage)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = h.Usecase.DeleteWebhookEndpoint(ctx, id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(model.HttpRes{
+		Message: "success",
+	})
+}
+
+// RotateWebhookSecret replaces the signing secret of an endpoint, the response holds the new one.
+func (h *Handler) RotateWebhookSecret(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	var req model.RotateWebhookSecretReq
+	err := decode(w, r, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	_, err = h.authorize(r, constant.PermissionWebhookManage)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	endpoint, err := h.Usecase.RotateWebhookSecret(ctx, req.Id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(model.HttpResWebhookEndpoint{
+		Message: "success",
+		Data:    model.NewWebhookEndpointRes(endpoint, true),
+	})
+}
+
+// GetWebhookDeliveries lists the latest deliveries of the endpoint given by the endpoint_id query parameter,
+// optionally only those with the status query parameter.
+func (h *Handler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	endpointId, err := strconv.ParseInt(r.URL.Query().Get("endpoint_id"), 10, 64)
+	if err != nil || endpointId < 1 {
+		writeError(w, r, apperror.Validation(apperror.FieldError{Field: "endpoint_id", Message: "is required"}))
+		return
+	}
+
+	_, err = h.authorize(r, constant.PermissionWebhookManage)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	deliveries, err := h.Usecase.GetWebhookDeliveries(ctx, endpointId, r.URL.Query().Get("status"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(model.HttpResWebhookDeliveries{
+		Message: "success",
+		Data:    model.NewWebhookDeliveryResList(deliveries),
+	})
+}
+
+// GetWebhookDelivery returns the delivery given by the id query parameter with its log.
+func (h *Handler) GetWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil || id < 1 {
+		writeError(w, r, apperror.Validation(apperror.FieldError{Field: "id", Message: "is required"}))
+		return
+	}
+
+	_, err = h.authorize(r, constant.PermissionWebhookManage)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	delivery, err := h.Usecase.GetWebhookDelivery(ctx, id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(model.HttpResWebhookDelivery{
+		Message: "success",
+		Data:    model.NewWebhookDeliveryRes(delivery),
+	})
+}
+
+func (h *Handler) RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(constant.HttpHeaderSetContent, constant.HttpHeaderAppJson)
+	ctx := r.Context()
+
+	var req model.RedeliverWebhookReq
+	err := decode(w, r, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	_, err = h.authorize(r, constant.PermissionWebhookManage)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = h.Usecase.RedeliverWebhook(ctx, req.DeliveryId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(model.HttpRes{
+		Message: "success",
+	})
+}
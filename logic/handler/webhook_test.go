@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	u "example.com/m/v2/logic/usecase"
+	"example.com/m/v2/model"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func Test_CreateWebhookEndpoint(t *testing.T) {
+	ucMock := new(u.MockUsecase)
+	principal := model.Principal{UserId: 1, Roles: []model.Role{{Name: constant.RoleSuperAdmin}}}
+	body := `{"url": "https://partner.tes/hooks", "description": "partner", "event_types": ["loan.paid"]}`
+	endpoint := model.WebhookEndpoint{Url: "https://partner.tes/hooks", Description: "partner", EventTypes: []string{constant.EventLoanPaid}}
+	createdAt := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+
+	type args struct {
+		w *httptest.ResponseRecorder
+		r *http.Request
+	}
+
+	tests := []struct {
+		name           string
+		mock           func()
+		args           args
+		wantStatusCode int
+		wantBody       model.HttpResWebhookEndpoint
+		wantProblem    model.Problem
+	}{
+		{
+			name: "err invalid fields",
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/admin/webhooks", strings.NewReader(`{"event_types": ["loan.paid", "loan.paid"]}`)),
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantProblem: model.Problem{
+				Type:     "/problems/validation",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "request validation failed",
+				Instance: "/admin/webhooks",
+				Code:     "VALIDATION",
+				Errors: []model.ProblemFieldError{
+					{Field: "url", Message: "is required"},
+					{Field: "event_types", Message: "must not contain duplicates"},
+				},
+			},
+		},
+		{
+			name: "forbidden",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(3),
+					}, nil).
+					Once()
+				ucMock.
+					On("Authorize", context.Background(), int64(3), constant.PermissionWebhookManage).
+					Return(model.Principal{UserId: 3}, apperror.ErrForbidden).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/admin/webhooks", strings.NewReader(body)),
+			},
+			wantStatusCode: http.StatusForbidden,
+			wantProblem: model.Problem{
+				Type:     "/problems/forbidden",
+				Title:    "Forbidden",
+				Status:   http.StatusForbidden,
+				Detail:   "forbidden",
+				Instance: "/admin/webhooks",
+				Code:     "FORBIDDEN",
+			},
+		},
+		{
+			name: "err http url",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(1),
+					}, nil).
+					Once()
+				ucMock.
+					On("Authorize", context.Background(), int64(1), constant.PermissionWebhookManage).
+					Return(principal, nil).
+					Once()
+				ucMock.
+					On("CreateWebhookEndpoint", context.Background(), principal, model.WebhookEndpoint{Url: "http://partner.tes/hooks"}).
+					Return(model.WebhookEndpoint{}, apperror.Validation(apperror.FieldError{Field: "url", Message: "must use https"})).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/admin/webhooks", strings.NewReader(`{"url": "http://partner.tes/hooks"}`)),
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantProblem: model.Problem{
+				Type:     "/problems/validation",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "request validation failed",
+				Instance: "/admin/webhooks",
+				Code:     "VALIDATION",
+				Errors: []model.ProblemFieldError{
+					{Field: "url", Message: "must use https"},
+				},
+			},
+		},
+		{
+			name: "success",
+			mock: func() {
+				ucMock.
+					On("DecodeJwt", context.Background(), []*http.Cookie{}).
+					Return(jwt.MapClaims{
+						"id": float64(1),
+					}, nil).
+					Once()
+				ucMock.
+					On("Authorize", context.Background(), int64(1), constant.PermissionWebhookManage).
+					Return(principal, nil).
+					Once()
+
+				created := endpoint
+				created.Id = 3
+				created.Secret = "whsec_tes"
+				created.Active = true
+				created.CreatedBy = 1
+				created.CreatedAt = createdAt
+				created.UpdatedAt = createdAt
+				ucMock.
+					On("CreateWebhookEndpoint", context.Background(), principal, endpoint).
+					Return(created, nil).
+					Once()
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest("POST", "/admin/webhooks", strings.NewReader(body)),
+			},
+			wantStatusCode: 200,
+			wantBody: model.HttpResWebhookEndpoint{
+				Message: "success",
+				Data: model.WebhookEndpointRes{
+					Id:          3,
+					Url:         "https://partner.tes/hooks",
+					Description: "partner",
+					Secret:      "whsec_tes",
+					EventTypes:  []string{constant.EventLoanPaid},
+					Active:      true,
+					CreatedAt:   createdAt,
+					UpdatedAt:   createdAt,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		h := Handler{
+			Usecase: ucMock,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			h.CreateWebhookEndpoint(tt.args.w, tt.args.r)
+			if tt.args.w.Result().StatusCode != tt.wantStatusCode {
+				t.Errorf("Status code returned, %d, did not match expected code %d", tt.args.w.Result().StatusCode, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode >= http.StatusBadRequest {
+				var got model.Problem
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantProblem) {
+					t.Errorf("handler returned unexpected problem: got %+v want %+v", got, tt.wantProblem)
+				}
+			} else {
+				var got model.HttpResWebhookEndpoint
+				json.NewDecoder(tt.args.w.Body).Decode(&got)
+				if !reflect.DeepEqual(got, tt.wantBody) {
+					t.Errorf("handler returned unexpected body: got %+v want %+v", got, tt.wantBody)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/model"
+	"example.com/m/v2/tracing"
+)
+
+// GetLoanForUpdate locks the loan until tx ends, concurrent approvals of the same loan are serialized.
+func (r *repository) GetLoanForUpdate(ctx context.Context, tx *sql.Tx, loanId int64) (res model.Loan, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetLoanForUpdate", "SELECT", "loans")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT
+			id, user_id, amount, status, created_at, disbursed_at
+		FROM
+			loans
+		WHERE
+			id = $1
+		FOR UPDATE
+	`
+
+	row := tx.QueryRowContext(ctx, query, loanId)
+	err = row.Scan(&res.Id, &res.UserId, &res.Amount, &res.Status, &res.CreatedAt, &res.DisbursedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = apperror.ErrLoanNotFound.WithCause(err)
+	}
+
+	return
+}
+
+// InsertLoanApproval records an approval, an expired approval of the same approver is renewed.
+// It fails with ErrAlreadyApproved while the approver's previous approval still counts.
+func (r *repository) InsertLoanApproval(ctx context.Context, tx *sql.Tx, approval model.LoanApproval) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.InsertLoanApproval", "INSERT", "loan_approvals")
+	defer tracing.End(span, &err)
+
+	query := `
+		INSERT INTO
+			loan_approvals(
+				loan_id, approver_id, approved_at, expires_at
+			)
+		VALUES
+			($1,$2,$3,$4)
+		ON CONFLICT (loan_id, approver_id) DO UPDATE SET
+			approved_at = EXCLUDED.approved_at,
+			expires_at = EXCLUDED.expires_at
+		WHERE
+			loan_approvals.expires_at <= EXCLUDED.approved_at
+	`
+	res, err := tx.ExecContext(ctx, query, approval.LoanId, approval.ApproverId, approval.ApprovedAt, approval.ExpiresAt)
+	if err != nil {
+		return
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if affected == 0 {
+		err = apperror.ErrAlreadyApproved
+	}
+
+	return
+}
+
+// CountLoanApprovals counts the approvals of a loan not expired at now.
+func (r *repository) CountLoanApprovals(ctx context.Context, tx *sql.Tx, loanId int64, now time.Time) (n int, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.CountLoanApprovals", "SELECT", "loan_approvals")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT
+			COUNT(*)
+		FROM
+			loan_approvals
+		WHERE
+			loan_id = $1 AND expires_at > $2
+	`
+	err = tx.QueryRowContext(ctx, query, loanId, now).Scan(&n)
+
+	return
+}
+
+// GetLoanApprovals returns every approval of a loan, expired ones included, oldest first.
+func (r *repository) GetLoanApprovals(ctx context.Context, loanId int64) (res []model.LoanApproval, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetLoanApprovals", "SELECT", "loan_approvals")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT
+			id, loan_id, approver_id, approved_at, expires_at
+		FROM
+			loan_approvals
+		WHERE
+			loan_id = $1
+		ORDER BY
+			approved_at
+	`
+
+	rows, err := r.Db.QueryContext(ctx, query, loanId)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		temp := model.LoanApproval{}
+		err = rows.Scan(&temp.Id, &temp.LoanId, &temp.ApproverId, &temp.ApprovedAt, &temp.ExpiresAt)
+		if err != nil {
+			return
+		}
+		res = append(res, temp)
+	}
+	err = rows.Err()
+
+	return
+}
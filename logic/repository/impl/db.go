@@ -3,6 +3,9 @@ package impl
 import (
 	"context"
 	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
 )
 
 func (r *repository) BeginTx(ctx context.Context) (*sql.Tx, error) {
@@ -16,3 +19,9 @@ func (r *repository) RollbackTx(tx *sql.Tx) error {
 func (r *repository) CommitTx(tx *sql.Tx) error {
 	return tx.Commit()
 }
+
+// isUniqueViolation reports whether err is a postgres unique_violation (23505).
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
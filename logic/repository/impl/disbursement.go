@@ -0,0 +1,174 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/model"
+	"example.com/m/v2/tracing"
+)
+
+func (r *repository) PayoutProvider() string {
+	return r.payout.Name()
+}
+
+func (r *repository) Payout(ctx context.Context, req model.PayoutRequest) (res model.PayoutResult, err error) {
+	ctx, span := tracing.Start(ctx, "repository.Payout")
+	defer tracing.End(span, &err)
+
+	return r.payout.Payout(ctx, req)
+}
+
+func (r *repository) InsertDisbursement(ctx context.Context, tx *sql.Tx, disbursement model.Disbursement) (id int64, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.InsertDisbursement", "INSERT", "disbursements")
+	defer tracing.End(span, &err)
+
+	query := `
+		INSERT INTO
+			disbursements(
+				loan_id, amount, idempotency_key, provider, status, created_at, updated_at
+			)
+		VALUES
+			($1,$2,$3,$4,$5,$6,$6)
+		RETURNING
+			id
+	`
+	row := tx.QueryRowContext(ctx, query, disbursement.LoanId, disbursement.Amount, disbursement.IdempotencyKey,
+		disbursement.Provider, disbursement.Status, disbursement.CreatedAt)
+
+	err = row.Scan(&id)
+
+	return
+}
+
+// GetActiveDisbursement returns the pending or succeeded disbursement of a loan, failed attempts are ignored.
+func (r *repository) GetActiveDisbursement(ctx context.Context, tx *sql.Tx, loanId int64) (res model.Disbursement, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetActiveDisbursement", "SELECT", "disbursements")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT
+			id, loan_id, amount, idempotency_key, provider, COALESCE(provider_reference,''), status,
+			COALESCE(failure_reason,''), attempts, COALESCE(last_error,''), created_at, completed_at
+		FROM
+			disbursements
+		WHERE
+			loan_id = $1 AND status <> 'FAILED'
+	`
+
+	row := tx.QueryRowContext(ctx, query, loanId)
+	err = row.Scan(&res.Id, &res.LoanId, &res.Amount, &res.IdempotencyKey, &res.Provider, &res.ProviderReference,
+		&res.Status, &res.FailureReason, &res.Attempts, &res.LastError, &res.CreatedAt, &res.CompletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = apperror.ErrDisbursementNotFound.WithCause(err)
+	}
+
+	return
+}
+
+// UpdateDisbursement stores the outcome of a payout attempt and counts the attempt.
+func (r *repository) UpdateDisbursement(ctx context.Context, tx *sql.Tx, disbursement model.Disbursement) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.UpdateDisbursement", "UPDATE", "disbursements")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE
+			disbursements
+		SET
+			provider_reference = COALESCE(NULLIF($1,''),provider_reference),
+			status = $2,
+			failure_reason = NULLIF($3,''),
+			completed_at = $4,
+			attempts = attempts + 1,
+			last_error = NULL,
+			updated_at = $5
+		WHERE
+			id = $6
+	`
+	_, err = tx.ExecContext(ctx, query, disbursement.ProviderReference, disbursement.Status, disbursement.FailureReason,
+		disbursement.CompletedAt, time.Now(), disbursement.Id)
+
+	return
+}
+
+// RecordDisbursementError counts a payout attempt that got no answer from the provider, the disbursement stays pending.
+func (r *repository) RecordDisbursementError(ctx context.Context, id int64, message string, at time.Time) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.RecordDisbursementError", "UPDATE", "disbursements")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE
+			disbursements
+		SET
+			attempts = attempts + 1,
+			last_error = $1,
+			updated_at = $2
+		WHERE
+			id = $3
+	`
+	_, err = r.Db.ExecContext(ctx, query, message, at, id)
+
+	return
+}
+
+// GetStaleDisbursements lists the DISBURSING loans whose pending payout was last updated before updatedBefore.
+func (r *repository) GetStaleDisbursements(ctx context.Context, updatedBefore time.Time) (loanIds []int64, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetStaleDisbursements", "SELECT", "disbursements")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT
+			d.loan_id
+		FROM
+			disbursements d
+			JOIN loans l ON l.id = d.loan_id
+		WHERE
+			d.status = 'PENDING'
+			AND l.status = 'DISBURSING'
+			AND d.updated_at < $1
+		ORDER BY
+			d.id ASC
+	`
+
+	rows, err := r.Db.QueryContext(ctx, query, updatedBefore)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var loanId int64
+		err = rows.Scan(&loanId)
+		if err != nil {
+			return
+		}
+		loanIds = append(loanIds, loanId)
+	}
+	err = rows.Err()
+
+	return
+}
+
+// ScheduleRepayments sets the due dates of a loan's terms, one week apart starting a week after from.
+func (r *repository) ScheduleRepayments(ctx context.Context, tx *sql.Tx, loanId int64, from time.Time) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.ScheduleRepayments", "UPDATE", "repayments")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE
+			repayments
+		SET
+			due_date = $1::timestamptz + make_interval(days => 7 * terms.term::int),
+			updated_at = $2
+		FROM
+			(SELECT id, row_number() OVER (ORDER BY id) AS term FROM repayments WHERE loan_id = $3) terms
+		WHERE
+			repayments.id = terms.id
+	`
+	_, err = tx.ExecContext(ctx, query, from, time.Now(), loanId)
+
+	return
+}
@@ -3,16 +3,28 @@ package impl
 import (
 	"database/sql"
 
+	"example.com/m/v2/config"
 	r "example.com/m/v2/logic/repository"
+	"example.com/m/v2/mailer"
+	"example.com/m/v2/payment"
+	"example.com/m/v2/payout"
 	"example.com/m/v2/resource"
 )
 
 type repository struct {
-	Db *sql.DB
+	Db        *sql.DB
+	jwtSecret []byte
+	mailer    mailer.Mailer
+	payout    payout.PayoutProvider
+	gateway   payment.PaymentGateway
 }
 
-func New(res *resource.Resource) r.Repository {
+func New(res *resource.Resource, cfg *config.Config) r.Repository {
 	return &repository{
-		Db: res.PostgresDb,
+		Db:        res.PostgresDb,
+		jwtSecret: []byte(cfg.JwtSecret),
+		mailer:    res.Mailer,
+		payout:    res.Payout,
+		gateway:   res.Payment,
 	}
 }
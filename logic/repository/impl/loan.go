@@ -3,12 +3,18 @@ package impl
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
+	"example.com/m/v2/apperror"
 	"example.com/m/v2/model"
+	"example.com/m/v2/tracing"
 )
 
 func (r *repository) InsertLoan(ctx context.Context, tx *sql.Tx, loan model.Loan) (id int64, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.InsertLoan", "INSERT", "loans")
+	defer tracing.End(span, &err)
+
 	query := `
 		INSERT INTO
 			loans(
@@ -27,6 +33,9 @@ func (r *repository) InsertLoan(ctx context.Context, tx *sql.Tx, loan model.Loan
 }
 
 func (r *repository) UpdateLoan(ctx context.Context, tx *sql.Tx, loan model.Loan) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.UpdateLoan", "UPDATE", "loans")
+	defer tracing.End(span, &err)
+
 	query := `
 		UPDATE
 			loans
@@ -34,20 +43,54 @@ func (r *repository) UpdateLoan(ctx context.Context, tx *sql.Tx, loan model.Loan
 			amount = COALESCE($1,amount), 
 			status = COALESCE($2,status), 
 			user_id = COALESCE($3,user_id),
-			updated_at = $4
+			disbursed_at = COALESCE($4,disbursed_at),
+			updated_at = $5
+		WHERE
+			id = $6
+	`
+
+	result, err := tx.ExecContext(ctx, query, loan.Amount, loan.Status, loan.UserId, loan.DisbursedAt, time.Now(), loan.Id)
+	if err != nil {
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	if err == nil && affected == 0 {
+		err = apperror.ErrLoanNotFound
+	}
+
+	return
+}
+
+func (r *repository) GetLoanById(ctx context.Context, loanId int64) (res model.Loan, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetLoanById", "SELECT", "loans")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT
+			id, user_id, amount, status, created_at, disbursed_at
+		FROM
+			loans
 		WHERE
-			id = $5
+			id = $1
 	`
 
-	_, err = tx.ExecContext(ctx, query, loan.Amount, loan.Status, loan.UserId, time.Now(), loan.Id)
+	row := r.Db.QueryRowContext(ctx, query, loanId)
+	err = row.Scan(&res.Id, &res.UserId, &res.Amount, &res.Status, &res.CreatedAt, &res.DisbursedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = apperror.ErrLoanNotFound.WithCause(err)
+	}
 
 	return
 }
 
 func (r *repository) GetLoanByIdAndUserId(ctx context.Context, loanId, userId int64) (res model.Loan, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetLoanByIdAndUserId", "SELECT", "loans")
+	defer tracing.End(span, &err)
+
 	query := `
 		SELECT
-			id, user_id, amount, status, created_at
+			id, user_id, amount, status, created_at, disbursed_at
 		FROM
 			loans
 		WHERE
@@ -59,15 +102,21 @@ func (r *repository) GetLoanByIdAndUserId(ctx context.Context, loanId, userId in
 	if err != nil {
 		return
 	}
-	err = row.Scan(&res.Id, &res.UserId, &res.Amount, &res.Status, &res.CreatedAt)
+	err = row.Scan(&res.Id, &res.UserId, &res.Amount, &res.Status, &res.CreatedAt, &res.DisbursedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = apperror.ErrLoanNotFound.WithCause(err)
+	}
 
 	return
 }
 
 func (r *repository) GetLoanByUserId(ctx context.Context, userId int64) (res []model.Loan, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetLoanByUserId", "SELECT", "loans")
+	defer tracing.End(span, &err)
+
 	query := `
 		SELECT
-			id, user_id, amount, status, created_at
+			id, user_id, amount, status, created_at, disbursed_at
 		FROM
 			loans
 		WHERE
@@ -82,7 +131,7 @@ func (r *repository) GetLoanByUserId(ctx context.Context, userId int64) (res []m
 
 	for rows.Next() {
 		temp := model.Loan{}
-		err = rows.Scan(&temp.Id, &temp.UserId, &temp.Amount, &temp.Status, &temp.CreatedAt)
+		err = rows.Scan(&temp.Id, &temp.UserId, &temp.Amount, &temp.Status, &temp.CreatedAt, &temp.DisbursedAt)
 		if err != nil {
 			return
 		}
@@ -91,3 +140,40 @@ func (r *repository) GetLoanByUserId(ctx context.Context, userId int64) (res []m
 
 	return
 }
+
+// ExpirePendingLoans moves the loans still PENDING that were created before createdBefore to EXPIRED.
+func (r *repository) ExpirePendingLoans(ctx context.Context, createdBefore, at time.Time) (ids []int64, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.ExpirePendingLoans", "UPDATE", "loans")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE
+			loans
+		SET
+			status = 'EXPIRED',
+			updated_at = $2
+		WHERE
+			status = 'PENDING'
+			AND created_at < $1
+		RETURNING
+			id
+	`
+
+	rows, err := r.Db.QueryContext(ctx, query, createdBefore, at)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		err = rows.Scan(&id)
+		if err != nil {
+			return
+		}
+		ids = append(ids, id)
+	}
+	err = rows.Err()
+
+	return
+}
@@ -0,0 +1,15 @@
+package impl
+
+import (
+	"context"
+
+	"example.com/m/v2/model"
+	"example.com/m/v2/tracing"
+)
+
+func (r *repository) SendMail(ctx context.Context, mail model.Mail) (err error) {
+	ctx, span := tracing.Start(ctx, "repository.SendMail")
+	defer tracing.End(span, &err)
+
+	return r.mailer.Send(ctx, mail)
+}
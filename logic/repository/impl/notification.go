@@ -0,0 +1,164 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/model"
+	"example.com/m/v2/tracing"
+	"github.com/lib/pq"
+)
+
+// InsertNotifications queues the notification in tx on every channel of channels the user has enabled, in the
+// user's locale. Email is enabled unless turned off and goes to the account email, the other channels need an
+// address. queued is the number of notifications written.
+func (r *repository) InsertNotifications(ctx context.Context, tx *sql.Tx, notification model.Notification, channels []string) (queued int64, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.InsertNotifications", "INSERT", "notifications")
+	defer tracing.End(span, &err)
+
+	query := `
+		INSERT INTO
+			notifications(
+				user_id, kind, channel, locale, recipient, data, next_attempt_at, created_at
+			)
+		SELECT
+			u.id, $2, c.channel, u.locale, CASE WHEN c.channel = $5 THEN u.email ELSE p.address END, $3, $4, $4
+		FROM
+			users u
+			CROSS JOIN unnest($6::TEXT[]) AS c(channel)
+			LEFT JOIN notification_preferences p ON p.user_id = u.id AND p.channel = c.channel
+		WHERE
+			u.id = $1 AND (
+				(c.channel = $5 AND COALESCE(p.enabled, TRUE)) OR
+				(c.channel <> $5 AND COALESCE(p.enabled, FALSE) AND p.address <> '')
+			)
+	`
+	res, err := tx.ExecContext(ctx, query, notification.UserId, notification.Kind, []byte(notification.Data),
+		notification.CreatedAt, constant.NotificationChannelEmail, pq.Array(channels))
+	if err != nil {
+		return
+	}
+
+	return res.RowsAffected()
+}
+
+// GetNotifications returns the latest notifications of a user, newest first.
+func (r *repository) GetNotifications(ctx context.Context, userId int64, limit int) (res []model.Notification, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetNotifications", "SELECT", "notifications")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT
+			id, user_id, kind, channel, locale, recipient, data, status, attempts, next_attempt_at,
+			COALESCE(subject,''), COALESCE(last_error,''), created_at, sent_at
+		FROM
+			notifications
+		WHERE
+			user_id = $1
+		ORDER BY
+			id DESC
+		LIMIT $2
+	`
+	rows, err := r.Db.QueryContext(ctx, query, userId, limit)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		temp := model.Notification{}
+		var data []byte
+		err = rows.Scan(&temp.Id, &temp.UserId, &temp.Kind, &temp.Channel, &temp.Locale, &temp.Recipient, &data,
+			&temp.Status, &temp.Attempts, &temp.NextAttemptAt, &temp.Subject, &temp.LastError, &temp.CreatedAt, &temp.SentAt)
+		if err != nil {
+			return
+		}
+		temp.Data = data
+		res = append(res, temp)
+	}
+	err = rows.Err()
+
+	return
+}
+
+// GetNotificationPreferences returns the user's locale and the channels they set, channels never set are left out.
+func (r *repository) GetNotificationPreferences(ctx context.Context, userId int64) (res model.NotificationPreferences, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetNotificationPreferences", "SELECT", "notification_preferences")
+	defer tracing.End(span, &err)
+
+	err = r.Db.QueryRowContext(ctx, `SELECT locale FROM users WHERE id = $1`, userId).Scan(&res.Locale)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = apperror.ErrUserNotFound.WithCause(err)
+	}
+	if err != nil {
+		return
+	}
+
+	query := `
+		SELECT
+			channel, enabled, address
+		FROM
+			notification_preferences
+		WHERE
+			user_id = $1
+		ORDER BY
+			channel
+	`
+	rows, err := r.Db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		temp := model.ChannelPreference{}
+		err = rows.Scan(&temp.Channel, &temp.Enabled, &temp.Address)
+		if err != nil {
+			return
+		}
+		res.Channels = append(res.Channels, temp)
+	}
+	err = rows.Err()
+
+	return
+}
+
+func (r *repository) UpdateUserLocale(ctx context.Context, tx *sql.Tx, userId int64, locale string, at time.Time) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.UpdateUserLocale", "UPDATE", "users")
+	defer tracing.End(span, &err)
+
+	res, err := tx.ExecContext(ctx, `UPDATE users SET locale = $2, updated_at = $3 WHERE id = $1`, userId, locale, at)
+	if err != nil {
+		return
+	}
+
+	affected, err := res.RowsAffected()
+	if err == nil && affected == 0 {
+		err = apperror.ErrUserNotFound
+	}
+
+	return
+}
+
+func (r *repository) UpsertChannelPreference(ctx context.Context, tx *sql.Tx, userId int64, pref model.ChannelPreference, at time.Time) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.UpsertChannelPreference", "INSERT", "notification_preferences")
+	defer tracing.End(span, &err)
+
+	query := `
+		INSERT INTO
+			notification_preferences(
+				user_id, channel, enabled, address, updated_at
+			)
+		VALUES
+			($1,$2,$3,$4,$5)
+		ON CONFLICT (user_id, channel) DO UPDATE SET
+			enabled = EXCLUDED.enabled, address = EXCLUDED.address, updated_at = EXCLUDED.updated_at
+	`
+	_, err = tx.ExecContext(ctx, query, userId, pref.Channel, pref.Enabled, pref.Address, at)
+
+	return
+}
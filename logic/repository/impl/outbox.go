@@ -0,0 +1,49 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+
+	"example.com/m/v2/model"
+	"example.com/m/v2/tracing"
+)
+
+// InsertOutboxEvent queues a domain event in tx, along with a delivery to every active webhook endpoint subscribed
+// to its type. Both are only published once tx commits.
+func (r *repository) InsertOutboxEvent(ctx context.Context, tx *sql.Tx, event model.OutboxEvent) (id int64, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.InsertOutboxEvent", "INSERT", "outbox")
+	defer tracing.End(span, &err)
+
+	query := `
+		WITH ev AS (
+			INSERT INTO
+				outbox(
+					aggregate_type, aggregate_id, event_type, payload, next_attempt_at, created_at
+				)
+			VALUES
+				($1,$2,$3,$4,$5,$5)
+			RETURNING
+				id, event_type, created_at
+		), deliveries AS (
+			INSERT INTO
+				webhook_deliveries(
+					endpoint_id, event_id, next_attempt_at, created_at
+				)
+			SELECT
+				e.id, ev.id, ev.created_at, ev.created_at
+			FROM
+				webhook_endpoints e, ev
+			WHERE
+				e.active AND (cardinality(e.event_types) = 0 OR ev.event_type = ANY(e.event_types))
+		)
+		SELECT
+			id
+		FROM
+			ev
+	`
+	row := tx.QueryRowContext(ctx, query, event.AggregateType, event.AggregateId, event.Type, []byte(event.Payload), event.CreatedAt)
+
+	err = row.Scan(&id)
+
+	return
+}
@@ -0,0 +1,148 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"example.com/m/v2/model"
+	"example.com/m/v2/tracing"
+)
+
+// GetOverdueRepayments lists the unpaid terms of disbursed and defaulted loans that fell due before dueBefore, with
+// the late fees already charged on them.
+func (r *repository) GetOverdueRepayments(ctx context.Context, dueBefore time.Time) (res []model.Repayment, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetOverdueRepayments", "SELECT", "repayments")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT
+			r.id, r.loan_id, r.minimum_payment, r.actual_payment, r.status, r.due_date,
+			(SELECT COALESCE(SUM(amount),0) FROM repayment_fees WHERE repayment_id = r.id) AS late_fees
+		FROM
+			repayments r
+			JOIN loans l ON l.id = r.loan_id
+		WHERE
+			r.status IN ('PENDING','OVERDUE')
+			AND r.due_date < $1
+			AND l.status IN ('DISBURSED','DEFAULTED')
+		ORDER BY
+			r.id ASC
+	`
+
+	rows, err := r.Db.QueryContext(ctx, query, dueBefore)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		temp := model.Repayment{}
+		err = rows.Scan(&temp.Id, &temp.LoanId, &temp.MinimumPayment, &temp.ActualPayment, &temp.Status, &temp.DueDate, &temp.LateFees)
+		if err != nil {
+			return
+		}
+		res = append(res, temp)
+	}
+	err = rows.Err()
+
+	return
+}
+
+// MarkRepaymentOverdue sets an unpaid term OVERDUE and locks it until tx ends, marked is false when the term was
+// paid meanwhile.
+func (r *repository) MarkRepaymentOverdue(ctx context.Context, tx *sql.Tx, id int64) (marked bool, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.MarkRepaymentOverdue", "UPDATE", "repayments")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE
+			repayments
+		SET
+			status = 'OVERDUE',
+			updated_at = $1
+		WHERE
+			id = $2
+			AND status IN ('PENDING','OVERDUE')
+	`
+	result, err := tx.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	marked = affected > 0
+
+	return
+}
+
+// GetRepaymentLateFees sums the late fees charged to a term, read in tx after the term was locked it sees the fees
+// charged by the transactions that held the lock before.
+func (r *repository) GetRepaymentLateFees(ctx context.Context, tx *sql.Tx, id int64) (fees float64, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetRepaymentLateFees", "SELECT", "repayment_fees")
+	defer tracing.End(span, &err)
+
+	err = tx.QueryRowContext(ctx, `SELECT COALESCE(SUM(amount),0) FROM repayment_fees WHERE repayment_id = $1`, id).Scan(&fees)
+
+	return
+}
+
+func (r *repository) InsertRepaymentFee(ctx context.Context, tx *sql.Tx, fee model.RepaymentFee) (id int64, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.InsertRepaymentFee", "INSERT", "repayment_fees")
+	defer tracing.End(span, &err)
+
+	query := `
+		INSERT INTO
+			repayment_fees(
+				repayment_id, kind, amount, assessed_at
+			)
+		VALUES
+			($1,$2,$3,$4)
+		RETURNING
+			id
+	`
+	row := tx.QueryRowContext(ctx, query, fee.RepaymentId, fee.Kind, fee.Amount, fee.AssessedAt)
+
+	err = row.Scan(&id)
+
+	return
+}
+
+// DefaultLoans moves the disbursed loans with a term unpaid since before dueBefore to DEFAULTED in tx.
+func (r *repository) DefaultLoans(ctx context.Context, tx *sql.Tx, dueBefore, at time.Time) (ids []int64, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.DefaultLoans", "UPDATE", "loans")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE
+			loans
+		SET
+			status = 'DEFAULTED',
+			updated_at = $2
+		WHERE
+			status = 'DISBURSED'
+			AND id IN (
+				SELECT loan_id FROM repayments WHERE status IN ('PENDING','OVERDUE') AND due_date < $1
+			)
+		RETURNING
+			id
+	`
+
+	rows, err := tx.QueryContext(ctx, query, dueBefore, at)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		err = rows.Scan(&id)
+		if err != nil {
+			return
+		}
+		ids = append(ids, id)
+	}
+	err = rows.Err()
+
+	return
+}
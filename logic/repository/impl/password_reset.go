@@ -0,0 +1,86 @@
+package impl
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/model"
+	"example.com/m/v2/tracing"
+)
+
+// RandomToken returns 32 bytes from crypto/rand, base64url encoded.
+func (r *repository) RandomToken() (token string, err error) {
+	b := make([]byte, 32)
+	_, err = rand.Read(b)
+	if err != nil {
+		return
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (r *repository) InsertPasswordResetToken(ctx context.Context, token model.PasswordResetToken) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.InsertPasswordResetToken", "INSERT", "password_reset_tokens")
+	defer tracing.End(span, &err)
+
+	query := `
+		INSERT INTO
+			password_reset_tokens(
+				user_id, token_hash, expires_at, created_at
+			)
+		VALUES
+			($1,$2,$3,$4)
+	`
+	_, err = r.Db.ExecContext(ctx, query, token.UserId, token.TokenHash, token.ExpiresAt, time.Now())
+
+	return
+}
+
+// ConsumePasswordResetToken marks an unused, unexpired token as used and returns its user,
+// concurrent resets with the same token can't both succeed.
+func (r *repository) ConsumePasswordResetToken(ctx context.Context, tx *sql.Tx, tokenHash string, now time.Time) (userId int64, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.ConsumePasswordResetToken", "UPDATE", "password_reset_tokens")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE
+			password_reset_tokens
+		SET
+			used_at = $2
+		WHERE
+			token_hash = $1 AND used_at IS NULL AND expires_at > $2
+		RETURNING
+			user_id
+	`
+	row := tx.QueryRowContext(ctx, query, tokenHash, now)
+
+	err = row.Scan(&userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = apperror.ErrInvalidResetToken.WithCause(err)
+	}
+
+	return
+}
+
+// RevokePasswordResetTokens marks every outstanding token of the user as used.
+func (r *repository) RevokePasswordResetTokens(ctx context.Context, tx *sql.Tx, userId int64, now time.Time) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.RevokePasswordResetTokens", "UPDATE", "password_reset_tokens")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE
+			password_reset_tokens
+		SET
+			used_at = $2
+		WHERE
+			user_id = $1 AND used_at IS NULL
+	`
+	_, err = tx.ExecContext(ctx, query, userId, now)
+
+	return
+}
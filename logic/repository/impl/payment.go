@@ -0,0 +1,169 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/model"
+	"example.com/m/v2/tracing"
+)
+
+func (r *repository) PaymentGateway() string {
+	return r.gateway.Name()
+}
+
+func (r *repository) CreateGatewayIntent(ctx context.Context, req model.PaymentIntentRequest) (res model.PaymentIntentResult, err error) {
+	ctx, span := tracing.Start(ctx, "repository.CreateGatewayIntent")
+	defer tracing.End(span, &err)
+
+	return r.gateway.CreateIntent(ctx, req)
+}
+
+// InsertPaymentIntent fails with ErrPaymentIntentPending while the term has another pending intent.
+func (r *repository) InsertPaymentIntent(ctx context.Context, intent model.PaymentIntent) (id int64, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.InsertPaymentIntent", "INSERT", "payment_intents")
+	defer tracing.End(span, &err)
+
+	query := `
+		INSERT INTO
+			payment_intents(
+				loan_id, user_id, term, amount, gateway, gateway_reference, virtual_account, status, expires_at,
+				created_at, updated_at
+			)
+		VALUES
+			($1,$2,$3,$4,$5,$6,NULLIF($7,''),$8,$9,$10,$10)
+		RETURNING
+			id
+	`
+	row := r.Db.QueryRowContext(ctx, query, intent.LoanId, intent.UserId, intent.Term, intent.Amount, intent.Gateway,
+		intent.GatewayReference, intent.VirtualAccount, intent.Status, intent.ExpiresAt, intent.CreatedAt)
+
+	err = row.Scan(&id)
+	if isUniqueViolation(err) {
+		err = apperror.ErrPaymentIntentPending.WithCause(err)
+	}
+
+	return
+}
+
+// HasPendingPaymentIntent reports whether the term has a pending intent that expires after now. The pending intents
+// that expired are failed first, they don't hold the term anymore.
+func (r *repository) HasPendingPaymentIntent(ctx context.Context, loanId, term int64, now time.Time) (pending bool, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.HasPendingPaymentIntent", "UPDATE", "payment_intents")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE
+			payment_intents
+		SET
+			status = $1,
+			failure_reason = 'payment intent expired',
+			updated_at = $4
+		WHERE
+			loan_id = $2 AND
+			term = $3 AND
+			status = $5 AND
+			expires_at <= $4
+	`
+	_, err = r.Db.ExecContext(ctx, query, constant.PaymentIntentStatusFailed, loanId, term, now, constant.PaymentIntentStatusPending)
+	if err != nil {
+		return
+	}
+
+	query = `
+		SELECT EXISTS(
+			SELECT 1 FROM payment_intents WHERE loan_id = $1 AND term = $2 AND status = $3
+		)
+	`
+	err = r.Db.QueryRowContext(ctx, query, loanId, term, constant.PaymentIntentStatusPending).Scan(&pending)
+
+	return
+}
+
+// GetPaymentIntentForUpdate locks the intent until tx ends, concurrent callbacks for the same intent are serialized.
+func (r *repository) GetPaymentIntentForUpdate(ctx context.Context, tx *sql.Tx, reference string) (res model.PaymentIntent, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetPaymentIntentForUpdate", "SELECT", "payment_intents")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT
+			id, loan_id, user_id, term, amount, gateway, gateway_reference, COALESCE(virtual_account,''), status,
+			COALESCE(failure_reason,''), received_amount, expires_at, created_at, settled_at
+		FROM
+			payment_intents
+		WHERE
+			gateway_reference = $1
+		FOR UPDATE
+	`
+
+	row := tx.QueryRowContext(ctx, query, reference)
+	err = row.Scan(&res.Id, &res.LoanId, &res.UserId, &res.Term, &res.Amount, &res.Gateway, &res.GatewayReference,
+		&res.VirtualAccount, &res.Status, &res.FailureReason, &res.ReceivedAmount, &res.ExpiresAt, &res.CreatedAt, &res.SettledAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = apperror.ErrPaymentIntentNotFound.WithCause(err)
+	}
+
+	return
+}
+
+func (r *repository) UpdatePaymentIntent(ctx context.Context, tx *sql.Tx, intent model.PaymentIntent) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.UpdatePaymentIntent", "UPDATE", "payment_intents")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE
+			payment_intents
+		SET
+			status = $1,
+			failure_reason = NULLIF($2,''),
+			received_amount = $3,
+			settled_at = $4,
+			updated_at = $5
+		WHERE
+			id = $6
+	`
+	_, err = tx.ExecContext(ctx, query, intent.Status, intent.FailureReason, intent.ReceivedAmount, intent.SettledAt, time.Now(), intent.Id)
+
+	return
+}
+
+// UseWebhookNonce accepts a webhook nonce once, it fails with ErrWebhookReplayed when the nonce was seen before.
+// Nonces received before forgetBefore can't be replayed anymore, their timestamps are out of tolerance, and are
+// deleted.
+func (r *repository) UseWebhookNonce(ctx context.Context, tx *sql.Tx, nonce string, at, forgetBefore time.Time) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.UseWebhookNonce", "INSERT", "webhook_nonces")
+	defer tracing.End(span, &err)
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM webhook_nonces WHERE received_at < $1`, forgetBefore)
+	if err != nil {
+		return
+	}
+
+	query := `
+		INSERT INTO
+			webhook_nonces(
+				nonce, received_at
+			)
+		VALUES
+			($1,$2)
+		ON CONFLICT (nonce) DO NOTHING
+	`
+	res, err := tx.ExecContext(ctx, query, nonce, at)
+	if err != nil {
+		return
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if affected == 0 {
+		err = apperror.ErrWebhookReplayed
+	}
+
+	return
+}
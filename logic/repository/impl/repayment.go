@@ -6,9 +6,13 @@ import (
 	"time"
 
 	"example.com/m/v2/model"
+	"example.com/m/v2/tracing"
 )
 
 func (r *repository) InsertRepayment(ctx context.Context, tx *sql.Tx, repayment model.Repayment) (id int64, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.InsertRepayment", "INSERT", "repayments")
+	defer tracing.End(span, &err)
+
 	query := `
 		INSERT INTO
 			repayments(
@@ -27,16 +31,19 @@ func (r *repository) InsertRepayment(ctx context.Context, tx *sql.Tx, repayment
 }
 
 func (r *repository) GetRepaymentByLoanId(ctx context.Context, loanId int64) (res []model.Repayment, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetRepaymentByLoanId", "SELECT", "repayments")
+	defer tracing.End(span, &err)
+
 	query := `
 		SELECT
-			id, loan_id, minimum_payment, actual_payment, status, due_date
+			id, loan_id, minimum_payment, actual_payment, status, due_date,
+			(SELECT COALESCE(SUM(amount),0) FROM repayment_fees WHERE repayment_id = repayments.id) AS late_fees
 		FROM
 			repayments
 		WHERE
 			loan_id = $1 
 		ORDER BY
-			due_date ASC
-			
+			id ASC
 	`
 
 	rows, err := r.Db.QueryContext(ctx, query, loanId)
@@ -47,7 +54,43 @@ func (r *repository) GetRepaymentByLoanId(ctx context.Context, loanId int64) (re
 
 	for rows.Next() {
 		temp := model.Repayment{}
-		err = rows.Scan(&temp.Id, &temp.LoanId, &temp.MinimumPayment, &temp.ActualPayment, &temp.Status, &temp.DueDate)
+		err = rows.Scan(&temp.Id, &temp.LoanId, &temp.MinimumPayment, &temp.ActualPayment, &temp.Status, &temp.DueDate, &temp.LateFees)
+		if err != nil {
+			return
+		}
+		res = append(res, temp)
+	}
+
+	return
+}
+
+// GetRepaymentsForUpdate locks the repayments of the loan until tx ends.
+func (r *repository) GetRepaymentsForUpdate(ctx context.Context, tx *sql.Tx, loanId int64) (res []model.Repayment, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetRepaymentsForUpdate", "SELECT", "repayments")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT
+			id, loan_id, minimum_payment, actual_payment, status, due_date,
+			(SELECT COALESCE(SUM(amount),0) FROM repayment_fees WHERE repayment_id = repayments.id) AS late_fees
+		FROM
+			repayments
+		WHERE
+			loan_id = $1
+		ORDER BY
+			id ASC
+		FOR UPDATE
+	`
+
+	rows, err := tx.QueryContext(ctx, query, loanId)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		temp := model.Repayment{}
+		err = rows.Scan(&temp.Id, &temp.LoanId, &temp.MinimumPayment, &temp.ActualPayment, &temp.Status, &temp.DueDate, &temp.LateFees)
 		if err != nil {
 			return
 		}
@@ -58,6 +101,8 @@ func (r *repository) GetRepaymentByLoanId(ctx context.Context, loanId int64) (re
 }
 
 func (r *repository) UpdateRepayment(ctx context.Context, tx *sql.Tx, repayment model.Repayment) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.UpdateRepayment", "UPDATE", "repayments")
+	defer tracing.End(span, &err)
 
 	query := `
 		UPDATE
@@ -73,3 +118,63 @@ func (r *repository) UpdateRepayment(ctx context.Context, tx *sql.Tx, repayment
 
 	return
 }
+
+// GetRepaymentReminders lists the PENDING terms of disbursed loans due in (dueAfter, dueBefore] whose borrower was not
+// reminded yet, Term is the position of the term in its loan.
+func (r *repository) GetRepaymentReminders(ctx context.Context, dueAfter, dueBefore time.Time) (res []model.RepaymentReminder, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetRepaymentReminders", "SELECT", "repayments")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT
+			t.id, t.loan_id, t.term, l.user_id, t.minimum_payment, t.due_date
+		FROM
+			(
+				SELECT
+					id, loan_id, minimum_payment, status, due_date, reminded_at,
+					ROW_NUMBER() OVER (PARTITION BY loan_id ORDER BY id) AS term
+				FROM
+					repayments
+				WHERE
+					loan_id IN (
+						SELECT loan_id FROM repayments WHERE status = 'PENDING' AND due_date > $1 AND due_date <= $2
+					)
+			) t
+			JOIN loans l ON l.id = t.loan_id
+		WHERE
+			t.status = 'PENDING'
+			AND t.reminded_at IS NULL
+			AND t.due_date > $1
+			AND t.due_date <= $2
+			AND l.status = 'DISBURSED'
+		ORDER BY
+			t.due_date ASC, t.id ASC
+	`
+
+	rows, err := r.Db.QueryContext(ctx, query, dueAfter, dueBefore)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		temp := model.RepaymentReminder{}
+		err = rows.Scan(&temp.RepaymentId, &temp.LoanId, &temp.Term, &temp.UserId, &temp.MinimumPayment, &temp.DueDate)
+		if err != nil {
+			return
+		}
+		res = append(res, temp)
+	}
+	err = rows.Err()
+
+	return
+}
+
+func (r *repository) MarkRepaymentReminded(ctx context.Context, tx *sql.Tx, id int64, at time.Time) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.MarkRepaymentReminded", "UPDATE", "repayments")
+	defer tracing.End(span, &err)
+
+	_, err = tx.ExecContext(ctx, `UPDATE repayments SET reminded_at = $1 WHERE id = $2`, at, id)
+
+	return
+}
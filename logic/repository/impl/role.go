@@ -0,0 +1,104 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"example.com/m/v2/model"
+	"example.com/m/v2/tracing"
+	"github.com/lib/pq"
+)
+
+const selectRoles = `
+	SELECT
+		r.name, r.description, r.approval_limit,
+		COALESCE(array_agg(p.permission ORDER BY p.permission) FILTER (WHERE p.permission IS NOT NULL), '{}')
+	FROM
+		roles r
+		LEFT JOIN role_permissions p ON p.role = r.name
+`
+
+// ListRoles returns every role with its permissions, ordered by name.
+func (r *repository) ListRoles(ctx context.Context) (res []model.Role, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.ListRoles", "SELECT", "roles")
+	defer tracing.End(span, &err)
+
+	rows, err := r.Db.QueryContext(ctx, selectRoles+`
+		GROUP BY
+			r.name
+		ORDER BY
+			r.name
+	`)
+	if err != nil {
+		return
+	}
+
+	return scanRoles(rows)
+}
+
+// GetUserRoles returns the roles granted to a user, a user without staff roles has none.
+func (r *repository) GetUserRoles(ctx context.Context, userId int64) (res []model.Role, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetUserRoles", "SELECT", "user_roles")
+	defer tracing.End(span, &err)
+
+	rows, err := r.Db.QueryContext(ctx, selectRoles+`
+		WHERE
+			r.name IN (SELECT role FROM user_roles WHERE user_id = $1)
+		GROUP BY
+			r.name
+		ORDER BY
+			r.name
+	`, userId)
+	if err != nil {
+		return
+	}
+
+	return scanRoles(rows)
+}
+
+func scanRoles(rows *sql.Rows) (res []model.Role, err error) {
+	defer rows.Close()
+
+	for rows.Next() {
+		temp := model.Role{}
+		err = rows.Scan(&temp.Name, &temp.Description, &temp.ApprovalLimit, pq.Array(&temp.Permissions))
+		if err != nil {
+			return
+		}
+		res = append(res, temp)
+	}
+	err = rows.Err()
+
+	return
+}
+
+// ReplaceUserRoles grants exactly roles to the user, roles kept from before keep their original assignment.
+// assignedBy 0 records no assigner, for accounts created by an operator.
+func (r *repository) ReplaceUserRoles(ctx context.Context, tx *sql.Tx, userId int64, roles []string, assignedBy int64, at time.Time) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.ReplaceUserRoles", "INSERT", "user_roles")
+	defer tracing.End(span, &err)
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM user_roles WHERE user_id = $1 AND NOT (role = ANY($2))`, userId, pq.Array(roles))
+	if err != nil {
+		return
+	}
+
+	query := `
+		INSERT INTO
+			user_roles(
+				user_id, role, assigned_by, assigned_at
+			)
+		VALUES
+			($1,$2,NULLIF($3,0),$4)
+		ON CONFLICT (user_id, role) DO NOTHING
+	`
+	for _, role := range roles {
+		_, err = tx.ExecContext(ctx, query, userId, role, assignedBy, at)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
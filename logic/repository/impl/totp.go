@@ -0,0 +1,171 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/totp"
+	"example.com/m/v2/tracing"
+)
+
+func (r *repository) TotpGenerateSecret() (string, error) {
+	return totp.GenerateSecret()
+}
+
+func (r *repository) TotpValidate(secret, code string, at time.Time) (step int64, ok bool) {
+	return totp.Validate(secret, code, at)
+}
+
+func (r *repository) TotpRecoveryCodes() ([]string, error) {
+	return totp.RecoveryCodes(constant.TotpRecoveryCodes)
+}
+
+// SetUserTotpSecret starts or restarts an enrollment, it fails once TOTP is enabled.
+func (r *repository) SetUserTotpSecret(ctx context.Context, id int64, secret string) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.SetUserTotpSecret", "UPDATE", "users")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE
+			users
+		SET
+			totp_secret = $2,
+			updated_at = $3
+		WHERE
+			id = $1 AND totp_enabled_at IS NULL
+	`
+	res, err := r.Db.ExecContext(ctx, query, id, secret, time.Now())
+	if err != nil {
+		return
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if affected == 0 {
+		err = apperror.ErrTotpAlreadyEnabled
+	}
+
+	return
+}
+
+// EnableUserTotp activates the enrolled secret and bumps the session version, sessions opened with the password
+// alone are logged out.
+func (r *repository) EnableUserTotp(ctx context.Context, tx *sql.Tx, id, step int64, at time.Time) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.EnableUserTotp", "UPDATE", "users")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE
+			users
+		SET
+			totp_enabled_at = $3,
+			totp_last_step = $2,
+			session_version = session_version + 1,
+			updated_at = $3
+		WHERE
+			id = $1 AND totp_enabled_at IS NULL AND totp_secret IS NOT NULL
+	`
+	res, err := tx.ExecContext(ctx, query, id, step, at)
+	if err != nil {
+		return
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if affected == 0 {
+		err = apperror.ErrTotpAlreadyEnabled
+	}
+
+	return
+}
+
+// UseTotpStep records step as the last accepted one, concurrent logins with the same code can't both succeed.
+func (r *repository) UseTotpStep(ctx context.Context, id, step int64) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.UseTotpStep", "UPDATE", "users")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE
+			users
+		SET
+			totp_last_step = $2
+		WHERE
+			id = $1 AND totp_last_step < $2
+	`
+	res, err := r.Db.ExecContext(ctx, query, id, step)
+	if err != nil {
+		return
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if affected == 0 {
+		err = apperror.ErrInvalidTotpCode
+	}
+
+	return
+}
+
+// ReplaceRecoveryCodes drops the previous codes of the user and stores the new hashes.
+func (r *repository) ReplaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userId int64, codeHashes []string, at time.Time) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.ReplaceRecoveryCodes", "INSERT", "recovery_codes")
+	defer tracing.End(span, &err)
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userId)
+	if err != nil {
+		return
+	}
+
+	query := `
+		INSERT INTO
+			recovery_codes(
+				user_id, code_hash, created_at
+			)
+		VALUES
+			($1,$2,$3)
+	`
+	for _, hash := range codeHashes {
+		_, err = tx.ExecContext(ctx, query, userId, hash, at)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// ConsumeRecoveryCode marks an unused code of the user as used.
+func (r *repository) ConsumeRecoveryCode(ctx context.Context, userId int64, codeHash string, at time.Time) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.ConsumeRecoveryCode", "UPDATE", "recovery_codes")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE
+			recovery_codes
+		SET
+			used_at = $3
+		WHERE
+			id = (
+				SELECT id FROM recovery_codes WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL LIMIT 1 FOR UPDATE
+			)
+		RETURNING
+			id
+	`
+	var id int64
+	err = r.Db.QueryRowContext(ctx, query, userId, codeHash, at).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = apperror.ErrInvalidTotpCode.WithCause(err)
+	}
+
+	return
+}
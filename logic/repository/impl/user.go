@@ -3,15 +3,22 @@ package impl
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
+	"example.com/m/v2/apperror"
 	"example.com/m/v2/model"
+	"example.com/m/v2/tracing"
 )
 
 func (r *repository) GetUserByEmail(ctx context.Context, email string) (res model.User, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetUserByEmail", "SELECT", "users")
+	defer tracing.End(span, &err)
+
 	query := `
 		SELECT
-			id, email, password, role
+			id, email, password, role, email_verified_at, session_version,
+			COALESCE(totp_secret, ''), totp_enabled_at, totp_last_step, password_change_required
 		FROM
 			users
 		WHERE
@@ -19,25 +26,122 @@ func (r *repository) GetUserByEmail(ctx context.Context, email string) (res mode
 	`
 	row := r.Db.QueryRowContext(ctx, query, email)
 
-	err = row.Scan(&res.Id, &res.Email, &res.Password, &res.Role)
+	err = row.Scan(&res.Id, &res.Email, &res.Password, &res.Role, &res.EmailVerifiedAt, &res.SessionVersion,
+		&res.TotpSecret, &res.TotpEnabledAt, &res.TotpLastStep, &res.PasswordChangeRequired)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = apperror.ErrUserNotFound.WithCause(err)
+	}
+
+	return
+}
+
+func (r *repository) GetUserById(ctx context.Context, id int64) (res model.User, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetUserById", "SELECT", "users")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT
+			id, email, password, role, email_verified_at, session_version,
+			COALESCE(totp_secret, ''), totp_enabled_at, totp_last_step, password_change_required
+		FROM
+			users
+		WHERE
+			id = $1
+	`
+	row := r.Db.QueryRowContext(ctx, query, id)
+
+	err = row.Scan(&res.Id, &res.Email, &res.Password, &res.Role, &res.EmailVerifiedAt, &res.SessionVersion,
+		&res.TotpSecret, &res.TotpEnabledAt, &res.TotpLastStep, &res.PasswordChangeRequired)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = apperror.ErrUserNotFound.WithCause(err)
+	}
 
 	return
 }
 
 func (r *repository) InsertUser(ctx context.Context, tx *sql.Tx, user model.User) (id int64, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.InsertUser", "INSERT", "users")
+	defer tracing.End(span, &err)
+
 	query := `
 		INSERT INTO
 			users(
-				email, password,role, created_at,updated_at
+				email, password,role, email_verified_at, password_change_required, created_at,updated_at
 			)
 		VALUES
-			($1,$2,$3,$4,$4)
+			($1,$2,$3,$4,$5,$6,$6)
 		RETURNING
 			id
 	`
-	row := tx.QueryRowContext(ctx, query, user.Email, user.Password, user.Role, time.Now())
+	row := tx.QueryRowContext(ctx, query, user.Email, user.Password, user.Role, user.EmailVerifiedAt, user.PasswordChangeRequired, time.Now())
 
 	err = row.Scan(&id)
+	if isUniqueViolation(err) {
+		err = apperror.ErrEmailRegistered.WithCause(err)
+	}
+
+	return
+}
+
+// VerifyUserEmail marks the email verified, it only matches while the account still has that unverified email.
+func (r *repository) VerifyUserEmail(ctx context.Context, id int64, email string, verifiedAt time.Time) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.VerifyUserEmail", "UPDATE", "users")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE
+			users
+		SET
+			email_verified_at = $3,
+			updated_at = $3
+		WHERE
+			id = $1 AND email = $2 AND email_verified_at IS NULL
+	`
+	res, err := r.Db.ExecContext(ctx, query, id, email, verifiedAt)
+	if err != nil {
+		return
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if affected == 0 {
+		err = apperror.ErrInvalidVerificationToken
+	}
+
+	return
+}
+
+// UpdateUserPassword stores the new hash and bumps the session version, logging the user out of every session. A
+// required password change is then done.
+func (r *repository) UpdateUserPassword(ctx context.Context, tx *sql.Tx, id int64, hash string) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.UpdateUserPassword", "UPDATE", "users")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE
+			users
+		SET
+			password = $2,
+			session_version = session_version + 1,
+			password_change_required = FALSE,
+			updated_at = $3
+		WHERE
+			id = $1
+	`
+	res, err := tx.ExecContext(ctx, query, id, hash, time.Now())
+	if err != nil {
+		return
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if affected == 0 {
+		err = apperror.ErrUserNotFound
+	}
 
 	return
 }
@@ -0,0 +1,281 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/model"
+	"example.com/m/v2/tracing"
+	"github.com/lib/pq"
+)
+
+const selectWebhookEndpoints = `
+	SELECT
+		id, url, description, secret, event_types, active, COALESCE(created_by,0), created_at, updated_at
+	FROM
+		webhook_endpoints
+`
+
+func scanWebhookEndpoint(row interface{ Scan(...any) error }, res *model.WebhookEndpoint) error {
+	return row.Scan(&res.Id, &res.Url, &res.Description, &res.Secret, pq.Array(&res.EventTypes), &res.Active,
+		&res.CreatedBy, &res.CreatedAt, &res.UpdatedAt)
+}
+
+func (r *repository) InsertWebhookEndpoint(ctx context.Context, endpoint model.WebhookEndpoint) (id int64, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.InsertWebhookEndpoint", "INSERT", "webhook_endpoints")
+	defer tracing.End(span, &err)
+
+	query := `
+		INSERT INTO
+			webhook_endpoints(
+				url, description, secret, event_types, active, created_by, created_at, updated_at
+			)
+		VALUES
+			($1,$2,$3,$4,$5,NULLIF($6,0),$7,$7)
+		RETURNING
+			id
+	`
+	row := r.Db.QueryRowContext(ctx, query, endpoint.Url, endpoint.Description, endpoint.Secret,
+		pq.Array(nonNil(endpoint.EventTypes)), endpoint.Active, endpoint.CreatedBy, endpoint.CreatedAt)
+
+	err = row.Scan(&id)
+
+	return
+}
+
+// GetWebhookEndpoints returns every endpoint, oldest first.
+func (r *repository) GetWebhookEndpoints(ctx context.Context) (res []model.WebhookEndpoint, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetWebhookEndpoints", "SELECT", "webhook_endpoints")
+	defer tracing.End(span, &err)
+
+	rows, err := r.Db.QueryContext(ctx, selectWebhookEndpoints+`
+		ORDER BY
+			id
+	`)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		temp := model.WebhookEndpoint{}
+		err = scanWebhookEndpoint(rows, &temp)
+		if err != nil {
+			return
+		}
+		res = append(res, temp)
+	}
+	err = rows.Err()
+
+	return
+}
+
+func (r *repository) GetWebhookEndpointById(ctx context.Context, id int64) (res model.WebhookEndpoint, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetWebhookEndpointById", "SELECT", "webhook_endpoints")
+	defer tracing.End(span, &err)
+
+	row := r.Db.QueryRowContext(ctx, selectWebhookEndpoints+`
+		WHERE
+			id = $1
+	`, id)
+	err = scanWebhookEndpoint(row, &res)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = apperror.ErrWebhookEndpointNotFound.WithCause(err)
+	}
+
+	return
+}
+
+// UpdateWebhookEndpoint replaces the url, description, event types and active flag of an endpoint.
+func (r *repository) UpdateWebhookEndpoint(ctx context.Context, endpoint model.WebhookEndpoint) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.UpdateWebhookEndpoint", "UPDATE", "webhook_endpoints")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE
+			webhook_endpoints
+		SET
+			url = $2, description = $3, event_types = $4, active = $5, updated_at = $6
+		WHERE
+			id = $1
+	`
+	res, err := r.Db.ExecContext(ctx, query, endpoint.Id, endpoint.Url, endpoint.Description,
+		pq.Array(nonNil(endpoint.EventTypes)), endpoint.Active, endpoint.UpdatedAt)
+	if err != nil {
+		return
+	}
+
+	return webhookEndpointAffected(res)
+}
+
+func (r *repository) UpdateWebhookSecret(ctx context.Context, id int64, secret string, at time.Time) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.UpdateWebhookSecret", "UPDATE", "webhook_endpoints")
+	defer tracing.End(span, &err)
+
+	res, err := r.Db.ExecContext(ctx, `UPDATE webhook_endpoints SET secret = $2, updated_at = $3 WHERE id = $1`, id, secret, at)
+	if err != nil {
+		return
+	}
+
+	return webhookEndpointAffected(res)
+}
+
+// DeleteWebhookEndpoint removes an endpoint with its deliveries and their log.
+func (r *repository) DeleteWebhookEndpoint(ctx context.Context, id int64) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.DeleteWebhookEndpoint", "DELETE", "webhook_endpoints")
+	defer tracing.End(span, &err)
+
+	res, err := r.Db.ExecContext(ctx, `DELETE FROM webhook_endpoints WHERE id = $1`, id)
+	if err != nil {
+		return
+	}
+
+	return webhookEndpointAffected(res)
+}
+
+func webhookEndpointAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return apperror.ErrWebhookEndpointNotFound
+	}
+	return nil
+}
+
+const selectWebhookDeliveries = `
+	SELECT
+		d.id, d.endpoint_id, d.event_id, o.event_type, d.status, d.attempts, d.next_attempt_at, d.response_code,
+		COALESCE(d.last_error,''), d.created_at, d.delivered_at
+	FROM
+		webhook_deliveries d
+		JOIN outbox o ON o.id = d.event_id
+`
+
+func scanWebhookDelivery(row interface{ Scan(...any) error }, res *model.WebhookDelivery) error {
+	return row.Scan(&res.Id, &res.EndpointId, &res.EventId, &res.EventType, &res.Status, &res.Attempts,
+		&res.NextAttemptAt, &res.ResponseCode, &res.LastError, &res.CreatedAt, &res.DeliveredAt)
+}
+
+// GetWebhookDeliveries returns the latest deliveries of an endpoint, newest first, status "" keeps every status.
+func (r *repository) GetWebhookDeliveries(ctx context.Context, endpointId int64, status string, limit int) (res []model.WebhookDelivery, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetWebhookDeliveries", "SELECT", "webhook_deliveries")
+	defer tracing.End(span, &err)
+
+	rows, err := r.Db.QueryContext(ctx, selectWebhookDeliveries+`
+		WHERE
+			d.endpoint_id = $1 AND ($2 = '' OR d.status = $2)
+		ORDER BY
+			d.id DESC
+		LIMIT $3
+	`, endpointId, status, limit)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		temp := model.WebhookDelivery{}
+		err = scanWebhookDelivery(rows, &temp)
+		if err != nil {
+			return
+		}
+		res = append(res, temp)
+	}
+	err = rows.Err()
+
+	return
+}
+
+func (r *repository) GetWebhookDeliveryById(ctx context.Context, id int64) (res model.WebhookDelivery, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetWebhookDeliveryById", "SELECT", "webhook_deliveries")
+	defer tracing.End(span, &err)
+
+	row := r.Db.QueryRowContext(ctx, selectWebhookDeliveries+`
+		WHERE
+			d.id = $1
+	`, id)
+	err = scanWebhookDelivery(row, &res)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = apperror.ErrWebhookDeliveryNotFound.WithCause(err)
+	}
+
+	return
+}
+
+// GetWebhookDeliveryAttempts returns the log of a delivery, oldest attempt first.
+func (r *repository) GetWebhookDeliveryAttempts(ctx context.Context, deliveryId int64) (res []model.WebhookDeliveryAttempt, err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.GetWebhookDeliveryAttempts", "SELECT", "webhook_delivery_attempts")
+	defer tracing.End(span, &err)
+
+	query := `
+		SELECT
+			id, delivery_id, attempted_at, response_code, COALESCE(error,''), duration_ms
+		FROM
+			webhook_delivery_attempts
+		WHERE
+			delivery_id = $1
+		ORDER BY
+			id
+	`
+	rows, err := r.Db.QueryContext(ctx, query, deliveryId)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		temp := model.WebhookDeliveryAttempt{}
+		var durationMs int64
+		err = rows.Scan(&temp.Id, &temp.DeliveryId, &temp.AttemptedAt, &temp.ResponseCode, &temp.Error, &durationMs)
+		if err != nil {
+			return
+		}
+		temp.Duration = time.Duration(durationMs) * time.Millisecond
+		res = append(res, temp)
+	}
+	err = rows.Err()
+
+	return
+}
+
+// RedeliverWebhook sends a delivery again at at whatever its status, with a fresh set of attempts. Its log is kept.
+func (r *repository) RedeliverWebhook(ctx context.Context, id int64, at time.Time) (err error) {
+	ctx, span := tracing.StartDb(ctx, "repository.RedeliverWebhook", "UPDATE", "webhook_deliveries")
+	defer tracing.End(span, &err)
+
+	query := `
+		UPDATE
+			webhook_deliveries
+		SET
+			status = 'PENDING', attempts = 0, next_attempt_at = $2
+		WHERE
+			id = $1
+	`
+	res, err := r.Db.ExecContext(ctx, query, id, at)
+	if err != nil {
+		return
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if n == 0 {
+		err = apperror.ErrWebhookDeliveryNotFound
+	}
+
+	return
+}
+
+// nonNil stores an empty array rather than NULL for a nil slice.
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -11,6 +11,8 @@ import (
 	model "example.com/m/v2/model"
 
 	sql "database/sql"
+
+	time "time"
 )
 
 // MockRepository is an autogenerated mock type for the Repository type
@@ -98,23 +100,23 @@ func (_m *MockRepository) CommitTx(tx *sql.Tx) error {
 	return r0
 }
 
-// GetLoanByIdAndUserId provides a mock function with given fields: ctx, loanId, userId
-func (_m *MockRepository) GetLoanByIdAndUserId(ctx context.Context, loanId int64, userId int64) (model.Loan, error) {
-	ret := _m.Called(ctx, loanId, userId)
+// ConsumePasswordResetToken provides a mock function with given fields: ctx, tx, tokenHash, now
+func (_m *MockRepository) ConsumePasswordResetToken(ctx context.Context, tx *sql.Tx, tokenHash string, now time.Time) (int64, error) {
+	ret := _m.Called(ctx, tx, tokenHash, now)
 
-	var r0 model.Loan
+	var r0 int64
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) (model.Loan, error)); ok {
-		return rf(ctx, loanId, userId)
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, string, time.Time) (int64, error)); ok {
+		return rf(ctx, tx, tokenHash, now)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) model.Loan); ok {
-		r0 = rf(ctx, loanId, userId)
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, string, time.Time) int64); ok {
+		r0 = rf(ctx, tx, tokenHash, now)
 	} else {
-		r0 = ret.Get(0).(model.Loan)
+		r0 = ret.Get(0).(int64)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
-		r1 = rf(ctx, loanId, userId)
+	if rf, ok := ret.Get(1).(func(context.Context, *sql.Tx, string, time.Time) error); ok {
+		r1 = rf(ctx, tx, tokenHash, now)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -122,25 +124,87 @@ func (_m *MockRepository) GetLoanByIdAndUserId(ctx context.Context, loanId int64
 	return r0, r1
 }
 
-// GetLoanByUserId provides a mock function with given fields: ctx, userId
-func (_m *MockRepository) GetLoanByUserId(ctx context.Context, userId int64) ([]model.Loan, error) {
-	ret := _m.Called(ctx, userId)
+// ConsumeRecoveryCode provides a mock function with given fields: ctx, userId, codeHash, at
+func (_m *MockRepository) ConsumeRecoveryCode(ctx context.Context, userId int64, codeHash string, at time.Time) error {
+	ret := _m.Called(ctx, userId, codeHash, at)
 
-	var r0 []model.Loan
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, time.Time) error); ok {
+		r0 = rf(ctx, userId, codeHash, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CountLoanApprovals provides a mock function with given fields: ctx, tx, loanId, now
+func (_m *MockRepository) CountLoanApprovals(ctx context.Context, tx *sql.Tx, loanId int64, now time.Time) (int, error) {
+	ret := _m.Called(ctx, tx, loanId, now)
+
+	var r0 int
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]model.Loan, error)); ok {
-		return rf(ctx, userId)
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, int64, time.Time) (int, error)); ok {
+		return rf(ctx, tx, loanId, now)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int64) []model.Loan); ok {
-		r0 = rf(ctx, userId)
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, int64, time.Time) int); ok {
+		r0 = rf(ctx, tx, loanId, now)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *sql.Tx, int64, time.Time) error); ok {
+		r1 = rf(ctx, tx, loanId, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateGatewayIntent provides a mock function with given fields: ctx, req
+func (_m *MockRepository) CreateGatewayIntent(ctx context.Context, req model.PaymentIntentRequest) (model.PaymentIntentResult, error) {
+	ret := _m.Called(ctx, req)
+
+	var r0 model.PaymentIntentResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.PaymentIntentRequest) (model.PaymentIntentResult, error)); ok {
+		return rf(ctx, req)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, model.PaymentIntentRequest) model.PaymentIntentResult); ok {
+		r0 = rf(ctx, req)
+	} else {
+		r0 = ret.Get(0).(model.PaymentIntentResult)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, model.PaymentIntentRequest) error); ok {
+		r1 = rf(ctx, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DefaultLoans provides a mock function with given fields: ctx, tx, dueBefore, at
+func (_m *MockRepository) DefaultLoans(ctx context.Context, tx *sql.Tx, dueBefore time.Time, at time.Time) ([]int64, error) {
+	ret := _m.Called(ctx, tx, dueBefore, at)
+
+	var r0 []int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, time.Time, time.Time) ([]int64, error)); ok {
+		return rf(ctx, tx, dueBefore, at)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, time.Time, time.Time) []int64); ok {
+		r0 = rf(ctx, tx, dueBefore, at)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]model.Loan)
+			r0 = ret.Get(0).([]int64)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
-		r1 = rf(ctx, userId)
+	if rf, ok := ret.Get(1).(func(context.Context, *sql.Tx, time.Time, time.Time) error); ok {
+		r1 = rf(ctx, tx, dueBefore, at)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -148,25 +212,53 @@ func (_m *MockRepository) GetLoanByUserId(ctx context.Context, userId int64) ([]
 	return r0, r1
 }
 
-// GetRepaymentByLoanId provides a mock function with given fields: ctx, loanId
-func (_m *MockRepository) GetRepaymentByLoanId(ctx context.Context, loanId int64) ([]model.Repayment, error) {
-	ret := _m.Called(ctx, loanId)
+// DeleteWebhookEndpoint provides a mock function with given fields: ctx, id
+func (_m *MockRepository) DeleteWebhookEndpoint(ctx context.Context, id int64) error {
+	ret := _m.Called(ctx, id)
 
-	var r0 []model.Repayment
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EnableUserTotp provides a mock function with given fields: ctx, tx, id, step, at
+func (_m *MockRepository) EnableUserTotp(ctx context.Context, tx *sql.Tx, id int64, step int64, at time.Time) error {
+	ret := _m.Called(ctx, tx, id, step, at)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, int64, int64, time.Time) error); ok {
+		r0 = rf(ctx, tx, id, step, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ExpirePendingLoans provides a mock function with given fields: ctx, createdBefore, at
+func (_m *MockRepository) ExpirePendingLoans(ctx context.Context, createdBefore time.Time, at time.Time) ([]int64, error) {
+	ret := _m.Called(ctx, createdBefore, at)
+
+	var r0 []int64
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]model.Repayment, error)); ok {
-		return rf(ctx, loanId)
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Time) ([]int64, error)); ok {
+		return rf(ctx, createdBefore, at)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int64) []model.Repayment); ok {
-		r0 = rf(ctx, loanId)
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Time) []int64); ok {
+		r0 = rf(ctx, createdBefore, at)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]model.Repayment)
+			r0 = ret.Get(0).([]int64)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
-		r1 = rf(ctx, loanId)
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, time.Time) error); ok {
+		r1 = rf(ctx, createdBefore, at)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -174,23 +266,23 @@ func (_m *MockRepository) GetRepaymentByLoanId(ctx context.Context, loanId int64
 	return r0, r1
 }
 
-// GetUserByEmail provides a mock function with given fields: ctx, email
-func (_m *MockRepository) GetUserByEmail(ctx context.Context, email string) (model.User, error) {
-	ret := _m.Called(ctx, email)
+// GetActiveDisbursement provides a mock function with given fields: ctx, tx, loanId
+func (_m *MockRepository) GetActiveDisbursement(ctx context.Context, tx *sql.Tx, loanId int64) (model.Disbursement, error) {
+	ret := _m.Called(ctx, tx, loanId)
 
-	var r0 model.User
+	var r0 model.Disbursement
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (model.User, error)); ok {
-		return rf(ctx, email)
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, int64) (model.Disbursement, error)); ok {
+		return rf(ctx, tx, loanId)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) model.User); ok {
-		r0 = rf(ctx, email)
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, int64) model.Disbursement); ok {
+		r0 = rf(ctx, tx, loanId)
 	} else {
-		r0 = ret.Get(0).(model.User)
+		r0 = ret.Get(0).(model.Disbursement)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, email)
+	if rf, ok := ret.Get(1).(func(context.Context, *sql.Tx, int64) error); ok {
+		r1 = rf(ctx, tx, loanId)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -198,23 +290,25 @@ func (_m *MockRepository) GetUserByEmail(ctx context.Context, email string) (mod
 	return r0, r1
 }
 
-// InsertLoan provides a mock function with given fields: ctx, tx, loan
-func (_m *MockRepository) InsertLoan(ctx context.Context, tx *sql.Tx, loan model.Loan) (int64, error) {
-	ret := _m.Called(ctx, tx, loan)
+// GetLoanApprovals provides a mock function with given fields: ctx, loanId
+func (_m *MockRepository) GetLoanApprovals(ctx context.Context, loanId int64) ([]model.LoanApproval, error) {
+	ret := _m.Called(ctx, loanId)
 
-	var r0 int64
+	var r0 []model.LoanApproval
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.Loan) (int64, error)); ok {
-		return rf(ctx, tx, loan)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]model.LoanApproval, error)); ok {
+		return rf(ctx, loanId)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.Loan) int64); ok {
-		r0 = rf(ctx, tx, loan)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []model.LoanApproval); ok {
+		r0 = rf(ctx, loanId)
 	} else {
-		r0 = ret.Get(0).(int64)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.LoanApproval)
+		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, *sql.Tx, model.Loan) error); ok {
-		r1 = rf(ctx, tx, loan)
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, loanId)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -222,23 +316,23 @@ func (_m *MockRepository) InsertLoan(ctx context.Context, tx *sql.Tx, loan model
 	return r0, r1
 }
 
-// InsertRepayment provides a mock function with given fields: ctx, tx, repayment
-func (_m *MockRepository) InsertRepayment(ctx context.Context, tx *sql.Tx, repayment model.Repayment) (int64, error) {
-	ret := _m.Called(ctx, tx, repayment)
+// GetLoanById provides a mock function with given fields: ctx, loanId
+func (_m *MockRepository) GetLoanById(ctx context.Context, loanId int64) (model.Loan, error) {
+	ret := _m.Called(ctx, loanId)
 
-	var r0 int64
+	var r0 model.Loan
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.Repayment) (int64, error)); ok {
-		return rf(ctx, tx, repayment)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (model.Loan, error)); ok {
+		return rf(ctx, loanId)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.Repayment) int64); ok {
-		r0 = rf(ctx, tx, repayment)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) model.Loan); ok {
+		r0 = rf(ctx, loanId)
 	} else {
-		r0 = ret.Get(0).(int64)
+		r0 = ret.Get(0).(model.Loan)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, *sql.Tx, model.Repayment) error); ok {
-		r1 = rf(ctx, tx, repayment)
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, loanId)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -246,23 +340,23 @@ func (_m *MockRepository) InsertRepayment(ctx context.Context, tx *sql.Tx, repay
 	return r0, r1
 }
 
-// InsertUser provides a mock function with given fields: ctx, tx, user
-func (_m *MockRepository) InsertUser(ctx context.Context, tx *sql.Tx, user model.User) (int64, error) {
-	ret := _m.Called(ctx, tx, user)
+// GetLoanByIdAndUserId provides a mock function with given fields: ctx, loanId, userId
+func (_m *MockRepository) GetLoanByIdAndUserId(ctx context.Context, loanId int64, userId int64) (model.Loan, error) {
+	ret := _m.Called(ctx, loanId, userId)
 
-	var r0 int64
+	var r0 model.Loan
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.User) (int64, error)); ok {
-		return rf(ctx, tx, user)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) (model.Loan, error)); ok {
+		return rf(ctx, loanId, userId)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.User) int64); ok {
-		r0 = rf(ctx, tx, user)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) model.Loan); ok {
+		r0 = rf(ctx, loanId, userId)
 	} else {
-		r0 = ret.Get(0).(int64)
+		r0 = ret.Get(0).(model.Loan)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, *sql.Tx, model.User) error); ok {
-		r1 = rf(ctx, tx, user)
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, loanId, userId)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -270,41 +364,49 @@ func (_m *MockRepository) InsertUser(ctx context.Context, tx *sql.Tx, user model
 	return r0, r1
 }
 
-// JwtNew provides a mock function with given fields: claim
-func (_m *MockRepository) JwtNew(claim jwt.MapClaims) *jwt.Token {
-	ret := _m.Called(claim)
+// GetLoanByUserId provides a mock function with given fields: ctx, userId
+func (_m *MockRepository) GetLoanByUserId(ctx context.Context, userId int64) ([]model.Loan, error) {
+	ret := _m.Called(ctx, userId)
 
-	var r0 *jwt.Token
-	if rf, ok := ret.Get(0).(func(jwt.MapClaims) *jwt.Token); ok {
-		r0 = rf(claim)
+	var r0 []model.Loan
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]model.Loan, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []model.Loan); ok {
+		r0 = rf(ctx, userId)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*jwt.Token)
+			r0 = ret.Get(0).([]model.Loan)
 		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// JwtParse provides a mock function with given fields: token
-func (_m *MockRepository) JwtParse(token string) (jwt.MapClaims, error) {
-	ret := _m.Called(token)
+// GetLoanForUpdate provides a mock function with given fields: ctx, tx, loanId
+func (_m *MockRepository) GetLoanForUpdate(ctx context.Context, tx *sql.Tx, loanId int64) (model.Loan, error) {
+	ret := _m.Called(ctx, tx, loanId)
 
-	var r0 jwt.MapClaims
+	var r0 model.Loan
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (jwt.MapClaims, error)); ok {
-		return rf(token)
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, int64) (model.Loan, error)); ok {
+		return rf(ctx, tx, loanId)
 	}
-	if rf, ok := ret.Get(0).(func(string) jwt.MapClaims); ok {
-		r0 = rf(token)
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, int64) model.Loan); ok {
+		r0 = rf(ctx, tx, loanId)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(jwt.MapClaims)
-		}
+		r0 = ret.Get(0).(model.Loan)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(token)
+	if rf, ok := ret.Get(1).(func(context.Context, *sql.Tx, int64) error); ok {
+		r1 = rf(ctx, tx, loanId)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -312,23 +414,23 @@ func (_m *MockRepository) JwtParse(token string) (jwt.MapClaims, error) {
 	return r0, r1
 }
 
-// JwtSign provides a mock function with given fields: token
-func (_m *MockRepository) JwtSign(token *jwt.Token) (string, error) {
-	ret := _m.Called(token)
+// GetNotificationPreferences provides a mock function with given fields: ctx, userId
+func (_m *MockRepository) GetNotificationPreferences(ctx context.Context, userId int64) (model.NotificationPreferences, error) {
+	ret := _m.Called(ctx, userId)
 
-	var r0 string
+	var r0 model.NotificationPreferences
 	var r1 error
-	if rf, ok := ret.Get(0).(func(*jwt.Token) (string, error)); ok {
-		return rf(token)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (model.NotificationPreferences, error)); ok {
+		return rf(ctx, userId)
 	}
-	if rf, ok := ret.Get(0).(func(*jwt.Token) string); ok {
-		r0 = rf(token)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) model.NotificationPreferences); ok {
+		r0 = rf(ctx, userId)
 	} else {
-		r0 = ret.Get(0).(string)
+		r0 = ret.Get(0).(model.NotificationPreferences)
 	}
 
-	if rf, ok := ret.Get(1).(func(*jwt.Token) error); ok {
-		r1 = rf(token)
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userId)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -336,40 +438,1132 @@ func (_m *MockRepository) JwtSign(token *jwt.Token) (string, error) {
 	return r0, r1
 }
 
-// RollbackTx provides a mock function with given fields: tx
-func (_m *MockRepository) RollbackTx(tx *sql.Tx) error {
-	ret := _m.Called(tx)
+// GetNotifications provides a mock function with given fields: ctx, userId, limit
+func (_m *MockRepository) GetNotifications(ctx context.Context, userId int64, limit int) ([]model.Notification, error) {
+	ret := _m.Called(ctx, userId, limit)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(*sql.Tx) error); ok {
-		r0 = rf(tx)
+	var r0 []model.Notification
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) ([]model.Notification, error)); ok {
+		return rf(ctx, userId, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) []model.Notification); ok {
+		r0 = rf(ctx, userId, limit)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.Notification)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int) error); ok {
+		r1 = rf(ctx, userId, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// UpdateLoan provides a mock function with given fields: ctx, tx, loan
-func (_m *MockRepository) UpdateLoan(ctx context.Context, tx *sql.Tx, loan model.Loan) error {
-	ret := _m.Called(ctx, tx, loan)
+// GetOverdueRepayments provides a mock function with given fields: ctx, dueBefore
+func (_m *MockRepository) GetOverdueRepayments(ctx context.Context, dueBefore time.Time) ([]model.Repayment, error) {
+	ret := _m.Called(ctx, dueBefore)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.Loan) error); ok {
-		r0 = rf(ctx, tx, loan)
+	var r0 []model.Repayment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) ([]model.Repayment, error)); ok {
+		return rf(ctx, dueBefore)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) []model.Repayment); ok {
+		r0 = rf(ctx, dueBefore)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.Repayment)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, dueBefore)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// UpdateRepayment provides a mock function with given fields: ctx, tx, repayment
-func (_m *MockRepository) UpdateRepayment(ctx context.Context, tx *sql.Tx, repayment model.Repayment) error {
-	ret := _m.Called(ctx, tx, repayment)
+// GetPaymentIntentForUpdate provides a mock function with given fields: ctx, tx, reference
+func (_m *MockRepository) GetPaymentIntentForUpdate(ctx context.Context, tx *sql.Tx, reference string) (model.PaymentIntent, error) {
+	ret := _m.Called(ctx, tx, reference)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.Repayment) error); ok {
+	var r0 model.PaymentIntent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, string) (model.PaymentIntent, error)); ok {
+		return rf(ctx, tx, reference)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, string) model.PaymentIntent); ok {
+		r0 = rf(ctx, tx, reference)
+	} else {
+		r0 = ret.Get(0).(model.PaymentIntent)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *sql.Tx, string) error); ok {
+		r1 = rf(ctx, tx, reference)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRepaymentByLoanId provides a mock function with given fields: ctx, loanId
+func (_m *MockRepository) GetRepaymentByLoanId(ctx context.Context, loanId int64) ([]model.Repayment, error) {
+	ret := _m.Called(ctx, loanId)
+
+	var r0 []model.Repayment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]model.Repayment, error)); ok {
+		return rf(ctx, loanId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []model.Repayment); ok {
+		r0 = rf(ctx, loanId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.Repayment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, loanId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRepaymentLateFees provides a mock function with given fields: ctx, tx, id
+func (_m *MockRepository) GetRepaymentLateFees(ctx context.Context, tx *sql.Tx, id int64) (float64, error) {
+	ret := _m.Called(ctx, tx, id)
+
+	var r0 float64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, int64) (float64, error)); ok {
+		return rf(ctx, tx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, int64) float64); ok {
+		r0 = rf(ctx, tx, id)
+	} else {
+		r0 = ret.Get(0).(float64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *sql.Tx, int64) error); ok {
+		r1 = rf(ctx, tx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRepaymentReminders provides a mock function with given fields: ctx, dueAfter, dueBefore
+func (_m *MockRepository) GetRepaymentReminders(ctx context.Context, dueAfter time.Time, dueBefore time.Time) ([]model.RepaymentReminder, error) {
+	ret := _m.Called(ctx, dueAfter, dueBefore)
+
+	var r0 []model.RepaymentReminder
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Time) ([]model.RepaymentReminder, error)); ok {
+		return rf(ctx, dueAfter, dueBefore)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Time) []model.RepaymentReminder); ok {
+		r0 = rf(ctx, dueAfter, dueBefore)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.RepaymentReminder)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, time.Time) error); ok {
+		r1 = rf(ctx, dueAfter, dueBefore)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRepaymentsForUpdate provides a mock function with given fields: ctx, tx, loanId
+func (_m *MockRepository) GetRepaymentsForUpdate(ctx context.Context, tx *sql.Tx, loanId int64) ([]model.Repayment, error) {
+	ret := _m.Called(ctx, tx, loanId)
+
+	var r0 []model.Repayment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, int64) ([]model.Repayment, error)); ok {
+		return rf(ctx, tx, loanId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, int64) []model.Repayment); ok {
+		r0 = rf(ctx, tx, loanId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.Repayment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *sql.Tx, int64) error); ok {
+		r1 = rf(ctx, tx, loanId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetStaleDisbursements provides a mock function with given fields: ctx, updatedBefore
+func (_m *MockRepository) GetStaleDisbursements(ctx context.Context, updatedBefore time.Time) ([]int64, error) {
+	ret := _m.Called(ctx, updatedBefore)
+
+	var r0 []int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) ([]int64, error)); ok {
+		return rf(ctx, updatedBefore)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) []int64); ok {
+		r0 = rf(ctx, updatedBefore)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]int64)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, updatedBefore)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserByEmail provides a mock function with given fields: ctx, email
+func (_m *MockRepository) GetUserByEmail(ctx context.Context, email string) (model.User, error) {
+	ret := _m.Called(ctx, email)
+
+	var r0 model.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (model.User, error)); ok {
+		return rf(ctx, email)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) model.User); ok {
+		r0 = rf(ctx, email)
+	} else {
+		r0 = ret.Get(0).(model.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, email)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserById provides a mock function with given fields: ctx, id
+func (_m *MockRepository) GetUserById(ctx context.Context, id int64) (model.User, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 model.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (model.User, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) model.User); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(model.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserRoles provides a mock function with given fields: ctx, userId
+func (_m *MockRepository) GetUserRoles(ctx context.Context, userId int64) ([]model.Role, error) {
+	ret := _m.Called(ctx, userId)
+
+	var r0 []model.Role
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]model.Role, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []model.Role); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.Role)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWebhookDeliveries provides a mock function with given fields: ctx, endpointId, status, limit
+func (_m *MockRepository) GetWebhookDeliveries(ctx context.Context, endpointId int64, status string, limit int) ([]model.WebhookDelivery, error) {
+	ret := _m.Called(ctx, endpointId, status, limit)
+
+	var r0 []model.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, int) ([]model.WebhookDelivery, error)); ok {
+		return rf(ctx, endpointId, status, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, int) []model.WebhookDelivery); ok {
+		r0 = rf(ctx, endpointId, status, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.WebhookDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, int) error); ok {
+		r1 = rf(ctx, endpointId, status, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWebhookDeliveryAttempts provides a mock function with given fields: ctx, deliveryId
+func (_m *MockRepository) GetWebhookDeliveryAttempts(ctx context.Context, deliveryId int64) ([]model.WebhookDeliveryAttempt, error) {
+	ret := _m.Called(ctx, deliveryId)
+
+	var r0 []model.WebhookDeliveryAttempt
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]model.WebhookDeliveryAttempt, error)); ok {
+		return rf(ctx, deliveryId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []model.WebhookDeliveryAttempt); ok {
+		r0 = rf(ctx, deliveryId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.WebhookDeliveryAttempt)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, deliveryId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWebhookDeliveryById provides a mock function with given fields: ctx, id
+func (_m *MockRepository) GetWebhookDeliveryById(ctx context.Context, id int64) (model.WebhookDelivery, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 model.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (model.WebhookDelivery, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) model.WebhookDelivery); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(model.WebhookDelivery)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWebhookEndpointById provides a mock function with given fields: ctx, id
+func (_m *MockRepository) GetWebhookEndpointById(ctx context.Context, id int64) (model.WebhookEndpoint, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 model.WebhookEndpoint
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (model.WebhookEndpoint, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) model.WebhookEndpoint); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(model.WebhookEndpoint)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWebhookEndpoints provides a mock function with given fields: ctx
+func (_m *MockRepository) GetWebhookEndpoints(ctx context.Context) ([]model.WebhookEndpoint, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []model.WebhookEndpoint
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]model.WebhookEndpoint, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []model.WebhookEndpoint); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.WebhookEndpoint)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// HasPendingPaymentIntent provides a mock function with given fields: ctx, loanId, term, now
+func (_m *MockRepository) HasPendingPaymentIntent(ctx context.Context, loanId int64, term int64, now time.Time) (bool, error) {
+	ret := _m.Called(ctx, loanId, term, now)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, time.Time) (bool, error)); ok {
+		return rf(ctx, loanId, term, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, time.Time) bool); ok {
+		r0 = rf(ctx, loanId, term, now)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, time.Time) error); ok {
+		r1 = rf(ctx, loanId, term, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// InsertDisbursement provides a mock function with given fields: ctx, tx, disbursement
+func (_m *MockRepository) InsertDisbursement(ctx context.Context, tx *sql.Tx, disbursement model.Disbursement) (int64, error) {
+	ret := _m.Called(ctx, tx, disbursement)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.Disbursement) (int64, error)); ok {
+		return rf(ctx, tx, disbursement)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.Disbursement) int64); ok {
+		r0 = rf(ctx, tx, disbursement)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *sql.Tx, model.Disbursement) error); ok {
+		r1 = rf(ctx, tx, disbursement)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// InsertLoan provides a mock function with given fields: ctx, tx, loan
+func (_m *MockRepository) InsertLoan(ctx context.Context, tx *sql.Tx, loan model.Loan) (int64, error) {
+	ret := _m.Called(ctx, tx, loan)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.Loan) (int64, error)); ok {
+		return rf(ctx, tx, loan)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.Loan) int64); ok {
+		r0 = rf(ctx, tx, loan)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *sql.Tx, model.Loan) error); ok {
+		r1 = rf(ctx, tx, loan)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// InsertLoanApproval provides a mock function with given fields: ctx, tx, approval
+func (_m *MockRepository) InsertLoanApproval(ctx context.Context, tx *sql.Tx, approval model.LoanApproval) error {
+	ret := _m.Called(ctx, tx, approval)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.LoanApproval) error); ok {
+		r0 = rf(ctx, tx, approval)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// InsertNotifications provides a mock function with given fields: ctx, tx, notification, channels
+func (_m *MockRepository) InsertNotifications(ctx context.Context, tx *sql.Tx, notification model.Notification, channels []string) (int64, error) {
+	ret := _m.Called(ctx, tx, notification, channels)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.Notification, []string) (int64, error)); ok {
+		return rf(ctx, tx, notification, channels)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.Notification, []string) int64); ok {
+		r0 = rf(ctx, tx, notification, channels)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *sql.Tx, model.Notification, []string) error); ok {
+		r1 = rf(ctx, tx, notification, channels)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// InsertOutboxEvent provides a mock function with given fields: ctx, tx, event
+func (_m *MockRepository) InsertOutboxEvent(ctx context.Context, tx *sql.Tx, event model.OutboxEvent) (int64, error) {
+	ret := _m.Called(ctx, tx, event)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.OutboxEvent) (int64, error)); ok {
+		return rf(ctx, tx, event)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.OutboxEvent) int64); ok {
+		r0 = rf(ctx, tx, event)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *sql.Tx, model.OutboxEvent) error); ok {
+		r1 = rf(ctx, tx, event)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// InsertPasswordResetToken provides a mock function with given fields: ctx, token
+func (_m *MockRepository) InsertPasswordResetToken(ctx context.Context, token model.PasswordResetToken) error {
+	ret := _m.Called(ctx, token)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.PasswordResetToken) error); ok {
+		r0 = rf(ctx, token)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// InsertPaymentIntent provides a mock function with given fields: ctx, intent
+func (_m *MockRepository) InsertPaymentIntent(ctx context.Context, intent model.PaymentIntent) (int64, error) {
+	ret := _m.Called(ctx, intent)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.PaymentIntent) (int64, error)); ok {
+		return rf(ctx, intent)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, model.PaymentIntent) int64); ok {
+		r0 = rf(ctx, intent)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, model.PaymentIntent) error); ok {
+		r1 = rf(ctx, intent)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// InsertRepayment provides a mock function with given fields: ctx, tx, repayment
+func (_m *MockRepository) InsertRepayment(ctx context.Context, tx *sql.Tx, repayment model.Repayment) (int64, error) {
+	ret := _m.Called(ctx, tx, repayment)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.Repayment) (int64, error)); ok {
+		return rf(ctx, tx, repayment)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.Repayment) int64); ok {
+		r0 = rf(ctx, tx, repayment)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *sql.Tx, model.Repayment) error); ok {
+		r1 = rf(ctx, tx, repayment)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// InsertRepaymentFee provides a mock function with given fields: ctx, tx, fee
+func (_m *MockRepository) InsertRepaymentFee(ctx context.Context, tx *sql.Tx, fee model.RepaymentFee) (int64, error) {
+	ret := _m.Called(ctx, tx, fee)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.RepaymentFee) (int64, error)); ok {
+		return rf(ctx, tx, fee)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.RepaymentFee) int64); ok {
+		r0 = rf(ctx, tx, fee)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *sql.Tx, model.RepaymentFee) error); ok {
+		r1 = rf(ctx, tx, fee)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// InsertUser provides a mock function with given fields: ctx, tx, user
+func (_m *MockRepository) InsertUser(ctx context.Context, tx *sql.Tx, user model.User) (int64, error) {
+	ret := _m.Called(ctx, tx, user)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.User) (int64, error)); ok {
+		return rf(ctx, tx, user)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.User) int64); ok {
+		r0 = rf(ctx, tx, user)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *sql.Tx, model.User) error); ok {
+		r1 = rf(ctx, tx, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// InsertWebhookEndpoint provides a mock function with given fields: ctx, endpoint
+func (_m *MockRepository) InsertWebhookEndpoint(ctx context.Context, endpoint model.WebhookEndpoint) (int64, error) {
+	ret := _m.Called(ctx, endpoint)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.WebhookEndpoint) (int64, error)); ok {
+		return rf(ctx, endpoint)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, model.WebhookEndpoint) int64); ok {
+		r0 = rf(ctx, endpoint)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, model.WebhookEndpoint) error); ok {
+		r1 = rf(ctx, endpoint)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// JwtNew provides a mock function with given fields: claim
+func (_m *MockRepository) JwtNew(claim jwt.MapClaims) *jwt.Token {
+	ret := _m.Called(claim)
+
+	var r0 *jwt.Token
+	if rf, ok := ret.Get(0).(func(jwt.MapClaims) *jwt.Token); ok {
+		r0 = rf(claim)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*jwt.Token)
+		}
+	}
+
+	return r0
+}
+
+// JwtParse provides a mock function with given fields: token
+func (_m *MockRepository) JwtParse(token string) (jwt.MapClaims, error) {
+	ret := _m.Called(token)
+
+	var r0 jwt.MapClaims
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (jwt.MapClaims, error)); ok {
+		return rf(token)
+	}
+	if rf, ok := ret.Get(0).(func(string) jwt.MapClaims); ok {
+		r0 = rf(token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(jwt.MapClaims)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// JwtSign provides a mock function with given fields: token
+func (_m *MockRepository) JwtSign(token *jwt.Token) (string, error) {
+	ret := _m.Called(token)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*jwt.Token) (string, error)); ok {
+		return rf(token)
+	}
+	if rf, ok := ret.Get(0).(func(*jwt.Token) string); ok {
+		r0 = rf(token)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(*jwt.Token) error); ok {
+		r1 = rf(token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListRoles provides a mock function with given fields: ctx
+func (_m *MockRepository) ListRoles(ctx context.Context) ([]model.Role, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []model.Role
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]model.Role, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []model.Role); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.Role)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkRepaymentOverdue provides a mock function with given fields: ctx, tx, id
+func (_m *MockRepository) MarkRepaymentOverdue(ctx context.Context, tx *sql.Tx, id int64) (bool, error) {
+	ret := _m.Called(ctx, tx, id)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, int64) (bool, error)); ok {
+		return rf(ctx, tx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, int64) bool); ok {
+		r0 = rf(ctx, tx, id)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *sql.Tx, int64) error); ok {
+		r1 = rf(ctx, tx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkRepaymentReminded provides a mock function with given fields: ctx, tx, id, at
+func (_m *MockRepository) MarkRepaymentReminded(ctx context.Context, tx *sql.Tx, id int64, at time.Time) error {
+	ret := _m.Called(ctx, tx, id, at)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, int64, time.Time) error); ok {
+		r0 = rf(ctx, tx, id, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PaymentGateway provides a mock function with given fields:
+func (_m *MockRepository) PaymentGateway() string {
+	ret := _m.Called()
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// Payout provides a mock function with given fields: ctx, req
+func (_m *MockRepository) Payout(ctx context.Context, req model.PayoutRequest) (model.PayoutResult, error) {
+	ret := _m.Called(ctx, req)
+
+	var r0 model.PayoutResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.PayoutRequest) (model.PayoutResult, error)); ok {
+		return rf(ctx, req)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, model.PayoutRequest) model.PayoutResult); ok {
+		r0 = rf(ctx, req)
+	} else {
+		r0 = ret.Get(0).(model.PayoutResult)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, model.PayoutRequest) error); ok {
+		r1 = rf(ctx, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PayoutProvider provides a mock function with given fields:
+func (_m *MockRepository) PayoutProvider() string {
+	ret := _m.Called()
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// RandomToken provides a mock function with given fields:
+func (_m *MockRepository) RandomToken() (string, error) {
+	ret := _m.Called()
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (string, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecordDisbursementError provides a mock function with given fields: ctx, id, message, at
+func (_m *MockRepository) RecordDisbursementError(ctx context.Context, id int64, message string, at time.Time) error {
+	ret := _m.Called(ctx, id, message, at)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, time.Time) error); ok {
+		r0 = rf(ctx, id, message, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RedeliverWebhook provides a mock function with given fields: ctx, id, at
+func (_m *MockRepository) RedeliverWebhook(ctx context.Context, id int64, at time.Time) error {
+	ret := _m.Called(ctx, id, at)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, time.Time) error); ok {
+		r0 = rf(ctx, id, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReplaceRecoveryCodes provides a mock function with given fields: ctx, tx, userId, codeHashes, at
+func (_m *MockRepository) ReplaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userId int64, codeHashes []string, at time.Time) error {
+	ret := _m.Called(ctx, tx, userId, codeHashes, at)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, int64, []string, time.Time) error); ok {
+		r0 = rf(ctx, tx, userId, codeHashes, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReplaceUserRoles provides a mock function with given fields: ctx, tx, userId, roles, assignedBy, at
+func (_m *MockRepository) ReplaceUserRoles(ctx context.Context, tx *sql.Tx, userId int64, roles []string, assignedBy int64, at time.Time) error {
+	ret := _m.Called(ctx, tx, userId, roles, assignedBy, at)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, int64, []string, int64, time.Time) error); ok {
+		r0 = rf(ctx, tx, userId, roles, assignedBy, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokePasswordResetTokens provides a mock function with given fields: ctx, tx, userId, now
+func (_m *MockRepository) RevokePasswordResetTokens(ctx context.Context, tx *sql.Tx, userId int64, now time.Time) error {
+	ret := _m.Called(ctx, tx, userId, now)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, int64, time.Time) error); ok {
+		r0 = rf(ctx, tx, userId, now)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RollbackTx provides a mock function with given fields: tx
+func (_m *MockRepository) RollbackTx(tx *sql.Tx) error {
+	ret := _m.Called(tx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*sql.Tx) error); ok {
+		r0 = rf(tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ScheduleRepayments provides a mock function with given fields: ctx, tx, loanId, from
+func (_m *MockRepository) ScheduleRepayments(ctx context.Context, tx *sql.Tx, loanId int64, from time.Time) error {
+	ret := _m.Called(ctx, tx, loanId, from)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, int64, time.Time) error); ok {
+		r0 = rf(ctx, tx, loanId, from)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SendMail provides a mock function with given fields: ctx, mail
+func (_m *MockRepository) SendMail(ctx context.Context, mail model.Mail) error {
+	ret := _m.Called(ctx, mail)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.Mail) error); ok {
+		r0 = rf(ctx, mail)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetUserTotpSecret provides a mock function with given fields: ctx, id, secret
+func (_m *MockRepository) SetUserTotpSecret(ctx context.Context, id int64, secret string) error {
+	ret := _m.Called(ctx, id, secret)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, id, secret)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// TotpGenerateSecret provides a mock function with given fields:
+func (_m *MockRepository) TotpGenerateSecret() (string, error) {
+	ret := _m.Called()
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (string, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// TotpRecoveryCodes provides a mock function with given fields:
+func (_m *MockRepository) TotpRecoveryCodes() ([]string, error) {
+	ret := _m.Called()
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]string, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// TotpValidate provides a mock function with given fields: secret, code, at
+func (_m *MockRepository) TotpValidate(secret string, code string, at time.Time) (int64, bool) {
+	ret := _m.Called(secret, code, at)
+
+	var r0 int64
+	var r1 bool
+	if rf, ok := ret.Get(0).(func(string, string, time.Time) (int64, bool)); ok {
+		return rf(secret, code, at)
+	}
+	if rf, ok := ret.Get(0).(func(string, string, time.Time) int64); ok {
+		r0 = rf(secret, code, at)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string, time.Time) bool); ok {
+		r1 = rf(secret, code, at)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
+}
+
+// UpdateDisbursement provides a mock function with given fields: ctx, tx, disbursement
+func (_m *MockRepository) UpdateDisbursement(ctx context.Context, tx *sql.Tx, disbursement model.Disbursement) error {
+	ret := _m.Called(ctx, tx, disbursement)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.Disbursement) error); ok {
+		r0 = rf(ctx, tx, disbursement)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateLoan provides a mock function with given fields: ctx, tx, loan
+func (_m *MockRepository) UpdateLoan(ctx context.Context, tx *sql.Tx, loan model.Loan) error {
+	ret := _m.Called(ctx, tx, loan)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.Loan) error); ok {
+		r0 = rf(ctx, tx, loan)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdatePaymentIntent provides a mock function with given fields: ctx, tx, intent
+func (_m *MockRepository) UpdatePaymentIntent(ctx context.Context, tx *sql.Tx, intent model.PaymentIntent) error {
+	ret := _m.Called(ctx, tx, intent)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.PaymentIntent) error); ok {
+		r0 = rf(ctx, tx, intent)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateRepayment provides a mock function with given fields: ctx, tx, repayment
+func (_m *MockRepository) UpdateRepayment(ctx context.Context, tx *sql.Tx, repayment model.Repayment) error {
+	ret := _m.Called(ctx, tx, repayment)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, model.Repayment) error); ok {
 		r0 = rf(ctx, tx, repayment)
 	} else {
 		r0 = ret.Error(0)
@@ -378,6 +1572,118 @@ func (_m *MockRepository) UpdateRepayment(ctx context.Context, tx *sql.Tx, repay
 	return r0
 }
 
+// UpdateUserLocale provides a mock function with given fields: ctx, tx, userId, locale, at
+func (_m *MockRepository) UpdateUserLocale(ctx context.Context, tx *sql.Tx, userId int64, locale string, at time.Time) error {
+	ret := _m.Called(ctx, tx, userId, locale, at)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, int64, string, time.Time) error); ok {
+		r0 = rf(ctx, tx, userId, locale, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateUserPassword provides a mock function with given fields: ctx, tx, id, hash
+func (_m *MockRepository) UpdateUserPassword(ctx context.Context, tx *sql.Tx, id int64, hash string) error {
+	ret := _m.Called(ctx, tx, id, hash)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, int64, string) error); ok {
+		r0 = rf(ctx, tx, id, hash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateWebhookEndpoint provides a mock function with given fields: ctx, endpoint
+func (_m *MockRepository) UpdateWebhookEndpoint(ctx context.Context, endpoint model.WebhookEndpoint) error {
+	ret := _m.Called(ctx, endpoint)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.WebhookEndpoint) error); ok {
+		r0 = rf(ctx, endpoint)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateWebhookSecret provides a mock function with given fields: ctx, id, secret, at
+func (_m *MockRepository) UpdateWebhookSecret(ctx context.Context, id int64, secret string, at time.Time) error {
+	ret := _m.Called(ctx, id, secret, at)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, time.Time) error); ok {
+		r0 = rf(ctx, id, secret, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpsertChannelPreference provides a mock function with given fields: ctx, tx, userId, pref, at
+func (_m *MockRepository) UpsertChannelPreference(ctx context.Context, tx *sql.Tx, userId int64, pref model.ChannelPreference, at time.Time) error {
+	ret := _m.Called(ctx, tx, userId, pref, at)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, int64, model.ChannelPreference, time.Time) error); ok {
+		r0 = rf(ctx, tx, userId, pref, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UseTotpStep provides a mock function with given fields: ctx, id, step
+func (_m *MockRepository) UseTotpStep(ctx context.Context, id int64, step int64) error {
+	ret := _m.Called(ctx, id, step)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, id, step)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UseWebhookNonce provides a mock function with given fields: ctx, tx, nonce, at, forgetBefore
+func (_m *MockRepository) UseWebhookNonce(ctx context.Context, tx *sql.Tx, nonce string, at time.Time, forgetBefore time.Time) error {
+	ret := _m.Called(ctx, tx, nonce, at, forgetBefore)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *sql.Tx, string, time.Time, time.Time) error); ok {
+		r0 = rf(ctx, tx, nonce, at, forgetBefore)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// VerifyUserEmail provides a mock function with given fields: ctx, id, email, verifiedAt
+func (_m *MockRepository) VerifyUserEmail(ctx context.Context, id int64, email string, verifiedAt time.Time) error {
+	ret := _m.Called(ctx, id, email, verifiedAt)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, time.Time) error); ok {
+		r0 = rf(ctx, id, email, verifiedAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewMockRepository interface {
 	mock.TestingT
 	Cleanup(func())
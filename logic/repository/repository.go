@@ -3,6 +3,7 @@ package io
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"example.com/m/v2/model"
 	"github.com/golang-jwt/jwt/v5"
@@ -10,6 +11,7 @@ import (
 
 type Repository interface {
 	GetUserByEmail(ctx context.Context, email string) (res model.User, err error)
+	GetUserById(ctx context.Context, id int64) (res model.User, err error)
 	JwtNew(claim jwt.MapClaims) *jwt.Token
 	BcryptComparePassword(hash, password []byte) error
 	JwtSign(token *jwt.Token) (string, error)
@@ -23,7 +25,70 @@ type Repository interface {
 	JwtParse(token string) (claims jwt.MapClaims, err error)
 	UpdateLoan(ctx context.Context, tx *sql.Tx, loan model.Loan) (err error)
 	GetRepaymentByLoanId(ctx context.Context, loanId int64) (res []model.Repayment, err error)
+	GetRepaymentsForUpdate(ctx context.Context, tx *sql.Tx, loanId int64) (res []model.Repayment, err error)
+	GetLoanById(ctx context.Context, loanId int64) (res model.Loan, err error)
 	GetLoanByIdAndUserId(ctx context.Context, loanId, userId int64) (res model.Loan, err error)
 	UpdateRepayment(ctx context.Context, tx *sql.Tx, repayment model.Repayment) (err error)
 	GetLoanByUserId(ctx context.Context, userId int64) (res []model.Loan, err error)
+	VerifyUserEmail(ctx context.Context, id int64, email string, verifiedAt time.Time) (err error)
+	SendMail(ctx context.Context, mail model.Mail) (err error)
+	UpdateUserPassword(ctx context.Context, tx *sql.Tx, id int64, hash string) (err error)
+	RandomToken() (token string, err error)
+	InsertPasswordResetToken(ctx context.Context, token model.PasswordResetToken) (err error)
+	ConsumePasswordResetToken(ctx context.Context, tx *sql.Tx, tokenHash string, now time.Time) (userId int64, err error)
+	RevokePasswordResetTokens(ctx context.Context, tx *sql.Tx, userId int64, now time.Time) (err error)
+	TotpGenerateSecret() (string, error)
+	TotpValidate(secret, code string, at time.Time) (step int64, ok bool)
+	TotpRecoveryCodes() ([]string, error)
+	SetUserTotpSecret(ctx context.Context, id int64, secret string) (err error)
+	EnableUserTotp(ctx context.Context, tx *sql.Tx, id, step int64, at time.Time) (err error)
+	UseTotpStep(ctx context.Context, id, step int64) (err error)
+	ReplaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userId int64, codeHashes []string, at time.Time) (err error)
+	ConsumeRecoveryCode(ctx context.Context, userId int64, codeHash string, at time.Time) (err error)
+	ListRoles(ctx context.Context) (res []model.Role, err error)
+	GetUserRoles(ctx context.Context, userId int64) (res []model.Role, err error)
+	GetLoanForUpdate(ctx context.Context, tx *sql.Tx, loanId int64) (res model.Loan, err error)
+	InsertLoanApproval(ctx context.Context, tx *sql.Tx, approval model.LoanApproval) (err error)
+	CountLoanApprovals(ctx context.Context, tx *sql.Tx, loanId int64, now time.Time) (n int, err error)
+	GetLoanApprovals(ctx context.Context, loanId int64) (res []model.LoanApproval, err error)
+	ReplaceUserRoles(ctx context.Context, tx *sql.Tx, userId int64, roles []string, assignedBy int64, at time.Time) (err error)
+	PayoutProvider() string
+	Payout(ctx context.Context, req model.PayoutRequest) (res model.PayoutResult, err error)
+	InsertDisbursement(ctx context.Context, tx *sql.Tx, disbursement model.Disbursement) (id int64, err error)
+	GetActiveDisbursement(ctx context.Context, tx *sql.Tx, loanId int64) (res model.Disbursement, err error)
+	UpdateDisbursement(ctx context.Context, tx *sql.Tx, disbursement model.Disbursement) (err error)
+	RecordDisbursementError(ctx context.Context, id int64, message string, at time.Time) (err error)
+	GetStaleDisbursements(ctx context.Context, updatedBefore time.Time) (loanIds []int64, err error)
+	ScheduleRepayments(ctx context.Context, tx *sql.Tx, loanId int64, from time.Time) (err error)
+	PaymentGateway() string
+	CreateGatewayIntent(ctx context.Context, req model.PaymentIntentRequest) (res model.PaymentIntentResult, err error)
+	InsertPaymentIntent(ctx context.Context, intent model.PaymentIntent) (id int64, err error)
+	HasPendingPaymentIntent(ctx context.Context, loanId, term int64, now time.Time) (pending bool, err error)
+	GetPaymentIntentForUpdate(ctx context.Context, tx *sql.Tx, reference string) (res model.PaymentIntent, err error)
+	UpdatePaymentIntent(ctx context.Context, tx *sql.Tx, intent model.PaymentIntent) (err error)
+	UseWebhookNonce(ctx context.Context, tx *sql.Tx, nonce string, at, forgetBefore time.Time) (err error)
+	GetOverdueRepayments(ctx context.Context, dueBefore time.Time) (res []model.Repayment, err error)
+	MarkRepaymentOverdue(ctx context.Context, tx *sql.Tx, id int64) (marked bool, err error)
+	GetRepaymentLateFees(ctx context.Context, tx *sql.Tx, id int64) (fees float64, err error)
+	InsertRepaymentFee(ctx context.Context, tx *sql.Tx, fee model.RepaymentFee) (id int64, err error)
+	DefaultLoans(ctx context.Context, tx *sql.Tx, dueBefore, at time.Time) (ids []int64, err error)
+	ExpirePendingLoans(ctx context.Context, createdBefore, at time.Time) (ids []int64, err error)
+	GetRepaymentReminders(ctx context.Context, dueAfter, dueBefore time.Time) (res []model.RepaymentReminder, err error)
+	MarkRepaymentReminded(ctx context.Context, tx *sql.Tx, id int64, at time.Time) (err error)
+	InsertOutboxEvent(ctx context.Context, tx *sql.Tx, event model.OutboxEvent) (id int64, err error)
+	InsertWebhookEndpoint(ctx context.Context, endpoint model.WebhookEndpoint) (id int64, err error)
+	GetWebhookEndpoints(ctx context.Context) (res []model.WebhookEndpoint, err error)
+	GetWebhookEndpointById(ctx context.Context, id int64) (res model.WebhookEndpoint, err error)
+	UpdateWebhookEndpoint(ctx context.Context, endpoint model.WebhookEndpoint) (err error)
+	UpdateWebhookSecret(ctx context.Context, id int64, secret string, at time.Time) (err error)
+	DeleteWebhookEndpoint(ctx context.Context, id int64) (err error)
+	GetWebhookDeliveries(ctx context.Context, endpointId int64, status string, limit int) (res []model.WebhookDelivery, err error)
+	GetWebhookDeliveryById(ctx context.Context, id int64) (res model.WebhookDelivery, err error)
+	GetWebhookDeliveryAttempts(ctx context.Context, deliveryId int64) (res []model.WebhookDeliveryAttempt, err error)
+	RedeliverWebhook(ctx context.Context, id int64, at time.Time) (err error)
+	InsertNotifications(ctx context.Context, tx *sql.Tx, notification model.Notification, channels []string) (queued int64, err error)
+	GetNotifications(ctx context.Context, userId int64, limit int) (res []model.Notification, err error)
+	GetNotificationPreferences(ctx context.Context, userId int64) (res model.NotificationPreferences, err error)
+	UpdateUserLocale(ctx context.Context, tx *sql.Tx, userId int64, locale string, at time.Time) (err error)
+	UpsertChannelPreference(ctx context.Context, tx *sql.Tx, userId int64, pref model.ChannelPreference, at time.Time) (err error)
 }
@@ -0,0 +1,216 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/logger"
+	"example.com/m/v2/model"
+	"example.com/m/v2/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DisburseLoan pays an approved loan out through the payout provider. The loan is DISBURSING while the payout is
+// pending and DISBURSED once the provider confirms it, repayment due dates then start from the disbursement date.
+// A failed payout puts the loan back to APPROVED for a new attempt. Calling it again on a DISBURSING loan resubmits
+// the same idempotency key, so retries never pay twice, and on a DISBURSED loan it returns the disbursement.
+func (u *usecase) DisburseLoan(ctx context.Context, loanId int64) (res model.Disbursement, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.DisburseLoan", attribute.Int64("loan_id", loanId))
+	defer tracing.End(span, &err)
+
+	disbursement, userId, settled, err := u.startDisbursement(ctx, loanId)
+	if err != nil || settled {
+		return disbursement, err
+	}
+
+	result, err := u.repository.Payout(ctx, model.PayoutRequest{
+		IdempotencyKey: disbursement.IdempotencyKey,
+		LoanId:         loanId,
+		UserId:         userId,
+		Amount:         disbursement.Amount,
+	})
+	if err != nil {
+		// the payout may or may not have gone through, the loan stays DISBURSING until a retry gets an answer
+		if errRecord := u.repository.RecordDisbursementError(ctx, disbursement.Id, err.Error(), time.Now()); errRecord != nil {
+			logger.FromContext(ctx).ErrorContext(ctx, "failed to record disbursement error", "disbursement_id", disbursement.Id, "error", errRecord)
+		}
+		return
+	}
+
+	return u.settleDisbursement(ctx, disbursement, result)
+}
+
+// ReconcileDisbursements resubmits the payouts that stayed pending for longer than the reconcile window, the provider
+// answers a known idempotency key with the payout's current state. A failed loan is logged and left for the next run
+// while the others go on, the failures are returned together.
+func (u *usecase) ReconcileDisbursements(ctx context.Context, now time.Time) (settled int, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.ReconcileDisbursements")
+	defer tracing.End(span, &err)
+
+	loanIds, err := u.repository.GetStaleDisbursements(ctx, now.Add(-u.cfg.Jobs.ReconcileAfter))
+	if err != nil {
+		return
+	}
+
+	log := logger.FromContext(ctx)
+	var errs []error
+	for _, loanId := range loanIds {
+		disbursement, disburseErr := u.DisburseLoan(ctx, loanId)
+		if disburseErr != nil {
+			log.ErrorContext(ctx, "disbursement reconcile failed", "loan_id", loanId, "error", disburseErr)
+			errs = append(errs, disburseErr)
+			continue
+		}
+
+		if disbursement.Status != constant.DisbursementStatusPending {
+			settled++
+		}
+	}
+
+	return settled, errors.Join(errs...)
+}
+
+// startDisbursement creates the disbursement of an approved loan, or returns the one in progress. settled is true
+// when the loan was already disbursed and there is nothing left to submit.
+func (u *usecase) startDisbursement(ctx context.Context, loanId int64) (res model.Disbursement, userId int64, settled bool, err error) {
+	tx, err := u.repository.BeginTx(ctx)
+	if err != nil {
+		return
+	}
+	defer u.repository.RollbackTx(tx)
+
+	loan, err := u.repository.GetLoanForUpdate(ctx, tx, loanId)
+	if err != nil {
+		return
+	}
+	if loan.UserId != nil {
+		userId = *loan.UserId
+	}
+
+	var disbursement model.Disbursement
+	switch loan.Status {
+	case constant.LoanStatusDisbursed:
+		disbursement, err = u.repository.GetActiveDisbursement(ctx, tx, loanId)
+		if errors.Is(err, apperror.ErrDisbursementNotFound) {
+			// disbursed before disbursements were recorded
+			err = apperror.ErrLoanAlreadyDisbursed
+		}
+		if err != nil {
+			return
+		}
+		return disbursement, userId, true, nil
+
+	case constant.LoanStatusDisbursing:
+		disbursement, err = u.repository.GetActiveDisbursement(ctx, tx, loanId)
+		if err != nil {
+			return
+		}
+
+	case constant.LoanStatusApproved:
+		disbursement = model.Disbursement{
+			LoanId:    loanId,
+			Provider:  u.repository.PayoutProvider(),
+			Status:    constant.DisbursementStatusPending,
+			CreatedAt: time.Now(),
+		}
+		if loan.Amount != nil {
+			disbursement.Amount = *loan.Amount
+		}
+		disbursement.IdempotencyKey, err = u.repository.RandomToken()
+		if err != nil {
+			return
+		}
+		disbursement.Id, err = u.repository.InsertDisbursement(ctx, tx, disbursement)
+		if err != nil {
+			return
+		}
+		err = u.repository.UpdateLoan(ctx, tx, model.Loan{
+			Id:     loanId,
+			Status: constant.LoanStatusDisbursing,
+		})
+		if err != nil {
+			return
+		}
+
+	default:
+		err = apperror.ErrLoanNotApproved
+		return
+	}
+
+	err = u.repository.CommitTx(tx)
+	if err != nil {
+		return
+	}
+
+	return disbursement, userId, false, nil
+}
+
+// settleDisbursement stores the provider's answer and moves the loan on when the payout succeeded or failed.
+func (u *usecase) settleDisbursement(ctx context.Context, disbursement model.Disbursement, result model.PayoutResult) (res model.Disbursement, err error) {
+	tx, err := u.repository.BeginTx(ctx)
+	if err != nil {
+		return
+	}
+	defer u.repository.RollbackTx(tx)
+
+	loan, err := u.repository.GetLoanForUpdate(ctx, tx, disbursement.LoanId)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	disbursement.ProviderReference = result.Reference
+	disbursement.Status = result.Status
+	disbursement.FailureReason = result.FailureReason
+	disbursement.Attempts++
+	if result.Status != constant.DisbursementStatusPending {
+		disbursement.CompletedAt = &now
+	}
+
+	if loan.Status != constant.LoanStatusDisbursing {
+		// a concurrent retry already stored the same answer
+		return disbursement, nil
+	}
+
+	err = u.repository.UpdateDisbursement(ctx, tx, disbursement)
+	if err != nil {
+		return
+	}
+
+	switch result.Status {
+	case constant.DisbursementStatusSucceeded:
+		err = u.repository.UpdateLoan(ctx, tx, model.Loan{
+			Id:          disbursement.LoanId,
+			Status:      constant.LoanStatusDisbursed,
+			DisbursedAt: &now,
+		})
+		if err != nil {
+			return
+		}
+		err = u.repository.ScheduleRepayments(ctx, tx, disbursement.LoanId, now)
+		if err != nil {
+			return
+		}
+
+	case constant.DisbursementStatusFailed:
+		err = u.repository.UpdateLoan(ctx, tx, model.Loan{
+			Id:     disbursement.LoanId,
+			Status: constant.LoanStatusApproved,
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	err = u.repository.CommitTx(tx)
+	if err != nil {
+		return
+	}
+
+	logger.FromContext(ctx).InfoContext(ctx, "loan disbursement updated", "loan_id", disbursement.LoanId,
+		"disbursement_id", disbursement.Id, "status", disbursement.Status, "reference", disbursement.ProviderReference)
+	return disbursement, nil
+}
@@ -0,0 +1,503 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	repo "example.com/m/v2/logic/repository"
+	"example.com/m/v2/model"
+	"example.com/m/v2/util"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_DisburseLoan(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+
+	borrowerId := int64(2)
+	amount := float64(1000)
+	loan := func(status string) model.Loan {
+		return model.Loan{Id: 1, UserId: &borrowerId, Amount: &amount, Status: status}
+	}
+
+	pending := model.Disbursement{
+		Id:             5,
+		LoanId:         1,
+		Amount:         1000,
+		IdempotencyKey: "key",
+		Provider:       "fake",
+		Status:         constant.DisbursementStatusPending,
+	}
+	payoutReq := model.PayoutRequest{IdempotencyKey: "key", LoanId: 1, UserId: 2, Amount: 1000}
+
+	// beginLocked mocks a transaction up to the locked loan read
+	beginLocked := func(loan model.Loan, err error) {
+		repoMock.
+			On("BeginTx", mock.Anything).
+			Return(&sql.Tx{}, nil).
+			Once()
+
+		repoMock.
+			On("RollbackTx", &sql.Tx{}).
+			Return(nil).
+			Once()
+
+		repoMock.
+			On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+			Return(loan, err).
+			Once()
+	}
+
+	// create mocks the first transaction of an approved loan, the disbursement is created and the loan is DISBURSING
+	create := func() {
+		beginLocked(loan(constant.LoanStatusApproved), nil)
+
+		repoMock.
+			On("PayoutProvider").
+			Return("fake").
+			Once()
+
+		repoMock.
+			On("RandomToken").
+			Return("key", nil).
+			Once()
+
+		repoMock.
+			On("InsertDisbursement", mock.Anything, &sql.Tx{}, mock.MatchedBy(func(d model.Disbursement) bool {
+				return d.LoanId == 1 && d.Amount == 1000 && d.IdempotencyKey == "key" && d.Provider == "fake" &&
+					d.Status == constant.DisbursementStatusPending
+			})).
+			Return(int64(5), nil).
+			Once()
+
+		repoMock.
+			On("UpdateLoan", mock.Anything, &sql.Tx{}, model.Loan{Id: 1, Status: constant.LoanStatusDisbursing}).
+			Return(nil).
+			Once()
+
+		repoMock.
+			On("CommitTx", &sql.Tx{}).
+			Return(nil).
+			Once()
+	}
+
+	// settle mocks the second transaction up to the stored disbursement
+	settle := func(status, reference, reason string) {
+		beginLocked(loan(constant.LoanStatusDisbursing), nil)
+
+		repoMock.
+			On("UpdateDisbursement", mock.Anything, &sql.Tx{}, mock.MatchedBy(func(d model.Disbursement) bool {
+				return d.Id == 5 && d.Status == status && d.ProviderReference == reference && d.FailureReason == reason &&
+					(d.CompletedAt == nil) == (status == constant.DisbursementStatusPending)
+			})).
+			Return(nil).
+			Once()
+	}
+
+	tests := []struct {
+		name          string
+		mock          func()
+		want          model.Disbursement
+		wantCompleted bool
+		wantErr       error
+	}{
+		{
+			name: "fail beginTx",
+			mock: func() {
+				repoMock.
+					On("BeginTx", mock.Anything).
+					Return(nil, errors.New("err beginTx")).
+					Once()
+			},
+			wantErr: errors.New("err beginTx"),
+		},
+		{
+			name: "loan not approved",
+			mock: func() {
+				beginLocked(loan(constant.LoanStatusPending), nil)
+			},
+			wantErr: apperror.ErrLoanNotApproved,
+		},
+		{
+			name: "loan already disbursed",
+			mock: func() {
+				beginLocked(loan(constant.LoanStatusDisbursed), nil)
+
+				succeeded := pending
+				succeeded.Status = constant.DisbursementStatusSucceeded
+				repoMock.
+					On("GetActiveDisbursement", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(succeeded, nil).
+					Once()
+			},
+			want: func() model.Disbursement {
+				d := pending
+				d.Status = constant.DisbursementStatusSucceeded
+				return d
+			}(),
+		},
+		{
+			name: "loan disbursed before disbursements were recorded",
+			mock: func() {
+				beginLocked(loan(constant.LoanStatusDisbursed), nil)
+
+				repoMock.
+					On("GetActiveDisbursement", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(model.Disbursement{}, apperror.ErrDisbursementNotFound).
+					Once()
+			},
+			wantErr: apperror.ErrLoanAlreadyDisbursed,
+		},
+		{
+			name: "fail RandomToken",
+			mock: func() {
+				beginLocked(loan(constant.LoanStatusApproved), nil)
+
+				repoMock.
+					On("PayoutProvider").
+					Return("fake").
+					Once()
+
+				repoMock.
+					On("RandomToken").
+					Return("", errors.New("err RandomToken")).
+					Once()
+			},
+			wantErr: errors.New("err RandomToken"),
+		},
+		{
+			name: "provider unreachable, the loan stays disbursing",
+			mock: func() {
+				create()
+
+				repoMock.
+					On("Payout", mock.Anything, payoutReq).
+					Return(model.PayoutResult{}, errors.New("err Payout")).
+					Once()
+
+				repoMock.
+					On("RecordDisbursementError", mock.Anything, int64(5), "err Payout", mock.Anything).
+					Return(nil).
+					Once()
+			},
+			wantErr: errors.New("err Payout"),
+		},
+		{
+			name: "payout succeeded",
+			mock: func() {
+				create()
+
+				repoMock.
+					On("Payout", mock.Anything, payoutReq).
+					Return(model.PayoutResult{Reference: "ref", Status: constant.DisbursementStatusSucceeded}, nil).
+					Once()
+
+				settle(constant.DisbursementStatusSucceeded, "ref", "")
+
+				repoMock.
+					On("UpdateLoan", mock.Anything, &sql.Tx{}, mock.MatchedBy(func(l model.Loan) bool {
+						return l.Id == 1 && l.Status == constant.LoanStatusDisbursed && l.DisbursedAt != nil
+					})).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("ScheduleRepayments", mock.Anything, &sql.Tx{}, int64(1), mock.Anything).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("CommitTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+			},
+			want: func() model.Disbursement {
+				d := pending
+				d.ProviderReference = "ref"
+				d.Status = constant.DisbursementStatusSucceeded
+				d.Attempts = 1
+				return d
+			}(),
+			wantCompleted: true,
+		},
+		{
+			name: "fail ScheduleRepayments",
+			mock: func() {
+				create()
+
+				repoMock.
+					On("Payout", mock.Anything, payoutReq).
+					Return(model.PayoutResult{Reference: "ref", Status: constant.DisbursementStatusSucceeded}, nil).
+					Once()
+
+				settle(constant.DisbursementStatusSucceeded, "ref", "")
+
+				repoMock.
+					On("UpdateLoan", mock.Anything, &sql.Tx{}, mock.Anything).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("ScheduleRepayments", mock.Anything, &sql.Tx{}, int64(1), mock.Anything).
+					Return(errors.New("err ScheduleRepayments")).
+					Once()
+			},
+			wantErr: errors.New("err ScheduleRepayments"),
+		},
+		{
+			name: "payout failed, the loan is approved again",
+			mock: func() {
+				create()
+
+				repoMock.
+					On("Payout", mock.Anything, payoutReq).
+					Return(model.PayoutResult{Reference: "ref", Status: constant.DisbursementStatusFailed, FailureReason: "declined"}, nil).
+					Once()
+
+				settle(constant.DisbursementStatusFailed, "ref", "declined")
+
+				repoMock.
+					On("UpdateLoan", mock.Anything, &sql.Tx{}, model.Loan{Id: 1, Status: constant.LoanStatusApproved}).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("CommitTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+			},
+			want: func() model.Disbursement {
+				d := pending
+				d.ProviderReference = "ref"
+				d.Status = constant.DisbursementStatusFailed
+				d.FailureReason = "declined"
+				d.Attempts = 1
+				return d
+			}(),
+			wantCompleted: true,
+		},
+		{
+			name: "retry of a pending payout resubmits the same key",
+			mock: func() {
+				beginLocked(loan(constant.LoanStatusDisbursing), nil)
+
+				retried := pending
+				retried.Attempts = 1
+				repoMock.
+					On("GetActiveDisbursement", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(retried, nil).
+					Once()
+
+				repoMock.
+					On("CommitTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("Payout", mock.Anything, payoutReq).
+					Return(model.PayoutResult{Reference: "ref", Status: constant.DisbursementStatusPending}, nil).
+					Once()
+
+				settle(constant.DisbursementStatusPending, "ref", "")
+
+				repoMock.
+					On("CommitTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+			},
+			want: func() model.Disbursement {
+				d := pending
+				d.ProviderReference = "ref"
+				d.Attempts = 2
+				return d
+			}(),
+		},
+		{
+			name: "concurrent retry settled the payout first",
+			mock: func() {
+				create()
+
+				repoMock.
+					On("Payout", mock.Anything, payoutReq).
+					Return(model.PayoutResult{Reference: "ref", Status: constant.DisbursementStatusSucceeded}, nil).
+					Once()
+
+				beginLocked(loan(constant.LoanStatusDisbursed), nil)
+			},
+			want: func() model.Disbursement {
+				d := pending
+				d.ProviderReference = "ref"
+				d.Status = constant.DisbursementStatusSucceeded
+				d.Attempts = 1
+				return d
+			}(),
+			wantCompleted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		u := usecase{
+			repository: repoMock,
+			cfg:        testCfg,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			got, err := u.DisburseLoan(context.Background(), 1)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("DisburseLoan test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+			if (got.CompletedAt != nil) != tt.wantCompleted {
+				t.Errorf("DisburseLoan test failed. wantCompleted: %v, got: %v", tt.wantCompleted, got.CompletedAt)
+			}
+			got.CompletedAt = nil
+			got.CreatedAt = time.Time{}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DisburseLoan test failed. want: %+v, got: %+v", tt.want, got)
+			}
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_ReconcileDisbursements(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+
+	now := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	updatedBefore := now.Add(-10 * time.Minute)
+
+	borrowerId := int64(2)
+	amount := float64(1000)
+
+	// beginLocked mocks a transaction up to the locked loan read
+	beginLocked := func(loanId int64, err error) {
+		repoMock.
+			On("BeginTx", mock.Anything).
+			Return(&sql.Tx{}, nil).
+			Once()
+
+		repoMock.
+			On("RollbackTx", &sql.Tx{}).
+			Return(nil).
+			Once()
+
+		repoMock.
+			On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, loanId).
+			Return(model.Loan{Id: loanId, UserId: &borrowerId, Amount: &amount, Status: constant.LoanStatusDisbursing}, err).
+			Once()
+	}
+
+	// reconcile mocks the resubmitted payout of a DISBURSING loan and its stored answer
+	reconcile := func(loanId int64, status string) {
+		beginLocked(loanId, nil)
+
+		repoMock.
+			On("GetActiveDisbursement", mock.Anything, &sql.Tx{}, loanId).
+			Return(model.Disbursement{Id: loanId + 10, LoanId: loanId, Amount: 1000, IdempotencyKey: "key", Status: constant.DisbursementStatusPending, Attempts: 1}, nil).
+			Once()
+
+		repoMock.
+			On("CommitTx", &sql.Tx{}).
+			Return(nil).
+			Once()
+
+		repoMock.
+			On("Payout", mock.Anything, model.PayoutRequest{IdempotencyKey: "key", LoanId: loanId, UserId: 2, Amount: 1000}).
+			Return(model.PayoutResult{Reference: "ref", Status: status}, nil).
+			Once()
+
+		beginLocked(loanId, nil)
+
+		repoMock.
+			On("UpdateDisbursement", mock.Anything, &sql.Tx{}, mock.MatchedBy(func(d model.Disbursement) bool {
+				return d.Id == loanId+10 && d.Status == status && d.Attempts == 2
+			})).
+			Return(nil).
+			Once()
+
+		if status == constant.DisbursementStatusSucceeded {
+			repoMock.
+				On("UpdateLoan", mock.Anything, &sql.Tx{}, mock.MatchedBy(func(l model.Loan) bool {
+					return l.Id == loanId && l.Status == constant.LoanStatusDisbursed && l.DisbursedAt != nil
+				})).
+				Return(nil).
+				Once()
+
+			repoMock.
+				On("ScheduleRepayments", mock.Anything, &sql.Tx{}, loanId, mock.Anything).
+				Return(nil).
+				Once()
+		}
+
+		repoMock.
+			On("CommitTx", &sql.Tx{}).
+			Return(nil).
+			Once()
+	}
+
+	tests := []struct {
+		name    string
+		mock    func()
+		want    int
+		wantErr error
+	}{
+		{
+			name: "fail GetStaleDisbursements",
+			mock: func() {
+				repoMock.On("GetStaleDisbursements", mock.Anything, updatedBefore).Return(nil, errors.New("err GetStaleDisbursements")).Once()
+			},
+			wantErr: errors.New("err GetStaleDisbursements"),
+		},
+		{
+			name: "fail DisburseLoan does not stop the other loans",
+			mock: func() {
+				repoMock.On("GetStaleDisbursements", mock.Anything, updatedBefore).Return([]int64{1, 2}, nil).Once()
+
+				beginLocked(1, errors.New("err GetLoanForUpdate"))
+				reconcile(2, constant.DisbursementStatusSucceeded)
+			},
+			want:    1,
+			wantErr: errors.New("err GetLoanForUpdate"),
+		},
+		{
+			name: "success, a payout still pending is not settled",
+			mock: func() {
+				repoMock.On("GetStaleDisbursements", mock.Anything, updatedBefore).Return([]int64{1, 2}, nil).Once()
+
+				reconcile(1, constant.DisbursementStatusPending)
+				reconcile(2, constant.DisbursementStatusSucceeded)
+			},
+			want: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		u := usecase{
+			repository: repoMock,
+			cfg:        testCfg,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			got, err := u.ReconcileDisbursements(context.Background(), now)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("ReconcileDisbursements test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("ReconcileDisbursements test failed. want: %d, got: %d", tt.want, got)
+			}
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"example.com/m/v2/constant"
+	"example.com/m/v2/model"
+)
+
+// recordLoanEvent queues an event about a loan in the outbox within tx, it is published once tx commits and never
+// when it rolls back.
+func (u *usecase) recordLoanEvent(ctx context.Context, tx *sql.Tx, eventType string, loanId int64, data any) (err error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	_, err = u.repository.InsertOutboxEvent(ctx, tx, model.OutboxEvent{
+		AggregateType: constant.AggregateLoan,
+		AggregateId:   loanId,
+		Type:          eventType,
+		Payload:       payload,
+		CreatedAt:     time.Now(),
+	})
+
+	return
+}
@@ -2,22 +2,29 @@ package impl
 
 import (
 	"example.com/m/v2/config"
+	"example.com/m/v2/credential"
+	"example.com/m/v2/lockout"
 	r "example.com/m/v2/logic/repository"
 	u "example.com/m/v2/logic/usecase"
 )
 
 type usecase struct {
-	repository r.Repository
-	cfg        *config.Config
+	repository     r.Repository
+	cfg            *config.Config
+	passwordPolicy *credential.Policy
+	loginGuard     *lockout.Guard
 }
 
 func New(
 	repository r.Repository,
 	cfg *config.Config,
-
+	passwordPolicy *credential.Policy,
+	loginGuard *lockout.Guard,
 ) u.Usecase {
 	return &usecase{
-		repository: repository,
-		cfg:        cfg,
+		repository:     repository,
+		cfg:            cfg,
+		passwordPolicy: passwordPolicy,
+		loginGuard:     loginGuard,
 	}
 }
@@ -1,18 +1,118 @@
 package impl
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"testing"
+	"time"
 
 	"example.com/m/v2/config"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/credential"
+	"example.com/m/v2/lockout"
 	r "example.com/m/v2/logic/repository"
 	u "example.com/m/v2/logic/usecase"
+	"example.com/m/v2/model"
+	"github.com/stretchr/testify/mock"
 )
 
+var testPasswordPolicy = credential.NewPolicyFromList(10, 72, "password123")
+
+var testCfg = &config.Config{
+	Verification: config.Verification{
+		TokenTtl: time.Hour,
+		Url:      "https://tes.com/verify",
+	},
+	PasswordReset: config.PasswordReset{
+		TokenTtl: time.Hour,
+		Url:      "https://tes.com/reset",
+	},
+	Totp: config.Totp{
+		Issuer:       "tes",
+		ChallengeTtl: 5 * time.Minute,
+	},
+	Approval: config.Approval{
+		DualThreshold: 500,
+		Quorum:        2,
+		Ttl:           72 * time.Hour,
+	},
+	Payment: config.Payment{
+		WebhookSecret:    "tes",
+		WebhookTolerance: 5 * time.Minute,
+		IntentTtl:        time.Hour,
+	},
+	Overdue: config.Overdue{
+		GracePeriod:  72 * time.Hour,
+		FeeType:      "percent",
+		FeeAmount:    2,
+		FeeInterval:  7 * 24 * time.Hour,
+		FeeCap:       100,
+		DefaultAfter: 30 * 24 * time.Hour,
+	},
+	Jobs: config.Jobs{
+		PendingLoanTtl: 30 * 24 * time.Hour,
+		RemindBefore:   72 * time.Hour,
+		ReconcileAfter: 10 * time.Minute,
+	},
+	Notifications: config.Notifications{
+		Email: "console",
+		Sms:   "http",
+		Push:  "none",
+	},
+}
+
+// testNotificationChannels are the channels of testCfg.Notifications whose driver isn't none.
+var testNotificationChannels = []string{constant.NotificationChannelEmail, constant.NotificationChannelSms}
+
+var testLockoutCfg = config.Lockout{
+	AccountThreshold: 3,
+	IpThreshold:      5,
+	BaseDelay:        time.Minute,
+	MaxDelay:         time.Hour,
+	ResetAfter:       time.Hour,
+}
+
+// loanEvent matches the outbox event recorded about a loan whatever its creation time, payload is its JSON data.
+func loanEvent(eventType string, loanId int64, payload string) interface{} {
+	return mock.MatchedBy(func(e model.OutboxEvent) bool {
+		return e.AggregateType == constant.AggregateLoan && e.AggregateId == loanId && e.Type == eventType && string(e.Payload) == payload
+	})
+}
+
+func userNotification(userId int64, kind, data string) interface{} {
+	return mock.MatchedBy(func(n model.Notification) bool {
+		return n.UserId == userId && n.Kind == kind && string(n.Data) == data
+	})
+}
+
+// errLockoutStore fails every call, to check that logins fail closed.
+type errLockoutStore struct{}
+
+func (errLockoutStore) Get(ctx context.Context, key string) (lockout.State, error) {
+	return lockout.State{}, errors.New("err lockout store")
+}
+
+func (errLockoutStore) Fail(ctx context.Context, key string, now, resetBefore time.Time) (lockout.State, error) {
+	return lockout.State{}, errors.New("err lockout store")
+}
+
+func (errLockoutStore) Reset(ctx context.Context, key string) error {
+	return errors.New("err lockout store")
+}
+
+func (errLockoutStore) Prune(ctx context.Context, resetBefore time.Time) (int64, error) {
+	return 0, errors.New("err lockout store")
+}
+
 func Test_New(t *testing.T) {
+	testLoginGuard := lockout.New(lockout.NewMemoryStore(), testLockoutCfg)
+
 	type args struct {
-		repo *r.MockRepository
-		cfg  *config.Config
+		repo           *r.MockRepository
+		cfg            *config.Config
+		passwordPolicy *credential.Policy
+		loginGuard     *lockout.Guard
 	}
 	tests := []struct {
 		name string
@@ -22,19 +122,23 @@ func Test_New(t *testing.T) {
 		{
 			name: "success",
 			args: args{
-				repo: new(r.MockRepository),
-				cfg:  &config.Config{},
+				repo:           new(r.MockRepository),
+				cfg:            &config.Config{},
+				passwordPolicy: testPasswordPolicy,
+				loginGuard:     testLoginGuard,
 			},
 			want: &usecase{
-				repository: new(r.MockRepository),
-				cfg:        &config.Config{},
+				repository:     new(r.MockRepository),
+				cfg:            &config.Config{},
+				passwordPolicy: testPasswordPolicy,
+				loginGuard:     testLoginGuard,
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := New(tt.args.repo, tt.args.cfg); !reflect.DeepEqual(got, tt.want) {
+			if got := New(tt.args.repo, tt.args.cfg, tt.args.passwordPolicy, tt.args.loginGuard); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("fail New")
 			}
 		})
@@ -2,15 +2,35 @@ package impl
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"math"
 	"time"
 
+	"example.com/m/v2/apperror"
 	"example.com/m/v2/constant"
+	"example.com/m/v2/logger"
+	"example.com/m/v2/metrics"
 	"example.com/m/v2/model"
+	"example.com/m/v2/policy"
+	"example.com/m/v2/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// NewLoan creates a pending loan split into weekly terms, due dates are set once the loan is disbursed.
 func (u *usecase) NewLoan(ctx context.Context, amount float64, terms int, userId int64) (err error) {
+	ctx, span := tracing.Start(ctx, "usecase.NewLoan", attribute.Int64("user_id", userId), attribute.Int("terms", terms))
+	defer tracing.End(span, &err)
+
+	user, err := u.repository.GetUserById(ctx, userId)
+	if err != nil {
+		return
+	}
+	if !user.EmailVerified() {
+		err = apperror.ErrEmailNotVerified
+		return
+	}
+
 	tx, err := u.repository.BeginTx(ctx)
 	if err != nil {
 		return
@@ -46,7 +66,6 @@ func (u *usecase) NewLoan(ctx context.Context, amount float64, terms int, userId
 			LoanId:         loanId,
 			MinimumPayment: minimumPayment,
 			Status:         constant.RepaymentStatusPending,
-			DueDate:        time.Now().AddDate(0, 0, 7*i),
 		})
 		if errRepayment != nil {
 			err = errRepayment
@@ -59,37 +78,232 @@ func (u *usecase) NewLoan(ctx context.Context, amount float64, terms int, userId
 
 	}
 
+	event := model.LoanCreatedEvent{
+		LoanId: loanId,
+		UserId: userId,
+		Amount: amount,
+		Terms:  terms,
+	}
+	err = u.recordLoanEvent(ctx, tx, constant.EventLoanCreated, loanId, event)
+	if err != nil {
+		return
+	}
+
+	err = u.notifyUser(ctx, tx, userId, constant.NotificationLoanCreated, event)
+	if err != nil {
+		return
+	}
+
 	err = u.repository.CommitTx(tx)
+	if err != nil {
+		return
+	}
+
+	metrics.LoanCreated(amount)
+	logger.FromContext(ctx).InfoContext(ctx, "loan created", "loan_id", loanId, "user_id", userId, "terms", terms)
 	return
 }
 
-func (u *usecase) ApproveLoan(ctx context.Context, loanId int64) (err error) {
+// ApproveLoan records the approver's sign-off on a pending loan. Loans above approval.dual_threshold stay pending
+// until approval.quorum distinct approvers signed off within approval.ttl of each other, smaller loans are approved
+// at once. Borrowers never approve their own loans and every approver must be within their approval limit.
+func (u *usecase) ApproveLoan(ctx context.Context, loanId int64, approver model.Principal) (res model.ApprovalResult, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.ApproveLoan", attribute.Int64("loan_id", loanId), attribute.Int64("approver_id", approver.UserId))
+	defer tracing.End(span, &err)
+
 	tx, err := u.repository.BeginTx(ctx)
 	if err != nil {
 		return
 	}
 	defer u.repository.RollbackTx(tx)
 
-	err = u.repository.UpdateLoan(ctx, tx, model.Loan{
-		Id:     loanId,
-		Status: constant.LoanStatusApproved,
+	loan, err := u.repository.GetLoanForUpdate(ctx, tx, loanId)
+	if err != nil {
+		return
+	}
+	if loan.Status != constant.LoanStatusPending {
+		err = apperror.ErrLoanNotPending
+		return
+	}
+	if loan.UserId != nil && *loan.UserId == approver.UserId {
+		err = apperror.ErrSelfApproval
+		return
+	}
+
+	amount := float64(0)
+	if loan.Amount != nil {
+		amount = *loan.Amount
+	}
+	err = policy.AuthorizeApproval(approver, amount)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	err = u.repository.InsertLoanApproval(ctx, tx, model.LoanApproval{
+		LoanId:     loanId,
+		ApproverId: approver.UserId,
+		ApprovedAt: now,
+		ExpiresAt:  now.Add(u.cfg.Approval.Ttl),
 	})
 	if err != nil {
 		return
 	}
 
+	approvals, err := u.repository.CountLoanApprovals(ctx, tx, loanId, now)
+	if err != nil {
+		return
+	}
+
+	result := model.ApprovalResult{
+		LoanId:    loanId,
+		Status:    constant.LoanStatusPending,
+		Approvals: approvals,
+		Required:  u.requiredApprovals(amount),
+	}
+	if result.Approvals >= result.Required {
+		result.Status = constant.LoanStatusApproved
+		err = u.repository.UpdateLoan(ctx, tx, model.Loan{
+			Id:     loanId,
+			Status: constant.LoanStatusApproved,
+		})
+		if err != nil {
+			return
+		}
+
+		event := model.LoanApprovedEvent{
+			LoanId:    loanId,
+			Amount:    amount,
+			Approvals: approvals,
+		}
+		err = u.recordLoanEvent(ctx, tx, constant.EventLoanApproved, loanId, event)
+		if err != nil {
+			return
+		}
+
+		if loan.UserId != nil {
+			err = u.notifyUser(ctx, tx, *loan.UserId, constant.NotificationLoanApproved, event)
+			if err != nil {
+				return
+			}
+		}
+	}
+
 	err = u.repository.CommitTx(tx)
-	return
+	if err != nil {
+		return
+	}
+
+	log := logger.FromContext(ctx)
+	if result.Status == constant.LoanStatusApproved {
+		metrics.LoanApproved()
+		log.InfoContext(ctx, "loan approved", "loan_id", loanId, "approver_id", approver.UserId, "approvals", result.Approvals)
+	} else {
+		log.InfoContext(ctx, "loan approval recorded", "loan_id", loanId, "approver_id", approver.UserId, "approvals", result.Approvals, "required", result.Required)
+	}
+	return result, nil
+}
+
+// ExpirePendingLoans expires the loans left PENDING for longer than the pending loan ttl, they can't be approved
+// anymore and the borrower has to ask again.
+func (u *usecase) ExpirePendingLoans(ctx context.Context, now time.Time) (expired int, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.ExpirePendingLoans")
+	defer tracing.End(span, &err)
+
+	ids, err := u.repository.ExpirePendingLoans(ctx, now.Add(-u.cfg.Jobs.PendingLoanTtl), now)
+	if err != nil {
+		return
+	}
+
+	log := logger.FromContext(ctx)
+	for _, loanId := range ids {
+		log.InfoContext(ctx, "loan expired", "loan_id", loanId)
+	}
+	return len(ids), nil
+}
+
+// requiredApprovals is the quorum for loans above the dual approval threshold, one approver otherwise.
+func (u *usecase) requiredApprovals(amount float64) int {
+	if amount > u.cfg.Approval.DualThreshold {
+		return u.cfg.Approval.Quorum
+	}
+	return 1
+}
+
+// GetLoanApprovals lists every approval of a loan, expired ones included.
+func (u *usecase) GetLoanApprovals(ctx context.Context, loanId int64) (approvals []model.LoanApproval, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.GetLoanApprovals", attribute.Int64("loan_id", loanId))
+	defer tracing.End(span, &err)
+
+	_, err = u.repository.GetLoanById(ctx, loanId)
+	if err != nil {
+		return
+	}
+
+	return u.repository.GetLoanApprovals(ctx, loanId)
 }
 
+// PayLoan pays a term with the loan and its repayments locked, concurrent payments of a loan are checked and applied
+// one after the other so a term is paid once.
 func (u *usecase) PayLoan(ctx context.Context, amount float64, loanId, term, userId int64) (err error) {
-	loan, err := u.repository.GetLoanByIdAndUserId(ctx, loanId, userId)
+	ctx, span := tracing.Start(ctx, "usecase.PayLoan", attribute.Int64("loan_id", loanId), attribute.Int64("term", term), attribute.Int64("user_id", userId))
+	defer tracing.End(span, &err)
+
+	tx, err := u.repository.BeginTx(ctx)
+	if err != nil {
+		return
+	}
+	defer u.repository.RollbackTx(tx)
+
+	payment, err := u.lockPayment(ctx, tx, amount, loanId, term, userId)
+	if err != nil {
+		return
+	}
+
+	err = u.applyPayment(ctx, tx, payment)
+	if err != nil {
+		return
+	}
+
+	err = u.repository.CommitTx(tx)
 	if err != nil {
 		return
 	}
 
-	if loan.Status != constant.LoanStatusApproved {
-		return errors.New("loan not approved")
+	metrics.PaymentReceived(amount, payment.settlesLoan())
+	logger.FromContext(ctx).InfoContext(ctx, "loan payment received", "loan_id", loanId, "term", term, "user_id", userId)
+	return
+}
+
+// loanPayment is a payment of a term that passed checkPayment, ready to be applied.
+type loanPayment struct {
+	loanId     int64
+	userId     int64
+	loanAmount float64
+	repayments []model.Repayment
+	term       int64
+	amount     float64
+	// paid is the sum of the terms paid before this payment
+	paid float64
+	// fees is the sum of the late fees charged on the loan, they are owed on top of loanAmount
+	fees float64
+}
+
+// settlesLoan is true when the payment pays the rest of the loan, to the cent.
+func (p loanPayment) settlesLoan() bool {
+	return roundCents(p.paid+p.amount) == roundCents(p.loanAmount+p.fees)
+}
+
+// previewPayment checks a payment of the user's loan without locking nor writing anything, it is checked again by
+// lockPayment when it is applied.
+func (u *usecase) previewPayment(ctx context.Context, amount float64, loanId, term, userId int64) (payment loanPayment, err error) {
+	loan, err := u.repository.GetLoanByIdAndUserId(ctx, loanId, userId)
+	if err != nil {
+		return
+	}
+	if !payable(loan) {
+		err = apperror.ErrLoanNotDisbursed
+		return
 	}
 
 	repayments, err := u.repository.GetRepaymentByLoanId(ctx, loanId)
@@ -97,46 +311,100 @@ func (u *usecase) PayLoan(ctx context.Context, amount float64, loanId, term, use
 		return
 	}
 
+	return checkPayment(loan, repayments, amount, term, userId)
+}
+
+// lockPayment locks the user's loan and its repayments until tx ends and checks the payment against them.
+func (u *usecase) lockPayment(ctx context.Context, tx *sql.Tx, amount float64, loanId, term, userId int64) (payment loanPayment, err error) {
+	loan, err := u.repository.GetLoanForUpdate(ctx, tx, loanId)
+	if err != nil {
+		return
+	}
+	if loan.UserId == nil || *loan.UserId != userId {
+		err = apperror.ErrLoanNotFound
+		return
+	}
+	if !payable(loan) {
+		err = apperror.ErrLoanNotDisbursed
+		return
+	}
+
+	repayments, err := u.repository.GetRepaymentsForUpdate(ctx, tx, loanId)
+	if err != nil {
+		return
+	}
+
+	return checkPayment(loan, repayments, amount, term, userId)
+}
+
+// payable is true for the loans being repaid, disbursed or defaulted.
+func payable(loan model.Loan) bool {
+	return loan.Status == constant.LoanStatusDisbursed || loan.Status == constant.LoanStatusDefaulted
+}
+
+// checkPayment validates a payment of amount for a term of a payable loan: every earlier term must be paid, and
+// amount must reach the minimum payment of the terms up to this one, their late fees included, without exceeding the
+// loan and its fees.
+func checkPayment(loan model.Loan, repayments []model.Repayment, amount float64, term, userId int64) (payment loanPayment, err error) {
+	if term < 1 || term > int64(len(repayments)) {
+		err = apperror.ErrTermNotFound
+		return
+	}
+
 	for _, repayment := range repayments[:term-1] {
-		if repayment.Status == constant.RepaymentStatusPending {
-			return errors.New("there is a term before that has not been paid")
+		if repayment.Status != constant.RepaymentStatusPaid {
+			err = apperror.ErrPreviousTermUnpaid
+			return
 		}
 	}
 
-	repaymentData := repayments[term-1]
-	if repaymentData.Status == constant.RepaymentStatusPaid {
-		return errors.New("already paid for this term")
+	if repayments[term-1].Status == constant.RepaymentStatusPaid {
+		err = apperror.ErrTermAlreadyPaid
+		return
 	}
 
-	paid := float64(0)
+	paid, fees := float64(0), float64(0)
 	for _, repayment := range repayments {
 		if repayment.Status == constant.RepaymentStatusPaid && repayment.ActualPayment != nil {
 			paid += *repayment.ActualPayment
 		}
+		fees += repayment.LateFees
 	}
 
 	minimumPayment := float64(0)
 	for _, repayment := range repayments[0:term] {
-		minimumPayment += repayment.MinimumPayment
-	}
-
-	if paid+amount < minimumPayment {
-		return errors.New("minimum payment not reached")
+		minimumPayment += repayment.MinimumPayment + repayment.LateFees
 	}
 
-	if paid+amount > *loan.Amount {
-		return errors.New("paid more than loan")
+	// amounts are compared in cents, the sums of floats drift below them
+	if roundCents(paid+amount) < roundCents(minimumPayment) {
+		err = apperror.ErrMinimumPaymentNotReached
+		return
 	}
 
-	tx, err := u.repository.BeginTx(ctx)
-	if err != nil {
+	if roundCents(paid+amount) > roundCents(*loan.Amount+fees) {
+		err = apperror.ErrPaidMoreThanLoan
 		return
 	}
-	defer u.repository.RollbackTx(tx)
 
-	if paid+amount == *loan.Amount {
+	return loanPayment{
+		loanId:     loan.Id,
+		userId:     userId,
+		loanAmount: *loan.Amount,
+		repayments: repayments,
+		term:       term,
+		amount:     amount,
+		paid:       paid,
+		fees:       fees,
+	}, nil
+}
+
+// applyPayment marks the term paid in tx, paying the rest of the loan marks it PAID and releases the later terms.
+// The payment, and the loan being paid off, are recorded as events in tx and the borrower is notified of the payment.
+func (u *usecase) applyPayment(ctx context.Context, tx *sql.Tx, payment loanPayment) (err error) {
+	if payment.settlesLoan() {
 		err = u.repository.UpdateLoan(ctx, tx, model.Loan{
-			Id:     loanId,
+			Id:     payment.loanId,
 			Status: constant.LoanStatusPaid,
 		})
 		if err != nil {
@@ -144,8 +412,8 @@ func (u *usecase) PayLoan(ctx context.Context, amount float64, loanId, term, use
 		}
 
 		// release all remaining pending repayment if user already pay before last schedule
-		if int(term) < len(repayments) {
-			for _, repayment := range repayments[term:] {
+		if int(payment.term) < len(payment.repayments) {
+			for _, repayment := range payment.repayments[payment.term:] {
 				pay := float64(0)
 				err = u.repository.UpdateRepayment(ctx, tx, model.Repayment{
 					Id:            repayment.Id,
@@ -159,8 +427,9 @@ func (u *usecase) PayLoan(ctx context.Context, amount float64, loanId, term, use
 		}
 	}
 
+	amount := payment.amount
 	err = u.repository.UpdateRepayment(ctx, tx, model.Repayment{
-		Id:            repaymentData.Id,
+		Id:            payment.repayments[payment.term-1].Id,
 		Status:        constant.RepaymentStatusPaid,
 		ActualPayment: &amount,
 	})
@@ -168,12 +437,45 @@ func (u *usecase) PayLoan(ctx context.Context, amount float64, loanId, term, use
 		return
 	}
 
-	err = u.repository.CommitTx(tx)
+	event := model.LoanPaymentReceivedEvent{
+		LoanId: payment.loanId,
+		Term:   payment.term,
+		Amount: payment.amount,
+	}
+	err = u.recordLoanEvent(ctx, tx, constant.EventLoanPaymentReceived, payment.loanId, event)
+	if err != nil {
+		return
+	}
 
-	return
+	err = u.notifyUser(ctx, tx, payment.userId, constant.NotificationLoanPaymentReceived, event)
+	if err != nil || !payment.settlesLoan() {
+		return
+	}
+
+	return u.recordLoanEvent(ctx, tx, constant.EventLoanPaid, payment.loanId, model.LoanStatusEvent{LoanId: payment.loanId})
+}
+
+// RecordPayment pays a term on behalf of the borrower, for payments received outside the app.
+func (u *usecase) RecordPayment(ctx context.Context, amount float64, loanId, term int64) (err error) {
+	ctx, span := tracing.Start(ctx, "usecase.RecordPayment", attribute.Int64("loan_id", loanId), attribute.Int64("term", term))
+	defer tracing.End(span, &err)
+
+	loan, err := u.repository.GetLoanById(ctx, loanId)
+	if err != nil {
+		return
+	}
+	if loan.UserId == nil {
+		err = apperror.ErrLoanNotFound
+		return
+	}
+
+	return u.PayLoan(ctx, amount, loanId, term, *loan.UserId)
 }
 
 func (u *usecase) GetLoan(ctx context.Context, userId int64) (loans []model.Loan, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.GetLoan", attribute.Int64("user_id", userId))
+	defer tracing.End(span, &err)
+
 	loans, err = u.repository.GetLoanByUserId(ctx, userId)
 	if err != nil {
 		return
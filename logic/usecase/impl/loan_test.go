@@ -6,7 +6,9 @@ import (
 	"errors"
 	"reflect"
 	"testing"
+	"time"
 
+	"example.com/m/v2/apperror"
 	"example.com/m/v2/constant"
 	repo "example.com/m/v2/logic/repository"
 	"example.com/m/v2/model"
@@ -35,17 +37,50 @@ func Test_NewLoan(t *testing.T) {
 		Status: constant.LoanStatusPending,
 	}
 
+	verifiedAt := time.Now()
+	verifiedUser := model.User{
+		Id:              1,
+		EmailVerifiedAt: &verifiedAt,
+	}
+
 	tests := []struct {
 		name    string
 		mock    func()
 		args    args
 		wantErr error
 	}{
+		{
+			name: "user not found",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(model.User{}, apperror.ErrUserNotFound).
+					Once()
+			},
+			args:    req,
+			wantErr: apperror.ErrUserNotFound,
+		},
+		{
+			name: "email not verified",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(model.User{Id: 1}, nil).
+					Once()
+			},
+			args:    req,
+			wantErr: apperror.ErrEmailNotVerified,
+		},
 		{
 			name: "fail beginTx",
 			mock: func() {
 				repoMock.
-					On("BeginTx", context.Background()).
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(verifiedUser, nil).
+					Once()
+
+				repoMock.
+					On("BeginTx", mock.Anything).
 					Return(nil, errors.New("err beginTx")).
 					Once()
 			},
@@ -56,7 +91,12 @@ func Test_NewLoan(t *testing.T) {
 			name: "fail InsertLoan",
 			mock: func() {
 				repoMock.
-					On("BeginTx", context.Background()).
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(verifiedUser, nil).
+					Once()
+
+				repoMock.
+					On("BeginTx", mock.Anything).
 					Return(&sql.Tx{}, nil).
 					Once()
 
@@ -66,7 +106,7 @@ func Test_NewLoan(t *testing.T) {
 					Once()
 
 				repoMock.
-					On("InsertLoan", context.Background(), &sql.Tx{}, reqInsertLoan).
+					On("InsertLoan", mock.Anything, &sql.Tx{}, reqInsertLoan).
 					Return(int64(0), errors.New("err InsertLoan")).
 					Once()
 			},
@@ -76,9 +116,13 @@ func Test_NewLoan(t *testing.T) {
 		{
 			name: "loan not created",
 			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(verifiedUser, nil).
+					Once()
 
 				repoMock.
-					On("BeginTx", context.Background()).
+					On("BeginTx", mock.Anything).
 					Return(&sql.Tx{}, nil).
 					Once()
 
@@ -88,7 +132,7 @@ func Test_NewLoan(t *testing.T) {
 					Once()
 
 				repoMock.
-					On("InsertLoan", context.Background(), &sql.Tx{}, reqInsertLoan).
+					On("InsertLoan", mock.Anything, &sql.Tx{}, reqInsertLoan).
 					Return(int64(0), nil).
 					Once()
 			},
@@ -98,9 +142,13 @@ func Test_NewLoan(t *testing.T) {
 		{
 			name: "failed InsertRepayment",
 			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(verifiedUser, nil).
+					Once()
 
 				repoMock.
-					On("BeginTx", context.Background()).
+					On("BeginTx", mock.Anything).
 					Return(&sql.Tx{}, nil).
 					Once()
 
@@ -110,12 +158,12 @@ func Test_NewLoan(t *testing.T) {
 					Once()
 
 				repoMock.
-					On("InsertLoan", context.Background(), &sql.Tx{}, reqInsertLoan).
+					On("InsertLoan", mock.Anything, &sql.Tx{}, reqInsertLoan).
 					Return(int64(1), nil).
 					Once()
 
 				repoMock.
-					On("InsertRepayment", context.Background(), &sql.Tx{}, mock.Anything).
+					On("InsertRepayment", mock.Anything, &sql.Tx{}, mock.Anything).
 					Return(int64(0), errors.New("err InsertRepayment")).
 					Once()
 			},
@@ -125,9 +173,13 @@ func Test_NewLoan(t *testing.T) {
 		{
 			name: "repayment not created",
 			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(verifiedUser, nil).
+					Once()
 
 				repoMock.
-					On("BeginTx", context.Background()).
+					On("BeginTx", mock.Anything).
 					Return(&sql.Tx{}, nil).
 					Once()
 
@@ -137,24 +189,64 @@ func Test_NewLoan(t *testing.T) {
 					Once()
 
 				repoMock.
-					On("InsertLoan", context.Background(), &sql.Tx{}, reqInsertLoan).
+					On("InsertLoan", mock.Anything, &sql.Tx{}, reqInsertLoan).
 					Return(int64(1), nil).
 					Once()
 
 				repoMock.
-					On("InsertRepayment", context.Background(), &sql.Tx{}, mock.Anything).
+					On("InsertRepayment", mock.Anything, &sql.Tx{}, mock.Anything).
 					Return(int64(0), nil).
 					Once()
 			},
 			args:    req,
 			wantErr: errors.New("failed create repayment"),
 		},
+		{
+			name: "fail InsertOutboxEvent",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(verifiedUser, nil).
+					Once()
+
+				repoMock.
+					On("BeginTx", mock.Anything).
+					Return(&sql.Tx{}, nil).
+					Once()
+
+				repoMock.
+					On("RollbackTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("InsertLoan", mock.Anything, &sql.Tx{}, reqInsertLoan).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("InsertRepayment", mock.Anything, &sql.Tx{}, mock.Anything).
+					Return(int64(1), nil).
+					Times(3)
+
+				repoMock.
+					On("InsertOutboxEvent", mock.Anything, &sql.Tx{}, mock.Anything).
+					Return(int64(0), errors.New("err InsertOutboxEvent")).
+					Once()
+			},
+			args:    req,
+			wantErr: errors.New("err InsertOutboxEvent"),
+		},
 		{
 			name: "fail CommitTx",
 			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(verifiedUser, nil).
+					Once()
 
 				repoMock.
-					On("BeginTx", context.Background()).
+					On("BeginTx", mock.Anything).
 					Return(&sql.Tx{}, nil).
 					Once()
 
@@ -164,15 +256,25 @@ func Test_NewLoan(t *testing.T) {
 					Once()
 
 				repoMock.
-					On("InsertLoan", context.Background(), &sql.Tx{}, reqInsertLoan).
+					On("InsertLoan", mock.Anything, &sql.Tx{}, reqInsertLoan).
 					Return(int64(1), nil).
 					Once()
 
 				repoMock.
-					On("InsertRepayment", context.Background(), &sql.Tx{}, mock.Anything).
+					On("InsertRepayment", mock.Anything, &sql.Tx{}, mock.Anything).
 					Return(int64(1), nil).
 					Times(3)
 
+				repoMock.
+					On("InsertOutboxEvent", mock.Anything, &sql.Tx{}, loanEvent(constant.EventLoanCreated, 1, `{"loan_id":1,"user_id":1,"amount":10000,"terms":3}`)).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("InsertNotifications", mock.Anything, &sql.Tx{}, userNotification(int64(1), constant.NotificationLoanCreated, `{"loan_id":1,"user_id":1,"amount":10000,"terms":3}`), testNotificationChannels).
+					Return(int64(1), nil).
+					Once()
+
 				repoMock.
 					On("CommitTx", &sql.Tx{}).
 					Return(errors.New("err CommitTx")).
@@ -184,9 +286,13 @@ func Test_NewLoan(t *testing.T) {
 		{
 			name: "success",
 			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(verifiedUser, nil).
+					Once()
 
 				repoMock.
-					On("BeginTx", context.Background()).
+					On("BeginTx", mock.Anything).
 					Return(&sql.Tx{}, nil).
 					Once()
 
@@ -196,15 +302,25 @@ func Test_NewLoan(t *testing.T) {
 					Once()
 
 				repoMock.
-					On("InsertLoan", context.Background(), &sql.Tx{}, reqInsertLoan).
+					On("InsertLoan", mock.Anything, &sql.Tx{}, reqInsertLoan).
 					Return(int64(1), nil).
 					Once()
 
 				repoMock.
-					On("InsertRepayment", context.Background(), &sql.Tx{}, mock.Anything).
+					On("InsertRepayment", mock.Anything, &sql.Tx{}, mock.Anything).
 					Return(int64(1), nil).
 					Times(3)
 
+				repoMock.
+					On("InsertOutboxEvent", mock.Anything, &sql.Tx{}, loanEvent(constant.EventLoanCreated, 1, `{"loan_id":1,"user_id":1,"amount":10000,"terms":3}`)).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("InsertNotifications", mock.Anything, &sql.Tx{}, userNotification(int64(1), constant.NotificationLoanCreated, `{"loan_id":1,"user_id":1,"amount":10000,"terms":3}`), testNotificationChannels).
+					Return(int64(1), nil).
+					Once()
+
 				repoMock.
 					On("CommitTx", &sql.Tx{}).
 					Return(nil).
@@ -217,6 +333,7 @@ func Test_NewLoan(t *testing.T) {
 	for _, tt := range tests {
 		u := usecase{
 			repository: repoMock,
+			cfg:        testCfg,
 		}
 
 		t.Run(tt.name, func(t *testing.T) {
@@ -225,7 +342,7 @@ func Test_NewLoan(t *testing.T) {
 			}
 
 			err := u.NewLoan(context.Background(), tt.args.amount, tt.args.terms, tt.args.userId)
-			if !util.SameErrorMessage(err, tt.wantErr) {
+			if !util.SameError(err, tt.wantErr) {
 				t.Errorf("NewLoan test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
 			}
 		})
@@ -235,12 +352,52 @@ func Test_NewLoan(t *testing.T) {
 func Test_ApproveLoan(t *testing.T) {
 	repoMock := new(repo.MockRepository)
 
-	type args struct {
-		loanId int64
+	officerLimit := float64(1000)
+	approver := model.Principal{
+		UserId: 9,
+		Roles: []model.Role{{
+			Name:          constant.RoleLoanOfficer,
+			Permissions:   []string{constant.PermissionLoanApprove},
+			ApprovalLimit: &officerLimit,
+		}},
 	}
 
-	req := args{
-		loanId: 1,
+	borrowerId := int64(2)
+	pendingLoan := func(amount float64) model.Loan {
+		return model.Loan{Id: 1, UserId: &borrowerId, Amount: &amount, Status: constant.LoanStatusPending}
+	}
+
+	// beginLocked mocks the transaction up to the locked loan read
+	beginLocked := func(loan model.Loan, err error) {
+		repoMock.
+			On("BeginTx", mock.Anything).
+			Return(&sql.Tx{}, nil).
+			Once()
+
+		repoMock.
+			On("RollbackTx", &sql.Tx{}).
+			Return(nil).
+			Once()
+
+		repoMock.
+			On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+			Return(loan, err).
+			Once()
+	}
+
+	// recordApproval mocks the insert of the approval and the count that follows it
+	recordApproval := func(approvals int) {
+		repoMock.
+			On("InsertLoanApproval", mock.Anything, &sql.Tx{}, mock.MatchedBy(func(approval model.LoanApproval) bool {
+				return approval.LoanId == 1 && approval.ApproverId == 9 && approval.ExpiresAt.Sub(approval.ApprovedAt) == 72*time.Hour
+			})).
+			Return(nil).
+			Once()
+
+		repoMock.
+			On("CountLoanApprovals", mock.Anything, &sql.Tx{}, int64(1), mock.Anything).
+			Return(approvals, nil).
+			Once()
 	}
 
 	reqUpdateLoan := model.Loan{
@@ -249,87 +406,179 @@ func Test_ApproveLoan(t *testing.T) {
 	}
 
 	tests := []struct {
-		name    string
-		mock    func()
-		args    args
-		wantErr error
+		name     string
+		mock     func()
+		approver model.Principal
+		want     model.ApprovalResult
+		wantErr  error
 	}{
 		{
 			name: "fail beginTx",
 			mock: func() {
 				repoMock.
-					On("BeginTx", context.Background()).
+					On("BeginTx", mock.Anything).
 					Return(nil, errors.New("err beginTx")).
 					Once()
 			},
-			args:    req,
-			wantErr: errors.New("err beginTx"),
+			approver: approver,
+			wantErr:  errors.New("err beginTx"),
+		},
+		{
+			name: "loan not found",
+			mock: func() {
+				beginLocked(model.Loan{}, apperror.ErrLoanNotFound)
+			},
+			approver: approver,
+			wantErr:  apperror.ErrLoanNotFound,
+		},
+		{
+			name: "loan already approved",
+			mock: func() {
+				loan := pendingLoan(400)
+				loan.Status = constant.LoanStatusApproved
+				beginLocked(loan, nil)
+			},
+			approver: approver,
+			wantErr:  apperror.ErrLoanNotPending,
+		},
+		{
+			name: "borrower approving their own loan",
+			mock: func() {
+				beginLocked(pendingLoan(400), nil)
+			},
+			approver: model.Principal{UserId: borrowerId, Roles: approver.Roles},
+			wantErr:  apperror.ErrSelfApproval,
+		},
+		{
+			name: "above the approval limit",
+			mock: func() {
+				beginLocked(pendingLoan(1000.5), nil)
+			},
+			approver: approver,
+			wantErr:  apperror.ErrApprovalLimitExceeded,
+		},
+		{
+			name: "approver already signed off",
+			mock: func() {
+				beginLocked(pendingLoan(800), nil)
+
+				repoMock.
+					On("InsertLoanApproval", mock.Anything, &sql.Tx{}, mock.Anything).
+					Return(apperror.ErrAlreadyApproved).
+					Once()
+			},
+			approver: approver,
+			wantErr:  apperror.ErrAlreadyApproved,
 		},
 		{
 			name: "fail UpdateLoan",
 			mock: func() {
+				beginLocked(pendingLoan(400), nil)
+				recordApproval(1)
+
 				repoMock.
-					On("BeginTx", context.Background()).
-					Return(&sql.Tx{}, nil).
+					On("UpdateLoan", mock.Anything, &sql.Tx{}, reqUpdateLoan).
+					Return(errors.New("err UpdateLoan")).
 					Once()
+			},
+			approver: approver,
+			wantErr:  errors.New("err UpdateLoan"),
+		},
+		{
+			name: "fail InsertOutboxEvent",
+			mock: func() {
+				beginLocked(pendingLoan(400), nil)
+				recordApproval(1)
 
 				repoMock.
-					On("RollbackTx", &sql.Tx{}).
+					On("UpdateLoan", mock.Anything, &sql.Tx{}, reqUpdateLoan).
 					Return(nil).
 					Once()
 
 				repoMock.
-					On("UpdateLoan", context.Background(), &sql.Tx{}, reqUpdateLoan).
-					Return(errors.New("err UpdateLoan")).
+					On("InsertOutboxEvent", mock.Anything, &sql.Tx{}, mock.Anything).
+					Return(int64(0), errors.New("err InsertOutboxEvent")).
 					Once()
 			},
-			args:    req,
-			wantErr: errors.New("err UpdateLoan"),
+			approver: approver,
+			wantErr:  errors.New("err InsertOutboxEvent"),
 		},
 		{
 			name: "fail CommitTx",
 			mock: func() {
+				beginLocked(pendingLoan(800), nil)
+				recordApproval(1)
 
 				repoMock.
-					On("BeginTx", context.Background()).
-					Return(&sql.Tx{}, nil).
+					On("CommitTx", &sql.Tx{}).
+					Return(errors.New("err CommitTx")).
 					Once()
+			},
+			approver: approver,
+			wantErr:  errors.New("err CommitTx"),
+		},
+		{
+			name: "small loan approved by a single approver",
+			mock: func() {
+				beginLocked(pendingLoan(400), nil)
+				recordApproval(1)
 
 				repoMock.
-					On("RollbackTx", &sql.Tx{}).
+					On("UpdateLoan", mock.Anything, &sql.Tx{}, reqUpdateLoan).
 					Return(nil).
 					Once()
 
 				repoMock.
-					On("UpdateLoan", context.Background(), &sql.Tx{}, reqUpdateLoan).
-					Return(nil).
+					On("InsertOutboxEvent", mock.Anything, &sql.Tx{}, loanEvent(constant.EventLoanApproved, 1, `{"loan_id":1,"amount":400,"approvals":1}`)).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("InsertNotifications", mock.Anything, &sql.Tx{}, userNotification(borrowerId, constant.NotificationLoanApproved, `{"loan_id":1,"amount":400,"approvals":1}`), testNotificationChannels).
+					Return(int64(1), nil).
 					Once()
 
 				repoMock.
 					On("CommitTx", &sql.Tx{}).
-					Return(errors.New("err CommitTx")).
+					Return(nil).
 					Once()
 			},
-			args:    req,
-			wantErr: errors.New("err CommitTx"),
+			approver: approver,
+			want:     model.ApprovalResult{LoanId: 1, Status: constant.LoanStatusApproved, Approvals: 1, Required: 1},
 		},
 		{
-			name: "success",
+			name: "large loan stays pending after the first approval",
 			mock: func() {
+				beginLocked(pendingLoan(800), nil)
+				recordApproval(1)
 
 				repoMock.
-					On("BeginTx", context.Background()).
-					Return(&sql.Tx{}, nil).
+					On("CommitTx", &sql.Tx{}).
+					Return(nil).
 					Once()
+			},
+			approver: approver,
+			want:     model.ApprovalResult{LoanId: 1, Status: constant.LoanStatusPending, Approvals: 1, Required: 2},
+		},
+		{
+			name: "large loan approved once the quorum is met",
+			mock: func() {
+				beginLocked(pendingLoan(800), nil)
+				recordApproval(2)
 
 				repoMock.
-					On("RollbackTx", &sql.Tx{}).
+					On("UpdateLoan", mock.Anything, &sql.Tx{}, reqUpdateLoan).
 					Return(nil).
 					Once()
 
 				repoMock.
-					On("UpdateLoan", context.Background(), &sql.Tx{}, reqUpdateLoan).
-					Return(nil).
+					On("InsertOutboxEvent", mock.Anything, &sql.Tx{}, loanEvent(constant.EventLoanApproved, 1, `{"loan_id":1,"amount":800,"approvals":2}`)).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("InsertNotifications", mock.Anything, &sql.Tx{}, userNotification(borrowerId, constant.NotificationLoanApproved, `{"loan_id":1,"amount":800,"approvals":2}`), testNotificationChannels).
+					Return(int64(1), nil).
 					Once()
 
 				repoMock.
@@ -337,13 +586,15 @@ func Test_ApproveLoan(t *testing.T) {
 					Return(nil).
 					Once()
 			},
-			args: req,
+			approver: approver,
+			want:     model.ApprovalResult{LoanId: 1, Status: constant.LoanStatusApproved, Approvals: 2, Required: 2},
 		},
 	}
 
 	for _, tt := range tests {
 		u := usecase{
 			repository: repoMock,
+			cfg:        testCfg,
 		}
 
 		t.Run(tt.name, func(t *testing.T) {
@@ -351,10 +602,14 @@ func Test_ApproveLoan(t *testing.T) {
 				tt.mock()
 			}
 
-			err := u.ApproveLoan(context.Background(), tt.args.loanId)
-			if !util.SameErrorMessage(err, tt.wantErr) {
+			got, err := u.ApproveLoan(context.Background(), 1, tt.approver)
+			if !util.SameError(err, tt.wantErr) {
 				t.Errorf("ApproveLoan test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
 			}
+			if got != tt.want {
+				t.Errorf("ApproveLoan test failed. want: %+v, got: %+v", tt.want, got)
+			}
+			repoMock.AssertExpectations(t)
 		})
 	}
 }
@@ -377,13 +632,16 @@ func Test_PayLoan(t *testing.T) {
 	}
 
 	amt := float64(10000)
-	getLoanByIdAndUserIdRes := model.Loan{
-		Status: constant.LoanStatusApproved,
+	borrower := int64(1)
+	getLoanForUpdateRes := model.Loan{
+		Id:     1,
+		UserId: &borrower,
+		Status: constant.LoanStatusDisbursed,
 		Amount: &amt,
 	}
 
 	actualPay := float64(3333.33)
-	GetRepaymentByLoanIdRes := []model.Repayment{
+	getRepaymentsForUpdateRes := []model.Repayment{
 		{
 			Id:             1,
 			Status:         constant.RepaymentStatusPaid,
@@ -397,6 +655,19 @@ func Test_PayLoan(t *testing.T) {
 		},
 	}
 
+	// begin mocks the transaction the loan is locked in
+	begin := func() {
+		repoMock.
+			On("BeginTx", mock.Anything).
+			Return(&sql.Tx{}, nil).
+			Once()
+
+		repoMock.
+			On("RollbackTx", &sql.Tx{}).
+			Return(nil).
+			Once()
+	}
+
 	tests := []struct {
 		name    string
 		mock    func()
@@ -404,53 +675,97 @@ func Test_PayLoan(t *testing.T) {
 		wantErr error
 	}{
 		{
-			name: "fail GetLoanByIdAndUserId",
+			name: "fail GetLoanForUpdate",
+			mock: func() {
+				begin()
+
+				repoMock.
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(model.Loan{}, errors.New("err GetLoanForUpdate")).
+					Once()
+			},
+			args:    req,
+			wantErr: errors.New("err GetLoanForUpdate"),
+		},
+		{
+			name: "loan of another user",
 			mock: func() {
+				begin()
+
+				other := int64(2)
 				repoMock.
-					On("GetLoanByIdAndUserId", context.Background(), int64(1), int64(1)).
-					Return(model.Loan{}, errors.New("err GetLoanByIdAndUserId")).
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(model.Loan{Id: 1, UserId: &other, Status: constant.LoanStatusDisbursed, Amount: &amt}, nil).
 					Once()
 			},
 			args:    req,
-			wantErr: errors.New("err GetLoanByIdAndUserId"),
+			wantErr: apperror.ErrLoanNotFound,
 		},
 		{
-			name: "loan not approved",
+			name: "loan not disbursed",
 			mock: func() {
+				begin()
+
 				repoMock.
-					On("GetLoanByIdAndUserId", context.Background(), int64(1), int64(1)).
-					Return(model.Loan{}, nil).
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(model.Loan{Id: 1, UserId: &borrower, Status: constant.LoanStatusApproved}, nil).
 					Once()
 			},
 			args:    req,
-			wantErr: errors.New("loan not approved"),
+			wantErr: apperror.ErrLoanNotDisbursed,
 		},
 		{
-			name: "err GetRepaymentByLoanId",
+			name: "err GetRepaymentsForUpdate",
 			mock: func() {
+				begin()
+
 				repoMock.
-					On("GetLoanByIdAndUserId", context.Background(), int64(1), int64(1)).
-					Return(getLoanByIdAndUserIdRes, nil).
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(getLoanForUpdateRes, nil).
 					Once()
 
 				repoMock.
-					On("GetRepaymentByLoanId", context.Background(), int64(1)).
-					Return(nil, errors.New("err GetRepaymentByLoanId")).
+					On("GetRepaymentsForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(nil, errors.New("err GetRepaymentsForUpdate")).
 					Once()
 			},
 			args:    req,
-			wantErr: errors.New("err GetRepaymentByLoanId"),
+			wantErr: errors.New("err GetRepaymentsForUpdate"),
+		},
+		{
+			name: "term beyond the last repayment",
+			mock: func() {
+				begin()
+
+				repoMock.
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(getLoanForUpdateRes, nil).
+					Once()
+
+				repoMock.
+					On("GetRepaymentsForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return([]model.Repayment{
+						{
+							Status: constant.RepaymentStatusPending,
+						},
+					}, nil).
+					Once()
+			},
+			args:    req,
+			wantErr: apperror.ErrTermNotFound,
 		},
 		{
 			name: "a term before that has not been paid",
 			mock: func() {
+				begin()
+
 				repoMock.
-					On("GetLoanByIdAndUserId", context.Background(), int64(1), int64(1)).
-					Return(getLoanByIdAndUserIdRes, nil).
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(getLoanForUpdateRes, nil).
 					Once()
 
 				repoMock.
-					On("GetRepaymentByLoanId", context.Background(), int64(1)).
+					On("GetRepaymentsForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
 					Return([]model.Repayment{
 						{
 							Status: constant.RepaymentStatusPending,
@@ -462,18 +777,20 @@ func Test_PayLoan(t *testing.T) {
 					Once()
 			},
 			args:    req,
-			wantErr: errors.New("there is a term before that has not been paid"),
+			wantErr: apperror.ErrPreviousTermUnpaid,
 		},
 		{
 			name: "already paid for this term",
 			mock: func() {
+				begin()
+
 				repoMock.
-					On("GetLoanByIdAndUserId", context.Background(), int64(1), int64(1)).
-					Return(getLoanByIdAndUserIdRes, nil).
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(getLoanForUpdateRes, nil).
 					Once()
 
 				repoMock.
-					On("GetRepaymentByLoanId", context.Background(), int64(1)).
+					On("GetRepaymentsForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
 					Return([]model.Repayment{
 						{
 							Status: constant.RepaymentStatusPaid,
@@ -485,19 +802,21 @@ func Test_PayLoan(t *testing.T) {
 					Once()
 			},
 			args:    req,
-			wantErr: errors.New("already paid for this term"),
+			wantErr: apperror.ErrTermAlreadyPaid,
 		},
 		{
 			name: "minimum payment not reached",
 			mock: func() {
+				begin()
+
 				repoMock.
-					On("GetLoanByIdAndUserId", context.Background(), int64(1), int64(1)).
-					Return(getLoanByIdAndUserIdRes, nil).
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(getLoanForUpdateRes, nil).
 					Once()
 
 				actualPay := 3333.33
 				repoMock.
-					On("GetRepaymentByLoanId", context.Background(), int64(1)).
+					On("GetRepaymentsForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
 					Return([]model.Repayment{
 						{
 							Status:         constant.RepaymentStatusPaid,
@@ -517,19 +836,21 @@ func Test_PayLoan(t *testing.T) {
 				term:   2,
 				userId: 1,
 			},
-			wantErr: errors.New("minimum payment not reached"),
+			wantErr: apperror.ErrMinimumPaymentNotReached,
 		},
 		{
 			name: "paid more than loan",
 			mock: func() {
+				begin()
+
 				repoMock.
-					On("GetLoanByIdAndUserId", context.Background(), int64(1), int64(1)).
-					Return(getLoanByIdAndUserIdRes, nil).
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(getLoanForUpdateRes, nil).
 					Once()
 
 				actualPay := float64(8000)
 				repoMock.
-					On("GetRepaymentByLoanId", context.Background(), int64(1)).
+					On("GetRepaymentsForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
 					Return([]model.Repayment{
 						{
 							Status:         constant.RepaymentStatusPaid,
@@ -549,53 +870,237 @@ func Test_PayLoan(t *testing.T) {
 				term:   2,
 				userId: 1,
 			},
-			wantErr: errors.New("paid more than loan"),
+			wantErr: apperror.ErrPaidMoreThanLoan,
 		},
 		{
-			name: "fail beginTx",
+			name: "late fees not covered by the minimum payment",
 			mock: func() {
+				begin()
+
 				repoMock.
-					On("GetLoanByIdAndUserId", context.Background(), int64(1), int64(1)).
-					Return(getLoanByIdAndUserIdRes, nil).
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(getLoanForUpdateRes, nil).
 					Once()
 
+				actualPay := 3333.33
 				repoMock.
-					On("GetRepaymentByLoanId", context.Background(), int64(1)).
-					Return(GetRepaymentByLoanIdRes, nil).
+					On("GetRepaymentsForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return([]model.Repayment{
+						{
+							Status:         constant.RepaymentStatusPaid,
+							MinimumPayment: 3333.33,
+							ActualPayment:  &actualPay,
+						},
+						{
+							Status:         constant.RepaymentStatusOverdue,
+							MinimumPayment: 3333.33,
+							LateFees:       100,
+						},
+					}, nil).
 					Once()
+			},
+			args: args{
+				loanId: 1,
+				amount: 3400,
+				term:   2,
+				userId: 1,
+			},
+			wantErr: apperror.ErrMinimumPaymentNotReached,
+		},
+		{
+			name: "an overdue term before that has not been paid",
+			mock: func() {
+				begin()
+
 				repoMock.
-					On("BeginTx", context.Background()).
-					Return(nil, errors.New("err beginTx")).
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(getLoanForUpdateRes, nil).
+					Once()
+
+				repoMock.
+					On("GetRepaymentsForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return([]model.Repayment{
+						{
+							Status: constant.RepaymentStatusOverdue,
+						},
+						{
+							Status: constant.RepaymentStatusPending,
+						},
+					}, nil).
 					Once()
 			},
 			args:    req,
-			wantErr: errors.New("err beginTx"),
+			wantErr: apperror.ErrPreviousTermUnpaid,
 		},
 		{
-			name: "fail UpdateLoan",
+			name: "defaulted loan settled with its late fees",
 			mock: func() {
+				begin()
+
 				repoMock.
-					On("GetLoanByIdAndUserId", context.Background(), int64(1), int64(1)).
-					Return(getLoanByIdAndUserIdRes, nil).
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(model.Loan{Id: 1, UserId: &borrower, Status: constant.LoanStatusDefaulted, Amount: &amt}, nil).
 					Once()
 
+				actualPay := float64(5000)
 				repoMock.
-					On("GetRepaymentByLoanId", context.Background(), int64(1)).
-					Return(GetRepaymentByLoanIdRes, nil).
+					On("GetRepaymentsForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return([]model.Repayment{
+						{
+							Id:             1,
+							Status:         constant.RepaymentStatusPaid,
+							MinimumPayment: 5000,
+							ActualPayment:  &actualPay,
+						},
+						{
+							Id:             2,
+							Status:         constant.RepaymentStatusOverdue,
+							MinimumPayment: 5000,
+							LateFees:       250,
+						},
+					}, nil).
 					Once()
 
 				repoMock.
-					On("BeginTx", context.Background()).
-					Return(&sql.Tx{}, nil).
+					On("UpdateLoan", mock.Anything, &sql.Tx{}, model.Loan{
+						Id:     1,
+						Status: constant.LoanStatusPaid,
+					}).
+					Return(nil).
 					Once()
 
+				temp := float64(5250)
 				repoMock.
-					On("RollbackTx", &sql.Tx{}).
+					On("UpdateRepayment", mock.Anything, &sql.Tx{}, model.Repayment{
+						Id:            2,
+						Status:        constant.RepaymentStatusPaid,
+						ActualPayment: &temp,
+					}).
 					Return(nil).
 					Once()
 
 				repoMock.
-					On("UpdateLoan", context.Background(), &sql.Tx{}, model.Loan{
+					On("InsertOutboxEvent", mock.Anything, &sql.Tx{}, loanEvent(constant.EventLoanPaymentReceived, 1, `{"loan_id":1,"term":2,"amount":5250}`)).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("InsertNotifications", mock.Anything, &sql.Tx{}, userNotification(int64(1), constant.NotificationLoanPaymentReceived, `{"loan_id":1,"term":2,"amount":5250}`), testNotificationChannels).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("InsertOutboxEvent", mock.Anything, &sql.Tx{}, loanEvent(constant.EventLoanPaid, 1, `{"loan_id":1}`)).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("CommitTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+			},
+			args: args{
+				loanId: 1,
+				amount: 5250,
+				term:   2,
+				userId: 1,
+			},
+		},
+		{
+			name: "last term settles the loan to the cent",
+			mock: func() {
+				begin()
+
+				amount := 3000.6
+				repoMock.
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(model.Loan{Id: 1, UserId: &borrower, Status: constant.LoanStatusDisbursed, Amount: &amount}, nil).
+					Once()
+
+				// 1000.1 + 1000.2 + 1000.3 is not 3000.6 in floats
+				first, second := 1000.1, 1000.2
+				repoMock.
+					On("GetRepaymentsForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return([]model.Repayment{
+						{Id: 1, Status: constant.RepaymentStatusPaid, MinimumPayment: 1000.1, ActualPayment: &first},
+						{Id: 2, Status: constant.RepaymentStatusPaid, MinimumPayment: 1000.2, ActualPayment: &second},
+						{Id: 3, Status: constant.RepaymentStatusPending, MinimumPayment: 1000.3},
+					}, nil).
+					Once()
+
+				repoMock.
+					On("UpdateLoan", mock.Anything, &sql.Tx{}, model.Loan{
+						Id:     1,
+						Status: constant.LoanStatusPaid,
+					}).
+					Return(nil).
+					Once()
+
+				temp := 1000.3
+				repoMock.
+					On("UpdateRepayment", mock.Anything, &sql.Tx{}, model.Repayment{
+						Id:            3,
+						Status:        constant.RepaymentStatusPaid,
+						ActualPayment: &temp,
+					}).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("InsertOutboxEvent", mock.Anything, &sql.Tx{}, loanEvent(constant.EventLoanPaymentReceived, 1, `{"loan_id":1,"term":3,"amount":1000.3}`)).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("InsertNotifications", mock.Anything, &sql.Tx{}, userNotification(int64(1), constant.NotificationLoanPaymentReceived, `{"loan_id":1,"term":3,"amount":1000.3}`), testNotificationChannels).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("InsertOutboxEvent", mock.Anything, &sql.Tx{}, loanEvent(constant.EventLoanPaid, 1, `{"loan_id":1}`)).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("CommitTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+			},
+			args: args{
+				loanId: 1,
+				amount: 1000.3,
+				term:   3,
+				userId: 1,
+			},
+		},
+		{
+			name: "fail beginTx",
+			mock: func() {
+				repoMock.
+					On("BeginTx", mock.Anything).
+					Return(nil, errors.New("err beginTx")).
+					Once()
+			},
+			args:    req,
+			wantErr: errors.New("err beginTx"),
+		},
+		{
+			name: "fail UpdateLoan",
+			mock: func() {
+				begin()
+
+				repoMock.
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(getLoanForUpdateRes, nil).
+					Once()
+
+				repoMock.
+					On("GetRepaymentsForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(getRepaymentsForUpdateRes, nil).
+					Once()
+
+				repoMock.
+					On("UpdateLoan", mock.Anything, &sql.Tx{}, model.Loan{
 						Id:     1,
 						Status: constant.LoanStatusPaid,
 					}).
@@ -613,13 +1118,15 @@ func Test_PayLoan(t *testing.T) {
 		{
 			name: "fail UpdateRepayment for remaining repayment",
 			mock: func() {
+				begin()
+
 				repoMock.
-					On("GetLoanByIdAndUserId", context.Background(), int64(1), int64(1)).
-					Return(getLoanByIdAndUserIdRes, nil).
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(getLoanForUpdateRes, nil).
 					Once()
 
 				repoMock.
-					On("GetRepaymentByLoanId", context.Background(), int64(1)).
+					On("GetRepaymentsForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
 					Return([]model.Repayment{
 						{
 							Id:             1,
@@ -641,17 +1148,7 @@ func Test_PayLoan(t *testing.T) {
 					Once()
 
 				repoMock.
-					On("BeginTx", context.Background()).
-					Return(&sql.Tx{}, nil).
-					Once()
-
-				repoMock.
-					On("RollbackTx", &sql.Tx{}).
-					Return(nil).
-					Once()
-
-				repoMock.
-					On("UpdateLoan", context.Background(), &sql.Tx{}, model.Loan{
+					On("UpdateLoan", mock.Anything, &sql.Tx{}, model.Loan{
 						Id:     1,
 						Status: constant.LoanStatusPaid,
 					}).
@@ -660,7 +1157,7 @@ func Test_PayLoan(t *testing.T) {
 
 				temp := float64(0)
 				repoMock.
-					On("UpdateRepayment", context.Background(), &sql.Tx{}, model.Repayment{
+					On("UpdateRepayment", mock.Anything, &sql.Tx{}, model.Repayment{
 						Id:            3,
 						Status:        constant.RepaymentStatusPaid,
 						ActualPayment: &temp,
@@ -679,29 +1176,21 @@ func Test_PayLoan(t *testing.T) {
 		{
 			name: "fail UpdateRepayment",
 			mock: func() {
-				repoMock.
-					On("GetLoanByIdAndUserId", context.Background(), int64(1), int64(1)).
-					Return(getLoanByIdAndUserIdRes, nil).
-					Once()
-
-				repoMock.
-					On("GetRepaymentByLoanId", context.Background(), int64(1)).
-					Return(GetRepaymentByLoanIdRes, nil).
-					Once()
+				begin()
 
 				repoMock.
-					On("BeginTx", context.Background()).
-					Return(&sql.Tx{}, nil).
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(getLoanForUpdateRes, nil).
 					Once()
 
 				repoMock.
-					On("RollbackTx", &sql.Tx{}).
-					Return(nil).
+					On("GetRepaymentsForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(getRepaymentsForUpdateRes, nil).
 					Once()
 
 				temp := float64(4000)
 				repoMock.
-					On("UpdateRepayment", context.Background(), &sql.Tx{}, model.Repayment{
+					On("UpdateRepayment", mock.Anything, &sql.Tx{}, model.Repayment{
 						Id:            2,
 						Status:        constant.RepaymentStatusPaid,
 						ActualPayment: &temp,
@@ -713,31 +1202,56 @@ func Test_PayLoan(t *testing.T) {
 			wantErr: errors.New("err UpdateRepayment"),
 		},
 		{
-			name: "fail CommitTx",
+			name: "fail InsertOutboxEvent",
 			mock: func() {
+				begin()
+
+				repoMock.
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(getLoanForUpdateRes, nil).
+					Once()
+
 				repoMock.
-					On("GetLoanByIdAndUserId", context.Background(), int64(1), int64(1)).
-					Return(getLoanByIdAndUserIdRes, nil).
+					On("GetRepaymentsForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(getRepaymentsForUpdateRes, nil).
 					Once()
 
+				temp := float64(4000)
 				repoMock.
-					On("GetRepaymentByLoanId", context.Background(), int64(1)).
-					Return(GetRepaymentByLoanIdRes, nil).
+					On("UpdateRepayment", mock.Anything, &sql.Tx{}, model.Repayment{
+						Id:            2,
+						Status:        constant.RepaymentStatusPaid,
+						ActualPayment: &temp,
+					}).
+					Return(nil).
 					Once()
 
 				repoMock.
-					On("BeginTx", context.Background()).
-					Return(&sql.Tx{}, nil).
+					On("InsertOutboxEvent", mock.Anything, &sql.Tx{}, mock.Anything).
+					Return(int64(0), errors.New("err InsertOutboxEvent")).
+					Once()
+			},
+			args:    req,
+			wantErr: errors.New("err InsertOutboxEvent"),
+		},
+		{
+			name: "fail CommitTx",
+			mock: func() {
+				begin()
+
+				repoMock.
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(getLoanForUpdateRes, nil).
 					Once()
 
 				repoMock.
-					On("RollbackTx", &sql.Tx{}).
-					Return(nil).
+					On("GetRepaymentsForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(getRepaymentsForUpdateRes, nil).
 					Once()
 
 				temp := float64(4000)
 				repoMock.
-					On("UpdateRepayment", context.Background(), &sql.Tx{}, model.Repayment{
+					On("UpdateRepayment", mock.Anything, &sql.Tx{}, model.Repayment{
 						Id:            2,
 						Status:        constant.RepaymentStatusPaid,
 						ActualPayment: &temp,
@@ -745,6 +1259,16 @@ func Test_PayLoan(t *testing.T) {
 					Return(nil).
 					Once()
 
+				repoMock.
+					On("InsertOutboxEvent", mock.Anything, &sql.Tx{}, loanEvent(constant.EventLoanPaymentReceived, 1, `{"loan_id":1,"term":2,"amount":4000}`)).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("InsertNotifications", mock.Anything, &sql.Tx{}, userNotification(int64(1), constant.NotificationLoanPaymentReceived, `{"loan_id":1,"term":2,"amount":4000}`), testNotificationChannels).
+					Return(int64(1), nil).
+					Once()
+
 				repoMock.
 					On("CommitTx", &sql.Tx{}).
 					Return(errors.New("err CommitTx")).
@@ -756,29 +1280,21 @@ func Test_PayLoan(t *testing.T) {
 		{
 			name: "success",
 			mock: func() {
-				repoMock.
-					On("GetLoanByIdAndUserId", context.Background(), int64(1), int64(1)).
-					Return(getLoanByIdAndUserIdRes, nil).
-					Once()
+				begin()
 
 				repoMock.
-					On("GetRepaymentByLoanId", context.Background(), int64(1)).
-					Return(GetRepaymentByLoanIdRes, nil).
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(getLoanForUpdateRes, nil).
 					Once()
 
 				repoMock.
-					On("BeginTx", context.Background()).
-					Return(&sql.Tx{}, nil).
-					Once()
-
-				repoMock.
-					On("RollbackTx", &sql.Tx{}).
-					Return(nil).
+					On("GetRepaymentsForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(getRepaymentsForUpdateRes, nil).
 					Once()
 
 				temp := float64(4000)
 				repoMock.
-					On("UpdateRepayment", context.Background(), &sql.Tx{}, model.Repayment{
+					On("UpdateRepayment", mock.Anything, &sql.Tx{}, model.Repayment{
 						Id:            2,
 						Status:        constant.RepaymentStatusPaid,
 						ActualPayment: &temp,
@@ -786,6 +1302,16 @@ func Test_PayLoan(t *testing.T) {
 					Return(nil).
 					Once()
 
+				repoMock.
+					On("InsertOutboxEvent", mock.Anything, &sql.Tx{}, loanEvent(constant.EventLoanPaymentReceived, 1, `{"loan_id":1,"term":2,"amount":4000}`)).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("InsertNotifications", mock.Anything, &sql.Tx{}, userNotification(int64(1), constant.NotificationLoanPaymentReceived, `{"loan_id":1,"term":2,"amount":4000}`), testNotificationChannels).
+					Return(int64(1), nil).
+					Once()
+
 				repoMock.
 					On("CommitTx", &sql.Tx{}).
 					Return(nil).
@@ -798,6 +1324,7 @@ func Test_PayLoan(t *testing.T) {
 	for _, tt := range tests {
 		u := usecase{
 			repository: repoMock,
+			cfg:        testCfg,
 		}
 
 		t.Run(tt.name, func(t *testing.T) {
@@ -806,7 +1333,7 @@ func Test_PayLoan(t *testing.T) {
 			}
 
 			err := u.PayLoan(context.Background(), tt.args.amount, tt.args.loanId, tt.args.term, tt.args.userId)
-			if !util.SameErrorMessage(err, tt.wantErr) {
+			if !util.SameError(err, tt.wantErr) {
 				t.Errorf("PayLoan test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
 			}
 		})
@@ -841,7 +1368,7 @@ func Test_GetLoan(t *testing.T) {
 			name: "fail GetLoanByUserId",
 			mock: func() {
 				repoMock.
-					On("GetLoanByUserId", context.Background(), int64(1)).
+					On("GetLoanByUserId", mock.Anything, int64(1)).
 					Return(nil, errors.New("err GetLoanByUserId")).
 					Once()
 			},
@@ -852,12 +1379,12 @@ func Test_GetLoan(t *testing.T) {
 			name: "fail GetRepaymentByLoanId",
 			mock: func() {
 				repoMock.
-					On("GetLoanByUserId", context.Background(), int64(1)).
+					On("GetLoanByUserId", mock.Anything, int64(1)).
 					Return(getLoanByUserIdRes, nil).
 					Once()
 
 				repoMock.
-					On("GetRepaymentByLoanId", context.Background(), int64(1)).
+					On("GetRepaymentByLoanId", mock.Anything, int64(1)).
 					Return(nil, errors.New("err GetRepaymentByLoanId")).
 					Once()
 			},
@@ -873,12 +1400,12 @@ func Test_GetLoan(t *testing.T) {
 			name: "success",
 			mock: func() {
 				repoMock.
-					On("GetLoanByUserId", context.Background(), int64(1)).
+					On("GetLoanByUserId", mock.Anything, int64(1)).
 					Return(getLoanByUserIdRes, nil).
 					Once()
 
 				repoMock.
-					On("GetRepaymentByLoanId", context.Background(), int64(1)).
+					On("GetRepaymentByLoanId", mock.Anything, int64(1)).
 					Return([]model.Repayment{
 						{
 							Id: 1,
@@ -903,6 +1430,7 @@ func Test_GetLoan(t *testing.T) {
 	for _, tt := range tests {
 		u := usecase{
 			repository: repoMock,
+			cfg:        testCfg,
 		}
 
 		t.Run(tt.name, func(t *testing.T) {
@@ -911,7 +1439,7 @@ func Test_GetLoan(t *testing.T) {
 			}
 
 			got, err := u.GetLoan(context.Background(), tt.args.userId)
-			if !util.SameErrorMessage(err, tt.wantErr) {
+			if !util.SameError(err, tt.wantErr) {
 				t.Errorf("GetLoan test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
 			}
 			if !reflect.DeepEqual(got, tt.want) {
@@ -920,3 +1448,60 @@ func Test_GetLoan(t *testing.T) {
 		})
 	}
 }
+
+func Test_ExpirePendingLoans(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	createdBefore := now.Add(-30 * 24 * time.Hour)
+
+	tests := []struct {
+		name    string
+		mock    func()
+		want    int
+		wantErr error
+	}{
+		{
+			name: "fail ExpirePendingLoans",
+			mock: func() {
+				repoMock.
+					On("ExpirePendingLoans", mock.Anything, createdBefore, now).
+					Return(nil, errors.New("err ExpirePendingLoans")).
+					Once()
+			},
+			wantErr: errors.New("err ExpirePendingLoans"),
+		},
+		{
+			name: "success",
+			mock: func() {
+				repoMock.
+					On("ExpirePendingLoans", mock.Anything, createdBefore, now).
+					Return([]int64{3, 4}, nil).
+					Once()
+			},
+			want: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		u := usecase{
+			repository: repoMock,
+			cfg:        testCfg,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			got, err := u.ExpirePendingLoans(context.Background(), now)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("ExpirePendingLoans test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("ExpirePendingLoans test failed. want: %d, got: %d", tt.want, got)
+			}
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/logger"
+	"example.com/m/v2/model"
+	"example.com/m/v2/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// notificationsLimit caps the notification history of a user, the latest come first.
+const notificationsLimit = 50
+
+// phoneNumber is an E.164 number, the format sms providers take.
+var phoneNumber = regexp.MustCompile(`^\+[1-9]\d{6,14}$`)
+
+// notifyUser queues a notification of kind for the user within tx, one per channel the user enabled among the
+// configured ones. It is sent by the notification dispatcher once tx commits and never when it rolls back.
+func (u *usecase) notifyUser(ctx context.Context, tx *sql.Tx, userId int64, kind string, data any) (err error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	_, err = u.repository.InsertNotifications(ctx, tx, model.Notification{
+		UserId:    userId,
+		Kind:      kind,
+		Data:      payload,
+		CreatedAt: time.Now(),
+	}, u.notificationChannels())
+
+	return
+}
+
+// notificationChannels lists the channels whose driver isn't none.
+func (u *usecase) notificationChannels() []string {
+	drivers := map[string]string{
+		constant.NotificationChannelEmail: u.cfg.Notifications.Email,
+		constant.NotificationChannelSms:   u.cfg.Notifications.Sms,
+		constant.NotificationChannelPush:  u.cfg.Notifications.Push,
+	}
+
+	channels := make([]string, 0, len(constant.NotificationChannels))
+	for _, channel := range constant.NotificationChannels {
+		if drivers[channel] != "none" {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+func (u *usecase) GetNotifications(ctx context.Context, userId int64) (notifications []model.Notification, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.GetNotifications", attribute.Int64("user_id", userId))
+	defer tracing.End(span, &err)
+
+	return u.repository.GetNotifications(ctx, userId, notificationsLimit)
+}
+
+// GetNotificationPreferences lists every channel, the ones the user never set are at their default: email enabled,
+// the others disabled.
+func (u *usecase) GetNotificationPreferences(ctx context.Context, userId int64) (prefs model.NotificationPreferences, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.GetNotificationPreferences", attribute.Int64("user_id", userId))
+	defer tracing.End(span, &err)
+
+	stored, err := u.repository.GetNotificationPreferences(ctx, userId)
+	if err != nil {
+		return
+	}
+
+	prefs.Locale = stored.Locale
+	for _, channel := range constant.NotificationChannels {
+		pref := model.ChannelPreference{Channel: channel, Enabled: channel == constant.NotificationChannelEmail}
+		i := slices.IndexFunc(stored.Channels, func(p model.ChannelPreference) bool { return p.Channel == channel })
+		if i >= 0 {
+			pref = stored.Channels[i]
+		}
+		prefs.Channels = append(prefs.Channels, pref)
+	}
+
+	return
+}
+
+// UpdateNotificationPreferences sets the locale of the user and the channels in prefs, the other channels keep their
+// setting. Enabling sms takes an E.164 phone number and push a device token, email always goes to the account email.
+func (u *usecase) UpdateNotificationPreferences(ctx context.Context, userId int64, prefs model.NotificationPreferences) (res model.NotificationPreferences, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.UpdateNotificationPreferences", attribute.Int64("user_id", userId))
+	defer tracing.End(span, &err)
+
+	err = checkNotificationPreferences(prefs)
+	if err != nil {
+		return
+	}
+
+	tx, err := u.repository.BeginTx(ctx)
+	if err != nil {
+		return
+	}
+	defer u.repository.RollbackTx(tx)
+
+	now := time.Now()
+	err = u.repository.UpdateUserLocale(ctx, tx, userId, prefs.Locale, now)
+	if err != nil {
+		return
+	}
+
+	for _, pref := range prefs.Channels {
+		err = u.repository.UpsertChannelPreference(ctx, tx, userId, pref, now)
+		if err != nil {
+			return
+		}
+	}
+
+	err = u.repository.CommitTx(tx)
+	if err != nil {
+		return
+	}
+
+	logger.FromContext(ctx).InfoContext(ctx, "notification preferences updated", "user_id", userId, "locale", prefs.Locale)
+	return u.GetNotificationPreferences(ctx, userId)
+}
+
+func checkNotificationPreferences(prefs model.NotificationPreferences) error {
+	var fields []apperror.FieldError
+
+	if !slices.Contains(constant.NotificationLocales, prefs.Locale) {
+		fields = append(fields, apperror.FieldError{Field: "locale", Message: fmt.Sprintf("unknown locale %q", prefs.Locale)})
+	}
+
+	for _, pref := range prefs.Channels {
+		switch pref.Channel {
+		case constant.NotificationChannelEmail:
+			if pref.Address != "" {
+				fields = append(fields, apperror.FieldError{Field: "channels", Message: "email has no address, it goes to the account email"})
+			}
+		case constant.NotificationChannelSms:
+			if pref.Enabled && !phoneNumber.MatchString(pref.Address) {
+				fields = append(fields, apperror.FieldError{Field: "channels", Message: "sms address must be an E.164 phone number"})
+			}
+		case constant.NotificationChannelPush:
+			if pref.Enabled && pref.Address == "" {
+				fields = append(fields, apperror.FieldError{Field: "channels", Message: "push address must be a device token"})
+			}
+		default:
+			fields = append(fields, apperror.FieldError{Field: "channels", Message: fmt.Sprintf("unknown channel %q", pref.Channel)})
+		}
+	}
+
+	if len(fields) > 0 {
+		return apperror.Validation(fields...)
+	}
+	return nil
+}
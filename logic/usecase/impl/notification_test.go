@@ -0,0 +1,237 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	repo "example.com/m/v2/logic/repository"
+	"example.com/m/v2/model"
+	"example.com/m/v2/util"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_GetNotificationPreferences(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+
+	tests := []struct {
+		name    string
+		mock    func()
+		want    model.NotificationPreferences
+		wantErr error
+	}{
+		{
+			name: "fail GetNotificationPreferences",
+			mock: func() {
+				repoMock.
+					On("GetNotificationPreferences", mock.Anything, int64(1)).
+					Return(model.NotificationPreferences{}, apperror.ErrUserNotFound).
+					Once()
+			},
+			wantErr: apperror.ErrUserNotFound,
+		},
+		{
+			name: "defaults for channels never set",
+			mock: func() {
+				repoMock.
+					On("GetNotificationPreferences", mock.Anything, int64(1)).
+					Return(model.NotificationPreferences{Locale: "id"}, nil).
+					Once()
+			},
+			want: model.NotificationPreferences{
+				Locale: "id",
+				Channels: []model.ChannelPreference{
+					{Channel: constant.NotificationChannelEmail, Enabled: true},
+					{Channel: constant.NotificationChannelSms},
+					{Channel: constant.NotificationChannelPush},
+				},
+			},
+		},
+		{
+			name: "stored channels",
+			mock: func() {
+				repoMock.
+					On("GetNotificationPreferences", mock.Anything, int64(1)).
+					Return(model.NotificationPreferences{Locale: "en", Channels: []model.ChannelPreference{
+						{Channel: constant.NotificationChannelSms, Enabled: true, Address: "+6281234567890"},
+						{Channel: constant.NotificationChannelEmail},
+					}}, nil).
+					Once()
+			},
+			want: model.NotificationPreferences{
+				Locale: "en",
+				Channels: []model.ChannelPreference{
+					{Channel: constant.NotificationChannelEmail},
+					{Channel: constant.NotificationChannelSms, Enabled: true, Address: "+6281234567890"},
+					{Channel: constant.NotificationChannelPush},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		u := usecase{
+			repository: repoMock,
+			cfg:        testCfg,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			got, err := u.GetNotificationPreferences(context.Background(), 1)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("GetNotificationPreferences test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetNotificationPreferences test failed. want: %+v, got: %+v", tt.want, got)
+			}
+		})
+	}
+
+	repoMock.AssertExpectations(t)
+}
+
+func Test_UpdateNotificationPreferences(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+
+	sms := model.ChannelPreference{Channel: constant.NotificationChannelSms, Enabled: true, Address: "+6281234567890"}
+
+	begin := func() {
+		repoMock.
+			On("BeginTx", mock.Anything).
+			Return(&sql.Tx{}, nil).
+			Once()
+
+		repoMock.
+			On("RollbackTx", &sql.Tx{}).
+			Return(nil).
+			Once()
+	}
+
+	tests := []struct {
+		name    string
+		mock    func()
+		prefs   model.NotificationPreferences
+		wantErr error
+	}{
+		{
+			name: "unknown locale and channel",
+			prefs: model.NotificationPreferences{Locale: "fr", Channels: []model.ChannelPreference{
+				{Channel: "fax", Enabled: true},
+			}},
+			wantErr: apperror.Validation(
+				apperror.FieldError{Field: "locale", Message: `unknown locale "fr"`},
+				apperror.FieldError{Field: "channels", Message: `unknown channel "fax"`},
+			),
+		},
+		{
+			name: "invalid addresses",
+			prefs: model.NotificationPreferences{Locale: "en", Channels: []model.ChannelPreference{
+				{Channel: constant.NotificationChannelEmail, Enabled: true, Address: "other@tes.com"},
+				{Channel: constant.NotificationChannelSms, Enabled: true, Address: "0812345"},
+				{Channel: constant.NotificationChannelPush, Enabled: true},
+			}},
+			wantErr: apperror.Validation(
+				apperror.FieldError{Field: "channels", Message: "email has no address, it goes to the account email"},
+				apperror.FieldError{Field: "channels", Message: "sms address must be an E.164 phone number"},
+				apperror.FieldError{Field: "channels", Message: "push address must be a device token"},
+			),
+		},
+		{
+			name: "fail UpdateUserLocale",
+			mock: func() {
+				begin()
+
+				repoMock.
+					On("UpdateUserLocale", mock.Anything, &sql.Tx{}, int64(1), "id", mock.Anything).
+					Return(apperror.ErrUserNotFound).
+					Once()
+			},
+			prefs:   model.NotificationPreferences{Locale: "id"},
+			wantErr: apperror.ErrUserNotFound,
+		},
+		{
+			name: "fail UpsertChannelPreference",
+			mock: func() {
+				begin()
+
+				repoMock.
+					On("UpdateUserLocale", mock.Anything, &sql.Tx{}, int64(1), "id", mock.Anything).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("UpsertChannelPreference", mock.Anything, &sql.Tx{}, int64(1), sms, mock.Anything).
+					Return(errors.New("err UpsertChannelPreference")).
+					Once()
+			},
+			prefs:   model.NotificationPreferences{Locale: "id", Channels: []model.ChannelPreference{sms}},
+			wantErr: errors.New("err UpsertChannelPreference"),
+		},
+		{
+			name: "success",
+			mock: func() {
+				begin()
+
+				repoMock.
+					On("UpdateUserLocale", mock.Anything, &sql.Tx{}, int64(1), "id", mock.Anything).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("UpsertChannelPreference", mock.Anything, &sql.Tx{}, int64(1), sms, mock.Anything).
+					Return(nil).
+					Once()
+
+				// disabling push takes no address
+				repoMock.
+					On("UpsertChannelPreference", mock.Anything, &sql.Tx{}, int64(1), model.ChannelPreference{Channel: constant.NotificationChannelPush}, mock.Anything).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("CommitTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("GetNotificationPreferences", mock.Anything, int64(1)).
+					Return(model.NotificationPreferences{Locale: "id", Channels: []model.ChannelPreference{sms}}, nil).
+					Once()
+			},
+			prefs: model.NotificationPreferences{Locale: "id", Channels: []model.ChannelPreference{
+				sms,
+				{Channel: constant.NotificationChannelPush},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		u := usecase{
+			repository: repoMock,
+			cfg:        testCfg,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			got, err := u.UpdateNotificationPreferences(context.Background(), 1, tt.prefs)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("UpdateNotificationPreferences test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+			if tt.wantErr == nil && (got.Locale != "id" || len(got.Channels) != len(constant.NotificationChannels)) {
+				t.Errorf("UpdateNotificationPreferences test failed. got: %+v", got)
+			}
+		})
+	}
+
+	repoMock.AssertExpectations(t)
+}
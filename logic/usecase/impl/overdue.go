@@ -0,0 +1,157 @@
+package impl
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"example.com/m/v2/config"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/logger"
+	"example.com/m/v2/model"
+	"example.com/m/v2/tracing"
+)
+
+// MarkOverdue moves the unpaid terms past their grace period to OVERDUE and charges the late fees they accrued up to
+// now, then defaults the loans with a term unpaid for longer than the default period. Fees are derived from the due
+// date, so a run after missed ones charges what the missed runs would have and running it twice charges nothing more,
+// even concurrently.
+func (u *usecase) MarkOverdue(ctx context.Context, now time.Time) (run model.OverdueRun, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.MarkOverdue")
+	defer tracing.End(span, &err)
+
+	repayments, err := u.repository.GetOverdueRepayments(ctx, now.Add(-u.cfg.Overdue.GracePeriod))
+	if err != nil {
+		return
+	}
+
+	log := logger.FromContext(ctx)
+	for _, repayment := range repayments {
+		// the listed fees can only be behind, a term owing nothing by them owes nothing under the lock either
+		if repayment.Status == constant.RepaymentStatusOverdue && roundCents(lateFees(u.cfg.Overdue, repayment, now)-repayment.LateFees) <= 0 {
+			continue
+		}
+
+		var fee float64
+		var marked bool
+		fee, marked, err = u.chargeLateFee(ctx, repayment, now)
+		if err != nil {
+			return
+		}
+		if !marked {
+			continue
+		}
+
+		if repayment.Status == constant.RepaymentStatusPending {
+			run.Overdue++
+			log.InfoContext(ctx, "repayment overdue", "loan_id", repayment.LoanId, "repayment_id", repayment.Id)
+		}
+		if fee > 0 {
+			run.Fees++
+			run.FeeAmount += fee
+		}
+	}
+
+	defaulted, err := u.defaultLoans(ctx, now)
+	if err != nil {
+		return
+	}
+	for _, loanId := range defaulted {
+		log.WarnContext(ctx, "loan defaulted", "loan_id", loanId)
+	}
+	run.Defaulted = len(defaulted)
+
+	return
+}
+
+// defaultLoans defaults the loans with a term unpaid for longer than the default period and records their events.
+func (u *usecase) defaultLoans(ctx context.Context, now time.Time) (defaulted []int64, err error) {
+	tx, err := u.repository.BeginTx(ctx)
+	if err != nil {
+		return
+	}
+	defer u.repository.RollbackTx(tx)
+
+	defaulted, err = u.repository.DefaultLoans(ctx, tx, now.Add(-u.cfg.Overdue.DefaultAfter), now)
+	if err != nil {
+		return
+	}
+
+	for _, loanId := range defaulted {
+		err = u.recordLoanEvent(ctx, tx, constant.EventLoanDefaulted, loanId, model.LoanStatusEvent{LoanId: loanId})
+		if err != nil {
+			return
+		}
+	}
+
+	err = u.repository.CommitTx(tx)
+
+	return
+}
+
+// chargeLateFee marks the term OVERDUE and adds a fee line for the late fees it accrued beyond those already charged,
+// marked is false when the term was paid since it was listed. The charged fees are read with the term locked, so
+// concurrent runs don't charge the same fee twice.
+func (u *usecase) chargeLateFee(ctx context.Context, repayment model.Repayment, now time.Time) (fee float64, marked bool, err error) {
+	tx, err := u.repository.BeginTx(ctx)
+	if err != nil {
+		return
+	}
+	defer u.repository.RollbackTx(tx)
+
+	marked, err = u.repository.MarkRepaymentOverdue(ctx, tx, repayment.Id)
+	if err != nil || !marked {
+		return
+	}
+
+	charged, err := u.repository.GetRepaymentLateFees(ctx, tx, repayment.Id)
+	if err != nil {
+		return
+	}
+
+	fee = roundCents(lateFees(u.cfg.Overdue, repayment, now) - charged)
+	if fee > 0 {
+		_, err = u.repository.InsertRepaymentFee(ctx, tx, model.RepaymentFee{
+			RepaymentId: repayment.Id,
+			Kind:        constant.RepaymentFeeKindLate,
+			Amount:      fee,
+			AssessedAt:  now,
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	err = u.repository.CommitTx(tx)
+
+	return
+}
+
+// lateFees is the total late fee a term has accrued at now: one fee when the grace period ends and one more every
+// fee interval after that, never more than the cap.
+func lateFees(cfg config.Overdue, repayment model.Repayment, now time.Time) float64 {
+	if repayment.DueDate == nil {
+		return 0
+	}
+
+	overdueSince := repayment.DueDate.Add(cfg.GracePeriod)
+	if now.Before(overdueSince) {
+		return 0
+	}
+
+	fee := cfg.FeeAmount
+	if cfg.FeeType == constant.LateFeeTypePercent {
+		fee = repayment.MinimumPayment * cfg.FeeAmount / 100
+	}
+
+	total := roundCents(fee * float64(now.Sub(overdueSince)/cfg.FeeInterval+1))
+	if cfg.FeeCap > 0 && total > cfg.FeeCap {
+		total = cfg.FeeCap
+	}
+
+	return total
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
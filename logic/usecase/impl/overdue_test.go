@@ -0,0 +1,264 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"example.com/m/v2/config"
+	"example.com/m/v2/constant"
+	repo "example.com/m/v2/logic/repository"
+	"example.com/m/v2/model"
+	"example.com/m/v2/util"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_MarkOverdue(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	dueBefore := now.Add(-72 * time.Hour)
+	defaultBefore := now.Add(-30 * 24 * time.Hour)
+
+	// pending is past its grace period since a day, its first fee is 2% of 1000
+	pendingDue := now.Add(-4 * 24 * time.Hour)
+	pending := model.Repayment{Id: 1, LoanId: 1, MinimumPayment: 1000, Status: constant.RepaymentStatusPending, DueDate: &pendingDue}
+	// overdue was charged its first fee, a week later it owes the second one
+	overdueDue := now.Add(-11 * 24 * time.Hour)
+	overdue := model.Repayment{Id: 2, LoanId: 2, MinimumPayment: 1000, Status: constant.RepaymentStatusOverdue, DueDate: &overdueDue, LateFees: 20}
+	// charged already owes nothing more until its next fee interval
+	charged := model.Repayment{Id: 3, LoanId: 3, MinimumPayment: 1000, Status: constant.RepaymentStatusOverdue, DueDate: &pendingDue, LateFees: 20}
+
+	fee := func(repaymentId int64, amount float64) model.RepaymentFee {
+		return model.RepaymentFee{RepaymentId: repaymentId, Kind: constant.RepaymentFeeKindLate, Amount: amount, AssessedAt: now}
+	}
+	// charge mocks the transaction of one term up to the locked term
+	charge := func(repaymentId int64, marked bool, err error) {
+		repoMock.On("BeginTx", mock.Anything).Return(&sql.Tx{}, nil).Once()
+		repoMock.On("RollbackTx", &sql.Tx{}).Return(nil).Once()
+		repoMock.On("MarkRepaymentOverdue", mock.Anything, &sql.Tx{}, repaymentId).Return(marked, err).Once()
+	}
+	// charged mocks the late fees of a term read under its lock
+	chargedFees := func(repaymentId int64, fees float64, err error) {
+		repoMock.On("GetRepaymentLateFees", mock.Anything, &sql.Tx{}, repaymentId).Return(fees, err).Once()
+	}
+	// defaultLoans mocks the transaction defaulting loans up to their update
+	defaultLoans := func(ids []int64, err error) {
+		repoMock.On("BeginTx", mock.Anything).Return(&sql.Tx{}, nil).Once()
+		repoMock.On("RollbackTx", &sql.Tx{}).Return(nil).Once()
+		repoMock.On("DefaultLoans", mock.Anything, &sql.Tx{}, defaultBefore, now).Return(ids, err).Once()
+	}
+
+	tests := []struct {
+		name    string
+		mock    func()
+		want    model.OverdueRun
+		wantErr error
+	}{
+		{
+			name: "fail GetOverdueRepayments",
+			mock: func() {
+				repoMock.On("GetOverdueRepayments", mock.Anything, dueBefore).Return(nil, errors.New("err GetOverdueRepayments")).Once()
+			},
+			wantErr: errors.New("err GetOverdueRepayments"),
+		},
+		{
+			name: "fail BeginTx",
+			mock: func() {
+				repoMock.On("GetOverdueRepayments", mock.Anything, dueBefore).Return([]model.Repayment{pending}, nil).Once()
+				repoMock.On("BeginTx", mock.Anything).Return(nil, errors.New("err BeginTx")).Once()
+			},
+			wantErr: errors.New("err BeginTx"),
+		},
+		{
+			name: "fail MarkRepaymentOverdue",
+			mock: func() {
+				repoMock.On("GetOverdueRepayments", mock.Anything, dueBefore).Return([]model.Repayment{pending}, nil).Once()
+				charge(1, false, errors.New("err MarkRepaymentOverdue"))
+			},
+			wantErr: errors.New("err MarkRepaymentOverdue"),
+		},
+		{
+			name: "fail GetRepaymentLateFees",
+			mock: func() {
+				repoMock.On("GetOverdueRepayments", mock.Anything, dueBefore).Return([]model.Repayment{pending}, nil).Once()
+				charge(1, true, nil)
+				chargedFees(1, 0, errors.New("err GetRepaymentLateFees"))
+			},
+			wantErr: errors.New("err GetRepaymentLateFees"),
+		},
+		{
+			name: "fail InsertRepaymentFee",
+			mock: func() {
+				repoMock.On("GetOverdueRepayments", mock.Anything, dueBefore).Return([]model.Repayment{pending}, nil).Once()
+				charge(1, true, nil)
+				chargedFees(1, 0, nil)
+				repoMock.On("InsertRepaymentFee", mock.Anything, &sql.Tx{}, fee(1, 20)).Return(int64(0), errors.New("err InsertRepaymentFee")).Once()
+			},
+			wantErr: errors.New("err InsertRepaymentFee"),
+		},
+		{
+			name: "fail CommitTx",
+			mock: func() {
+				repoMock.On("GetOverdueRepayments", mock.Anything, dueBefore).Return([]model.Repayment{pending}, nil).Once()
+				charge(1, true, nil)
+				chargedFees(1, 0, nil)
+				repoMock.On("InsertRepaymentFee", mock.Anything, &sql.Tx{}, fee(1, 20)).Return(int64(1), nil).Once()
+				repoMock.On("CommitTx", &sql.Tx{}).Return(errors.New("err CommitTx")).Once()
+			},
+			wantErr: errors.New("err CommitTx"),
+		},
+		{
+			name: "term paid since it was listed",
+			mock: func() {
+				repoMock.On("GetOverdueRepayments", mock.Anything, dueBefore).Return([]model.Repayment{pending}, nil).Once()
+				charge(1, false, nil)
+				defaultLoans(nil, nil)
+				repoMock.On("CommitTx", &sql.Tx{}).Return(nil).Once()
+			},
+		},
+		{
+			name: "fee charged by a concurrent run is not charged again",
+			mock: func() {
+				repoMock.On("GetOverdueRepayments", mock.Anything, dueBefore).Return([]model.Repayment{pending}, nil).Once()
+				charge(1, true, nil)
+				chargedFees(1, 20, nil)
+				repoMock.On("CommitTx", &sql.Tx{}).Return(nil).Once()
+				defaultLoans(nil, nil)
+				repoMock.On("CommitTx", &sql.Tx{}).Return(nil).Once()
+			},
+			want: model.OverdueRun{Overdue: 1},
+		},
+		{
+			name: "fail DefaultLoans",
+			mock: func() {
+				repoMock.On("GetOverdueRepayments", mock.Anything, dueBefore).Return([]model.Repayment{charged}, nil).Once()
+				defaultLoans(nil, errors.New("err DefaultLoans"))
+			},
+			wantErr: errors.New("err DefaultLoans"),
+		},
+		{
+			name: "fail InsertOutboxEvent for a defaulted loan",
+			mock: func() {
+				repoMock.On("GetOverdueRepayments", mock.Anything, dueBefore).Return([]model.Repayment{charged}, nil).Once()
+				defaultLoans([]int64{7}, nil)
+				repoMock.On("InsertOutboxEvent", mock.Anything, &sql.Tx{}, mock.Anything).Return(int64(0), errors.New("err InsertOutboxEvent")).Once()
+			},
+			wantErr: errors.New("err InsertOutboxEvent"),
+		},
+		{
+			name: "success",
+			mock: func() {
+				repoMock.On("GetOverdueRepayments", mock.Anything, dueBefore).Return([]model.Repayment{pending, overdue, charged}, nil).Once()
+
+				charge(1, true, nil)
+				chargedFees(1, 0, nil)
+				repoMock.On("InsertRepaymentFee", mock.Anything, &sql.Tx{}, fee(1, 20)).Return(int64(1), nil).Once()
+				repoMock.On("CommitTx", &sql.Tx{}).Return(nil).Once()
+
+				charge(2, true, nil)
+				chargedFees(2, 20, nil)
+				repoMock.On("InsertRepaymentFee", mock.Anything, &sql.Tx{}, fee(2, 20)).Return(int64(2), nil).Once()
+				repoMock.On("CommitTx", &sql.Tx{}).Return(nil).Once()
+
+				defaultLoans([]int64{7}, nil)
+				repoMock.On("InsertOutboxEvent", mock.Anything, &sql.Tx{}, loanEvent(constant.EventLoanDefaulted, 7, `{"loan_id":7}`)).Return(int64(1), nil).Once()
+				repoMock.On("CommitTx", &sql.Tx{}).Return(nil).Once()
+			},
+			want: model.OverdueRun{Overdue: 1, Fees: 2, FeeAmount: 40, Defaulted: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		u := usecase{
+			repository: repoMock,
+			cfg:        testCfg,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			got, err := u.MarkOverdue(context.Background(), now)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("MarkOverdue test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MarkOverdue test failed. want: %+v, got: %+v", tt.want, got)
+			}
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_lateFees(t *testing.T) {
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) *time.Time {
+		due := now.Add(-time.Duration(days) * 24 * time.Hour)
+		return &due
+	}
+
+	percent := testCfg.Overdue
+	flat := config.Overdue{
+		GracePeriod: 24 * time.Hour,
+		FeeType:     "flat",
+		FeeAmount:   15,
+		FeeInterval: 24 * time.Hour,
+	}
+
+	tests := []struct {
+		name      string
+		cfg       config.Overdue
+		repayment model.Repayment
+		want      float64
+	}{
+		{
+			name:      "not scheduled yet",
+			cfg:       percent,
+			repayment: model.Repayment{MinimumPayment: 1000},
+			want:      0,
+		},
+		{
+			name:      "within the grace period",
+			cfg:       percent,
+			repayment: model.Repayment{MinimumPayment: 1000, DueDate: daysAgo(2)},
+			want:      0,
+		},
+		{
+			name:      "first percent fee when the grace period ends",
+			cfg:       percent,
+			repayment: model.Repayment{MinimumPayment: 3333.33, DueDate: daysAgo(3)},
+			want:      66.67,
+		},
+		{
+			name:      "one more fee every interval",
+			cfg:       percent,
+			repayment: model.Repayment{MinimumPayment: 1000, DueDate: daysAgo(17)},
+			want:      60,
+		},
+		{
+			name:      "capped",
+			cfg:       percent,
+			repayment: model.Repayment{MinimumPayment: 1000, DueDate: daysAgo(60)},
+			want:      100,
+		},
+		{
+			name:      "flat fees without a cap",
+			cfg:       flat,
+			repayment: model.Repayment{MinimumPayment: 1000, DueDate: daysAgo(60)},
+			want:      900,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lateFees(tt.cfg, tt.repayment, now); got != tt.want {
+				t.Errorf("lateFees test failed. want: %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package impl
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/logger"
+	"example.com/m/v2/model"
+	"example.com/m/v2/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ForgotPassword mails a single-use reset link. Unknown emails succeed silently so the endpoint can't be used
+// to find out which emails are registered.
+func (u *usecase) ForgotPassword(ctx context.Context, email string) (err error) {
+	ctx, span := tracing.Start(ctx, "usecase.ForgotPassword")
+	defer tracing.End(span, &err)
+
+	user, err := u.repository.GetUserByEmail(ctx, model.NormalizeEmail(email))
+	if errors.Is(err, apperror.ErrUserNotFound) {
+		logger.FromContext(ctx).InfoContext(ctx, "password reset requested for an unknown email")
+		return nil
+	}
+	if err != nil {
+		return
+	}
+
+	token, err := u.repository.RandomToken()
+	if err != nil {
+		return
+	}
+
+	err = u.repository.InsertPasswordResetToken(ctx, model.PasswordResetToken{
+		UserId:    user.Id,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(u.cfg.PasswordReset.TokenTtl),
+	})
+	if err != nil {
+		return
+	}
+
+	link := u.cfg.PasswordReset.Url + "?token=" + url.QueryEscape(token)
+
+	err = u.repository.SendMail(ctx, model.Mail{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Text: "A password reset was requested for your account.\n\n" +
+			"Choose a new password by opening the link below, it can be used once and expires in " + u.cfg.PasswordReset.TokenTtl.String() + ".\n\n" +
+			link + "\n\n" +
+			"If you did not request it you can ignore this mail, your password stays unchanged.",
+	})
+	if err != nil {
+		return
+	}
+
+	logger.FromContext(ctx).InfoContext(ctx, "password reset requested", "user_id", user.Id)
+	return
+}
+
+func (u *usecase) ResetPassword(ctx context.Context, token, password string) (err error) {
+	ctx, span := tracing.Start(ctx, "usecase.ResetPassword")
+	defer tracing.End(span, &err)
+
+	tx, err := u.repository.BeginTx(ctx)
+	if err != nil {
+		return
+	}
+	defer u.repository.RollbackTx(tx)
+
+	now := time.Now()
+	userId, err := u.repository.ConsumePasswordResetToken(ctx, tx, hashToken(token), now)
+	if err != nil {
+		return
+	}
+
+	user, err := u.repository.GetUserById(ctx, userId)
+	if err != nil {
+		return
+	}
+
+	// a rejected password rolls back, the token stays usable
+	err = u.setPassword(ctx, tx, user, password, now)
+	if err != nil {
+		return
+	}
+
+	err = u.repository.CommitTx(tx)
+	if err != nil {
+		return
+	}
+
+	logger.FromContext(ctx).InfoContext(ctx, "password reset", "user_id", userId)
+	return
+}
+
+// ChangePassword checks the old password against the same lockout as UserLogin, a wrong one counts as a failed login
+// of the email and the ip so a stolen session can't be used to guess the password.
+func (u *usecase) ChangePassword(ctx context.Context, userId int64, oldPassword, newPassword, ip string) (err error) {
+	ctx, span := tracing.Start(ctx, "usecase.ChangePassword", attribute.Int64("user_id", userId))
+	defer tracing.End(span, &err)
+
+	user, err := u.repository.GetUserById(ctx, userId)
+	if err != nil {
+		return
+	}
+
+	err = u.loginGuard.Check(ctx, user.Email, ip)
+	if err != nil {
+		return
+	}
+
+	errCompare := u.repository.BcryptComparePassword([]byte(user.Password), []byte(oldPassword))
+	if errCompare != nil {
+		err = u.loginGuard.Fail(ctx, user.Email, ip)
+		if err != nil {
+			return
+		}
+		logger.FromContext(ctx).WarnContext(ctx, "password change failed", "user_id", userId, "ip", ip)
+		err = apperror.ErrOldPasswordInvalid.WithCause(errCompare)
+		return
+	}
+
+	err = u.loginGuard.Succeed(ctx, user.Email)
+	if err != nil {
+		return
+	}
+
+	tx, err := u.repository.BeginTx(ctx)
+	if err != nil {
+		return
+	}
+	defer u.repository.RollbackTx(tx)
+
+	err = u.setPassword(ctx, tx, user, newPassword, time.Now())
+	if err != nil {
+		return
+	}
+
+	err = u.repository.CommitTx(tx)
+	if err != nil {
+		return
+	}
+
+	logger.FromContext(ctx).InfoContext(ctx, "password changed", "user_id", userId)
+	return
+}
+
+// setPassword stores a new password that passed the policy, ending every session and outstanding reset link.
+func (u *usecase) setPassword(ctx context.Context, tx *sql.Tx, user model.User, password string, now time.Time) (err error) {
+	err = u.passwordPolicy.Check(password, user.Email)
+	if err != nil {
+		return
+	}
+
+	hash, err := u.repository.BcryptGenerateHash([]byte(password))
+	if err != nil {
+		return
+	}
+
+	err = u.repository.UpdateUserPassword(ctx, tx, user.Id, string(hash))
+	if err != nil {
+		return
+	}
+
+	return u.repository.RevokePasswordResetTokens(ctx, tx, user.Id, now)
+}
+
+// hashToken is what gets stored for random tokens, they carry enough entropy for a fast unsalted hash.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,408 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/lockout"
+	repo "example.com/m/v2/logic/repository"
+	"example.com/m/v2/model"
+	"example.com/m/v2/util"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_ForgotPassword(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+	user := model.User{
+		Id:    1,
+		Email: "tes@tes.com",
+	}
+
+	tests := []struct {
+		name    string
+		mock    func()
+		email   string
+		wantErr error
+	}{
+		{
+			name:  "unknown email succeeds silently",
+			email: "unknown@tes.com",
+			mock: func() {
+				repoMock.
+					On("GetUserByEmail", mock.Anything, "unknown@tes.com").
+					Return(model.User{}, apperror.ErrUserNotFound.WithCause(sql.ErrNoRows)).
+					Once()
+			},
+		},
+		{
+			name:  "fail GetUserByEmail",
+			email: "tes@tes.com",
+			mock: func() {
+				repoMock.
+					On("GetUserByEmail", mock.Anything, "tes@tes.com").
+					Return(model.User{}, errors.New("err GetUserByEmail")).
+					Once()
+			},
+			wantErr: errors.New("err GetUserByEmail"),
+		},
+		{
+			name:  "fail InsertPasswordResetToken",
+			email: "tes@tes.com",
+			mock: func() {
+				repoMock.
+					On("GetUserByEmail", mock.Anything, "tes@tes.com").
+					Return(user, nil).
+					Once()
+
+				repoMock.
+					On("RandomToken").
+					Return("tes", nil).
+					Once()
+
+				repoMock.
+					On("InsertPasswordResetToken", mock.Anything, mock.Anything).
+					Return(errors.New("err InsertPasswordResetToken")).
+					Once()
+			},
+			wantErr: errors.New("err InsertPasswordResetToken"),
+		},
+		{
+			name:  "success stores the hash and mails the token",
+			email: " TES@tes.com",
+			mock: func() {
+				repoMock.
+					On("GetUserByEmail", mock.Anything, "tes@tes.com").
+					Return(user, nil).
+					Once()
+
+				repoMock.
+					On("RandomToken").
+					Return("tes", nil).
+					Once()
+
+				repoMock.
+					On("InsertPasswordResetToken", mock.Anything, mock.MatchedBy(func(token model.PasswordResetToken) bool {
+						return token.UserId == 1 && token.TokenHash == hashToken("tes") && token.TokenHash != "tes" && !token.ExpiresAt.IsZero()
+					})).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("SendMail", mock.Anything, mock.MatchedBy(func(mail model.Mail) bool {
+						return mail.To == "tes@tes.com" && strings.Contains(mail.Text, "https://tes.com/reset?token=tes")
+					})).
+					Return(nil).
+					Once()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		u := usecase{
+			repository: repoMock,
+			cfg:        testCfg,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			err := u.ForgotPassword(context.Background(), tt.email)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("ForgotPassword test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_ResetPassword(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+	user := model.User{
+		Id:    1,
+		Email: "tes@tes.com",
+	}
+
+	tests := []struct {
+		name     string
+		mock     func()
+		password string
+		wantErr  error
+	}{
+		{
+			name:     "used or expired token",
+			password: "correct-horse-battery",
+			mock: func() {
+				repoMock.
+					On("BeginTx", mock.Anything).
+					Return(&sql.Tx{}, nil).
+					Once()
+
+				repoMock.
+					On("RollbackTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("ConsumePasswordResetToken", mock.Anything, &sql.Tx{}, hashToken("tes"), mock.Anything).
+					Return(int64(0), apperror.ErrInvalidResetToken).
+					Once()
+			},
+			wantErr: apperror.ErrInvalidResetToken,
+		},
+		{
+			name:     "password rejected by policy rolls back",
+			password: "short",
+			mock: func() {
+				repoMock.
+					On("BeginTx", mock.Anything).
+					Return(&sql.Tx{}, nil).
+					Once()
+
+				repoMock.
+					On("RollbackTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("ConsumePasswordResetToken", mock.Anything, &sql.Tx{}, hashToken("tes"), mock.Anything).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(user, nil).
+					Once()
+			},
+			wantErr: apperror.Validation(apperror.FieldError{Field: "password", Message: "must be at least 10 characters"}),
+		},
+		{
+			name:     "success",
+			password: "correct-horse-battery",
+			mock: func() {
+				repoMock.
+					On("BeginTx", mock.Anything).
+					Return(&sql.Tx{}, nil).
+					Once()
+
+				repoMock.
+					On("RollbackTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("ConsumePasswordResetToken", mock.Anything, &sql.Tx{}, hashToken("tes"), mock.Anything).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(user, nil).
+					Once()
+
+				repoMock.
+					On("BcryptGenerateHash", []byte("correct-horse-battery")).
+					Return([]byte("hash"), nil).
+					Once()
+
+				repoMock.
+					On("UpdateUserPassword", mock.Anything, &sql.Tx{}, int64(1), "hash").
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("RevokePasswordResetTokens", mock.Anything, &sql.Tx{}, int64(1), mock.Anything).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("CommitTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		u := usecase{
+			repository:     repoMock,
+			passwordPolicy: testPasswordPolicy,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			err := u.ResetPassword(context.Background(), "tes", tt.password)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("ResetPassword test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_ChangePassword(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+	user := model.User{
+		Id:       1,
+		Email:    "tes@tes.com",
+		Password: "old-hash",
+	}
+
+	tests := []struct {
+		name    string
+		mock    func()
+		before  func(g *lockout.Guard)
+		wantErr error
+		// wantFailures is the number of failures counted against the email
+		wantFailures int
+	}{
+		{
+			name: "locked",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(user, nil).
+					Once()
+			},
+			before: func(g *lockout.Guard) {
+				for i := 0; i < testLockoutCfg.AccountThreshold; i++ {
+					g.Fail(context.Background(), "tes@tes.com", "")
+				}
+			},
+			wantErr:      apperror.ErrTooManyLoginAttempts,
+			wantFailures: testLockoutCfg.AccountThreshold,
+		},
+		{
+			name: "wrong old password counts a failure",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(user, nil).
+					Once()
+
+				repoMock.
+					On("BcryptComparePassword", []byte("old-hash"), []byte("old-password-1")).
+					Return(errors.New("mismatch")).
+					Once()
+			},
+			wantErr:      apperror.ErrOldPasswordInvalid,
+			wantFailures: 1,
+		},
+		{
+			name: "fail UpdateUserPassword",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(user, nil).
+					Once()
+
+				repoMock.
+					On("BcryptComparePassword", []byte("old-hash"), []byte("old-password-1")).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("BeginTx", mock.Anything).
+					Return(&sql.Tx{}, nil).
+					Once()
+
+				repoMock.
+					On("RollbackTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("BcryptGenerateHash", []byte("correct-horse-battery")).
+					Return([]byte("hash"), nil).
+					Once()
+
+				repoMock.
+					On("UpdateUserPassword", mock.Anything, &sql.Tx{}, int64(1), "hash").
+					Return(errors.New("err UpdateUserPassword")).
+					Once()
+			},
+			wantErr: errors.New("err UpdateUserPassword"),
+		},
+		{
+			name: "success",
+			before: func(g *lockout.Guard) {
+				g.Fail(context.Background(), "tes@tes.com", "")
+			},
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(user, nil).
+					Once()
+
+				repoMock.
+					On("BcryptComparePassword", []byte("old-hash"), []byte("old-password-1")).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("BeginTx", mock.Anything).
+					Return(&sql.Tx{}, nil).
+					Once()
+
+				repoMock.
+					On("RollbackTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("BcryptGenerateHash", []byte("correct-horse-battery")).
+					Return([]byte("hash"), nil).
+					Once()
+
+				repoMock.
+					On("UpdateUserPassword", mock.Anything, &sql.Tx{}, int64(1), "hash").
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("RevokePasswordResetTokens", mock.Anything, &sql.Tx{}, int64(1), mock.Anything).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("CommitTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		store := lockout.NewMemoryStore()
+		guard := lockout.New(store, testLockoutCfg)
+		u := usecase{
+			repository:     repoMock,
+			passwordPolicy: testPasswordPolicy,
+			loginGuard:     guard,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+			if tt.before != nil {
+				tt.before(guard)
+			}
+
+			err := u.ChangePassword(context.Background(), 1, "old-password-1", "correct-horse-battery", "192.0.2.1")
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("ChangePassword test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+			state, _ := store.Get(context.Background(), lockout.AccountKey("tes@tes.com"))
+			if state.Failures != tt.wantFailures {
+				t.Errorf("ChangePassword test failed. wantFailures: %d, gotFailures: %d", tt.wantFailures, state.Failures)
+			}
+		})
+	}
+}
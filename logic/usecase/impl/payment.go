@@ -0,0 +1,188 @@
+package impl
+
+import (
+	"context"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/logger"
+	"example.com/m/v2/metrics"
+	"example.com/m/v2/model"
+	"example.com/m/v2/payment"
+	"example.com/m/v2/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// CreatePaymentIntent starts the payment of a term at the payment gateway. The payment is checked like PayLoan but
+// nothing is paid until the gateway confirms the settlement through the webhook. A term has one pending intent at
+// most, another one is refused with ErrPaymentIntentPending until it expires.
+func (u *usecase) CreatePaymentIntent(ctx context.Context, amount float64, loanId, term, userId int64) (intent model.PaymentIntent, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.CreatePaymentIntent", attribute.Int64("loan_id", loanId), attribute.Int64("term", term), attribute.Int64("user_id", userId))
+	defer tracing.End(span, &err)
+
+	_, err = u.previewPayment(ctx, amount, loanId, term, userId)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	pending, err := u.repository.HasPendingPaymentIntent(ctx, loanId, term, now)
+	if err != nil {
+		return
+	}
+	if pending {
+		err = apperror.ErrPaymentIntentPending
+		return
+	}
+
+	expiresAt := now.Add(u.cfg.Payment.IntentTtl)
+	result, err := u.repository.CreateGatewayIntent(ctx, model.PaymentIntentRequest{
+		LoanId:    loanId,
+		UserId:    userId,
+		Term:      term,
+		Amount:    amount,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return
+	}
+
+	created := model.PaymentIntent{
+		LoanId:           loanId,
+		UserId:           userId,
+		Term:             term,
+		Amount:           amount,
+		Gateway:          u.repository.PaymentGateway(),
+		GatewayReference: result.Reference,
+		VirtualAccount:   result.VirtualAccount,
+		Status:           constant.PaymentIntentStatusPending,
+		ExpiresAt:        expiresAt,
+		CreatedAt:        now,
+	}
+	created.Id, err = u.repository.InsertPaymentIntent(ctx, created)
+	if err != nil {
+		return
+	}
+
+	logger.FromContext(ctx).InfoContext(ctx, "payment intent created", "loan_id", loanId, "term", term, "user_id", userId, "reference", created.GatewayReference)
+	return created, nil
+}
+
+// VerifyPaymentWebhook checks the signature and timestamp of a payment gateway callback against its raw body.
+func (u *usecase) VerifyPaymentWebhook(ctx context.Context, sig model.WebhookSignature, body []byte) (err error) {
+	ctx, span := tracing.Start(ctx, "usecase.VerifyPaymentWebhook")
+	defer tracing.End(span, &err)
+
+	err = payment.Verify([]byte(u.cfg.Payment.WebhookSecret), sig, body, time.Now(), u.cfg.Payment.WebhookTolerance)
+	if err != nil {
+		logger.FromContext(ctx).WarnContext(ctx, "payment webhook rejected", "error", err)
+		return apperror.ErrInvalidWebhookSignature.WithCause(err)
+	}
+
+	return
+}
+
+// SettlePayment applies a verified gateway callback. The nonce, the intent and the payment are written in one
+// transaction with the intent, the loan and its repayments locked, so a term is paid exactly once however often the
+// gateway calls back and whatever is paid meanwhile. A payment failed at the gateway fails the intent. A payment
+// received that can't be applied anymore (the intent expired, the term was paid meanwhile, the amount differs...)
+// leaves the intent UNAPPLIED with the received amount and queues a loan.payment_unapplied event, the money is
+// refunded or applied by hand.
+func (u *usecase) SettlePayment(ctx context.Context, nonce string, event model.PaymentWebhookReq) (err error) {
+	ctx, span := tracing.Start(ctx, "usecase.SettlePayment", attribute.String("reference", event.Reference))
+	defer tracing.End(span, &err)
+
+	tx, err := u.repository.BeginTx(ctx)
+	if err != nil {
+		return
+	}
+	defer u.repository.RollbackTx(tx)
+
+	now := time.Now()
+	err = u.repository.UseWebhookNonce(ctx, tx, nonce, now, now.Add(-2*u.cfg.Payment.WebhookTolerance))
+	if err != nil {
+		return
+	}
+
+	intent, err := u.repository.GetPaymentIntentForUpdate(ctx, tx, event.Reference)
+	if err != nil {
+		return
+	}
+
+	log := logger.FromContext(ctx)
+	if intent.Status != constant.PaymentIntentStatusPending {
+		log.InfoContext(ctx, "payment intent already settled", "reference", intent.GatewayReference, "status", intent.Status)
+		return u.repository.CommitTx(tx)
+	}
+
+	intent.Status = constant.PaymentIntentStatusSucceeded
+	intent.SettledAt = &now
+
+	var settled loanPayment
+	switch {
+	case event.Status == constant.PaymentIntentStatusFailed:
+		intent.Status = constant.PaymentIntentStatusFailed
+		intent.FailureReason = "payment failed at the gateway"
+	case now.After(intent.ExpiresAt):
+		unapplyIntent(&intent, event.Amount, "payment intent expired")
+	case roundCents(event.Amount) != roundCents(intent.Amount):
+		unapplyIntent(&intent, event.Amount, "settled amount does not match the intent")
+	default:
+		settled, err = u.lockPayment(ctx, tx, intent.Amount, intent.LoanId, intent.Term, intent.UserId)
+		if err != nil {
+			if apperror.CodeOf(err) == apperror.CodeInternal {
+				return
+			}
+			unapplyIntent(&intent, event.Amount, err.Error())
+			err = nil
+			break
+		}
+		err = u.applyPayment(ctx, tx, settled)
+		if err != nil {
+			return
+		}
+	}
+
+	if intent.Status == constant.PaymentIntentStatusUnapplied {
+		err = u.recordLoanEvent(ctx, tx, constant.EventLoanPaymentUnapplied, intent.LoanId, model.LoanPaymentUnappliedEvent{
+			LoanId:    intent.LoanId,
+			Term:      intent.Term,
+			Reference: intent.GatewayReference,
+			Amount:    event.Amount,
+			Reason:    intent.FailureReason,
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	err = u.repository.UpdatePaymentIntent(ctx, tx, intent)
+	if err != nil {
+		return
+	}
+
+	err = u.repository.CommitTx(tx)
+	if err != nil {
+		return
+	}
+
+	switch intent.Status {
+	case constant.PaymentIntentStatusSucceeded:
+		metrics.PaymentReceived(intent.Amount, settled.settlesLoan())
+		log.InfoContext(ctx, "loan payment received", "loan_id", intent.LoanId, "term", intent.Term, "user_id", intent.UserId, "reference", intent.GatewayReference)
+	case constant.PaymentIntentStatusUnapplied:
+		metrics.PaymentUnapplied(event.Amount)
+		log.ErrorContext(ctx, "payment received but not applied, refund it or apply it by hand", "loan_id", intent.LoanId, "term", intent.Term, "reference", intent.GatewayReference, "amount", event.Amount, "reason", intent.FailureReason)
+	default:
+		log.WarnContext(ctx, "payment intent failed", "loan_id", intent.LoanId, "term", intent.Term, "reference", intent.GatewayReference, "reason", intent.FailureReason)
+	}
+	return
+}
+
+// unapplyIntent marks an intent whose payment was received but can't be applied to its term.
+func unapplyIntent(intent *model.PaymentIntent, received float64, reason string) {
+	intent.Status = constant.PaymentIntentStatusUnapplied
+	intent.FailureReason = reason
+	intent.ReceivedAmount = &received
+}
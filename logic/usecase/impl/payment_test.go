@@ -0,0 +1,541 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	repo "example.com/m/v2/logic/repository"
+	"example.com/m/v2/model"
+	"example.com/m/v2/payment"
+	"example.com/m/v2/util"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_CreatePaymentIntent(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+
+	amount := float64(1000)
+	disbursed := model.Loan{Id: 1, Amount: &amount, Status: constant.LoanStatusDisbursed}
+	repayments := []model.Repayment{
+		{Id: 1, LoanId: 1, MinimumPayment: 500, Status: constant.RepaymentStatusPending},
+		{Id: 2, LoanId: 1, MinimumPayment: 500, Status: constant.RepaymentStatusPending},
+	}
+
+	// payable mocks the reads of previewPayment for a disbursed loan with nothing paid yet
+	payable := func() {
+		repoMock.
+			On("GetLoanByIdAndUserId", mock.Anything, int64(1), int64(2)).
+			Return(disbursed, nil).
+			Once()
+
+		repoMock.
+			On("GetRepaymentByLoanId", mock.Anything, int64(1)).
+			Return(repayments, nil).
+			Once()
+	}
+
+	pending := func(pending bool) {
+		repoMock.
+			On("HasPendingPaymentIntent", mock.Anything, int64(1), int64(1), mock.Anything).
+			Return(pending, nil).
+			Once()
+	}
+
+	gatewayReq := mock.MatchedBy(func(req model.PaymentIntentRequest) bool {
+		return req.LoanId == 1 && req.UserId == 2 && req.Term == 1 && req.Amount == 500 && !req.ExpiresAt.IsZero()
+	})
+
+	tests := []struct {
+		name    string
+		mock    func()
+		amount  float64
+		want    model.PaymentIntent
+		wantErr error
+	}{
+		{
+			name: "loan not disbursed",
+			mock: func() {
+				repoMock.
+					On("GetLoanByIdAndUserId", mock.Anything, int64(1), int64(2)).
+					Return(model.Loan{Status: constant.LoanStatusApproved}, nil).
+					Once()
+			},
+			amount:  500,
+			wantErr: apperror.ErrLoanNotDisbursed,
+		},
+		{
+			name: "minimum payment not reached",
+			mock: func() {
+				payable()
+			},
+			amount:  400,
+			wantErr: apperror.ErrMinimumPaymentNotReached,
+		},
+		{
+			name: "fail HasPendingPaymentIntent",
+			mock: func() {
+				payable()
+
+				repoMock.
+					On("HasPendingPaymentIntent", mock.Anything, int64(1), int64(1), mock.Anything).
+					Return(false, errors.New("err HasPendingPaymentIntent")).
+					Once()
+			},
+			amount:  500,
+			wantErr: errors.New("err HasPendingPaymentIntent"),
+		},
+		{
+			name: "term already has a pending intent",
+			mock: func() {
+				payable()
+				pending(true)
+			},
+			amount:  500,
+			wantErr: apperror.ErrPaymentIntentPending,
+		},
+		{
+			name: "fail CreateGatewayIntent",
+			mock: func() {
+				payable()
+				pending(false)
+
+				repoMock.
+					On("CreateGatewayIntent", mock.Anything, gatewayReq).
+					Return(model.PaymentIntentResult{}, errors.New("err CreateGatewayIntent")).
+					Once()
+			},
+			amount:  500,
+			wantErr: errors.New("err CreateGatewayIntent"),
+		},
+		{
+			name: "pending intent created meanwhile",
+			mock: func() {
+				payable()
+				pending(false)
+
+				repoMock.
+					On("CreateGatewayIntent", mock.Anything, gatewayReq).
+					Return(model.PaymentIntentResult{Reference: "mock_ref", VirtualAccount: "88080000000001"}, nil).
+					Once()
+
+				repoMock.
+					On("PaymentGateway").
+					Return("mock").
+					Once()
+
+				repoMock.
+					On("InsertPaymentIntent", mock.Anything, mock.Anything).
+					Return(int64(0), apperror.ErrPaymentIntentPending).
+					Once()
+			},
+			amount:  500,
+			wantErr: apperror.ErrPaymentIntentPending,
+		},
+		{
+			name: "success",
+			mock: func() {
+				payable()
+				pending(false)
+
+				repoMock.
+					On("CreateGatewayIntent", mock.Anything, gatewayReq).
+					Return(model.PaymentIntentResult{Reference: "mock_ref", VirtualAccount: "88080000000001"}, nil).
+					Once()
+
+				repoMock.
+					On("PaymentGateway").
+					Return("mock").
+					Once()
+
+				repoMock.
+					On("InsertPaymentIntent", mock.Anything, mock.MatchedBy(func(intent model.PaymentIntent) bool {
+						return intent.LoanId == 1 && intent.UserId == 2 && intent.Term == 1 && intent.Amount == 500 &&
+							intent.Gateway == "mock" && intent.GatewayReference == "mock_ref" &&
+							intent.Status == constant.PaymentIntentStatusPending &&
+							intent.ExpiresAt.Sub(intent.CreatedAt) == time.Hour
+					})).
+					Return(int64(7), nil).
+					Once()
+			},
+			amount: 500,
+			want: model.PaymentIntent{
+				Id:               7,
+				LoanId:           1,
+				UserId:           2,
+				Term:             1,
+				Amount:           500,
+				Gateway:          "mock",
+				GatewayReference: "mock_ref",
+				VirtualAccount:   "88080000000001",
+				Status:           constant.PaymentIntentStatusPending,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		u := usecase{
+			repository: repoMock,
+			cfg:        testCfg,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			got, err := u.CreatePaymentIntent(context.Background(), tt.amount, 1, 1, 2)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("CreatePaymentIntent test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+			got.ExpiresAt = time.Time{}
+			got.CreatedAt = time.Time{}
+			if got != tt.want {
+				t.Errorf("CreatePaymentIntent test failed. want: %+v, got: %+v", tt.want, got)
+			}
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_VerifyPaymentWebhook(t *testing.T) {
+	body := []byte(`{"reference":"mock_ref","status":"SUCCEEDED","amount":500}`)
+	now := time.Now()
+	signed := model.WebhookSignature{
+		Timestamp: strconv.FormatInt(now.Unix(), 10),
+		Nonce:     "nonce",
+		Signature: payment.Sign([]byte(testCfg.Payment.WebhookSecret), now.Unix(), "nonce", body),
+	}
+
+	tests := []struct {
+		name    string
+		sig     model.WebhookSignature
+		wantErr error
+	}{
+		{
+			name: "valid",
+			sig:  signed,
+		},
+		{
+			name: "signed with another secret",
+			sig: func() model.WebhookSignature {
+				s := signed
+				s.Signature = payment.Sign([]byte("other"), now.Unix(), "nonce", body)
+				return s
+			}(),
+			wantErr: apperror.ErrInvalidWebhookSignature,
+		},
+		{
+			name: "stale timestamp",
+			sig: func() model.WebhookSignature {
+				at := now.Add(-time.Hour).Unix()
+				return model.WebhookSignature{
+					Timestamp: strconv.FormatInt(at, 10),
+					Nonce:     "nonce",
+					Signature: payment.Sign([]byte(testCfg.Payment.WebhookSecret), at, "nonce", body),
+				}
+			}(),
+			wantErr: apperror.ErrInvalidWebhookSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		u := usecase{
+			cfg: testCfg,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			err := u.VerifyPaymentWebhook(context.Background(), tt.sig, body)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("VerifyPaymentWebhook test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_SettlePayment(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+
+	amount := float64(1000)
+	borrower := int64(2)
+	disbursed := model.Loan{Id: 1, UserId: &borrower, Amount: &amount, Status: constant.LoanStatusDisbursed}
+	paidAmount := float64(500)
+	repayments := []model.Repayment{
+		{Id: 1, LoanId: 1, MinimumPayment: 500, Status: constant.RepaymentStatusPending},
+		{Id: 2, LoanId: 1, MinimumPayment: 500, Status: constant.RepaymentStatusPending},
+	}
+
+	pending := model.PaymentIntent{
+		Id:               7,
+		LoanId:           1,
+		UserId:           2,
+		Term:             1,
+		Amount:           500,
+		GatewayReference: "mock_ref",
+		Status:           constant.PaymentIntentStatusPending,
+		ExpiresAt:        time.Now().Add(time.Hour),
+	}
+	event := model.PaymentWebhookReq{Reference: "mock_ref", Status: constant.PaymentIntentStatusSucceeded, Amount: 500}
+
+	// begin mocks the transaction up to the locked intent
+	begin := func(intent model.PaymentIntent, err error) {
+		repoMock.
+			On("BeginTx", mock.Anything).
+			Return(&sql.Tx{}, nil).
+			Once()
+
+		repoMock.
+			On("RollbackTx", &sql.Tx{}).
+			Return(nil).
+			Once()
+
+		repoMock.
+			On("UseWebhookNonce", mock.Anything, &sql.Tx{}, "nonce", mock.Anything, mock.Anything).
+			Return(nil).
+			Once()
+
+		repoMock.
+			On("GetPaymentIntentForUpdate", mock.Anything, &sql.Tx{}, "mock_ref").
+			Return(intent, err).
+			Once()
+	}
+
+	// settle mocks the intent update with status and the commit
+	settle := func(status, reason string) {
+		repoMock.
+			On("UpdatePaymentIntent", mock.Anything, &sql.Tx{}, mock.MatchedBy(func(intent model.PaymentIntent) bool {
+				return intent.Id == 7 && intent.Status == status && intent.FailureReason == reason && intent.SettledAt != nil
+			})).
+			Return(nil).
+			Once()
+
+		repoMock.
+			On("CommitTx", &sql.Tx{}).
+			Return(nil).
+			Once()
+	}
+
+	// unapply mocks the loan.payment_unapplied event, the intent left UNAPPLIED with the received amount and the commit
+	unapply := func(reason string, received float64, payload string) {
+		repoMock.
+			On("InsertOutboxEvent", mock.Anything, &sql.Tx{}, loanEvent(constant.EventLoanPaymentUnapplied, 1, payload)).
+			Return(int64(1), nil).
+			Once()
+
+		repoMock.
+			On("UpdatePaymentIntent", mock.Anything, &sql.Tx{}, mock.MatchedBy(func(intent model.PaymentIntent) bool {
+				return intent.Id == 7 && intent.Status == constant.PaymentIntentStatusUnapplied && intent.FailureReason == reason &&
+					intent.ReceivedAmount != nil && *intent.ReceivedAmount == received && intent.SettledAt != nil
+			})).
+			Return(nil).
+			Once()
+
+		repoMock.
+			On("CommitTx", &sql.Tx{}).
+			Return(nil).
+			Once()
+	}
+
+	tests := []struct {
+		name    string
+		mock    func()
+		event   model.PaymentWebhookReq
+		wantErr error
+	}{
+		{
+			name: "fail beginTx",
+			mock: func() {
+				repoMock.
+					On("BeginTx", mock.Anything).
+					Return(nil, errors.New("err beginTx")).
+					Once()
+			},
+			event:   event,
+			wantErr: errors.New("err beginTx"),
+		},
+		{
+			name: "replayed nonce",
+			mock: func() {
+				repoMock.
+					On("BeginTx", mock.Anything).
+					Return(&sql.Tx{}, nil).
+					Once()
+
+				repoMock.
+					On("RollbackTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("UseWebhookNonce", mock.Anything, &sql.Tx{}, "nonce", mock.Anything, mock.Anything).
+					Return(apperror.ErrWebhookReplayed).
+					Once()
+			},
+			event:   event,
+			wantErr: apperror.ErrWebhookReplayed,
+		},
+		{
+			name: "unknown intent",
+			mock: func() {
+				begin(model.PaymentIntent{}, apperror.ErrPaymentIntentNotFound)
+			},
+			event:   event,
+			wantErr: apperror.ErrPaymentIntentNotFound,
+		},
+		{
+			name: "intent already settled is not paid again",
+			mock: func() {
+				settled := pending
+				settled.Status = constant.PaymentIntentStatusSucceeded
+				begin(settled, nil)
+
+				repoMock.
+					On("CommitTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+			},
+			event: event,
+		},
+		{
+			name: "payment failed at the gateway",
+			mock: func() {
+				begin(pending, nil)
+				settle(constant.PaymentIntentStatusFailed, "payment failed at the gateway")
+			},
+			event: model.PaymentWebhookReq{Reference: "mock_ref", Status: constant.PaymentIntentStatusFailed, Amount: 500},
+		},
+		{
+			name: "settled after the intent expired",
+			mock: func() {
+				expired := pending
+				expired.ExpiresAt = time.Now().Add(-time.Minute)
+				begin(expired, nil)
+				unapply("payment intent expired", 500, `{"loan_id":1,"term":1,"reference":"mock_ref","amount":500,"reason":"payment intent expired"}`)
+			},
+			event: event,
+		},
+		{
+			name: "settled amount differs from the intent",
+			mock: func() {
+				begin(pending, nil)
+				unapply("settled amount does not match the intent", 499, `{"loan_id":1,"term":1,"reference":"mock_ref","amount":499,"reason":"settled amount does not match the intent"}`)
+			},
+			event: model.PaymentWebhookReq{Reference: "mock_ref", Status: constant.PaymentIntentStatusSucceeded, Amount: 499},
+		},
+		{
+			name: "fail InsertOutboxEvent of an unapplied payment",
+			mock: func() {
+				begin(pending, nil)
+
+				repoMock.
+					On("InsertOutboxEvent", mock.Anything, &sql.Tx{}, mock.Anything).
+					Return(int64(0), errors.New("err InsertOutboxEvent")).
+					Once()
+			},
+			event:   model.PaymentWebhookReq{Reference: "mock_ref", Status: constant.PaymentIntentStatusSucceeded, Amount: 499},
+			wantErr: errors.New("err InsertOutboxEvent"),
+		},
+		{
+			name: "term paid meanwhile",
+			mock: func() {
+				begin(pending, nil)
+
+				repoMock.
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(disbursed, nil).
+					Once()
+
+				repoMock.
+					On("GetRepaymentsForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return([]model.Repayment{
+						{Id: 1, LoanId: 1, MinimumPayment: 500, ActualPayment: &paidAmount, Status: constant.RepaymentStatusPaid},
+						repayments[1],
+					}, nil).
+					Once()
+
+				unapply(apperror.ErrTermAlreadyPaid.Error(), 500, `{"loan_id":1,"term":1,"reference":"mock_ref","amount":500,"reason":"`+apperror.ErrTermAlreadyPaid.Error()+`"}`)
+			},
+			event: event,
+		},
+		{
+			name: "fail GetRepaymentsForUpdate",
+			mock: func() {
+				begin(pending, nil)
+
+				repoMock.
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(disbursed, nil).
+					Once()
+
+				repoMock.
+					On("GetRepaymentsForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(nil, errors.New("err GetRepaymentsForUpdate")).
+					Once()
+			},
+			event:   event,
+			wantErr: errors.New("err GetRepaymentsForUpdate"),
+		},
+		{
+			name: "success",
+			mock: func() {
+				begin(pending, nil)
+
+				repoMock.
+					On("GetLoanForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(disbursed, nil).
+					Once()
+
+				repoMock.
+					On("GetRepaymentsForUpdate", mock.Anything, &sql.Tx{}, int64(1)).
+					Return(repayments, nil).
+					Once()
+
+				repoMock.
+					On("UpdateRepayment", mock.Anything, &sql.Tx{}, model.Repayment{
+						Id:            1,
+						Status:        constant.RepaymentStatusPaid,
+						ActualPayment: &paidAmount,
+					}).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("InsertOutboxEvent", mock.Anything, &sql.Tx{}, loanEvent(constant.EventLoanPaymentReceived, 1, `{"loan_id":1,"term":1,"amount":500}`)).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("InsertNotifications", mock.Anything, &sql.Tx{}, userNotification(int64(2), constant.NotificationLoanPaymentReceived, `{"loan_id":1,"term":1,"amount":500}`), testNotificationChannels).
+					Return(int64(1), nil).
+					Once()
+
+				settle(constant.PaymentIntentStatusSucceeded, "")
+			},
+			event: event,
+		},
+	}
+
+	for _, tt := range tests {
+		u := usecase{
+			repository: repoMock,
+			cfg:        testCfg,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			err := u.SettlePayment(context.Background(), "nonce", tt.event)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("SettlePayment test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
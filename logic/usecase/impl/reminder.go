@@ -0,0 +1,66 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"example.com/m/v2/constant"
+	"example.com/m/v2/logger"
+	"example.com/m/v2/model"
+	"example.com/m/v2/tracing"
+)
+
+// SendDueReminders notifies the borrowers of the terms falling due within the reminder window, once per term. The
+// reminder is queued and the term marked reminded in one transaction. A failed term is logged and left for the next
+// run while the others go on, the failures are returned together.
+func (u *usecase) SendDueReminders(ctx context.Context, now time.Time) (sent int, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.SendDueReminders")
+	defer tracing.End(span, &err)
+
+	reminders, err := u.repository.GetRepaymentReminders(ctx, now, now.Add(u.cfg.Jobs.RemindBefore))
+	if err != nil {
+		return
+	}
+
+	log := logger.FromContext(ctx)
+	var errs []error
+	for _, reminder := range reminders {
+		remindErr := u.remindRepayment(ctx, reminder, now)
+		if remindErr != nil {
+			log.ErrorContext(ctx, "due date reminder failed", "loan_id", reminder.LoanId, "repayment_id", reminder.RepaymentId, "error", remindErr)
+			errs = append(errs, remindErr)
+			continue
+		}
+
+		sent++
+		log.InfoContext(ctx, "due date reminder queued", "loan_id", reminder.LoanId, "repayment_id", reminder.RepaymentId)
+	}
+
+	return sent, errors.Join(errs...)
+}
+
+func (u *usecase) remindRepayment(ctx context.Context, reminder model.RepaymentReminder, now time.Time) (err error) {
+	tx, err := u.repository.BeginTx(ctx)
+	if err != nil {
+		return
+	}
+	defer u.repository.RollbackTx(tx)
+
+	err = u.notifyUser(ctx, tx, reminder.UserId, constant.NotificationLoanRepaymentDue, model.RepaymentDueNotification{
+		LoanId:         reminder.LoanId,
+		Term:           reminder.Term,
+		MinimumPayment: reminder.MinimumPayment,
+		DueDate:        reminder.DueDate.Format(time.DateOnly),
+	})
+	if err != nil {
+		return
+	}
+
+	err = u.repository.MarkRepaymentReminded(ctx, tx, reminder.RepaymentId, now)
+	if err != nil {
+		return
+	}
+
+	return u.repository.CommitTx(tx)
+}
@@ -0,0 +1,146 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"example.com/m/v2/constant"
+	repo "example.com/m/v2/logic/repository"
+	"example.com/m/v2/model"
+	"example.com/m/v2/util"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_SendDueReminders(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+
+	now := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	dueBefore := now.Add(72 * time.Hour)
+
+	reminders := []model.RepaymentReminder{
+		{RepaymentId: 5, LoanId: 1, Term: 2, UserId: 3, MinimumPayment: 3333.33, DueDate: time.Date(2026, 10, 21, 0, 0, 0, 0, time.UTC)},
+		{RepaymentId: 9, LoanId: 2, Term: 1, UserId: 4, MinimumPayment: 500, DueDate: time.Date(2026, 10, 22, 0, 0, 0, 0, time.UTC)},
+	}
+	first := userNotification(int64(3), constant.NotificationLoanRepaymentDue, `{"loan_id":1,"term":2,"minimum_payment":3333.33,"due_date":"2026-10-21"}`)
+	second := userNotification(int64(4), constant.NotificationLoanRepaymentDue, `{"loan_id":2,"term":1,"minimum_payment":500,"due_date":"2026-10-22"}`)
+
+	begin := func() {
+		repoMock.
+			On("BeginTx", mock.Anything).
+			Return(&sql.Tx{}, nil).
+			Once()
+
+		repoMock.
+			On("RollbackTx", &sql.Tx{}).
+			Return(nil).
+			Once()
+	}
+
+	// remind mocks the reminder of a term queued and marked in its transaction
+	remind := func(notification interface{}, repaymentId int64) {
+		begin()
+
+		repoMock.
+			On("InsertNotifications", mock.Anything, &sql.Tx{}, notification, testNotificationChannels).
+			Return(int64(1), nil).
+			Once()
+
+		repoMock.
+			On("MarkRepaymentReminded", mock.Anything, &sql.Tx{}, repaymentId, now).
+			Return(nil).
+			Once()
+
+		repoMock.
+			On("CommitTx", &sql.Tx{}).
+			Return(nil).
+			Once()
+	}
+
+	tests := []struct {
+		name    string
+		mock    func()
+		want    int
+		wantErr error
+	}{
+		{
+			name: "fail GetRepaymentReminders",
+			mock: func() {
+				repoMock.On("GetRepaymentReminders", mock.Anything, now, dueBefore).Return(nil, errors.New("err GetRepaymentReminders")).Once()
+			},
+			wantErr: errors.New("err GetRepaymentReminders"),
+		},
+		{
+			name: "fail InsertNotifications does not stop the other reminders",
+			mock: func() {
+				repoMock.On("GetRepaymentReminders", mock.Anything, now, dueBefore).Return(reminders, nil).Once()
+
+				begin()
+				repoMock.
+					On("InsertNotifications", mock.Anything, &sql.Tx{}, first, testNotificationChannels).
+					Return(int64(0), errors.New("err InsertNotifications")).
+					Once()
+
+				remind(second, 9)
+			},
+			want:    1,
+			wantErr: errors.New("err InsertNotifications"),
+		},
+		{
+			name: "fail MarkRepaymentReminded",
+			mock: func() {
+				repoMock.On("GetRepaymentReminders", mock.Anything, now, dueBefore).Return(reminders, nil).Once()
+
+				begin()
+				repoMock.
+					On("InsertNotifications", mock.Anything, &sql.Tx{}, first, testNotificationChannels).
+					Return(int64(1), nil).
+					Once()
+				repoMock.
+					On("MarkRepaymentReminded", mock.Anything, &sql.Tx{}, int64(5), now).
+					Return(errors.New("err MarkRepaymentReminded")).
+					Once()
+
+				begin()
+				repoMock.
+					On("InsertNotifications", mock.Anything, &sql.Tx{}, second, testNotificationChannels).
+					Return(int64(0), errors.New("err InsertNotifications")).
+					Once()
+			},
+			wantErr: errors.Join(errors.New("err MarkRepaymentReminded"), errors.New("err InsertNotifications")),
+		},
+		{
+			name: "success",
+			mock: func() {
+				repoMock.On("GetRepaymentReminders", mock.Anything, now, dueBefore).Return(reminders, nil).Once()
+				remind(first, 5)
+				remind(second, 9)
+			},
+			want: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		u := usecase{
+			repository: repoMock,
+			cfg:        testCfg,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			got, err := u.SendDueReminders(context.Background(), now)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("SendDueReminders test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("SendDueReminders test failed. want: %d, got: %d", tt.want, got)
+			}
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
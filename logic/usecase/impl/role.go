@@ -0,0 +1,109 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/logger"
+	"example.com/m/v2/model"
+	"example.com/m/v2/policy"
+	"example.com/m/v2/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Authorize resolves the roles of an authenticated user and fails with ErrForbidden unless they grant permission.
+func (u *usecase) Authorize(ctx context.Context, userId int64, permission string) (principal model.Principal, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.Authorize", attribute.Int64("user_id", userId), attribute.String("permission", permission))
+	defer tracing.End(span, &err)
+
+	roles, err := u.repository.GetUserRoles(ctx, userId)
+	if err != nil {
+		return
+	}
+
+	principal = model.Principal{
+		UserId: userId,
+		Roles:  roles,
+	}
+	err = policy.Authorize(principal, permission)
+
+	return
+}
+
+func (u *usecase) ListRoles(ctx context.Context) (roles []model.Role, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.ListRoles")
+	defer tracing.End(span, &err)
+
+	return u.repository.ListRoles(ctx)
+}
+
+// AssignRoles replaces the staff roles of an admin account. Nobody changes their own roles, so the last super
+// admin can't lock everyone out by accident.
+func (u *usecase) AssignRoles(ctx context.Context, actor model.Principal, userId int64, roles []string) (err error) {
+	ctx, span := tracing.Start(ctx, "usecase.AssignRoles", attribute.Int64("user_id", userId))
+	defer tracing.End(span, &err)
+
+	if actor.UserId == userId {
+		err = apperror.ErrCannotChangeOwnRoles
+		return
+	}
+
+	user, err := u.repository.GetUserById(ctx, userId)
+	if err != nil {
+		return
+	}
+	if user.Role != constant.AdminRole {
+		err = apperror.ErrUserNotStaff
+		return
+	}
+
+	known, err := u.repository.ListRoles(ctx)
+	if err != nil {
+		return
+	}
+	err = checkRoles(known, roles)
+	if err != nil {
+		return
+	}
+
+	tx, err := u.repository.BeginTx(ctx)
+	if err != nil {
+		return
+	}
+	defer u.repository.RollbackTx(tx)
+
+	err = u.repository.ReplaceUserRoles(ctx, tx, userId, roles, actor.UserId, time.Now())
+	if err != nil {
+		return
+	}
+
+	err = u.repository.CommitTx(tx)
+	if err != nil {
+		return
+	}
+
+	logger.FromContext(ctx).InfoContext(ctx, "roles assigned", "user_id", userId, "roles", roles, "assigned_by", actor.UserId)
+	return
+}
+
+// checkRoles rejects names that are not in known.
+func checkRoles(known []model.Role, roles []string) error {
+	names := make(map[string]bool, len(known))
+	for _, role := range known {
+		names[role.Name] = true
+	}
+
+	var fields []apperror.FieldError
+	for _, role := range roles {
+		if !names[role] {
+			fields = append(fields, apperror.FieldError{Field: "roles", Message: fmt.Sprintf("unknown role %q", role)})
+		}
+	}
+	if len(fields) > 0 {
+		return apperror.Validation(fields...)
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	repo "example.com/m/v2/logic/repository"
+	"example.com/m/v2/model"
+	"example.com/m/v2/util"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_Authorize(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+	support := model.Role{
+		Name:        constant.RoleSupport,
+		Permissions: []string{constant.PermissionLoanRead, constant.PermissionLoginUnlock},
+	}
+
+	tests := []struct {
+		name       string
+		mock       func()
+		permission string
+		want       model.Principal
+		wantErr    error
+	}{
+		{
+			name: "fail GetUserRoles",
+			mock: func() {
+				repoMock.
+					On("GetUserRoles", mock.Anything, int64(1)).
+					Return([]model.Role(nil), errors.New("err GetUserRoles")).
+					Once()
+			},
+			permission: constant.PermissionLoanRead,
+			wantErr:    errors.New("err GetUserRoles"),
+		},
+		{
+			name: "customer without roles",
+			mock: func() {
+				repoMock.
+					On("GetUserRoles", mock.Anything, int64(1)).
+					Return([]model.Role(nil), nil).
+					Once()
+			},
+			permission: constant.PermissionLoanRead,
+			want:       model.Principal{UserId: 1},
+			wantErr:    apperror.ErrForbidden,
+		},
+		{
+			name: "permission not granted",
+			mock: func() {
+				repoMock.
+					On("GetUserRoles", mock.Anything, int64(1)).
+					Return([]model.Role{support}, nil).
+					Once()
+			},
+			permission: constant.PermissionLoanApprove,
+			want:       model.Principal{UserId: 1, Roles: []model.Role{support}},
+			wantErr:    apperror.ErrForbidden,
+		},
+		{
+			name: "success",
+			mock: func() {
+				repoMock.
+					On("GetUserRoles", mock.Anything, int64(1)).
+					Return([]model.Role{support}, nil).
+					Once()
+			},
+			permission: constant.PermissionLoanRead,
+			want:       model.Principal{UserId: 1, Roles: []model.Role{support}},
+		},
+	}
+
+	for _, tt := range tests {
+		u := usecase{
+			repository: repoMock,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			got, err := u.Authorize(context.Background(), 1, tt.permission)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("Authorize test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Authorize test failed. want: %+v, got: %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_AssignRoles(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+	actor := model.Principal{UserId: 1}
+	known := []model.Role{{Name: constant.RoleFinance}, {Name: constant.RoleSupport}}
+
+	tests := []struct {
+		name    string
+		mock    func()
+		userId  int64
+		roles   []string
+		wantErr error
+	}{
+		{
+			name:    "own roles",
+			userId:  1,
+			roles:   []string{constant.RoleSupport},
+			wantErr: apperror.ErrCannotChangeOwnRoles,
+		},
+		{
+			name: "customer account",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(2)).
+					Return(model.User{Id: 2, Role: constant.CustomerRole}, nil).
+					Once()
+			},
+			userId:  2,
+			roles:   []string{constant.RoleSupport},
+			wantErr: apperror.ErrUserNotStaff,
+		},
+		{
+			name: "unknown role",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(2)).
+					Return(model.User{Id: 2, Role: constant.AdminRole}, nil).
+					Once()
+
+				repoMock.
+					On("ListRoles", mock.Anything).
+					Return(known, nil).
+					Once()
+			},
+			userId:  2,
+			roles:   []string{constant.RoleSupport, "JANITOR"},
+			wantErr: apperror.Validation(apperror.FieldError{Field: "roles", Message: `unknown role "JANITOR"`}),
+		},
+		{
+			name: "fail ReplaceUserRoles",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(2)).
+					Return(model.User{Id: 2, Role: constant.AdminRole}, nil).
+					Once()
+
+				repoMock.
+					On("ListRoles", mock.Anything).
+					Return(known, nil).
+					Once()
+
+				repoMock.
+					On("BeginTx", mock.Anything).
+					Return(&sql.Tx{}, nil).
+					Once()
+
+				repoMock.
+					On("RollbackTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("ReplaceUserRoles", mock.Anything, &sql.Tx{}, int64(2), []string{constant.RoleSupport}, int64(1), mock.Anything).
+					Return(errors.New("err ReplaceUserRoles")).
+					Once()
+			},
+			userId:  2,
+			roles:   []string{constant.RoleSupport},
+			wantErr: errors.New("err ReplaceUserRoles"),
+		},
+		{
+			name: "success revoking every role",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(2)).
+					Return(model.User{Id: 2, Role: constant.AdminRole}, nil).
+					Once()
+
+				repoMock.
+					On("ListRoles", mock.Anything).
+					Return(known, nil).
+					Once()
+
+				repoMock.
+					On("BeginTx", mock.Anything).
+					Return(&sql.Tx{}, nil).
+					Once()
+
+				repoMock.
+					On("RollbackTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("ReplaceUserRoles", mock.Anything, &sql.Tx{}, int64(2), []string{}, int64(1), mock.Anything).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("CommitTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+			},
+			userId: 2,
+			roles:  []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		u := usecase{
+			repository: repoMock,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			err := u.AssignRoles(context.Background(), actor, tt.userId, tt.roles)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("AssignRoles test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+		})
+	}
+}
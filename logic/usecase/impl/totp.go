@@ -0,0 +1,246 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/logger"
+	"example.com/m/v2/model"
+	"example.com/m/v2/totp"
+	"example.com/m/v2/tracing"
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// UserLoginTotp completes a login with a TOTP or recovery code. Wrong codes count as failed logins of the email
+// and the ip, the same lockout applies as for passwords.
+func (u *usecase) UserLoginTotp(ctx context.Context, challengeToken, code, ip string) (token string, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.UserLoginTotp")
+	defer tracing.End(span, &err)
+
+	user, err := u.decodeChallenge(ctx, challengeToken, constant.TokenPurposeTotpChallenge)
+	if err != nil {
+		return
+	}
+
+	err = u.loginGuard.Check(ctx, user.Email, ip)
+	if err != nil {
+		return
+	}
+
+	err = u.verifySecondFactor(ctx, user, code)
+	if errors.Is(err, apperror.ErrInvalidTotpCode) {
+		errFail := u.loginGuard.Fail(ctx, user.Email, ip)
+		if errFail != nil {
+			err = errFail
+			return
+		}
+		logger.FromContext(ctx).WarnContext(ctx, "two factor login failed", "user_id", user.Id, "ip", ip)
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	err = u.loginGuard.Succeed(ctx, user.Email)
+	if err != nil {
+		return
+	}
+
+	return u.sessionToken(user, true)
+}
+
+// DecodeTotpEnrollChallenge resolves the challenge token given to admins who log in before enrolling TOTP.
+func (u *usecase) DecodeTotpEnrollChallenge(ctx context.Context, challengeToken string) (userId int64, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.DecodeTotpEnrollChallenge")
+	defer tracing.End(span, &err)
+
+	user, err := u.decodeChallenge(ctx, challengeToken, constant.TokenPurposeTotpEnroll)
+	if err != nil {
+		return
+	}
+
+	return user.Id, nil
+}
+
+// EnrollTotp stores a new pending secret, replacing a previous one that was never verified.
+func (u *usecase) EnrollTotp(ctx context.Context, userId int64) (enrollment model.TotpEnrollment, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.EnrollTotp", attribute.Int64("user_id", userId))
+	defer tracing.End(span, &err)
+
+	user, err := u.repository.GetUserById(ctx, userId)
+	if err != nil {
+		return
+	}
+	if user.TotpEnabled() {
+		err = apperror.ErrTotpAlreadyEnabled
+		return
+	}
+
+	secret, err := u.repository.TotpGenerateSecret()
+	if err != nil {
+		return
+	}
+
+	err = u.repository.SetUserTotpSecret(ctx, user.Id, secret)
+	if err != nil {
+		return
+	}
+
+	enrollment = model.TotpEnrollment{
+		Secret:          secret,
+		ProvisioningUri: totp.ProvisioningUri(u.cfg.Totp.Issuer, user.Email, secret),
+	}
+
+	return
+}
+
+// EnableTotp activates the pending secret once code proves the authenticator app has it, and returns fresh
+// recovery codes. Existing sessions are logged out.
+func (u *usecase) EnableTotp(ctx context.Context, userId int64, code string) (recoveryCodes []string, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.EnableTotp", attribute.Int64("user_id", userId))
+	defer tracing.End(span, &err)
+
+	user, err := u.repository.GetUserById(ctx, userId)
+	if err != nil {
+		return
+	}
+	if user.TotpEnabled() {
+		err = apperror.ErrTotpAlreadyEnabled
+		return
+	}
+	if user.TotpSecret == "" {
+		err = apperror.ErrTotpNotEnrolled
+		return
+	}
+
+	now := time.Now()
+	step, ok := u.repository.TotpValidate(user.TotpSecret, code, now)
+	if !ok {
+		err = apperror.ErrInvalidTotpCode
+		return
+	}
+
+	codes, err := u.repository.TotpRecoveryCodes()
+	if err != nil {
+		return
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, c := range codes {
+		hashes = append(hashes, hashToken(c))
+	}
+
+	tx, err := u.repository.BeginTx(ctx)
+	if err != nil {
+		return
+	}
+	defer u.repository.RollbackTx(tx)
+
+	err = u.repository.EnableUserTotp(ctx, tx, user.Id, step, now)
+	if err != nil {
+		return
+	}
+
+	err = u.repository.ReplaceRecoveryCodes(ctx, tx, user.Id, hashes, now)
+	if err != nil {
+		return
+	}
+
+	err = u.repository.CommitTx(tx)
+	if err != nil {
+		return
+	}
+
+	logger.FromContext(ctx).InfoContext(ctx, "two factor enabled", "user_id", user.Id)
+	return codes, nil
+}
+
+// loginChallenge signs the token standing in for a session until the second step is done.
+func (u *usecase) loginChallenge(user model.User) (res model.LoginResult, err error) {
+	purpose := constant.TokenPurposeTotpChallenge
+	res.TwoFactor = constant.TwoFactorVerify
+	if !user.TotpEnabled() {
+		purpose = constant.TokenPurposeTotpEnroll
+		res.TwoFactor = constant.TwoFactorEnroll
+	}
+
+	res.ChallengeToken, err = u.repository.JwtSign(u.repository.JwtNew(jwt.MapClaims{
+		"sub":     strconv.FormatInt(user.Id, 10),
+		"purpose": purpose,
+		"sv":      user.SessionVersion,
+		"exp":     time.Now().Add(u.cfg.Totp.ChallengeTtl).Unix(),
+	}))
+
+	return
+}
+
+// decodeChallenge resolves a challenge token of purpose, a password change since the login invalidates it.
+func (u *usecase) decodeChallenge(ctx context.Context, challengeToken, purpose string) (user model.User, err error) {
+	claims, err := u.repository.JwtParse(challengeToken)
+	if err != nil {
+		err = apperror.ErrInvalidChallengeToken.WithCause(err)
+		return
+	}
+
+	gotPurpose, _ := claims["purpose"].(string)
+	sub, _ := claims["sub"].(string)
+	sessionVersion, _ := claims["sv"].(float64)
+	id, errId := strconv.ParseInt(sub, 10, 64)
+	if gotPurpose != purpose || errId != nil {
+		err = apperror.ErrInvalidChallengeToken
+		return
+	}
+
+	user, err = u.repository.GetUserById(ctx, id)
+	if errors.Is(err, apperror.ErrUserNotFound) {
+		err = apperror.ErrInvalidChallengeToken.WithCause(err)
+		return
+	}
+	if err != nil {
+		return
+	}
+	if int64(sessionVersion) != user.SessionVersion {
+		user, err = model.User{}, apperror.ErrInvalidChallengeToken
+	}
+
+	return
+}
+
+// verifySecondFactor accepts a TOTP code of a step after the last accepted one, or an unused recovery code.
+func (u *usecase) verifySecondFactor(ctx context.Context, user model.User, code string) (err error) {
+	if !user.TotpEnabled() {
+		return apperror.ErrInvalidChallengeToken
+	}
+
+	if isTotpCode(code) {
+		step, ok := u.repository.TotpValidate(user.TotpSecret, code, time.Now())
+		if !ok || step <= user.TotpLastStep {
+			return apperror.ErrInvalidTotpCode
+		}
+		return u.repository.UseTotpStep(ctx, user.Id, step)
+	}
+
+	err = u.repository.ConsumeRecoveryCode(ctx, user.Id, hashToken(totp.NormalizeRecoveryCode(code)), time.Now())
+	if err != nil {
+		return
+	}
+
+	logger.FromContext(ctx).WarnContext(ctx, "recovery code used", "user_id", user.Id)
+	return
+}
+
+func isTotpCode(code string) bool {
+	if len(code) != totp.Digits {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
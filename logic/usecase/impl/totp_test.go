@@ -0,0 +1,514 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/lockout"
+	repo "example.com/m/v2/logic/repository"
+	"example.com/m/v2/model"
+	"example.com/m/v2/util"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_UserLoginTotp(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+	enabledAt := time.Now()
+	user := model.User{
+		Id:            1,
+		Email:         "tes@tes.com",
+		Role:          constant.AdminRole,
+		TotpSecret:    "JBSWY3DPEHPK3PXP",
+		TotpEnabledAt: &enabledAt,
+		TotpLastStep:  100,
+	}
+	challenge := jwt.MapClaims{
+		"sub":     "1",
+		"purpose": constant.TokenPurposeTotpChallenge,
+		"sv":      float64(0),
+	}
+
+	tests := []struct {
+		name    string
+		mock    func()
+		before  func(g *lockout.Guard)
+		code    string
+		wantErr error
+		want    string
+		// wantFailures is the number of failures counted against the email
+		wantFailures int
+	}{
+		{
+			name: "fail JwtParse",
+			mock: func() {
+				repoMock.
+					On("JwtParse", "challenge").
+					Return(jwt.MapClaims(nil), errors.New("token is expired")).
+					Once()
+			},
+			code:    "123456",
+			wantErr: apperror.ErrInvalidChallengeToken,
+		},
+		{
+			name: "enroll challenge can't log in",
+			mock: func() {
+				repoMock.
+					On("JwtParse", "challenge").
+					Return(jwt.MapClaims{"sub": "1", "purpose": constant.TokenPurposeTotpEnroll, "sv": float64(0)}, nil).
+					Once()
+			},
+			code:    "123456",
+			wantErr: apperror.ErrInvalidChallengeToken,
+		},
+		{
+			name: "challenge issued before a password change",
+			mock: func() {
+				repoMock.
+					On("JwtParse", "challenge").
+					Return(challenge, nil).
+					Once()
+
+				revoked := user
+				revoked.SessionVersion = 1
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(revoked, nil).
+					Once()
+			},
+			code:    "123456",
+			wantErr: apperror.ErrInvalidChallengeToken,
+		},
+		{
+			name: "locked",
+			mock: func() {
+				repoMock.
+					On("JwtParse", "challenge").
+					Return(challenge, nil).
+					Once()
+
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(user, nil).
+					Once()
+			},
+			before: func(g *lockout.Guard) {
+				for i := 0; i < testLockoutCfg.AccountThreshold; i++ {
+					g.Fail(context.Background(), "tes@tes.com", "")
+				}
+			},
+			code:         "123456",
+			wantErr:      apperror.ErrTooManyLoginAttempts,
+			wantFailures: testLockoutCfg.AccountThreshold,
+		},
+		{
+			name: "wrong code counts a failure",
+			mock: func() {
+				repoMock.
+					On("JwtParse", "challenge").
+					Return(challenge, nil).
+					Once()
+
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(user, nil).
+					Once()
+
+				repoMock.
+					On("TotpValidate", "JBSWY3DPEHPK3PXP", "123456", mock.Anything).
+					Return(int64(0), false).
+					Once()
+			},
+			code:         "123456",
+			wantErr:      apperror.ErrInvalidTotpCode,
+			wantFailures: 1,
+		},
+		{
+			name: "replayed code",
+			mock: func() {
+				repoMock.
+					On("JwtParse", "challenge").
+					Return(challenge, nil).
+					Once()
+
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(user, nil).
+					Once()
+
+				repoMock.
+					On("TotpValidate", "JBSWY3DPEHPK3PXP", "123456", mock.Anything).
+					Return(int64(100), true).
+					Once()
+			},
+			code:         "123456",
+			wantErr:      apperror.ErrInvalidTotpCode,
+			wantFailures: 1,
+		},
+		{
+			name: "unknown recovery code",
+			mock: func() {
+				repoMock.
+					On("JwtParse", "challenge").
+					Return(challenge, nil).
+					Once()
+
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(user, nil).
+					Once()
+
+				repoMock.
+					On("ConsumeRecoveryCode", mock.Anything, int64(1), hashToken("abcde-fghij"), mock.Anything).
+					Return(apperror.ErrInvalidTotpCode.WithCause(sql.ErrNoRows)).
+					Once()
+			},
+			code:         "ABCDEFGHIJ",
+			wantErr:      apperror.ErrInvalidTotpCode,
+			wantFailures: 1,
+		},
+		{
+			name: "success totp",
+			mock: func() {
+				repoMock.
+					On("JwtParse", "challenge").
+					Return(challenge, nil).
+					Once()
+
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(user, nil).
+					Once()
+
+				repoMock.
+					On("TotpValidate", "JBSWY3DPEHPK3PXP", "123456", mock.Anything).
+					Return(int64(101), true).
+					Once()
+
+				repoMock.
+					On("UseTotpStep", mock.Anything, int64(1), int64(101)).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("JwtNew", jwt.MapClaims{
+						"id":   int64(1),
+						"role": constant.AdminRole,
+						"sv":   int64(0),
+						"tfa":  true,
+					}).
+					Return(&jwt.Token{}).
+					Once()
+
+				repoMock.
+					On("JwtSign", &jwt.Token{}).
+					Return("got", nil).
+					Once()
+			},
+			before: func(g *lockout.Guard) {
+				g.Fail(context.Background(), "tes@tes.com", "")
+			},
+			code: "123456",
+			want: "got",
+		},
+		{
+			name: "success recovery code",
+			mock: func() {
+				repoMock.
+					On("JwtParse", "challenge").
+					Return(challenge, nil).
+					Once()
+
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(user, nil).
+					Once()
+
+				repoMock.
+					On("ConsumeRecoveryCode", mock.Anything, int64(1), hashToken("abcde-fghij"), mock.Anything).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("JwtNew", jwt.MapClaims{
+						"id":   int64(1),
+						"role": constant.AdminRole,
+						"sv":   int64(0),
+						"tfa":  true,
+					}).
+					Return(&jwt.Token{}).
+					Once()
+
+				repoMock.
+					On("JwtSign", &jwt.Token{}).
+					Return("got", nil).
+					Once()
+			},
+			code: " abcde-fghij ",
+			want: "got",
+		},
+	}
+
+	for _, tt := range tests {
+		store := lockout.NewMemoryStore()
+		guard := lockout.New(store, testLockoutCfg)
+		u := usecase{
+			repository: repoMock,
+			cfg:        testCfg,
+			loginGuard: guard,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+			if tt.before != nil {
+				tt.before(guard)
+			}
+
+			got, err := u.UserLoginTotp(context.Background(), "challenge", tt.code, "192.0.2.1")
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("UserLoginTotp test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("UserLoginTotp test failed. want: %+v, got: %+v", tt.want, got)
+			}
+			state, _ := store.Get(context.Background(), lockout.AccountKey("tes@tes.com"))
+			if state.Failures != tt.wantFailures {
+				t.Errorf("UserLoginTotp test failed. wantFailures: %d, gotFailures: %d", tt.wantFailures, state.Failures)
+			}
+		})
+	}
+}
+
+func Test_EnrollTotp(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+	enabledAt := time.Now()
+
+	tests := []struct {
+		name    string
+		mock    func()
+		wantErr error
+		want    model.TotpEnrollment
+	}{
+		{
+			name: "already enabled",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(model.User{Id: 1, TotpEnabledAt: &enabledAt}, nil).
+					Once()
+			},
+			wantErr: apperror.ErrTotpAlreadyEnabled,
+		},
+		{
+			name: "fail SetUserTotpSecret",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(model.User{Id: 1, Email: "tes@tes.com"}, nil).
+					Once()
+
+				repoMock.
+					On("TotpGenerateSecret").
+					Return("SECRET", nil).
+					Once()
+
+				repoMock.
+					On("SetUserTotpSecret", mock.Anything, int64(1), "SECRET").
+					Return(errors.New("err SetUserTotpSecret")).
+					Once()
+			},
+			wantErr: errors.New("err SetUserTotpSecret"),
+		},
+		{
+			name: "success",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(model.User{Id: 1, Email: "tes@tes.com"}, nil).
+					Once()
+
+				repoMock.
+					On("TotpGenerateSecret").
+					Return("SECRET", nil).
+					Once()
+
+				repoMock.
+					On("SetUserTotpSecret", mock.Anything, int64(1), "SECRET").
+					Return(nil).
+					Once()
+			},
+			want: model.TotpEnrollment{
+				Secret:          "SECRET",
+				ProvisioningUri: "otpauth://totp/tes:tes@tes.com?algorithm=SHA1&digits=6&issuer=tes&period=30&secret=SECRET",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		u := usecase{
+			repository: repoMock,
+			cfg:        testCfg,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			got, err := u.EnrollTotp(context.Background(), 1)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("EnrollTotp test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EnrollTotp test failed. want: %+v, got: %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_EnableTotp(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+	pending := model.User{Id: 1, Email: "tes@tes.com", TotpSecret: "SECRET"}
+
+	tests := []struct {
+		name    string
+		mock    func()
+		wantErr error
+		want    []string
+	}{
+		{
+			name: "not enrolled",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(model.User{Id: 1}, nil).
+					Once()
+			},
+			wantErr: apperror.ErrTotpNotEnrolled,
+		},
+		{
+			name: "wrong code",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(pending, nil).
+					Once()
+
+				repoMock.
+					On("TotpValidate", "SECRET", "123456", mock.Anything).
+					Return(int64(0), false).
+					Once()
+			},
+			wantErr: apperror.ErrInvalidTotpCode,
+		},
+		{
+			name: "fail EnableUserTotp",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(pending, nil).
+					Once()
+
+				repoMock.
+					On("TotpValidate", "SECRET", "123456", mock.Anything).
+					Return(int64(7), true).
+					Once()
+
+				repoMock.
+					On("TotpRecoveryCodes").
+					Return([]string{"aaaaa-bbbbb"}, nil).
+					Once()
+
+				repoMock.
+					On("BeginTx", mock.Anything).
+					Return(&sql.Tx{}, nil).
+					Once()
+
+				repoMock.
+					On("RollbackTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("EnableUserTotp", mock.Anything, &sql.Tx{}, int64(1), int64(7), mock.Anything).
+					Return(apperror.ErrTotpAlreadyEnabled).
+					Once()
+			},
+			wantErr: apperror.ErrTotpAlreadyEnabled,
+		},
+		{
+			name: "success",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(pending, nil).
+					Once()
+
+				repoMock.
+					On("TotpValidate", "SECRET", "123456", mock.Anything).
+					Return(int64(7), true).
+					Once()
+
+				repoMock.
+					On("TotpRecoveryCodes").
+					Return([]string{"aaaaa-bbbbb", "ccccc-ddddd"}, nil).
+					Once()
+
+				repoMock.
+					On("BeginTx", mock.Anything).
+					Return(&sql.Tx{}, nil).
+					Once()
+
+				repoMock.
+					On("RollbackTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("EnableUserTotp", mock.Anything, &sql.Tx{}, int64(1), int64(7), mock.Anything).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("ReplaceRecoveryCodes", mock.Anything, &sql.Tx{}, int64(1), []string{hashToken("aaaaa-bbbbb"), hashToken("ccccc-ddddd")}, mock.Anything).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("CommitTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+			},
+			want: []string{"aaaaa-bbbbb", "ccccc-ddddd"},
+		},
+	}
+
+	for _, tt := range tests {
+		u := usecase{
+			repository: repoMock,
+			cfg:        testCfg,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			got, err := u.EnableTotp(context.Background(), 1, "123456")
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("EnableTotp test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EnableTotp test failed. want: %+v, got: %+v", tt.want, got)
+			}
+		})
+	}
+}
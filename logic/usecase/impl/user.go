@@ -4,44 +4,236 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
+	"example.com/m/v2/apperror"
 	"example.com/m/v2/constant"
+	"example.com/m/v2/logger"
 	"example.com/m/v2/model"
+	"example.com/m/v2/tracing"
 	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-func (u *usecase) UserLogin(ctx context.Context, email, password string) (token string, err error) {
+// UserLogin answers ErrInvalidCredentials for unknown emails and wrong passwords alike, after the same bcrypt work,
+// and counts both as failures of the email and the ip. Locked emails and ips are rejected before any lookup.
+// Users with TOTP enabled, and admins who must enroll it, get a challenge token instead of a session.
+func (u *usecase) UserLogin(ctx context.Context, email, password, ip string) (res model.LoginResult, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.UserLogin")
+	defer tracing.End(span, &err)
+
+	email = model.NormalizeEmail(email)
+	err = u.loginGuard.Check(ctx, email, ip)
+	if err != nil {
+		return
+	}
+
 	user, err := u.repository.GetUserByEmail(ctx, email)
+	if errors.Is(err, apperror.ErrUserNotFound) {
+		user, err = model.User{Password: constant.DummyPasswordHash}, nil
+	}
 	if err != nil {
 		return
 	}
-	if user.Id <= 0 {
-		err = errors.New("email not registered")
+
+	errCompare := u.repository.BcryptComparePassword([]byte(user.Password), []byte(password))
+	if user.Id <= 0 || errCompare != nil {
+		// a failure that can't be recorded must not give the attacker a free guess
+		err = u.loginGuard.Fail(ctx, email, ip)
+		if err != nil {
+			return
+		}
+		logger.FromContext(ctx).WarnContext(ctx, "login failed", "ip", ip)
+		err = apperror.ErrInvalidCredentials
 		return
 	}
 
-	err = u.repository.BcryptComparePassword([]byte(user.Password), []byte(password))
+	err = u.loginGuard.Succeed(ctx, email)
 	if err != nil {
 		return
 	}
 
-	tkn := u.repository.JwtNew(jwt.MapClaims{
-		"id":   user.Id,
-		"role": user.Role,
-	})
+	if user.TotpEnabled() || user.Role == constant.AdminRole {
+		return u.loginChallenge(user)
+	}
 
-	token, err = u.repository.JwtSign(tkn)
+	res.SessionToken, err = u.sessionToken(user, false)
 
 	return
 }
 
+func (u *usecase) UnlockLogin(ctx context.Context, email, ip string) (err error) {
+	ctx, span := tracing.Start(ctx, "usecase.UnlockLogin")
+	defer tracing.End(span, &err)
+
+	email = model.NormalizeEmail(email)
+	err = u.loginGuard.Unlock(ctx, email, ip)
+	if err != nil {
+		return
+	}
+
+	logger.FromContext(ctx).InfoContext(ctx, "login unlocked", "email", email, "ip", ip)
+	return
+}
+
 func (u *usecase) UserRegister(ctx context.Context, user model.User) (err error) {
+	ctx, span := tracing.Start(ctx, "usecase.UserRegister")
+	defer tracing.End(span, &err)
+
+	user.Email = model.NormalizeEmail(user.Email)
+	user.Role = constant.CustomerRole
+	user.EmailVerifiedAt = nil
+
+	id, err := u.createUser(ctx, user)
+	if err != nil {
+		return
+	}
+
+	// the account exists at this point, a lost mail can be sent again through ResendVerification
+	errMail := u.sendVerification(ctx, id, user.Email)
+	if errMail != nil {
+		logger.FromContext(ctx).ErrorContext(ctx, "send verification mail", "user_id", id, "error", errMail)
+	}
+
+	return
+}
+
+func (u *usecase) UserCreateAdmin(ctx context.Context, email, password string) (err error) {
+	ctx, span := tracing.Start(ctx, "usecase.UserCreateAdmin")
+	defer tracing.End(span, &err)
+
+	// admins are created by an operator, their email is trusted
+	now := time.Now()
+	_, err = u.createUser(ctx, model.User{
+		Email:           model.NormalizeEmail(email),
+		Password:        password,
+		Role:            constant.AdminRole,
+		EmailVerifiedAt: &now,
+	}, constant.RoleSuperAdmin)
+
+	return
+}
+
+// SeedAdmin creates the SUPER_ADMIN SeedAdminEmail with password, or a generated one when it is empty. Either way the
+// password must be changed on first login, generated is returned so it can be shown once.
+func (u *usecase) SeedAdmin(ctx context.Context, password string) (generated string, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.SeedAdmin")
+	defer tracing.End(span, &err)
+
+	if password == "" {
+		generated, err = u.repository.RandomToken()
+		if err != nil {
+			return
+		}
+		password = generated
+	}
+
+	now := time.Now()
+	_, err = u.createUser(ctx, model.User{
+		Email:                  constant.SeedAdminEmail,
+		Password:               password,
+		Role:                   constant.AdminRole,
+		EmailVerifiedAt:        &now,
+		PasswordChangeRequired: true,
+	}, constant.RoleSuperAdmin)
+	if err != nil {
+		return "", err
+	}
+
+	return
+}
+
+func (u *usecase) GetUser(ctx context.Context, userId int64) (user model.User, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.GetUser", attribute.Int64("user_id", userId))
+	defer tracing.End(span, &err)
+
+	return u.repository.GetUserById(ctx, userId)
+}
+
+func (u *usecase) VerifyEmail(ctx context.Context, token string) (err error) {
+	ctx, span := tracing.Start(ctx, "usecase.VerifyEmail")
+	defer tracing.End(span, &err)
+
+	claims, err := u.repository.JwtParse(token)
+	if err != nil {
+		err = apperror.ErrInvalidVerificationToken.WithCause(err)
+		return
+	}
+
+	purpose, _ := claims["purpose"].(string)
+	email, _ := claims["email"].(string)
+	sub, _ := claims["sub"].(string)
+	id, errId := strconv.ParseInt(sub, 10, 64)
+	if purpose != constant.TokenPurposeVerifyEmail || email == "" || errId != nil {
+		err = apperror.ErrInvalidVerificationToken
+		return
+	}
+
+	err = u.repository.VerifyUserEmail(ctx, id, email, time.Now())
+	if err != nil {
+		return
+	}
+
+	logger.FromContext(ctx).InfoContext(ctx, "email verified", "user_id", id)
+	return
+}
+
+func (u *usecase) ResendVerification(ctx context.Context, userId int64) (err error) {
+	ctx, span := tracing.Start(ctx, "usecase.ResendVerification", attribute.Int64("user_id", userId))
+	defer tracing.End(span, &err)
+
+	user, err := u.repository.GetUserById(ctx, userId)
+	if err != nil {
+		return
+	}
+	if user.EmailVerified() {
+		err = apperror.ErrEmailAlreadyVerified
+		return
+	}
+
+	return u.sendVerification(ctx, user.Id, user.Email)
+}
+
+// sendVerification mails a signed link proving ownership of email, it expires after cfg.Verification.TokenTtl.
+// The purpose claim keeps it from being accepted as a session and sessions from being accepted as a verification.
+func (u *usecase) sendVerification(ctx context.Context, userId int64, email string) (err error) {
+	token, err := u.repository.JwtSign(u.repository.JwtNew(jwt.MapClaims{
+		"sub":     strconv.FormatInt(userId, 10),
+		"email":   email,
+		"purpose": constant.TokenPurposeVerifyEmail,
+		"exp":     time.Now().Add(u.cfg.Verification.TokenTtl).Unix(),
+	}))
+	if err != nil {
+		return
+	}
+
+	link := u.cfg.Verification.Url + "?token=" + url.QueryEscape(token)
+
+	return u.repository.SendMail(ctx, model.Mail{
+		To:      email,
+		Subject: "Verify your email",
+		Text: "Welcome to mini aspire!\n\n" +
+			"Confirm your email address by opening the link below, it expires in " + u.cfg.Verification.TokenTtl.String() + ".\n\n" +
+			link + "\n\n" +
+			"If you did not create an account you can ignore this mail.",
+	})
+}
+
+// createUser stores a new account and grants it roles, staff roles only make sense for admins. Customers get a
+// welcome notification.
+func (u *usecase) createUser(ctx context.Context, user model.User, roles ...string) (id int64, err error) {
+	err = u.passwordPolicy.Check(user.Password, user.Email)
+	if err != nil {
+		return
+	}
+
 	hashPass, err := u.repository.BcryptGenerateHash([]byte(user.Password))
 	if err != nil {
 		return
 	}
 	user.Password = string(hashPass)
-	user.Role = constant.CustomerRole
 
 	tx, err := u.repository.BeginTx(ctx)
 	if err != nil {
@@ -49,7 +241,7 @@ func (u *usecase) UserRegister(ctx context.Context, user model.User) (err error)
 	}
 	defer u.repository.RollbackTx(tx)
 
-	id, err := u.repository.InsertUser(ctx, tx, user)
+	id, err = u.repository.InsertUser(ctx, tx, user)
 	if err != nil {
 		return
 	}
@@ -58,12 +250,32 @@ func (u *usecase) UserRegister(ctx context.Context, user model.User) (err error)
 		return
 	}
 
+	if len(roles) > 0 {
+		err = u.repository.ReplaceUserRoles(ctx, tx, id, roles, 0, time.Now())
+		if err != nil {
+			return
+		}
+	}
+
+	if user.Role == constant.CustomerRole {
+		err = u.notifyUser(ctx, tx, id, constant.NotificationUserRegistered, model.UserRegisteredNotification{Email: user.Email})
+		if err != nil {
+			return
+		}
+	}
+
 	err = u.repository.CommitTx(tx)
 
 	return
 }
 
-func (u *usecase) DecodeJwt(cookies []*http.Cookie) (claims jwt.MapClaims, err error) {
+// DecodeJwt resolves the session cookie, rejecting sessions issued before the user's last password change and
+// admin sessions opened without a second factor. A user who must change their password gets the claims along with
+// ErrPasswordChangeRequired, only the password change accepts them.
+func (u *usecase) DecodeJwt(ctx context.Context, cookies []*http.Cookie) (claims jwt.MapClaims, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.DecodeJwt")
+	defer tracing.End(span, &err)
+
 	tokenStr := ""
 	for _, c := range cookies {
 		if c.Name == "SID" {
@@ -71,9 +283,61 @@ func (u *usecase) DecodeJwt(cookies []*http.Cookie) (claims jwt.MapClaims, err e
 		}
 	}
 	if tokenStr == "" {
-		err = errors.New("cookie not found")
+		err = apperror.ErrCookieNotFound
+		return
+	}
+
+	claims, err = u.repository.JwtParse(tokenStr)
+	if err != nil {
+		err = apperror.ErrInvalidToken.WithCause(err)
+		return
+	}
+	if _, ok := claims["purpose"]; ok {
+		claims, err = nil, apperror.ErrInvalidToken
+		return
+	}
+
+	id, ok := claims["id"].(float64)
+	if !ok {
+		claims, err = nil, apperror.ErrInvalidToken
+		return
+	}
+	sessionVersion, _ := claims["sv"].(float64)
+
+	user, err := u.repository.GetUserById(ctx, int64(id))
+	if errors.Is(err, apperror.ErrUserNotFound) {
+		claims, err = nil, apperror.ErrInvalidToken.WithCause(err)
+		return
+	}
+	if err != nil {
+		claims = nil
+		return
+	}
+	if int64(sessionVersion) != user.SessionVersion {
+		claims, err = nil, apperror.ErrSessionRevoked
 		return
 	}
+	if twoFactor, _ := claims["tfa"].(bool); !twoFactor && (user.Role == constant.AdminRole || user.TotpEnabled()) {
+		claims, err = nil, apperror.ErrTwoFactorRequired
+		return
+	}
+	if user.PasswordChangeRequired {
+		err = apperror.ErrPasswordChangeRequired
+	}
+
+	return
+}
+
+// sessionToken signs the SID cookie value, twoFactor records that a TOTP or recovery code was checked.
+func (u *usecase) sessionToken(user model.User, twoFactor bool) (token string, err error) {
+	claims := jwt.MapClaims{
+		"id":   user.Id,
+		"role": user.Role,
+		"sv":   user.SessionVersion,
+	}
+	if twoFactor {
+		claims["tfa"] = true
+	}
 
-	return u.repository.JwtParse(tokenStr)
+	return u.repository.JwtSign(u.repository.JwtNew(claims))
 }
@@ -6,13 +6,18 @@ import (
 	"errors"
 	"net/http"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"example.com/m/v2/apperror"
 	"example.com/m/v2/constant"
+	"example.com/m/v2/lockout"
 	repo "example.com/m/v2/logic/repository"
 	"example.com/m/v2/model"
 	"example.com/m/v2/util"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/mock"
 )
 
 func Test_UserLogin(t *testing.T) {
@@ -21,25 +26,51 @@ func Test_UserLogin(t *testing.T) {
 	type args struct {
 		email string
 		pass  string
+		ip    string
 	}
 
 	req := args{
-		email: "tes",
+		email: " Tes@tes.com",
 		pass:  "tes",
+		ip:    "192.0.2.1",
+	}
+
+	// lock fails the threshold of the account in testLockoutCfg
+	lock := func(g *lockout.Guard) {
+		for i := 0; i < testLockoutCfg.AccountThreshold; i++ {
+			g.Fail(context.Background(), "tes@tes.com", "")
+		}
 	}
 
 	tests := []struct {
 		name    string
 		mock    func()
+		before  func(g *lockout.Guard)
+		store   lockout.Store
 		args    args
 		wantErr error
-		want    string
+		want    model.LoginResult
+		// wantLocked checks that the failure was counted
+		wantLocked bool
 	}{
+		{
+			name:       "fail locked",
+			before:     lock,
+			args:       req,
+			wantErr:    apperror.ErrTooManyLoginAttempts,
+			wantLocked: true,
+		},
+		{
+			name:    "fail lockout store",
+			store:   errLockoutStore{},
+			args:    req,
+			wantErr: errors.New("err lockout store"),
+		},
 		{
 			name: "fail GetUserByEmail",
 			mock: func() {
 				repoMock.
-					On("GetUserByEmail", context.Background(), "tes").
+					On("GetUserByEmail", mock.Anything, "tes@tes.com").
 					Return(model.User{}, errors.New("err GetUserByEmail")).
 					Once()
 			},
@@ -47,21 +78,32 @@ func Test_UserLogin(t *testing.T) {
 			args:    req,
 		},
 		{
-			name: "fail email not registered",
+			name: "fail user not found compares against the dummy hash",
 			mock: func() {
 				repoMock.
-					On("GetUserByEmail", context.Background(), "tes").
-					Return(model.User{}, nil).
+					On("GetUserByEmail", mock.Anything, "tes@tes.com").
+					Return(model.User{}, apperror.ErrUserNotFound.WithCause(sql.ErrNoRows)).
+					Once()
+
+				repoMock.
+					On("BcryptComparePassword", []byte(constant.DummyPasswordHash), []byte("tes")).
+					Return(errors.New("err BcryptComparePassword")).
 					Once()
 			},
-			wantErr: errors.New("email not registered"),
-			args:    req,
+			before: func(g *lockout.Guard) {
+				for i := 0; i < testLockoutCfg.AccountThreshold-1; i++ {
+					g.Fail(context.Background(), "tes@tes.com", "")
+				}
+			},
+			wantErr:    apperror.ErrInvalidCredentials,
+			args:       req,
+			wantLocked: true,
 		},
 		{
 			name: "fail BcryptComparePassword",
 			mock: func() {
 				repoMock.
-					On("GetUserByEmail", context.Background(), "tes").
+					On("GetUserByEmail", mock.Anything, "tes@tes.com").
 					Return(model.User{
 						Id:       1,
 						Password: "tes",
@@ -73,14 +115,20 @@ func Test_UserLogin(t *testing.T) {
 					Return(errors.New("err BcryptComparePassword")).
 					Once()
 			},
-			wantErr: errors.New("err BcryptComparePassword"),
-			args:    req,
+			before: func(g *lockout.Guard) {
+				for i := 0; i < testLockoutCfg.AccountThreshold-1; i++ {
+					g.Fail(context.Background(), "tes@tes.com", "")
+				}
+			},
+			wantErr:    apperror.ErrInvalidCredentials,
+			args:       req,
+			wantLocked: true,
 		},
 		{
 			name: "fail JwtSign",
 			mock: func() {
 				repoMock.
-					On("GetUserByEmail", context.Background(), "tes").
+					On("GetUserByEmail", mock.Anything, "tes@tes.com").
 					Return(model.User{
 						Id:       1,
 						Password: "tes",
@@ -97,6 +145,7 @@ func Test_UserLogin(t *testing.T) {
 					On("JwtNew", jwt.MapClaims{
 						"id":   int64(1),
 						"role": "tes",
+						"sv":   int64(0),
 					}).
 					Return(&jwt.Token{}).
 					Once()
@@ -110,10 +159,10 @@ func Test_UserLogin(t *testing.T) {
 			args:    req,
 		},
 		{
-			name: "success",
+			name: "success resets the account failures",
 			mock: func() {
 				repoMock.
-					On("GetUserByEmail", context.Background(), "tes").
+					On("GetUserByEmail", mock.Anything, "tes@tes.com").
 					Return(model.User{
 						Id:       1,
 						Password: "tes",
@@ -130,6 +179,7 @@ func Test_UserLogin(t *testing.T) {
 					On("JwtNew", jwt.MapClaims{
 						"id":   int64(1),
 						"role": "tes",
+						"sv":   int64(0),
 					}).
 					Return(&jwt.Token{}).
 					Once()
@@ -139,28 +189,172 @@ func Test_UserLogin(t *testing.T) {
 					Return("got", nil).
 					Once()
 			},
+			before: func(g *lockout.Guard) {
+				for i := 0; i < testLockoutCfg.AccountThreshold-1; i++ {
+					g.Fail(context.Background(), "tes@tes.com", "")
+				}
+			},
+			args: req,
+			want: model.LoginResult{SessionToken: "got"},
+		},
+		{
+			name: "success admin must enroll totp",
+			mock: func() {
+				repoMock.
+					On("GetUserByEmail", mock.Anything, "tes@tes.com").
+					Return(model.User{
+						Id:             1,
+						Password:       "tes",
+						Role:           constant.AdminRole,
+						SessionVersion: 2,
+					}, nil).
+					Once()
+
+				repoMock.
+					On("BcryptComparePassword", []byte("tes"), []byte("tes")).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("JwtNew", mock.MatchedBy(func(claims jwt.MapClaims) bool {
+						return claims["sub"] == "1" && claims["purpose"] == constant.TokenPurposeTotpEnroll && claims["sv"] == int64(2)
+					})).
+					Return(&jwt.Token{}).
+					Once()
+
+				repoMock.
+					On("JwtSign", &jwt.Token{}).
+					Return("challenge", nil).
+					Once()
+			},
+			args: req,
+			want: model.LoginResult{ChallengeToken: "challenge", TwoFactor: constant.TwoFactorEnroll},
+		},
+		{
+			name: "success totp enabled needs a code",
+			mock: func() {
+				enabledAt := time.Now()
+				repoMock.
+					On("GetUserByEmail", mock.Anything, "tes@tes.com").
+					Return(model.User{
+						Id:            1,
+						Password:      "tes",
+						Role:          constant.CustomerRole,
+						TotpSecret:    "JBSWY3DPEHPK3PXP",
+						TotpEnabledAt: &enabledAt,
+					}, nil).
+					Once()
+
+				repoMock.
+					On("BcryptComparePassword", []byte("tes"), []byte("tes")).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("JwtNew", mock.MatchedBy(func(claims jwt.MapClaims) bool {
+						return claims["sub"] == "1" && claims["purpose"] == constant.TokenPurposeTotpChallenge
+					})).
+					Return(&jwt.Token{}).
+					Once()
+
+				repoMock.
+					On("JwtSign", &jwt.Token{}).
+					Return("challenge", nil).
+					Once()
+			},
 			args: req,
-			want: "got",
+			want: model.LoginResult{ChallengeToken: "challenge", TwoFactor: constant.TwoFactorVerify},
 		},
 	}
 
 	for _, tt := range tests {
+		store := tt.store
+		if store == nil {
+			store = lockout.NewMemoryStore()
+		}
+		guard := lockout.New(store, testLockoutCfg)
 		u := usecase{
 			repository: repoMock,
+			cfg:        testCfg,
+			loginGuard: guard,
 		}
 
 		t.Run(tt.name, func(t *testing.T) {
 			if tt.mock != nil {
 				tt.mock()
 			}
+			if tt.before != nil {
+				tt.before(guard)
+			}
 
-			got, err := u.UserLogin(context.Background(), tt.args.email, tt.args.pass)
-			if !util.SameErrorMessage(err, tt.wantErr) {
+			got, err := u.UserLogin(context.Background(), tt.args.email, tt.args.pass, tt.args.ip)
+			if !util.SameError(err, tt.wantErr) {
 				t.Errorf("UserLogin test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
 			}
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("UserLogin test failed. want: %+v, got: %+v", tt.want, got)
 			}
+			if tt.store == nil {
+				locked := guard.Check(context.Background(), "tes@tes.com", "") != nil
+				if locked != tt.wantLocked {
+					t.Errorf("UserLogin test failed. wantLocked: %v, gotLocked: %v", tt.wantLocked, locked)
+				}
+			}
+		})
+	}
+}
+
+func Test_UnlockLogin(t *testing.T) {
+	tests := []struct {
+		name    string
+		store   lockout.Store
+		email   string
+		ip      string
+		wantErr error
+	}{
+		{
+			name:    "fail lockout store",
+			store:   errLockoutStore{},
+			email:   "tes@tes.com",
+			wantErr: errors.New("err lockout store"),
+		},
+		{
+			name:  "success email",
+			email: " Tes@tes.com",
+		},
+		{
+			name: "success ip",
+			ip:   "192.0.2.1",
+		},
+	}
+
+	for _, tt := range tests {
+		store := tt.store
+		if store == nil {
+			store = lockout.NewMemoryStore()
+		}
+		guard := lockout.New(store, testLockoutCfg)
+		u := usecase{
+			loginGuard: guard,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.store == nil {
+				for i := 0; i < testLockoutCfg.IpThreshold; i++ {
+					guard.Fail(context.Background(), "tes@tes.com", "192.0.2.1")
+				}
+			}
+
+			err := u.UnlockLogin(context.Background(), tt.email, tt.ip)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("UnlockLogin test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+			if tt.store == nil {
+				errCheck := guard.Check(context.Background(), model.NormalizeEmail(tt.email), tt.ip)
+				if errCheck != nil {
+					t.Errorf("UnlockLogin test failed. still locked: %v", errCheck)
+				}
+			}
 		})
 	}
 }
@@ -174,22 +368,38 @@ func Test_UserRegister(t *testing.T) {
 
 	req := args{
 		user: model.User{
-			Email:    "tes",
-			Password: "tes",
+			Email:    " Tes@Tes.com",
+			Password: "correct-horse-battery",
 		},
 	}
 
+	insertUser := model.User{
+		Email:    "tes@tes.com",
+		Password: "hash",
+		Role:     constant.CustomerRole,
+	}
+
 	tests := []struct {
 		name    string
 		mock    func()
 		args    args
 		wantErr error
 	}{
+		{
+			name: "password rejected by policy",
+			args: args{
+				user: model.User{
+					Email:    "tes@tes.com",
+					Password: "Password123",
+				},
+			},
+			wantErr: apperror.Validation(apperror.FieldError{Field: "password", Message: "is too common, choose another one"}),
+		},
 		{
 			name: "fail BcryptGenerateHash",
 			mock: func() {
 				repoMock.
-					On("BcryptGenerateHash", []byte("tes")).
+					On("BcryptGenerateHash", []byte("correct-horse-battery")).
 					Return([]byte(""), errors.New("err BcryptGenerateHash")).
 					Once()
 			},
@@ -200,12 +410,12 @@ func Test_UserRegister(t *testing.T) {
 			name: "fail beginTx",
 			mock: func() {
 				repoMock.
-					On("BcryptGenerateHash", []byte("tes")).
-					Return([]byte("tes"), nil).
+					On("BcryptGenerateHash", []byte("correct-horse-battery")).
+					Return([]byte("hash"), nil).
 					Once()
 
 				repoMock.
-					On("BeginTx", context.Background()).
+					On("BeginTx", mock.Anything).
 					Return(nil, errors.New("err beginTx")).
 					Once()
 			},
@@ -216,12 +426,12 @@ func Test_UserRegister(t *testing.T) {
 			name: "fail InsertUser",
 			mock: func() {
 				repoMock.
-					On("BcryptGenerateHash", []byte("tes")).
-					Return([]byte("tes"), nil).
+					On("BcryptGenerateHash", []byte("correct-horse-battery")).
+					Return([]byte("hash"), nil).
 					Once()
 
 				repoMock.
-					On("BeginTx", context.Background()).
+					On("BeginTx", mock.Anything).
 					Return(&sql.Tx{}, nil).
 					Once()
 
@@ -231,11 +441,7 @@ func Test_UserRegister(t *testing.T) {
 					Once()
 
 				repoMock.
-					On("InsertUser", context.Background(), &sql.Tx{}, model.User{
-						Email:    "tes",
-						Password: "tes",
-						Role:     constant.CustomerRole,
-					}).
+					On("InsertUser", mock.Anything, &sql.Tx{}, insertUser).
 					Return(int64(0), errors.New("err InsertUser")).
 					Once()
 			},
@@ -246,12 +452,12 @@ func Test_UserRegister(t *testing.T) {
 			name: "user not created",
 			mock: func() {
 				repoMock.
-					On("BcryptGenerateHash", []byte("tes")).
-					Return([]byte("tes"), nil).
+					On("BcryptGenerateHash", []byte("correct-horse-battery")).
+					Return([]byte("hash"), nil).
 					Once()
 
 				repoMock.
-					On("BeginTx", context.Background()).
+					On("BeginTx", mock.Anything).
 					Return(&sql.Tx{}, nil).
 					Once()
 
@@ -261,27 +467,54 @@ func Test_UserRegister(t *testing.T) {
 					Once()
 
 				repoMock.
-					On("InsertUser", context.Background(), &sql.Tx{}, model.User{
-						Email:    "tes",
-						Password: "tes",
-						Role:     constant.CustomerRole,
-					}).
+					On("InsertUser", mock.Anything, &sql.Tx{}, insertUser).
 					Return(int64(0), nil).
 					Once()
 			},
 			args:    req,
 			wantErr: errors.New("failed create user"),
 		},
+		{
+			name: "fail InsertNotifications",
+			mock: func() {
+				repoMock.
+					On("BcryptGenerateHash", []byte("correct-horse-battery")).
+					Return([]byte("hash"), nil).
+					Once()
+
+				repoMock.
+					On("BeginTx", mock.Anything).
+					Return(&sql.Tx{}, nil).
+					Once()
+
+				repoMock.
+					On("RollbackTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("InsertUser", mock.Anything, &sql.Tx{}, insertUser).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("InsertNotifications", mock.Anything, &sql.Tx{}, mock.Anything, testNotificationChannels).
+					Return(int64(0), errors.New("err InsertNotifications")).
+					Once()
+			},
+			args:    req,
+			wantErr: errors.New("err InsertNotifications"),
+		},
 		{
 			name: "fail CommitTx",
 			mock: func() {
 				repoMock.
-					On("BcryptGenerateHash", []byte("tes")).
-					Return([]byte("tes"), nil).
+					On("BcryptGenerateHash", []byte("correct-horse-battery")).
+					Return([]byte("hash"), nil).
 					Once()
 
 				repoMock.
-					On("BeginTx", context.Background()).
+					On("BeginTx", mock.Anything).
 					Return(&sql.Tx{}, nil).
 					Once()
 
@@ -291,11 +524,12 @@ func Test_UserRegister(t *testing.T) {
 					Once()
 
 				repoMock.
-					On("InsertUser", context.Background(), &sql.Tx{}, model.User{
-						Email:    "tes",
-						Password: "tes",
-						Role:     constant.CustomerRole,
-					}).
+					On("InsertUser", mock.Anything, &sql.Tx{}, insertUser).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("InsertNotifications", mock.Anything, &sql.Tx{}, userNotification(1, constant.NotificationUserRegistered, `{"email":"tes@tes.com"}`), testNotificationChannels).
 					Return(int64(1), nil).
 					Once()
 
@@ -311,12 +545,12 @@ func Test_UserRegister(t *testing.T) {
 			name: "success",
 			mock: func() {
 				repoMock.
-					On("BcryptGenerateHash", []byte("tes")).
-					Return([]byte("tes"), nil).
+					On("BcryptGenerateHash", []byte("correct-horse-battery")).
+					Return([]byte("hash"), nil).
 					Once()
 
 				repoMock.
-					On("BeginTx", context.Background()).
+					On("BeginTx", mock.Anything).
 					Return(&sql.Tx{}, nil).
 					Once()
 
@@ -326,11 +560,66 @@ func Test_UserRegister(t *testing.T) {
 					Once()
 
 				repoMock.
-					On("InsertUser", context.Background(), &sql.Tx{}, model.User{
-						Email:    "tes",
-						Password: "tes",
-						Role:     constant.CustomerRole,
-					}).
+					On("InsertUser", mock.Anything, &sql.Tx{}, insertUser).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("InsertNotifications", mock.Anything, &sql.Tx{}, userNotification(1, constant.NotificationUserRegistered, `{"email":"tes@tes.com"}`), testNotificationChannels).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("CommitTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("JwtNew", mock.MatchedBy(func(claims jwt.MapClaims) bool {
+						return claims["sub"] == "1" && claims["email"] == "tes@tes.com" && claims["purpose"] == constant.TokenPurposeVerifyEmail
+					})).
+					Return(&jwt.Token{}).
+					Once()
+
+				repoMock.
+					On("JwtSign", &jwt.Token{}).
+					Return("verify", nil).
+					Once()
+
+				repoMock.
+					On("SendMail", mock.Anything, mock.MatchedBy(func(mail model.Mail) bool {
+						return mail.To == "tes@tes.com" && strings.Contains(mail.Text, "https://tes.com/verify?token=verify")
+					})).
+					Return(nil).
+					Once()
+			},
+			args: req,
+		},
+		{
+			name: "mail failure does not fail the registration",
+			mock: func() {
+				repoMock.
+					On("BcryptGenerateHash", []byte("correct-horse-battery")).
+					Return([]byte("hash"), nil).
+					Once()
+
+				repoMock.
+					On("BeginTx", mock.Anything).
+					Return(&sql.Tx{}, nil).
+					Once()
+
+				repoMock.
+					On("RollbackTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("InsertUser", mock.Anything, &sql.Tx{}, insertUser).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("InsertNotifications", mock.Anything, &sql.Tx{}, userNotification(1, constant.NotificationUserRegistered, `{"email":"tes@tes.com"}`), testNotificationChannels).
 					Return(int64(1), nil).
 					Once()
 
@@ -338,6 +627,25 @@ func Test_UserRegister(t *testing.T) {
 					On("CommitTx", &sql.Tx{}).
 					Return(nil).
 					Once()
+
+				repoMock.
+					On("JwtNew", mock.MatchedBy(func(claims jwt.MapClaims) bool {
+						return claims["sub"] == "1" && claims["email"] == "tes@tes.com" && claims["purpose"] == constant.TokenPurposeVerifyEmail
+					})).
+					Return(&jwt.Token{}).
+					Once()
+
+				repoMock.
+					On("JwtSign", &jwt.Token{}).
+					Return("verify", nil).
+					Once()
+
+				repoMock.
+					On("SendMail", mock.Anything, mock.MatchedBy(func(mail model.Mail) bool {
+						return mail.To == "tes@tes.com" && strings.Contains(mail.Text, "https://tes.com/verify?token=verify")
+					})).
+					Return(errors.New("err SendMail")).
+					Once()
 			},
 			args: req,
 		},
@@ -345,7 +653,9 @@ func Test_UserRegister(t *testing.T) {
 
 	for _, tt := range tests {
 		u := usecase{
-			repository: repoMock,
+			repository:     repoMock,
+			cfg:            testCfg,
+			passwordPolicy: testPasswordPolicy,
 		}
 
 		t.Run(tt.name, func(t *testing.T) {
@@ -354,27 +664,24 @@ func Test_UserRegister(t *testing.T) {
 			}
 
 			err := u.UserRegister(context.Background(), tt.args.user)
-			if !util.SameErrorMessage(err, tt.wantErr) {
+			if !util.SameError(err, tt.wantErr) {
 				t.Errorf("UserRegister test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
 			}
 		})
 	}
 }
 
-func Test_DecodeJwt(t *testing.T) {
+func Test_UserCreateAdmin(t *testing.T) {
 	repoMock := new(repo.MockRepository)
 
 	type args struct {
-		cookies []*http.Cookie
+		email    string
+		password string
 	}
 
 	req := args{
-		cookies: []*http.Cookie{
-			{
-				Name:  "SID",
-				Value: "tes",
-			},
-		},
+		email:    "Admin@tes.com",
+		password: "correct-horse-battery",
 	}
 
 	tests := []struct {
@@ -382,43 +689,682 @@ func Test_DecodeJwt(t *testing.T) {
 		mock    func()
 		args    args
 		wantErr error
-		want    jwt.MapClaims
 	}{
 		{
-			name: "cookie not found",
+			name: "password rejected by policy",
 			args: args{
-				cookies: []*http.Cookie{
-					{
-						Value: "tes",
-					},
-				},
+				email:    "admin@tes.com",
+				password: "short",
 			},
-			wantErr: errors.New("cookie not found"),
+			wantErr: apperror.Validation(apperror.FieldError{Field: "password", Message: "must be at least 10 characters"}),
 		},
 		{
-			name:    "fail JwtParse",
-			args:    req,
-			wantErr: errors.New("err JwtParse"),
+			name: "fail BcryptGenerateHash",
 			mock: func() {
 				repoMock.
-					On("JwtParse", "tes").
-					Return(nil, errors.New("err JwtParse")).
+					On("BcryptGenerateHash", []byte("correct-horse-battery")).
+					Return([]byte(""), errors.New("err BcryptGenerateHash")).
 					Once()
 			},
+			args:    req,
+			wantErr: errors.New("err BcryptGenerateHash"),
 		},
 		{
 			name: "success",
-			args: req,
 			mock: func() {
 				repoMock.
-					On("JwtParse", "tes").
-					Return(jwt.MapClaims{
-						"a": "b",
-					}, nil).
+					On("BcryptGenerateHash", []byte("correct-horse-battery")).
+					Return([]byte("hash"), nil).
+					Once()
+
+				repoMock.
+					On("BeginTx", mock.Anything).
+					Return(&sql.Tx{}, nil).
+					Once()
+
+				repoMock.
+					On("RollbackTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("InsertUser", mock.Anything, &sql.Tx{}, mock.MatchedBy(func(user model.User) bool {
+						return user.Email == "admin@tes.com" && user.Password == "hash" && user.Role == constant.AdminRole && user.EmailVerified()
+					})).
+					Return(int64(1), nil).
+					Once()
+
+				repoMock.
+					On("ReplaceUserRoles", mock.Anything, &sql.Tx{}, int64(1), []string{constant.RoleSuperAdmin}, int64(0), mock.Anything).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("CommitTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+			},
+			args: req,
+		},
+	}
+
+	for _, tt := range tests {
+		u := usecase{
+			repository:     repoMock,
+			passwordPolicy: testPasswordPolicy,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			err := u.UserCreateAdmin(context.Background(), tt.args.email, tt.args.password)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("UserCreateAdmin test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_SeedAdmin(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+
+	// create mocks the admin inserted with hash, its password to be changed on first login
+	create := func(password string) {
+		repoMock.
+			On("BcryptGenerateHash", []byte(password)).
+			Return([]byte("hash"), nil).
+			Once()
+
+		repoMock.
+			On("BeginTx", mock.Anything).
+			Return(&sql.Tx{}, nil).
+			Once()
+
+		repoMock.
+			On("RollbackTx", &sql.Tx{}).
+			Return(nil).
+			Once()
+
+		repoMock.
+			On("InsertUser", mock.Anything, &sql.Tx{}, mock.MatchedBy(func(user model.User) bool {
+				return user.Email == constant.SeedAdminEmail && user.Password == "hash" && user.Role == constant.AdminRole &&
+					user.EmailVerified() && user.PasswordChangeRequired
+			})).
+			Return(int64(1), nil).
+			Once()
+
+		repoMock.
+			On("ReplaceUserRoles", mock.Anything, &sql.Tx{}, int64(1), []string{constant.RoleSuperAdmin}, int64(0), mock.Anything).
+			Return(nil).
+			Once()
+
+		repoMock.
+			On("CommitTx", &sql.Tx{}).
+			Return(nil).
+			Once()
+	}
+
+	tests := []struct {
+		name          string
+		mock          func()
+		password      string
+		wantGenerated string
+		wantErr       error
+	}{
+		{
+			name:     "password rejected by policy",
+			password: "admin",
+			wantErr:  apperror.Validation(apperror.FieldError{Field: "password", Message: "must be at least 10 characters"}),
+		},
+		{
+			name: "fail RandomToken",
+			mock: func() {
+				repoMock.
+					On("RandomToken").
+					Return("", errors.New("err RandomToken")).
+					Once()
+			},
+			wantErr: errors.New("err RandomToken"),
+		},
+		{
+			name: "already seeded",
+			mock: func() {
+				repoMock.
+					On("BcryptGenerateHash", []byte("correct-horse-battery")).
+					Return([]byte("hash"), nil).
+					Once()
+
+				repoMock.
+					On("BeginTx", mock.Anything).
+					Return(&sql.Tx{}, nil).
+					Once()
+
+				repoMock.
+					On("RollbackTx", &sql.Tx{}).
+					Return(nil).
+					Once()
+
+				repoMock.
+					On("InsertUser", mock.Anything, &sql.Tx{}, mock.Anything).
+					Return(int64(0), apperror.ErrEmailRegistered).
+					Once()
+			},
+			password: "correct-horse-battery",
+			wantErr:  apperror.ErrEmailRegistered,
+		},
+		{
+			name: "success with the configured password",
+			mock: func() {
+				create("correct-horse-battery")
+			},
+			password: "correct-horse-battery",
+		},
+		{
+			name: "success with a generated password",
+			mock: func() {
+				repoMock.
+					On("RandomToken").
+					Return("generated-password-of-43-characters-long-xx", nil).
+					Once()
+
+				create("generated-password-of-43-characters-long-xx")
+			},
+			wantGenerated: "generated-password-of-43-characters-long-xx",
+		},
+	}
+
+	for _, tt := range tests {
+		u := usecase{
+			repository:     repoMock,
+			passwordPolicy: testPasswordPolicy,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			got, err := u.SeedAdmin(context.Background(), tt.password)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("SeedAdmin test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+			if got != tt.wantGenerated {
+				t.Errorf("SeedAdmin test failed. want: %s, got: %s", tt.wantGenerated, got)
+			}
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_GetUser(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+
+	tests := []struct {
+		name    string
+		mock    func()
+		userId  int64
+		want    model.User
+		wantErr error
+	}{
+		{
+			name: "user not found",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(model.User{}, apperror.ErrUserNotFound.WithCause(sql.ErrNoRows)).
+					Once()
+			},
+			userId:  1,
+			wantErr: apperror.ErrUserNotFound,
+		},
+		{
+			name: "success",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(model.User{
+						Id:    1,
+						Email: "tes@tes.com",
+						Role:  constant.CustomerRole,
+					}, nil).
+					Once()
+			},
+			userId: 1,
+			want: model.User{
+				Id:    1,
+				Email: "tes@tes.com",
+				Role:  constant.CustomerRole,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		u := usecase{
+			repository: repoMock,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			got, err := u.GetUser(context.Background(), tt.userId)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("GetUser test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("GetUser test failed. want: %+v, got: %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_VerifyEmail(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+
+	tests := []struct {
+		name    string
+		mock    func()
+		token   string
+		wantErr error
+	}{
+		{
+			name:  "expired or tampered token",
+			token: "tes",
+			mock: func() {
+				repoMock.
+					On("JwtParse", "tes").
+					Return(nil, errors.New("token is expired")).
+					Once()
+			},
+			wantErr: apperror.ErrInvalidVerificationToken,
+		},
+		{
+			name:  "session token",
+			token: "tes",
+			mock: func() {
+				repoMock.
+					On("JwtParse", "tes").
+					Return(jwt.MapClaims{
+						"id":   float64(1),
+						"role": constant.CustomerRole,
+					}, nil).
+					Once()
+			},
+			wantErr: apperror.ErrInvalidVerificationToken,
+		},
+		{
+			name:  "already used or email changed",
+			token: "tes",
+			mock: func() {
+				repoMock.
+					On("JwtParse", "tes").
+					Return(jwt.MapClaims{
+						"sub":     "1",
+						"email":   "tes@tes.com",
+						"purpose": constant.TokenPurposeVerifyEmail,
+					}, nil).
+					Once()
+
+				repoMock.
+					On("VerifyUserEmail", mock.Anything, int64(1), "tes@tes.com", mock.Anything).
+					Return(apperror.ErrInvalidVerificationToken).
+					Once()
+			},
+			wantErr: apperror.ErrInvalidVerificationToken,
+		},
+		{
+			name:  "success",
+			token: "tes",
+			mock: func() {
+				repoMock.
+					On("JwtParse", "tes").
+					Return(jwt.MapClaims{
+						"sub":     "1",
+						"email":   "tes@tes.com",
+						"purpose": constant.TokenPurposeVerifyEmail,
+					}, nil).
+					Once()
+
+				repoMock.
+					On("VerifyUserEmail", mock.Anything, int64(1), "tes@tes.com", mock.Anything).
+					Return(nil).
+					Once()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		u := usecase{
+			repository: repoMock,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			err := u.VerifyEmail(context.Background(), tt.token)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("VerifyEmail test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_ResendVerification(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+	verifiedAt := time.Now()
+
+	tests := []struct {
+		name    string
+		mock    func()
+		wantErr error
+	}{
+		{
+			name: "already verified",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(model.User{Id: 1, Email: "tes@tes.com", EmailVerifiedAt: &verifiedAt}, nil).
+					Once()
+			},
+			wantErr: apperror.ErrEmailAlreadyVerified,
+		},
+		{
+			name: "fail SendMail",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(model.User{Id: 1, Email: "tes@tes.com"}, nil).
+					Once()
+
+				repoMock.
+					On("JwtNew", mock.Anything).
+					Return(&jwt.Token{}).
+					Once()
+
+				repoMock.
+					On("JwtSign", &jwt.Token{}).
+					Return("verify", nil).
+					Once()
+
+				repoMock.
+					On("SendMail", mock.Anything, mock.Anything).
+					Return(errors.New("err SendMail")).
+					Once()
+			},
+			wantErr: errors.New("err SendMail"),
+		},
+		{
+			name: "success",
+			mock: func() {
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(model.User{Id: 1, Email: "tes@tes.com"}, nil).
+					Once()
+
+				repoMock.
+					On("JwtNew", mock.Anything).
+					Return(&jwt.Token{}).
+					Once()
+
+				repoMock.
+					On("JwtSign", &jwt.Token{}).
+					Return("verify", nil).
+					Once()
+
+				repoMock.
+					On("SendMail", mock.Anything, mock.Anything).
+					Return(nil).
+					Once()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		u := usecase{
+			repository: repoMock,
+			cfg:        testCfg,
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mock != nil {
+				tt.mock()
+			}
+
+			err := u.ResendVerification(context.Background(), 1)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("ResendVerification test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_DecodeJwt(t *testing.T) {
+	repoMock := new(repo.MockRepository)
+
+	type args struct {
+		cookies []*http.Cookie
+	}
+
+	req := args{
+		cookies: []*http.Cookie{
+			{
+				Name:  "SID",
+				Value: "tes",
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		mock    func()
+		args    args
+		wantErr error
+		want    jwt.MapClaims
+	}{
+		{
+			name: "cookie not found",
+			args: args{
+				cookies: []*http.Cookie{
+					{
+						Value: "tes",
+					},
+				},
+			},
+			wantErr: apperror.ErrCookieNotFound,
+		},
+		{
+			name:    "fail JwtParse",
+			args:    req,
+			wantErr: apperror.ErrInvalidToken,
+			mock: func() {
+				repoMock.
+					On("JwtParse", "tes").
+					Return(nil, errors.New("err JwtParse")).
+					Once()
+			},
+		},
+		{
+			name:    "verification token used as session",
+			args:    req,
+			wantErr: apperror.ErrInvalidToken,
+			mock: func() {
+				repoMock.
+					On("JwtParse", "tes").
+					Return(jwt.MapClaims{
+						"sub":     "1",
+						"purpose": constant.TokenPurposeVerifyEmail,
+					}, nil).
+					Once()
+			},
+		},
+		{
+			name:    "missing user id",
+			args:    req,
+			wantErr: apperror.ErrInvalidToken,
+			mock: func() {
+				repoMock.
+					On("JwtParse", "tes").
+					Return(jwt.MapClaims{
+						"a": "b",
+					}, nil).
+					Once()
+			},
+		},
+		{
+			name:    "user deleted",
+			args:    req,
+			wantErr: apperror.ErrInvalidToken,
+			mock: func() {
+				repoMock.
+					On("JwtParse", "tes").
+					Return(jwt.MapClaims{
+						"id": float64(1),
+						"sv": float64(0),
+					}, nil).
+					Once()
+
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(model.User{}, apperror.ErrUserNotFound).
+					Once()
+			},
+		},
+		{
+			name:    "fail GetUserById",
+			args:    req,
+			wantErr: errors.New("err GetUserById"),
+			mock: func() {
+				repoMock.
+					On("JwtParse", "tes").
+					Return(jwt.MapClaims{
+						"id": float64(1),
+						"sv": float64(0),
+					}, nil).
+					Once()
+
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(model.User{}, errors.New("err GetUserById")).
+					Once()
+			},
+		},
+		{
+			name:    "session issued before a password change",
+			args:    req,
+			wantErr: apperror.ErrSessionRevoked,
+			mock: func() {
+				repoMock.
+					On("JwtParse", "tes").
+					Return(jwt.MapClaims{
+						"id": float64(1),
+						"sv": float64(0),
+					}, nil).
+					Once()
+
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(model.User{Id: 1, SessionVersion: 1}, nil).
+					Once()
+			},
+		},
+		{
+			name:    "admin session without a second factor",
+			args:    req,
+			wantErr: apperror.ErrTwoFactorRequired,
+			mock: func() {
+				repoMock.
+					On("JwtParse", "tes").
+					Return(jwt.MapClaims{
+						"id":   float64(1),
+						"role": constant.AdminRole,
+						"sv":   float64(1),
+					}, nil).
+					Once()
+
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(model.User{Id: 1, Role: constant.AdminRole, SessionVersion: 1}, nil).
+					Once()
+			},
+		},
+		{
+			name:    "password change required",
+			args:    req,
+			wantErr: apperror.ErrPasswordChangeRequired,
+			mock: func() {
+				repoMock.
+					On("JwtParse", "tes").
+					Return(jwt.MapClaims{
+						"id":   float64(1),
+						"role": constant.AdminRole,
+						"sv":   float64(1),
+						"tfa":  true,
+					}, nil).
+					Once()
+
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(model.User{Id: 1, Role: constant.AdminRole, SessionVersion: 1, PasswordChangeRequired: true}, nil).
+					Once()
+			},
+			want: jwt.MapClaims{
+				"id":   float64(1),
+				"role": constant.AdminRole,
+				"sv":   float64(1),
+				"tfa":  true,
+			},
+		},
+		{
+			name: "success admin with a second factor",
+			args: req,
+			mock: func() {
+				repoMock.
+					On("JwtParse", "tes").
+					Return(jwt.MapClaims{
+						"id":   float64(1),
+						"role": constant.AdminRole,
+						"sv":   float64(1),
+						"tfa":  true,
+					}, nil).
+					Once()
+
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(model.User{Id: 1, Role: constant.AdminRole, SessionVersion: 1}, nil).
+					Once()
+			},
+			want: jwt.MapClaims{
+				"id":   float64(1),
+				"role": constant.AdminRole,
+				"sv":   float64(1),
+				"tfa":  true,
+			},
+		},
+		{
+			name: "success",
+			args: req,
+			mock: func() {
+				repoMock.
+					On("JwtParse", "tes").
+					Return(jwt.MapClaims{
+						"id": float64(1),
+						"sv": float64(1),
+					}, nil).
+					Once()
+
+				repoMock.
+					On("GetUserById", mock.Anything, int64(1)).
+					Return(model.User{Id: 1, SessionVersion: 1}, nil).
 					Once()
 			},
 			want: jwt.MapClaims{
-				"a": "b",
+				"id": float64(1),
+				"sv": float64(1),
 			},
 		},
 	}
@@ -433,8 +1379,8 @@ func Test_DecodeJwt(t *testing.T) {
 				tt.mock()
 			}
 
-			got, err := u.DecodeJwt(tt.args.cookies)
-			if !util.SameErrorMessage(err, tt.wantErr) {
+			got, err := u.DecodeJwt(context.Background(), tt.args.cookies)
+			if !util.SameError(err, tt.wantErr) {
 				t.Errorf("DecodeJwt test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
 			}
 			if !reflect.DeepEqual(got, tt.want) {
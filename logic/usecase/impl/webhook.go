@@ -0,0 +1,199 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/logger"
+	"example.com/m/v2/model"
+	"example.com/m/v2/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// webhookDeliveriesLimit caps the deliveries listed for an endpoint, the latest come first.
+const webhookDeliveriesLimit = 100
+
+// CreateWebhookEndpoint registers an endpoint with a freshly generated signing secret, returned only this once.
+func (u *usecase) CreateWebhookEndpoint(ctx context.Context, actor model.Principal, endpoint model.WebhookEndpoint) (res model.WebhookEndpoint, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.CreateWebhookEndpoint", attribute.Int64("created_by", actor.UserId))
+	defer tracing.End(span, &err)
+
+	err = u.checkWebhookEndpoint(endpoint)
+	if err != nil {
+		return
+	}
+
+	secret, err := u.webhookSecret()
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	endpoint.Secret = secret
+	endpoint.Active = true
+	endpoint.CreatedBy = actor.UserId
+	endpoint.CreatedAt = now
+	endpoint.UpdatedAt = now
+
+	endpoint.Id, err = u.repository.InsertWebhookEndpoint(ctx, endpoint)
+	if err != nil {
+		return
+	}
+
+	logger.FromContext(ctx).InfoContext(ctx, "webhook endpoint created", "endpoint_id", endpoint.Id, "created_by", actor.UserId)
+	return endpoint, nil
+}
+
+func (u *usecase) ListWebhookEndpoints(ctx context.Context) (endpoints []model.WebhookEndpoint, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.ListWebhookEndpoints")
+	defer tracing.End(span, &err)
+
+	return u.repository.GetWebhookEndpoints(ctx)
+}
+
+// UpdateWebhookEndpoint replaces the settings of an endpoint, deactivating it holds its pending deliveries until it
+// is active again.
+func (u *usecase) UpdateWebhookEndpoint(ctx context.Context, endpoint model.WebhookEndpoint) (res model.WebhookEndpoint, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.UpdateWebhookEndpoint", attribute.Int64("endpoint_id", endpoint.Id))
+	defer tracing.End(span, &err)
+
+	err = u.checkWebhookEndpoint(endpoint)
+	if err != nil {
+		return
+	}
+
+	endpoint.UpdatedAt = time.Now()
+	err = u.repository.UpdateWebhookEndpoint(ctx, endpoint)
+	if err != nil {
+		return
+	}
+
+	return u.repository.GetWebhookEndpointById(ctx, endpoint.Id)
+}
+
+// DeleteWebhookEndpoint removes an endpoint, its pending deliveries are dropped.
+func (u *usecase) DeleteWebhookEndpoint(ctx context.Context, id int64) (err error) {
+	ctx, span := tracing.Start(ctx, "usecase.DeleteWebhookEndpoint", attribute.Int64("endpoint_id", id))
+	defer tracing.End(span, &err)
+
+	err = u.repository.DeleteWebhookEndpoint(ctx, id)
+	if err != nil {
+		return
+	}
+
+	logger.FromContext(ctx).InfoContext(ctx, "webhook endpoint deleted", "endpoint_id", id)
+	return
+}
+
+// RotateWebhookSecret replaces the signing secret of an endpoint, the next attempts are signed with the new one.
+func (u *usecase) RotateWebhookSecret(ctx context.Context, id int64) (res model.WebhookEndpoint, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.RotateWebhookSecret", attribute.Int64("endpoint_id", id))
+	defer tracing.End(span, &err)
+
+	secret, err := u.webhookSecret()
+	if err != nil {
+		return
+	}
+
+	err = u.repository.UpdateWebhookSecret(ctx, id, secret, time.Now())
+	if err != nil {
+		return
+	}
+
+	res, err = u.repository.GetWebhookEndpointById(ctx, id)
+	if err != nil {
+		return
+	}
+
+	logger.FromContext(ctx).InfoContext(ctx, "webhook secret rotated", "endpoint_id", id)
+	return
+}
+
+// GetWebhookDeliveries lists the latest deliveries of an endpoint, status "" lists every status.
+func (u *usecase) GetWebhookDeliveries(ctx context.Context, endpointId int64, status string) (deliveries []model.WebhookDelivery, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.GetWebhookDeliveries", attribute.Int64("endpoint_id", endpointId))
+	defer tracing.End(span, &err)
+
+	switch status {
+	case "", constant.WebhookDeliveryPending, constant.WebhookDeliverySucceeded, constant.WebhookDeliveryFailed:
+	default:
+		err = apperror.Validation(apperror.FieldError{Field: "status", Message: "must be PENDING, SUCCEEDED or FAILED"})
+		return
+	}
+
+	_, err = u.repository.GetWebhookEndpointById(ctx, endpointId)
+	if err != nil {
+		return
+	}
+
+	return u.repository.GetWebhookDeliveries(ctx, endpointId, status, webhookDeliveriesLimit)
+}
+
+// GetWebhookDelivery returns a delivery with its log.
+func (u *usecase) GetWebhookDelivery(ctx context.Context, id int64) (delivery model.WebhookDelivery, err error) {
+	ctx, span := tracing.Start(ctx, "usecase.GetWebhookDelivery", attribute.Int64("delivery_id", id))
+	defer tracing.End(span, &err)
+
+	delivery, err = u.repository.GetWebhookDeliveryById(ctx, id)
+	if err != nil {
+		return
+	}
+
+	delivery.Log, err = u.repository.GetWebhookDeliveryAttempts(ctx, id)
+
+	return
+}
+
+// RedeliverWebhook sends a delivery again right away, a failed or succeeded one included.
+func (u *usecase) RedeliverWebhook(ctx context.Context, id int64) (err error) {
+	ctx, span := tracing.Start(ctx, "usecase.RedeliverWebhook", attribute.Int64("delivery_id", id))
+	defer tracing.End(span, &err)
+
+	err = u.repository.RedeliverWebhook(ctx, id, time.Now())
+	if err != nil {
+		return
+	}
+
+	logger.FromContext(ctx).InfoContext(ctx, "webhook redelivery queued", "delivery_id", id)
+	return
+}
+
+// checkWebhookEndpoint requires an absolute https url, http too when webhooks.allow_http is set, and known event
+// types.
+func (u *usecase) checkWebhookEndpoint(endpoint model.WebhookEndpoint) error {
+	var fields []apperror.FieldError
+
+	parsed, err := url.Parse(endpoint.Url)
+	switch {
+	case err != nil || parsed.Host == "" || (parsed.Scheme != "https" && parsed.Scheme != "http"):
+		fields = append(fields, apperror.FieldError{Field: "url", Message: "must be an absolute http(s) url"})
+	case parsed.Scheme == "http" && !u.cfg.Webhooks.AllowHttp:
+		fields = append(fields, apperror.FieldError{Field: "url", Message: "must use https"})
+	case parsed.User != nil:
+		fields = append(fields, apperror.FieldError{Field: "url", Message: "must not contain credentials"})
+	}
+
+	for _, eventType := range endpoint.EventTypes {
+		if !slices.Contains(constant.EventTypes, eventType) {
+			fields = append(fields, apperror.FieldError{Field: "event_types", Message: fmt.Sprintf("unknown event type %q", eventType)})
+		}
+	}
+
+	if len(fields) > 0 {
+		return apperror.Validation(fields...)
+	}
+	return nil
+}
+
+func (u *usecase) webhookSecret() (string, error) {
+	token, err := u.repository.RandomToken()
+	if err != nil {
+		return "", err
+	}
+	return "whsec_" + token, nil
+}
@@ -11,6 +11,8 @@ import (
 	mock "github.com/stretchr/testify/mock"
 
 	model "example.com/m/v2/model"
+
+	time "time"
 )
 
 // MockUsecase is an autogenerated mock type for the Usecase type
@@ -18,13 +20,37 @@ type MockUsecase struct {
 	mock.Mock
 }
 
-// ApproveLoan provides a mock function with given fields: ctx, loanId
-func (_m *MockUsecase) ApproveLoan(ctx context.Context, loanId int64) error {
-	ret := _m.Called(ctx, loanId)
+// ApproveLoan provides a mock function with given fields: ctx, loanId, approver
+func (_m *MockUsecase) ApproveLoan(ctx context.Context, loanId int64, approver model.Principal) (model.ApprovalResult, error) {
+	ret := _m.Called(ctx, loanId, approver)
+
+	var r0 model.ApprovalResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, model.Principal) (model.ApprovalResult, error)); ok {
+		return rf(ctx, loanId, approver)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, model.Principal) model.ApprovalResult); ok {
+		r0 = rf(ctx, loanId, approver)
+	} else {
+		r0 = ret.Get(0).(model.ApprovalResult)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, model.Principal) error); ok {
+		r1 = rf(ctx, loanId, approver)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AssignRoles provides a mock function with given fields: ctx, actor, userId, roles
+func (_m *MockUsecase) AssignRoles(ctx context.Context, actor model.Principal, userId int64, roles []string) error {
+	ret := _m.Called(ctx, actor, userId, roles)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
-		r0 = rf(ctx, loanId)
+	if rf, ok := ret.Get(0).(func(context.Context, model.Principal, int64, []string) error); ok {
+		r0 = rf(ctx, actor, userId, roles)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -32,25 +58,223 @@ func (_m *MockUsecase) ApproveLoan(ctx context.Context, loanId int64) error {
 	return r0
 }
 
-// DecodeJwt provides a mock function with given fields: cookies
-func (_m *MockUsecase) DecodeJwt(cookies []*http.Cookie) (jwt.MapClaims, error) {
-	ret := _m.Called(cookies)
+// Authorize provides a mock function with given fields: ctx, userId, permission
+func (_m *MockUsecase) Authorize(ctx context.Context, userId int64, permission string) (model.Principal, error) {
+	ret := _m.Called(ctx, userId, permission)
+
+	var r0 model.Principal
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) (model.Principal, error)); ok {
+		return rf(ctx, userId, permission)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) model.Principal); ok {
+		r0 = rf(ctx, userId, permission)
+	} else {
+		r0 = ret.Get(0).(model.Principal)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string) error); ok {
+		r1 = rf(ctx, userId, permission)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ChangePassword provides a mock function with given fields: ctx, userId, oldPassword, newPassword, ip
+func (_m *MockUsecase) ChangePassword(ctx context.Context, userId int64, oldPassword string, newPassword string, ip string) error {
+	ret := _m.Called(ctx, userId, oldPassword, newPassword, ip)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, string, string) error); ok {
+		r0 = rf(ctx, userId, oldPassword, newPassword, ip)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreatePaymentIntent provides a mock function with given fields: ctx, amount, loanId, term, userId
+func (_m *MockUsecase) CreatePaymentIntent(ctx context.Context, amount float64, loanId int64, term int64, userId int64) (model.PaymentIntent, error) {
+	ret := _m.Called(ctx, amount, loanId, term, userId)
+
+	var r0 model.PaymentIntent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, float64, int64, int64, int64) (model.PaymentIntent, error)); ok {
+		return rf(ctx, amount, loanId, term, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, float64, int64, int64, int64) model.PaymentIntent); ok {
+		r0 = rf(ctx, amount, loanId, term, userId)
+	} else {
+		r0 = ret.Get(0).(model.PaymentIntent)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, float64, int64, int64, int64) error); ok {
+		r1 = rf(ctx, amount, loanId, term, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateWebhookEndpoint provides a mock function with given fields: ctx, actor, endpoint
+func (_m *MockUsecase) CreateWebhookEndpoint(ctx context.Context, actor model.Principal, endpoint model.WebhookEndpoint) (model.WebhookEndpoint, error) {
+	ret := _m.Called(ctx, actor, endpoint)
+
+	var r0 model.WebhookEndpoint
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.Principal, model.WebhookEndpoint) (model.WebhookEndpoint, error)); ok {
+		return rf(ctx, actor, endpoint)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, model.Principal, model.WebhookEndpoint) model.WebhookEndpoint); ok {
+		r0 = rf(ctx, actor, endpoint)
+	} else {
+		r0 = ret.Get(0).(model.WebhookEndpoint)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, model.Principal, model.WebhookEndpoint) error); ok {
+		r1 = rf(ctx, actor, endpoint)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DecodeJwt provides a mock function with given fields: ctx, cookies
+func (_m *MockUsecase) DecodeJwt(ctx context.Context, cookies []*http.Cookie) (jwt.MapClaims, error) {
+	ret := _m.Called(ctx, cookies)
 
 	var r0 jwt.MapClaims
 	var r1 error
-	if rf, ok := ret.Get(0).(func([]*http.Cookie) (jwt.MapClaims, error)); ok {
-		return rf(cookies)
+	if rf, ok := ret.Get(0).(func(context.Context, []*http.Cookie) (jwt.MapClaims, error)); ok {
+		return rf(ctx, cookies)
 	}
-	if rf, ok := ret.Get(0).(func([]*http.Cookie) jwt.MapClaims); ok {
-		r0 = rf(cookies)
+	if rf, ok := ret.Get(0).(func(context.Context, []*http.Cookie) jwt.MapClaims); ok {
+		r0 = rf(ctx, cookies)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(jwt.MapClaims)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func([]*http.Cookie) error); ok {
-		r1 = rf(cookies)
+	if rf, ok := ret.Get(1).(func(context.Context, []*http.Cookie) error); ok {
+		r1 = rf(ctx, cookies)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DecodeTotpEnrollChallenge provides a mock function with given fields: ctx, challengeToken
+func (_m *MockUsecase) DecodeTotpEnrollChallenge(ctx context.Context, challengeToken string) (int64, error) {
+	ret := _m.Called(ctx, challengeToken)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (int64, error)); ok {
+		return rf(ctx, challengeToken)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) int64); ok {
+		r0 = rf(ctx, challengeToken)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, challengeToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteWebhookEndpoint provides a mock function with given fields: ctx, id
+func (_m *MockUsecase) DeleteWebhookEndpoint(ctx context.Context, id int64) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DisburseLoan provides a mock function with given fields: ctx, loanId
+func (_m *MockUsecase) DisburseLoan(ctx context.Context, loanId int64) (model.Disbursement, error) {
+	ret := _m.Called(ctx, loanId)
+
+	var r0 model.Disbursement
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (model.Disbursement, error)); ok {
+		return rf(ctx, loanId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) model.Disbursement); ok {
+		r0 = rf(ctx, loanId)
+	} else {
+		r0 = ret.Get(0).(model.Disbursement)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, loanId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// EnableTotp provides a mock function with given fields: ctx, userId, code
+func (_m *MockUsecase) EnableTotp(ctx context.Context, userId int64, code string) ([]string, error) {
+	ret := _m.Called(ctx, userId, code)
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) ([]string, error)); ok {
+		return rf(ctx, userId, code)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) []string); ok {
+		r0 = rf(ctx, userId, code)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string) error); ok {
+		r1 = rf(ctx, userId, code)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// EnrollTotp provides a mock function with given fields: ctx, userId
+func (_m *MockUsecase) EnrollTotp(ctx context.Context, userId int64) (model.TotpEnrollment, error) {
+	ret := _m.Called(ctx, userId)
+
+	var r0 model.TotpEnrollment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (model.TotpEnrollment, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) model.TotpEnrollment); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Get(0).(model.TotpEnrollment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userId)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -58,6 +282,44 @@ func (_m *MockUsecase) DecodeJwt(cookies []*http.Cookie) (jwt.MapClaims, error)
 	return r0, r1
 }
 
+// ExpirePendingLoans provides a mock function with given fields: ctx, now
+func (_m *MockUsecase) ExpirePendingLoans(ctx context.Context, now time.Time) (int, error) {
+	ret := _m.Called(ctx, now)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int, error)); ok {
+		return rf(ctx, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int); ok {
+		r0 = rf(ctx, now)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ForgotPassword provides a mock function with given fields: ctx, email
+func (_m *MockUsecase) ForgotPassword(ctx context.Context, email string) error {
+	ret := _m.Called(ctx, email)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, email)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // GetLoan provides a mock function with given fields: ctx, userId
 func (_m *MockUsecase) GetLoan(ctx context.Context, userId int64) ([]model.Loan, error) {
 	ret := _m.Called(ctx, userId)
@@ -84,6 +346,232 @@ func (_m *MockUsecase) GetLoan(ctx context.Context, userId int64) ([]model.Loan,
 	return r0, r1
 }
 
+// GetLoanApprovals provides a mock function with given fields: ctx, loanId
+func (_m *MockUsecase) GetLoanApprovals(ctx context.Context, loanId int64) ([]model.LoanApproval, error) {
+	ret := _m.Called(ctx, loanId)
+
+	var r0 []model.LoanApproval
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]model.LoanApproval, error)); ok {
+		return rf(ctx, loanId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []model.LoanApproval); ok {
+		r0 = rf(ctx, loanId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.LoanApproval)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, loanId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetNotificationPreferences provides a mock function with given fields: ctx, userId
+func (_m *MockUsecase) GetNotificationPreferences(ctx context.Context, userId int64) (model.NotificationPreferences, error) {
+	ret := _m.Called(ctx, userId)
+
+	var r0 model.NotificationPreferences
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (model.NotificationPreferences, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) model.NotificationPreferences); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Get(0).(model.NotificationPreferences)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetNotifications provides a mock function with given fields: ctx, userId
+func (_m *MockUsecase) GetNotifications(ctx context.Context, userId int64) ([]model.Notification, error) {
+	ret := _m.Called(ctx, userId)
+
+	var r0 []model.Notification
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]model.Notification, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []model.Notification); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.Notification)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUser provides a mock function with given fields: ctx, userId
+func (_m *MockUsecase) GetUser(ctx context.Context, userId int64) (model.User, error) {
+	ret := _m.Called(ctx, userId)
+
+	var r0 model.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (model.User, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) model.User); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Get(0).(model.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWebhookDeliveries provides a mock function with given fields: ctx, endpointId, status
+func (_m *MockUsecase) GetWebhookDeliveries(ctx context.Context, endpointId int64, status string) ([]model.WebhookDelivery, error) {
+	ret := _m.Called(ctx, endpointId, status)
+
+	var r0 []model.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) ([]model.WebhookDelivery, error)); ok {
+		return rf(ctx, endpointId, status)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) []model.WebhookDelivery); ok {
+		r0 = rf(ctx, endpointId, status)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.WebhookDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string) error); ok {
+		r1 = rf(ctx, endpointId, status)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWebhookDelivery provides a mock function with given fields: ctx, id
+func (_m *MockUsecase) GetWebhookDelivery(ctx context.Context, id int64) (model.WebhookDelivery, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 model.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (model.WebhookDelivery, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) model.WebhookDelivery); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(model.WebhookDelivery)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListRoles provides a mock function with given fields: ctx
+func (_m *MockUsecase) ListRoles(ctx context.Context) ([]model.Role, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []model.Role
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]model.Role, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []model.Role); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.Role)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListWebhookEndpoints provides a mock function with given fields: ctx
+func (_m *MockUsecase) ListWebhookEndpoints(ctx context.Context) ([]model.WebhookEndpoint, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []model.WebhookEndpoint
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]model.WebhookEndpoint, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []model.WebhookEndpoint); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.WebhookEndpoint)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkOverdue provides a mock function with given fields: ctx, now
+func (_m *MockUsecase) MarkOverdue(ctx context.Context, now time.Time) (model.OverdueRun, error) {
+	ret := _m.Called(ctx, now)
+
+	var r0 model.OverdueRun
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (model.OverdueRun, error)); ok {
+		return rf(ctx, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) model.OverdueRun); ok {
+		r0 = rf(ctx, now)
+	} else {
+		r0 = ret.Get(0).(model.OverdueRun)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewLoan provides a mock function with given fields: ctx, amount, terms, userId
 func (_m *MockUsecase) NewLoan(ctx context.Context, amount float64, terms int, userId int64) error {
 	ret := _m.Called(ctx, amount, terms, userId)
@@ -112,23 +600,289 @@ func (_m *MockUsecase) PayLoan(ctx context.Context, amount float64, loanId int64
 	return r0
 }
 
-// UserLogin provides a mock function with given fields: ctx, email, password
-func (_m *MockUsecase) UserLogin(ctx context.Context, email string, password string) (string, error) {
-	ret := _m.Called(ctx, email, password)
+// ReconcileDisbursements provides a mock function with given fields: ctx, now
+func (_m *MockUsecase) ReconcileDisbursements(ctx context.Context, now time.Time) (int, error) {
+	ret := _m.Called(ctx, now)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int, error)); ok {
+		return rf(ctx, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int); ok {
+		r0 = rf(ctx, now)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecordPayment provides a mock function with given fields: ctx, amount, loanId, term
+func (_m *MockUsecase) RecordPayment(ctx context.Context, amount float64, loanId int64, term int64) error {
+	ret := _m.Called(ctx, amount, loanId, term)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, float64, int64, int64) error); ok {
+		r0 = rf(ctx, amount, loanId, term)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RedeliverWebhook provides a mock function with given fields: ctx, id
+func (_m *MockUsecase) RedeliverWebhook(ctx context.Context, id int64) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ResendVerification provides a mock function with given fields: ctx, userId
+func (_m *MockUsecase) ResendVerification(ctx context.Context, userId int64) error {
+	ret := _m.Called(ctx, userId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ResetPassword provides a mock function with given fields: ctx, token, password
+func (_m *MockUsecase) ResetPassword(ctx context.Context, token string, password string) error {
+	ret := _m.Called(ctx, token, password)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, token, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RotateWebhookSecret provides a mock function with given fields: ctx, id
+func (_m *MockUsecase) RotateWebhookSecret(ctx context.Context, id int64) (model.WebhookEndpoint, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 model.WebhookEndpoint
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (model.WebhookEndpoint, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) model.WebhookEndpoint); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(model.WebhookEndpoint)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SeedAdmin provides a mock function with given fields: ctx, password
+func (_m *MockUsecase) SeedAdmin(ctx context.Context, password string) (string, error) {
+	ret := _m.Called(ctx, password)
 
 	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
-		return rf(ctx, email, password)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return rf(ctx, password)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, password)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, password)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SendDueReminders provides a mock function with given fields: ctx, now
+func (_m *MockUsecase) SendDueReminders(ctx context.Context, now time.Time) (int, error) {
+	ret := _m.Called(ctx, now)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int, error)); ok {
+		return rf(ctx, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int); ok {
+		r0 = rf(ctx, now)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SettlePayment provides a mock function with given fields: ctx, nonce, event
+func (_m *MockUsecase) SettlePayment(ctx context.Context, nonce string, event model.PaymentWebhookReq) error {
+	ret := _m.Called(ctx, nonce, event)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, model.PaymentWebhookReq) error); ok {
+		r0 = rf(ctx, nonce, event)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UnlockLogin provides a mock function with given fields: ctx, email, ip
+func (_m *MockUsecase) UnlockLogin(ctx context.Context, email string, ip string) error {
+	ret := _m.Called(ctx, email, ip)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, email, ip)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateNotificationPreferences provides a mock function with given fields: ctx, userId, prefs
+func (_m *MockUsecase) UpdateNotificationPreferences(ctx context.Context, userId int64, prefs model.NotificationPreferences) (model.NotificationPreferences, error) {
+	ret := _m.Called(ctx, userId, prefs)
+
+	var r0 model.NotificationPreferences
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, model.NotificationPreferences) (model.NotificationPreferences, error)); ok {
+		return rf(ctx, userId, prefs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, model.NotificationPreferences) model.NotificationPreferences); ok {
+		r0 = rf(ctx, userId, prefs)
+	} else {
+		r0 = ret.Get(0).(model.NotificationPreferences)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, model.NotificationPreferences) error); ok {
+		r1 = rf(ctx, userId, prefs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateWebhookEndpoint provides a mock function with given fields: ctx, endpoint
+func (_m *MockUsecase) UpdateWebhookEndpoint(ctx context.Context, endpoint model.WebhookEndpoint) (model.WebhookEndpoint, error) {
+	ret := _m.Called(ctx, endpoint)
+
+	var r0 model.WebhookEndpoint
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.WebhookEndpoint) (model.WebhookEndpoint, error)); ok {
+		return rf(ctx, endpoint)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, model.WebhookEndpoint) model.WebhookEndpoint); ok {
+		r0 = rf(ctx, endpoint)
+	} else {
+		r0 = ret.Get(0).(model.WebhookEndpoint)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+
+	if rf, ok := ret.Get(1).(func(context.Context, model.WebhookEndpoint) error); ok {
+		r1 = rf(ctx, endpoint)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserCreateAdmin provides a mock function with given fields: ctx, email, password
+func (_m *MockUsecase) UserCreateAdmin(ctx context.Context, email string, password string) error {
+	ret := _m.Called(ctx, email, password)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
 		r0 = rf(ctx, email, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserLogin provides a mock function with given fields: ctx, email, password, ip
+func (_m *MockUsecase) UserLogin(ctx context.Context, email string, password string, ip string) (model.LoginResult, error) {
+	ret := _m.Called(ctx, email, password, ip)
+
+	var r0 model.LoginResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (model.LoginResult, error)); ok {
+		return rf(ctx, email, password, ip)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) model.LoginResult); ok {
+		r0 = rf(ctx, email, password, ip)
+	} else {
+		r0 = ret.Get(0).(model.LoginResult)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, email, password, ip)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserLoginTotp provides a mock function with given fields: ctx, challengeToken, code, ip
+func (_m *MockUsecase) UserLoginTotp(ctx context.Context, challengeToken string, code string, ip string) (string, error) {
+	ret := _m.Called(ctx, challengeToken, code, ip)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (string, error)); ok {
+		return rf(ctx, challengeToken, code, ip)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) string); ok {
+		r0 = rf(ctx, challengeToken, code, ip)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
-		r1 = rf(ctx, email, password)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, challengeToken, code, ip)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -150,6 +904,34 @@ func (_m *MockUsecase) UserRegister(ctx context.Context, user model.User) error
 	return r0
 }
 
+// VerifyEmail provides a mock function with given fields: ctx, token
+func (_m *MockUsecase) VerifyEmail(ctx context.Context, token string) error {
+	ret := _m.Called(ctx, token)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, token)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// VerifyPaymentWebhook provides a mock function with given fields: ctx, sig, body
+func (_m *MockUsecase) VerifyPaymentWebhook(ctx context.Context, sig model.WebhookSignature, body []byte) error {
+	ret := _m.Called(ctx, sig, body)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.WebhookSignature, []byte) error); ok {
+		r0 = rf(ctx, sig, body)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewMockUsecase interface {
 	mock.TestingT
 	Cleanup(func())
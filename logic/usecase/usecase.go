@@ -3,17 +3,55 @@ package ohlc
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"example.com/m/v2/model"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type Usecase interface {
-	UserLogin(ctx context.Context, email, password string) (token string, err error)
+	UserLogin(ctx context.Context, email, password, ip string) (res model.LoginResult, err error)
+	UserLoginTotp(ctx context.Context, challengeToken, code, ip string) (token string, err error)
+	DecodeTotpEnrollChallenge(ctx context.Context, challengeToken string) (userId int64, err error)
+	EnrollTotp(ctx context.Context, userId int64) (enrollment model.TotpEnrollment, err error)
+	EnableTotp(ctx context.Context, userId int64, code string) (recoveryCodes []string, err error)
+	UnlockLogin(ctx context.Context, email, ip string) (err error)
 	UserRegister(ctx context.Context, user model.User) (err error)
+	SeedAdmin(ctx context.Context, password string) (generated string, err error)
+	UserCreateAdmin(ctx context.Context, email, password string) (err error)
+	GetUser(ctx context.Context, userId int64) (user model.User, err error)
+	VerifyEmail(ctx context.Context, token string) (err error)
+	ResendVerification(ctx context.Context, userId int64) (err error)
+	ForgotPassword(ctx context.Context, email string) (err error)
+	ResetPassword(ctx context.Context, token, password string) (err error)
+	ChangePassword(ctx context.Context, userId int64, oldPassword, newPassword, ip string) (err error)
 	NewLoan(ctx context.Context, amount float64, terms int, userId int64) (err error)
-	DecodeJwt(cookies []*http.Cookie) (claims jwt.MapClaims, err error)
-	ApproveLoan(ctx context.Context, loanId int64) (err error)
+	DecodeJwt(ctx context.Context, cookies []*http.Cookie) (claims jwt.MapClaims, err error)
+	Authorize(ctx context.Context, userId int64, permission string) (principal model.Principal, err error)
+	ListRoles(ctx context.Context) (roles []model.Role, err error)
+	AssignRoles(ctx context.Context, actor model.Principal, userId int64, roles []string) (err error)
+	ApproveLoan(ctx context.Context, loanId int64, approver model.Principal) (res model.ApprovalResult, err error)
+	GetLoanApprovals(ctx context.Context, loanId int64) (approvals []model.LoanApproval, err error)
+	DisburseLoan(ctx context.Context, loanId int64) (disbursement model.Disbursement, err error)
+	ReconcileDisbursements(ctx context.Context, now time.Time) (settled int, err error)
+	RecordPayment(ctx context.Context, amount float64, loanId, term int64) (err error)
 	PayLoan(ctx context.Context, amount float64, loanId, term, userId int64) (err error)
+	CreatePaymentIntent(ctx context.Context, amount float64, loanId, term, userId int64) (intent model.PaymentIntent, err error)
+	VerifyPaymentWebhook(ctx context.Context, sig model.WebhookSignature, body []byte) (err error)
+	SettlePayment(ctx context.Context, nonce string, event model.PaymentWebhookReq) (err error)
+	MarkOverdue(ctx context.Context, now time.Time) (run model.OverdueRun, err error)
+	ExpirePendingLoans(ctx context.Context, now time.Time) (expired int, err error)
+	SendDueReminders(ctx context.Context, now time.Time) (sent int, err error)
 	GetLoan(ctx context.Context, userId int64) (loans []model.Loan, err error)
+	CreateWebhookEndpoint(ctx context.Context, actor model.Principal, endpoint model.WebhookEndpoint) (res model.WebhookEndpoint, err error)
+	ListWebhookEndpoints(ctx context.Context) (endpoints []model.WebhookEndpoint, err error)
+	UpdateWebhookEndpoint(ctx context.Context, endpoint model.WebhookEndpoint) (res model.WebhookEndpoint, err error)
+	DeleteWebhookEndpoint(ctx context.Context, id int64) (err error)
+	RotateWebhookSecret(ctx context.Context, id int64) (res model.WebhookEndpoint, err error)
+	GetWebhookDeliveries(ctx context.Context, endpointId int64, status string) (deliveries []model.WebhookDelivery, err error)
+	GetWebhookDelivery(ctx context.Context, id int64) (delivery model.WebhookDelivery, err error)
+	RedeliverWebhook(ctx context.Context, id int64) (err error)
+	GetNotifications(ctx context.Context, userId int64) (notifications []model.Notification, err error)
+	GetNotificationPreferences(ctx context.Context, userId int64) (prefs model.NotificationPreferences, err error)
+	UpdateNotificationPreferences(ctx context.Context, userId int64, prefs model.NotificationPreferences) (res model.NotificationPreferences, err error)
 }
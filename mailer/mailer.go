@@ -0,0 +1,84 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"example.com/m/v2/config"
+	"example.com/m/v2/logger"
+	"example.com/m/v2/model"
+)
+
+// Mailer delivers transactional mails, implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, mail model.Mail) error
+	Close() error
+}
+
+// New builds the Mailer selected by cfg.Driver.
+func New(cfg config.Mailer) (Mailer, error) {
+	switch cfg.Driver {
+	case "log":
+		return &logMailer{from: cfg.From}, nil
+	case "file":
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("open mailer file: %w", err)
+		}
+		return NewWriter(file, cfg.From), nil
+	}
+	return nil, fmt.Errorf("unknown mailer driver %q", cfg.Driver)
+}
+
+// logMailer writes mails to the request scoped logger, for local development only as links end up in logs.
+type logMailer struct {
+	from string
+}
+
+func (m *logMailer) Send(ctx context.Context, mail model.Mail) error {
+	logger.FromContext(ctx).InfoContext(ctx, "mail sent",
+		"from", m.from,
+		"to", mail.To,
+		"subject", mail.Subject,
+		"text", mail.Text,
+	)
+	return nil
+}
+
+func (m *logMailer) Close() error {
+	return nil
+}
+
+type writerMailer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from string
+}
+
+// NewWriter appends every mail to w in a plain text, mbox like format, closing w on Close when it is an io.Closer.
+func NewWriter(w io.Writer, from string) Mailer {
+	return &writerMailer{
+		w:    w,
+		from: from,
+	}
+}
+
+func (m *writerMailer) Send(ctx context.Context, mail model.Mail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(m.w, "From: %s\nTo: %s\nDate: %s\nSubject: %s\n\n%s\n\n",
+		m.from, mail.To, time.Now().Format(time.RFC1123Z), mail.Subject, mail.Text)
+	return err
+}
+
+func (m *writerMailer) Close() error {
+	if c, ok := m.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"example.com/m/v2/config"
+	"example.com/m/v2/model"
+)
+
+func Test_New(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		cfg     config.Mailer
+		wantErr bool
+	}{
+		{
+			name: "log",
+			cfg:  config.Mailer{Driver: "log", From: "no-reply@tes.com"},
+		},
+		{
+			name: "file",
+			cfg:  config.Mailer{Driver: "file", File: filepath.Join(dir, "mail.log"), From: "no-reply@tes.com"},
+		},
+		{
+			name:    "file in a missing directory",
+			cfg:     config.Mailer{Driver: "file", File: filepath.Join(dir, "missing", "mail.log")},
+			wantErr: true,
+		},
+		{
+			name:    "unknown driver",
+			cfg:     config.Mailer{Driver: "smtp"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New test failed. wantErr: %v, gotErr: %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			defer m.Close()
+
+			err = m.Send(context.Background(), model.Mail{To: "tes@tes.com", Subject: "tes", Text: "hello"})
+			if err != nil {
+				t.Errorf("Send failed: %v", err)
+			}
+		})
+	}
+
+	b, _ := os.ReadFile(filepath.Join(dir, "mail.log"))
+	if !strings.Contains(string(b), "To: tes@tes.com") || !strings.Contains(string(b), "hello") {
+		t.Errorf("file mailer did not write the mail: %s", b)
+	}
+}
+
+func Test_writerMailer(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewWriter(&buf, "no-reply@tes.com")
+
+	m.Send(context.Background(), model.Mail{To: "a@tes.com", Subject: "first", Text: "one"})
+	m.Send(context.Background(), model.Mail{To: "b@tes.com", Subject: "second", Text: "two"})
+
+	got := buf.String()
+	for _, want := range []string{"From: no-reply@tes.com", "To: a@tes.com", "Subject: first", "one", "To: b@tes.com", "Subject: second", "two"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("mail output missing %q: %s", want, got)
+		}
+	}
+	if err := m.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "mini_aspire"
+
+// Registry holds every application metric, it is served by Handler.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of http requests by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Http request latency by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	loansCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "loans_created_total",
+		Help:      "Number of loans created.",
+	})
+
+	loansCreatedAmount = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "loans_created_amount_total",
+		Help:      "Sum of the amount of created loans.",
+	})
+
+	loansApproved = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "loans_approved_total",
+		Help:      "Number of loans approved.",
+	})
+
+	loansPaid = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "loans_paid_total",
+		Help:      "Number of loans fully paid.",
+	})
+
+	paymentsReceived = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payments_received_total",
+		Help:      "Number of repayments received.",
+	})
+
+	paymentsReceivedAmount = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payments_received_amount_total",
+		Help:      "Sum of the amount of repayments received.",
+	})
+
+	paymentsUnapplied = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payments_unapplied_total",
+		Help:      "Number of payments received by the gateway that could not be applied to their term.",
+	})
+
+	paymentsUnappliedAmount = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payments_unapplied_amount_total",
+		Help:      "Sum of the amount of payments received by the gateway that could not be applied to their term.",
+	})
+
+	jobRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_runs_total",
+		Help:      "Number of background job runs by job and outcome.",
+	}, []string{"job", "outcome"})
+	eventsPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_events_total",
+		Help:      "Number of outbox events relayed by type and outcome.",
+	}, []string{"type", "outcome"})
+	webhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Number of webhook delivery attempts by outcome.",
+	}, []string{"outcome"})
+	notificationsSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_total",
+		Help:      "Number of notification attempts by channel and outcome.",
+	}, []string{"channel", "outcome"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		loansCreated,
+		loansCreatedAmount,
+		loansApproved,
+		loansPaid,
+		paymentsReceived,
+		paymentsReceivedAmount,
+		paymentsUnapplied,
+		paymentsUnappliedAmount,
+		jobRuns,
+		eventsPublished,
+		webhookDeliveries,
+		notificationsSent,
+	)
+}
+
+// LoanCreated records a new loan of amount.
+func LoanCreated(amount float64) {
+	loansCreated.Inc()
+	addPositive(loansCreatedAmount, amount)
+}
+
+func LoanApproved() {
+	loansApproved.Inc()
+}
+
+// PaymentReceived records a repayment of amount, loanPaid is set when it settles the whole loan.
+func PaymentReceived(amount float64, loanPaid bool) {
+	paymentsReceived.Inc()
+	addPositive(paymentsReceivedAmount, amount)
+	if loanPaid {
+		loansPaid.Inc()
+	}
+}
+
+// PaymentUnapplied records a payment of amount received by the gateway that could not be applied.
+func PaymentUnapplied(amount float64) {
+	paymentsUnapplied.Inc()
+	addPositive(paymentsUnappliedAmount, amount)
+}
+
+// JobRun records a run of a background job, ok is false when it failed.
+func JobRun(job string, ok bool) {
+	outcome := "success"
+	if !ok {
+		outcome = "failure"
+	}
+	jobRuns.WithLabelValues(job, outcome).Inc()
+}
+
+// EventPublished records an attempt to relay an outbox event, outcome is published, retry or dead.
+func EventPublished(eventType, outcome string) {
+	eventsPublished.WithLabelValues(eventType, outcome).Inc()
+}
+
+// WebhookDelivered records a webhook delivery attempt, outcome is succeeded, retry or failed.
+func WebhookDelivered(outcome string) {
+	webhookDeliveries.WithLabelValues(outcome).Inc()
+}
+
+// NotificationSent records an attempt to send a notification, outcome is sent, retry or failed.
+func NotificationSent(channel, outcome string) {
+	notificationsSent.WithLabelValues(channel, outcome).Inc()
+}
+
+// addPositive guards counters against negative values, prometheus counters panic when decreased.
+func addPositive(c prometheus.Counter, v float64) {
+	if v > 0 {
+		c.Add(v)
+	}
+}
+
+// Handler serves Registry in the prometheus text exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// RegisterDbStats exposes the sql.DBStats of db as go_sql_* gauges labelled with dbName.
+func RegisterDbStats(db *sql.DB, dbName string) error {
+	err := Registry.Register(collectors.NewDBStatsCollector(db, dbName))
+
+	var alreadyRegistered prometheus.AlreadyRegisteredError
+	if errors.As(err, &alreadyRegistered) {
+		return nil
+	}
+	return err
+}
+
+// HttpRequest records a request served under route and method, middleware.Instrument calls it for every route.
+func HttpRequest(route, method string, status int, duration time.Duration) {
+	httpDuration.WithLabelValues(route, method).Observe(duration.Seconds())
+	httpRequests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+}
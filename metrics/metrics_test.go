@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+func scrape(t *testing.T) string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status code returned, %d, did not match expected code %d", w.Code, http.StatusOK)
+	}
+
+	body, _ := io.ReadAll(w.Body)
+	return string(body)
+}
+
+func Test_HttpRequest(t *testing.T) {
+	HttpRequest("/tes", "POST", http.StatusBadRequest, time.Millisecond)
+	HttpRequest("/tes", "POST", http.StatusBadRequest, time.Millisecond)
+	HttpRequest("/tes", "GET", http.StatusOK, time.Millisecond)
+
+	got := scrape(t)
+	for _, want := range []string{
+		`mini_aspire_http_requests_total{method="POST",route="/tes",status="400"} 2`,
+		`mini_aspire_http_requests_total{method="GET",route="/tes",status="200"} 1`,
+		`mini_aspire_http_request_duration_seconds_count{method="POST",route="/tes"} 2`,
+		`mini_aspire_http_request_duration_seconds_bucket{method="GET",route="/tes",le="+Inf"} 1`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("scrape missing %q", want)
+		}
+	}
+}
+
+func Test_BusinessCounters(t *testing.T) {
+	LoanCreated(10000)
+	LoanApproved()
+	PaymentReceived(3333.33, false)
+	PaymentReceived(6666.67, true)
+	PaymentReceived(-1, false)
+	PaymentUnapplied(500)
+	JobRun("mark-overdue", true)
+	JobRun("mark-overdue", false)
+	EventPublished("loan.created", "published")
+	WebhookDelivered("retry")
+	NotificationSent("email", "sent")
+
+	got := scrape(t)
+	for _, want := range []string{
+		"mini_aspire_loans_created_total 1",
+		"mini_aspire_loans_created_amount_total 10000",
+		"mini_aspire_loans_approved_total 1",
+		"mini_aspire_loans_paid_total 1",
+		"mini_aspire_payments_received_total 3",
+		"mini_aspire_payments_received_amount_total 10000",
+		"mini_aspire_payments_unapplied_total 1",
+		"mini_aspire_payments_unapplied_amount_total 500",
+		`mini_aspire_job_runs_total{job="mark-overdue",outcome="success"} 1`,
+		`mini_aspire_outbox_events_total{outcome="published",type="loan.created"} 1`,
+		`mini_aspire_webhook_deliveries_total{outcome="retry"} 1`,
+		`mini_aspire_notifications_total{channel="email",outcome="sent"} 1`,
+		`mini_aspire_job_runs_total{job="mark-overdue",outcome="failure"} 1`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("scrape missing %q", want)
+		}
+	}
+}
+
+func Test_RegisterDbStats(t *testing.T) {
+	db, err := sql.Open("postgres", "")
+	if err != nil {
+		t.Fatalf("sql.Open failed: %v", err)
+	}
+	defer db.Close()
+
+	if err := RegisterDbStats(db, "tes"); err != nil {
+		t.Fatalf("RegisterDbStats failed: %v", err)
+	}
+
+	got := scrape(t)
+	for _, want := range []string{
+		`go_sql_open_connections{db_name="tes"} 0`,
+		`go_sql_max_open_connections{db_name="tes"} 0`,
+		`go_sql_wait_count_total{db_name="tes"} 0`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("scrape missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"example.com/m/v2/constant"
+)
+
+type clientIpKey struct{}
+
+// ClientIp resolves the ip of the caller and stores it in the request context. With trustProxy the last
+// X-Forwarded-For entry is used, it is the one appended by the reverse proxy in front of the server, earlier
+// entries are client controlled. Without a proxy the header is ignored so clients can't spoof it.
+func ClientIp(trustProxy bool) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := remoteIp(r.RemoteAddr)
+			if trustProxy {
+				if forwarded := lastForwardedFor(r.Header.Values(constant.HttpHeaderForwardedFor)); forwarded != "" {
+					ip = forwarded
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIpKey{}, ip)))
+		})
+	}
+}
+
+// ClientIpFromRequest returns the ip resolved by ClientIp, falling back to the RemoteAddr host.
+func ClientIpFromRequest(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIpKey{}).(string); ok {
+		return ip
+	}
+	return remoteIp(r.RemoteAddr)
+}
+
+func remoteIp(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+func lastForwardedFor(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	entries := strings.Split(values[len(values)-1], ",")
+	ip := net.ParseIP(strings.TrimSpace(entries[len(entries)-1]))
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"example.com/m/v2/constant"
+)
+
+func Test_ClientIp(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy bool
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{
+			name:       "remote addr",
+			remoteAddr: "192.0.2.1:1234",
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "forwarded for ignored without proxy",
+			remoteAddr: "192.0.2.1:1234",
+			forwarded:  []string{"198.51.100.7"},
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "last forwarded for entry behind proxy",
+			trustProxy: true,
+			remoteAddr: "10.0.0.2:1234",
+			forwarded:  []string{"203.0.113.9, 198.51.100.7", "198.51.100.8"},
+			want:       "198.51.100.8",
+		},
+		{
+			name:       "invalid forwarded for falls back to remote addr",
+			trustProxy: true,
+			remoteAddr: "10.0.0.2:1234",
+			forwarded:  []string{"tes"},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "ipv6 remote addr",
+			remoteAddr: "[2001:db8::1]:1234",
+			want:       "2001:db8::1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := ClientIp(tt.trustProxy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ClientIpFromRequest(r)
+			}))
+
+			r := httptest.NewRequest("POST", "/user/login", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.forwarded {
+				r.Header.Add(constant.HttpHeaderForwardedFor, v)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if got != tt.want {
+				t.Errorf("unexpected client ip: got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"example.com/m/v2/constant"
+	"example.com/m/v2/logger"
+)
+
+type requestIdKey struct{}
+
+// RequestId propagates a valid incoming X-Request-ID or assigns a new one, echoes it on the response and
+// attaches a logger annotated with it to the request context.
+func RequestId(base *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(constant.HttpHeaderRequestId)
+			if !validRequestId(id) {
+				id = newRequestId()
+			}
+			w.Header().Set(constant.HttpHeaderRequestId, id)
+
+			ctx := context.WithValue(r.Context(), requestIdKey{}, id)
+			ctx = logger.WithContext(ctx, base.With("request_id", id))
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequestIdFromContext returns the id assigned by RequestId, empty outside of a request.
+func RequestIdFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey{}).(string)
+	return id
+}
+
+// AccessLog writes one line per request with status and latency using the request scoped logger.
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		status := rec.Status()
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		} else if status >= http.StatusBadRequest {
+			level = slog.LevelWarn
+		}
+
+		logger.FromContext(r.Context()).LogAttrs(r.Context(), level, "http request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int("bytes", rec.bytes),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("remote_addr", r.RemoteAddr),
+		)
+	})
+}
+
+func validRequestId(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestId() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
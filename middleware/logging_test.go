@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"example.com/m/v2/constant"
+	"example.com/m/v2/logger"
+)
+
+func Test_RequestIdAccessLog(t *testing.T) {
+	tests := []struct {
+		name          string
+		requestId     string
+		wantRequestId string
+	}{
+		{
+			name:          "propagate incoming id",
+			requestId:     "tes-123",
+			wantRequestId: "tes-123",
+		},
+		{
+			name:      "generate id when missing",
+			requestId: "",
+		},
+		{
+			name:      "replace invalid id",
+			requestId: "tes\n123",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			base := slog.New(slog.NewJSONHandler(&buf, nil))
+
+			var ctxRequestId string
+			h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctxRequestId = RequestIdFromContext(r.Context())
+				logger.FromContext(r.Context()).Info("inside handler")
+				w.WriteHeader(http.StatusTeapot)
+			}), RequestId(base), AccessLog)
+
+			r := httptest.NewRequest("GET", "/loan", nil)
+			if tt.requestId != "" {
+				r.Header.Set(constant.HttpHeaderRequestId, tt.requestId)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			got := w.Header().Get(constant.HttpHeaderRequestId)
+			if got == "" || got != ctxRequestId {
+				t.Errorf("unexpected request id: header %q, context %q", got, ctxRequestId)
+			}
+			if tt.wantRequestId != "" && got != tt.wantRequestId {
+				t.Errorf("unexpected request id: got %q want %q", got, tt.wantRequestId)
+			}
+
+			dec := json.NewDecoder(&buf)
+			var lines []map[string]interface{}
+			for dec.More() {
+				line := map[string]interface{}{}
+				dec.Decode(&line)
+				lines = append(lines, line)
+			}
+			if len(lines) != 2 {
+				t.Fatalf("want 2 log lines, got %d", len(lines))
+			}
+			for _, line := range lines {
+				if line["request_id"] != got {
+					t.Errorf("log line missing request id: %+v", line)
+				}
+			}
+			access := lines[1]
+			if access["msg"] != "http request" || access["status"] != float64(http.StatusTeapot) || access["path"] != "/loan" {
+				t.Errorf("unexpected access log: %+v", access)
+			}
+			if _, ok := access["latency"]; !ok {
+				t.Errorf("access log missing latency: %+v", access)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"example.com/m/v2/metrics"
+)
+
+// Instrument counts and times every call of fn under route and method.
+func Instrument(route, method string, fn func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+
+		fn(rec, r)
+
+		metrics.HttpRequest(route, method, rec.Status(), time.Since(start))
+	}
+}
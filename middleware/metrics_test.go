@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/m/v2/metrics"
+)
+
+func Test_Instrument(t *testing.T) {
+	h := Instrument("/tes", "POST", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	h(httptest.NewRecorder(), httptest.NewRequest("POST", "/tes", nil))
+	h(httptest.NewRecorder(), httptest.NewRequest("POST", "/tes", nil))
+
+	ok := Instrument("/tes", "GET", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	ok(httptest.NewRecorder(), httptest.NewRequest("GET", "/tes", nil))
+
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+
+	got := string(body)
+	for _, want := range []string{
+		`mini_aspire_http_requests_total{method="POST",route="/tes",status="400"} 2`,
+		`mini_aspire_http_requests_total{method="GET",route="/tes",status="200"} 1`,
+		`mini_aspire_http_request_duration_seconds_count{method="POST",route="/tes"} 2`,
+		`mini_aspire_http_request_duration_seconds_bucket{method="GET",route="/tes",le="+Inf"} 1`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("scrape missing %q", want)
+		}
+	}
+}
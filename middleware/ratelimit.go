@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/logger"
+	"example.com/m/v2/ratelimit"
+)
+
+// RateKey identifies the client a request is counted against.
+type RateKey func(r *http.Request) string
+
+// ByIp counts requests per client ip, see ClientIp.
+func ByIp(r *http.Request) string {
+	return "ip:" + ClientIpFromRequest(r)
+}
+
+// RateLimitPolicy limits one route, Name keeps the buckets of routes sharing a key apart.
+type RateLimitPolicy struct {
+	Name  string
+	Limit ratelimit.Limit
+	Key   RateKey
+}
+
+// RateLimit answers apperror.ErrRateLimited through reject once the bucket of the caller is empty and sets the
+// RateLimit-Limit / -Remaining / -Reset headers on every response. A failing store lets requests through, the
+// limiter protects capacity and must not take the api down with it.
+func RateLimit(store ratelimit.Store, policy RateLimitPolicy, reject func(http.ResponseWriter, *http.Request, error)) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			res, err := store.Take(ctx, policy.Name+":"+policy.Key(r), policy.Limit, time.Now())
+			if err != nil {
+				logger.FromContext(ctx).ErrorContext(ctx, "rate limit store failed", "policy", policy.Name, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set(constant.HttpHeaderRateLimitLimit, strconv.Itoa(res.Limit))
+			w.Header().Set(constant.HttpHeaderRateLimitRemaining, strconv.Itoa(res.Remaining))
+			w.Header().Set(constant.HttpHeaderRateLimitReset, strconv.FormatInt(int64(math.Ceil(res.Reset.Seconds())), 10))
+			if !res.Allowed {
+				reject(w, r, apperror.ErrRateLimited.WithRetryAfter(res.RetryAfter))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
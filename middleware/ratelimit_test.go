@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/ratelimit"
+)
+
+type errRateLimitStore struct{}
+
+func (errRateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit, now time.Time) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("err store")
+}
+
+func (errRateLimitStore) Prune(ctx context.Context, now time.Time) (int64, error) {
+	return 0, errors.New("err store")
+}
+
+func Test_RateLimit(t *testing.T) {
+	policy := RateLimitPolicy{
+		Name:  "login",
+		Limit: ratelimit.Limit{Requests: 2, Per: time.Minute},
+		Key:   ByIp,
+	}
+
+	tests := []struct {
+		name       string
+		store      ratelimit.Store
+		requests   int
+		wantStatus int
+		wantHeader map[string]string
+	}{
+		{
+			name:       "allowed",
+			store:      ratelimit.NewMemoryStore(),
+			requests:   1,
+			wantStatus: http.StatusOK,
+			wantHeader: map[string]string{
+				constant.HttpHeaderRateLimitLimit:     "2",
+				constant.HttpHeaderRateLimitRemaining: "1",
+				constant.HttpHeaderRateLimitReset:     "30",
+			},
+		},
+		{
+			name:       "limited",
+			store:      ratelimit.NewMemoryStore(),
+			requests:   3,
+			wantStatus: http.StatusTooManyRequests,
+			wantHeader: map[string]string{
+				constant.HttpHeaderRateLimitLimit:     "2",
+				constant.HttpHeaderRateLimitRemaining: "0",
+			},
+		},
+		{
+			name:       "failing store lets requests through",
+			store:      errRateLimitStore{},
+			requests:   3,
+			wantStatus: http.StatusOK,
+			wantHeader: map[string]string{
+				constant.HttpHeaderRateLimitLimit: "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rejected error
+			reject := func(w http.ResponseWriter, r *http.Request, err error) {
+				rejected = err
+				w.WriteHeader(apperror.HttpStatus(err))
+			}
+			h := RateLimit(tt.store, policy, reject)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			var w *httptest.ResponseRecorder
+			for i := 0; i < tt.requests; i++ {
+				w = httptest.NewRecorder()
+				h.ServeHTTP(w, httptest.NewRequest("POST", "/user/login", nil))
+			}
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("unexpected status: got %d want %d", w.Code, tt.wantStatus)
+			}
+			var appErr *apperror.Error
+			if tt.wantStatus == http.StatusTooManyRequests &&
+				!(errors.As(rejected, &appErr) && appErr.Message == apperror.ErrRateLimited.Message && appErr.RetryAfter > 29*time.Second && appErr.RetryAfter <= 30*time.Second) {
+				t.Errorf("unexpected rejection: got %#v want %v retrying after 30s", rejected, apperror.ErrRateLimited)
+			}
+			for key, val := range tt.wantHeader {
+				if got := w.Header().Get(key); got != val {
+					t.Errorf("unexpected header %s: got %q want %q", key, got, val)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/m/v2/tracing"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func Test_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracing.Install(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer tracing.Install(trace.NewNoopTracerProvider())
+
+	h := Tracing(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.Start(r.Context(), "usecase.tes")
+		span.End()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	r := httptest.NewRequest("POST", "/loan/pay", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("want 2 spans, got %d", len(spans))
+	}
+	child, server := spans[0], spans[1]
+
+	if server.Name() != "POST /loan/pay" || server.SpanKind() != trace.SpanKindServer {
+		t.Errorf("unexpected server span: %s %s", server.Name(), server.SpanKind())
+	}
+	if got := server.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("server span did not continue incoming trace, got trace id %s", got)
+	}
+	if got := server.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("server span has unexpected parent %s", got)
+	}
+	if child.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Errorf("usecase span is not a child of the server span")
+	}
+	if server.Status().Code.String() != "Error" {
+		t.Errorf("want error status for 500 response, got %s", server.Status().Code)
+	}
+
+	traceparent := w.Header().Get("traceparent")
+	if !strings.HasPrefix(traceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+server.SpanContext().SpanID().String()) {
+		t.Errorf("unexpected response traceparent %q", traceparent)
+	}
+}
@@ -0,0 +1,70 @@
+package model
+
+import "time"
+
+// LoanApproval is one approver's sign-off on a pending loan, it stops counting once ExpiresAt has passed.
+type LoanApproval struct {
+	Id         int64     `db:"id"`
+	LoanId     int64     `db:"loan_id"`
+	ApproverId int64     `db:"approver_id"`
+	ApprovedAt time.Time `db:"approved_at"`
+	ExpiresAt  time.Time `db:"expires_at"`
+}
+
+func (a LoanApproval) Expired(now time.Time) bool {
+	return !now.Before(a.ExpiresAt)
+}
+
+// ApprovalResult is the state of a loan after an approval was recorded.
+type ApprovalResult struct {
+	LoanId    int64
+	Status    string
+	Approvals int
+	Required  int
+}
+
+type ApprovalRes struct {
+	LoanId            int64  `json:"loan_id"`
+	Status            string `json:"status"`
+	Approvals         int    `json:"approvals"`
+	ApprovalsRequired int    `json:"approvals_required"`
+}
+
+func NewApprovalRes(result ApprovalResult) ApprovalRes {
+	return ApprovalRes{
+		LoanId:            result.LoanId,
+		Status:            result.Status,
+		Approvals:         result.Approvals,
+		ApprovalsRequired: result.Required,
+	}
+}
+
+type LoanApprovalRes struct {
+	ApproverId int64     `json:"approver_id"`
+	ApprovedAt time.Time `json:"approved_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Expired    bool      `json:"expired"`
+}
+
+func NewLoanApprovalResList(approvals []LoanApproval, now time.Time) []LoanApprovalRes {
+	res := make([]LoanApprovalRes, 0, len(approvals))
+	for _, approval := range approvals {
+		res = append(res, LoanApprovalRes{
+			ApproverId: approval.ApproverId,
+			ApprovedAt: approval.ApprovedAt,
+			ExpiresAt:  approval.ExpiresAt,
+			Expired:    approval.Expired(now),
+		})
+	}
+	return res
+}
+
+type HttpResApproval struct {
+	Message string      `json:"message,omitempty"`
+	Data    ApprovalRes `json:"data"`
+}
+
+type HttpResLoanApprovals struct {
+	Message string            `json:"message,omitempty"`
+	Data    []LoanApprovalRes `json:"data"`
+}
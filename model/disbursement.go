@@ -0,0 +1,80 @@
+package model
+
+import "time"
+
+// Disbursement is one attempt to pay an approved loan out to the borrower. IdempotencyKey is sent with every
+// submission of the attempt, so retrying it never pays twice.
+type Disbursement struct {
+	Id                int64      `db:"id"`
+	LoanId            int64      `db:"loan_id"`
+	Amount            float64    `db:"amount"`
+	IdempotencyKey    string     `db:"idempotency_key"`
+	Provider          string     `db:"provider"`
+	ProviderReference string     `db:"provider_reference"`
+	Status            string     `db:"status"`
+	FailureReason     string     `db:"failure_reason"`
+	Attempts          int        `db:"attempts"`
+	LastError         string     `db:"last_error"`
+	CreatedAt         time.Time  `db:"created_at"`
+	CompletedAt       *time.Time `db:"completed_at"`
+}
+
+// PayoutRequest asks the payout provider to send Amount to the borrower of a loan.
+type PayoutRequest struct {
+	IdempotencyKey string
+	LoanId         int64
+	UserId         int64
+	Amount         float64
+}
+
+// PayoutResult is the state of a payout at the provider, Status is one of the disbursement statuses.
+type PayoutResult struct {
+	Reference     string
+	Status        string
+	FailureReason string
+}
+
+type DisbursementRes struct {
+	Id                int64      `json:"id"`
+	LoanId            int64      `json:"loan_id"`
+	Amount            float64    `json:"amount"`
+	Provider          string     `json:"provider"`
+	ProviderReference string     `json:"provider_reference,omitempty"`
+	Status            string     `json:"status"`
+	FailureReason     string     `json:"failure_reason,omitempty"`
+	Attempts          int        `json:"attempts"`
+	CreatedAt         time.Time  `json:"created_at"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+}
+
+func NewDisbursementRes(disbursement Disbursement) DisbursementRes {
+	return DisbursementRes{
+		Id:                disbursement.Id,
+		LoanId:            disbursement.LoanId,
+		Amount:            disbursement.Amount,
+		Provider:          disbursement.Provider,
+		ProviderReference: disbursement.ProviderReference,
+		Status:            disbursement.Status,
+		FailureReason:     disbursement.FailureReason,
+		Attempts:          disbursement.Attempts,
+		CreatedAt:         disbursement.CreatedAt,
+		CompletedAt:       disbursement.CompletedAt,
+	}
+}
+
+type HttpResDisbursement struct {
+	Message string          `json:"message,omitempty"`
+	Data    DisbursementRes `json:"data"`
+}
+
+type DisburseLoanReq struct {
+	LoanId int64 `json:"loan_id"`
+}
+
+func (r DisburseLoanReq) Validate() error {
+	var fields fieldErrors
+	if r.LoanId < 1 {
+		fields.add("loan_id", "is required")
+	}
+	return fields.err()
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is a domain event waiting in the outbox, Payload is the JSON encoded event data.
+type OutboxEvent struct {
+	Id            int64           `db:"id"`
+	AggregateType string          `db:"aggregate_type"`
+	AggregateId   int64           `db:"aggregate_id"`
+	Type          string          `db:"event_type"`
+	Payload       json.RawMessage `db:"payload"`
+	Attempts      int             `db:"attempts"`
+	CreatedAt     time.Time       `db:"created_at"`
+}
+
+// EventMessage is an event as sinks publish it. Delivery is at least once, consumers deduplicate on Id.
+type EventMessage struct {
+	Id            int64           `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateId   int64           `json:"aggregate_id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+func NewEventMessage(event OutboxEvent) EventMessage {
+	return EventMessage{
+		Id:            event.Id,
+		Type:          event.Type,
+		AggregateType: event.AggregateType,
+		AggregateId:   event.AggregateId,
+		OccurredAt:    event.CreatedAt,
+		Data:          event.Payload,
+	}
+}
+
+type LoanCreatedEvent struct {
+	LoanId int64   `json:"loan_id"`
+	UserId int64   `json:"user_id"`
+	Amount float64 `json:"amount"`
+	Terms  int     `json:"terms"`
+}
+
+type LoanApprovedEvent struct {
+	LoanId    int64   `json:"loan_id"`
+	Amount    float64 `json:"amount"`
+	Approvals int     `json:"approvals"`
+}
+
+type LoanPaymentReceivedEvent struct {
+	LoanId int64   `json:"loan_id"`
+	Term   int64   `json:"term"`
+	Amount float64 `json:"amount"`
+}
+
+// LoanPaymentUnappliedEvent is a payment received by the gateway that could not be applied, Amount is what was
+// received.
+type LoanPaymentUnappliedEvent struct {
+	LoanId    int64   `json:"loan_id"`
+	Term      int64   `json:"term"`
+	Reference string  `json:"reference"`
+	Amount    float64 `json:"amount"`
+	Reason    string  `json:"reason"`
+}
+
+// LoanStatusEvent is the data of events that only move a loan to another status, like loan.paid.
+type LoanStatusEvent struct {
+	LoanId int64 `json:"loan_id"`
+}
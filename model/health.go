@@ -0,0 +1,12 @@
+package model
+
+type HealthRes struct {
+	Status string                    `json:"status"`
+	Checks map[string]HealthCheckRes `json:"checks,omitempty"`
+}
+
+type HealthCheckRes struct {
+	Status   string `json:"status"`
+	Duration string `json:"duration,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
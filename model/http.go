@@ -6,6 +6,28 @@ type HttpRes struct {
 }
 
 type HttpResLoan struct {
-	Message string `json:"message,omitempty"`
-	Data    []Loan `json:"data,omitempty"`
+	Message string    `json:"message,omitempty"`
+	Data    []LoanRes `json:"data,omitempty"`
+}
+
+type HttpResUser struct {
+	Message string  `json:"message,omitempty"`
+	Data    UserRes `json:"data"`
+}
+
+// Problem is an RFC 7807 application/problem+json error body.
+type Problem struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail,omitempty"`
+	Instance  string              `json:"instance,omitempty"`
+	Code      string              `json:"code"`
+	RequestId string              `json:"request_id,omitempty"`
+	Errors    []ProblemFieldError `json:"errors,omitempty"`
+}
+
+type ProblemFieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
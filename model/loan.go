@@ -1,14 +1,54 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
+// Loan is the domain model, it is never written to clients directly, see LoanRes.
 type Loan struct {
-	Id        int64        `db:"id" json:"id,omitempty"`
-	UserId    *int64       `db:"user_id" json:"user_id,omitempty"`
-	Amount    *float64     `db:"amount" json:"amount,omitempty"`
-	Status    string       `db:"status" json:"status,omitempty"`
-	CreatedAt time.Time    `db:"created_at" json:"created_at,omitempty"`
-	Repayment *[]Repayment `json:"repayment,omitempty"`
+	Id          int64      `db:"id"`
+	UserId      *int64     `db:"user_id"`
+	Amount      *float64   `db:"amount"`
+	Status      string     `db:"status"`
+	CreatedAt   time.Time  `db:"created_at"`
+	DisbursedAt *time.Time `db:"disbursed_at"`
+	Repayment   *[]Repayment
+}
+
+type LoanRes struct {
+	Id          int64          `json:"id"`
+	UserId      *int64         `json:"user_id,omitempty"`
+	Amount      *float64       `json:"amount,omitempty"`
+	Status      string         `json:"status"`
+	CreatedAt   time.Time      `json:"created_at"`
+	DisbursedAt *time.Time     `json:"disbursed_at,omitempty"`
+	Repayment   []RepaymentRes `json:"repayment,omitempty"`
+}
+
+func NewLoanRes(loan Loan) LoanRes {
+	res := LoanRes{
+		Id:          loan.Id,
+		UserId:      loan.UserId,
+		Amount:      loan.Amount,
+		Status:      loan.Status,
+		CreatedAt:   loan.CreatedAt,
+		DisbursedAt: loan.DisbursedAt,
+	}
+	if loan.Repayment != nil {
+		for _, repayment := range *loan.Repayment {
+			res.Repayment = append(res.Repayment, NewRepaymentRes(repayment))
+		}
+	}
+	return res
+}
+
+func NewLoanResList(loans []Loan) []LoanRes {
+	res := make([]LoanRes, 0, len(loans))
+	for _, loan := range loans {
+		res = append(res, NewLoanRes(loan))
+	}
+	return res
 }
 
 type NewLoanReq struct {
@@ -25,3 +65,36 @@ type PayLoanReq struct {
 	Term   int64   `json:"term"`
 	Amount float64 `json:"amount"`
 }
+
+func (r NewLoanReq) Validate() error {
+	var fields fieldErrors
+	if r.Amount <= 0 || r.Amount > MaxLoanAmount {
+		fields.add("amount", fmt.Sprintf("must be greater than 0 and at most %d", MaxLoanAmount))
+	}
+	if r.Terms < 1 || r.Terms > MaxLoanTerms {
+		fields.add("terms", fmt.Sprintf("must be between 1 and %d", MaxLoanTerms))
+	}
+	return fields.err()
+}
+
+func (r ApproveLoanReq) Validate() error {
+	var fields fieldErrors
+	if r.LoanId < 1 {
+		fields.add("loan_id", "is required")
+	}
+	return fields.err()
+}
+
+func (r PayLoanReq) Validate() error {
+	var fields fieldErrors
+	if r.LoanId < 1 {
+		fields.add("loan_id", "is required")
+	}
+	if r.Term < 1 || r.Term > MaxLoanTerms {
+		fields.add("term", fmt.Sprintf("must be between 1 and %d", MaxLoanTerms))
+	}
+	if r.Amount <= 0 {
+		fields.add("amount", "must be greater than 0")
+	}
+	return fields.err()
+}
@@ -0,0 +1,143 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Notification is a message to a user on one channel. It is rendered from the catalogue entry of Kind in Locale
+// with Data when it is sent, Subject keeps what was sent for the history.
+type Notification struct {
+	Id            int64           `db:"id"`
+	UserId        int64           `db:"user_id"`
+	Kind          string          `db:"kind"`
+	Channel       string          `db:"channel"`
+	Locale        string          `db:"locale"`
+	Recipient     string          `db:"recipient"`
+	Data          json.RawMessage `db:"data"`
+	Status        string          `db:"status"`
+	Attempts      int             `db:"attempts"`
+	NextAttemptAt time.Time       `db:"next_attempt_at"`
+	Subject       string          `db:"subject"`
+	LastError     string          `db:"last_error"`
+	CreatedAt     time.Time       `db:"created_at"`
+	SentAt        *time.Time      `db:"sent_at"`
+}
+
+// NotificationPreferences is the locale messages are rendered in and the channels a user is notified on.
+type NotificationPreferences struct {
+	Locale   string
+	Channels []ChannelPreference
+}
+
+// ChannelPreference enables a channel, Address is the phone number (sms) or device token (push). Email goes to the
+// account email and has no address.
+type ChannelPreference struct {
+	Channel string `db:"channel" json:"channel"`
+	Enabled bool   `db:"enabled" json:"enabled"`
+	Address string `db:"address" json:"address"`
+}
+
+// UserRegisteredNotification is the data of the welcome message.
+type UserRegisteredNotification struct {
+	Email string `json:"email"`
+}
+
+// RepaymentDueNotification is the data of the due date reminder of a term, DueDate is a time.DateOnly date.
+type RepaymentDueNotification struct {
+	LoanId         int64   `json:"loan_id"`
+	Term           int64   `json:"term"`
+	MinimumPayment float64 `json:"minimum_payment"`
+	DueDate        string  `json:"due_date"`
+}
+
+type NotificationRes struct {
+	Id        int64      `json:"id"`
+	Kind      string     `json:"kind"`
+	Channel   string     `json:"channel"`
+	Recipient string     `json:"recipient"`
+	Status    string     `json:"status"`
+	Subject   string     `json:"subject,omitempty"`
+	Attempts  int        `json:"attempts"`
+	CreatedAt time.Time  `json:"created_at"`
+	SentAt    *time.Time `json:"sent_at,omitempty"`
+}
+
+func NewNotificationRes(notification Notification) NotificationRes {
+	return NotificationRes{
+		Id:        notification.Id,
+		Kind:      notification.Kind,
+		Channel:   notification.Channel,
+		Recipient: notification.Recipient,
+		Status:    notification.Status,
+		Subject:   notification.Subject,
+		Attempts:  notification.Attempts,
+		CreatedAt: notification.CreatedAt,
+		SentAt:    notification.SentAt,
+	}
+}
+
+func NewNotificationResList(notifications []Notification) []NotificationRes {
+	res := make([]NotificationRes, 0, len(notifications))
+	for _, notification := range notifications {
+		res = append(res, NewNotificationRes(notification))
+	}
+	return res
+}
+
+type NotificationPreferencesRes struct {
+	Locale   string              `json:"locale"`
+	Channels []ChannelPreference `json:"channels"`
+}
+
+func NewNotificationPreferencesRes(prefs NotificationPreferences) NotificationPreferencesRes {
+	channels := prefs.Channels
+	if channels == nil {
+		channels = []ChannelPreference{}
+	}
+	return NotificationPreferencesRes{
+		Locale:   prefs.Locale,
+		Channels: channels,
+	}
+}
+
+type HttpResNotifications struct {
+	Message string            `json:"message,omitempty"`
+	Data    []NotificationRes `json:"data"`
+}
+
+type HttpResNotificationPreferences struct {
+	Message string                     `json:"message,omitempty"`
+	Data    NotificationPreferencesRes `json:"data"`
+}
+
+// UpdateNotificationPreferencesReq sets the locale and the listed channels, channels left out keep their setting.
+// Known locales and channels and the address formats are checked by the usecase.
+type UpdateNotificationPreferencesReq struct {
+	Locale   string              `json:"locale"`
+	Channels []ChannelPreference `json:"channels"`
+}
+
+func (r UpdateNotificationPreferencesReq) Validate() error {
+	var fields fieldErrors
+	if r.Locale == "" {
+		fields.add("locale", "is required")
+	}
+	seen := make(map[string]bool, len(r.Channels))
+	for _, channel := range r.Channels {
+		if channel.Channel == "" {
+			fields.add("channels", "must not contain empty channels")
+			break
+		}
+		if seen[channel.Channel] {
+			fields.add("channels", "must not contain duplicates")
+			break
+		}
+		seen[channel.Channel] = true
+		if len(channel.Address) > 4096 {
+			fields.add("channels", "address must not exceed 4096 characters")
+			break
+		}
+	}
+	return fields.err()
+}
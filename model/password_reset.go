@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// PasswordResetToken only stores the sha256 of the emailed token, a database leak can't be used to reset passwords.
+type PasswordResetToken struct {
+	Id        int64      `db:"id"`
+	UserId    int64      `db:"user_id"`
+	TokenHash string     `db:"token_hash"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
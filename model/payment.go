@@ -0,0 +1,106 @@
+package model
+
+import (
+	"time"
+
+	"example.com/m/v2/constant"
+)
+
+// PaymentIntent is a payment of a loan term the customer started at the payment gateway. It is only applied to the
+// loan once the gateway confirms the settlement through a signed webhook.
+type PaymentIntent struct {
+	Id               int64      `db:"id"`
+	LoanId           int64      `db:"loan_id"`
+	UserId           int64      `db:"user_id"`
+	Term             int64      `db:"term"`
+	Amount           float64    `db:"amount"`
+	Gateway          string     `db:"gateway"`
+	GatewayReference string     `db:"gateway_reference"`
+	VirtualAccount   string     `db:"virtual_account"`
+	Status           string     `db:"status"`
+	FailureReason    string     `db:"failure_reason"`
+	ReceivedAmount   *float64   `db:"received_amount"`
+	ExpiresAt        time.Time  `db:"expires_at"`
+	CreatedAt        time.Time  `db:"created_at"`
+	SettledAt        *time.Time `db:"settled_at"`
+}
+
+// PaymentIntentRequest asks the payment gateway for a way to pay Amount before ExpiresAt.
+type PaymentIntentRequest struct {
+	LoanId    int64
+	UserId    int64
+	Term      int64
+	Amount    float64
+	ExpiresAt time.Time
+}
+
+// PaymentIntentResult identifies the intent at the gateway and the virtual account the customer transfers to.
+type PaymentIntentResult struct {
+	Reference      string
+	VirtualAccount string
+}
+
+// WebhookSignature carries the signature headers of a payment gateway callback.
+type WebhookSignature struct {
+	Timestamp string
+	Nonce     string
+	Signature string
+}
+
+type PaymentIntentRes struct {
+	Id             int64      `json:"id"`
+	LoanId         int64      `json:"loan_id"`
+	Term           int64      `json:"term"`
+	Amount         float64    `json:"amount"`
+	Gateway        string     `json:"gateway"`
+	Reference      string     `json:"reference"`
+	VirtualAccount string     `json:"virtual_account,omitempty"`
+	Status         string     `json:"status"`
+	FailureReason  string     `json:"failure_reason,omitempty"`
+	ReceivedAmount *float64   `json:"received_amount,omitempty"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	SettledAt      *time.Time `json:"settled_at,omitempty"`
+}
+
+func NewPaymentIntentRes(intent PaymentIntent) PaymentIntentRes {
+	return PaymentIntentRes{
+		Id:             intent.Id,
+		LoanId:         intent.LoanId,
+		Term:           intent.Term,
+		Amount:         intent.Amount,
+		Gateway:        intent.Gateway,
+		Reference:      intent.GatewayReference,
+		VirtualAccount: intent.VirtualAccount,
+		Status:         intent.Status,
+		FailureReason:  intent.FailureReason,
+		ReceivedAmount: intent.ReceivedAmount,
+		ExpiresAt:      intent.ExpiresAt,
+		SettledAt:      intent.SettledAt,
+	}
+}
+
+type HttpResPaymentIntent struct {
+	Message string           `json:"message,omitempty"`
+	Data    PaymentIntentRes `json:"data"`
+}
+
+// PaymentWebhookReq is the settlement callback of the payment gateway, Status is SUCCEEDED or FAILED.
+type PaymentWebhookReq struct {
+	Reference string  `json:"reference"`
+	Status    string  `json:"status"`
+	Amount    float64 `json:"amount"`
+}
+
+func (r PaymentWebhookReq) Validate() error {
+	var fields fieldErrors
+	if r.Reference == "" {
+		fields.add("reference", "is required")
+	}
+	if r.Status != constant.PaymentIntentStatusSucceeded && r.Status != constant.PaymentIntentStatusFailed {
+		fields.add("status", "must be SUCCEEDED or FAILED")
+	}
+	if r.Amount <= 0 {
+		fields.add("amount", "must be greater than 0")
+	}
+	return fields.err()
+}
@@ -2,11 +2,64 @@ package model
 
 import "time"
 
+// Repayment is the domain model, it is never written to clients directly, see RepaymentRes.
+// DueDate is nil until the loan is disbursed, terms then fall due weekly from the disbursement date.
+// LateFees is the sum of the fee lines charged on the term, they are due with its minimum payment.
 type Repayment struct {
-	Id             int64     `db:"id" json:"id,omitempty"`
-	LoanId         int64     `db:"loan_id" json:"loan_id,omitempty"`
-	MinimumPayment float64   `db:"minimum_payment" json:"minimum_payment,omitempty"`
-	ActualPayment  *float64  `db:"actual_payment" json:"actual_payment,omitempty"`
-	Status         string    `db:"status" json:"status,omitempty"`
-	DueDate        time.Time `db:"due_date" json:"due_date,omitempty"`
+	Id             int64      `db:"id"`
+	LoanId         int64      `db:"loan_id"`
+	MinimumPayment float64    `db:"minimum_payment"`
+	ActualPayment  *float64   `db:"actual_payment"`
+	Status         string     `db:"status"`
+	DueDate        *time.Time `db:"due_date"`
+	LateFees       float64    `db:"late_fees"`
+}
+
+// RepaymentFee is a fee line charged on a term.
+type RepaymentFee struct {
+	Id          int64     `db:"id"`
+	RepaymentId int64     `db:"repayment_id"`
+	Kind        string    `db:"kind"`
+	Amount      float64   `db:"amount"`
+	AssessedAt  time.Time `db:"assessed_at"`
+}
+
+// OverdueRun sums up what MarkOverdue changed.
+type OverdueRun struct {
+	Overdue   int
+	Fees      int
+	FeeAmount float64
+	Defaulted int
+}
+
+// RepaymentReminder is a term falling due soon, with the borrower to remind of it.
+type RepaymentReminder struct {
+	RepaymentId    int64
+	LoanId         int64
+	Term           int64
+	UserId         int64
+	MinimumPayment float64
+	DueDate        time.Time
+}
+
+type RepaymentRes struct {
+	Id             int64      `json:"id"`
+	LoanId         int64      `json:"loan_id"`
+	MinimumPayment float64    `json:"minimum_payment"`
+	ActualPayment  *float64   `json:"actual_payment,omitempty"`
+	Status         string     `json:"status"`
+	DueDate        *time.Time `json:"due_date,omitempty"`
+	LateFees       float64    `json:"late_fees,omitempty"`
+}
+
+func NewRepaymentRes(repayment Repayment) RepaymentRes {
+	return RepaymentRes{
+		Id:             repayment.Id,
+		LoanId:         repayment.LoanId,
+		MinimumPayment: repayment.MinimumPayment,
+		ActualPayment:  repayment.ActualPayment,
+		Status:         repayment.Status,
+		DueDate:        repayment.DueDate,
+		LateFees:       repayment.LateFees,
+	}
 }
@@ -0,0 +1,79 @@
+package model
+
+// Role is a named set of permissions. ApprovalLimit caps the loans a role with loan:approve may approve,
+// nil means unlimited.
+type Role struct {
+	Name          string
+	Description   string
+	Permissions   []string
+	ApprovalLimit *float64
+}
+
+// Principal is an authenticated user with the staff roles granted to them, resolved on every request so
+// revoking a role takes effect immediately.
+type Principal struct {
+	UserId int64
+	Roles  []Role
+}
+
+type RoleRes struct {
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	Permissions   []string `json:"permissions"`
+	ApprovalLimit *float64 `json:"approval_limit"`
+}
+
+func NewRoleRes(role Role) RoleRes {
+	permissions := role.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return RoleRes{
+		Name:          role.Name,
+		Description:   role.Description,
+		Permissions:   permissions,
+		ApprovalLimit: role.ApprovalLimit,
+	}
+}
+
+func NewRoleResList(roles []Role) []RoleRes {
+	res := make([]RoleRes, 0, len(roles))
+	for _, role := range roles {
+		res = append(res, NewRoleRes(role))
+	}
+	return res
+}
+
+type HttpResRoles struct {
+	Message string    `json:"message,omitempty"`
+	Data    []RoleRes `json:"data"`
+}
+
+// AssignRolesReq replaces every staff role of a user, an empty list revokes them all.
+type AssignRolesReq struct {
+	UserId int64    `json:"user_id"`
+	Roles  []string `json:"roles"`
+}
+
+func (r AssignRolesReq) Validate() error {
+	var fields fieldErrors
+	if r.UserId < 1 {
+		fields.add("user_id", "is required")
+	}
+	if r.Roles == nil {
+		fields.add("roles", "is required")
+	}
+	seen := make(map[string]bool, len(r.Roles))
+	for _, role := range r.Roles {
+		if role == "" {
+			fields.add("roles", "must not contain empty names")
+			break
+		}
+		if seen[role] {
+			fields.add("roles", "must not contain duplicates")
+			break
+		}
+		seen[role] = true
+	}
+	return fields.err()
+}
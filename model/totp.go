@@ -0,0 +1,90 @@
+package model
+
+import "strings"
+
+// LoginResult holds either the session token or, when a second factor is needed, a challenge token.
+type LoginResult struct {
+	SessionToken   string
+	ChallengeToken string
+	// TwoFactor is constant.TwoFactorVerify or constant.TwoFactorEnroll when ChallengeToken is set
+	TwoFactor string
+}
+
+// TotpEnrollment is a pending TOTP secret, it becomes active once a code generated from it is verified.
+type TotpEnrollment struct {
+	Secret          string
+	ProvisioningUri string
+}
+
+type LoginRes struct {
+	Message        string `json:"message"`
+	TwoFactor      string `json:"two_factor,omitempty"`
+	ChallengeToken string `json:"challenge_token,omitempty"`
+}
+
+// LoginTotpReq completes a login, Code is a TOTP code or a recovery code.
+type LoginTotpReq struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+// TotpEnrollReq authenticates with the enroll challenge of a login, or with the session cookie when it is empty.
+type TotpEnrollReq struct {
+	ChallengeToken string `json:"challenge_token"`
+}
+
+// TotpEnableReq authenticates like TotpEnrollReq.
+type TotpEnableReq struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+type TotpEnrollRes struct {
+	Secret          string `json:"secret"`
+	ProvisioningUri string `json:"provisioning_uri"`
+}
+
+func NewTotpEnrollRes(enrollment TotpEnrollment) TotpEnrollRes {
+	return TotpEnrollRes{
+		Secret:          enrollment.Secret,
+		ProvisioningUri: enrollment.ProvisioningUri,
+	}
+}
+
+// TotpEnableRes lists the recovery codes, they are only shown this once.
+type TotpEnableRes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type HttpResTotpEnroll struct {
+	Message string        `json:"message,omitempty"`
+	Data    TotpEnrollRes `json:"data"`
+}
+
+type HttpResTotpEnable struct {
+	Message string        `json:"message,omitempty"`
+	Data    TotpEnableRes `json:"data"`
+}
+
+func (r LoginTotpReq) Validate() error {
+	var fields fieldErrors
+	if r.ChallengeToken == "" {
+		fields.add("challenge_token", "is required")
+	}
+	if strings.TrimSpace(r.Code) == "" {
+		fields.add("code", "is required")
+	}
+	return fields.err()
+}
+
+func (r TotpEnrollReq) Validate() error {
+	return nil
+}
+
+func (r TotpEnableReq) Validate() error {
+	var fields fieldErrors
+	if r.Code == "" {
+		fields.add("code", "is required")
+	}
+	return fields.err()
+}
@@ -1,8 +1,173 @@
 package model
 
+import (
+	"net"
+	"strings"
+	"time"
+)
+
+// User is the domain model, Password holds the bcrypt hash and must never reach a client, see UserRes.
 type User struct {
-	Id       int64  `db:"id" json:"id,omitempty"`
-	Email    string `db:"email" json:"email,omitempty"`
-	Password string `db:"password" json:"password,omitempty"`
-	Role     string `db:"role" json:"role,omitempty"`
+	Id       int64  `db:"id"`
+	Email    string `db:"email"`
+	Password string `db:"password" json:"-"`
+	Role     string `db:"role"`
+	// EmailVerifiedAt is nil until the emailed verification link is used
+	EmailVerifiedAt *time.Time `db:"email_verified_at"`
+	// SessionVersion is signed into session tokens, bumping it logs the user out everywhere
+	SessionVersion int64 `db:"session_version"`
+	// TotpSecret is the base32 TOTP key, set on enrollment and only used once TotpEnabledAt is set
+	TotpSecret    string     `db:"totp_secret" json:"-"`
+	TotpEnabledAt *time.Time `db:"totp_enabled_at"`
+	// TotpLastStep is the last accepted TOTP time step
+	TotpLastStep int64 `db:"totp_last_step"`
+	// PasswordChangeRequired keeps the user's sessions to the password change until a new password is set
+	PasswordChangeRequired bool `db:"password_change_required" json:"-"`
+}
+
+func (u User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+func (u User) TotpEnabled() bool {
+	return u.TotpEnabledAt != nil
+}
+
+// NormalizeEmail is applied before an email is stored or looked up, emails are case-insensitive.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+type LoginReq struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type RegisterReq struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type ForgotPasswordReq struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordReq struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type ChangePasswordReq struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+type VerifyEmailReq struct {
+	Token string `json:"token"`
+}
+
+// UnlockLoginReq clears the failed logins of an email, an ip or both.
+type UnlockLoginReq struct {
+	Email string `json:"email"`
+	Ip    string `json:"ip"`
+}
+
+// UserRes is the public profile of a user.
+type UserRes struct {
+	Id            int64  `json:"id"`
+	Email         string `json:"email"`
+	Role          string `json:"role"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+func NewUserRes(user User) UserRes {
+	return UserRes{
+		Id:            user.Id,
+		Email:         user.Email,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerified(),
+	}
+}
+
+func (r LoginReq) Validate() error {
+	return validateCredentials(r.Email, r.Password)
+}
+
+// ToUser maps the body to the domain model, the role and verification are always decided by the usecase.
+func (r RegisterReq) ToUser() User {
+	return User{
+		Email:    r.Email,
+		Password: r.Password,
+	}
+}
+
+func (r RegisterReq) Validate() error {
+	return validateCredentials(r.Email, r.Password)
+}
+
+func (r VerifyEmailReq) Validate() error {
+	var fields fieldErrors
+	if r.Token == "" {
+		fields.add("token", "is required")
+	}
+	return fields.err()
+}
+
+func (r ForgotPasswordReq) Validate() error {
+	var fields fieldErrors
+	if r.Email == "" {
+		fields.add("email", "is required")
+	} else if !validEmail(NormalizeEmail(r.Email)) {
+		fields.add("email", "must be a valid email address")
+	}
+	return fields.err()
+}
+
+func (r UnlockLoginReq) Validate() error {
+	var fields fieldErrors
+	if r.Email == "" && r.Ip == "" {
+		fields.add("email", "email or ip is required")
+	}
+	if r.Email != "" && !validEmail(NormalizeEmail(r.Email)) {
+		fields.add("email", "must be a valid email address")
+	}
+	if r.Ip != "" && net.ParseIP(r.Ip) == nil {
+		fields.add("ip", "must be a valid ip address")
+	}
+	return fields.err()
+}
+
+func (r ResetPasswordReq) Validate() error {
+	var fields fieldErrors
+	if r.Token == "" {
+		fields.add("token", "is required")
+	}
+	if r.Password == "" {
+		fields.add("password", "is required")
+	}
+	return fields.err()
+}
+
+func (r ChangePasswordReq) Validate() error {
+	var fields fieldErrors
+	if r.OldPassword == "" {
+		fields.add("old_password", "is required")
+	}
+	if r.NewPassword == "" {
+		fields.add("new_password", "is required")
+	}
+	return fields.err()
+}
+
+func validateCredentials(email, password string) error {
+	var fields fieldErrors
+	if email == "" {
+		fields.add("email", "is required")
+	} else if !validEmail(NormalizeEmail(email)) {
+		fields.add("email", "must be a valid email address")
+	}
+	if password == "" {
+		fields.add("password", "is required")
+	}
+	return fields.err()
 }
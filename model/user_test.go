@@ -0,0 +1,90 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sensitiveJsonNames must never appear as a json key of anything written to clients.
+var sensitiveJsonNames = []string{"password", "hash"}
+
+func Test_UserNeverMarshalsPassword(t *testing.T) {
+	hash := "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy"
+	user := User{
+		Id:         1,
+		Email:      "tes@tes.com",
+		Password:   hash,
+		Role:       "CUSTOMER",
+		TotpSecret: "JBSWY3DPEHPK3PXP",
+	}
+
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{
+			name:  "domain user",
+			value: user,
+		},
+		{
+			name:  "user response",
+			value: NewUserRes(user),
+		},
+		{
+			name: "user response envelope",
+			value: HttpResUser{
+				Message: "success",
+				Data:    NewUserRes(user),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(tt.value)
+			if err != nil {
+				t.Fatalf("marshal failed: %v", err)
+			}
+			if strings.Contains(string(b), hash) || strings.Contains(strings.ToLower(string(b)), "password") {
+				t.Errorf("password marshalled: %s", b)
+			}
+			if strings.Contains(string(b), user.TotpSecret) {
+				t.Errorf("totp secret marshalled: %s", b)
+			}
+		})
+	}
+}
+
+func Test_ResponseHasNoSensitiveField(t *testing.T) {
+	responses := []interface{}{
+		HttpRes{},
+		HttpResLoan{},
+		HttpResUser{},
+		UserRes{},
+		LoginRes{},
+		TotpEnableRes{},
+		LoanRes{},
+		RepaymentRes{},
+		Problem{},
+	}
+
+	for _, res := range responses {
+		typ := reflect.TypeOf(res)
+		t.Run(typ.Name(), func(t *testing.T) {
+			for i := 0; i < typ.NumField(); i++ {
+				field := typ.Field(i)
+				name := strings.Split(field.Tag.Get("json"), ",")[0]
+				if name == "" {
+					name = field.Name
+				}
+				for _, sensitive := range sensitiveJsonNames {
+					if strings.Contains(strings.ToLower(name), sensitive) {
+						t.Errorf("%s.%s is marshalled as %q", typ.Name(), field.Name, name)
+					}
+				}
+			}
+		})
+	}
+}
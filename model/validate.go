@@ -0,0 +1,45 @@
+package model
+
+import (
+	"net/mail"
+	"strings"
+
+	"example.com/m/v2/apperror"
+)
+
+const (
+	// MaxLoanTerms caps the number of weekly repayments of a loan
+	MaxLoanTerms = 520
+	// MaxLoanAmount caps the principal of a single loan
+	MaxLoanAmount = 1_000_000_000
+)
+
+// Validator is implemented by request bodies, the handler rejects the request with a 422 when Validate fails.
+type Validator interface {
+	Validate() error
+}
+
+type fieldErrors []apperror.FieldError
+
+func (f *fieldErrors) add(field, message string) {
+	*f = append(*f, apperror.FieldError{Field: field, Message: message})
+}
+
+// err returns nil when no field was rejected.
+func (f fieldErrors) err() error {
+	if len(f) == 0 {
+		return nil
+	}
+	return apperror.Validation(f...)
+}
+
+// validEmail accepts a bare address (no display name) whose domain has at least one dot.
+func validEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return false
+	}
+	at := strings.LastIndex(email, "@")
+	domain := email[at+1:]
+	return strings.Contains(domain, ".") && !strings.HasPrefix(domain, ".") && !strings.HasSuffix(domain, ".")
+}
@@ -0,0 +1,247 @@
+package model
+
+import (
+	"time"
+
+	"example.com/m/v2/constant"
+)
+
+// WebhookEndpoint is a partner url notified of loan events. EventTypes filters the events it receives, empty
+// receives every event. Secret signs every delivery, it is only shown when created or rotated.
+type WebhookEndpoint struct {
+	Id          int64     `db:"id"`
+	Url         string    `db:"url"`
+	Description string    `db:"description"`
+	Secret      string    `db:"secret"`
+	EventTypes  []string  `db:"event_types"`
+	Active      bool      `db:"active"`
+	CreatedBy   int64     `db:"created_by"`
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+// WebhookDelivery is an outbox event to be sent to an endpoint, Log holds its attempts when loaded.
+type WebhookDelivery struct {
+	Id            int64      `db:"id"`
+	EndpointId    int64      `db:"endpoint_id"`
+	EventId       int64      `db:"event_id"`
+	EventType     string     `db:"event_type"`
+	Status        string     `db:"status"`
+	Attempts      int        `db:"attempts"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	ResponseCode  *int       `db:"response_code"`
+	LastError     string     `db:"last_error"`
+	CreatedAt     time.Time  `db:"created_at"`
+	DeliveredAt   *time.Time `db:"delivered_at"`
+	Log           []WebhookDeliveryAttempt
+}
+
+// WebhookDeliveryAttempt is an entry of the delivery log, ResponseCode is nil when no response came back.
+type WebhookDeliveryAttempt struct {
+	Id           int64         `db:"id"`
+	DeliveryId   int64         `db:"delivery_id"`
+	AttemptedAt  time.Time     `db:"attempted_at"`
+	ResponseCode *int          `db:"response_code"`
+	Error        string        `db:"error"`
+	Duration     time.Duration `db:"duration_ms"`
+}
+
+type WebhookEndpointRes struct {
+	Id          int64     `json:"id"`
+	Url         string    `json:"url"`
+	Description string    `json:"description"`
+	Secret      string    `json:"secret,omitempty"`
+	EventTypes  []string  `json:"event_types"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// NewWebhookEndpointRes leaves the secret out, withSecret is only set right after it was generated.
+func NewWebhookEndpointRes(endpoint WebhookEndpoint, withSecret bool) WebhookEndpointRes {
+	eventTypes := endpoint.EventTypes
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	res := WebhookEndpointRes{
+		Id:          endpoint.Id,
+		Url:         endpoint.Url,
+		Description: endpoint.Description,
+		EventTypes:  eventTypes,
+		Active:      endpoint.Active,
+		CreatedAt:   endpoint.CreatedAt,
+		UpdatedAt:   endpoint.UpdatedAt,
+	}
+	if withSecret {
+		res.Secret = endpoint.Secret
+	}
+	return res
+}
+
+func NewWebhookEndpointResList(endpoints []WebhookEndpoint) []WebhookEndpointRes {
+	res := make([]WebhookEndpointRes, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		res = append(res, NewWebhookEndpointRes(endpoint, false))
+	}
+	return res
+}
+
+type WebhookDeliveryAttemptRes struct {
+	AttemptedAt  time.Time `json:"attempted_at"`
+	ResponseCode *int      `json:"response_code"`
+	Error        string    `json:"error,omitempty"`
+	DurationMs   int64     `json:"duration_ms"`
+}
+
+type WebhookDeliveryRes struct {
+	Id            int64                       `json:"id"`
+	EndpointId    int64                       `json:"endpoint_id"`
+	EventId       int64                       `json:"event_id"`
+	EventType     string                      `json:"event_type"`
+	Status        string                      `json:"status"`
+	Attempts      int                         `json:"attempts"`
+	NextAttemptAt *time.Time                  `json:"next_attempt_at,omitempty"`
+	ResponseCode  *int                        `json:"response_code"`
+	LastError     string                      `json:"last_error,omitempty"`
+	CreatedAt     time.Time                   `json:"created_at"`
+	DeliveredAt   *time.Time                  `json:"delivered_at,omitempty"`
+	Log           []WebhookDeliveryAttemptRes `json:"log,omitempty"`
+}
+
+// NewWebhookDeliveryRes only shows the next attempt of pending deliveries.
+func NewWebhookDeliveryRes(delivery WebhookDelivery) WebhookDeliveryRes {
+	res := WebhookDeliveryRes{
+		Id:           delivery.Id,
+		EndpointId:   delivery.EndpointId,
+		EventId:      delivery.EventId,
+		EventType:    delivery.EventType,
+		Status:       delivery.Status,
+		Attempts:     delivery.Attempts,
+		ResponseCode: delivery.ResponseCode,
+		LastError:    delivery.LastError,
+		CreatedAt:    delivery.CreatedAt,
+		DeliveredAt:  delivery.DeliveredAt,
+	}
+	if delivery.Status == constant.WebhookDeliveryPending {
+		next := delivery.NextAttemptAt
+		res.NextAttemptAt = &next
+	}
+	for _, attempt := range delivery.Log {
+		res.Log = append(res.Log, WebhookDeliveryAttemptRes{
+			AttemptedAt:  attempt.AttemptedAt,
+			ResponseCode: attempt.ResponseCode,
+			Error:        attempt.Error,
+			DurationMs:   attempt.Duration.Milliseconds(),
+		})
+	}
+	return res
+}
+
+func NewWebhookDeliveryResList(deliveries []WebhookDelivery) []WebhookDeliveryRes {
+	res := make([]WebhookDeliveryRes, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		res = append(res, NewWebhookDeliveryRes(delivery))
+	}
+	return res
+}
+
+type HttpResWebhookEndpoint struct {
+	Message string             `json:"message,omitempty"`
+	Data    WebhookEndpointRes `json:"data"`
+}
+
+type HttpResWebhookEndpoints struct {
+	Message string               `json:"message,omitempty"`
+	Data    []WebhookEndpointRes `json:"data"`
+}
+
+type HttpResWebhookDelivery struct {
+	Message string             `json:"message,omitempty"`
+	Data    WebhookDeliveryRes `json:"data"`
+}
+
+type HttpResWebhookDeliveries struct {
+	Message string               `json:"message,omitempty"`
+	Data    []WebhookDeliveryRes `json:"data"`
+}
+
+// CreateWebhookEndpointReq registers an endpoint, the url scheme and event types are checked by the usecase.
+type CreateWebhookEndpointReq struct {
+	Url         string   `json:"url"`
+	Description string   `json:"description"`
+	EventTypes  []string `json:"event_types"`
+}
+
+func (r CreateWebhookEndpointReq) Validate() error {
+	var fields fieldErrors
+	validateWebhookEndpoint(&fields, r.Url, r.Description, r.EventTypes)
+	return fields.err()
+}
+
+// UpdateWebhookEndpointReq replaces the settings of an endpoint, its secret is rotated separately.
+type UpdateWebhookEndpointReq struct {
+	Id          int64    `json:"id"`
+	Url         string   `json:"url"`
+	Description string   `json:"description"`
+	EventTypes  []string `json:"event_types"`
+	Active      *bool    `json:"active"`
+}
+
+func (r UpdateWebhookEndpointReq) Validate() error {
+	var fields fieldErrors
+	if r.Id < 1 {
+		fields.add("id", "is required")
+	}
+	validateWebhookEndpoint(&fields, r.Url, r.Description, r.EventTypes)
+	if r.Active == nil {
+		fields.add("active", "is required")
+	}
+	return fields.err()
+}
+
+func validateWebhookEndpoint(fields *fieldErrors, url, description string, eventTypes []string) {
+	if url == "" {
+		fields.add("url", "is required")
+	} else if len(url) > 2048 {
+		fields.add("url", "must not exceed 2048 characters")
+	}
+	if len(description) > 200 {
+		fields.add("description", "must not exceed 200 characters")
+	}
+	seen := make(map[string]bool, len(eventTypes))
+	for _, eventType := range eventTypes {
+		if eventType == "" {
+			fields.add("event_types", "must not contain empty types")
+			break
+		}
+		if seen[eventType] {
+			fields.add("event_types", "must not contain duplicates")
+			break
+		}
+		seen[eventType] = true
+	}
+}
+
+type RotateWebhookSecretReq struct {
+	Id int64 `json:"id"`
+}
+
+func (r RotateWebhookSecretReq) Validate() error {
+	var fields fieldErrors
+	if r.Id < 1 {
+		fields.add("id", "is required")
+	}
+	return fields.err()
+}
+
+type RedeliverWebhookReq struct {
+	DeliveryId int64 `json:"delivery_id"`
+}
+
+func (r RedeliverWebhookReq) Validate() error {
+	var fields fieldErrors
+	if r.DeliveryId < 1 {
+		fields.add("delivery_id", "is required")
+	}
+	return fields.err()
+}
@@ -0,0 +1,198 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"example.com/m/v2/constant"
+)
+
+//go:embed templates
+var templates embed.FS
+
+var ErrUnknownKind = errors.New("unknown notification kind")
+
+// Content is a rendered notification: Subject and Text for every channel, Html for email and Short for sms and push.
+type Content struct {
+	Subject string
+	Text    string
+	Html    string
+	Short   string
+}
+
+// Catalogue renders notifications from the templates of their locale. <locale>.txt defines <kind>.subject,
+// <kind>.text and <kind>.short, <locale>.html defines <kind>.html. Templates get the notification data as a map, an
+// amount function formatting numbers and a date function formatting time.DateOnly dates the way the locale does.
+type Catalogue struct {
+	defaultLocale string
+	text          map[string]*texttemplate.Template
+	html          map[string]*htmltemplate.Template
+}
+
+// DefaultCatalogue is the catalogue shipped with the binary, in every locale of constant.NotificationLocales.
+func DefaultCatalogue(defaultLocale string) (*Catalogue, error) {
+	fsys, err := fs.Sub(templates, "templates")
+	if err != nil {
+		return nil, err
+	}
+	return NewCatalogue(fsys, constant.NotificationLocales, defaultLocale)
+}
+
+// NewCatalogue parses the templates of locales from fsys, defaultLocale renders the locales it lacks.
+func NewCatalogue(fsys fs.FS, locales []string, defaultLocale string) (*Catalogue, error) {
+	if !slices.Contains(locales, defaultLocale) {
+		return nil, fmt.Errorf("default locale %q is not in the catalogue", defaultLocale)
+	}
+
+	c := &Catalogue{
+		defaultLocale: defaultLocale,
+		text:          make(map[string]*texttemplate.Template, len(locales)),
+		html:          make(map[string]*htmltemplate.Template, len(locales)),
+	}
+	for _, locale := range locales {
+		funcs := map[string]any{"amount": amountFormatter(locale), "date": dateFormatter(locale)}
+
+		text, err := texttemplate.New(locale).Funcs(funcs).Option("missingkey=error").ParseFS(fsys, locale+".txt")
+		if err != nil {
+			return nil, fmt.Errorf("parse %s text templates: %w", locale, err)
+		}
+		html, err := htmltemplate.New(locale).Funcs(funcs).Option("missingkey=error").ParseFS(fsys, locale+".html")
+		if err != nil {
+			return nil, fmt.Errorf("parse %s html templates: %w", locale, err)
+		}
+
+		c.text[locale], c.html[locale] = text, html
+	}
+
+	return c, nil
+}
+
+// Render renders kind in locale, or in the default locale when the catalogue lacks locale.
+func (c *Catalogue) Render(kind, locale string, data json.RawMessage) (content Content, err error) {
+	if _, ok := c.text[locale]; !ok {
+		locale = c.defaultLocale
+	}
+	text, html := c.text[locale], c.html[locale]
+	if text.Lookup(kind+".subject") == nil {
+		err = fmt.Errorf("%w %q", ErrUnknownKind, kind)
+		return
+	}
+
+	// numbers stay json.Number so ids print as they are and amount gets their exact value
+	var values map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err = dec.Decode(&values)
+	if err != nil {
+		err = fmt.Errorf("decode notification data: %w", err)
+		return
+	}
+
+	var buf strings.Builder
+	for _, part := range []struct {
+		name string
+		dst  *string
+	}{
+		{kind + ".subject", &content.Subject},
+		{kind + ".text", &content.Text},
+		{kind + ".short", &content.Short},
+	} {
+		buf.Reset()
+		err = text.ExecuteTemplate(&buf, part.name, values)
+		if err != nil {
+			return
+		}
+		*part.dst = strings.TrimSpace(buf.String())
+	}
+
+	buf.Reset()
+	err = html.ExecuteTemplate(&buf, kind+".html", values)
+	if err != nil {
+		return
+	}
+	content.Html = buf.String()
+
+	return
+}
+
+// amountSeparators are the thousands and decimal separators of a locale, locales missing here use en.
+var amountSeparators = map[string][2]string{
+	"en": {",", "."},
+	"id": {".", ","},
+}
+
+// amountFormatter formats numbers with two decimals and grouped thousands, like 1,250,000.00 in en.
+func amountFormatter(locale string) func(v any) (string, error) {
+	sep, ok := amountSeparators[locale]
+	if !ok {
+		sep = amountSeparators["en"]
+	}
+
+	return func(v any) (string, error) {
+		var f float64
+		switch n := v.(type) {
+		case json.Number:
+			var err error
+			f, err = n.Float64()
+			if err != nil {
+				return "", err
+			}
+		case float64:
+			f = n
+		case int:
+			f = float64(n)
+		default:
+			return "", fmt.Errorf("amount of %T", v)
+		}
+
+		cents := int64(math.Round(math.Abs(f) * 100))
+		units := strconv.FormatInt(cents/100, 10)
+		var grouped strings.Builder
+		if f < 0 && cents > 0 {
+			grouped.WriteByte('-')
+		}
+		for i, digit := range units {
+			if i > 0 && (len(units)-i)%3 == 0 {
+				grouped.WriteString(sep[0])
+			}
+			grouped.WriteRune(digit)
+		}
+		return fmt.Sprintf("%s%s%02d", grouped.String(), sep[1], cents%100), nil
+	}
+}
+
+// monthNames are the month names of a locale, locales missing here use the english ones.
+var monthNames = map[string][12]string{
+	"id": {"Januari", "Februari", "Maret", "April", "Mei", "Juni", "Juli", "Agustus", "September", "Oktober", "November", "Desember"},
+}
+
+// dateFormatter formats a time.DateOnly date as day, month name and year, like 21 October 2026 in en.
+func dateFormatter(locale string) func(v any) (string, error) {
+	return func(v any) (string, error) {
+		s, ok := v.(string)
+		if !ok {
+			return "", fmt.Errorf("date of %T", v)
+		}
+		d, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			return "", err
+		}
+
+		month := d.Month().String()
+		if names, ok := monthNames[locale]; ok {
+			month = names[d.Month()-1]
+		}
+		return fmt.Sprintf("%d %s %d", d.Day(), month, d.Year()), nil
+	}
+}
@@ -0,0 +1,162 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"example.com/m/v2/config"
+	"example.com/m/v2/constant"
+)
+
+// Message is a rendered notification addressed to its recipient. Only email messages have Html, sms messages have
+// no Subject.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	Html    string
+}
+
+// Channel sends messages to users, implementations must be safe for concurrent use.
+type Channel interface {
+	Send(ctx context.Context, msg Message) error
+	Close() error
+}
+
+// NewChannels builds the channel of every driver configured in cfg, channels set to none are left out. Console
+// channels write to stdout, which is not closed.
+func NewChannels(cfg config.Notifications, stdout io.Writer) (channels map[string]Channel, err error) {
+	channels = make(map[string]Channel, len(constant.NotificationChannels))
+	defer func() {
+		if err != nil {
+			CloseChannels(channels)
+		}
+	}()
+
+	for _, channel := range []struct{ name, driver, url, token string }{
+		{constant.NotificationChannelEmail, cfg.Email, "", ""},
+		{constant.NotificationChannelSms, cfg.Sms, cfg.SmsUrl, cfg.SmsToken},
+		{constant.NotificationChannelPush, cfg.Push, cfg.PushUrl, cfg.PushToken},
+	} {
+		switch channel.driver {
+		case "none":
+		case "console":
+			channels[channel.name] = NewWriterChannel(channel.name, struct{ io.Writer }{stdout})
+		case "file":
+			file, errOpen := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+			if errOpen != nil {
+				return nil, fmt.Errorf("open notifications file: %w", errOpen)
+			}
+			channels[channel.name] = NewWriterChannel(channel.name, file)
+		case "smtp":
+			channels[channel.name] = NewSmtpChannel(cfg.SmtpHost, cfg.SmtpPort, cfg.SmtpUsername, cfg.SmtpPassword, cfg.SmtpFrom, cfg.Timeout)
+		case "http":
+			channels[channel.name] = NewHttpChannel(channel.url, channel.token, &http.Client{Timeout: cfg.Timeout})
+		default:
+			return nil, fmt.Errorf("unknown %s driver %q", channel.name, channel.driver)
+		}
+	}
+
+	return channels, nil
+}
+
+// CloseChannels closes every channel, joining their errors.
+func CloseChannels(channels map[string]Channel) error {
+	var errs []error
+	for _, channel := range channels {
+		errs = append(errs, channel.Close())
+	}
+	return errors.Join(errs...)
+}
+
+type writerChannel struct {
+	mu   sync.Mutex
+	name string
+	w    io.Writer
+}
+
+// NewWriterChannel writes every message to w in a readable text form, for local development. w is closed on Close
+// when it is an io.Closer.
+func NewWriterChannel(name string, w io.Writer) Channel {
+	return &writerChannel{name: name, w: w}
+}
+
+func (c *writerChannel) Send(ctx context.Context, msg Message) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s to %s\n", c.name, msg.To)
+	if msg.Subject != "" {
+		fmt.Fprintf(&buf, "Subject: %s\n", msg.Subject)
+	}
+	fmt.Fprintf(&buf, "\n%s\n", msg.Text)
+	if msg.Html != "" {
+		fmt.Fprintf(&buf, "\n--- html\n%s\n", msg.Html)
+	}
+	buf.WriteString("\n")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.w.Write(buf.Bytes())
+	return err
+}
+
+func (c *writerChannel) Close() error {
+	if closer, ok := c.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+type httpChannel struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewHttpChannel hands messages to an sms or push provider: a POST of {"to", "title", "text"} to url with token as
+// bearer token, any status but 2xx fails the message.
+func NewHttpChannel(url, token string, client *http.Client) Channel {
+	return &httpChannel{url: url, token: token, client: client}
+}
+
+func (c *httpChannel) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(map[string]string{
+		"to":    msg.To,
+		"title": msg.Subject,
+		"text":  msg.Text,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("provider answered %s", res.Status)
+	}
+	return nil
+}
+
+func (c *httpChannel) Close() error {
+	return nil
+}
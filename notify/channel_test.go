@@ -0,0 +1,171 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/m/v2/config"
+	"example.com/m/v2/constant"
+)
+
+func Test_NewChannels(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "notifications.log")
+	var stdout bytes.Buffer
+
+	channels, err := NewChannels(config.Notifications{Email: "console", Sms: "file", Push: "none", File: file}, &stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(channels) != 2 || channels[constant.NotificationChannelPush] != nil {
+		t.Fatalf("want email and sms channels, got %v", channels)
+	}
+
+	err = channels[constant.NotificationChannelEmail].Send(context.Background(), Message{To: "tes@tes.com", Subject: "Hi", Text: "Hello", Html: "<p>Hello</p>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = channels[constant.NotificationChannelSms].Send(context.Background(), Message{To: "+6281234567890", Text: "Hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = CloseChannels(channels); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "--- email to tes@tes.com\nSubject: Hi\n\nHello\n\n--- html\n<p>Hello</p>\n\n"
+	if stdout.String() != want {
+		t.Errorf("want console output %q, got %q", want, stdout.String())
+	}
+	written, _ := os.ReadFile(file)
+	if want := "--- sms to +6281234567890\n\nHello\n\n"; string(written) != want {
+		t.Errorf("want file content %q, got %q", want, written)
+	}
+
+	if _, err = NewChannels(config.Notifications{Email: "pigeon", Sms: "none", Push: "none"}, &stdout); err == nil {
+		t.Error("want an error for an unknown driver")
+	}
+}
+
+func Test_httpChannel_Send(t *testing.T) {
+	var gotAuth string
+	var gotBody map[string]string
+	status := http.StatusAccepted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	channel := NewHttpChannel(server.URL, "secret", server.Client())
+	err := channel.Send(context.Background(), Message{To: "device-token", Subject: "Hi", Text: "Hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotAuth != "Bearer secret" || gotBody["to"] != "device-token" || gotBody["title"] != "Hi" || gotBody["text"] != "Hello" {
+		t.Errorf("unexpected request: %q %v", gotAuth, gotBody)
+	}
+
+	status = http.StatusServiceUnavailable
+	err = channel.Send(context.Background(), Message{To: "device-token", Text: "Hello"})
+	if err == nil || err.Error() != "provider answered 503 Service Unavailable" {
+		t.Errorf("want the provider status as error, got %v", err)
+	}
+}
+
+// fakeSmtpServer accepts one mail without STARTTLS nor AUTH and returns the DATA it received.
+func fakeSmtpServer(t *testing.T) (host string, port int, data <-chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); verb {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250 localhost")
+			case "MAIL", "RCPT":
+				tp.PrintfLine("250 ok")
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				lines, err := tp.ReadDotLines()
+				if err != nil {
+					return
+				}
+				received <- strings.Join(lines, "\n")
+				tp.PrintfLine("250 queued")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				return
+			default:
+				tp.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+
+	addr := l.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, received
+}
+
+func Test_smtpChannel_Send(t *testing.T) {
+	host, port, data := fakeSmtpServer(t)
+
+	channel := NewSmtpChannel(host, port, "", "", "Mini Aspire <no-reply@mini-aspire.local>", time.Second)
+	err := channel.Send(context.Background(), Message{To: "tes@tes.com", Subject: "Pinjaman disetujui ✓", Text: "Hello", Html: "<p>Hello</p>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mail := <-data
+	for _, want := range []string{
+		"From: Mini Aspire <no-reply@mini-aspire.local>",
+		"To: tes@tes.com",
+		"Subject: =?utf-8?q?Pinjaman_disetujui_=E2=9C=93?=",
+		"Content-Type: multipart/alternative",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Type: text/html; charset=utf-8",
+		"<p>Hello</p>",
+	} {
+		if !strings.Contains(mail, want) {
+			t.Errorf("want mail containing %q, got:\n%s", want, mail)
+		}
+	}
+}
+
+func Test_smtpChannel_Send_unreachable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	channel := NewSmtpChannel("127.0.0.1", port, "", "", "no-reply@mini-aspire.local", time.Second)
+	if err = channel.Send(context.Background(), Message{To: "tes@tes.com", Text: "Hello"}); err == nil {
+		t.Error("want an error when the relay is unreachable")
+	}
+}
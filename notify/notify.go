@@ -0,0 +1,184 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"example.com/m/v2/config"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/metrics"
+	"example.com/m/v2/poll"
+)
+
+// Notification is a claimed notification with what it takes to render and send it.
+type Notification struct {
+	Id        int64
+	Kind      string
+	Channel   string
+	Locale    string
+	Recipient string
+	Data      json.RawMessage
+	Attempts  int
+}
+
+// Outcome is what became of a notification after an attempt.
+type Outcome struct {
+	At time.Time
+	// Status is the notification status after the attempt, NextAttemptAt only matters while it is PENDING
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	// Subject is the rendered subject, empty when rendering failed
+	Subject string
+	Error   string
+}
+
+// Store hands out the notifications to send.
+type Store interface {
+	// Claim starts a batch of at most limit pending notifications due at now.
+	Claim(ctx context.Context, now time.Time, limit int) (Batch, error)
+	// RetryFailed puts the failed notification id, or every failed notification when id is 0, back to PENDING.
+	RetryFailed(ctx context.Context, id int64, now time.Time) (retried int64, err error)
+}
+
+// Batch holds claimed notifications, see poll.Batch.
+type Batch interface {
+	poll.Batch
+	Notifications() []Notification
+	Record(ctx context.Context, id int64, outcome Outcome) error
+}
+
+// Dispatcher renders notifications from the catalogue and sends them on their channel. A failed send is retried
+// with an exponential backoff until MaxAttempts, then the notification is FAILED until retried by hand. A
+// notification that can't be rendered fails at once. Sending is at least once, a crash after sending sends again.
+type Dispatcher struct {
+	store     Store
+	catalogue *Catalogue
+	channels  map[string]Channel
+	cfg       config.Notifications
+	now       func() time.Time
+}
+
+func NewDispatcher(store Store, catalogue *Catalogue, channels map[string]Channel, cfg config.Notifications) *Dispatcher {
+	return &Dispatcher{
+		store:     store,
+		catalogue: catalogue,
+		channels:  channels,
+		cfg:       cfg,
+		now:       time.Now,
+	}
+}
+
+// Run dispatches batches of notifications every PollInterval until ctx is done, see poll.Run.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	poll.Run(ctx, "notification dispatcher", d.cfg.PollInterval, d.DispatchBatch)
+	return nil
+}
+
+// DispatchBatch sends one batch of notifications concurrently, handled is the number of notifications attempted.
+func (d *Dispatcher) DispatchBatch(ctx context.Context) (handled int, err error) {
+	batch, err := d.store.Claim(ctx, d.now(), d.cfg.BatchSize)
+	if err != nil {
+		return
+	}
+	defer batch.Abort()
+
+	notifications := batch.Notifications()
+	outcomes := make([]Outcome, len(notifications))
+
+	var wg sync.WaitGroup
+	for i, notification := range notifications {
+		wg.Add(1)
+		go func(i int, notification Notification) {
+			defer wg.Done()
+			outcomes[i] = d.attempt(ctx, notification)
+		}(i, notification)
+	}
+	wg.Wait()
+
+	for i, notification := range notifications {
+		err = batch.Record(ctx, notification.Id, outcomes[i])
+		if err != nil {
+			return
+		}
+		handled++
+	}
+
+	err = batch.End()
+
+	return
+}
+
+// attempt renders and sends a notification once and decides what becomes of it.
+func (d *Dispatcher) attempt(ctx context.Context, notification Notification) Outcome {
+	log := slog.Default().With("notification_id", notification.Id, "kind", notification.Kind, "channel", notification.Channel)
+
+	outcome := Outcome{At: d.now(), Attempts: notification.Attempts + 1}
+	content, err := d.catalogue.Render(notification.Kind, notification.Locale, notification.Data)
+	if err != nil {
+		// rendering fails the same way every time, there is nothing to retry
+		outcome.Status = constant.NotificationStatusFailed
+		outcome.Error = err.Error()
+		metrics.NotificationSent(notification.Channel, "failed")
+		log.ErrorContext(ctx, "render notification", "error", err)
+		return outcome
+	}
+	outcome.Subject = content.Subject
+
+	sendErr := d.send(ctx, notification, content)
+	switch {
+	case sendErr == nil:
+		outcome.Status = constant.NotificationStatusSent
+		metrics.NotificationSent(notification.Channel, "sent")
+	case outcome.Attempts >= d.cfg.MaxAttempts:
+		outcome.Status = constant.NotificationStatusFailed
+		outcome.Error = sendErr.Error()
+		metrics.NotificationSent(notification.Channel, "failed")
+		log.WarnContext(ctx, "notification failed", "attempts", outcome.Attempts, "error", sendErr)
+	default:
+		outcome.Status = constant.NotificationStatusPending
+		outcome.Error = sendErr.Error()
+		outcome.NextAttemptAt = outcome.At.Add(d.backoff(outcome.Attempts))
+		metrics.NotificationSent(notification.Channel, "retry")
+		log.InfoContext(ctx, "notification failed, retrying", "attempt", outcome.Attempts, "retry_at", outcome.NextAttemptAt, "error", sendErr)
+	}
+
+	return outcome
+}
+
+// send hands the content to the channel of the notification, in the form that channel takes.
+func (d *Dispatcher) send(ctx context.Context, notification Notification, content Content) error {
+	channel, ok := d.channels[notification.Channel]
+	if !ok {
+		return fmt.Errorf("channel %s is not configured", notification.Channel)
+	}
+
+	msg := Message{To: notification.Recipient}
+	switch notification.Channel {
+	case constant.NotificationChannelEmail:
+		msg.Subject, msg.Text, msg.Html = content.Subject, content.Text, content.Html
+	case constant.NotificationChannelSms:
+		msg.Text = content.Short
+	default:
+		msg.Subject, msg.Text = content.Subject, content.Short
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+	defer cancel()
+
+	err := channel.Send(ctx, msg)
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("no answer within %s: %w", d.cfg.Timeout, err)
+	}
+	return err
+}
+
+// backoff is the delay before retrying a notification that failed attempts times.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	return poll.Backoff(d.cfg.BaseBackoff, d.cfg.MaxBackoff, attempts)
+}
@@ -0,0 +1,319 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/m/v2/config"
+	"example.com/m/v2/constant"
+)
+
+var testCfg = config.Notifications{
+	Timeout:     time.Second,
+	BatchSize:   10,
+	MaxAttempts: 3,
+	BaseBackoff: 30 * time.Second,
+	MaxBackoff:  time.Minute,
+}
+
+var testNow = time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+
+// testData has every field the templates use
+var testData = map[string]string{
+	constant.NotificationUserRegistered:      `{"email":"tes@tes.com"}`,
+	constant.NotificationLoanCreated:         `{"loan_id":7,"user_id":1,"amount":1250000,"terms":3}`,
+	constant.NotificationLoanApproved:        `{"loan_id":7,"amount":1250000,"approvals":2}`,
+	constant.NotificationLoanPaymentReceived: `{"loan_id":7,"term":2,"amount":416666.67}`,
+	constant.NotificationLoanRepaymentDue:    `{"loan_id":7,"term":2,"minimum_payment":416666.67,"due_date":"2026-10-21"}`,
+}
+
+func testCatalogue(t *testing.T) *Catalogue {
+	c, err := DefaultCatalogue("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func Test_DefaultCatalogue(t *testing.T) {
+	c := testCatalogue(t)
+
+	// every kind renders in every locale, with no field left empty
+	for _, locale := range constant.NotificationLocales {
+		for _, kind := range constant.NotificationKinds {
+			content, err := c.Render(kind, locale, json.RawMessage(testData[kind]))
+			if err != nil {
+				t.Errorf("render %s in %s: %v", kind, locale, err)
+				continue
+			}
+			if content.Subject == "" || content.Text == "" || content.Short == "" || !strings.Contains(content.Html, "</html>") {
+				t.Errorf("render %s in %s: incomplete content %+v", kind, locale, content)
+			}
+		}
+	}
+
+	if _, err := DefaultCatalogue("fr"); err == nil {
+		t.Error("want an error for a default locale missing from the catalogue")
+	}
+}
+
+func Test_Catalogue_Render(t *testing.T) {
+	c := testCatalogue(t)
+
+	tests := []struct {
+		name        string
+		kind        string
+		locale      string
+		data        string
+		wantSubject string
+		wantShort   string
+		wantHtml    string
+		wantErr     bool
+	}{
+		{
+			name:        "en",
+			kind:        constant.NotificationLoanPaymentReceived,
+			locale:      "en",
+			data:        testData[constant.NotificationLoanPaymentReceived],
+			wantSubject: "Payment received for loan #7",
+			wantShort:   "We received 416,666.67 for term 2 of loan #7. Thank you!",
+		},
+		{
+			name:        "id formats amounts its own way",
+			kind:        constant.NotificationLoanApproved,
+			locale:      "id",
+			data:        testData[constant.NotificationLoanApproved],
+			wantSubject: "Pinjaman #7 Anda disetujui",
+			wantShort:   "Pinjaman #7 Anda sebesar 1.250.000,00 telah disetujui.",
+		},
+		{
+			name:        "id formats dates its own way",
+			kind:        constant.NotificationLoanRepaymentDue,
+			locale:      "id",
+			data:        testData[constant.NotificationLoanRepaymentDue],
+			wantSubject: "Angsuran ke-2 pinjaman #7 segera jatuh tempo",
+			wantShort:   "Angsuran ke-2 pinjaman #7 jatuh tempo pada 21 Oktober 2026, bayar minimal 416.666,67 agar tidak dikenakan denda.",
+		},
+		{
+			name:        "unknown locale falls back to the default",
+			kind:        constant.NotificationUserRegistered,
+			locale:      "fr",
+			data:        testData[constant.NotificationUserRegistered],
+			wantSubject: "Welcome to Mini Aspire",
+			wantShort:   "Welcome to Mini Aspire, your account is ready.",
+		},
+		{
+			name:     "html is escaped",
+			kind:     constant.NotificationUserRegistered,
+			locale:   "en",
+			data:     `{"email":"<b>@tes.com"}`,
+			wantHtml: "&lt;b&gt;@tes.com",
+		},
+		{
+			name:    "unknown kind",
+			kind:    "loan.deleted",
+			locale:  "en",
+			data:    `{}`,
+			wantErr: true,
+		},
+		{
+			name:    "missing data",
+			kind:    constant.NotificationLoanCreated,
+			locale:  "en",
+			data:    `{"loan_id":7}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.Render(tt.kind, tt.locale, json.RawMessage(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render test failed. wantErr: %v, gotErr: %v", tt.wantErr, err)
+			}
+			if tt.wantSubject != "" && got.Subject != tt.wantSubject {
+				t.Errorf("want subject %q, got %q", tt.wantSubject, got.Subject)
+			}
+			if tt.wantShort != "" && got.Short != tt.wantShort {
+				t.Errorf("want short %q, got %q", tt.wantShort, got.Short)
+			}
+			if tt.wantHtml != "" && !strings.Contains(got.Html, tt.wantHtml) {
+				t.Errorf("want html containing %q, got %q", tt.wantHtml, got.Html)
+			}
+		})
+	}
+}
+
+func Test_amountFormatter(t *testing.T) {
+	for _, tt := range []struct {
+		locale string
+		v      any
+		want   string
+	}{
+		{"en", json.Number("1250000"), "1,250,000.00"},
+		{"en", json.Number("999.999"), "1,000.00"},
+		{"en", 0.5, "0.50"},
+		{"en", -1234.5, "-1,234.50"},
+		{"id", json.Number("416666.67"), "416.666,67"},
+		{"fr", 1000, "1,000.00"},
+	} {
+		got, err := amountFormatter(tt.locale)(tt.v)
+		if err != nil || got != tt.want {
+			t.Errorf("amount(%v) in %s = %q, %v, want %q", tt.v, tt.locale, got, err, tt.want)
+		}
+	}
+}
+
+func Test_dateFormatter(t *testing.T) {
+	for _, tt := range []struct {
+		locale  string
+		v       any
+		want    string
+		wantErr bool
+	}{
+		{locale: "en", v: "2026-10-21", want: "21 October 2026"},
+		{locale: "id", v: "2026-08-01", want: "1 Agustus 2026"},
+		{locale: "fr", v: "2026-12-31", want: "31 December 2026"},
+		{locale: "en", v: "21/10/2026", wantErr: true},
+		{locale: "en", v: json.Number("20261021"), wantErr: true},
+	} {
+		got, err := dateFormatter(tt.locale)(tt.v)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("date(%v) in %s = %q, %v, want %q", tt.v, tt.locale, got, err, tt.want)
+		}
+	}
+}
+
+type fakeBatch struct {
+	notifications []Notification
+	recorded      map[int64]Outcome
+	ended         bool
+}
+
+func (b *fakeBatch) Notifications() []Notification { return b.notifications }
+
+func (b *fakeBatch) Record(ctx context.Context, id int64, outcome Outcome) error {
+	b.recorded[id] = outcome
+	return nil
+}
+
+func (b *fakeBatch) End() error { b.ended = true; return nil }
+
+func (b *fakeBatch) Abort() {}
+
+type fakeStore struct {
+	batch *fakeBatch
+}
+
+func (s *fakeStore) Claim(ctx context.Context, now time.Time, limit int) (Batch, error) {
+	return s.batch, nil
+}
+
+func (s *fakeStore) RetryFailed(ctx context.Context, id int64, now time.Time) (int64, error) {
+	return 0, nil
+}
+
+// recordingChannel keeps the messages it is sent, or fails them all with err.
+type recordingChannel struct {
+	mu   sync.Mutex
+	sent []Message
+	err  error
+}
+
+func (c *recordingChannel) Send(ctx context.Context, msg Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.sent = append(c.sent, msg)
+	return nil
+}
+
+func (c *recordingChannel) Close() error { return nil }
+
+func notification(id int64, kind, channel, recipient string, attempts int) Notification {
+	return Notification{
+		Id:        id,
+		Kind:      kind,
+		Channel:   channel,
+		Locale:    "en",
+		Recipient: recipient,
+		Data:      json.RawMessage(testData[kind]),
+		Attempts:  attempts,
+	}
+}
+
+func Test_Dispatcher_DispatchBatch(t *testing.T) {
+	email := &recordingChannel{}
+	sms := &recordingChannel{err: errors.New("provider answered 503 Service Unavailable")}
+
+	batch := &fakeBatch{
+		notifications: []Notification{
+			notification(1, constant.NotificationLoanApproved, constant.NotificationChannelEmail, "tes@tes.com", 0),
+			notification(2, constant.NotificationLoanApproved, constant.NotificationChannelSms, "+6281234567890", 0),
+			notification(3, constant.NotificationLoanApproved, constant.NotificationChannelSms, "+6281234567890", 2),
+			notification(4, constant.NotificationLoanApproved, constant.NotificationChannelPush, "device-token", 0),
+			{Id: 5, Kind: "loan.deleted", Channel: constant.NotificationChannelEmail, Locale: "en", Data: json.RawMessage(`{}`)},
+		},
+		recorded: map[int64]Outcome{},
+	}
+
+	d := NewDispatcher(&fakeStore{batch: batch}, testCatalogue(t), map[string]Channel{
+		constant.NotificationChannelEmail: email,
+		constant.NotificationChannelSms:   sms,
+	}, testCfg)
+	d.now = func() time.Time { return testNow }
+
+	handled, err := d.DispatchBatch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if handled != 5 || !batch.ended {
+		t.Fatalf("want 5 notifications handled in an ended batch, got %d (ended %v)", handled, batch.ended)
+	}
+
+	for id, want := range map[int64]Outcome{
+		1: {Status: constant.NotificationStatusSent, Attempts: 1, Subject: "Your loan #7 is approved"},
+		// a first failure waits BaseBackoff
+		2: {Status: constant.NotificationStatusPending, Attempts: 1, NextAttemptAt: testNow.Add(30 * time.Second), Subject: "Your loan #7 is approved", Error: "provider answered 503 Service Unavailable"},
+		// the last attempt fails the notification for good
+		3: {Status: constant.NotificationStatusFailed, Attempts: 3, Subject: "Your loan #7 is approved", Error: "provider answered 503 Service Unavailable"},
+		// no push channel configured
+		4: {Status: constant.NotificationStatusPending, Attempts: 1, NextAttemptAt: testNow.Add(30 * time.Second), Subject: "Your loan #7 is approved", Error: "channel push is not configured"},
+		// what can't be rendered is not retried
+		5: {Status: constant.NotificationStatusFailed, Attempts: 1, Error: `unknown notification kind "loan.deleted"`},
+	} {
+		got := batch.recorded[id]
+		if got.Status != want.Status || got.Attempts != want.Attempts || !got.NextAttemptAt.Equal(want.NextAttemptAt) ||
+			got.Subject != want.Subject || got.Error != want.Error {
+			t.Errorf("notification %d: want %+v, got %+v", id, want, got)
+		}
+	}
+
+	if len(email.sent) != 1 {
+		t.Fatalf("want 1 email sent, got %d", len(email.sent))
+	}
+	msg := email.sent[0]
+	if msg.To != "tes@tes.com" || msg.Subject != "Your loan #7 is approved" || !strings.Contains(msg.Text, "1,250,000.00") || msg.Html == "" {
+		t.Errorf("unexpected email %+v", msg)
+	}
+}
+
+func Test_Dispatcher_backoff(t *testing.T) {
+	d := NewDispatcher(&fakeStore{}, nil, nil, testCfg)
+	for attempts, want := range map[int]time.Duration{
+		1: 30 * time.Second,
+		2: time.Minute,
+		9: time.Minute,
+	} {
+		if got := d.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
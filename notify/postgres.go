@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"example.com/m/v2/constant"
+	"example.com/m/v2/tracing"
+)
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore dispatches the notifications table, any number of dispatchers can share it.
+func NewPostgresStore(db *sql.DB) Store {
+	return &postgresStore{db: db}
+}
+
+// Claim locks the batch in a transaction kept open until the batch ends, SKIP LOCKED lets concurrent dispatchers
+// pass over the notifications another one holds.
+func (s *postgresStore) Claim(ctx context.Context, now time.Time, limit int) (_ Batch, err error) {
+	ctx, span := tracing.StartDb(ctx, "notify.Claim", "SELECT", "notifications")
+	defer tracing.End(span, &err)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id, kind, channel, locale, recipient, data, attempts
+		FROM
+			notifications
+		WHERE
+			status = 'PENDING' AND next_attempt_at <= $1
+		ORDER BY
+			next_attempt_at, id
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`, now, limit)
+	if err != nil {
+		tx.Rollback()
+		return
+	}
+	defer rows.Close()
+
+	batch := &postgresBatch{tx: tx}
+	for rows.Next() {
+		var notification Notification
+		var data []byte
+		err = rows.Scan(&notification.Id, &notification.Kind, &notification.Channel, &notification.Locale,
+			&notification.Recipient, &data, &notification.Attempts)
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		notification.Data = data
+		batch.notifications = append(batch.notifications, notification)
+	}
+	if err = rows.Err(); err != nil {
+		tx.Rollback()
+		return
+	}
+
+	return batch, nil
+}
+
+func (s *postgresStore) RetryFailed(ctx context.Context, id int64, now time.Time) (retried int64, err error) {
+	ctx, span := tracing.StartDb(ctx, "notify.RetryFailed", "UPDATE", "notifications")
+	defer tracing.End(span, &err)
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE notifications SET status = 'PENDING', attempts = 0, next_attempt_at = $2
+		WHERE status = 'FAILED' AND ($1 = 0 OR id = $1)
+	`, id, now)
+	if err != nil {
+		return
+	}
+
+	return res.RowsAffected()
+}
+
+type postgresBatch struct {
+	tx            *sql.Tx
+	notifications []Notification
+}
+
+func (b *postgresBatch) Notifications() []Notification {
+	return b.notifications
+}
+
+// Record moves the notification to the state the attempt left it in.
+func (b *postgresBatch) Record(ctx context.Context, id int64, outcome Outcome) (err error) {
+	var nextAttemptAt, sentAt *time.Time
+	switch outcome.Status {
+	case constant.NotificationStatusPending:
+		nextAttemptAt = &outcome.NextAttemptAt
+	case constant.NotificationStatusSent:
+		sentAt = &outcome.At
+	}
+
+	_, err = b.tx.ExecContext(ctx, `
+		UPDATE
+			notifications
+		SET
+			status = $2, attempts = $3, next_attempt_at = COALESCE($4, next_attempt_at),
+			subject = COALESCE(NULLIF($5,''), subject), last_error = NULLIF($6,''), sent_at = $7
+		WHERE
+			id = $1
+	`, id, outcome.Status, outcome.Attempts, nextAttemptAt, outcome.Subject, outcome.Error, sentAt)
+
+	return
+}
+
+func (b *postgresBatch) End() error {
+	return b.tx.Commit()
+}
+
+func (b *postgresBatch) Abort() {
+	b.tx.Rollback()
+}
@@ -0,0 +1,150 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+type smtpChannel struct {
+	host     string
+	addr     string
+	username string
+	password string
+	from     string
+	timeout  time.Duration
+}
+
+// NewSmtpChannel mails messages through an SMTP relay. STARTTLS is used when the server offers it and the username
+// authenticates with PLAIN when set, which net/smtp only allows over TLS or to localhost.
+func NewSmtpChannel(host string, port int, username, password, from string, timeout time.Duration) Channel {
+	return &smtpChannel{
+		host:     host,
+		addr:     net.JoinHostPort(host, strconv.Itoa(port)),
+		username: username,
+		password: password,
+		from:     from,
+		timeout:  timeout,
+	}
+}
+
+func (c *smtpChannel) Send(ctx context.Context, msg Message) (err error) {
+	from, err := mail.ParseAddress(c.from)
+	if err != nil {
+		return
+	}
+	body, err := buildMail(c.from, msg, time.Now())
+	if err != nil {
+		return
+	}
+
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return
+	}
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, c.host)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: c.host})
+		if err != nil {
+			return
+		}
+	}
+	if c.username != "" {
+		err = client.Auth(smtp.PlainAuth("", c.username, c.password, c.host))
+		if err != nil {
+			return
+		}
+	}
+
+	err = client.Mail(from.Address)
+	if err != nil {
+		return
+	}
+	err = client.Rcpt(msg.To)
+	if err != nil {
+		return
+	}
+	w, err := client.Data()
+	if err != nil {
+		return
+	}
+	_, err = w.Write(body)
+	if err != nil {
+		return
+	}
+	err = w.Close()
+	if err != nil {
+		return
+	}
+
+	return client.Quit()
+}
+
+func (c *smtpChannel) Close() error {
+	return nil
+}
+
+// buildMail is msg as a multipart/alternative mail with a quoted-printable text part, and an html part when msg
+// has one.
+func buildMail(from string, msg Message, date time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	parts := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", parts.Boundary())
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.Html},
+	} {
+		if part.body == "" {
+			continue
+		}
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		_, err = qp.Write([]byte(part.body))
+		if err != nil {
+			return nil, err
+		}
+		err = qp.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := parts.Close()
+	return buf.Bytes(), err
+}
@@ -0,0 +1,103 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"example.com/m/v2/config"
+	"example.com/m/v2/metrics"
+	"example.com/m/v2/model"
+	"example.com/m/v2/poll"
+)
+
+// Store hands out the outbox events to publish.
+type Store interface {
+	// Claim starts a batch of at most limit events due at now. Only the oldest pending event of every aggregate is
+	// claimed, so the events of a loan are published in the order they were written.
+	Claim(ctx context.Context, now time.Time, limit int) (Batch, error)
+	// RequeueDead puts dead-lettered events back in the outbox to be published at now, id 0 requeues all of them.
+	RequeueDead(ctx context.Context, id int64, now time.Time) (requeued int64, err error)
+}
+
+// Batch holds claimed events, see poll.Batch.
+type Batch interface {
+	poll.Batch
+	Events() []model.OutboxEvent
+	Published(ctx context.Context, id int64, at time.Time) error
+	Retry(ctx context.Context, id int64, attempts int, next time.Time, lastError string) error
+	Dead(ctx context.Context, id int64, attempts int, lastError string) error
+}
+
+// Relay publishes outbox events to a sink. Delivery is at least once: an event is marked published after the sink
+// accepted it, a crash in between publishes it again. A failing event is retried with an exponential backoff and
+// holds back the later events of its loan, after MaxAttempts it is dead-lettered and they go on.
+type Relay struct {
+	store Store
+	sink  Sink
+	cfg   config.Outbox
+	now   func() time.Time
+}
+
+func NewRelay(store Store, sink Sink, cfg config.Outbox) *Relay {
+	return &Relay{
+		store: store,
+		sink:  sink,
+		cfg:   cfg,
+		now:   time.Now,
+	}
+}
+
+// Run relays batches of events every PollInterval until ctx is done, see poll.Run.
+func (r *Relay) Run(ctx context.Context) error {
+	poll.Run(ctx, "outbox relay", r.cfg.PollInterval, r.RelayBatch, "sink", r.sink.Name())
+	return nil
+}
+
+// RelayBatch publishes one batch of events, handled is the number of events published, retried or dead-lettered.
+func (r *Relay) RelayBatch(ctx context.Context) (handled int, err error) {
+	batch, err := r.store.Claim(ctx, r.now(), r.cfg.BatchSize)
+	if err != nil {
+		return
+	}
+	defer batch.Abort()
+
+	for _, event := range batch.Events() {
+		err = r.publish(ctx, batch, event)
+		if err != nil {
+			return
+		}
+		handled++
+	}
+
+	err = batch.End()
+
+	return
+}
+
+func (r *Relay) publish(ctx context.Context, batch Batch, event model.OutboxEvent) error {
+	log := slog.Default().With("event_id", event.Id, "event_type", event.Type, "aggregate_id", event.AggregateId)
+
+	pubErr := r.sink.Publish(ctx, model.NewEventMessage(event))
+	if pubErr == nil {
+		metrics.EventPublished(event.Type, "published")
+		return batch.Published(ctx, event.Id, r.now())
+	}
+
+	attempts := event.Attempts + 1
+	if attempts >= r.cfg.MaxAttempts {
+		metrics.EventPublished(event.Type, "dead")
+		log.ErrorContext(ctx, "outbox event dead-lettered", "attempts", attempts, "error", pubErr)
+		return batch.Dead(ctx, event.Id, attempts, pubErr.Error())
+	}
+
+	next := r.now().Add(r.backoff(attempts))
+	metrics.EventPublished(event.Type, "retry")
+	log.WarnContext(ctx, "publish outbox event failed, retrying", "attempt", attempts, "retry_at", next, "error", pubErr)
+	return batch.Retry(ctx, event.Id, attempts, next, pubErr.Error())
+}
+
+// backoff is the delay before retrying an event that failed attempts times.
+func (r *Relay) backoff(attempts int) time.Duration {
+	return poll.Backoff(r.cfg.BaseBackoff, r.cfg.MaxBackoff, attempts)
+}
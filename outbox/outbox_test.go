@@ -0,0 +1,204 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/m/v2/config"
+	"example.com/m/v2/model"
+)
+
+var testCfg = config.Outbox{
+	BatchSize:   10,
+	MaxAttempts: 3,
+	BaseBackoff: 5 * time.Second,
+	MaxBackoff:  15 * time.Second,
+}
+
+var testNow = time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+
+// outcome is what a batch recorded for an event
+type outcome struct {
+	status    string
+	attempts  int
+	next      time.Time
+	lastError string
+}
+
+type fakeBatch struct {
+	events   []model.OutboxEvent
+	outcomes map[int64]outcome
+	ended    bool
+}
+
+func (b *fakeBatch) Events() []model.OutboxEvent { return b.events }
+
+func (b *fakeBatch) Published(ctx context.Context, id int64, at time.Time) error {
+	b.outcomes[id] = outcome{status: "PUBLISHED"}
+	return nil
+}
+
+func (b *fakeBatch) Retry(ctx context.Context, id int64, attempts int, next time.Time, lastError string) error {
+	b.outcomes[id] = outcome{status: "PENDING", attempts: attempts, next: next, lastError: lastError}
+	return nil
+}
+
+func (b *fakeBatch) Dead(ctx context.Context, id int64, attempts int, lastError string) error {
+	b.outcomes[id] = outcome{status: "DEAD", attempts: attempts, lastError: lastError}
+	return nil
+}
+
+func (b *fakeBatch) End() error { b.ended = true; return nil }
+
+func (b *fakeBatch) Abort() {}
+
+type fakeStore struct {
+	batch *fakeBatch
+}
+
+func (s *fakeStore) Claim(ctx context.Context, now time.Time, limit int) (Batch, error) {
+	return s.batch, nil
+}
+
+func (s *fakeStore) RequeueDead(ctx context.Context, id int64, now time.Time) (int64, error) {
+	return 0, nil
+}
+
+// failingSink fails the events listed in fail and keeps the others
+type failingSink struct {
+	fail      map[int64]bool
+	published []int64
+}
+
+func (s *failingSink) Name() string { return "tes" }
+
+func (s *failingSink) Publish(ctx context.Context, msg model.EventMessage) error {
+	if s.fail[msg.Id] {
+		return errors.New("err tes")
+	}
+	s.published = append(s.published, msg.Id)
+	return nil
+}
+
+func (s *failingSink) Close() error { return nil }
+
+func Test_Relay_RelayBatch(t *testing.T) {
+	batch := &fakeBatch{
+		events: []model.OutboxEvent{
+			{Id: 1, AggregateType: "loan", AggregateId: 7, Type: "loan.created", Payload: json.RawMessage(`{"loan_id":7}`)},
+			{Id: 2, AggregateType: "loan", AggregateId: 8, Type: "loan.created", Payload: json.RawMessage(`{"loan_id":8}`)},
+			{Id: 3, AggregateType: "loan", AggregateId: 9, Type: "loan.paid", Payload: json.RawMessage(`{"loan_id":9}`), Attempts: 2},
+		},
+		outcomes: map[int64]outcome{},
+	}
+	sink := &failingSink{fail: map[int64]bool{2: true, 3: true}}
+
+	r := NewRelay(&fakeStore{batch: batch}, sink, testCfg)
+	r.now = func() time.Time { return testNow }
+
+	handled, err := r.RelayBatch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if handled != 3 || !batch.ended {
+		t.Fatalf("want 3 events handled in an ended batch, got %d (ended %v)", handled, batch.ended)
+	}
+	if len(sink.published) != 1 || sink.published[0] != 1 {
+		t.Errorf("want event 1 published, got %v", sink.published)
+	}
+
+	want := map[int64]outcome{
+		1: {status: "PUBLISHED"},
+		// a first failure waits BaseBackoff
+		2: {status: "PENDING", attempts: 1, next: testNow.Add(5 * time.Second), lastError: "err tes"},
+		// the last attempt dead-letters the event
+		3: {status: "DEAD", attempts: 3, lastError: "err tes"},
+	}
+	for id, w := range want {
+		if got := batch.outcomes[id]; got != w {
+			t.Errorf("event %d: want %+v, got %+v", id, w, got)
+		}
+	}
+}
+
+func Test_Relay_backoff(t *testing.T) {
+	r := NewRelay(&fakeStore{}, &failingSink{}, testCfg)
+	for attempts, want := range map[int]time.Duration{
+		1: 5 * time.Second,
+		2: 10 * time.Second,
+		3: 15 * time.Second,
+		9: 15 * time.Second,
+	} {
+		if got := r.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+var testMsg = model.EventMessage{
+	Id:            42,
+	Type:          "loan.approved",
+	AggregateType: "loan",
+	AggregateId:   7,
+	OccurredAt:    testNow,
+	Data:          json.RawMessage(`{"loan_id":7,"amount":10000,"approvals":1}`),
+}
+
+const testMsgJSON = `{"id":42,"type":"loan.approved","aggregate_type":"loan","aggregate_id":7,"occurred_at":"2026-10-19T10:00:00Z","data":{"loan_id":7,"amount":10000,"approvals":1}}`
+
+func Test_webhookSink_Publish(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	status := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sink := NewWebhookSink(srv.URL, srv.Client())
+	defer sink.Close()
+
+	if err := sink.Publish(context.Background(), testMsg); err != nil {
+		t.Fatal(err)
+	}
+	if got.Method != http.MethodPost || got.Header.Get("X-Event-Id") != "42" || got.Header.Get("X-Event-Type") != "loan.approved" {
+		t.Errorf("unexpected request %s %v", got.Method, got.Header)
+	}
+	if string(body) != testMsgJSON {
+		t.Errorf("want body %s, got %s", testMsgJSON, body)
+	}
+
+	status = http.StatusBadGateway
+	err := sink.Publish(context.Background(), testMsg)
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("want a 502 error, got %v", err)
+	}
+}
+
+func Test_writerSink_Publish(t *testing.T) {
+	var buf bytes.Buffer
+	sink, err := NewSink(config.Outbox{Sink: "stdout"}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink.Publish(context.Background(), testMsg)
+	sink.Publish(context.Background(), testMsg)
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := testMsgJSON + "\n" + testMsgJSON + "\n"; buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
@@ -0,0 +1,127 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"example.com/m/v2/model"
+	"example.com/m/v2/tracing"
+)
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore relays the outbox table, any number of relays can share it.
+func NewPostgresStore(db *sql.DB) Store {
+	return &postgresStore{db: db}
+}
+
+// Claim locks the batch in a transaction kept open until the batch ends. SKIP LOCKED lets concurrent relays pass
+// over the events another one holds, and an event only qualifies once no older event of its aggregate is pending,
+// locked ones included, so two relays never publish the events of a loan out of order.
+func (s *postgresStore) Claim(ctx context.Context, now time.Time, limit int) (_ Batch, err error) {
+	ctx, span := tracing.StartDb(ctx, "outbox.Claim", "SELECT", "outbox")
+	defer tracing.End(span, &err)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			o.id, o.aggregate_type, o.aggregate_id, o.event_type, o.payload, o.attempts, o.created_at
+		FROM
+			outbox o
+		WHERE
+			o.status = 'PENDING'
+			AND o.next_attempt_at <= $1
+			AND NOT EXISTS (
+				SELECT 1 FROM outbox p
+				WHERE p.aggregate_type = o.aggregate_type AND p.aggregate_id = o.aggregate_id AND p.status = 'PENDING' AND p.id < o.id
+			)
+		ORDER BY
+			o.id ASC
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`, now, limit)
+	if err != nil {
+		tx.Rollback()
+		return
+	}
+	defer rows.Close()
+
+	batch := &postgresBatch{tx: tx}
+	for rows.Next() {
+		var event model.OutboxEvent
+		var payload []byte
+		err = rows.Scan(&event.Id, &event.AggregateType, &event.AggregateId, &event.Type, &payload, &event.Attempts, &event.CreatedAt)
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		event.Payload = payload
+		batch.events = append(batch.events, event)
+	}
+	if err = rows.Err(); err != nil {
+		tx.Rollback()
+		return
+	}
+
+	return batch, nil
+}
+
+func (s *postgresStore) RequeueDead(ctx context.Context, id int64, now time.Time) (requeued int64, err error) {
+	ctx, span := tracing.StartDb(ctx, "outbox.RequeueDead", "UPDATE", "outbox")
+	defer tracing.End(span, &err)
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE outbox SET status = 'PENDING', attempts = 0, next_attempt_at = $2
+		WHERE status = 'DEAD' AND ($1 = 0 OR id = $1)
+	`, id, now)
+	if err != nil {
+		return
+	}
+
+	return res.RowsAffected()
+}
+
+type postgresBatch struct {
+	tx     *sql.Tx
+	events []model.OutboxEvent
+}
+
+func (b *postgresBatch) Events() []model.OutboxEvent {
+	return b.events
+}
+
+func (b *postgresBatch) Published(ctx context.Context, id int64, at time.Time) (err error) {
+	_, err = b.tx.ExecContext(ctx, `
+		UPDATE outbox SET status = 'PUBLISHED', attempts = attempts + 1, last_error = NULL, published_at = $2 WHERE id = $1
+	`, id, at)
+	return
+}
+
+func (b *postgresBatch) Retry(ctx context.Context, id int64, attempts int, next time.Time, lastError string) (err error) {
+	_, err = b.tx.ExecContext(ctx, `
+		UPDATE outbox SET attempts = $2, next_attempt_at = $3, last_error = $4 WHERE id = $1
+	`, id, attempts, next, lastError)
+	return
+}
+
+func (b *postgresBatch) Dead(ctx context.Context, id int64, attempts int, lastError string) (err error) {
+	_, err = b.tx.ExecContext(ctx, `
+		UPDATE outbox SET status = 'DEAD', attempts = $2, last_error = $3 WHERE id = $1
+	`, id, attempts, lastError)
+	return
+}
+
+func (b *postgresBatch) End() error {
+	return b.tx.Commit()
+}
+
+func (b *postgresBatch) Abort() {
+	b.tx.Rollback()
+}
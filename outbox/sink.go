@@ -0,0 +1,128 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"example.com/m/v2/config"
+	"example.com/m/v2/model"
+)
+
+// Sink publishes events to a downstream system, implementations must be safe for concurrent use.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, msg model.EventMessage) error
+	Close() error
+}
+
+// NewSink builds the Sink selected by cfg.Sink, stdout is where the stdout sink writes.
+func NewSink(cfg config.Outbox, stdout io.Writer) (Sink, error) {
+	switch cfg.Sink {
+	case "webhook":
+		return NewWebhookSink(cfg.WebhookUrl, &http.Client{Timeout: cfg.WebhookTimeout}), nil
+	case "file":
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("open outbox file: %w", err)
+		}
+		return NewWriterSink("file", file), nil
+	case "stdout":
+		// hidden behind a struct, stdout must not be closed with the sink
+		return NewWriterSink("stdout", struct{ io.Writer }{stdout}), nil
+	}
+	return nil, fmt.Errorf("unknown outbox sink %q", cfg.Sink)
+}
+
+type writerSink struct {
+	mu   sync.Mutex
+	name string
+	w    io.Writer
+}
+
+// NewWriterSink writes every event to w as a JSON line, closing w on Close when it is an io.Closer.
+func NewWriterSink(name string, w io.Writer) Sink {
+	return &writerSink{
+		name: name,
+		w:    w,
+	}
+}
+
+func (s *writerSink) Name() string {
+	return s.name
+}
+
+func (s *writerSink) Publish(ctx context.Context, msg model.EventMessage) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+func (s *writerSink) Close() error {
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink POSTs every event as JSON to url, any status but 2xx fails the delivery. X-Event-Id lets the
+// receiver drop the events it already got.
+func NewWebhookSink(url string, client *http.Client) Sink {
+	return &webhookSink{
+		url:    url,
+		client: client,
+	}
+}
+
+func (s *webhookSink) Name() string {
+	return "webhook"
+}
+
+func (s *webhookSink) Publish(ctx context.Context, msg model.EventMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Id", fmt.Sprint(msg.Id))
+	req.Header.Set("X-Event-Type", msg.Type)
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", res.Status)
+	}
+	return nil
+}
+
+func (s *webhookSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,125 @@
+// Package payment takes loan repayments through a payment gateway, settlements come back as signed webhooks.
+package payment
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"example.com/m/v2/config"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/model"
+)
+
+// PaymentGateway creates payment intents, the customer then pays the returned virtual account and the gateway
+// calls the payment webhook once the money settled. Implementations must be safe for concurrent use.
+type PaymentGateway interface {
+	Name() string
+	CreateIntent(ctx context.Context, req model.PaymentIntentRequest) (model.PaymentIntentResult, error)
+}
+
+// ErrIntentNotFound is returned by Mock.Settle for references it never issued.
+var ErrIntentNotFound = errors.New("payment intent not found at the gateway")
+
+// New builds the PaymentGateway selected by cfg.Driver.
+func New(cfg config.Payment) (PaymentGateway, error) {
+	switch cfg.Driver {
+	case "mock":
+		return NewMock([]byte(cfg.WebhookSecret)), nil
+	}
+	return nil, fmt.Errorf("unknown payment driver %q", cfg.Driver)
+}
+
+// Mock is an in-memory gateway for local development and tests. It remembers the intents it created and builds
+// their signed settlement callbacks with Settle, the way the real gateway would call the webhook.
+type Mock struct {
+	mu      sync.Mutex
+	secret  []byte
+	now     func() time.Time
+	intents map[string]model.PaymentIntentRequest
+}
+
+func NewMock(secret []byte) *Mock {
+	return &Mock{
+		secret:  secret,
+		now:     time.Now,
+		intents: make(map[string]model.PaymentIntentRequest),
+	}
+}
+
+func (m *Mock) Name() string {
+	return "mock"
+}
+
+func (m *Mock) CreateIntent(ctx context.Context, req model.PaymentIntentRequest) (model.PaymentIntentResult, error) {
+	reference, err := randomHex(8)
+	if err != nil {
+		return model.PaymentIntentResult{}, err
+	}
+	account, err := rand.Int(rand.Reader, big.NewInt(1e10))
+	if err != nil {
+		return model.PaymentIntentResult{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := model.PaymentIntentResult{
+		Reference:      "mock_" + reference,
+		VirtualAccount: fmt.Sprintf("8808%010d", account),
+	}
+	m.intents[result.Reference] = req
+
+	return result, nil
+}
+
+// Settle builds the webhook callback settling an intent with status, SUCCEEDED or FAILED, for the full amount.
+// The returned header carries a fresh nonce and the signature.
+func (m *Mock) Settle(reference, status string) (body []byte, header http.Header, err error) {
+	m.mu.Lock()
+	req, ok := m.intents[reference]
+	m.mu.Unlock()
+	if !ok {
+		return nil, nil, ErrIntentNotFound
+	}
+
+	body, err = json.Marshal(model.PaymentWebhookReq{
+		Reference: reference,
+		Status:    status,
+		Amount:    req.Amount,
+	})
+	if err != nil {
+		return
+	}
+
+	nonce, err := randomHex(16)
+	if err != nil {
+		return
+	}
+	timestamp := m.now().Unix()
+
+	header = http.Header{}
+	header.Set(constant.HttpHeaderContentType, constant.HttpHeaderAppJson)
+	header.Set(constant.HttpHeaderWebhookTimestamp, strconv.FormatInt(timestamp, 10))
+	header.Set(constant.HttpHeaderWebhookNonce, nonce)
+	header.Set(constant.HttpHeaderWebhookSignature, Sign(m.secret, timestamp, nonce, body))
+
+	return
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
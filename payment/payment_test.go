@@ -0,0 +1,145 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/m/v2/config"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/model"
+)
+
+func Test_New(t *testing.T) {
+	_, err := New(config.Payment{Driver: "mock"})
+	if err != nil {
+		t.Errorf("New test failed. gotErr: %v", err)
+	}
+
+	_, err = New(config.Payment{Driver: "stripe"})
+	if err == nil {
+		t.Errorf("New test failed. want an error for an unknown driver")
+	}
+}
+
+func Test_MockSettle(t *testing.T) {
+	secret := []byte("secret")
+	m := NewMock(secret)
+
+	intent, err := m.CreateIntent(context.Background(), model.PaymentIntentRequest{LoanId: 1, UserId: 2, Term: 1, Amount: 100})
+	if err != nil {
+		t.Fatalf("CreateIntent test failed. gotErr: %v", err)
+	}
+	if !strings.HasPrefix(intent.Reference, "mock_") || len(intent.VirtualAccount) != 14 {
+		t.Errorf("CreateIntent test failed. got: %+v", intent)
+	}
+
+	body, header, err := m.Settle(intent.Reference, constant.PaymentIntentStatusSucceeded)
+	if err != nil {
+		t.Fatalf("Settle test failed. gotErr: %v", err)
+	}
+
+	var got model.PaymentWebhookReq
+	json.Unmarshal(body, &got)
+	want := model.PaymentWebhookReq{Reference: intent.Reference, Status: constant.PaymentIntentStatusSucceeded, Amount: 100}
+	if got != want {
+		t.Errorf("Settle test failed. want: %+v, got: %+v", want, got)
+	}
+
+	sig := model.WebhookSignature{
+		Timestamp: header.Get(constant.HttpHeaderWebhookTimestamp),
+		Nonce:     header.Get(constant.HttpHeaderWebhookNonce),
+		Signature: header.Get(constant.HttpHeaderWebhookSignature),
+	}
+	if err = Verify(secret, sig, body, time.Now(), time.Minute); err != nil {
+		t.Errorf("Settle test failed. the callback does not verify: %v", err)
+	}
+
+	_, _, err = m.Settle("mock_unknown", constant.PaymentIntentStatusSucceeded)
+	if !errors.Is(err, ErrIntentNotFound) {
+		t.Errorf("Settle test failed. wantErr: %v, gotErr: %v", ErrIntentNotFound, err)
+	}
+}
+
+func Test_Verify(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"reference":"mock_1","status":"SUCCEEDED","amount":100}`)
+	signed := func(at time.Time, nonce string) model.WebhookSignature {
+		return model.WebhookSignature{
+			Timestamp: strconv.FormatInt(at.Unix(), 10),
+			Nonce:     nonce,
+			Signature: Sign(secret, at.Unix(), nonce, body),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		sig     model.WebhookSignature
+		body    []byte
+		wantErr error
+	}{
+		{
+			name: "valid",
+			sig:  signed(now.Add(-time.Minute), "n1"),
+			body: body,
+		},
+		{
+			name:    "tampered body",
+			sig:     signed(now, "n1"),
+			body:    []byte(`{"reference":"mock_1","status":"SUCCEEDED","amount":1000}`),
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "nonce not covered by the signature",
+			sig: func() model.WebhookSignature {
+				s := signed(now, "n1")
+				s.Nonce = "n2"
+				return s
+			}(),
+			body:    body,
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "missing nonce",
+			sig:     signed(now, ""),
+			body:    body,
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "timestamp not a number",
+			sig: func() model.WebhookSignature {
+				s := signed(now, "n1")
+				s.Timestamp = "yesterday"
+				return s
+			}(),
+			body:    body,
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "replayed after the tolerance",
+			sig:     signed(now.Add(-6*time.Minute), "n1"),
+			body:    body,
+			wantErr: ErrStaleTimestamp,
+		},
+		{
+			name:    "timestamp in the future",
+			sig:     signed(now.Add(6*time.Minute), "n1"),
+			body:    body,
+			wantErr: ErrStaleTimestamp,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(secret, tt.sig, tt.body, now, 5*time.Minute)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify test failed. wantErr: %v, gotErr: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
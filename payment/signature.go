@@ -0,0 +1,49 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"example.com/m/v2/model"
+)
+
+var (
+	ErrInvalidSignature = errors.New("webhook signature does not match")
+	ErrStaleTimestamp   = errors.New("webhook timestamp outside the tolerance")
+)
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<nonce>.<body>" keyed with secret.
+func Sign(secret []byte, timestamp int64, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write([]byte(nonce))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a webhook body and that its timestamp, unix seconds, is within tolerance of now.
+// Replays inside the tolerance are left to the caller, which must accept every nonce only once.
+func Verify(secret []byte, sig model.WebhookSignature, body []byte, now time.Time, tolerance time.Duration) error {
+	timestamp, err := strconv.ParseInt(sig.Timestamp, 10, 64)
+	if err != nil || sig.Nonce == "" {
+		return ErrInvalidSignature
+	}
+
+	want := Sign(secret, timestamp, sig.Nonce, body)
+	if !hmac.Equal([]byte(want), []byte(sig.Signature)) {
+		return ErrInvalidSignature
+	}
+
+	age := now.Sub(time.Unix(timestamp, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrStaleTimestamp
+	}
+
+	return nil
+}
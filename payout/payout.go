@@ -0,0 +1,113 @@
+// Package payout sends disbursed loans to borrowers through a payout provider.
+package payout
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"example.com/m/v2/config"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/model"
+)
+
+// PayoutProvider submits payouts. Submitting a request again with the same idempotency key must not pay twice, it
+// returns the current state of the first submission instead. Implementations must be safe for concurrent use.
+type PayoutProvider interface {
+	Name() string
+	Payout(ctx context.Context, req model.PayoutRequest) (model.PayoutResult, error)
+}
+
+// ErrIdempotencyMismatch is returned when an idempotency key is reused for a different request.
+var ErrIdempotencyMismatch = errors.New("idempotency key reused with a different request")
+
+// New builds the PayoutProvider selected by cfg.Driver.
+func New(cfg config.Payout) (PayoutProvider, error) {
+	switch cfg.Driver {
+	case "fake":
+		return NewFake(cfg.FakeOutcome, cfg.FakeDelay), nil
+	}
+	return nil, fmt.Errorf("unknown payout driver %q", cfg.Driver)
+}
+
+// Fake is an in-memory provider for local development and tests. Depending on its outcome payouts succeed,
+// are declined, or stay pending for delay before they succeed.
+type Fake struct {
+	mu      sync.Mutex
+	outcome string
+	delay   time.Duration
+	now     func() time.Time
+	payouts map[string]fakePayout
+}
+
+type fakePayout struct {
+	req      model.PayoutRequest
+	result   model.PayoutResult
+	settleAt time.Time
+}
+
+func NewFake(outcome string, delay time.Duration) *Fake {
+	return &Fake{
+		outcome: outcome,
+		delay:   delay,
+		now:     time.Now,
+		payouts: make(map[string]fakePayout),
+	}
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) Payout(ctx context.Context, req model.PayoutRequest) (model.PayoutResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	if p, ok := f.payouts[req.IdempotencyKey]; ok {
+		if p.req != req {
+			return model.PayoutResult{}, ErrIdempotencyMismatch
+		}
+		if p.result.Status == constant.DisbursementStatusPending && !now.Before(p.settleAt) {
+			p.result.Status = constant.DisbursementStatusSucceeded
+			f.payouts[req.IdempotencyKey] = p
+		}
+		return p.result, nil
+	}
+
+	reference, err := fakeReference()
+	if err != nil {
+		return model.PayoutResult{}, err
+	}
+
+	p := fakePayout{
+		req:    req,
+		result: model.PayoutResult{Reference: reference},
+	}
+	switch f.outcome {
+	case "failure":
+		p.result.Status = constant.DisbursementStatusFailed
+		p.result.FailureReason = "declined by the fake provider"
+	case "delay":
+		p.result.Status = constant.DisbursementStatusPending
+		p.settleAt = now.Add(f.delay)
+	default:
+		p.result.Status = constant.DisbursementStatusSucceeded
+	}
+	f.payouts[req.IdempotencyKey] = p
+
+	return p.result, nil
+}
+
+func fakeReference() (string, error) {
+	b := make([]byte, 8)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return "fake_" + hex.EncodeToString(b), nil
+}
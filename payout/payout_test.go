@@ -0,0 +1,105 @@
+package payout
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"example.com/m/v2/config"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/model"
+)
+
+func Test_New(t *testing.T) {
+	_, err := New(config.Payout{Driver: "fake", FakeOutcome: "success"})
+	if err != nil {
+		t.Errorf("New test failed. gotErr: %v", err)
+	}
+
+	_, err = New(config.Payout{Driver: "bank"})
+	if err == nil {
+		t.Errorf("New test failed. want an error for an unknown driver")
+	}
+}
+
+func Test_FakePayout(t *testing.T) {
+	req := model.PayoutRequest{IdempotencyKey: "key", LoanId: 1, UserId: 2, Amount: 100}
+
+	tests := []struct {
+		name    string
+		outcome string
+		// after is how long after the first submission the request is submitted again
+		after      time.Duration
+		req        model.PayoutRequest
+		wantFirst  string
+		wantSecond string
+		wantErr    error
+	}{
+		{
+			name:       "success",
+			outcome:    "success",
+			req:        req,
+			wantFirst:  constant.DisbursementStatusSucceeded,
+			wantSecond: constant.DisbursementStatusSucceeded,
+		},
+		{
+			name:       "failure",
+			outcome:    "failure",
+			req:        req,
+			wantFirst:  constant.DisbursementStatusFailed,
+			wantSecond: constant.DisbursementStatusFailed,
+		},
+		{
+			name:       "delay not elapsed",
+			outcome:    "delay",
+			after:      59 * time.Second,
+			req:        req,
+			wantFirst:  constant.DisbursementStatusPending,
+			wantSecond: constant.DisbursementStatusPending,
+		},
+		{
+			name:       "delay elapsed",
+			outcome:    "delay",
+			after:      time.Minute,
+			req:        req,
+			wantFirst:  constant.DisbursementStatusPending,
+			wantSecond: constant.DisbursementStatusSucceeded,
+		},
+		{
+			name:      "key reused for another amount",
+			outcome:   "success",
+			req:       model.PayoutRequest{IdempotencyKey: "key", LoanId: 1, UserId: 2, Amount: 200},
+			wantFirst: constant.DisbursementStatusSucceeded,
+			wantErr:   ErrIdempotencyMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			f := NewFake(tt.outcome, time.Minute)
+			f.now = func() time.Time { return now }
+
+			first, err := f.Payout(context.Background(), req)
+			if err != nil || first.Status != tt.wantFirst || first.Reference == "" {
+				t.Fatalf("Payout test failed. want status: %s, got: %+v, err: %v", tt.wantFirst, first, err)
+			}
+
+			now = now.Add(tt.after)
+			second, err := f.Payout(context.Background(), tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Payout test failed. wantErr: %v, gotErr: %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if second.Status != tt.wantSecond {
+				t.Errorf("Payout test failed. want status: %s, got: %s", tt.wantSecond, second.Status)
+			}
+			if second.Reference != first.Reference {
+				t.Errorf("Payout test failed. retry got a new reference %s, want %s", second.Reference, first.Reference)
+			}
+		})
+	}
+}
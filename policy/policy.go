@@ -0,0 +1,64 @@
+// Package policy decides what a principal may do from the permissions of their roles. Handlers use it to gate
+// routes, usecases for decisions that depend on the resource such as the approval limit.
+package policy
+
+import (
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/model"
+)
+
+// Can reports whether one of p's roles grants permission.
+func Can(p model.Principal, permission string) bool {
+	for _, role := range p.Roles {
+		if grants(role, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+func grants(role model.Role, permission string) bool {
+	for _, granted := range role.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize fails with apperror.ErrForbidden unless p holds permission.
+func Authorize(p model.Principal, permission string) error {
+	if !Can(p, permission) {
+		return apperror.ErrForbidden
+	}
+	return nil
+}
+
+// ApprovalLimit returns the highest limit among p's roles granting loan:approve, unlimited when one of them has
+// no limit. ok is false when no role grants loan:approve at all.
+func ApprovalLimit(p model.Principal) (limit float64, unlimited, ok bool) {
+	for _, role := range p.Roles {
+		if !grants(role, constant.PermissionLoanApprove) {
+			continue
+		}
+		ok = true
+		if role.ApprovalLimit == nil {
+			return 0, true, true
+		}
+		limit = max(limit, *role.ApprovalLimit)
+	}
+	return
+}
+
+// AuthorizeApproval fails unless p may approve a loan of amount.
+func AuthorizeApproval(p model.Principal, amount float64) error {
+	limit, unlimited, ok := ApprovalLimit(p)
+	switch {
+	case !ok:
+		return apperror.ErrForbidden
+	case !unlimited && amount > limit:
+		return apperror.ErrApprovalLimitExceeded
+	}
+	return nil
+}
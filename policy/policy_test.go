@@ -0,0 +1,122 @@
+package policy
+
+import (
+	"testing"
+
+	"example.com/m/v2/apperror"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/model"
+	"example.com/m/v2/util"
+)
+
+func limit(v float64) *float64 {
+	return &v
+}
+
+var (
+	officer = model.Role{
+		Name:          constant.RoleLoanOfficer,
+		Permissions:   []string{constant.PermissionLoanRead, constant.PermissionLoanApprove},
+		ApprovalLimit: limit(1000),
+	}
+	seniorOfficer = model.Role{
+		Name:          "SENIOR_OFFICER",
+		Permissions:   []string{constant.PermissionLoanApprove},
+		ApprovalLimit: limit(5000),
+	}
+	superAdmin = model.Role{
+		Name:        constant.RoleSuperAdmin,
+		Permissions: []string{constant.PermissionLoanApprove, constant.PermissionRoleAssign},
+	}
+	support = model.Role{
+		Name:          constant.RoleSupport,
+		Permissions:   []string{constant.PermissionLoanRead},
+		ApprovalLimit: limit(1_000_000),
+	}
+)
+
+func Test_Authorize(t *testing.T) {
+	tests := []struct {
+		name       string
+		roles      []model.Role
+		permission string
+		wantErr    error
+	}{
+		{
+			name:       "no roles",
+			permission: constant.PermissionLoanRead,
+			wantErr:    apperror.ErrForbidden,
+		},
+		{
+			name:       "granted",
+			roles:      []model.Role{support},
+			permission: constant.PermissionLoanRead,
+		},
+		{
+			name:       "granted by a second role",
+			roles:      []model.Role{support, superAdmin},
+			permission: constant.PermissionRoleAssign,
+		},
+		{
+			name:       "not granted",
+			roles:      []model.Role{support, officer},
+			permission: constant.PermissionRoleAssign,
+			wantErr:    apperror.ErrForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Authorize(model.Principal{UserId: 1, Roles: tt.roles}, tt.permission)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("Authorize test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_AuthorizeApproval(t *testing.T) {
+	tests := []struct {
+		name    string
+		roles   []model.Role
+		amount  float64
+		wantErr error
+	}{
+		{
+			name:    "limit of a role without loan:approve is ignored",
+			roles:   []model.Role{support},
+			amount:  10,
+			wantErr: apperror.ErrForbidden,
+		},
+		{
+			name:   "at the limit",
+			roles:  []model.Role{officer},
+			amount: 1000,
+		},
+		{
+			name:    "above the limit",
+			roles:   []model.Role{officer, support},
+			amount:  1000.01,
+			wantErr: apperror.ErrApprovalLimitExceeded,
+		},
+		{
+			name:   "highest limit wins",
+			roles:  []model.Role{officer, seniorOfficer},
+			amount: 5000,
+		},
+		{
+			name:   "unlimited role wins",
+			roles:  []model.Role{seniorOfficer, superAdmin},
+			amount: 1_000_000_000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := AuthorizeApproval(model.Principal{UserId: 1, Roles: tt.roles}, tt.amount)
+			if !util.SameError(err, tt.wantErr) {
+				t.Errorf("AuthorizeApproval test failed. wantErr: %+v, gotErr: %+v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+// Package poll holds what the background workers draining a table share: the claimed batch contract, the poll loop
+// and the backoff of failed attempts.
+package poll
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Batch holds claimed rows until End or Abort, the outcomes recorded in between are only kept by End.
+type Batch interface {
+	End() error
+	// Abort releases the rows without their outcomes, it does nothing after End.
+	Abort()
+}
+
+// Run calls batch until ctx is done, logging as name with args. Batches follow each other while they handle
+// something and are interval apart once there is nothing left or a batch failed, a failure is logged and retried.
+func Run(ctx context.Context, name string, interval time.Duration, batch func(ctx context.Context) (handled int, err error), args ...any) {
+	slog.InfoContext(ctx, name+" started", args...)
+	for {
+		handled, err := batch(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, name+" batch failed", "error", err)
+		}
+
+		wait := interval
+		if err == nil && handled > 0 {
+			wait = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, name+" stopped")
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Backoff is the delay before retrying after attempts failures, base doubled with every failure and capped at max.
+func Backoff(base, max time.Duration, attempts int) time.Duration {
+	d := base
+	for i := 1; i < attempts && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
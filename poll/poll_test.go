@@ -0,0 +1,62 @@
+package poll
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// two busy batches, then an empty one and a failed one, each followed by the interval
+	results := []struct {
+		handled int
+		err     error
+	}{{2, nil}, {1, nil}, {0, nil}, {1, errors.New("err batch")}}
+
+	var calls []time.Time
+	done := make(chan struct{})
+	go func() {
+		Run(ctx, "tes", 50*time.Millisecond, func(ctx context.Context) (int, error) {
+			calls = append(calls, time.Now())
+			if len(calls) > len(results) {
+				cancel()
+				return 0, ctx.Err()
+			}
+			r := results[len(calls)-1]
+			return r.handled, r.err
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop once ctx was done")
+	}
+
+	if len(calls) != 5 {
+		t.Fatalf("want 5 batches, got %d", len(calls))
+	}
+	for i, waited := range []bool{false, false, true, true} {
+		if gap := calls[i+1].Sub(calls[i]); (gap >= 50*time.Millisecond) != waited {
+			t.Errorf("batch %d: want waited %v, got a %v gap", i+2, waited, gap)
+		}
+	}
+}
+
+func Test_Backoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1: 10 * time.Second,
+		2: 20 * time.Second,
+		3: 30 * time.Second,
+		9: 30 * time.Second,
+	} {
+		if got := Backoff(10*time.Second, 30*time.Second, attempts); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
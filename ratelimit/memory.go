@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// minSweep is the number of buckets a memory store holds before it starts evicting full ones.
+const minSweep = 1024
+
+type memoryEntry struct {
+	bucket
+	// fullAt is when the bucket is full again, from then on it is the same as a missing one
+	fullAt time.Time
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	sweepAt int
+}
+
+// NewMemoryStore keeps buckets in process memory, each instance then enforces its own limits. Buckets that
+// refilled completely are evicted once the map grows, so spraying keys can't exhaust memory.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		entries: map[string]memoryEntry{},
+		sweepAt: minSweep,
+	}
+}
+
+func (s *memoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.entries) >= s.sweepAt {
+		s.evict(now)
+	}
+
+	b, res := take(s.entries[key].bucket, limit, now)
+	s.entries[key] = memoryEntry{bucket: b, fullAt: now.Add(res.Reset)}
+
+	return res, nil
+}
+
+func (s *memoryStore) Prune(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.entries)
+	s.evict(now)
+	return int64(n - len(s.entries)), nil
+}
+
+// evict drops full buckets, then waits for the map to double before the next sweep so the cost stays
+// amortized constant per request.
+func (s *memoryStore) evict(now time.Time) {
+	for k, e := range s.entries {
+		if !now.Before(e.fullAt) {
+			delete(s.entries, k)
+		}
+	}
+	s.sweepAt = max(minSweep, 2*len(s.entries))
+}
@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"example.com/m/v2/tracing"
+)
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore keeps buckets in the rate_limit_buckets table, shared by every instance.
+func NewPostgresStore(db *sql.DB) Store {
+	return &postgresStore{db: db}
+}
+
+func (s *postgresStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (res Result, err error) {
+	ctx, span := tracing.StartDb(ctx, "ratelimit.Take", "UPDATE", "rate_limit_buckets")
+	defer tracing.End(span, &err)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback()
+
+	// the row lock serializes concurrent requests of one key, two first requests racing on a missing row may
+	// both pass, costing at most one extra token
+	var b bucket
+	err = tx.QueryRowContext(ctx, `SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE`, key).
+		Scan(&b.tokens, &b.updatedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+
+	b, res = take(b, limit, now)
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO rate_limit_buckets(key, tokens, updated_at, full_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key) DO UPDATE SET tokens = EXCLUDED.tokens, updated_at = EXCLUDED.updated_at, full_at = EXCLUDED.full_at
+	`, key, b.tokens, b.updatedAt, now.Add(res.Reset))
+	if err != nil {
+		return
+	}
+
+	err = tx.Commit()
+
+	return
+}
+
+func (s *postgresStore) Prune(ctx context.Context, now time.Time) (pruned int64, err error) {
+	ctx, span := tracing.StartDb(ctx, "ratelimit.Prune", "DELETE", "rate_limit_buckets")
+	defer tracing.End(span, &err)
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM rate_limit_buckets WHERE full_at <= $1`, now)
+	if err != nil {
+		return
+	}
+	pruned, err = res.RowsAffected()
+
+	return
+}
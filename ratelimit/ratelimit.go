@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"example.com/m/v2/config"
+)
+
+// Limit is a token bucket holding Requests tokens, refilled evenly over Per. A client may burst Requests
+// requests at once, then one every Per / Requests.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Result is the outcome of one Take.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is the wait until the next token, zero when Allowed
+	RetryAfter time.Duration
+	// Reset is the wait until the bucket is full again
+	Reset time.Duration
+}
+
+// Store keeps one bucket per key, implementations must be safe for concurrent use.
+type Store interface {
+	// Take consumes a token of the bucket of key when one is available.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+	// Prune deletes the buckets that are full again at now, they are the same as missing ones.
+	Prune(ctx context.Context, now time.Time) (pruned int64, err error)
+}
+
+// NewStore builds the Store selected by cfg.Store, db is only used by the postgres store.
+func NewStore(cfg config.RateLimit, db *sql.DB) (Store, error) {
+	switch cfg.Store {
+	case "postgres":
+		return NewPostgresStore(db), nil
+	case "memory":
+		return NewMemoryStore(), nil
+	}
+	return nil, fmt.Errorf("unknown rate limit store %q", cfg.Store)
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// take refills b up to now and consumes a token from it, the zero bucket is full.
+func take(b bucket, limit Limit, now time.Time) (bucket, Result) {
+	capacity := float64(limit.Requests)
+	rate := capacity / limit.Per.Seconds()
+
+	if b.updatedAt.IsZero() {
+		b.tokens = capacity
+	} else if elapsed := now.Sub(b.updatedAt).Seconds(); elapsed > 0 {
+		b.tokens = min(capacity, b.tokens+elapsed*rate)
+	}
+	b.updatedAt = now
+
+	res := Result{Limit: limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = seconds((capacity - b.tokens) / rate)
+
+	return b, res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
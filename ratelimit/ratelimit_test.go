@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func Test_memoryStore_Take(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limit := Limit{Requests: 3, Per: 3 * time.Second}
+
+	tests := []struct {
+		name string
+		// takes are the offsets of the requests before the checked one
+		takes []time.Duration
+		at    time.Duration
+		want  Result
+	}{
+		{
+			name: "first request",
+			want: Result{Allowed: true, Limit: 3, Remaining: 2, Reset: time.Second},
+		},
+		{
+			name:  "burst drains the bucket",
+			takes: []time.Duration{0, 0},
+			want:  Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 3 * time.Second},
+		},
+		{
+			name:  "empty bucket",
+			takes: []time.Duration{0, 0, 0},
+			at:    500 * time.Millisecond,
+			want:  Result{Allowed: false, Limit: 3, Remaining: 0, RetryAfter: 500 * time.Millisecond, Reset: 2500 * time.Millisecond},
+		},
+		{
+			name:  "refilled token",
+			takes: []time.Duration{0, 0, 0},
+			at:    time.Second,
+			want:  Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 3 * time.Second},
+		},
+		{
+			name:  "refill is capped",
+			takes: []time.Duration{0},
+			at:    time.Hour,
+			want:  Result{Allowed: true, Limit: 3, Remaining: 2, Reset: time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryStore()
+			for _, d := range tt.takes {
+				s.Take(context.Background(), "k", limit, start.Add(d))
+			}
+
+			got, err := s.Take(context.Background(), "k", limit, start.Add(tt.at))
+			if err != nil {
+				t.Fatalf("Take test failed. err: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Take test failed. want: %+v, got: %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_memoryStore_evict(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limit := Limit{Requests: 10, Per: time.Minute}
+	s := NewMemoryStore().(*memoryStore)
+
+	for i := 0; i < minSweep-1; i++ {
+		s.Take(ctx, fmt.Sprint(i), limit, start)
+	}
+	for i := 0; i < limit.Requests; i++ {
+		s.Take(ctx, "drained", limit, start.Add(50*time.Second))
+	}
+	s.Take(ctx, "new", limit, start.Add(time.Minute))
+
+	if len(s.entries) != 2 {
+		t.Errorf("evict test failed. want 2 entries, got %d", len(s.entries))
+	}
+	if _, ok := s.entries["drained"]; !ok {
+		t.Errorf("evict test failed. bucket still refilling was evicted")
+	}
+}
+
+func Test_memoryStore_Prune(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limit := Limit{Requests: 10, Per: time.Minute}
+	s := NewMemoryStore().(*memoryStore)
+
+	s.Take(ctx, "full", limit, start)
+	for i := 0; i < limit.Requests; i++ {
+		s.Take(ctx, "drained", limit, start.Add(50*time.Second))
+	}
+
+	pruned, err := s.Prune(ctx, start.Add(time.Minute))
+	if err != nil || pruned != 1 {
+		t.Fatalf("Prune test failed. want 1 pruned, got %d, err: %v", pruned, err)
+	}
+	if _, ok := s.entries["drained"]; !ok || len(s.entries) != 1 {
+		t.Errorf("Prune test failed. want only the refilling bucket kept, got %+v", s.entries)
+	}
+}
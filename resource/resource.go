@@ -1,23 +1,141 @@
 package resource
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"example.com/m/v2/config"
+	"example.com/m/v2/mailer"
+	"example.com/m/v2/payment"
+	"example.com/m/v2/payout"
 	_ "github.com/lib/pq"
 )
 
 type Resource struct {
 	PostgresDb *sql.DB
+	Mailer     mailer.Mailer
+	Payout     payout.PayoutProvider
+	Payment    payment.PaymentGateway
 }
 
-func Init(cfg *config.Config) *Resource {
-	psqlconn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", cfg.PostgresDb.Host, cfg.PostgresDb.Port, cfg.PostgresDb.User, cfg.PostgresDb.Password, cfg.PostgresDb.Dbname)
+// Init opens the postgres pool and verifies connectivity, retrying with exponential backoff.
+func Init(ctx context.Context, cfg *config.Config) (res *Resource, err error) {
+	dbCfg := cfg.PostgresDb
 
-	db, _ := sql.Open("postgres", psqlconn)
+	db, err := sql.Open("postgres", Dsn(dbCfg))
+	if err != nil {
+		return
+	}
+	db.SetMaxOpenConns(dbCfg.MaxOpenConns)
+	db.SetMaxIdleConns(dbCfg.MaxIdleConns)
+	db.SetConnMaxLifetime(dbCfg.ConnMaxLifetime)
+
+	err = ping(ctx, db, dbCfg.ConnectTimeout, dbCfg.ConnectRetries)
+	if err != nil {
+		db.Close()
+		return
+	}
+
+	m, err := mailer.New(cfg.Mailer)
+	if err != nil {
+		db.Close()
+		return
+	}
+
+	p, err := payout.New(cfg.Payout)
+	if err != nil {
+		db.Close()
+		m.Close()
+		return
+	}
 
-	return &Resource{
+	g, err := payment.New(cfg.Payment)
+	if err != nil {
+		db.Close()
+		m.Close()
+		return
+	}
+
+	res = &Resource{
 		PostgresDb: db,
+		Mailer:     m,
+		Payout:     p,
+		Payment:    g,
+	}
+
+	return
+}
+
+// DbStats exposes the postgres pool statistics (open, in use, idle, wait count...) for metrics.
+func (r *Resource) DbStats() sql.DBStats {
+	return r.PostgresDb.Stats()
+}
+
+func (r *Resource) Close() error {
+	return errors.Join(r.PostgresDb.Close(), r.Mailer.Close())
+}
+
+func ping(ctx context.Context, db *sql.DB, timeout time.Duration, retries int) (err error) {
+	backoff := 500 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		err = db.PingContext(pingCtx)
+		cancel()
+		if err == nil || attempt >= retries {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	if err != nil {
+		err = fmt.Errorf("postgres unreachable after %d attempts: %w", retries+1, err)
+	}
+
+	return
+}
+
+// Dsn builds the lib/pq connection string, quoting values as needed.
+func Dsn(cfg config.PostgresDb) string {
+	params := [][2]string{
+		{"host", cfg.Host},
+		{"port", fmt.Sprint(cfg.Port)},
+		{"user", cfg.User},
+		{"password", cfg.Password},
+		{"dbname", cfg.Dbname},
+		{"sslmode", cfg.SslMode},
+		{"sslcert", cfg.SslCert},
+		{"sslkey", cfg.SslKey},
+		{"sslrootcert", cfg.SslRootCert},
+	}
+	if cfg.ConnectTimeout > 0 {
+		params = append(params, [2]string{"connect_timeout", fmt.Sprint(int(cfg.ConnectTimeout.Seconds()))})
+	}
+
+	parts := make([]string, 0, len(params))
+	for _, p := range params {
+		if p[1] == "" {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s=%s", p[0], quoteDsnValue(p[1])))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func quoteDsnValue(v string) string {
+	if !strings.ContainsAny(v, ` '\`) {
+		return v
 	}
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
 }
@@ -2,35 +2,155 @@ package route
 
 import (
 	"net/http"
+	"time"
 
 	"example.com/m/v2/dependency"
+	"example.com/m/v2/logic/handler"
+	"example.com/m/v2/metrics"
+	"example.com/m/v2/middleware"
+	"example.com/m/v2/ratelimit"
 )
 
 type routeConfig struct {
 	path    string
 	method  string
 	handler func(http.ResponseWriter, *http.Request)
+	// rateLimit is applied when rate limiting is enabled, nil leaves the route unlimited
+	rateLimit *middleware.RateLimitPolicy
 }
 
 func Init(dep dependency.Dependency) {
-	routes := routeBuilder{make(map[string]func(http.ResponseWriter, *http.Request)), make(map[string]map[string]func(http.ResponseWriter, *http.Request))}
+	routes := routeBuilder{
+		routes:   make(map[string]func(http.ResponseWriter, *http.Request)),
+		internal: make(map[string]map[string]func(http.ResponseWriter, *http.Request)),
+		// nil when rate limiting is disabled
+		rateLimitStore: dep.RateLimitStore,
+		middlewares: []middleware.Middleware{
+			middleware.RequestId(dep.Logger),
+			middleware.ClientIp(dep.Config.TrustProxyHeaders),
+			middleware.Tracing,
+			middleware.AccessLog,
+		},
+	}
 
 	routes.register(routeConfig{
 		path:    "/user/login",
 		method:  "POST",
 		handler: dep.Handler.Login,
+		rateLimit: &middleware.RateLimitPolicy{
+			Name:  "login",
+			Limit: ratelimit.Limit{Requests: 10, Per: time.Minute},
+			Key:   middleware.ByIp,
+		},
+	})
+
+	routes.register(routeConfig{
+		path:    "/user/login/2fa",
+		method:  "POST",
+		handler: dep.Handler.LoginTotp,
+		rateLimit: &middleware.RateLimitPolicy{
+			Name:  "login_2fa",
+			Limit: ratelimit.Limit{Requests: 10, Per: time.Minute},
+			Key:   middleware.ByIp,
+		},
+	})
+
+	routes.register(routeConfig{
+		path:    "/user/2fa/enroll",
+		method:  "POST",
+		handler: dep.Handler.EnrollTotp,
+	})
+
+	routes.register(routeConfig{
+		path:    "/user/2fa/enable",
+		method:  "POST",
+		handler: dep.Handler.EnableTotp,
 	})
 
 	routes.register(routeConfig{
 		path:    "/user/register",
 		method:  "POST",
 		handler: dep.Handler.Register,
+		rateLimit: &middleware.RateLimitPolicy{
+			Name:  "register",
+			Limit: ratelimit.Limit{Requests: 10, Per: time.Hour},
+			Key:   middleware.ByIp,
+		},
+	})
+
+	routes.register(routeConfig{
+		path:    "/user/me",
+		method:  "GET",
+		handler: dep.Handler.Me,
+	})
+
+	routes.register(routeConfig{
+		path:    "/user/verify",
+		method:  "POST",
+		handler: dep.Handler.VerifyEmail,
+	})
+
+	routes.register(routeConfig{
+		path:    "/user/verify/resend",
+		method:  "POST",
+		handler: dep.Handler.ResendVerification,
+		rateLimit: &middleware.RateLimitPolicy{
+			Name:  "verify_resend",
+			Limit: ratelimit.Limit{Requests: 3, Per: time.Hour},
+			Key:   dep.Handler.UserRateKey,
+		},
+	})
+
+	routes.register(routeConfig{
+		path:    "/user/password/forgot",
+		method:  "POST",
+		handler: dep.Handler.ForgotPassword,
+		rateLimit: &middleware.RateLimitPolicy{
+			Name:  "password_forgot",
+			Limit: ratelimit.Limit{Requests: 5, Per: time.Hour},
+			Key:   middleware.ByIp,
+		},
+	})
+
+	routes.register(routeConfig{
+		path:    "/user/password/reset",
+		method:  "POST",
+		handler: dep.Handler.ResetPassword,
+	})
+
+	routes.register(routeConfig{
+		path:    "/user/password",
+		method:  "PUT",
+		handler: dep.Handler.ChangePassword,
+	})
+
+	routes.register(routeConfig{
+		path:    "/user/notifications",
+		method:  "GET",
+		handler: dep.Handler.GetNotifications,
+	})
+
+	routes.register(routeConfig{
+		path:    "/user/notifications/preferences",
+		method:  "GET",
+		handler: dep.Handler.GetNotificationPreferences,
+	})
+
+	routes.register(routeConfig{
+		path:    "/user/notifications/preferences",
+		method:  "PUT",
+		handler: dep.Handler.UpdateNotificationPreferences,
 	})
 
 	routes.register(routeConfig{
 		path:    "/loan",
 		method:  "POST",
 		handler: dep.Handler.NewLoan,
+		rateLimit: &middleware.RateLimitPolicy{
+			Name:  "new_loan",
+			Limit: ratelimit.Limit{Requests: 20, Per: time.Hour},
+			Key:   dep.Handler.UserRateKey,
+		},
 	})
 
 	routes.register(routeConfig{
@@ -43,6 +163,17 @@ func Init(dep dependency.Dependency) {
 		path:    "/loan/pay",
 		method:  "POST",
 		handler: dep.Handler.PayLoan,
+		rateLimit: &middleware.RateLimitPolicy{
+			Name:  "pay_loan",
+			Limit: ratelimit.Limit{Requests: 30, Per: time.Minute},
+			Key:   dep.Handler.UserRateKey,
+		},
+	})
+
+	routes.register(routeConfig{
+		path:    "/payment/webhook",
+		method:  "POST",
+		handler: dep.Handler.PaymentWebhook,
 	})
 
 	routes.register(routeConfig{
@@ -51,19 +182,135 @@ func Init(dep dependency.Dependency) {
 		handler: dep.Handler.GetLoan,
 	})
 
+	routes.register(routeConfig{
+		path:    "/admin/roles",
+		method:  "GET",
+		handler: dep.Handler.ListRoles,
+	})
+
+	routes.register(routeConfig{
+		path:    "/admin/user/roles",
+		method:  "PUT",
+		handler: dep.Handler.AssignRoles,
+	})
+
+	routes.register(routeConfig{
+		path:    "/admin/user/unlock",
+		method:  "POST",
+		handler: dep.Handler.UnlockLogin,
+	})
+
+	routes.register(routeConfig{
+		path:    "/admin/loan",
+		method:  "GET",
+		handler: dep.Handler.GetUserLoans,
+	})
+
+	routes.register(routeConfig{
+		path:    "/admin/loan/approvals",
+		method:  "GET",
+		handler: dep.Handler.GetLoanApprovals,
+	})
+
+	routes.register(routeConfig{
+		path:    "/admin/loan/disburse",
+		method:  "POST",
+		handler: dep.Handler.DisburseLoan,
+	})
+
+	routes.register(routeConfig{
+		path:    "/admin/loan/pay",
+		method:  "POST",
+		handler: dep.Handler.RecordPayment,
+	})
+
+	routes.register(routeConfig{
+		path:    "/admin/webhooks",
+		method:  "GET",
+		handler: dep.Handler.ListWebhookEndpoints,
+	})
+
+	routes.register(routeConfig{
+		path:    "/admin/webhooks",
+		method:  "POST",
+		handler: dep.Handler.CreateWebhookEndpoint,
+	})
+
+	routes.register(routeConfig{
+		path:    "/admin/webhooks",
+		method:  "PUT",
+		handler: dep.Handler.UpdateWebhookEndpoint,
+	})
+
+	routes.register(routeConfig{
+		path:    "/admin/webhooks",
+		method:  "DELETE",
+		handler: dep.Handler.DeleteWebhookEndpoint,
+	})
+
+	routes.register(routeConfig{
+		path:    "/admin/webhooks/secret",
+		method:  "POST",
+		handler: dep.Handler.RotateWebhookSecret,
+	})
+
+	routes.register(routeConfig{
+		path:    "/admin/webhooks/deliveries",
+		method:  "GET",
+		handler: dep.Handler.GetWebhookDeliveries,
+	})
+
+	routes.register(routeConfig{
+		path:    "/admin/webhooks/delivery",
+		method:  "GET",
+		handler: dep.Handler.GetWebhookDelivery,
+	})
+
+	routes.register(routeConfig{
+		path:    "/admin/webhooks/redeliver",
+		method:  "POST",
+		handler: dep.Handler.RedeliverWebhook,
+	})
+
+	routes.register(routeConfig{
+		path:    "/healthz",
+		method:  "GET",
+		handler: dep.Health.Liveness,
+	})
+
+	routes.register(routeConfig{
+		path:    "/readyz",
+		method:  "GET",
+		handler: dep.Health.Readiness,
+	})
+
 	routes.serve()
 }
 
+// Metrics serves /metrics, it is listened on metrics_address instead of the api address.
+func Metrics() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	return mux
+}
+
 type routeBuilder struct {
 	routes   map[string]func(http.ResponseWriter, *http.Request)
 	internal map[string]map[string]func(http.ResponseWriter, *http.Request)
+	// middlewares wrap every path, the first one is the outermost
+	middlewares    []middleware.Middleware
+	rateLimitStore ratelimit.Store
 }
 
 func (self *routeBuilder) register(routeCfg routeConfig) {
 	if _, ok := self.internal[routeCfg.path]; !ok {
 		self.internal[routeCfg.path] = make(map[string]func(http.ResponseWriter, *http.Request))
 	}
-	self.internal[routeCfg.path][routeCfg.method] = routeCfg.handler
+	h := routeCfg.handler
+	if routeCfg.rateLimit != nil && self.rateLimitStore != nil {
+		h = middleware.RateLimit(self.rateLimitStore, *routeCfg.rateLimit, handler.WriteError)(http.HandlerFunc(h)).ServeHTTP
+	}
+	self.internal[routeCfg.path][routeCfg.method] = middleware.Instrument(routeCfg.path, routeCfg.method, h)
 }
 
 func (self *routeBuilder) serve() {
@@ -80,6 +327,6 @@ func (self *routeBuilder) serve() {
 		}
 	}
 	for k, v := range self.routes {
-		http.HandleFunc(k, v)
+		http.Handle(k, middleware.Chain(http.HandlerFunc(v), self.middlewares...))
 	}
 }
@@ -0,0 +1,126 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Digits, Period and the SHA1 algorithm are the RFC 6238 defaults every authenticator app supports
+	Digits = 6
+	Period = 30 * time.Second
+	// Skew accepts codes from this many periods before and after the current one, for clock drift
+	Skew = 1
+	// SecretBytes is the RFC 4226 recommended secret length
+	SecretBytes = 20
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a random base32 secret.
+func GenerateSecret() (string, error) {
+	b := make([]byte, SecretBytes)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// ProvisioningUri is the otpauth uri authenticator apps import, usually rendered as a QR code by the client.
+func ProvisioningUri(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(Digits))
+	v.Set("period", fmt.Sprint(int(Period.Seconds())))
+
+	return (&url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: v.Encode(),
+	}).String()
+}
+
+// Step is the RFC 6238 time step of t.
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period.Seconds())
+}
+
+// Code is the code of secret at step.
+func Code(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("decode totp secret: %w", err)
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// RFC 4226 dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, value%mod), nil
+}
+
+// Validate returns the step code matched at t, within Skew. Callers must reject steps they already accepted,
+// otherwise an observed code can be replayed until it expires.
+func Validate(secret, code string, t time.Time) (step int64, ok bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+	current := Step(t)
+	for s := current - Skew; s <= current+Skew; s++ {
+		want, err := Code(secret, s)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// RecoveryCodes returns n random single-use codes formatted as xxxxx-xxxxx in lower case base32.
+func RecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		b := make([]byte, 7)
+		_, err := rand.Read(b)
+		if err != nil {
+			return nil, err
+		}
+		s := strings.ToLower(encoding.EncodeToString(b))[:10]
+		codes = append(codes, s[:5]+"-"+s[5:])
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode lets users type codes in any case, with or without the dash and surrounding spaces.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	if len(code) != 10 {
+		return code
+	}
+	return code[:5] + "-" + code[5:]
+}
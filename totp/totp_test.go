@@ -0,0 +1,123 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key of the RFC 6238 appendix B test vectors
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func Test_Code(t *testing.T) {
+	// the RFC lists 8 digit codes, these are their last 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+			if err != nil || got != tt.want {
+				t.Errorf("Code test failed. want: %s, got: %s, err: %v", tt.want, got, err)
+			}
+		})
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+
+	tests := []struct {
+		name     string
+		code     string
+		at       time.Time
+		wantStep int64
+		wantOk   bool
+	}{
+		{
+			name:     "current step",
+			code:     "050471",
+			at:       at,
+			wantStep: Step(at),
+			wantOk:   true,
+		},
+		{
+			name:     "previous step within skew",
+			code:     "050471",
+			at:       at.Add(Period),
+			wantStep: Step(at),
+			wantOk:   true,
+		},
+		{
+			name: "outside skew",
+			code: "050471",
+			at:   at.Add(2 * Period),
+		},
+		{
+			name: "wrong code",
+			code: "000000",
+			at:   at,
+		},
+		{
+			name: "wrong length",
+			code: "50471",
+			at:   at,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, tt.code, tt.at)
+			if ok != tt.wantOk || step != tt.wantStep {
+				t.Errorf("Validate test failed. want: %d %v, got: %d %v", tt.wantStep, tt.wantOk, step, ok)
+			}
+		})
+	}
+}
+
+func Test_ProvisioningUri(t *testing.T) {
+	got, err := url.Parse(ProvisioningUri("mini-aspire", "admin@tes.com", "ABC"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Scheme != "otpauth" || got.Host != "totp" || got.Path != "/mini-aspire:admin@tes.com" {
+		t.Errorf("ProvisioningUri test failed. got: %s", got)
+	}
+	q := got.Query()
+	if q.Get("secret") != "ABC" || q.Get("issuer") != "mini-aspire" || q.Get("digits") != "6" || q.Get("period") != "30" {
+		t.Errorf("ProvisioningUri test failed. got query: %v", q)
+	}
+}
+
+func Test_RecoveryCodes(t *testing.T) {
+	codes, err := RecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	format := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+	for _, c := range codes {
+		if !format.MatchString(c) || seen[c] {
+			t.Errorf("RecoveryCodes test failed. got: %v", codes)
+		}
+		seen[c] = true
+		if NormalizeRecoveryCode(" "+strings.ToUpper(c[:5]+c[6:])+" ") != c {
+			t.Errorf("NormalizeRecoveryCode test failed for %s", c)
+		}
+	}
+	if len(codes) != 10 {
+		t.Errorf("RecoveryCodes test failed. want 10 codes, got %d", len(codes))
+	}
+}
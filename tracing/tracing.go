@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"example.com/m/v2/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOtlp   = "otlp"
+
+	instrumentationName = "example.com/m/v2"
+)
+
+// Init installs the global tracer provider configured by cfg, stdout traces are written to w.
+// The returned shutdown flushes pending spans.
+func Init(ctx context.Context, cfg config.Tracing, w io.Writer) (shutdown func(context.Context) error, err error) {
+	shutdown = func(context.Context) error { return nil }
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case ExporterNone, "":
+		Install(trace.NewNoopTracerProvider())
+		return
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	case ExporterOtlp:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.OtlpEndpoint)}
+		if cfg.OtlpInsecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		err = fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName))),
+	)
+	Install(tp)
+
+	return tp.Shutdown, nil
+}
+
+// Install sets tp as the global tracer provider together with the W3C trace context propagator,
+// tests use it with an in-memory span recorder.
+func Install(tp trace.TracerProvider) {
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// Start opens a span named name as a child of the span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records *err on span, when set, and ends it. Use it deferred with a named error return.
+func End(span trace.Span, err *error) {
+	if err != nil && *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
+
+// StartDb opens a client span for a sql query on table.
+func StartDb(ctx context.Context, name, operation, table string) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBCollectionName(table),
+		),
+	)
+}
@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"example.com/m/v2/config"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func Test_StartDbEnd(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	Install(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer Install(trace.NewNoopTracerProvider())
+
+	query := func(ctx context.Context) (err error) {
+		ctx, span := StartDb(ctx, "repository.GetLoanByIdAndUserId", "SELECT", "loans")
+		defer End(span, &err)
+
+		return errors.New("err query")
+	}
+
+	ctx, span := Start(context.Background(), "usecase.PayLoan")
+	query(ctx)
+	End(span, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("want 2 spans, got %d", len(spans))
+	}
+
+	db := spans[0]
+	if db.Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Errorf("db span is not a child of the usecase span")
+	}
+	if db.SpanKind() != trace.SpanKindClient {
+		t.Errorf("want client span, got %s", db.SpanKind())
+	}
+	if db.Status().Code != codes.Error || db.Status().Description != "err query" {
+		t.Errorf("unexpected db span status %+v", db.Status())
+	}
+
+	attrs := map[string]string{}
+	for _, kv := range db.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	for key, want := range map[string]string{
+		"db.system":          "postgresql",
+		"db.operation.name":  "SELECT",
+		"db.collection.name": "loans",
+	} {
+		if attrs[key] != want {
+			t.Errorf("attribute %s: got %q want %q", key, attrs[key], want)
+		}
+	}
+
+	if spans[1].Status().Code != codes.Unset {
+		t.Errorf("usecase span should not be marked as error, got %+v", spans[1].Status())
+	}
+}
+
+func Test_Init(t *testing.T) {
+	defer Install(trace.NewNoopTracerProvider())
+
+	for _, exporter := range []string{ExporterNone, ExporterStdout, ExporterOtlp} {
+		shutdown, err := Init(context.Background(), configFor(exporter), io.Discard)
+		if err != nil {
+			t.Errorf("Init %s failed: %v", exporter, err)
+			continue
+		}
+		shutdown(context.Background())
+	}
+
+	if _, err := Init(context.Background(), configFor("tes"), io.Discard); err == nil {
+		t.Errorf("want error for unknown exporter")
+	}
+}
+
+func configFor(exporter string) config.Tracing {
+	return config.Tracing{
+		Exporter:     exporter,
+		OtlpEndpoint: "localhost:4318",
+		OtlpInsecure: true,
+		SampleRatio:  1,
+		ServiceName:  "tes",
+	}
+}
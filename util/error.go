@@ -1,8 +1,18 @@
 package util
 
+import "errors"
+
 func SameErrorMessage(err, target error) bool {
 	if target == nil || err == nil {
 		return err == target
 	}
 	return err.Error() == target.Error()
 }
+
+// SameError matches err against target with errors.Is, falling back to SameErrorMessage for ad-hoc errors.
+func SameError(err, target error) bool {
+	if errors.Is(err, target) {
+		return true
+	}
+	return SameErrorMessage(err, target)
+}
@@ -0,0 +1,124 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"example.com/m/v2/constant"
+	"example.com/m/v2/tracing"
+)
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore dispatches webhook_deliveries, any number of dispatchers can share it.
+func NewPostgresStore(db *sql.DB) Store {
+	return &postgresStore{db: db}
+}
+
+// Claim locks the batch in a transaction kept open until the batch ends, SKIP LOCKED lets concurrent dispatchers
+// pass over the deliveries another one holds.
+func (s *postgresStore) Claim(ctx context.Context, now time.Time, limit int) (_ Batch, err error) {
+	ctx, span := tracing.StartDb(ctx, "webhook.Claim", "SELECT", "webhook_deliveries")
+	defer tracing.End(span, &err)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			d.id, d.attempts, e.url, e.secret,
+			o.id, o.event_type, o.aggregate_type, o.aggregate_id, o.created_at, o.payload
+		FROM
+			webhook_deliveries d
+			JOIN webhook_endpoints e ON e.id = d.endpoint_id
+			JOIN outbox o ON o.id = d.event_id
+		WHERE
+			d.status = 'PENDING' AND d.next_attempt_at <= $1 AND e.active
+		ORDER BY
+			d.next_attempt_at, d.id
+		LIMIT $2
+		FOR UPDATE OF d SKIP LOCKED
+	`, now, limit)
+	if err != nil {
+		tx.Rollback()
+		return
+	}
+	defer rows.Close()
+
+	batch := &postgresBatch{tx: tx}
+	for rows.Next() {
+		var delivery Delivery
+		var payload []byte
+		ev := &delivery.Event
+		err = rows.Scan(&delivery.Id, &delivery.Attempts, &delivery.Url, &delivery.Secret,
+			&ev.Id, &ev.Type, &ev.AggregateType, &ev.AggregateId, &ev.OccurredAt, &payload)
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		ev.Data = payload
+		batch.deliveries = append(batch.deliveries, delivery)
+	}
+	if err = rows.Err(); err != nil {
+		tx.Rollback()
+		return
+	}
+
+	return batch, nil
+}
+
+type postgresBatch struct {
+	tx         *sql.Tx
+	deliveries []Delivery
+}
+
+func (b *postgresBatch) Deliveries() []Delivery {
+	return b.deliveries
+}
+
+// Record appends the attempt to the delivery log and moves the delivery to the state it left.
+func (b *postgresBatch) Record(ctx context.Context, id int64, attempt Attempt) (err error) {
+	_, err = b.tx.ExecContext(ctx, `
+		INSERT INTO
+			webhook_delivery_attempts(
+				delivery_id, attempted_at, response_code, error, duration_ms
+			)
+		VALUES
+			($1,$2,$3,NULLIF($4,''),$5)
+	`, id, attempt.At, attempt.ResponseCode, attempt.Error, attempt.Duration.Milliseconds())
+	if err != nil {
+		return
+	}
+
+	var nextAttemptAt, deliveredAt *time.Time
+	switch attempt.Status {
+	case constant.WebhookDeliveryPending:
+		nextAttemptAt = &attempt.NextAttemptAt
+	case constant.WebhookDeliverySucceeded:
+		deliveredAt = &attempt.At
+	}
+	_, err = b.tx.ExecContext(ctx, `
+		UPDATE
+			webhook_deliveries
+		SET
+			status = $2, attempts = $3, next_attempt_at = COALESCE($4, next_attempt_at), response_code = $5,
+			last_error = NULLIF($6,''), delivered_at = COALESCE($7, delivered_at)
+		WHERE
+			id = $1
+	`, id, attempt.Status, attempt.Attempts, nextAttemptAt, attempt.ResponseCode, attempt.Error, deliveredAt)
+
+	return
+}
+
+func (b *postgresBatch) End() error {
+	return b.tx.Commit()
+}
+
+func (b *postgresBatch) Abort() {
+	b.tx.Rollback()
+}
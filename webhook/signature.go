@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrStaleTimestamp   = errors.New("webhook timestamp outside tolerance")
+)
+
+// signatureVersion prefixes signatures, a new scheme would be sent next to it under another version.
+const signatureVersion = "v1"
+
+// Sign is the X-Webhook-Signature of a delivery: v1= and the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the
+// endpoint secret, timestamp being unix seconds.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signatureVersion + "=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify is what receivers do with a delivery: check its signature and that its timestamp is within tolerance of
+// now, which bounds replays. Deliveries are at least once, receivers deduplicate on the event id.
+func Verify(secret, signature, timestamp string, body []byte, now time.Time, tolerance time.Duration) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || !strings.HasPrefix(signature, signatureVersion+"=") {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal([]byte(Sign(secret, ts, body)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+
+	age := now.Sub(time.Unix(ts, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrStaleTimestamp
+	}
+
+	return nil
+}
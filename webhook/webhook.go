@@ -0,0 +1,188 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"example.com/m/v2/config"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/metrics"
+	"example.com/m/v2/model"
+	"example.com/m/v2/poll"
+)
+
+// Delivery is a claimed delivery with what it takes to send it.
+type Delivery struct {
+	Id       int64
+	Attempts int
+	Url      string
+	Secret   string
+	Event    model.EventMessage
+}
+
+// Attempt is the outcome of sending a delivery: the log entry and the state the delivery is left in.
+type Attempt struct {
+	At           time.Time
+	Duration     time.Duration
+	ResponseCode *int
+	Error        string
+	// Status is the delivery status after the attempt, NextAttemptAt only matters while it is PENDING
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+}
+
+// Store hands out the deliveries to send.
+type Store interface {
+	// Claim starts a batch of at most limit pending deliveries due at now, to active endpoints.
+	Claim(ctx context.Context, now time.Time, limit int) (Batch, error)
+}
+
+// Batch holds claimed deliveries, see poll.Batch.
+type Batch interface {
+	poll.Batch
+	Deliveries() []Delivery
+	Record(ctx context.Context, id int64, attempt Attempt) error
+}
+
+// Dispatcher sends webhook deliveries to partner endpoints. Every delivery is a signed POST of the event, any status
+// but 2xx fails it and it is retried with an exponential backoff until MaxAttempts, then it is FAILED until
+// redelivered by hand. Delivery is at least once, a crash after sending sends it again.
+type Dispatcher struct {
+	store  Store
+	client *http.Client
+	cfg    config.Webhooks
+	now    func() time.Time
+}
+
+func NewDispatcher(store Store, cfg config.Webhooks) *Dispatcher {
+	return &Dispatcher{
+		store: store,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// a redirect is a failed delivery, the endpoint url has to be fixed
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		cfg: cfg,
+		now: time.Now,
+	}
+}
+
+// Run dispatches batches of deliveries every PollInterval until ctx is done, see poll.Run.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	poll.Run(ctx, "webhook dispatcher", d.cfg.PollInterval, d.DispatchBatch)
+	return nil
+}
+
+// DispatchBatch sends one batch of deliveries concurrently, handled is the number of deliveries attempted.
+func (d *Dispatcher) DispatchBatch(ctx context.Context) (handled int, err error) {
+	batch, err := d.store.Claim(ctx, d.now(), d.cfg.BatchSize)
+	if err != nil {
+		return
+	}
+	defer batch.Abort()
+
+	deliveries := batch.Deliveries()
+	attempts := make([]Attempt, len(deliveries))
+
+	var wg sync.WaitGroup
+	for i, delivery := range deliveries {
+		wg.Add(1)
+		go func(i int, delivery Delivery) {
+			defer wg.Done()
+			attempts[i] = d.attempt(ctx, delivery)
+		}(i, delivery)
+	}
+	wg.Wait()
+
+	for i, delivery := range deliveries {
+		err = batch.Record(ctx, delivery.Id, attempts[i])
+		if err != nil {
+			return
+		}
+		handled++
+	}
+
+	err = batch.End()
+
+	return
+}
+
+// attempt sends a delivery once and decides what becomes of it.
+func (d *Dispatcher) attempt(ctx context.Context, delivery Delivery) Attempt {
+	log := slog.Default().With("delivery_id", delivery.Id, "event_id", delivery.Event.Id, "event_type", delivery.Event.Type)
+
+	attempt := Attempt{At: d.now(), Attempts: delivery.Attempts + 1}
+	code, sendErr := d.send(ctx, delivery, attempt.At)
+	attempt.Duration = d.now().Sub(attempt.At)
+	if code != 0 {
+		attempt.ResponseCode = &code
+	}
+
+	switch {
+	case sendErr == nil:
+		attempt.Status = constant.WebhookDeliverySucceeded
+		metrics.WebhookDelivered("succeeded")
+	case attempt.Attempts >= d.cfg.MaxAttempts:
+		attempt.Status = constant.WebhookDeliveryFailed
+		attempt.Error = sendErr.Error()
+		metrics.WebhookDelivered("failed")
+		log.WarnContext(ctx, "webhook delivery failed", "attempts", attempt.Attempts, "error", sendErr)
+	default:
+		attempt.Status = constant.WebhookDeliveryPending
+		attempt.Error = sendErr.Error()
+		attempt.NextAttemptAt = attempt.At.Add(d.backoff(attempt.Attempts))
+		metrics.WebhookDelivered("retry")
+		log.InfoContext(ctx, "webhook delivery failed, retrying", "attempt", attempt.Attempts, "retry_at", attempt.NextAttemptAt, "error", sendErr)
+	}
+
+	return attempt
+}
+
+// send POSTs the event signed at at, code is 0 when no response came back.
+func (d *Dispatcher) send(ctx context.Context, delivery Delivery, at time.Time) (code int, err error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	timestamp := at.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "mini-aspire-webhooks")
+	req.Header.Set(constant.WebhookDeliveryHeader, strconv.FormatInt(delivery.Id, 10))
+	req.Header.Set(constant.WebhookEventHeader, delivery.Event.Type)
+	req.Header.Set(constant.WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(constant.WebhookSignatureHeader, Sign(delivery.Secret, timestamp, body))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	code = res.StatusCode
+	if code < 200 || code > 299 {
+		err = fmt.Errorf("endpoint answered %s", res.Status)
+	}
+	return
+}
+
+// backoff is the delay before retrying a delivery that failed attempts times.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	return poll.Backoff(d.cfg.BaseBackoff, d.cfg.MaxBackoff, attempts)
+}
@@ -0,0 +1,216 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/m/v2/config"
+	"example.com/m/v2/constant"
+	"example.com/m/v2/model"
+)
+
+var testCfg = config.Webhooks{
+	Timeout:     time.Second,
+	BatchSize:   10,
+	MaxAttempts: 3,
+	BaseBackoff: 30 * time.Second,
+	MaxBackoff:  time.Minute,
+}
+
+var testNow = time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+
+const testSecret = "whsec_tes"
+
+type fakeBatch struct {
+	deliveries []Delivery
+	recorded   map[int64]Attempt
+	ended      bool
+}
+
+func (b *fakeBatch) Deliveries() []Delivery { return b.deliveries }
+
+func (b *fakeBatch) Record(ctx context.Context, id int64, attempt Attempt) error {
+	b.recorded[id] = attempt
+	return nil
+}
+
+func (b *fakeBatch) End() error { b.ended = true; return nil }
+
+func (b *fakeBatch) Abort() {}
+
+type fakeStore struct {
+	batch *fakeBatch
+}
+
+func (s *fakeStore) Claim(ctx context.Context, now time.Time, limit int) (Batch, error) {
+	return s.batch, nil
+}
+
+// receiver is a partner endpoint, it verifies every delivery and answers with the status of its event type
+type receiver struct {
+	mu       sync.Mutex
+	status   map[string]int
+	received []model.EventMessage
+	errs     []error
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	err := Verify(testSecret, r.Header.Get(constant.WebhookSignatureHeader), r.Header.Get(constant.WebhookTimestampHeader), body, testNow, 5*time.Minute)
+	if err != nil {
+		rc.errs = append(rc.errs, err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var msg model.EventMessage
+	json.Unmarshal(body, &msg)
+	rc.received = append(rc.received, msg)
+
+	if status, ok := rc.status[msg.Type]; ok {
+		w.WriteHeader(status)
+	}
+}
+
+func delivery(id int64, url string, attempts int, eventType string) Delivery {
+	return Delivery{
+		Id:       id,
+		Attempts: attempts,
+		Url:      url,
+		Secret:   testSecret,
+		Event: model.EventMessage{
+			Id:            id + 100,
+			Type:          eventType,
+			AggregateType: "loan",
+			AggregateId:   7,
+			OccurredAt:    testNow,
+			Data:          json.RawMessage(`{"loan_id":7}`),
+		},
+	}
+}
+
+func code(c int) *int { return &c }
+
+func Test_Dispatcher_DispatchBatch(t *testing.T) {
+	rc := &receiver{status: map[string]int{
+		constant.EventLoanApproved:  http.StatusInternalServerError,
+		constant.EventLoanPaid:      http.StatusServiceUnavailable,
+		constant.EventLoanDefaulted: http.StatusFound,
+	}}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	batch := &fakeBatch{
+		deliveries: []Delivery{
+			delivery(1, srv.URL, 0, constant.EventLoanCreated),
+			delivery(2, srv.URL, 0, constant.EventLoanApproved),
+			delivery(3, srv.URL, 2, constant.EventLoanPaid),
+			delivery(4, srv.URL, 0, constant.EventLoanDefaulted),
+			delivery(5, down.URL, 1, constant.EventLoanCreated),
+		},
+		recorded: map[int64]Attempt{},
+	}
+
+	d := NewDispatcher(&fakeStore{batch: batch}, testCfg)
+	d.now = func() time.Time { return testNow }
+
+	handled, err := d.DispatchBatch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if handled != 5 || !batch.ended {
+		t.Fatalf("want 5 deliveries handled in an ended batch, got %d (ended %v)", handled, batch.ended)
+	}
+	if len(rc.errs) > 0 {
+		t.Fatalf("receiver rejected signatures: %v", rc.errs)
+	}
+	if len(rc.received) != 4 {
+		t.Errorf("want 4 deliveries received, got %d", len(rc.received))
+	}
+
+	for id, want := range map[int64]Attempt{
+		1: {Status: constant.WebhookDeliverySucceeded, Attempts: 1, ResponseCode: code(200)},
+		// a first failure waits BaseBackoff
+		2: {Status: constant.WebhookDeliveryPending, Attempts: 1, ResponseCode: code(500), NextAttemptAt: testNow.Add(30 * time.Second), Error: "endpoint answered 500 Internal Server Error"},
+		// the last attempt fails the delivery for good
+		3: {Status: constant.WebhookDeliveryFailed, Attempts: 3, ResponseCode: code(503), Error: "endpoint answered 503 Service Unavailable"},
+		// redirects are not followed
+		4: {Status: constant.WebhookDeliveryPending, Attempts: 1, ResponseCode: code(302), NextAttemptAt: testNow.Add(30 * time.Second), Error: "endpoint answered 302 Found"},
+		// no response, no response code
+		5: {Status: constant.WebhookDeliveryPending, Attempts: 2, NextAttemptAt: testNow.Add(time.Minute)},
+	} {
+		got := batch.recorded[id]
+		if got.Status != want.Status || got.Attempts != want.Attempts || !got.NextAttemptAt.Equal(want.NextAttemptAt) {
+			t.Errorf("delivery %d: want %s after %d attempts next at %v, got %s after %d next at %v",
+				id, want.Status, want.Attempts, want.NextAttemptAt, got.Status, got.Attempts, got.NextAttemptAt)
+		}
+		if (got.ResponseCode == nil) != (want.ResponseCode == nil) || (got.ResponseCode != nil && *got.ResponseCode != *want.ResponseCode) {
+			t.Errorf("delivery %d: want response code %v, got %v", id, want.ResponseCode, got.ResponseCode)
+		}
+		if want.Error != "" && got.Error != want.Error {
+			t.Errorf("delivery %d: want error %q, got %q", id, want.Error, got.Error)
+		}
+	}
+	if batch.recorded[5].Error == "" {
+		t.Error("want the connection error of delivery 5 logged")
+	}
+}
+
+func Test_Dispatcher_backoff(t *testing.T) {
+	d := NewDispatcher(&fakeStore{}, testCfg)
+	for attempts, want := range map[int]time.Duration{
+		1: 30 * time.Second,
+		2: time.Minute,
+		9: time.Minute,
+	} {
+		if got := d.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func Test_Verify(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	sig := Sign(testSecret, testNow.Unix(), body)
+	ts := "1792404000" // testNow
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		timestamp string
+		body      []byte
+		now       time.Time
+		wantErr   error
+	}{
+		{name: "valid", secret: testSecret, signature: sig, timestamp: ts, body: body, now: testNow},
+		{name: "valid within tolerance", secret: testSecret, signature: sig, timestamp: ts, body: body, now: testNow.Add(5 * time.Minute)},
+		{name: "other secret", secret: "whsec_other", signature: sig, timestamp: ts, body: body, now: testNow, wantErr: ErrInvalidSignature},
+		{name: "tampered body", secret: testSecret, signature: sig, timestamp: ts, body: []byte(`{"id":2}`), now: testNow, wantErr: ErrInvalidSignature},
+		{name: "tampered timestamp", secret: testSecret, signature: sig, timestamp: "1792404001", body: body, now: testNow, wantErr: ErrInvalidSignature},
+		{name: "unknown version", secret: testSecret, signature: "v0=" + sig[3:], timestamp: ts, body: body, now: testNow, wantErr: ErrInvalidSignature},
+		{name: "stale", secret: testSecret, signature: sig, timestamp: ts, body: body, now: testNow.Add(6 * time.Minute), wantErr: ErrStaleTimestamp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.signature, tt.timestamp, tt.body, tt.now, 5*time.Minute)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("want %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}